@@ -0,0 +1,116 @@
+// Package audit records every transformation of a registry, who asked for it
+// and what it cost, with payloads redacted to their structure.
+//
+//	logger := audit.NewLogger(audit.NewJSONLinesSink(f), audit.RedactHash)
+//	registry.OnTransform(logger.Hook)
+//	ctx = audit.WithPrincipal(ctx, apiKeyID)
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// Record is one audited transformation.
+type Record struct {
+	Time      time.Time                   `json:"time"`
+	Principal string                      `json:"principal,omitempty"`
+	Source    transformer.Provider        `json:"source"`
+	Target    transformer.Provider        `json:"target"`
+	Type      transformer.TransformerType `json:"type"`
+	Warnings  []transformer.Warning       `json:"warnings,omitempty"`
+	Usage     *transformer.StreamUsage    `json:"usage,omitempty"`
+	Error     string                      `json:"error,omitempty"`
+	// Src and Dst are the redacted payloads, absent with RedactOmit.
+	Src json.RawMessage `json:"src,omitempty"`
+	Dst json.RawMessage `json:"dst,omitempty"`
+}
+
+// Sink stores audit records. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, record *Record) error
+}
+
+// JSONLinesSink writes one JSON record per line.
+type JSONLinesSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLinesSink returns a sink writing to w.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONLinesSink) Write(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(record)
+}
+
+// Logger turns transformation events into audit records.
+type Logger struct {
+	sink      Sink
+	redaction Redaction
+	// Keep lists the object keys whose string values are kept verbatim by
+	// RedactHash, DefaultKeep when nil.
+	Keep map[string]bool
+	// OnError is called when the sink fails, errors are dropped when nil.
+	OnError func(error)
+}
+
+// NewLogger returns a logger writing records to sink.
+func NewLogger(sink Sink, redaction Redaction) *Logger {
+	return &Logger{sink: sink, redaction: redaction}
+}
+
+// Hook is a transformer.TransformHook recording event.
+func (l *Logger) Hook(ctx context.Context, event *transformer.TransformEvent) {
+	record := &Record{
+		Time:      time.Now().UTC(),
+		Principal: PrincipalFromContext(ctx),
+		Source:    event.Source,
+		Target:    event.Target,
+		Type:      event.Type,
+		Warnings:  event.Warnings,
+	}
+	if event.Err != nil {
+		record.Error = event.Err.Error()
+	} else if usage, ok := transformer.UsageOf(event.Src); ok {
+		record.Usage = &usage
+	}
+
+	keep := l.Keep
+	if keep == nil {
+		keep = DefaultKeep
+	}
+	var err error
+	if record.Src, err = Redact(event.Src, l.redaction, keep); err == nil && event.Err == nil {
+		record.Dst, err = Redact(event.Dst, l.redaction, keep)
+	}
+	if err == nil {
+		err = l.sink.Write(ctx, record)
+	}
+	if err != nil && l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context attributing its transformations to principal,
+// e.g. a user or API key id.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, or "".
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
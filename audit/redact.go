@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Redaction selects how payloads are recorded.
+type Redaction int
+
+const (
+	// RedactHash replaces string values by a short hash, keeping the payload
+	// structure, numbers, booleans and the strings under Keep keys. Equal
+	// content hashes equally, so records can still be correlated.
+	RedactHash Redaction = iota
+	// RedactOmit records no payload.
+	RedactOmit
+	// RedactNone records payloads verbatim.
+	RedactNone
+)
+
+// DefaultKeep are the keys whose values describe structure rather than content.
+var DefaultKeep = map[string]bool{
+	"type": true, "role": true, "model": true, "object": true, "name": true,
+	"id": true, "tool_call_id": true, "tool_use_id": true,
+	"finish_reason": true, "stop_reason": true, "finishReason": true,
+	"media_type": true, "mimeType": true,
+}
+
+// Redact renders payload as JSON according to redaction.
+func Redact(payload any, redaction Redaction, keep map[string]bool) (json.RawMessage, error) {
+	if redaction == RedactOmit || payload == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil || redaction == RedactNone {
+		return data, err
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(v, keep))
+}
+
+func redactValue(v any, keep map[string]bool) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if _, isString := field.(string); isString && keep[key] {
+				continue
+			}
+			value[key] = redactValue(field, keep)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = redactValue(item, keep)
+		}
+		return value
+	case string:
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	default:
+		return value
+	}
+}
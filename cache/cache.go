@@ -0,0 +1,61 @@
+// Package cache stores responses keyed by canonicalized unified requests, so a
+// gateway can replay them in test and development environments or for
+// deterministic evaluations.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// ErrNotFound is returned by Cache.Get on a miss.
+var ErrNotFound = errors.New("cache: not found")
+
+// Entry is a stored response. Exactly one of Response and Events is set.
+type Entry struct {
+	Response *transformer.UnifiedResponse `json:"response,omitempty"`
+	// Events is a recorded stream in the protocol of Provider.
+	Provider transformer.Provider      `json:"provider,omitempty"`
+	Events   []transformer.StreamEvent `json:"events,omitempty"`
+}
+
+// Cache is a pluggable response store. Implementations must be safe for
+// concurrent use and should treat entries as immutable.
+type Cache interface {
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, entry *Entry) error
+}
+
+// Key returns the cache key of a request: a hash of its canonical JSON form.
+// Map keys are sorted by encoding/json, so equal requests built in different
+// orders share a key. Streaming and non-streaming requests get distinct keys.
+func Key(req *transformer.UnifiedRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RequestKey returns the cache key of a provider request.
+func RequestKey(ctx context.Context, provider transformer.Provider, req interface{}) (string, error) {
+	u, err := transformer.ToUnifiedRequest(ctx, provider, req)
+	if err != nil {
+		return "", err
+	}
+	return Key(u)
+}
+
+// ResponseAs writes the stored response into dst, a response of provider.
+func (e *Entry) ResponseAs(ctx context.Context, provider transformer.Provider, dst interface{}) error {
+	if e.Response == nil {
+		return errors.New("cache: entry holds a stream, not a response")
+	}
+	return transformer.FromUnifiedResponse(ctx, provider, e.Response, dst)
+}
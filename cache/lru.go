@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// LRU is an in-memory Cache evicting the least recently used entry once it
+// holds capacity entries.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+// NewLRU returns an empty LRU cache holding at most capacity entries.
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: max(capacity, 1),
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key, or ErrNotFound.
+func (c *LRU) Get(ctx context.Context, key string) (*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, nil
+}
+
+// Set stores entry under key, evicting the least recently used entry if full.
+func (c *LRU) Set(ctx context.Context, key string, entry *Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return nil
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+	return nil
+}
+
+// Len returns the number of cached entries.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,30 @@
+// Package claude is kept for migration only; the types now live in
+// github.com/phosae/llms/dto/claude.
+//
+// Deprecated: import github.com/phosae/llms/dto/claude instead.
+package claude
+
+import "github.com/phosae/llms/dto/claude"
+
+type (
+	ClaudeError                 = claude.ClaudeError
+	ClaudeErrorWithStatusCode   = claude.ClaudeErrorWithStatusCode
+	ClaudeMediaMessage          = claude.ClaudeMediaMessage
+	ClaudeMessage               = claude.ClaudeMessage
+	ClaudeMessageSource         = claude.ClaudeMessageSource
+	ClaudeMetadata              = claude.ClaudeMetadata
+	ClaudeRequest               = claude.ClaudeRequest
+	ClaudeResponse              = claude.ClaudeResponse
+	ClaudeServerToolUse         = claude.ClaudeServerToolUse
+	ClaudeToolChoice            = claude.ClaudeToolChoice
+	ClaudeUsage                 = claude.ClaudeUsage
+	ClaudeWebSearchTool         = claude.ClaudeWebSearchTool
+	ClaudeWebSearchUserLocation = claude.ClaudeWebSearchUserLocation
+	InputSchema                 = claude.InputSchema
+	Thinking                    = claude.Thinking
+	Tool                        = claude.Tool
+)
+
+var (
+	ProcessTools = claude.ProcessTools
+)
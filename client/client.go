@@ -0,0 +1,277 @@
+// Package client calls the chat APIs of providers with unified requests, so
+// Go programs talk to any of them through one interface:
+//
+//	c := &client.Client{Backends: []client.Backend{
+//		{Provider: transformer.ProviderClaude, Models: []string{"claude-"}, APIKey: anthropicKey},
+//		{Provider: transformer.ProviderOpenAI, APIKey: openAIKey},
+//	}}
+//	resp, usage, err := c.Do(ctx, &transformer.UnifiedRequest{
+//		Model:    "claude-sonnet-4-5",
+//		Messages: []transformer.UnifiedMessage{{Role: "user", Parts: []transformer.UnifiedPart{{Type: transformer.UnifiedPartText, Text: "Hello"}}}},
+//	})
+//
+// Requests are translated for the backend serving their model, and its
+// responses, streams and errors translated back, by the transformer
+// package. A Client is an eval.Client, running evaluation cases against
+// the backends directly.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/eval"
+	"github.com/phosae/llms/transformer"
+)
+
+var _ eval.Client = (*Client)(nil)
+
+// Signer signs a request once its headers are set, body is its payload.
+// The signers of the gateway package, such as gateway.AWSSigV4 for Bedrock
+// and gateway.GCPOAuth for Vertex AI, are Signers.
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// Backend is a provider endpoint serving some models.
+type Backend struct {
+	Provider transformer.Provider
+	// Models are the prefixes of the models the backend serves, every model
+	// when empty.
+	Models []string
+	// BaseURL is the root of the API, the public endpoint of the provider
+	// when empty. Bedrock and Vertex AI backends set it: the runtime
+	// endpoint of a region, e.g. https://bedrock-runtime.us-east-1.amazonaws.com,
+	// and .../v1/projects/{project}/locations/{location}.
+	BaseURL string
+	// APIKey authenticates requests in the header of the provider: x-api-key
+	// for Claude, x-goog-api-key for Gemini and a bearer token otherwise.
+	APIKey string
+	// Header is set on every request.
+	Header http.Header
+	// Signer, when set, signs every request.
+	Signer Signer
+}
+
+// baseURLs are the public endpoints of providers.
+var baseURLs = map[transformer.Provider]string{
+	transformer.ProviderOpenAI: "https://api.openai.com",
+	transformer.ProviderClaude: "https://api.anthropic.com",
+	transformer.ProviderGemini: "https://generativelanguage.googleapis.com",
+	transformer.ProviderOllama: "http://localhost:11434",
+}
+
+// Client sends unified requests to the backend serving their model. It is
+// safe for concurrent use.
+type Client struct {
+	// Backends serve requests by model: the backend whose Models has the
+	// longest prefix of the model, else the first serving every model.
+	Backends []Backend
+	// HTTPClient sends the requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Config maps models and selects presets for the translation, nil for none.
+	Config *config.Store
+	// Options apply to every translated request.
+	Options transformer.Options
+	// Hedge, when set, races a secondary backend against the one serving
+	// each request, for tail latency.
+	Hedge *Hedge
+}
+
+// Do sends u, without streaming, and returns the response of the backend
+// with its usage. Failed responses return a *transformer.UnifiedError.
+func (c *Client) Do(ctx context.Context, u *transformer.UnifiedRequest) (*transformer.UnifiedResponse, transformer.StreamUsage, error) {
+	backend, err := c.backend(u.Model)
+	if err != nil {
+		return nil, transformer.StreamUsage{}, err
+	}
+	single := *u
+	single.Stream = false
+	out, cancel, err := hedged(ctx, c, backend, func(ctx context.Context, backend *Backend) (*transformer.UnifiedResponse, error) {
+		return c.do(ctx, backend, &single)
+	}, func(*transformer.UnifiedResponse) {})
+	if err != nil {
+		return nil, transformer.StreamUsage{}, err
+	}
+	cancel()
+	return out, out.Usage, nil
+}
+
+// do sends u to backend and returns its response.
+func (c *Client) do(ctx context.Context, backend *Backend, u *transformer.UnifiedRequest) (*transformer.UnifiedResponse, error) {
+	ctx = c.context(ctx)
+	resp, model, err := c.send(ctx, backend, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	payload, err := transformer.NewPayload(backend.Provider, transformer.TransformerTypeResponse)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(payload); err != nil {
+		return nil, fmt.Errorf("invalid %s response: %w", backend.Provider, err)
+	}
+	out, err := transformer.ToUnifiedResponse(ctx, backend.Provider, payload)
+	if err != nil {
+		return nil, err
+	}
+	if out.Model == "" {
+		out.Model = model
+	}
+	return out, nil
+}
+
+// backend returns the backend serving model.
+func (c *Client) backend(model string) (*Backend, error) {
+	var found *Backend
+	best := -1
+	for i := range c.Backends {
+		b := &c.Backends[i]
+		if len(b.Models) == 0 && best < 0 {
+			found, best = b, 0
+		}
+		for _, prefix := range b.Models {
+			if strings.HasPrefix(model, prefix) && len(prefix) > best {
+				found, best = b, len(prefix)
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no backend serves model %q", model)
+	}
+	return found, nil
+}
+
+// context returns ctx with the config and options of the client. Tools
+// renamed for the backend get their names back in its responses.
+func (c *Client) context(ctx context.Context) context.Context {
+	if c.Config != nil {
+		ctx = config.NewContext(ctx, c.Config.Current())
+	}
+	ctx = transformer.WithOptions(ctx, c.Options)
+	ctx, _ = transformer.WithToolNames(ctx)
+	return ctx
+}
+
+// send translates u for backend and posts it, returning the successful
+// response and the model it was sent to.
+func (c *Client) send(ctx context.Context, backend *Backend, u *transformer.UnifiedRequest) (*http.Response, string, error) {
+	req, model, path, err := c.request(ctx, backend, u)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, "", err
+	}
+	baseURL := backend.BaseURL
+	if baseURL == "" {
+		if baseURL = baseURLs[backend.Provider]; baseURL == "" {
+			return nil, "", fmt.Errorf("%s backend has no BaseURL", backend.Provider)
+		}
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if backend.APIKey != "" {
+		switch backend.Provider {
+		case transformer.ProviderClaude:
+			httpReq.Header.Set("X-Api-Key", backend.APIKey)
+		case transformer.ProviderGemini:
+			httpReq.Header.Set("X-Goog-Api-Key", backend.APIKey)
+		default:
+			httpReq.Header.Set("Authorization", "Bearer "+backend.APIKey)
+		}
+	}
+	if req, ok := req.(*claude.ClaudeRequest); ok {
+		httpReq.Header.Set("Anthropic-Version", claude.APIVersion)
+		if betas := req.Betas(); len(betas) > 0 {
+			httpReq.Header.Set("Anthropic-Beta", strings.Join(betas, ","))
+		}
+	}
+	for name, values := range backend.Header {
+		httpReq.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if backend.Signer != nil {
+		if err := backend.Signer.Sign(httpReq, data); err != nil {
+			return nil, "", fmt.Errorf("sign request: %w", err)
+		}
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, "", transformer.ErrorToUnified(backend.Provider, resp.StatusCode, data)
+	}
+	return resp, model, nil
+}
+
+// request converts u to the request of backend, returning it with its
+// model and path.
+func (c *Client) request(ctx context.Context, backend *Backend, u *transformer.UnifiedRequest) (any, string, string, error) {
+	dst, err := transformer.NewPayload(backend.Provider, transformer.TransformerTypeRequest)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := transformer.FromUnifiedRequest(ctx, backend.Provider, u, dst); err != nil {
+		return nil, "", "", err
+	}
+
+	switch req := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		if req.Stream {
+			req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+		}
+		return req, req.Model, "/v1/chat/completions", nil
+	case *claude.ClaudeRequest:
+		return req, req.Model, "/v1/messages", nil
+	case *gemini.GeminiChatRequest, *vertex.GenerateContentRequest:
+		// Gemini requests carry no model, it goes in the path
+		model := transformer.TargetModel(ctx, backend.Provider, u.Model)
+		path := "/v1beta/models/" + url.PathEscape(model)
+		if backend.Provider == transformer.ProviderVertexGemini {
+			path = "/publishers/google/models/" + url.PathEscape(model)
+		}
+		if u.Stream {
+			return req, model, path + ":streamGenerateContent?alt=sse", nil
+		}
+		return req, model, path + ":generateContent", nil
+	case *bedrock.ConverseRequest:
+		// the model goes in the path, not the body
+		model := req.ModelID
+		req.ModelID = ""
+		path := "/model/" + url.PathEscape(model) + "/converse"
+		if u.Stream {
+			path += "-stream"
+		}
+		return req, model, path, nil
+	case *ollama.ChatRequest:
+		return req, req.Model, "/api/chat", nil
+	default:
+		return nil, "", "", fmt.Errorf("unsupported provider %s", backend.Provider)
+	}
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/phosae/llms/transformer"
+)
+
+func TestGeminiPathNamesTheMappedModel(t *testing.T) {
+	var path string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]},"finishReason":"STOP"}],"modelVersion":"gemini-2.5-flash"}`)
+	}))
+	defer backend.Close()
+	mapper, err := transformer.NewModelMapper([]transformer.ModelRule{{Prefix: "gpt-", To: "gemini-2.5-flash"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{Backends: []Backend{{Provider: transformer.ProviderGemini, BaseURL: backend.URL}}}
+	ctx := transformer.WithModelMapper(context.Background(), transformer.ProviderGemini, mapper)
+	if _, _, err := c.Do(ctx, &transformer.UnifiedRequest{
+		Model:    "gpt-4o",
+		Messages: []transformer.UnifiedMessage{{Role: "user", Parts: []transformer.UnifiedPart{{Type: transformer.UnifiedPartText, Text: "hi"}}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v1beta/models/gemini-2.5-flash:generateContent"; path != want {
+		t.Errorf("got path %q, want %q", path, want)
+	}
+}
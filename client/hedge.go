@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Hedge is the secondary backend of a Client. When the backend serving a
+// request has not started answering after Delay, the request is translated
+// for Backend and sent there too; the caller gets whichever response starts
+// first, and the other request is canceled. Streams start with their first
+// content delta: text, thinking or a tool call, not the opening events such
+// as Claude's message_start, nor pings.
+type Hedge struct {
+	Backend Backend
+	Delay   time.Duration
+}
+
+// hedged runs try with primary and, when it has not returned after the
+// delay of the hedge, with the hedge backend too. The first success wins,
+// with the cancel func of its context; the other attempt is canceled and
+// release is called on its result. When both fail, the error of primary is
+// returned.
+func hedged[T any](ctx context.Context, c *Client, primary *Backend, try func(context.Context, *Backend) (T, error), release func(T)) (T, context.CancelFunc, error) {
+	if c.Hedge == nil {
+		ctx, cancel := context.WithCancel(ctx)
+		value, err := try(ctx, primary)
+		if err != nil {
+			cancel()
+			return value, nil, err
+		}
+		return value, cancel, nil
+	}
+
+	type result struct {
+		value   T
+		err     error
+		primary bool
+	}
+	results := make(chan result, 2)
+	var cancels []context.CancelFunc
+	launch := func(backend *Backend) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		primary := backend == primary
+		go func() {
+			value, err := try(ctx, backend)
+			results <- result{value, err, primary}
+		}()
+	}
+	launch(primary)
+	pending := 1
+	timer := time.NewTimer(c.Hedge.Delay)
+	defer timer.Stop()
+
+	var failure error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			launch(&c.Hedge.Backend)
+			pending++
+		case r := <-results:
+			pending--
+			if r.err != nil {
+				if failure == nil || r.primary {
+					failure = r.err
+				}
+				if len(cancels) == 1 {
+					// the primary failed before the hedge was sent
+					pending = 0
+				}
+				continue
+			}
+			// the loser is canceled now and released once it returns
+			won := 0
+			if !r.primary {
+				won = 1
+			}
+			for i, cancel := range cancels {
+				if i != won {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for range pending {
+					if loser := <-results; loser.err == nil {
+						release(loser.value)
+					}
+				}
+			}(pending)
+			return r.value, cancels[won], nil
+		}
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	var zero T
+	return zero, nil, failure
+}
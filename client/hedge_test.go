@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// slowClaude opens its stream and pings at once, but sends its text late.
+func slowClaude(release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-sonnet-4-5\",\"content\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":1}}}\n\n")
+		io.WriteString(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		io.WriteString(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n")
+		io.WriteString(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"slow\"}}\n\n")
+		io.WriteString(w, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n")
+		io.WriteString(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n")
+		io.WriteString(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	})
+}
+
+func fastOpenAI() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"fast\"}}]}\n\n")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	})
+}
+
+func TestHedgeWaitsForTheFirstContentDelta(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	primary := httptest.NewServer(slowClaude(release))
+	defer primary.Close()
+	secondary := httptest.NewServer(fastOpenAI())
+	defer secondary.Close()
+
+	c := &Client{
+		Backends: []Backend{{Provider: transformer.ProviderClaude, BaseURL: primary.URL}},
+		Hedge:    &Hedge{Backend: Backend{Provider: transformer.ProviderOpenAI, BaseURL: secondary.URL}, Delay: 20 * time.Millisecond},
+	}
+	if got := streamText(t, c); got != "fast" {
+		t.Errorf("got %q, want the stream of the hedge", got)
+	}
+}
+
+func TestHedgeKeepsAPrimaryThatStarted(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	primary := httptest.NewServer(slowClaude(release))
+	defer primary.Close()
+	secondary := httptest.NewServer(fastOpenAI())
+	defer secondary.Close()
+
+	c := &Client{
+		Backends: []Backend{{Provider: transformer.ProviderClaude, BaseURL: primary.URL}},
+		Hedge:    &Hedge{Backend: Backend{Provider: transformer.ProviderOpenAI, BaseURL: secondary.URL}, Delay: time.Minute},
+	}
+	if got := streamText(t, c); got != "slow" {
+		t.Errorf("got %q, want the stream of the primary", got)
+	}
+}
+
+func streamText(t *testing.T, c *Client) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := c.Stream(ctx, &transformer.UnifiedRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []transformer.UnifiedMessage{{Role: "user", Parts: []transformer.UnifiedPart{{Type: transformer.UnifiedPartText, Text: "hi"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var text strings.Builder
+	for chunk, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range chunk.Delta.Parts {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/transformer"
+)
+
+// Stream is a streaming response, read once with Chunks:
+//
+//	stream, err := c.Stream(ctx, req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for chunk, err := range stream.Chunks() {
+//		...
+//	}
+type Stream struct {
+	// Model is the model the request was sent to.
+	Model string
+
+	resp   *http.Response
+	cancel context.CancelFunc
+	// next pulls the chunks of the response, read ahead into buffered
+	// by hedged streams
+	next     func() (*transformer.UnifiedChunk, error, bool)
+	stop     func()
+	buffered []*transformer.UnifiedChunk
+}
+
+// Stream sends u and returns the stream of the backend once it answers,
+// once it sends its first content delta with a Hedge. Failed responses
+// return a *transformer.UnifiedError.
+func (c *Client) Stream(ctx context.Context, u *transformer.UnifiedRequest) (*Stream, error) {
+	backend, err := c.backend(u.Model)
+	if err != nil {
+		return nil, err
+	}
+	streaming := *u
+	streaming.Stream = true
+	s, cancel, err := hedged(ctx, c, backend, func(ctx context.Context, backend *Backend) (*Stream, error) {
+		s, err := c.open(ctx, backend, &streaming)
+		if err != nil || c.Hedge == nil {
+			return s, err
+		}
+		if err := s.firstContent(); err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	}, func(s *Stream) { s.Close() })
+	if err != nil {
+		return nil, err
+	}
+	s.cancel = cancel
+	return s, nil
+}
+
+// open sends u to backend and returns its stream.
+func (c *Client) open(ctx context.Context, backend *Backend, u *transformer.UnifiedRequest) (*Stream, error) {
+	ctx = c.context(ctx)
+	resp, model, err := c.send(ctx, backend, u)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{Model: model, resp: resp}
+	s.next, s.stop = iter.Pull2(s.chunks(ctx, backend.Provider))
+	return s, nil
+}
+
+// chunks yields the chunks of the response of provider.
+func (s *Stream) chunks(ctx context.Context, provider transformer.Provider) iter.Seq2[*transformer.UnifiedChunk, error] {
+	return func(yield func(*transformer.UnifiedChunk, error) bool) {
+		// provider streams pivot through OpenAI chunks, with usage
+		opts := transformer.OptionsFromContext(ctx)
+		opts.IncludeUsage = true
+		ctx := transformer.WithOptions(ctx, opts)
+		events := sse.NewStream(s.resp.Body, provider, transformer.ProviderOpenAI)
+		events.Model = s.Model
+		tracker := transformer.NewTruncationTracker()
+		for event, err := range events.Events(ctx) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			chunk, err := transformer.ToUnifiedChunk(ctx, transformer.ProviderOpenAI, event)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			tracker.Observe(chunk)
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+// firstContent reads the chunks of the stream up to its first content
+// delta, or to its end.
+func (s *Stream) firstContent() error {
+	for {
+		chunk, err, ok := s.next()
+		if !ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.buffered = append(s.buffered, chunk)
+		if chunk.HasContent() {
+			return nil
+		}
+	}
+}
+
+// Chunks yields the chunks of the stream as they arrive, the last ones
+// with the finish reason and the usage. Tool calls cut off by the token
+// limit are flagged Partial, as TruncationTracker does. Once the context of
+// the request is done, the iteration ends with its error.
+func (s *Stream) Chunks() iter.Seq2[*transformer.UnifiedChunk, error] {
+	return func(yield func(*transformer.UnifiedChunk, error) bool) {
+		for len(s.buffered) > 0 {
+			chunk := s.buffered[0]
+			s.buffered = s.buffered[1:]
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+		for {
+			chunk, err, ok := s.next()
+			if !ok || !yield(chunk, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close releases the connection of the stream. It must not run while
+// Chunks is iterated in another goroutine; canceling the context of the
+// request ends the iteration instead.
+func (s *Stream) Close() error {
+	err := s.resp.Body.Close()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.stop()
+	return err
+}
@@ -0,0 +1,504 @@
+// Command llm-transformers converts and validates LLM API payloads from the
+// command line.
+//
+//	llm-transformers transform -from openai -to claude -type request req.json
+//	llm-transformers validate -provider claude -type response resp.json
+//	llm-transformers batch -from gemini -to openai -type response logs.jsonl
+//	llm-transformers fidelity -from openai -via claude req.json
+//	llm-transformers stream -from claude -to openai -ndjson events.txt
+//	llm-transformers eval -provider claude -base-url https://api.anthropic.com -header "X-Api-Key: $KEY" cases.jsonl
+//
+// Payloads are read from the given file, or from stdin when omitted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/eval"
+	"github.com/phosae/llms/gateway"
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/streamtest"
+	"github.com/phosae/llms/transformer"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "transform":
+		err = runTransform(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "batch":
+		err = runBatch(os.Args[2:])
+	case "fidelity":
+		err = runFidelity(os.Args[2:])
+	case "stream":
+		err = runStream(os.Args[2:])
+	case "eval":
+		err = runEval(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  llm-transformers transform -from <provider> -to <provider> [-type request|response|stream|chunk] [-embeddings | -audio transcription|speech | -images] [-strict-decoding] [-config file] [file]
+  llm-transformers validate -provider <provider> [-type request|response] [file]
+  llm-transformers batch -from <provider> -to <provider> [-type request|response|chunk] [-workers n] [-config file] [file.jsonl]
+  llm-transformers fidelity -from <provider> -via <provider> [-type request|response] [-config file] [file]
+  llm-transformers stream -from <provider> -to <provider> [-ndjson] [-recorded] [-webhook url [-interval d]] [-config file] [file]
+  llm-transformers eval -provider <provider> -base-url <url> [-header "Name: value"]... [-concurrency n] [-config file] [cases.jsonl]`)
+}
+
+func newRegistry() *transformer.TransformationRegistry {
+	registry := transformer.NewTransformationRegistry()
+	for _, t := range []transformer.Transformer{
+		transformer.NewOpenAITransformer(),
+		transformer.NewGeminiTransformer(),
+		transformer.NewClaudeTransformer(),
+		transformer.NewBedrockTransformer(),
+		transformer.NewVertexGeminiTransformer(),
+		transformer.NewOllamaTransformer(),
+	} {
+		for _, target := range []transformer.Provider{transformer.ProviderOpenAI, transformer.ProviderGemini, transformer.ProviderClaude, transformer.ProviderBedrock, transformer.ProviderVertexGemini, transformer.ProviderOllama} {
+			registry.Register(t.GetProvider(), target, t)
+		}
+	}
+	registry.RegisterEmbeddings(transformer.NewOpenAITransformer())
+	registry.RegisterEmbeddings(transformer.NewGeminiTransformer())
+	registry.RegisterEmbeddings(transformer.NewCohereTransformer())
+	registry.RegisterAudio(transformer.NewOpenAITransformer())
+	registry.RegisterAudio(transformer.NewGeminiTransformer())
+	registry.RegisterImages(transformer.NewOpenAITransformer())
+	registry.RegisterImages(transformer.NewGeminiTransformer())
+	return registry
+}
+
+func runTransform(args []string) error {
+	fs := flag.NewFlagSet("transform", flag.ExitOnError)
+	from := fs.String("from", "", "source provider")
+	to := fs.String("to", "", "target provider")
+	typ := fs.String("type", string(transformer.TransformerTypeRequest), "payload type")
+	configPath := fs.String("config", "", "mapping configuration file")
+	azure := fs.Bool("azure", false, "name Azure OpenAI deployments in OpenAI requests")
+	normalize := fs.String("normalize", "", "normalize payloads when from and to match: canonical or strip")
+	embeddings := fs.Bool("embeddings", false, "transform embedding payloads")
+	audio := fs.String("audio", "", "transform audio payloads: transcription or speech")
+	images := fs.Bool("images", false, "transform image generation payloads")
+	strict := fs.Bool("strict-decoding", false, "reject unknown fields of requests and responses")
+	fs.Parse(args)
+
+	opts := transformer.Options{Azure: *azure, StrictDecoding: *strict}
+	switch *normalize {
+	case "":
+	case "canonical":
+		opts.Normalize = transformer.NormalizeCanonical
+	case "strip":
+		opts.Normalize = transformer.NormalizeStrip
+	default:
+		return fmt.Errorf("unknown normalize policy %q", *normalize)
+	}
+	ctx := transformer.WithOptions(context.Background(), opts)
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		ctx = config.NewContext(ctx, cfg)
+	}
+
+	registry := newRegistry()
+	newPayload, transform := transformer.NewPayload, registry.Transform
+	if *embeddings {
+		newPayload, transform = transformer.NewEmbeddingsPayload, registry.TransformEmbeddings
+	}
+	if *images {
+		newPayload, transform = transformer.NewImagesPayload, registry.TransformImages
+	}
+	if *audio != "" {
+		newPayload = func(provider transformer.Provider, typ transformer.TransformerType) (interface{}, error) {
+			return transformer.NewAudioPayload(provider, transformer.AudioOperation(*audio), typ)
+		}
+		transform = registry.TransformAudio
+	}
+	var src interface{}
+	var err error
+	if *strict {
+		src, err = parsePayload(ctx, fs.Arg(0), transformer.Provider(*from), transformer.TransformerType(*typ))
+	} else {
+		src, err = readPayloadAs(fs.Arg(0), transformer.Provider(*from), transformer.TransformerType(*typ), newPayload)
+	}
+	if err != nil {
+		return err
+	}
+	dst, err := newPayload(transformer.Provider(*to), transformer.TransformerType(*typ))
+	if err != nil {
+		return err
+	}
+	ctx, warnings := transformer.WithWarnings(ctx)
+	if err := transform(ctx, transformer.Provider(*from), transformer.Provider(*to), transformer.TransformerType(*typ), src, dst); err != nil {
+		return err
+	}
+	for _, w := range warnings.List() {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	return writeJSON(os.Stdout, dst)
+}
+
+// runBatch converts a JSON Lines corpus, writing one converted payload per
+// line, or null for payloads that failed, in input order.
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	from := fs.String("from", "", "source provider")
+	to := fs.String("to", "", "target provider")
+	typ := fs.String("type", string(transformer.TransformerTypeRequest), "payload type")
+	workers := fs.Int("workers", 0, "concurrent transformations, GOMAXPROCS when 0")
+	configPath := fs.String("config", "", "mapping configuration file")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		ctx = config.NewContext(ctx, cfg)
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	source, target, payloadType := transformer.Provider(*from), transformer.Provider(*to), transformer.TransformerType(*typ)
+	var jobs []transformer.TransformJob
+	dec := json.NewDecoder(r)
+	for line := 1; dec.More(); line++ {
+		src, err := transformer.NewPayload(source, payloadType)
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(src); err != nil {
+			return fmt.Errorf("payload %d: %w", line, err)
+		}
+		dst, err := transformer.NewPayload(target, payloadType)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, transformer.TransformJob{Source: source, Target: target, Type: payloadType, Src: src, Dst: dst})
+	}
+
+	registry := newRegistry()
+	registry.SetBatchConcurrency(*workers)
+	enc := json.NewEncoder(os.Stdout)
+	failed := 0
+	for _, result := range registry.TransformAll(ctx, jobs) {
+		for _, w := range result.Warnings {
+			fmt.Fprintf(os.Stderr, "payload %d: warning: %s\n", result.Index+1, w)
+		}
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "payload %d: error: %v\n", result.Index+1, result.Err)
+			enc.Encode(nil)
+			continue
+		}
+		if err := enc.Encode(jobs[result.Index].Dst); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d payloads failed", failed, len(jobs))
+	}
+	return nil
+}
+
+// runStream translates a raw provider stream, as saved from a streaming
+// response body, into the stream of another provider, or into one NDJSON
+// record per translated event. With -recorded the input is such an NDJSON
+// recording, translated with its timing: into a recording again, or
+// replayed with the recorded delays between events.
+func runStream(args []string) error {
+	fs := flag.NewFlagSet("stream", flag.ExitOnError)
+	from := fs.String("from", "", "source provider")
+	to := fs.String("to", "", "target provider")
+	ndjson := fs.Bool("ndjson", false, "write one JSON record per event, with its index and timestamps")
+	recorded := fs.Bool("recorded", false, "read an NDJSON recording and keep its timing")
+	webhook := fs.String("webhook", "", "post the translated stream to this URL in batches instead")
+	interval := fs.Duration("interval", sse.DefaultWebhookInterval, "time a webhook batch collects chunks for")
+	configPath := fs.String("config", "", "mapping configuration file")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		ctx = config.NewContext(ctx, cfg)
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	source, target := transformer.Provider(*from), transformer.Provider(*to)
+	ctx, warnings := transformer.WithWarnings(ctx)
+	defer func() {
+		for _, w := range warnings.List() {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+	}()
+	if *recorded {
+		events, err := streamtest.ReadLog(r)
+		if err != nil {
+			return err
+		}
+		if events, err = streamtest.Translate(ctx, source, target, events); err != nil {
+			return err
+		}
+		if *ndjson {
+			return streamtest.WriteLog(os.Stdout, source, target, events)
+		}
+		if target == transformer.ProviderBedrock {
+			return fmt.Errorf("recorded streams replay as server-sent events, use -ndjson for %s", target)
+		}
+		return streamtest.Play(ctx, os.Stdout, events)
+	}
+	if *webhook != "" {
+		hook := &sse.Webhook{URL: *webhook, Interval: *interval}
+		return hook.Deliver(ctx, fmt.Sprintf("stream-%d", time.Now().UnixNano()), sse.NewStream(r, source, target).Events(ctx))
+	}
+	if !*ndjson {
+		return sse.StreamPipe(ctx, source, target, r, os.Stdout)
+	}
+	log := sse.NewLogWriter(os.Stdout, source, target)
+	for chunk, err := range sse.NewStream(r, source, target).Events(ctx) {
+		if err != nil {
+			return err
+		}
+		if err := log.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runEval runs the cases of a JSON lines file against an upstream and
+// reports their results, failing when any case fails.
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	provider := fs.String("provider", "", "upstream provider")
+	baseURL := fs.String("base-url", "", "root of the upstream API")
+	header := http.Header{}
+	fs.Func("header", "header sent upstream, \"Name: value\", repeatable", func(s string) error {
+		name, value, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("expected \"Name: value\", got %q", s)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		return nil
+	})
+	concurrency := fs.Int("concurrency", 1, "cases run at once")
+	configPath := fs.String("config", "", "mapping and pricing configuration file")
+	fs.Parse(args)
+
+	var cfg *config.Config
+	if *configPath != "" {
+		var err error
+		if cfg, err = config.Load(*configPath); err != nil {
+			return err
+		}
+	}
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	cases, err := eval.LoadCases(r)
+	if err != nil {
+		return err
+	}
+
+	proxy := &gateway.Proxy{
+		Target:   transformer.Provider(*provider),
+		Upstream: &gateway.Upstream{BaseURL: *baseURL, Header: header},
+	}
+	if cfg != nil {
+		proxy.Config = config.NewStaticStore(cfg)
+	}
+	runner := &eval.Runner{Client: proxy, Pricing: cfg, Concurrency: *concurrency}
+	report := runner.Run(context.Background(), cases)
+	if err := writeJSON(os.Stdout, report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", report.Failed, len(cases))
+	}
+	return nil
+}
+
+// runFidelity scores a round trip of a payload through another provider.
+func runFidelity(args []string) error {
+	fs := flag.NewFlagSet("fidelity", flag.ExitOnError)
+	from := fs.String("from", "", "payload provider")
+	via := fs.String("via", "", "provider of the round trip")
+	typ := fs.String("type", string(transformer.TransformerTypeRequest), "payload type: request or response")
+	configPath := fs.String("config", "", "mapping configuration file")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		ctx = config.NewContext(ctx, cfg)
+	}
+	src, err := readPayload(fs.Arg(0), transformer.Provider(*from), transformer.TransformerType(*typ))
+	if err != nil {
+		return err
+	}
+	report, err := newRegistry().Fidelity(ctx, transformer.Provider(*from), transformer.Provider(*via), transformer.TransformerType(*typ), src)
+	if err != nil {
+		return err
+	}
+	return writeJSON(os.Stdout, report)
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	provider := fs.String("provider", "", "payload provider")
+	typ := fs.String("type", string(transformer.TransformerTypeRequest), "payload type: request or response")
+	fs.Parse(args)
+
+	var t transformer.Transformer
+	switch transformer.Provider(*provider) {
+	case transformer.ProviderOpenAI:
+		t = transformer.NewOpenAITransformer()
+	case transformer.ProviderGemini:
+		t = transformer.NewGeminiTransformer()
+	case transformer.ProviderClaude:
+		t = transformer.NewClaudeTransformer()
+	case transformer.ProviderBedrock:
+		t = transformer.NewBedrockTransformer()
+	case transformer.ProviderVertexGemini:
+		t = transformer.NewVertexGeminiTransformer()
+	case transformer.ProviderOllama:
+		t = transformer.NewOllamaTransformer()
+	default:
+		return fmt.Errorf("unsupported provider: %s", *provider)
+	}
+
+	payload, err := readPayload(fs.Arg(0), transformer.Provider(*provider), transformer.TransformerType(*typ))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch transformer.TransformerType(*typ) {
+	case transformer.TransformerTypeRequest:
+		err = t.ValidateRequest(ctx, payload)
+	case transformer.TransformerTypeResponse:
+		err = t.ValidateResponse(ctx, payload)
+	default:
+		return fmt.Errorf("cannot validate payload type: %s", *typ)
+	}
+	if err != nil {
+		return fmt.Errorf("validation failed:\n%w", err)
+	}
+	fmt.Println("valid")
+	return nil
+}
+
+func readPayload(path string, provider transformer.Provider, typ transformer.TransformerType) (interface{}, error) {
+	return readPayloadAs(path, provider, typ, transformer.NewPayload)
+}
+
+// readPayloadAs decodes the payload created by newPayload.
+func readPayloadAs(path string, provider transformer.Provider, typ transformer.TransformerType, newPayload func(transformer.Provider, transformer.TransformerType) (interface{}, error)) (interface{}, error) {
+	var r io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	payload, err := newPayload(provider, typ)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(r).Decode(payload); err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %w", provider, typ, err)
+	}
+	return payload, nil
+}
+
+// parsePayload reads a chat request or response with the strict decoding of
+// ctx.
+func parsePayload(ctx context.Context, path string, provider transformer.Provider, typ transformer.TransformerType) (interface{}, error) {
+	var data []byte
+	var err error
+	if path != "" && path != "-" {
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case transformer.TransformerTypeRequest:
+		return transformer.ParseRequest(ctx, provider, data)
+	case transformer.TransformerTypeResponse:
+		return transformer.ParseResponse(ctx, provider, data)
+	default:
+		return nil, fmt.Errorf("strict decoding applies to requests and responses, not %s", typ)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,349 @@
+// Package config describes runtime-tunable transformation behavior: model
+// mappings, per-model default parameters, safety settings and provider quirks.
+package config
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Wildcard is the model key matching any model without its own entry.
+const Wildcard = "*"
+
+var knownProviders = map[string]bool{
+	"openai": true, "gemini": true, "claude": true, "bedrock": true, "vertex_gemini": true, "azure": true,
+}
+
+// Config is the root of a mapping configuration file.
+type Config struct {
+	// ModelMappings rewrites model names on the way to a target provider.
+	ModelMappings []ModelMapping `json:"model_mappings,omitempty"`
+	// Models holds per-target-model settings, keyed by model name or Wildcard.
+	Models map[string]ModelSettings `json:"models,omitempty"`
+	// SafetyProfiles are named Gemini-style safety setting lists.
+	SafetyProfiles map[string][]SafetySetting `json:"safety_profiles,omitempty"`
+	// QuirkProfiles are named sets of backend quirks.
+	QuirkProfiles map[string]Quirks `json:"quirk_profiles,omitempty"`
+	// Pricing holds per-model prices, keyed by model name or Wildcard.
+	Pricing map[string]Price `json:"pricing,omitempty"`
+}
+
+// ModelMapping maps a source model name to the model sent to the target.
+type ModelMapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Provider restricts the mapping to one target provider, empty means any.
+	// Mappings of provider azure name the deployments of Azure OpenAI.
+	Provider string `json:"provider,omitempty"`
+}
+
+// ModelSettings are defaults filled into requests that omit them.
+type ModelSettings struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	MaxTokens     int      `json:"max_tokens,omitempty"`
+	SafetyProfile string   `json:"safety_profile,omitempty"`
+	QuirkProfile  string   `json:"quirk_profile,omitempty"`
+	// SystemPrefix is prepended to the system prompt of every request.
+	SystemPrefix string `json:"system_prefix,omitempty"`
+}
+
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// Price is the cost of a model in currency units per million tokens.
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
+}
+
+// Cost returns the price of the given token counts.
+func (p Price) Cost(input, output, cacheRead, cacheWrite int) float64 {
+	return (float64(input)*p.Input + float64(output)*p.Output +
+		float64(cacheRead)*p.CacheRead + float64(cacheWrite)*p.CacheWrite) / 1e6
+}
+
+// Quirks toggles behavior for backends that deviate from the official APIs.
+type Quirks struct {
+	// UseMaxCompletionTokens sends max_completion_tokens instead of max_tokens to OpenAI targets.
+	UseMaxCompletionTokens bool `json:"use_max_completion_tokens,omitempty"`
+	// DropCacheControl strips the un-official cache_control fields.
+	DropCacheControl bool `json:"drop_cache_control,omitempty"`
+	// SystemAsUser folds system prompts into a leading user message.
+	SystemAsUser bool `json:"system_as_user,omitempty"`
+	// SendReasoning keeps the un-official reasoning_content and reasoning_details
+	// of the assistant messages converted for OpenAI targets, for backends taking
+	// them back such as OpenRouter. Other targets get neither.
+	SendReasoning bool `json:"send_reasoning,omitempty"`
+	// ReasoningLowBelow and ReasoningMediumBelow are the thinking budget thresholds
+	// used when mapping budgets to reasoning_effort, 1024 and 2048 when unset.
+	ReasoningLowBelow    int `json:"reasoning_low_below,omitempty"`
+	ReasoningMediumBelow int `json:"reasoning_medium_below,omitempty"`
+	// ReasoningLowBudget, ReasoningMediumBudget and ReasoningHighBudget are the
+	// thinking budgets of the reasoning_effort levels, 1024, 2048 and 4096 when
+	// unset.
+	ReasoningLowBudget    int `json:"reasoning_low_budget,omitempty"`
+	ReasoningMediumBudget int `json:"reasoning_medium_budget,omitempty"`
+	ReasoningHighBudget   int `json:"reasoning_high_budget,omitempty"`
+}
+
+var validThresholds = map[string]bool{
+	"BLOCK_NONE":                       true,
+	"BLOCK_ONLY_HIGH":                  true,
+	"BLOCK_MEDIUM_AND_ABOVE":           true,
+	"BLOCK_LOW_AND_ABOVE":              true,
+	"HARM_BLOCK_THRESHOLD_UNSPECIFIED": true,
+	"OFF":                              true,
+}
+
+// Validate reports every problem found in the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	for i, m := range c.ModelMappings {
+		if m.From == "" || m.To == "" {
+			errs = append(errs, fmt.Errorf("model_mappings[%d]: from and to are required", i))
+		}
+		if m.Provider != "" && !knownProviders[m.Provider] {
+			errs = append(errs, fmt.Errorf("model_mappings[%d]: unknown provider %q", i, m.Provider))
+		}
+	}
+	for name, s := range c.Models {
+		if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+			errs = append(errs, fmt.Errorf("models[%s]: temperature must be within [0, 2]", name))
+		}
+		if s.TopP != nil && (*s.TopP < 0 || *s.TopP > 1) {
+			errs = append(errs, fmt.Errorf("models[%s]: top_p must be within [0, 1]", name))
+		}
+		if s.MaxTokens < 0 {
+			errs = append(errs, fmt.Errorf("models[%s]: max_tokens must not be negative", name))
+		}
+		if _, ok := c.SafetyProfiles[s.SafetyProfile]; s.SafetyProfile != "" && !ok {
+			errs = append(errs, fmt.Errorf("models[%s]: undefined safety profile %q", name, s.SafetyProfile))
+		}
+		if _, ok := c.QuirkProfiles[s.QuirkProfile]; s.QuirkProfile != "" && !ok {
+			errs = append(errs, fmt.Errorf("models[%s]: undefined quirk profile %q", name, s.QuirkProfile))
+		}
+	}
+	for name, settings := range c.SafetyProfiles {
+		for i, s := range settings {
+			if s.Category == "" {
+				errs = append(errs, fmt.Errorf("safety_profiles[%s][%d]: category is required", name, i))
+			}
+			if !validThresholds[s.Threshold] {
+				errs = append(errs, fmt.Errorf("safety_profiles[%s][%d]: invalid threshold %q", name, i, s.Threshold))
+			}
+		}
+	}
+	for name, q := range c.QuirkProfiles {
+		if q.ReasoningLowBelow < 0 || q.ReasoningMediumBelow < 0 {
+			errs = append(errs, fmt.Errorf("quirk_profiles[%s]: reasoning thresholds must not be negative", name))
+		}
+		if q.ReasoningMediumBelow > 0 && q.ReasoningLowBelow > q.ReasoningMediumBelow {
+			errs = append(errs, fmt.Errorf("quirk_profiles[%s]: reasoning_low_below exceeds reasoning_medium_below", name))
+		}
+	}
+	for name, p := range c.Pricing {
+		if p.Input < 0 || p.Output < 0 || p.CacheRead < 0 || p.CacheWrite < 0 {
+			errs = append(errs, fmt.Errorf("pricing[%s]: prices must not be negative", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MapModel returns the model to send to the target provider.
+func (c *Config) MapModel(provider, model string) string {
+	if c == nil {
+		return model
+	}
+	for _, m := range c.ModelMappings {
+		if m.From == model && (m.Provider == "" || m.Provider == provider) {
+			return m.To
+		}
+	}
+	return model
+}
+
+// ModelSettings returns the settings for model. Fields the model's entry
+// leaves unset fall back to the Wildcard entry.
+func (c *Config) ModelSettings(model string) ModelSettings {
+	if c == nil {
+		return ModelSettings{}
+	}
+	s, wildcard := c.Models[model], c.Models[Wildcard]
+	if s.Temperature == nil {
+		s.Temperature = wildcard.Temperature
+	}
+	if s.TopP == nil {
+		s.TopP = wildcard.TopP
+	}
+	if s.MaxTokens == 0 {
+		s.MaxTokens = wildcard.MaxTokens
+	}
+	if s.SafetyProfile == "" {
+		s.SafetyProfile = wildcard.SafetyProfile
+	}
+	if s.QuirkProfile == "" {
+		s.QuirkProfile = wildcard.QuirkProfile
+	}
+	if s.SystemPrefix == "" {
+		s.SystemPrefix = wildcard.SystemPrefix
+	}
+	return s
+}
+
+// WithModelSettings returns a copy of c with the settings of model replaced,
+// for registering presets in code. c itself is left unchanged.
+func (c *Config) WithModelSettings(model string, s ModelSettings) (*Config, error) {
+	next := &Config{}
+	if c != nil {
+		*next = *c
+	}
+	next.Models = make(map[string]ModelSettings, len(next.Models)+1)
+	if c != nil {
+		for name, settings := range c.Models {
+			next.Models[name] = settings
+		}
+	}
+	next.Models[model] = s
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// SafetySettings returns the named safety profile and whether it exists.
+func (c *Config) SafetySettings(profile string) ([]SafetySetting, bool) {
+	if c == nil || profile == "" {
+		return nil, false
+	}
+	s, ok := c.SafetyProfiles[profile]
+	return s, ok
+}
+
+// Price returns the price of model, falling back to Wildcard.
+func (c *Config) Price(model string) (Price, bool) {
+	if c == nil {
+		return Price{}, false
+	}
+	if p, ok := c.Pricing[model]; ok {
+		return p, true
+	}
+	p, ok := c.Pricing[Wildcard]
+	return p, ok
+}
+
+// Quirks returns the quirk profile configured for model.
+func (c *Config) Quirks(model string) Quirks {
+	if c == nil {
+		return Quirks{}
+	}
+	return c.QuirkProfiles[c.ModelSettings(model).QuirkProfile]
+}
+
+// ReasoningEffort maps a thinking budget to an OpenAI reasoning_effort level.
+func (q Quirks) ReasoningEffort(budgetTokens int) string {
+	low, medium := q.ReasoningLowBelow, q.ReasoningMediumBelow
+	if low == 0 {
+		low = 1024
+	}
+	if medium == 0 {
+		medium = 2048
+	}
+	switch {
+	case budgetTokens < low:
+		return "low"
+	case budgetTokens < medium:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// ThinkingBudget maps an OpenAI reasoning_effort level to a thinking budget,
+// 0 when reasoning is off.
+func (q Quirks) ThinkingBudget(effort string) int {
+	switch effort {
+	case "low", "minimal":
+		return cmp.Or(q.ReasoningLowBudget, 1024)
+	case "medium":
+		return cmp.Or(q.ReasoningMediumBudget, 2048)
+	case "high":
+		return cmp.Or(q.ReasoningHighBudget, 4096)
+	default:
+		return 0
+	}
+}
+
+// DecodeFunc decodes a configuration document into v.
+type DecodeFunc func(data []byte, v any) error
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]DecodeFunc{".json": json.Unmarshal}
+)
+
+// RegisterDecoder registers a decoder for files with the given extension,
+// e.g. RegisterDecoder(".yaml", yaml.Unmarshal), keeping this package free of
+// third-party dependencies.
+func RegisterDecoder(ext string, fn DecodeFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(ext)] = fn
+}
+
+// Parse decodes and validates a configuration document. ext selects the
+// decoder, ".json" when empty.
+func Parse(data []byte, ext string) (*Config, error) {
+	if ext == "" {
+		ext = ".json"
+	}
+	decodersMu.RLock()
+	decode, ok := decoders[strings.ToLower(ext)]
+	decodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no config decoder registered for %q", ext)
+	}
+
+	cfg := &Config{}
+	if err := decode(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Load reads, decodes and validates the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filepath.Ext(path))
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying cfg.
+func NewContext(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the configuration carried by ctx, or nil. All Config
+// lookups are nil-safe, so callers can use the result directly.
+func FromContext(ctx context.Context) *Config {
+	cfg, _ := ctx.Value(contextKey{}).(*Config)
+	return cfg
+}
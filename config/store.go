@@ -0,0 +1,105 @@
+package config
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the active configuration of a long-running process and swaps it
+// atomically on reload. A *Config is never mutated once published, so work that
+// captured a snapshot (e.g. an in-flight stream) keeps a consistent view while
+// new work picks up the reloaded one.
+type Store struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	onReload []func(*Config, error)
+}
+
+// NewStore loads the configuration file at path.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStaticStore returns a store that always serves cfg. Reload is a no-op.
+func NewStaticStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Current returns the active configuration snapshot.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnReload registers fn to be called after every reload attempt with the new
+// configuration, or with the error that kept the previous one active.
+func (s *Store) OnReload(fn func(*Config, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReload = append(s.onReload, fn)
+}
+
+// Reload re-reads the configuration file. On failure the previous
+// configuration stays active and the error is returned.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err == nil {
+		var cfg *Config
+		if cfg, err = Load(s.path); err == nil {
+			s.current.Store(cfg)
+			s.modTime, s.size = info.ModTime(), info.Size()
+		}
+	}
+	for _, fn := range s.onReload {
+		fn(s.current.Load(), err)
+	}
+	return err
+}
+
+// Watch polls the configuration file every interval and reloads it when its
+// modification time or size changes, until ctx is done.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.changed() {
+				_ = s.Reload()
+			}
+		}
+	}
+}
+
+func (s *Store) changed() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+}
@@ -0,0 +1,344 @@
+// Package bedrock holds the payloads of the Amazon Bedrock runtime: the
+// Converse and ConverseStream APIs, and the envelope InvokeModel streams wrap
+// native model events in.
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AnthropicVersion is the anthropic_version InvokeModel requires in the body
+// of Anthropic Messages requests, which carry neither model nor stream.
+const AnthropicVersion = "bedrock-2023-05-31"
+
+type ConverseRequest struct {
+	// ModelID is the modelId path parameter of the Converse URL, kept here so
+	// a payload names its model; it is not part of the request body.
+	ModelID         string               `json:"modelId,omitempty"`
+	Messages        []Message            `json:"messages"`
+	System          []SystemContentBlock `json:"system,omitempty"`
+	InferenceConfig *InferenceConfig     `json:"inferenceConfig,omitempty"`
+	ToolConfig      *ToolConfig          `json:"toolConfig,omitempty"`
+	// AdditionalModelRequestFields holds model specific fields, such as the
+	// thinking and top_k fields of Anthropic models.
+	AdditionalModelRequestFields      map[string]any `json:"additionalModelRequestFields,omitempty"`
+	AdditionalModelResponseFieldPaths []string       `json:"additionalModelResponseFieldPaths,omitempty"`
+}
+
+type InferenceConfig struct {
+	MaxTokens     int      `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type Message struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+// ContentBlock is a union, exactly one field is set.
+type ContentBlock struct {
+	Text             *string                `json:"text,omitempty"`
+	Image            *ImageBlock            `json:"image,omitempty"`
+	Document         *DocumentBlock         `json:"document,omitempty"`
+	ToolUse          *ToolUseBlock          `json:"toolUse,omitempty"`
+	ToolResult       *ToolResultBlock       `json:"toolResult,omitempty"`
+	ReasoningContent *ReasoningContentBlock `json:"reasoningContent,omitempty"`
+	CachePoint       *CachePointBlock       `json:"cachePoint,omitempty"`
+}
+
+// SystemContentBlock is a union, exactly one field is set.
+type SystemContentBlock struct {
+	Text       *string          `json:"text,omitempty"`
+	CachePoint *CachePointBlock `json:"cachePoint,omitempty"`
+}
+
+// Cache point types
+const CachePointDefault = "default"
+
+// CachePointBlock caches the prefix of the request ending before it.
+type CachePointBlock struct {
+	Type string `json:"type"`
+}
+
+type ImageBlock struct {
+	// Format is png, jpeg, gif or webp.
+	Format string      `json:"format"`
+	Source ImageSource `json:"source"`
+}
+
+type ImageSource struct {
+	// Bytes are base64 encoded in JSON.
+	Bytes      []byte      `json:"bytes,omitempty"`
+	S3Location *S3Location `json:"s3Location,omitempty"`
+}
+
+type S3Location struct {
+	URI         string `json:"uri"`
+	BucketOwner string `json:"bucketOwner,omitempty"`
+}
+
+type DocumentBlock struct {
+	Format string      `json:"format"`
+	Name   string      `json:"name"`
+	Source ImageSource `json:"source"`
+}
+
+type ToolUseBlock struct {
+	ToolUseID string `json:"toolUseId"`
+	Name      string `json:"name"`
+	Input     any    `json:"input"`
+}
+
+// Tool result statuses
+const (
+	ToolResultSuccess = "success"
+	ToolResultError   = "error"
+)
+
+type ToolResultBlock struct {
+	ToolUseID string                   `json:"toolUseId"`
+	Content   []ToolResultContentBlock `json:"content"`
+	Status    string                   `json:"status,omitempty"`
+}
+
+// ToolResultContentBlock is a union, exactly one field is set.
+type ToolResultContentBlock struct {
+	Text  *string     `json:"text,omitempty"`
+	JSON  any         `json:"json,omitempty"`
+	Image *ImageBlock `json:"image,omitempty"`
+}
+
+// ReasoningContentBlock is a union, exactly one field is set.
+type ReasoningContentBlock struct {
+	ReasoningText   *ReasoningText `json:"reasoningText,omitempty"`
+	RedactedContent []byte         `json:"redactedContent,omitempty"`
+}
+
+type ReasoningText struct {
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type ToolConfig struct {
+	Tools      []Tool      `json:"tools"`
+	ToolChoice *ToolChoice `json:"toolChoice,omitempty"`
+}
+
+// Tool is a union, exactly one field is set.
+type Tool struct {
+	ToolSpec   *ToolSpec        `json:"toolSpec,omitempty"`
+	CachePoint *CachePointBlock `json:"cachePoint,omitempty"`
+}
+
+type ToolSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema ToolInputSchema `json:"inputSchema"`
+}
+
+type ToolInputSchema struct {
+	JSON any `json:"json"`
+}
+
+// ToolChoice is a union, exactly one field is set.
+type ToolChoice struct {
+	Auto *struct{}     `json:"auto,omitempty"`
+	Any  *struct{}     `json:"any,omitempty"`
+	Tool *SpecificTool `json:"tool,omitempty"`
+}
+
+type SpecificTool struct {
+	Name string `json:"name"`
+}
+
+// Stop reasons
+const (
+	StopReasonEndTurn             = "end_turn"
+	StopReasonToolUse             = "tool_use"
+	StopReasonMaxTokens           = "max_tokens"
+	StopReasonStopSequence        = "stop_sequence"
+	StopReasonGuardrailIntervened = "guardrail_intervened"
+	StopReasonContentFiltered     = "content_filtered"
+)
+
+type ConverseResponse struct {
+	Output                        ConverseOutput `json:"output"`
+	StopReason                    string         `json:"stopReason"`
+	Usage                         *TokenUsage    `json:"usage,omitempty"`
+	Metrics                       *Metrics       `json:"metrics,omitempty"`
+	AdditionalModelResponseFields any            `json:"additionalModelResponseFields,omitempty"`
+}
+
+type ConverseOutput struct {
+	Message *Message `json:"message,omitempty"`
+}
+
+// TokenUsage counts tokens; InputTokens excludes cache reads and writes.
+type TokenUsage struct {
+	InputTokens           int `json:"inputTokens"`
+	OutputTokens          int `json:"outputTokens"`
+	TotalTokens           int `json:"totalTokens"`
+	CacheReadInputTokens  int `json:"cacheReadInputTokens,omitempty"`
+	CacheWriteInputTokens int `json:"cacheWriteInputTokens,omitempty"`
+}
+
+type Metrics struct {
+	LatencyMs int64 `json:"latencyMs"`
+}
+
+// ConverseStreamEvent is one event of a ConverseStream response, a union
+// keyed by event type as in the JSON form of the stream; exactly one field
+// is set. On the wire each event is an event-stream message whose
+// :event-type header names the field and whose payload is its value.
+type ConverseStreamEvent struct {
+	MessageStart      *MessageStartEvent      `json:"messageStart,omitempty"`
+	ContentBlockStart *ContentBlockStartEvent `json:"contentBlockStart,omitempty"`
+	ContentBlockDelta *ContentBlockDeltaEvent `json:"contentBlockDelta,omitempty"`
+	ContentBlockStop  *ContentBlockStopEvent  `json:"contentBlockStop,omitempty"`
+	MessageStop       *MessageStopEvent       `json:"messageStop,omitempty"`
+	Metadata          *MetadataEvent          `json:"metadata,omitempty"`
+}
+
+type MessageStartEvent struct {
+	Role string `json:"role"`
+}
+
+type ContentBlockStartEvent struct {
+	ContentBlockIndex int               `json:"contentBlockIndex"`
+	Start             ContentBlockStart `json:"start"`
+}
+
+type ContentBlockStart struct {
+	ToolUse *ToolUseBlockStart `json:"toolUse,omitempty"`
+}
+
+type ToolUseBlockStart struct {
+	ToolUseID string `json:"toolUseId"`
+	Name      string `json:"name"`
+}
+
+type ContentBlockDeltaEvent struct {
+	ContentBlockIndex int               `json:"contentBlockIndex"`
+	Delta             ContentBlockDelta `json:"delta"`
+}
+
+// ContentBlockDelta is a union, exactly one field is set.
+type ContentBlockDelta struct {
+	Text             *string                `json:"text,omitempty"`
+	ToolUse          *ToolUseBlockDelta     `json:"toolUse,omitempty"`
+	ReasoningContent *ReasoningContentDelta `json:"reasoningContent,omitempty"`
+}
+
+type ToolUseBlockDelta struct {
+	// Input is a fragment of the JSON input of the tool.
+	Input string `json:"input"`
+}
+
+// ReasoningContentDelta is a union, exactly one field is set.
+type ReasoningContentDelta struct {
+	Text            *string `json:"text,omitempty"`
+	Signature       *string `json:"signature,omitempty"`
+	RedactedContent []byte  `json:"redactedContent,omitempty"`
+}
+
+type ContentBlockStopEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+}
+
+type MessageStopEvent struct {
+	StopReason                    string `json:"stopReason"`
+	AdditionalModelResponseFields any    `json:"additionalModelResponseFields,omitempty"`
+}
+
+type MetadataEvent struct {
+	Usage   *TokenUsage `json:"usage,omitempty"`
+	Metrics *Metrics    `json:"metrics,omitempty"`
+}
+
+// EventType returns the event type of e, the name of its set field.
+func (e *ConverseStreamEvent) EventType() string {
+	switch {
+	case e.MessageStart != nil:
+		return "messageStart"
+	case e.ContentBlockStart != nil:
+		return "contentBlockStart"
+	case e.ContentBlockDelta != nil:
+		return "contentBlockDelta"
+	case e.ContentBlockStop != nil:
+		return "contentBlockStop"
+	case e.MessageStop != nil:
+		return "messageStop"
+	case e.Metadata != nil:
+		return "metadata"
+	}
+	return ""
+}
+
+// Payload returns the value of the set field, the payload of the
+// event-stream message carrying e.
+func (e *ConverseStreamEvent) Payload() any {
+	switch {
+	case e.MessageStart != nil:
+		return e.MessageStart
+	case e.ContentBlockStart != nil:
+		return e.ContentBlockStart
+	case e.ContentBlockDelta != nil:
+		return e.ContentBlockDelta
+	case e.ContentBlockStop != nil:
+		return e.ContentBlockStop
+	case e.MessageStop != nil:
+		return e.MessageStop
+	case e.Metadata != nil:
+		return e.Metadata
+	}
+	return nil
+}
+
+// ParseConverseStreamEvent decodes the payload of an event-stream message
+// of the given event type.
+func ParseConverseStreamEvent(eventType string, payload []byte) (*ConverseStreamEvent, error) {
+	e := &ConverseStreamEvent{}
+	var v any
+	switch eventType {
+	case "messageStart":
+		e.MessageStart = &MessageStartEvent{}
+		v = e.MessageStart
+	case "contentBlockStart":
+		e.ContentBlockStart = &ContentBlockStartEvent{}
+		v = e.ContentBlockStart
+	case "contentBlockDelta":
+		e.ContentBlockDelta = &ContentBlockDeltaEvent{}
+		v = e.ContentBlockDelta
+	case "contentBlockStop":
+		e.ContentBlockStop = &ContentBlockStopEvent{}
+		v = e.ContentBlockStop
+	case "messageStop":
+		e.MessageStop = &MessageStopEvent{}
+		v = e.MessageStop
+	case "metadata":
+		e.Metadata = &MetadataEvent{}
+		v = e.Metadata
+	default:
+		return nil, fmt.Errorf("unknown converse stream event type %q", eventType)
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return nil, fmt.Errorf("invalid %s event: %w", eventType, err)
+	}
+	return e, nil
+}
+
+// PayloadPart is the payload of the chunk events of InvokeModelWithResponseStream:
+// Bytes holds one event of the model's native stream, e.g. a Claude stream
+// event for Anthropic models.
+type PayloadPart struct {
+	Bytes []byte `json:"bytes"`
+}
+
+// Exception is the payload of an exception message of a stream, whose
+// :exception-type header names the error, e.g. throttlingException.
+type Exception struct {
+	Message string `json:"message"`
+}
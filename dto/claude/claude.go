@@ -0,0 +1,607 @@
+package claude
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/phosae/llms/common"
+)
+
+// APIVersion is the anthropic-version of the Messages API.
+const APIVersion = "2023-06-01"
+
+type ClaudeMetadata struct {
+	UserId string `json:"user_id"`
+}
+
+type ClaudeMediaMessage struct {
+	Type         string               `json:"type,omitempty"`
+	Text         *string              `json:"text,omitempty"`
+	Model        string               `json:"model,omitempty"`
+	Source       *ClaudeMessageSource `json:"source,omitempty"`
+	Usage        *ClaudeUsage         `json:"usage,omitempty"`
+	StopReason   *string              `json:"stop_reason,omitempty"`
+	StopSequence *string              `json:"stop_sequence,omitempty"`
+	PartialJson  *string              `json:"partial_json,omitempty"`
+	Role         string               `json:"role,omitempty"`
+	Thinking     string               `json:"thinking,omitempty"`
+	Signature    string               `json:"signature,omitempty"`
+	Data         string               `json:"data,omitempty"` // redacted_thinking, opaque encrypted thinking
+	Delta        string               `json:"delta,omitempty"`
+	CacheControl *common.CacheControl `json:"cache_control,omitempty"`
+	// Title names a document.
+	Title string `json:"title,omitempty"`
+	// Citations are the []Citation of a text block, or the citations
+	// setting of a document, {"enabled": true}.
+	Citations json.RawMessage `json:"citations,omitempty"`
+	// tool_calls
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Input     any    `json:"input,omitempty"`
+	Content   any    `json:"content,omitempty"`
+	ToolUseId string `json:"tool_use_id,omitempty"`
+	IsError   *bool  `json:"is_error,omitempty"` // tool_result
+}
+
+// Server tool blocks: the call of a tool Anthropic runs and its result,
+// whose content is one of the result types below or a ServerToolError.
+const (
+	ContentTypeServerToolUse           = "server_tool_use"
+	ContentTypeWebSearchToolResult     = "web_search_tool_result"
+	ContentTypeCodeExecutionToolResult = "code_execution_tool_result"
+)
+
+// WebSearchResult is one element of the content of a web_search_tool_result.
+type WebSearchResult struct {
+	Type             string `json:"type"`
+	URL              string `json:"url"`
+	Title            string `json:"title"`
+	EncryptedContent string `json:"encrypted_content,omitempty"`
+	PageAge          string `json:"page_age,omitempty"`
+}
+
+// CodeExecutionResult is the content of a code_execution_tool_result.
+type CodeExecutionResult struct {
+	Type       string `json:"type"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	ReturnCode int    `json:"return_code"`
+	// Content lists the files the code created.
+	Content []any `json:"content,omitempty"`
+}
+
+// ServerToolError is the content of a server tool result when the tool
+// failed, with Type web_search_tool_result_error or
+// code_execution_tool_result_error.
+type ServerToolError struct {
+	Type      string `json:"type"`
+	ErrorCode string `json:"error_code"`
+}
+
+// ServerToolResult decodes the content of a server tool result block into
+// dst, a *[]WebSearchResult or a *CodeExecutionResult, unless the content
+// reports a failure, which is returned instead.
+func (c *ClaudeMediaMessage) ServerToolResult(dst any) (*ServerToolError, error) {
+	data, err := json.Marshal(c.Content)
+	if err != nil {
+		return nil, err
+	}
+	var failure ServerToolError
+	if json.Unmarshal(data, &failure) == nil && failure.ErrorCode != "" {
+		return &failure, nil
+	}
+	return nil, json.Unmarshal(data, dst)
+}
+
+func (c *ClaudeMediaMessage) GetIsError() bool {
+	return c.IsError != nil && *c.IsError
+}
+
+func (c *ClaudeMediaMessage) SetText(s string) {
+	c.Text = &s
+}
+
+func (c *ClaudeMediaMessage) GetText() string {
+	if c.Text == nil {
+		return ""
+	}
+	return *c.Text
+}
+
+// GetCitations returns the citations of a text block.
+func (c *ClaudeMediaMessage) GetCitations() ([]Citation, error) {
+	if len(c.Citations) == 0 || string(c.Citations) == "null" {
+		return nil, nil
+	}
+	var citations []Citation
+	err := json.Unmarshal(c.Citations, &citations)
+	return citations, err
+}
+
+// SetCitations sets the citations of a text block.
+func (c *ClaudeMediaMessage) SetCitations(citations []Citation) {
+	c.Citations, _ = json.Marshal(citations)
+}
+
+func (c *ClaudeMediaMessage) IsStringContent() bool {
+	if c.Content == nil {
+		return false
+	}
+	_, ok := c.Content.(string)
+	if ok {
+		return true
+	}
+	return false
+}
+
+func (c *ClaudeMediaMessage) GetStringContent() string {
+	if c.Content == nil {
+		return ""
+	}
+	switch c.Content.(type) {
+	case string:
+		return c.Content.(string)
+	case []any:
+		var contentStr string
+		for _, contentItem := range c.Content.([]any) {
+			contentMap, ok := contentItem.(map[string]any)
+			if !ok {
+				continue
+			}
+			if contentMap["type"] == "text" {
+				if subStr, ok := contentMap["text"].(string); ok {
+					contentStr += subStr
+				}
+			}
+		}
+		return contentStr
+	}
+
+	return ""
+}
+
+func (c *ClaudeMediaMessage) GetJsonRowString() string {
+	jsonContent, _ := json.Marshal(c)
+	return string(jsonContent)
+}
+
+func (c *ClaudeMediaMessage) SetContent(content any) {
+	c.Content = content
+}
+
+func (c *ClaudeMediaMessage) ParseMediaContent() []ClaudeMediaMessage {
+	mediaContent, _ := common.Any2Type[[]ClaudeMediaMessage](c.Content)
+	return mediaContent
+}
+
+type ClaudeMessageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      any    `json:"data,omitempty"`
+	Url       string `json:"url,omitempty"`
+	// FileId names a file uploaded to the Files API, for sources of type file.
+	FileId string `json:"file_id,omitempty"`
+	// Content holds the blocks of a document source of type content.
+	Content []ClaudeMediaMessage `json:"content,omitempty"`
+}
+
+// Citation types
+const (
+	CitationCharLocation            = "char_location"
+	CitationPageLocation            = "page_location"
+	CitationContentBlockLocation    = "content_block_location"
+	CitationWebSearchResultLocation = "web_search_result_location"
+	CitationSearchResultLocation    = "search_result_location"
+)
+
+// Citation ties a text block to the source it draws on: a range of a
+// document of the request, in characters, pages or content blocks, or a web
+// search result.
+type Citation struct {
+	Type      string `json:"type"`
+	CitedText string `json:"cited_text"`
+
+	DocumentIndex   *int   `json:"document_index,omitempty"`
+	DocumentTitle   string `json:"document_title,omitempty"`
+	StartCharIndex  *int   `json:"start_char_index,omitempty"`
+	EndCharIndex    *int   `json:"end_char_index,omitempty"`
+	StartPageNumber *int   `json:"start_page_number,omitempty"`
+	EndPageNumber   *int   `json:"end_page_number,omitempty"`
+	StartBlockIndex *int   `json:"start_block_index,omitempty"`
+	EndBlockIndex   *int   `json:"end_block_index,omitempty"`
+
+	URL            string `json:"url,omitempty"`
+	Title          string `json:"title,omitempty"`
+	EncryptedIndex string `json:"encrypted_index,omitempty"`
+}
+
+type ClaudeMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+func (c *ClaudeMessage) IsStringContent() bool {
+	if c.Content == nil {
+		return false
+	}
+	_, ok := c.Content.(string)
+	return ok
+}
+
+func (c *ClaudeMessage) GetStringContent() string {
+	if c.Content == nil {
+		return ""
+	}
+	switch c.Content.(type) {
+	case string:
+		return c.Content.(string)
+	case []any:
+		var contentStr string
+		for _, contentItem := range c.Content.([]any) {
+			contentMap, ok := contentItem.(map[string]any)
+			if !ok {
+				continue
+			}
+			if contentMap["type"] == "text" {
+				if subStr, ok := contentMap["text"].(string); ok {
+					contentStr += subStr
+				}
+			}
+		}
+		return contentStr
+	}
+
+	return ""
+}
+
+func (c *ClaudeMessage) SetStringContent(content string) {
+	c.Content = content
+}
+
+func (c *ClaudeMessage) ParseContent() ([]ClaudeMediaMessage, error) {
+	return common.Any2Type[[]ClaudeMediaMessage](c.Content)
+}
+
+type Tool struct {
+	// Type is empty or "custom" for client tools, versioned for server tools (e.g. WebSearchToolType).
+	Type         string                 `json:"type,omitempty"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"input_schema"`
+	CacheControl *common.CacheControl   `json:"cache_control,omitempty"`
+	// Strict guarantees inputs matching InputSchema, with StructuredOutputsBeta.
+	Strict bool `json:"strict,omitempty"`
+}
+
+const (
+	WebSearchToolType = "web_search_20250305"
+	WebSearchToolName = "web_search"
+)
+
+// CodeExecutionTool is the server-side code execution tool, with
+// CodeExecutionBeta.
+type CodeExecutionTool struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+const (
+	CodeExecutionToolType = "code_execution_20250522"
+	CodeExecutionToolName = "code_execution"
+)
+
+type InputSchema struct {
+	Type       string `json:"type"`
+	Properties any    `json:"properties,omitempty"`
+	Required   any    `json:"required,omitempty"`
+}
+
+// ClaudeWebSearchTool is the server-side web search tool, of type
+// WebSearchToolType.
+type ClaudeWebSearchTool struct {
+	Type         string                       `json:"type"`
+	Name         string                       `json:"name"`
+	MaxUses      int                          `json:"max_uses,omitempty"`
+	UserLocation *ClaudeWebSearchUserLocation `json:"user_location,omitempty"`
+	// AllowedDomains and BlockedDomains are exclusive.
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+}
+
+type ClaudeWebSearchUserLocation struct {
+	Type     string `json:"type"`
+	Timezone string `json:"timezone,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Region   string `json:"region,omitempty"`
+	City     string `json:"city,omitempty"`
+}
+
+type ClaudeToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type ClaudeRequest struct {
+	Model             string          `json:"model"`
+	Prompt            string          `json:"prompt,omitempty"`
+	System            any             `json:"system,omitempty"`
+	Messages          []ClaudeMessage `json:"messages,omitempty"`
+	MaxTokens         uint            `json:"max_tokens,omitempty"`
+	MaxTokensToSample uint            `json:"max_tokens_to_sample,omitempty"` // legacy complete endpoint
+	StopSequences     []string        `json:"stop_sequences,omitempty"`
+	Temperature       *float64        `json:"temperature,omitempty"`
+	TopP              *float64        `json:"top_p,omitempty"`
+	TopK              *int            `json:"top_k,omitempty"`
+	Stream            bool            `json:"stream,omitempty"`
+	Tools             any             `json:"tools,omitempty"`
+	ToolChoice        any             `json:"tool_choice,omitempty"`
+	Thinking          *Thinking       `json:"thinking,omitempty"`
+	Metadata          *ClaudeMetadata `json:"metadata,omitempty"`
+	// ServiceTier is "auto" or "standard_only".
+	ServiceTier string `json:"service_tier,omitempty"`
+	// Container is the id of a code execution container to reuse, or a container object on newer betas.
+	Container any `json:"container,omitempty"`
+	// MCPServers, ContextManagement and OutputFormat require anthropic-beta headers.
+	MCPServers        []ClaudeMCPServer `json:"mcp_servers,omitempty"`
+	ContextManagement json.RawMessage   `json:"context_management,omitempty"`
+	OutputFormat      json.RawMessage   `json:"output_format,omitempty"`
+}
+
+type ClaudeMCPServer struct {
+	Type               string                      `json:"type"`
+	URL                string                      `json:"url"`
+	Name               string                      `json:"name"`
+	AuthorizationToken string                      `json:"authorization_token,omitempty"`
+	ToolConfiguration  *ClaudeMCPToolConfiguration `json:"tool_configuration,omitempty"`
+}
+
+type ClaudeMCPToolConfiguration struct {
+	Enabled      *bool    `json:"enabled,omitempty"`
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+}
+
+// AddTool 添加工具到请求中
+func (c *ClaudeRequest) AddTool(tool any) {
+	if c.Tools == nil {
+		c.Tools = make([]any, 0)
+	}
+
+	switch tools := c.Tools.(type) {
+	case []any:
+		c.Tools = append(tools, tool)
+	default:
+		// 如果Tools不是[]any类型，重新初始化为[]any
+		c.Tools = []any{tool}
+	}
+}
+
+// GetTools 获取工具列表
+func (c *ClaudeRequest) GetTools() []any {
+	if c.Tools == nil {
+		return nil
+	}
+
+	switch tools := c.Tools.(type) {
+	case []any:
+		return tools
+	default:
+		return nil
+	}
+}
+
+// Betas returns the anthropic-beta features the request needs: structured
+// outputs for strict tools or an output_format, code execution for its tool.
+func (c *ClaudeRequest) Betas() []string {
+	tools := c.GetTools()
+	var betas []string
+	if len(c.OutputFormat) > 0 || slices.ContainsFunc(tools, func(tool any) bool {
+		custom, ok := tool.(Tool)
+		return ok && custom.Strict
+	}) {
+		betas = append(betas, StructuredOutputsBeta)
+	}
+	if slices.ContainsFunc(tools, func(tool any) bool {
+		_, ok := tool.(CodeExecutionTool)
+		return ok
+	}) {
+		betas = append(betas, CodeExecutionBeta)
+	}
+	return betas
+}
+
+// ProcessTools 处理工具列表，支持类型断言
+func ProcessTools(tools []any) ([]*Tool, []*ClaudeWebSearchTool) {
+	var normalTools []*Tool
+	var webSearchTools []*ClaudeWebSearchTool
+
+	for _, tool := range tools {
+		switch t := tool.(type) {
+		case *Tool:
+			normalTools = append(normalTools, t)
+		case *ClaudeWebSearchTool:
+			webSearchTools = append(webSearchTools, t)
+		case Tool:
+			normalTools = append(normalTools, &t)
+		case ClaudeWebSearchTool:
+			webSearchTools = append(webSearchTools, &t)
+		default:
+			// 未知类型，跳过
+			continue
+		}
+	}
+
+	return normalTools, webSearchTools
+}
+
+type Thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens *int   `json:"budget_tokens,omitempty"`
+}
+
+func (c *Thinking) GetBudgetTokens() int {
+	if c.BudgetTokens == nil {
+		return 0
+	}
+	return *c.BudgetTokens
+}
+
+func (c *ClaudeRequest) IsStringSystem() bool {
+	_, ok := c.System.(string)
+	return ok
+}
+
+func (c *ClaudeRequest) GetStringSystem() string {
+	if c.IsStringSystem() {
+		return c.System.(string)
+	}
+	return ""
+}
+
+func (c *ClaudeRequest) SetStringSystem(system string) {
+	c.System = system
+}
+
+func (c *ClaudeRequest) ParseSystem() []ClaudeMediaMessage {
+	mediaContent, _ := common.Any2Type[[]ClaudeMediaMessage](c.System)
+	return mediaContent
+}
+
+type ClaudeError struct {
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type ClaudeErrorWithStatusCode struct {
+	Error      ClaudeError `json:"error"`
+	StatusCode int         `json:"status_code"`
+	LocalError bool
+}
+
+type ClaudeResponse struct {
+	Id           string               `json:"id,omitempty"`
+	Type         string               `json:"type"`
+	Role         string               `json:"role,omitempty"`
+	Content      []ClaudeMediaMessage `json:"content,omitempty"`
+	Completion   string               `json:"completion,omitempty"`
+	StopReason   string               `json:"stop_reason,omitempty"`
+	StopSequence string               `json:"stop_sequence,omitempty"`
+	Stop         string               `json:"stop,omitempty"` // matched stop sequence of legacy completions
+	Model        string               `json:"model,omitempty"`
+	Error        *ClaudeError         `json:"error,omitempty"`
+	Usage        *ClaudeUsage         `json:"usage,omitempty"`
+	Index        *int                 `json:"index,omitempty"`
+	ContentBlock *ClaudeMediaMessage  `json:"content_block,omitempty"`
+	Delta        *ClaudeMediaMessage  `json:"delta,omitempty"`
+	Message      *ClaudeMediaMessage  `json:"message,omitempty"`
+	// Container is the code execution container of the response.
+	Container *ClaudeContainer `json:"container,omitempty"`
+}
+
+type ClaudeContainer struct {
+	ID string `json:"id"`
+	// ExpiresAt is an RFC 3339 timestamp.
+	ExpiresAt string `json:"expires_at"`
+}
+
+// set index
+func (c *ClaudeResponse) SetIndex(i int) {
+	c.Index = &i
+}
+
+// get index
+func (c *ClaudeResponse) GetIndex() int {
+	if c.Index == nil {
+		return 0
+	}
+	return *c.Index
+}
+
+type ClaudeUsage struct {
+	InputTokens              int                  `json:"input_tokens"`
+	CacheCreationInputTokens int                  `json:"cache_creation_input_tokens"`
+	CacheCreationDetails     *ClaudeCacheCreation `json:"cache_creation,omitempty"` // anthropic-beta: extended-cache-ttl-2025-04-11, cache_creation_input_tokens = cache_creation.ephemeral_5m_input_tokens + cache_creation.ephemeral_1h_input_tokens
+	CacheReadInputTokens     int                  `json:"cache_read_input_tokens"`
+	OutputTokens             int                  `json:"output_tokens"`
+	ServerToolUse            *ClaudeServerToolUse `json:"server_tool_use,omitempty"`
+}
+
+// ClaudeCacheCreation splits the cache writes of a request by their TTL.
+type ClaudeCacheCreation struct {
+	Ephemeral5mInputTokens int `json:"ephemeral_5m_input_tokens"`
+	Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+}
+
+type ClaudeServerToolUse struct {
+	WebSearchRequests int `json:"web_search_requests"`
+}
+
+// MessageBatchResult is one line of the results file of a Message Batch.
+type MessageBatchResult struct {
+	CustomID string                 `json:"custom_id"`
+	Result   MessageBatchResultBody `json:"result"`
+}
+
+// Message Batch result types
+const (
+	MessageBatchSucceeded = "succeeded"
+	MessageBatchErrored   = "errored"
+	MessageBatchCanceled  = "canceled"
+	MessageBatchExpired   = "expired"
+)
+
+type MessageBatchResultBody struct {
+	Type string `json:"type"`
+	// Message is set for succeeded results.
+	Message *ClaudeResponse `json:"message,omitempty"`
+	// Error is set for errored results.
+	Error *MessageBatchError `json:"error,omitempty"`
+}
+
+type MessageBatchError struct {
+	Type  string      `json:"type"`
+	Error ClaudeError `json:"error"`
+}
+
+// PromptToolsBeta is the anthropic-beta of the experimental prompt tools.
+const PromptToolsBeta = "prompt-tools-2025-04-02"
+
+// StructuredOutputsBeta is the anthropic-beta of strict tools and
+// output_format.
+const StructuredOutputsBeta = "structured-outputs-2025-11-13"
+
+// CodeExecutionBeta is the anthropic-beta of CodeExecutionTool.
+const CodeExecutionBeta = "code-execution-2025-05-22"
+
+// GeneratePromptRequest is the body of /v1/experimental/generate_prompt.
+type GeneratePromptRequest struct {
+	// Task describes what the prompt is for, e.g. "a chef for meal prep".
+	Task        string `json:"task"`
+	TargetModel string `json:"target_model,omitempty"`
+}
+
+// ImprovePromptRequest is the body of /v1/experimental/improve_prompt.
+type ImprovePromptRequest struct {
+	Messages []ClaudeMessage `json:"messages"`
+	System   string          `json:"system,omitempty"`
+	// Feedback says what to improve, e.g. "make it more detailed".
+	Feedback    string `json:"feedback,omitempty"`
+	TargetModel string `json:"target_model,omitempty"`
+}
+
+// TemplatizePromptRequest is the body of /v1/experimental/templatize_prompt.
+type TemplatizePromptRequest struct {
+	Messages []ClaudeMessage `json:"messages"`
+	System   string          `json:"system,omitempty"`
+}
+
+// PromptToolsResponse is the response of the prompt tools: a prompt whose
+// texts may hold {{VARIABLE}} placeholders.
+type PromptToolsResponse struct {
+	Messages []ClaudeMessage `json:"messages"`
+	System   string          `json:"system"`
+	Usage    []ClaudeUsage   `json:"usage,omitempty"`
+	// VariableValues are the values templatize_prompt extracted for the
+	// placeholders.
+	VariableValues map[string]string `json:"variable_values,omitempty"`
+}
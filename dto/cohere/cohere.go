@@ -0,0 +1,50 @@
+// Package cohere holds the payloads of the Cohere /v2/embed API.
+package cohere
+
+// Input types of embed requests
+const (
+	InputTypeSearchDocument = "search_document"
+	InputTypeSearchQuery    = "search_query"
+	InputTypeClassification = "classification"
+	InputTypeClustering     = "clustering"
+)
+
+type EmbedRequest struct {
+	Model string   `json:"model"`
+	Texts []string `json:"texts"`
+	// InputType is required by v3 and later models.
+	InputType string `json:"input_type,omitempty"`
+	// EmbeddingTypes are float, int8, uint8, binary or ubinary, float when
+	// omitted.
+	EmbeddingTypes []string `json:"embedding_types,omitempty"`
+	// Truncate is NONE, START or END.
+	Truncate        string `json:"truncate,omitempty"`
+	OutputDimension int    `json:"output_dimension,omitempty"`
+}
+
+type EmbedResponse struct {
+	ID string `json:"id"`
+	// Embeddings hold the embeddings of the texts by embedding type.
+	Embeddings EmbedByType `json:"embeddings"`
+	Texts      []string    `json:"texts,omitempty"`
+	Meta       *Meta       `json:"meta,omitempty"`
+}
+
+type EmbedByType struct {
+	Float [][]float64 `json:"float,omitempty"`
+	Int8  [][]int     `json:"int8,omitempty"`
+	Uint8 [][]int     `json:"uint8,omitempty"`
+}
+
+type Meta struct {
+	BilledUnits *BilledUnits `json:"billed_units,omitempty"`
+}
+
+type BilledUnits struct {
+	InputTokens int `json:"input_tokens,omitempty"`
+}
+
+// ErrorResponse is the body of failed requests.
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,65 @@
+package gemini
+
+// GenerateAnswer (models/aqa) answers questions grounded on passages given
+// inline or retrieved from a semantic retriever corpus.
+
+// Answer styles
+const (
+	AnswerStyleAbstractive = "ABSTRACTIVE"
+	AnswerStyleExtractive  = "EXTRACTIVE"
+	AnswerStyleVerbose     = "VERBOSE"
+)
+
+type GenerateAnswerRequest struct {
+	Contents          []GeminiChatContent        `json:"contents"`
+	AnswerStyle       string                     `json:"answerStyle"`
+	InlinePassages    *GroundingPassages         `json:"inlinePassages,omitempty"`
+	SemanticRetriever *SemanticRetrieverConfig   `json:"semanticRetriever,omitempty"`
+	SafetySettings    []GeminiChatSafetySettings `json:"safetySettings,omitempty"`
+	Temperature       *float64                   `json:"temperature,omitempty"`
+}
+
+type GroundingPassages struct {
+	Passages []GroundingPassage `json:"passages"`
+}
+
+type GroundingPassage struct {
+	ID      string            `json:"id"`
+	Content GeminiChatContent `json:"content"`
+}
+
+type SemanticRetrieverConfig struct {
+	// Source is a corpus or document name, e.g. corpora/123.
+	Source                string             `json:"source"`
+	Query                 *GeminiChatContent `json:"query"`
+	MetadataFilters       any                `json:"metadataFilters,omitempty"`
+	MaxChunksCount        int                `json:"maxChunksCount,omitempty"`
+	MinimumRelevanceScore *float64           `json:"minimumRelevanceScore,omitempty"`
+}
+
+type GenerateAnswerResponse struct {
+	Answer                *GeminiChatCandidate      `json:"answer,omitempty"`
+	AnswerableProbability *float64                  `json:"answerableProbability,omitempty"`
+	InputFeedback         *GeminiChatPromptFeedback `json:"inputFeedback,omitempty"`
+}
+
+type GroundingAttribution struct {
+	SourceID AttributionSourceID `json:"sourceId"`
+	Content  GeminiChatContent   `json:"content"`
+}
+
+// AttributionSourceID identifies an inline passage or a retrieved chunk.
+type AttributionSourceID struct {
+	GroundingPassage       *GroundingPassageID       `json:"groundingPassage,omitempty"`
+	SemanticRetrieverChunk *SemanticRetrieverChunkID `json:"semanticRetrieverChunk,omitempty"`
+}
+
+type GroundingPassageID struct {
+	PassageID string `json:"passageId"`
+	PartIndex int    `json:"partIndex"`
+}
+
+type SemanticRetrieverChunkID struct {
+	Source string `json:"source"`
+	Chunk  string `json:"chunk"`
+}
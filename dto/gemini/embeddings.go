@@ -0,0 +1,39 @@
+package gemini
+
+// Task types of embedding requests
+const (
+	TaskTypeRetrievalQuery     = "RETRIEVAL_QUERY"
+	TaskTypeRetrievalDocument  = "RETRIEVAL_DOCUMENT"
+	TaskTypeSemanticSimilarity = "SEMANTIC_SIMILARITY"
+	TaskTypeClassification     = "CLASSIFICATION"
+	TaskTypeClustering         = "CLUSTERING"
+)
+
+// EmbedContentRequest is the body of models/{model}:embedContent, and one
+// request of a batch.
+type EmbedContentRequest struct {
+	// Model is models/{model}; it is required in batches only.
+	Model    string            `json:"model,omitempty"`
+	Content  GeminiChatContent `json:"content"`
+	TaskType string            `json:"taskType,omitempty"`
+	// Title is the title of a RETRIEVAL_DOCUMENT.
+	Title                string `json:"title,omitempty"`
+	OutputDimensionality int    `json:"outputDimensionality,omitempty"`
+}
+
+type EmbedContentResponse struct {
+	Embedding ContentEmbedding `json:"embedding"`
+}
+
+// BatchEmbedContentsRequest is the body of models/{model}:batchEmbedContents.
+type BatchEmbedContentsRequest struct {
+	Requests []EmbedContentRequest `json:"requests"`
+}
+
+type BatchEmbedContentsResponse struct {
+	Embeddings []ContentEmbedding `json:"embeddings"`
+}
+
+type ContentEmbedding struct {
+	Values []float64 `json:"values"`
+}
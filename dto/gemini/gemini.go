@@ -0,0 +1,263 @@
+package gemini
+
+import (
+	"encoding/json"
+)
+
+type GeminiChatRequest struct {
+	Contents           []GeminiChatContent        `json:"contents"`
+	SafetySettings     []GeminiChatSafetySettings `json:"safetySettings,omitempty"`
+	GenerationConfig   GeminiChatGenerationConfig `json:"generationConfig,omitempty"`
+	Tools              []GeminiChatTool           `json:"tools,omitempty"`
+	SystemInstructions *GeminiChatContent         `json:"systemInstruction,omitempty"`
+	ToolConfig         *GeminiToolConfig          `json:"toolConfig,omitempty"`
+	// CachedContent names the cachedContents/{id} resource holding the
+	// prefix of the prompt.
+	CachedContent string `json:"cachedContent,omitempty"`
+}
+
+// CountTokensRequest is the body of models/{model}:countTokens: contents
+// alone, or a whole generateContent request, counted with its system
+// instruction and tools.
+type CountTokensRequest struct {
+	Contents               []GeminiChatContent     `json:"contents,omitempty"`
+	GenerateContentRequest *GenerateContentRequest `json:"generateContentRequest,omitempty"`
+}
+
+// GenerateContentRequest is a generateContent request naming its model,
+// models/{model}, as countTokens takes it.
+type GenerateContentRequest struct {
+	Model string `json:"model"`
+	GeminiChatRequest
+}
+
+// CountTokensResponse is the response of countTokens.
+type CountTokensResponse struct {
+	TotalTokens             int                         `json:"totalTokens"`
+	CachedContentTokenCount int                         `json:"cachedContentTokenCount,omitempty"`
+	PromptTokensDetails     []GeminiPromptTokensDetails `json:"promptTokensDetails,omitempty"`
+}
+
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+// Function calling modes
+const (
+	FunctionCallingAuto      = "AUTO"
+	FunctionCallingAny       = "ANY"
+	FunctionCallingNone      = "NONE"
+	FunctionCallingValidated = "VALIDATED"
+)
+
+type GeminiFunctionCallingConfig struct {
+	Mode string `json:"mode,omitempty"`
+	// AllowedFunctionNames restricts the callable functions, only with mode ANY or VALIDATED.
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type GeminiChatGenerationConfig struct {
+	Temperature        *float64              `json:"temperature,omitempty"`
+	TopP               *float64              `json:"topP,omitempty"`
+	TopK               *float64              `json:"topK,omitempty"`
+	PresencePenalty    *float64              `json:"presencePenalty,omitempty"`
+	FrequencyPenalty   *float64              `json:"frequencyPenalty,omitempty"`
+	MaxOutputTokens    uint                  `json:"maxOutputTokens,omitempty"`
+	CandidateCount     int                   `json:"candidateCount,omitempty"`
+	StopSequences      []string              `json:"stopSequences,omitempty"`
+	ResponseMimeType   string                `json:"responseMimeType,omitempty"`
+	ResponseSchema     any                   `json:"responseSchema,omitempty"`
+	Seed               *int64                `json:"seed,omitempty"`
+	ResponseModalities []string              `json:"responseModalities,omitempty"`
+	ThinkingConfig     *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
+	SpeechConfig       json.RawMessage       `json:"speechConfig,omitempty"`
+	// MediaResolution is one of the MediaResolution* levels, applied to all media of the request.
+	MediaResolution string `json:"mediaResolution,omitempty"`
+}
+
+// Media resolution levels
+const (
+	MediaResolutionLow    = "MEDIA_RESOLUTION_LOW"
+	MediaResolutionMedium = "MEDIA_RESOLUTION_MEDIUM"
+	MediaResolutionHigh   = "MEDIA_RESOLUTION_HIGH"
+)
+
+type GeminiThinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+}
+
+type GeminiChatTool struct {
+	GoogleSearch          any `json:"googleSearch,omitempty"`
+	GoogleSearchRetrieval any `json:"googleSearchRetrieval,omitempty"`
+	CodeExecution         any `json:"codeExecution,omitempty"`
+	FunctionDeclarations  any `json:"functionDeclarations,omitempty"`
+}
+
+type GeminiChatContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+type GeminiPart struct {
+	Text                string                         `json:"text,omitempty"`
+	Thought             bool                           `json:"thought,omitempty"`
+	InlineData          *GeminiInlineData              `json:"inlineData,omitempty"`
+	FunctionCall        *FunctionCall                  `json:"functionCall,omitempty"`
+	FunctionResponse    *FunctionResponse              `json:"functionResponse,omitempty"`
+	FileData            *GeminiFileData                `json:"fileData,omitempty"`
+	ExecutableCode      *GeminiPartExecutableCode      `json:"executableCode,omitempty"`
+	CodeExecutionResult *GeminiPartCodeExecutionResult `json:"codeExecutionResult,omitempty"`
+	// ThoughtSignature is an opaque signature of the model's thinking, it must be sent back
+	// unchanged with the part in follow-up requests for multi-turn function calling.
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type FunctionCall struct {
+	FunctionName string `json:"name"`
+	Arguments    any    `json:"args"`
+}
+
+type FunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type GeminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileUri  string `json:"fileUri,omitempty"`
+}
+
+type GeminiPartExecutableCode struct {
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+type GeminiPartCodeExecutionResult struct {
+	Outcome string `json:"outcome,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+type GeminiChatSafetySettings struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type GeminiChatCandidate struct {
+	Content       GeminiChatContent        `json:"content"`
+	FinishReason  *string                  `json:"finishReason"`
+	Index         int64                    `json:"index"`
+	SafetyRatings []GeminiChatSafetyRating `json:"safetyRatings"`
+	// GroundingMetadata holds a GroundingMetadata as it came.
+	GroundingMetadata json.RawMessage `json:"groundingMetadata,omitempty"`
+	// GroundingAttributions are set on GenerateAnswer answers.
+	GroundingAttributions []GroundingAttribution `json:"groundingAttributions,omitempty"`
+}
+
+// GroundingMetadata tells the sources of a candidate grounded with Google
+// Search: the web pages found, and the spans of the answer each supports.
+type GroundingMetadata struct {
+	WebSearchQueries  []string           `json:"webSearchQueries,omitempty"`
+	SearchEntryPoint  *SearchEntryPoint  `json:"searchEntryPoint,omitempty"`
+	GroundingChunks   []GroundingChunk   `json:"groundingChunks,omitempty"`
+	GroundingSupports []GroundingSupport `json:"groundingSupports,omitempty"`
+}
+
+// SearchEntryPoint is the Google Search suggestion to display with a
+// grounded answer.
+type SearchEntryPoint struct {
+	RenderedContent string `json:"renderedContent,omitempty"`
+}
+
+type GroundingChunk struct {
+	Web *GroundingChunkWeb `json:"web,omitempty"`
+}
+
+type GroundingChunkWeb struct {
+	URI   string `json:"uri"`
+	Title string `json:"title,omitempty"`
+}
+
+// GroundingSupport ties a segment of the answer to the chunks supporting
+// it.
+type GroundingSupport struct {
+	Segment               GroundingSegment `json:"segment"`
+	GroundingChunkIndices []int            `json:"groundingChunkIndices"`
+	ConfidenceScores      []float64        `json:"confidenceScores,omitempty"`
+}
+
+// GroundingSegment is the text of a part from StartIndex to EndIndex, in
+// bytes.
+type GroundingSegment struct {
+	PartIndex  int    `json:"partIndex,omitempty"`
+	StartIndex int    `json:"startIndex,omitempty"`
+	EndIndex   int    `json:"endIndex"`
+	Text       string `json:"text,omitempty"`
+}
+
+type GeminiChatSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+type GeminiChatPromptFeedback struct {
+	// BlockReason is set when the prompt was blocked, e.g. SAFETY.
+	BlockReason   string                   `json:"blockReason,omitempty"`
+	SafetyRatings []GeminiChatSafetyRating `json:"safetyRatings"`
+}
+
+type GeminiChatResponse struct {
+	Candidates     []GeminiChatCandidate    `json:"candidates"`
+	PromptFeedback GeminiChatPromptFeedback `json:"promptFeedback"`
+	UsageMetadata  GeminiUsageMetadata      `json:"usageMetadata"`
+	// ModelVersion is the version of the model that answered, and
+	// ResponseID identifies the response, the same in every chunk of a stream.
+	ModelVersion string `json:"modelVersion,omitempty"`
+	ResponseID   string `json:"responseId,omitempty"`
+}
+
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int                         `json:"promptTokenCount"`
+	CandidatesTokenCount    int                         `json:"candidatesTokenCount"`
+	TotalTokenCount         int                         `json:"totalTokenCount"`
+	ThoughtsTokenCount      int                         `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int                         `json:"cachedContentTokenCount"`
+	PromptTokensDetails     []GeminiPromptTokensDetails `json:"promptTokensDetails"`
+	// CandidatesTokensDetails splits the candidate tokens by modality.
+	CandidatesTokensDetails []GeminiPromptTokensDetails `json:"candidatesTokensDetails,omitempty"`
+	// ToolUsePromptTokenCount counts the results of built-in tools, such as
+	// Google Search, added to the prompt; PromptTokenCount excludes them.
+	ToolUsePromptTokenCount int `json:"toolUsePromptTokenCount,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+func (m *GeminiUsageMetadata) UnmarshalJSON(data []byte) error {
+	type Alias GeminiUsageMetadata
+	aux := &Alias{}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	*m = GeminiUsageMetadata(*aux)
+	m.Raw = data
+	return nil
+}
+
+type GeminiPromptTokensDetails struct {
+	Modality   string `json:"modality"`
+	TokenCount int    `json:"tokenCount"`
+}
+
+type GeminiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
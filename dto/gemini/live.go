@@ -0,0 +1,74 @@
+package gemini
+
+import "encoding/json"
+
+// Live API (BidiGenerateContent) messages. A client sends exactly one of the
+// LiveClientMessage fields per message, starting with Setup; the server
+// answers with LiveServerMessage values.
+
+type LiveClientMessage struct {
+	Setup         *LiveSetup         `json:"setup,omitempty"`
+	ClientContent *LiveClientContent `json:"clientContent,omitempty"`
+	RealtimeInput *LiveRealtimeInput `json:"realtimeInput,omitempty"`
+	ToolResponse  *LiveToolResponse  `json:"toolResponse,omitempty"`
+}
+
+type LiveSetup struct {
+	Model             string                      `json:"model"`
+	GenerationConfig  *GeminiChatGenerationConfig `json:"generationConfig,omitempty"`
+	SystemInstruction *GeminiChatContent          `json:"systemInstruction,omitempty"`
+	Tools             []GeminiChatTool            `json:"tools,omitempty"`
+}
+
+type LiveClientContent struct {
+	Turns []GeminiChatContent `json:"turns,omitempty"`
+	// TurnComplete asks the model to start generating.
+	TurnComplete bool `json:"turnComplete,omitempty"`
+}
+
+type LiveRealtimeInput struct {
+	Text        string             `json:"text,omitempty"`
+	Audio       *GeminiInlineData  `json:"audio,omitempty"`
+	Video       *GeminiInlineData  `json:"video,omitempty"`
+	MediaChunks []GeminiInlineData `json:"mediaChunks,omitempty"`
+}
+
+type LiveToolResponse struct {
+	FunctionResponses []LiveFunctionResponse `json:"functionResponses"`
+}
+
+type LiveFunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type LiveServerMessage struct {
+	SetupComplete        *struct{}                 `json:"setupComplete,omitempty"`
+	ServerContent        *LiveServerContent        `json:"serverContent,omitempty"`
+	ToolCall             *LiveToolCall             `json:"toolCall,omitempty"`
+	ToolCallCancellation *LiveToolCallCancellation `json:"toolCallCancellation,omitempty"`
+	UsageMetadata        *GeminiUsageMetadata      `json:"usageMetadata,omitempty"`
+	GoAway               json.RawMessage           `json:"goAway,omitempty"`
+}
+
+type LiveServerContent struct {
+	ModelTurn          *GeminiChatContent `json:"modelTurn,omitempty"`
+	GenerationComplete bool               `json:"generationComplete,omitempty"`
+	TurnComplete       bool               `json:"turnComplete,omitempty"`
+	Interrupted        bool               `json:"interrupted,omitempty"`
+}
+
+type LiveToolCall struct {
+	FunctionCalls []LiveFunctionCall `json:"functionCalls"`
+}
+
+type LiveFunctionCall struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Args any    `json:"args,omitempty"`
+}
+
+type LiveToolCallCancellation struct {
+	IDs []string `json:"ids"`
+}
@@ -0,0 +1,127 @@
+package gemini
+
+// Person generation settings of Imagen and Veo
+const (
+	PersonGenerationDontAllow  = "dont_allow"
+	PersonGenerationAllowAdult = "allow_adult"
+	PersonGenerationAllowAll   = "allow_all"
+)
+
+// ImagenRequest is the body of models/{model}:predict for Imagen models.
+type ImagenRequest struct {
+	Instances  []ImagenInstance `json:"instances"`
+	Parameters ImagenParameters `json:"parameters,omitzero"`
+}
+
+type ImagenInstance struct {
+	Prompt string `json:"prompt"`
+}
+
+type ImagenParameters struct {
+	// SampleCount is the number of images, 1 to 4.
+	SampleCount int `json:"sampleCount,omitempty"`
+	// AspectRatio is 1:1, the default, 3:4, 4:3, 9:16 or 16:9.
+	AspectRatio string `json:"aspectRatio,omitempty"`
+	// ImageSize is 1K, the default, or 2K.
+	ImageSize        string `json:"imageSize,omitempty"`
+	PersonGeneration string `json:"personGeneration,omitempty"`
+	// NegativePrompt and the fields below are Vertex AI only.
+	NegativePrompt string               `json:"negativePrompt,omitempty"`
+	Seed           *int                 `json:"seed,omitempty"`
+	EnhancePrompt  *bool                `json:"enhancePrompt,omitempty"`
+	AddWatermark   *bool                `json:"addWatermark,omitempty"`
+	OutputOptions  *ImagenOutputOptions `json:"outputOptions,omitempty"`
+}
+
+type ImagenOutputOptions struct {
+	// MimeType is image/png, the default, or image/jpeg.
+	MimeType           string `json:"mimeType,omitempty"`
+	CompressionQuality int    `json:"compressionQuality,omitempty"`
+}
+
+type ImagenResponse struct {
+	Predictions []ImagenPrediction `json:"predictions"`
+}
+
+// ImagenPrediction is a generated image, or the reason it was filtered.
+type ImagenPrediction struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded,omitempty"`
+	MimeType           string `json:"mimeType,omitempty"`
+	// Prompt is the enhanced prompt the image was generated from.
+	Prompt            string `json:"prompt,omitempty"`
+	RAIFilteredReason string `json:"raiFilteredReason,omitempty"`
+}
+
+// VeoRequest is the body of models/{model}:predictLongRunning for Veo
+// models, answered with a VideoOperation to poll.
+type VeoRequest struct {
+	Instances  []VeoInstance `json:"instances"`
+	Parameters VeoParameters `json:"parameters,omitzero"`
+}
+
+type VeoInstance struct {
+	Prompt string `json:"prompt,omitempty"`
+	// Image is the first frame of the video, LastFrame its last.
+	Image     *MediaBytes `json:"image,omitempty"`
+	LastFrame *MediaBytes `json:"lastFrame,omitempty"`
+}
+
+// MediaBytes is inline media, or media in Cloud Storage on Vertex AI.
+type MediaBytes struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded,omitempty"`
+	GCSURI             string `json:"gcsUri,omitempty"`
+	MimeType           string `json:"mimeType,omitempty"`
+}
+
+type VeoParameters struct {
+	// AspectRatio is 16:9, the default, or 9:16.
+	AspectRatio      string `json:"aspectRatio,omitempty"`
+	NegativePrompt   string `json:"negativePrompt,omitempty"`
+	PersonGeneration string `json:"personGeneration,omitempty"`
+	DurationSeconds  int    `json:"durationSeconds,omitempty"`
+	// Resolution is 720p or 1080p.
+	Resolution    string `json:"resolution,omitempty"`
+	SampleCount   int    `json:"sampleCount,omitempty"`
+	Seed          *int   `json:"seed,omitempty"`
+	GenerateAudio *bool  `json:"generateAudio,omitempty"`
+	EnhancePrompt *bool  `json:"enhancePrompt,omitempty"`
+	// StorageURI is the Cloud Storage prefix Vertex AI writes the videos to.
+	StorageURI string `json:"storageUri,omitempty"`
+}
+
+// VideoOperation is the long-running operation of a Veo request, polled
+// with GET {name} until Done.
+type VideoOperation struct {
+	Name     string          `json:"name"`
+	Done     bool            `json:"done,omitempty"`
+	Error    *OperationError `json:"error,omitempty"`
+	Response *VeoResponse    `json:"response,omitempty"`
+}
+
+type OperationError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// VeoResponse holds the videos of a done operation: generated samples on the
+// Gemini API, videos on Vertex AI.
+type VeoResponse struct {
+	GenerateVideoResponse *GenerateVideoResponse `json:"generateVideoResponse,omitempty"`
+	Videos                []MediaBytes           `json:"videos,omitempty"`
+	RAIMediaFilteredCount int                    `json:"raiMediaFilteredCount,omitempty"`
+}
+
+type GenerateVideoResponse struct {
+	GeneratedSamples        []GeneratedVideo `json:"generatedSamples"`
+	RAIMediaFilteredCount   int              `json:"raiMediaFilteredCount,omitempty"`
+	RAIMediaFilteredReasons []string         `json:"raiMediaFilteredReasons,omitempty"`
+}
+
+type GeneratedVideo struct {
+	Video VideoFile `json:"video"`
+}
+
+// VideoFile is a generated video, downloaded from URI with the API key.
+type VideoFile struct {
+	URI string `json:"uri"`
+}
@@ -0,0 +1,126 @@
+// Package ollama holds the payloads of the native Ollama API: /api/chat and
+// the single-prompt /api/generate. Both stream by default, as newline
+// delimited JSON objects of which the last has done set and carries the
+// metrics of the request.
+package ollama
+
+import "encoding/json"
+
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Tools    []Tool    `json:"tools,omitempty"`
+	// Format is "json" or a JSON schema the response must follow.
+	Format json.RawMessage `json:"format,omitempty"`
+	// Options are model parameters, e.g. temperature, top_p, num_predict,
+	// num_ctx, stop and seed.
+	Options map[string]any `json:"options,omitempty"`
+	// Stream defaults to true.
+	Stream *bool `json:"stream,omitempty"`
+	// KeepAlive is how long the model stays loaded after the request, a
+	// duration string such as "5m" or a number of seconds.
+	KeepAlive json.RawMessage `json:"keep_alive,omitempty"`
+	// Think is true, false or, for some models, "low", "medium" or "high".
+	Think json.RawMessage `json:"think,omitempty"`
+}
+
+type Message struct {
+	// Role is system, user, assistant or tool.
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Thinking string `json:"thinking,omitempty"`
+	// Images are base64 encoded, without a data URL prefix.
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolName names the tool whose result a tool message holds; Ollama
+	// tool calls have no IDs.
+	ToolName string `json:"tool_name,omitempty"`
+}
+
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Index     int            `json:"index,omitempty"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// Done reasons
+const (
+	DoneReasonStop   = "stop"
+	DoneReasonLength = "length"
+	DoneReasonLoad   = "load"
+	DoneReasonUnload = "unload"
+)
+
+// ChatResponse is a response, or one object of a stream.
+type ChatResponse struct {
+	Model string `json:"model"`
+	// CreatedAt is an RFC 3339 timestamp.
+	CreatedAt  string  `json:"created_at"`
+	Message    Message `json:"message"`
+	Done       bool    `json:"done"`
+	DoneReason string  `json:"done_reason,omitempty"`
+	Metrics
+}
+
+// Metrics are set on the final object. Durations are in nanoseconds.
+type Metrics struct {
+	TotalDuration      int64 `json:"total_duration,omitempty"`
+	LoadDuration       int64 `json:"load_duration,omitempty"`
+	PromptEvalCount    int   `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int   `json:"eval_count,omitempty"`
+	EvalDuration       int64 `json:"eval_duration,omitempty"`
+}
+
+type GenerateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	// Suffix is the text after the completion, for fill in the middle.
+	Suffix string `json:"suffix,omitempty"`
+	// System and Template override those of the model.
+	System   string `json:"system,omitempty"`
+	Template string `json:"template,omitempty"`
+	// Context is the context of a previous response, a conversational memory.
+	Context []int `json:"context,omitempty"`
+	// Images are base64 encoded, without a data URL prefix.
+	Images  []string        `json:"images,omitempty"`
+	Format  json.RawMessage `json:"format,omitempty"`
+	Options map[string]any  `json:"options,omitempty"`
+	Stream  *bool           `json:"stream,omitempty"`
+	// Raw sends the prompt without applying the template.
+	Raw       bool            `json:"raw,omitempty"`
+	KeepAlive json.RawMessage `json:"keep_alive,omitempty"`
+	Think     json.RawMessage `json:"think,omitempty"`
+}
+
+type GenerateResponse struct {
+	Model      string `json:"model"`
+	CreatedAt  string `json:"created_at"`
+	Response   string `json:"response"`
+	Thinking   string `json:"thinking,omitempty"`
+	Done       bool   `json:"done"`
+	DoneReason string `json:"done_reason,omitempty"`
+	Context    []int  `json:"context,omitempty"`
+	Metrics
+}
+
+// ErrorResponse is the body of failed requests, and the last object of a
+// stream failing midway.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,43 @@
+package openai
+
+// TranscriptionRequest holds the multipart form of /v1/audio/transcriptions.
+// In JSON, File is base64 encoded.
+type TranscriptionRequest struct {
+	File []byte `json:"file"`
+	// FileName names the uploaded file; its extension tells the audio format.
+	FileName string `json:"filename,omitempty"`
+	Model    string `json:"model"`
+	// Language is the ISO-639-1 code of the speech, detected when empty.
+	Language string `json:"language,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	// ResponseFormat is json, the default, text, srt, verbose_json or vtt.
+	ResponseFormat         string   `json:"response_format,omitempty"`
+	Temperature            *float64 `json:"temperature,omitempty"`
+	TimestampGranularities []string `json:"timestamp_granularities,omitempty"`
+}
+
+type TranscriptionResponse struct {
+	Text string `json:"text"`
+	// Language and Duration are set on verbose_json responses.
+	Language string  `json:"language,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// SpeechRequest is the body of /v1/audio/speech.
+type SpeechRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+	Voice string `json:"voice"`
+	// Instructions control the voice, not for tts-1 and tts-1-hd.
+	Instructions string `json:"instructions,omitempty"`
+	// ResponseFormat is mp3, the default, opus, aac, flac, wav or pcm.
+	ResponseFormat string   `json:"response_format,omitempty"`
+	Speed          *float64 `json:"speed,omitempty"`
+}
+
+// SpeechResponse holds the audio body of /v1/audio/speech. In JSON, Audio
+// is base64 encoded.
+type SpeechResponse struct {
+	Audio       []byte `json:"audio"`
+	ContentType string `json:"content_type,omitempty"`
+}
@@ -0,0 +1,34 @@
+package openai
+
+// EmbeddingRequest is the body of /v1/embeddings.
+type EmbeddingRequest struct {
+	// Input is a string, an array of strings, an array of token ids or an
+	// array of arrays of token ids.
+	Input any    `json:"input"`
+	Model string `json:"model"`
+	// EncodingFormat is "float", the default, or "base64".
+	EncodingFormat string `json:"encoding_format,omitempty"`
+	// Dimensions truncates the embeddings, for models that support it.
+	Dimensions int    `json:"dimensions,omitempty"`
+	User       string `json:"user,omitempty"`
+}
+
+type EmbeddingResponse struct {
+	// Object is "list".
+	Object string         `json:"object"`
+	Data   []Embedding    `json:"data"`
+	Model  string         `json:"model"`
+	Usage  EmbeddingUsage `json:"usage"`
+}
+
+type Embedding struct {
+	// Object is "embedding".
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
@@ -0,0 +1,40 @@
+package openai
+
+// ImageRequest is the body of /v1/images/generations.
+type ImageRequest struct {
+	Prompt string `json:"prompt"`
+	Model  string `json:"model,omitempty"`
+	N      int    `json:"n,omitempty"`
+	// Size is {width}x{height}, e.g. 1024x1024, or auto.
+	Size    string `json:"size,omitempty"`
+	Quality string `json:"quality,omitempty"`
+	// ResponseFormat is url, the default, or b64_json, for dall-e models;
+	// gpt-image models always return b64_json.
+	ResponseFormat string `json:"response_format,omitempty"`
+	// OutputFormat is png, the default, jpeg or webp, for gpt-image models.
+	OutputFormat      string `json:"output_format,omitempty"`
+	OutputCompression *int   `json:"output_compression,omitempty"`
+	Background        string `json:"background,omitempty"`
+	Moderation        string `json:"moderation,omitempty"`
+	Style             string `json:"style,omitempty"`
+	User              string `json:"user,omitempty"`
+}
+
+type ImageResponse struct {
+	Created int64       `json:"created"`
+	Data    []ImageData `json:"data"`
+	// Usage is reported for gpt-image models.
+	Usage *ImageUsage `json:"usage,omitempty"`
+}
+
+type ImageData struct {
+	B64JSON       string `json:"b64_json,omitempty"`
+	URL           string `json:"url,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+type ImageUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
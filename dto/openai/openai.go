@@ -49,6 +49,15 @@ type Profanity struct {
 	Detected bool `json:"detected"`
 }
 
+// Content filter severities
+const (
+	ContentFilterSeveritySafe   = "safe"
+	ContentFilterSeverityLow    = "low"
+	ContentFilterSeverityMedium = "medium"
+	ContentFilterSeverityHigh   = "high"
+)
+
+// ContentFilterResults are the content filter annotations of Azure OpenAI.
 type ContentFilterResults struct {
 	Hate      *Hate      `json:"hate,omitempty"`
 	SelfHarm  *SelfHarm  `json:"self_harm,omitempty"`
@@ -107,9 +116,11 @@ type ChatMessagePart struct {
 }
 
 type ChatCompletionMessage struct {
-	Role         string `json:"role"`
-	Content      string `json:"content,omitempty"`
-	Refusal      string `json:"refusal,omitempty"`
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	Refusal string `json:"refusal,omitempty"`
+	// Annotations cite the sources of the content, as search models do.
+	Annotations  []Annotation `json:"annotations,omitempty"`
 	MultiContent []ChatMessagePart
 
 	// This property isn't in the official documentation, but it's in
@@ -124,6 +135,11 @@ type ChatCompletionMessage struct {
 	// - https://api-docs.deepseek.com/api/create-chat-completion#responses
 	ReasoningContent string `json:"reasoning_content,omitempty"`
 
+	// ReasoningDetails is an un-official field, as used by OpenRouter, carrying
+	// the signatures of the reasoning and its redacted parts, which Claude and
+	// Gemini need back in multi-turn tool use.
+	ReasoningDetails []ReasoningDetail `json:"reasoning_details,omitempty"`
+
 	FunctionCall *FunctionCall `json:"function_call,omitempty"`
 
 	// For Role=assistant prompts this may be set to the tool calls generated by the model, such as function calls.
@@ -144,9 +160,11 @@ func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
 			Role             string               `json:"role"`
 			Content          string               `json:"-"`
 			Refusal          string               `json:"refusal,omitempty"`
+			Annotations      []Annotation         `json:"annotations,omitempty"`
 			MultiContent     []ChatMessagePart    `json:"content,omitempty"`
 			Name             string               `json:"name,omitempty"`
 			ReasoningContent string               `json:"reasoning_content,omitempty"`
+			ReasoningDetails []ReasoningDetail    `json:"reasoning_details,omitempty"`
 			FunctionCall     *FunctionCall        `json:"function_call,omitempty"`
 			ToolCalls        []ToolCall           `json:"tool_calls,omitempty"`
 			ToolCallID       string               `json:"tool_call_id,omitempty"`
@@ -159,9 +177,11 @@ func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
 		Role             string               `json:"role"`
 		Content          string               `json:"content,omitempty"`
 		Refusal          string               `json:"refusal,omitempty"`
+		Annotations      []Annotation         `json:"annotations,omitempty"`
 		MultiContent     []ChatMessagePart    `json:"-"`
 		Name             string               `json:"name,omitempty"`
 		ReasoningContent string               `json:"reasoning_content,omitempty"`
+		ReasoningDetails []ReasoningDetail    `json:"reasoning_details,omitempty"`
 		FunctionCall     *FunctionCall        `json:"function_call,omitempty"`
 		ToolCalls        []ToolCall           `json:"tool_calls,omitempty"`
 		ToolCallID       string               `json:"tool_call_id,omitempty"`
@@ -172,12 +192,14 @@ func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
 
 func (m *ChatCompletionMessage) UnmarshalJSON(bs []byte) error {
 	msg := struct {
-		Role             string `json:"role"`
-		Content          string `json:"content"`
-		Refusal          string `json:"refusal,omitempty"`
+		Role             string       `json:"role"`
+		Content          string       `json:"content"`
+		Refusal          string       `json:"refusal,omitempty"`
+		Annotations      []Annotation `json:"annotations,omitempty"`
 		MultiContent     []ChatMessagePart
 		Name             string               `json:"name,omitempty"`
 		ReasoningContent string               `json:"reasoning_content,omitempty"`
+		ReasoningDetails []ReasoningDetail    `json:"reasoning_details,omitempty"`
 		FunctionCall     *FunctionCall        `json:"function_call,omitempty"`
 		ToolCalls        []ToolCall           `json:"tool_calls,omitempty"`
 		ToolCallID       string               `json:"tool_call_id,omitempty"`
@@ -192,9 +214,11 @@ func (m *ChatCompletionMessage) UnmarshalJSON(bs []byte) error {
 		Role             string `json:"role"`
 		Content          string
 		Refusal          string               `json:"refusal,omitempty"`
+		Annotations      []Annotation         `json:"annotations,omitempty"`
 		MultiContent     []ChatMessagePart    `json:"content"`
 		Name             string               `json:"name,omitempty"`
 		ReasoningContent string               `json:"reasoning_content,omitempty"`
+		ReasoningDetails []ReasoningDetail    `json:"reasoning_details,omitempty"`
 		FunctionCall     *FunctionCall        `json:"function_call,omitempty"`
 		ToolCalls        []ToolCall           `json:"tool_calls,omitempty"`
 		ToolCallID       string               `json:"tool_call_id,omitempty"`
@@ -207,12 +231,79 @@ func (m *ChatCompletionMessage) UnmarshalJSON(bs []byte) error {
 	return nil
 }
 
+// Annotation types
+const (
+	AnnotationTypeURLCitation = "url_citation"
+	// AnnotationTypeDocumentCitation is an un-official annotation citing a
+	// document of the request, as Claude does.
+	AnnotationTypeDocumentCitation = "document_citation"
+)
+
+// Annotation cites the source of a span of the content of a message.
+type Annotation struct {
+	Type             string            `json:"type"`
+	URLCitation      *URLCitation      `json:"url_citation,omitempty"`
+	DocumentCitation *DocumentCitation `json:"document_citation,omitempty"`
+}
+
+// URLCitation cites a web page for the content from StartIndex to
+// EndIndex, in characters.
+type URLCitation struct {
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	// CitedText is an un-official field, the text of the page cited.
+	CitedText string `json:"cited_text,omitempty"`
+}
+
+// DocumentCitation cites the range from Start to End of a document of the
+// request, in Unit char, page or content_block, for the content from
+// StartIndex to EndIndex.
+type DocumentCitation struct {
+	StartIndex    int    `json:"start_index"`
+	EndIndex      int    `json:"end_index"`
+	DocumentIndex int    `json:"document_index"`
+	DocumentTitle string `json:"document_title,omitempty"`
+	CitedText     string `json:"cited_text,omitempty"`
+	Unit          string `json:"unit"`
+	Start         int    `json:"start"`
+	End           int    `json:"end"`
+}
+
+const (
+	ReasoningDetailTypeText      = "reasoning.text"
+	ReasoningDetailTypeEncrypted = "reasoning.encrypted"
+)
+
+// ReasoningDetail is a block of reasoning: text with its signature, or
+// encrypted data, e.g. Claude's redacted thinking. In chunks the text streams
+// as reasoning_content, the detail closing its block carries the signature.
+type ReasoningDetail struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
 type ToolCall struct {
 	// Index is not nil only in chat completion chunk object
 	Index    *int         `json:"index,omitempty"`
 	ID       string       `json:"id,omitempty"`
 	Type     ToolType     `json:"type"`
 	Function FunctionCall `json:"function"`
+
+	// ExtraContent is an un-official field used by Gemini's OpenAI compatible API
+	// to carry provider-specific data, such as thought signatures, along with the call.
+	ExtraContent *ToolCallExtraContent `json:"extra_content,omitempty"`
+}
+
+type ToolCallExtraContent struct {
+	Google *GoogleToolCallExtraContent `json:"google,omitempty"`
+}
+
+type GoogleToolCallExtraContent struct {
+	ThoughtSignature string `json:"thought_signature,omitempty"`
 }
 
 type FunctionCall struct {
@@ -275,6 +366,9 @@ type ChatCompletionRequestExtensions struct {
 	// ensuring predictable and consistent outputs in scenarios where specific
 	// choices are required.
 	GuidedChoice []string `json:"guided_choice,omitempty"`
+	// CachedContent names the Gemini cachedContents resource holding the
+	// prefix of the prompt.
+	CachedContent string `json:"cached_content,omitempty"`
 }
 
 // ChatCompletionRequest represents a request structure for chat completion API.
@@ -288,16 +382,18 @@ type ChatCompletionRequest struct {
 	MaxTokens int `json:"max_tokens,omitempty"`
 	// MaxCompletionTokens An upper bound for the number of tokens that can be generated for a completion,
 	// including visible output tokens and reasoning tokens https://platform.openai.com/docs/guides/reasoning
-	MaxCompletionTokens int                           `json:"max_completion_tokens,omitempty"`
-	Temperature         float32                       `json:"temperature,omitempty"`
-	TopP                float32                       `json:"top_p,omitempty"`
-	N                   int                           `json:"n,omitempty"`
-	Stream              bool                          `json:"stream,omitempty"`
-	Stop                []string                      `json:"stop,omitempty"`
-	PresencePenalty     float32                       `json:"presence_penalty,omitempty"`
-	ResponseFormat      *ChatCompletionResponseFormat `json:"response_format,omitempty"`
-	Seed                *int                          `json:"seed,omitempty"`
-	FrequencyPenalty    float32                       `json:"frequency_penalty,omitempty"`
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+	// Temperature, TopP and the penalties are unset when nil, so that 0 is
+	// sent as such.
+	Temperature      *float64                      `json:"temperature,omitempty"`
+	TopP             *float64                      `json:"top_p,omitempty"`
+	N                int                           `json:"n,omitempty"`
+	Stream           bool                          `json:"stream,omitempty"`
+	Stop             []string                      `json:"stop,omitempty"`
+	PresencePenalty  *float64                      `json:"presence_penalty,omitempty"`
+	ResponseFormat   *ChatCompletionResponseFormat `json:"response_format,omitempty"`
+	Seed             *int                          `json:"seed,omitempty"`
+	FrequencyPenalty *float64                      `json:"frequency_penalty,omitempty"`
 	// LogitBias is must be a token id string (specified by their token ID in the tokenizer), not a word string.
 	// incorrect: `"logit_bias":{"You": 6}`, correct: `"logit_bias":{"1639": 6}`
 	// refs: https://platform.openai.com/docs/api-reference/chat/create#chat/create-logit_bias
@@ -325,6 +421,10 @@ type ChatCompletionRequest struct {
 	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 	// Metadata to store with the completion.
 	Metadata map[string]string `json:"metadata,omitempty"`
+	// PromptCacheKey groups requests sharing a prompt prefix, raising their cache hits.
+	PromptCacheKey string `json:"prompt_cache_key,omitempty"`
+	// PromptCacheRetention is in_memory, the default, or 24h.
+	PromptCacheRetention string `json:"prompt_cache_retention,omitempty"`
 	// Configuration for a predicted output.
 	Prediction *Prediction `json:"prediction,omitempty"`
 	// ChatTemplateKwargs provides a way to add non-standard parameters to the request body.
@@ -334,10 +434,22 @@ type ChatCompletionRequest struct {
 	ChatTemplateKwargs map[string]any `json:"chat_template_kwargs,omitempty"`
 	// Specifies the latency tier to use for processing the request.
 	ServiceTier ServiceTier `json:"service_tier,omitempty"`
+	// Modalities are the output types the model should generate, "text" and "audio".
+	Modalities []string `json:"modalities,omitempty"`
+	// Audio configures the audio output, required when modalities include "audio".
+	Audio *AudioOutput `json:"audio,omitempty"`
+	// WebSearchOptions enables web search on search-capable models.
+	WebSearchOptions *WebSearchOptions `json:"web_search_options,omitempty"`
 	// Embedded struct for non-OpenAI extensions
 	ChatCompletionRequestExtensions
 }
 
+// Prompt cache retentions
+const (
+	PromptCacheRetentionInMemory = "in_memory"
+	PromptCacheRetention24h      = "24h"
+)
+
 type StreamOptions struct {
 	// If set, an additional chunk will be streamed before the data: [DONE] message.
 	// The usage field on this chunk shows the token usage statistics for the entire request,
@@ -350,6 +462,15 @@ type ToolType string
 
 const (
 	ToolTypeFunction ToolType = "function"
+	// ToolTypeAllowedTools restricts the tools the model may call to a subset
+	// of the declared ones.
+	ToolTypeAllowedTools ToolType = "allowed_tools"
+	// ToolTypeWebSearchPreview and ToolTypeCodeInterpreter are built-in tools
+	// of the Responses API, which requests may declare among their tools
+	// (un-official, for Claude and Gemini server tools); they have no
+	// Function. A web_search_preview tool is like web_search_options.
+	ToolTypeWebSearchPreview ToolType = "web_search_preview"
+	ToolTypeCodeInterpreter  ToolType = "code_interpreter"
 )
 
 type Tool struct {
@@ -359,8 +480,16 @@ type Tool struct {
 }
 
 type ToolChoice struct {
-	Type     ToolType     `json:"type"`
-	Function ToolFunction `json:"function,omitempty"`
+	Type         ToolType      `json:"type"`
+	Function     ToolFunction  `json:"function,omitzero"`
+	AllowedTools *AllowedTools `json:"allowed_tools,omitempty"`
+}
+
+// AllowedTools is the allowed_tools of a ToolChoice.
+type AllowedTools struct {
+	// Mode is auto or required.
+	Mode  string       `json:"mode"`
+	Tools []ToolChoice `json:"tools"`
 }
 
 type ToolFunction struct {
@@ -404,6 +533,29 @@ type LogProbs struct {
 	Content []LogProb `json:"content"`
 }
 
+type AudioOutput struct {
+	Voice  string `json:"voice"`
+	Format string `json:"format"`
+}
+
+type WebSearchOptions struct {
+	// SearchContextSize is one of low, medium or high.
+	SearchContextSize string                 `json:"search_context_size,omitempty"`
+	UserLocation      *WebSearchUserLocation `json:"user_location,omitempty"`
+}
+
+type WebSearchUserLocation struct {
+	Type        string                       `json:"type"`
+	Approximate WebSearchApproximateLocation `json:"approximate"`
+}
+
+type WebSearchApproximateLocation struct {
+	City     string `json:"city,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
 type Prediction struct {
 	Content string `json:"content"`
 	Type    string `json:"type"`
@@ -481,6 +633,8 @@ type ChatCompletionStreamChoiceDelta struct {
 	// the doc from deepseek:
 	// - https://api-docs.deepseek.com/api/create-chat-completion#responses
 	ReasoningContent string `json:"reasoning_content,omitempty"`
+
+	ReasoningDetails []ReasoningDetail `json:"reasoning_details,omitempty"`
 }
 
 type ChatCompletionStreamChoiceLogprobs struct {
@@ -549,3 +703,15 @@ type PromptTokensDetails struct {
 	CacheCreationInputTokens int `json:"cache_creation_input_tokens"` // used for anthropic
 	CacheReadInputTokens     int `json:"cache_read_input_tokens"`     // used for anthropic
 }
+
+// ErrorResponse is the error envelope of the OpenAI API.
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+type APIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    any     `json:"code"`
+}
@@ -0,0 +1,124 @@
+package openai
+
+// Realtime event types
+const (
+	RealtimeSessionUpdate          = "session.update"
+	RealtimeSessionCreated         = "session.created"
+	RealtimeSessionUpdated         = "session.updated"
+	RealtimeInputAudioBufferAppend = "input_audio_buffer.append"
+	RealtimeItemCreate             = "conversation.item.create"
+	RealtimeResponseCreate         = "response.create"
+	RealtimeResponseCancel         = "response.cancel"
+	RealtimeResponseCreated        = "response.created"
+	RealtimeResponseDone           = "response.done"
+	RealtimeOutputItemAdded        = "response.output_item.added"
+	RealtimeOutputItemDone         = "response.output_item.done"
+	RealtimeContentPartAdded       = "response.content_part.added"
+	RealtimeContentPartDone        = "response.content_part.done"
+	RealtimeTextDelta              = "response.text.delta"
+	RealtimeTextDone               = "response.text.done"
+	RealtimeOutputTextDelta        = "response.output_text.delta"
+	RealtimeOutputTextDone         = "response.output_text.done"
+	RealtimeAudioDelta             = "response.audio.delta"
+	RealtimeFunctionArgumentsDelta = "response.function_call_arguments.delta"
+	RealtimeFunctionArgumentsDone  = "response.function_call_arguments.done"
+	RealtimeErrorEvent             = "error"
+)
+
+// RealtimeEvent is a client or server event of the Realtime API. Like the
+// API's events, it is a union: Type selects which fields are set.
+type RealtimeEvent struct {
+	Type    string `json:"type"`
+	EventID string `json:"event_id,omitempty"`
+
+	// session.update, session.created, session.updated
+	Session *RealtimeSession `json:"session,omitempty"`
+	// conversation.item.create, conversation.item.created, response.output_item.*
+	Item           *RealtimeItem `json:"item,omitempty"`
+	PreviousItemID string        `json:"previous_item_id,omitempty"`
+	// response.create, response.created, response.done
+	Response *RealtimeResponse `json:"response,omitempty"`
+
+	// response.*.delta and response.*.done
+	ResponseID   string `json:"response_id,omitempty"`
+	ItemID       string `json:"item_id,omitempty"`
+	OutputIndex  int    `json:"output_index,omitempty"`
+	ContentIndex int    `json:"content_index,omitempty"`
+	Delta        string `json:"delta,omitempty"`
+	// response.content_part.*
+	Part *RealtimeContentPart `json:"part,omitempty"`
+	Text string               `json:"text,omitempty"`
+	// input_audio_buffer.append
+	Audio string `json:"audio,omitempty"`
+	// response.function_call_arguments.*
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+
+	Error *RealtimeErrorDetail `json:"error,omitempty"`
+}
+
+type RealtimeSession struct {
+	ID           string         `json:"id,omitempty"`
+	Model        string         `json:"model,omitempty"`
+	Modalities   []string       `json:"modalities,omitempty"`
+	Instructions string         `json:"instructions,omitempty"`
+	Voice        string         `json:"voice,omitempty"`
+	Tools        []RealtimeTool `json:"tools,omitempty"`
+	ToolChoice   any            `json:"tool_choice,omitempty"`
+	Temperature  *float64       `json:"temperature,omitempty"`
+	// MaxResponseOutputTokens is an integer or "inf".
+	MaxResponseOutputTokens any `json:"max_response_output_tokens,omitempty"`
+}
+
+type RealtimeTool struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type RealtimeItem struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"` // message, function_call, function_call_output
+	// Status is completed, incomplete or in_progress.
+	Status  string                `json:"status,omitempty"`
+	Role    string                `json:"role,omitempty"`
+	Content []RealtimeContentPart `json:"content,omitempty"`
+	// function_call and function_call_output items
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+}
+
+type RealtimeContentPart struct {
+	Type       string `json:"type"` // input_text, input_audio, text, audio
+	Text       string `json:"text,omitempty"`
+	Audio      string `json:"audio,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+type RealtimeResponse struct {
+	ID     string `json:"id,omitempty"`
+	Object string `json:"object,omitempty"`
+	// Status is in_progress, completed, cancelled, incomplete or failed.
+	Status       string         `json:"status,omitempty"`
+	Output       []RealtimeItem `json:"output,omitempty"`
+	Usage        *RealtimeUsage `json:"usage,omitempty"`
+	Modalities   []string       `json:"modalities,omitempty"`
+	Instructions string         `json:"instructions,omitempty"`
+}
+
+type RealtimeUsage struct {
+	TotalTokens  int `json:"total_tokens"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type RealtimeErrorDetail struct {
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	EventID string `json:"event_id,omitempty"`
+}
@@ -0,0 +1,39 @@
+// Package vertex holds the payloads of the Gemini API of Vertex AI where they
+// differ from the Gemini API of Google AI Studio in package gemini: requests
+// carry labels and safety settings with a method, responses identify
+// themselves and the model version. Vertex serves the models under
+// publisher paths, e.g.
+// /v1/projects/{project}/locations/{location}/publishers/google/models/{model}:generateContent,
+// authenticated with OAuth instead of a key query parameter.
+package vertex
+
+import "github.com/phosae/llms/dto/gemini"
+
+type GenerateContentRequest struct {
+	gemini.GeminiChatRequest
+	// SafetySettings shadows the settings of the embedded request.
+	SafetySettings []SafetySetting `json:"safetySettings,omitempty"`
+	// Labels are billing labels: lowercase keys and values of at most 63
+	// letters, digits, underscores and dashes, keys starting with a letter.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Safety setting methods
+const (
+	SafetyMethodSeverity    = "SEVERITY"
+	SafetyMethodProbability = "PROBABILITY"
+)
+
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+	// Method is what Threshold applies to, SEVERITY when empty. AI Studio
+	// always blocks on probability.
+	Method string `json:"method,omitempty"`
+}
+
+type GenerateContentResponse struct {
+	gemini.GeminiChatResponse
+	// CreateTime is an RFC 3339 timestamp.
+	CreateTime string `json:"createTime,omitempty"`
+}
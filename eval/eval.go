@@ -0,0 +1,272 @@
+// Package eval runs provider-agnostic evaluations: cases are unified
+// requests with assertions on their responses, so the same cases run
+// against any provider the transformers speak, e.g. through a gateway.Proxy.
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/transformer"
+)
+
+// Assertion types
+const (
+	// AssertRegex checks the text of the response against Pattern.
+	AssertRegex = "regex"
+	// AssertJSONSchema checks that the text of the response is JSON
+	// satisfying Schema.
+	AssertJSONSchema = "json_schema"
+	// AssertToolCall checks that the response calls Tool, with Arguments
+	// among its arguments.
+	AssertToolCall = "tool_call"
+	// AssertNoToolCall checks that the response calls no tool.
+	AssertNoToolCall = "no_tool_call"
+)
+
+// Case is one evaluation: a request and what its response must satisfy.
+type Case struct {
+	Name       string                     `json:"name"`
+	Request    transformer.UnifiedRequest `json:"request"`
+	Assertions []Assertion                `json:"assertions"`
+}
+
+// Assertion is one check of a response, by Type.
+type Assertion struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern,omitempty"`
+	// Schema supports type, enum, const, properties, required,
+	// additionalProperties, items, anyOf and the length, size and range
+	// keywords.
+	Schema    json.RawMessage `json:"schema,omitempty"`
+	Tool      string          `json:"tool,omitempty"`
+	Arguments map[string]any  `json:"arguments,omitempty"`
+}
+
+// Client sends unified requests, e.g. a *gateway.Proxy.
+type Client interface {
+	Do(ctx context.Context, u *transformer.UnifiedRequest) (*transformer.UnifiedResponse, transformer.StreamUsage, error)
+}
+
+// Runner runs cases against a Client.
+type Runner struct {
+	Client Client
+	// Pricing prices the usage of the cases by the model of their
+	// responses, nil for none.
+	Pricing *config.Config
+	// Concurrency is the number of cases run at once, 1 when zero.
+	Concurrency int
+}
+
+// Result is the outcome of a case.
+type Result struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Failures are the messages of the failed assertions, Error that of a
+	// failed request.
+	Failures  []string                `json:"failures,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+	Model     string                  `json:"model,omitempty"`
+	Usage     transformer.StreamUsage `json:"usage"`
+	Cost      float64                 `json:"cost,omitempty"`
+	LatencyMS float64                 `json:"latency_ms"`
+}
+
+// Report sums up a run.
+type Report struct {
+	Results []Result                `json:"results"`
+	Passed  int                     `json:"passed"`
+	Failed  int                     `json:"failed"`
+	Usage   transformer.StreamUsage `json:"usage"`
+	Cost    float64                 `json:"cost,omitempty"`
+}
+
+// Run runs the cases and reports their results in order.
+func (r *Runner) Run(ctx context.Context, cases []Case) *Report {
+	results := make([]Result, len(cases))
+	slots := make(chan struct{}, max(r.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range cases {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = r.run(ctx, &cases[i])
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Results: results}
+	var usage transformer.UsageTracker
+	for _, result := range results {
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		usage.Observe(result.Usage, transformer.UsageIncremental)
+		report.Cost += result.Cost
+	}
+	report.Usage = usage.Total()
+	return report
+}
+
+func (r *Runner) run(ctx context.Context, c *Case) Result {
+	result := Result{Name: c.Name, Model: c.Request.Model}
+	start := time.Now()
+	resp, usage, err := r.Client.Do(ctx, &c.Request)
+	result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	result.Usage = usage
+	if resp != nil && resp.Model != "" {
+		result.Model = resp.Model
+	}
+	if price, ok := r.Pricing.Price(result.Model); ok {
+		result.Cost = price.Cost(usage.InputTokens-usage.CacheReadTokens-usage.CacheCreationTokens, usage.OutputTokens, usage.CacheReadTokens, usage.CacheCreationTokens)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for i, assertion := range c.Assertions {
+		if err := assertion.Check(resp); err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("assertions[%d] %s: %v", i, assertion.Type, err))
+		}
+	}
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// Check returns why resp fails the assertion, nil when it passes.
+func (a Assertion) Check(resp *transformer.UnifiedResponse) error {
+	switch a.Type {
+	case AssertRegex:
+		re, err := regexp.Compile(a.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !re.MatchString(responseText(resp)) {
+			return fmt.Errorf("text does not match %q", a.Pattern)
+		}
+		return nil
+	case AssertJSONSchema:
+		var schema any
+		if err := json.Unmarshal(a.Schema, &schema); err != nil {
+			return fmt.Errorf("invalid schema: %w", err)
+		}
+		var value any
+		if err := json.Unmarshal([]byte(trimCodeFence(responseText(resp))), &value); err != nil {
+			return fmt.Errorf("text is not JSON: %w", err)
+		}
+		var problems []string
+		validateSchema(schema, value, "", &problems)
+		if len(problems) > 0 {
+			return fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+		return nil
+	case AssertToolCall:
+		var called []string
+		for _, call := range resp.Message.ToolCalls {
+			if call.Name != a.Tool {
+				called = append(called, call.Name)
+				continue
+			}
+			if err := containsArguments(call.Arguments, a.Arguments); err != nil {
+				return fmt.Errorf("call of %s: %w", a.Tool, err)
+			}
+			return nil
+		}
+		if len(called) == 0 {
+			return fmt.Errorf("no call of %s, no tool called", a.Tool)
+		}
+		return fmt.Errorf("no call of %s, called %s", a.Tool, strings.Join(called, ", "))
+	case AssertNoToolCall:
+		if calls := resp.Message.ToolCalls; len(calls) > 0 {
+			return fmt.Errorf("called %s", calls[0].Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+}
+
+// responseText returns the text parts of the message of resp.
+func responseText(resp *transformer.UnifiedResponse) string {
+	var b strings.Builder
+	for _, part := range resp.Message.Parts {
+		if part.Type == "text" {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+// trimCodeFence unwraps text a model fenced as a markdown code block.
+func trimCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimSuffix(text, "```")
+	if _, body, ok := strings.Cut(text, "\n"); ok {
+		return body
+	}
+	return text
+}
+
+// containsArguments checks that the JSON object arguments has every key of
+// want with an equal value.
+func containsArguments(arguments string, want map[string]any) error {
+	var got map[string]any
+	if err := json.Unmarshal([]byte(arguments), &got); err != nil {
+		return fmt.Errorf("arguments are not a JSON object: %w", err)
+	}
+	for key, value := range want {
+		actual, ok := got[key]
+		if !ok {
+			return fmt.Errorf("argument %q missing", key)
+		}
+		// compare as decoded from JSON, numbers being float64 on both sides
+		expected, _ := json.Marshal(value)
+		var normalized any
+		json.Unmarshal(expected, &normalized)
+		if !reflect.DeepEqual(actual, normalized) {
+			got, _ := json.Marshal(actual)
+			return fmt.Errorf("argument %q is %s, expected %s", key, got, expected)
+		}
+	}
+	return nil
+}
+
+// LoadCases reads cases from JSON lines, one case per line, skipping blank
+// lines.
+func LoadCases(r io.Reader) ([]Case, error) {
+	var cases []Case
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("line %d", line)
+		}
+		cases = append(cases, c)
+	}
+	return cases, scanner.Err()
+}
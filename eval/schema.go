@@ -0,0 +1,165 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateSchema reports where value, decoded from JSON, breaks schema, a
+// decoded JSON schema, as JSON pointers with their problems.
+func validateSchema(schema, value any, path string, problems *[]string) {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		// true accepts anything, false nothing
+		if accept, isBool := schema.(bool); isBool && !accept {
+			*problems = append(*problems, pointer(path)+": not allowed")
+		}
+		return
+	}
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, pointer(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		fail("expected %s, got %s", typeNames(t), jsonType(value))
+		return
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, value) }) {
+		fail("not one of the enum values")
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		fail("not the const value")
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && !slices.ContainsFunc(anyOf, func(sub any) bool {
+		var subProblems []string
+		validateSchema(sub, value, path, &subProblems)
+		return len(subProblems) == 0
+	}) {
+		fail("matches none of anyOf")
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := s["properties"].(map[string]any)
+		if required, ok := s["required"].([]any); ok {
+			for _, name := range required {
+				if key, _ := name.(string); key != "" {
+					if _, ok := v[key]; !ok {
+						fail("missing required property %q", key)
+					}
+				}
+			}
+		}
+		for key, item := range v {
+			if sub, ok := properties[key]; ok {
+				validateSchema(sub, item, path+"/"+pointerToken(key), problems)
+			} else if additional, ok := s["additionalProperties"]; ok {
+				validateSchema(additional, item, path+"/"+pointerToken(key), problems)
+			}
+		}
+	case []any:
+		if limit, ok := number(s["minItems"]); ok && float64(len(v)) < limit {
+			fail("expected at least %g items, got %d", limit, len(v))
+		}
+		if limit, ok := number(s["maxItems"]); ok && float64(len(v)) > limit {
+			fail("expected at most %g items, got %d", limit, len(v))
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				validateSchema(items, item, path+"/"+strconv.Itoa(i), problems)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if limit, ok := number(s["minLength"]); ok && length < limit {
+			fail("expected at least %g characters, got %g", limit, length)
+		}
+		if limit, ok := number(s["maxLength"]); ok && length > limit {
+			fail("expected at most %g characters, got %g", limit, length)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err != nil {
+				fail("invalid pattern: %v", err)
+			} else if !re.MatchString(v) {
+				fail("does not match %q", pattern)
+			}
+		}
+	case float64:
+		if limit, ok := number(s["minimum"]); ok && v < limit {
+			fail("expected at least %g, got %g", limit, v)
+		}
+		if limit, ok := number(s["maximum"]); ok && v > limit {
+			fail("expected at most %g, got %g", limit, v)
+		}
+	}
+}
+
+// matchesType reports whether value is of the schema type t, a name or a
+// list of names.
+func matchesType(t, value any) bool {
+	switch t := t.(type) {
+	case string:
+		actual := jsonType(value)
+		return actual == t || t == "number" && actual == "integer"
+	case []any:
+		return slices.ContainsFunc(t, func(name any) bool { return matchesType(name, value) })
+	default:
+		return true
+	}
+}
+
+// jsonType returns the schema type name of a value decoded from JSON.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func typeNames(t any) string {
+	if names, ok := t.([]any); ok {
+		data, _ := json.Marshal(names)
+		return "one of " + string(data)
+	}
+	return fmt.Sprint(t)
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+// pointer returns path as a JSON pointer, / for the root.
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// pointerToken escapes a key for a JSON pointer.
+func pointerToken(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,275 @@
+// Package eventstream reads and writes the binary event stream framing of
+// AWS APIs, in which Bedrock streams ConverseStream and
+// InvokeModelWithResponseStream events. Each message is a prelude with the
+// total and header lengths and their CRC, typed headers, the payload and a
+// CRC of the whole message.
+//
+//	r := eventstream.NewReader(resp.Body)
+//	for {
+//		msg, err := r.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+package eventstream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+const (
+	preludeLen = 12
+	crcLen     = 4
+	// maxMessageSize bounds a message, long enough for inline images.
+	maxMessageSize = 32 * 1024 * 1024
+)
+
+// Header value types
+const (
+	headerTrue = iota
+	headerFalse
+	headerByte
+	headerShort
+	headerInt
+	headerLong
+	headerBytes
+	headerString
+	headerTimestamp
+	headerUUID
+)
+
+// Message is one event stream message. Only string headers, the only kind
+// the AWS runtime APIs send, are kept when reading.
+type Message struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// MessageType returns the :message-type header, event, exception or error.
+func (m Message) MessageType() string {
+	return m.Headers[":message-type"]
+}
+
+// EventType returns the :event-type header of an event message.
+func (m Message) EventType() string {
+	return m.Headers[":event-type"]
+}
+
+// Err returns the error an exception or error message reports, nil for events.
+func (m Message) Err() error {
+	switch m.MessageType() {
+	case "exception":
+		var payload struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(m.Payload, &payload)
+		return &Error{Type: m.Headers[":exception-type"], Message: payload.Message}
+	case "error":
+		return &Error{Type: m.Headers[":error-code"], Message: m.Headers[":error-message"]}
+	}
+	return nil
+}
+
+// Error is an error reported in-band by a stream.
+type Error struct {
+	Type    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// NewEvent returns an event message with a JSON payload.
+func NewEvent(eventType string, payload any) (Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Headers: map[string]string{
+			":message-type": "event",
+			":event-type":   eventType,
+			":content-type": "application/json",
+		},
+		Payload: data,
+	}, nil
+}
+
+// NewException returns an exception message, e.g. of type throttlingException.
+func NewException(exceptionType, message string) Message {
+	data, _ := json.Marshal(map[string]string{"message": message})
+	return Message{
+		Headers: map[string]string{
+			":message-type":   "exception",
+			":exception-type": exceptionType,
+			":content-type":   "application/json",
+		},
+		Payload: data,
+	}
+}
+
+// Reader parses a stream into messages.
+type Reader struct {
+	br *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// Next returns the next message, or io.EOF at the end of the stream.
+func (r *Reader) Next() (Message, error) {
+	prelude := make([]byte, preludeLen)
+	if _, err := io.ReadFull(r.br, prelude); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Message{}, fmt.Errorf("truncated event stream prelude")
+		}
+		return Message{}, err
+	}
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return Message{}, fmt.Errorf("event stream prelude checksum mismatch")
+	}
+	if totalLen > maxMessageSize || uint64(totalLen) < uint64(preludeLen)+uint64(headersLen)+crcLen {
+		return Message{}, fmt.Errorf("invalid event stream message length %d", totalLen)
+	}
+
+	buf := make([]byte, totalLen)
+	copy(buf, prelude)
+	if _, err := io.ReadFull(r.br, buf[preludeLen:]); err != nil {
+		return Message{}, fmt.Errorf("truncated event stream message: %w", err)
+	}
+	if crc32.ChecksumIEEE(buf[:totalLen-crcLen]) != binary.BigEndian.Uint32(buf[totalLen-crcLen:]) {
+		return Message{}, fmt.Errorf("event stream message checksum mismatch")
+	}
+
+	headers, err := parseHeaders(buf[preludeLen : preludeLen+headersLen])
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Headers: headers, Payload: buf[preludeLen+headersLen : totalLen-crcLen]}, nil
+}
+
+// Messages yields the remaining messages. A failing read is yielded with
+// its error and ends the iteration; the end of the stream ends it without
+// one.
+func (r *Reader) Messages() iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for {
+			msg, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(msg, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+var errHeaders = errors.New("malformed event stream headers")
+
+func parseHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, errHeaders
+		}
+		name := string(b[1 : 1+nameLen])
+		typ := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		var size int
+		switch typ {
+		case headerTrue, headerFalse:
+		case headerByte:
+			size = 1
+		case headerShort:
+			size = 2
+		case headerInt:
+			size = 4
+		case headerLong, headerTimestamp:
+			size = 8
+		case headerUUID:
+			size = 16
+		case headerBytes, headerString:
+			if len(b) < 2 {
+				return nil, errHeaders
+			}
+			size = 2 + int(binary.BigEndian.Uint16(b))
+		default:
+			return nil, fmt.Errorf("unknown event stream header type %d", typ)
+		}
+		if len(b) < size {
+			return nil, errHeaders
+		}
+		if typ == headerString {
+			headers[name] = string(b[2:size])
+		}
+		b = b[size:]
+	}
+	return headers, nil
+}
+
+// Writer serializes messages. Messages are flushed as they are written when
+// the destination is an http.Flusher.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// ContentType is the content type of the stream.
+func (w *Writer) ContentType() string {
+	return "application/vnd.amazon.eventstream"
+}
+
+// Write writes one message.
+func (w *Writer) Write(msg Message) error {
+	var headers bytes.Buffer
+	// sorted, so the encoding is deterministic
+	for _, name := range slices.Sorted(maps.Keys(msg.Headers)) {
+		value := msg.Headers[name]
+		if len(name) > 255 || len(value) > 65535 {
+			return fmt.Errorf("event stream header %s too long", name)
+		}
+		headers.WriteByte(byte(len(name)))
+		headers.WriteString(name)
+		headers.WriteByte(headerString)
+		headers.Write(binary.BigEndian.AppendUint16(nil, uint16(len(value))))
+		headers.WriteString(value)
+	}
+
+	totalLen := preludeLen + headers.Len() + len(msg.Payload) + crcLen
+	buf := make([]byte, 0, totalLen)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(totalLen))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(headers.Len()))
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	buf = append(buf, headers.Bytes()...)
+	buf = append(buf, msg.Payload...)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	if _, err := w.w.Write(buf); err != nil {
+		return err
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
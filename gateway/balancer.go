@@ -0,0 +1,300 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// Balancing strategies
+const (
+	// BalanceWeighted spreads requests in proportion to the target weights,
+	// in a smooth round robin.
+	BalanceWeighted = "weighted"
+	// BalanceLeastLatency sends each request to the target that answered
+	// fastest lately, by a moving average of the time to the response
+	// headers. Targets not measured yet go first.
+	BalanceLeastLatency = "least_latency"
+)
+
+// BalancerTarget is one upstream of a Balancer.
+type BalancerTarget struct {
+	// Name reports the target in Stats, Target when empty.
+	Name     string
+	Target   transformer.Provider
+	Upstream *Upstream
+	// AzureAPIVersion makes an OpenAI Target an Azure OpenAI resource, as
+	// Proxy.AzureAPIVersion.
+	AzureAPIVersion string
+	// Weight is the share of requests of the target under BalanceWeighted,
+	// 1 when zero.
+	Weight int
+}
+
+// Balancer spreads the requests of a Proxy across several upstream targets
+// of the same or different providers. Each request is served by a copy of
+// Proxy with the Target, Upstream and AzureAPIVersion of the chosen target,
+// so it is translated for that target, or forwarded untouched when the
+// target speaks the Source of Proxy:
+//
+//	&gateway.Balancer{
+//		Proxy: &gateway.Proxy{Source: transformer.ProviderOpenAI},
+//		Targets: []gateway.BalancerTarget{
+//			{Target: transformer.ProviderOpenAI, Upstream: openai, Weight: 3},
+//			{Target: transformer.ProviderClaude, Upstream: anthropic, Weight: 1},
+//		},
+//	}
+//
+// Targets failing FailureThreshold requests in a row, with a 5xx or 429
+// status, are left out for Cooldown; when every target is left out, all of
+// them are tried again.
+//
+// With Sticky, the turns of a conversation go to the target that served
+// its first one, preserving prompt caches and the IDs of the upstream, as
+// long as that target stays healthy.
+type Balancer struct {
+	Proxy   *Proxy
+	Targets []BalancerTarget
+	// Strategy is BalanceWeighted when empty.
+	Strategy string
+	// FailureThreshold is 3 when zero.
+	FailureThreshold int
+	// Cooldown is 30 seconds when zero.
+	Cooldown time.Duration
+	// Sticky keys the conversations to pin, e.g. StickyHeader or
+	// StickyHistory; nil pins none.
+	Sticky StickyKey
+	// StickyTTL forgets pins unused for that long, an hour when zero.
+	StickyTTL time.Duration
+
+	mu    sync.Mutex
+	stats []targetState
+	pins  map[string]*pin
+	swept time.Time
+}
+
+// pin is the target of a conversation.
+type pin struct {
+	target int
+	used   time.Time
+}
+
+// targetState is what a Balancer observed of a target.
+type targetState struct {
+	requests, failures int64
+	// consecutive counts the failures since the last success.
+	consecutive int
+	ejected     time.Time
+	// latency is the moving average of the time to the response headers,
+	// zero before the first response.
+	latency time.Duration
+	// current is the smooth round robin credit of the target.
+	current int
+}
+
+// TargetStats reports a target of a Balancer.
+type TargetStats struct {
+	Name      string  `json:"name"`
+	Requests  int64   `json:"requests"`
+	Failures  int64   `json:"failures"`
+	LatencyMS float64 `json:"latency_ms"`
+	Healthy   bool    `json:"healthy"`
+}
+
+// latencyWeight is the weight of the last response in the latency average.
+const latencyWeight = 0.2
+
+func (b *Balancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(b.Targets) == 0 {
+		writeError(w, b.Proxy.Source, http.StatusServiceUnavailable, transformer.ErrorTypeOverloaded, "no upstream targets")
+		return
+	}
+	key := ""
+	if b.Sticky != nil {
+		body, ok := readBody(w, r, b.Proxy.Source, orDefault(b.Proxy.MaxRequestBytes, DefaultMaxRequestBytes))
+		if !ok {
+			return
+		}
+		key = b.Sticky(r, body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	i := b.pick(key, time.Now())
+	target := b.Targets[i]
+	proxy := *b.Proxy
+	proxy.Target, proxy.Upstream, proxy.AzureAPIVersion = target.Target, target.Upstream, target.AzureAPIVersion
+
+	rec := &statusRecorder{ResponseWriter: w}
+	start := time.Now()
+	proxy.ServeHTTP(rec, r)
+	if rec.status != 0 {
+		b.observe(i, rec.status, rec.started.Sub(start), time.Now())
+	}
+}
+
+// pick returns the index of the target to serve the next request, the
+// target of its conversation when key is pinned to a healthy one.
+func (b *Balancer) pick(key string, now time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.stats) != len(b.Targets) {
+		b.stats = make([]targetState, len(b.Targets))
+	}
+	if key == "" {
+		return b.balance(now)
+	}
+
+	ttl := b.StickyTTL
+	if ttl == 0 {
+		ttl = time.Hour
+	}
+	if now.Sub(b.swept) > ttl {
+		for k, p := range b.pins {
+			if now.Sub(p.used) > ttl {
+				delete(b.pins, k)
+			}
+		}
+		b.swept = now
+	}
+	if p, ok := b.pins[key]; ok && p.target < len(b.Targets) && now.Sub(p.used) <= ttl && b.healthy(p.target, now) {
+		p.used = now
+		return p.target
+	}
+	i := b.balance(now)
+	if b.pins == nil {
+		b.pins = make(map[string]*pin)
+	}
+	b.pins[key] = &pin{target: i, used: now}
+	return i
+}
+
+// healthy reports whether target i is not left out after failures.
+func (b *Balancer) healthy(i int, now time.Time) bool {
+	return b.stats[i].consecutive < b.failureThreshold() || now.After(b.stats[i].ejected)
+}
+
+// balance picks a target by Strategy among the healthy ones.
+func (b *Balancer) balance(now time.Time) int {
+	healthy := make([]int, 0, len(b.Targets))
+	for i := range b.stats {
+		if b.healthy(i, now) {
+			healthy = append(healthy, i)
+		}
+	}
+	if len(healthy) == 0 {
+		for i := range b.Targets {
+			healthy = append(healthy, i)
+		}
+	}
+
+	best := healthy[0]
+	if b.Strategy == BalanceLeastLatency {
+		for _, i := range healthy[1:] {
+			if b.stats[i].latency < b.stats[best].latency {
+				best = i
+			}
+		}
+		return best
+	}
+	total := 0
+	for _, i := range healthy {
+		weight := max(b.Targets[i].Weight, 1)
+		total += weight
+		b.stats[i].current += weight
+		if b.stats[i].current > b.stats[best].current {
+			best = i
+		}
+	}
+	b.stats[best].current -= total
+	return best
+}
+
+// observe records the response of target i.
+func (b *Balancer) observe(i, status int, latency time.Duration, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &b.stats[i]
+	s.requests++
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		s.failures++
+		s.consecutive++
+		if s.consecutive >= b.failureThreshold() {
+			cooldown := b.Cooldown
+			if cooldown == 0 {
+				cooldown = 30 * time.Second
+			}
+			s.ejected = now.Add(cooldown)
+		}
+		return
+	}
+	s.consecutive = 0
+	if s.latency == 0 {
+		s.latency = latency
+	} else {
+		s.latency += time.Duration(latencyWeight * float64(latency-s.latency))
+	}
+}
+
+func (b *Balancer) failureThreshold() int {
+	if b.FailureThreshold == 0 {
+		return 3
+	}
+	return b.FailureThreshold
+}
+
+// Stats reports the requests, failures, latency and health of each target.
+func (b *Balancer) Stats() []TargetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	stats := make([]TargetStats, len(b.Targets))
+	for i, target := range b.Targets {
+		name := target.Name
+		if name == "" {
+			name = string(target.Target)
+		}
+		stats[i] = TargetStats{Name: name, Healthy: true}
+		if i < len(b.stats) {
+			s := b.stats[i]
+			stats[i].Requests, stats[i].Failures = s.requests, s.failures
+			stats[i].LatencyMS = float64(s.latency.Microseconds()) / 1000
+			stats[i].Healthy = b.healthy(i, now)
+		}
+	}
+	return stats
+}
+
+// statusRecorder notes the status of a response and when it was written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	started time.Time
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status, r.started = status, time.Now()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush flushes streamed events through to the client.
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/transformer"
+)
+
+// messagesAPIVersion is the anthropic-version sent upstream, legacy clients
+// send versions older than the Messages API.
+const messagesAPIVersion = claude.APIVersion
+
+// Complete serves the legacy Anthropic /v1/complete endpoint from a Messages
+// API upstream: prompts are upgraded to messages and responses and streams
+// are downgraded back to completions.
+func Complete(upstream *Upstream) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, transformer.ProviderClaude, http.StatusMethodNotAllowed, "invalid_request_error", "method %s not allowed", r.Method)
+			return
+		}
+		var req claude.ClaudeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeReadError(w, transformer.ProviderClaude, err)
+			return
+		}
+		if req.Prompt == "" {
+			writeError(w, transformer.ProviderClaude, http.StatusBadRequest, "invalid_request_error", "prompt: field required")
+			return
+		}
+		if req.MaxTokensToSample == 0 {
+			writeError(w, transformer.ProviderClaude, http.StatusBadRequest, "invalid_request_error", "max_tokens_to_sample: field required")
+			return
+		}
+		msgReq, err := transformer.UpgradeClaudeCompletion(&req)
+		if err != nil {
+			writeError(w, transformer.ProviderClaude, http.StatusBadRequest, "invalid_request_error", "%v", err)
+			return
+		}
+
+		header := r.Header.Clone()
+		header.Set("Anthropic-Version", messagesAPIVersion)
+		resp, err := upstream.post(r.Context(), header, "/v1/messages", msgReq)
+		if err != nil {
+			writeError(w, transformer.ProviderClaude, http.StatusBadGateway, "api_error", "upstream request failed: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			// Messages errors use the same envelope as completions
+			relayError(w, resp)
+			return
+		}
+
+		if !req.Stream {
+			var msgResp claude.ClaudeResponse
+			if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
+				writeError(w, transformer.ProviderClaude, http.StatusBadGateway, "api_error", "invalid upstream response: %v", err)
+				return
+			}
+			reportUsage(r.Context(), msgResp.Model, transformer.StreamUsageFromClaude(msgResp.Usage))
+			writeJSON(w, http.StatusOK, transformer.DowngradeClaudeMessage(&msgResp))
+			return
+		}
+
+		out := sse.NewWriter(w, transformer.ProviderClaude)
+		w.Header().Set("Content-Type", out.ContentType())
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		var stream transformer.ClaudeCompletionStream
+		var usage transformer.UsageTracker
+		model := msgReq.Model
+		defer func() { reportUsage(r.Context(), model, usage.Total()) }()
+		err = eachEvent(resp.Body, transformer.ProviderClaude, func(ev sse.Event) error {
+			var event claude.ClaudeResponse
+			if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+				return err
+			}
+			if u, ok := transformer.UsageOf(&event); ok {
+				usage.Observe(u, transformer.UsageCumulative)
+			}
+			if event.Message != nil && event.Message.Model != "" {
+				model = event.Message.Model
+			}
+			if completion := stream.Downgrade(&event); completion != nil {
+				return out.Write(completion)
+			}
+			return nil
+		})
+		if err != nil && r.Context().Err() == nil {
+			// Headers are sent, report in-band
+			out.Write(&claude.ClaudeResponse{
+				Type:  "error",
+				Error: &claude.ClaudeError{Type: "api_error", Message: err.Error()},
+			})
+		}
+	})
+}
@@ -0,0 +1,496 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// KeySource returns an API key.
+type KeySource func(ctx context.Context) (string, error)
+
+// StaticKey returns key.
+func StaticKey(key string) KeySource {
+	return func(context.Context) (string, error) {
+		return key, nil
+	}
+}
+
+// EnvKey reads the key from the environment variable name on each call.
+func EnvKey(name string) KeySource {
+	return func(context.Context) (string, error) {
+		if key := os.Getenv(name); key != "" {
+			return key, nil
+		}
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+}
+
+// APIKeySigner authenticates requests to the API of Provider with a key,
+// sent as x-api-key to Claude, x-goog-api-key to Gemini and Vertex AI
+// (express mode) and a bearer token to OpenAI.
+type APIKeySigner struct {
+	Provider transformer.Provider
+	Key      KeySource
+	// Azure sends the key of an OpenAI Provider as api-key, as Azure OpenAI
+	// expects.
+	Azure bool
+}
+
+func (s *APIKeySigner) Sign(req *http.Request, _ []byte) error {
+	key, err := s.Key(req.Context())
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Api-Key"} {
+		req.Header.Del(name)
+	}
+	switch s.Provider {
+	case transformer.ProviderClaude:
+		req.Header.Set("X-Api-Key", key)
+	case transformer.ProviderGemini, transformer.ProviderVertexGemini:
+		req.Header.Set("X-Goog-Api-Key", key)
+	case transformer.ProviderOpenAI:
+		if s.Azure {
+			req.Header.Set("Api-Key", key)
+			break
+		}
+		req.Header.Set("Authorization", "Bearer "+key)
+	default:
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return nil
+}
+
+// credentialProbeTimeout bounds requests to metadata endpoints, which do not
+// answer outside their cloud.
+const credentialProbeTimeout = 2 * time.Second
+
+var credentialClient = &http.Client{Timeout: credentialProbeTimeout}
+
+// AWSCredentialsSource returns AWS credentials.
+type AWSCredentialsSource func(ctx context.Context) (AWSCredentials, error)
+
+// StaticAWSCredentials returns creds.
+func StaticAWSCredentials(creds AWSCredentials) AWSCredentialsSource {
+	return func(context.Context) (AWSCredentials, error) {
+		return creds, nil
+	}
+}
+
+// EnvAWSCredentials reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+func EnvAWSCredentials(context.Context) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY is not set")
+	}
+	return creds, nil
+}
+
+// SharedAWSCredentials reads the profile AWS_PROFILE, default when unset,
+// from the shared credentials file, AWS_SHARED_CREDENTIALS_FILE or
+// ~/.aws/credentials.
+func SharedAWSCredentials(context.Context) (AWSCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer f.Close()
+	var creds AWSCredentials
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		name, value, _ := strings.Cut(line, "=")
+		switch strings.TrimSpace(name) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return AWSCredentials{}, err
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("profile %s not found in %s", profile, path)
+	}
+	return creds, nil
+}
+
+// awsMetadataCredentials is the credentials document of the container and
+// instance metadata endpoints.
+type awsMetadataCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (c *awsMetadataCredentials) credentials() AWSCredentials {
+	return AWSCredentials{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.Token, Expires: c.Expiration}
+}
+
+// ContainerAWSCredentials fetches the credentials of the ECS task or EKS pod
+// from AWS_CONTAINER_CREDENTIALS_FULL_URI or _RELATIVE_URI.
+func ContainerAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = "http://169.254.170.2" + relative
+	}
+	if endpoint == "" {
+		return AWSCredentials{}, errors.New("not running in a container with an AWS credentials endpoint")
+	}
+	header := http.Header{}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		header.Set("Authorization", token)
+	}
+	var doc awsMetadataCredentials
+	if err := getJSON(ctx, endpoint, header, &doc); err != nil {
+		return AWSCredentials{}, err
+	}
+	return doc.credentials(), nil
+}
+
+// InstanceAWSCredentials fetches the credentials of the EC2 instance role
+// with IMDSv2.
+func InstanceAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	const imds = "http://169.254.169.254/latest"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, imds+"/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	token, err := doText(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("imds token: %w", err)
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {token}}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, imds+"/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header = header
+	roles, err := doText(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("imds role: %w", err)
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+	if role == "" {
+		return AWSCredentials{}, errors.New("instance has no IAM role")
+	}
+	var doc awsMetadataCredentials
+	if err := getJSON(ctx, imds+"/meta-data/iam/security-credentials/"+url.PathEscape(role), header, &doc); err != nil {
+		return AWSCredentials{}, err
+	}
+	return doc.credentials(), nil
+}
+
+// AWSCredentialsChain returns the credentials of the first source that has
+// them.
+func AWSCredentialsChain(sources ...AWSCredentialsSource) AWSCredentialsSource {
+	return func(ctx context.Context) (AWSCredentials, error) {
+		var errs []error
+		for _, source := range sources {
+			creds, err := source(ctx)
+			if err == nil {
+				return creds, nil
+			}
+			errs = append(errs, err)
+		}
+		return AWSCredentials{}, fmt.Errorf("no AWS credentials found: %w", errors.Join(errs...))
+	}
+}
+
+// DefaultAWSCredentials resolves credentials like the AWS SDKs: environment,
+// shared credentials file, container endpoint, then instance role. Resolved
+// credentials are reused until shortly before they expire.
+func DefaultAWSCredentials() AWSCredentialsSource {
+	return CachedAWSCredentials(AWSCredentialsChain(EnvAWSCredentials, SharedAWSCredentials, ContainerAWSCredentials, InstanceAWSCredentials))
+}
+
+// CachedAWSCredentials reuses the credentials of source until shortly
+// before they expire, forever when they have no expiry.
+func CachedAWSCredentials(source AWSCredentialsSource) AWSCredentialsSource {
+	var mu sync.Mutex
+	var cached *AWSCredentials
+	return func(ctx context.Context) (AWSCredentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if cached != nil && (cached.Expires.IsZero() || time.Until(cached.Expires) > tokenRefreshMargin) {
+			return *cached, nil
+		}
+		creds, err := source(ctx)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		cached = &creds
+		return creds, nil
+	}
+}
+
+// TokenSource returns an OAuth access token and its expiry.
+type TokenSource func(ctx context.Context) (string, time.Time, error)
+
+// GCPScope is the OAuth scope of Vertex AI.
+const GCPScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// gcpCredentialsFile is an application default credentials file.
+type gcpCredentialsFile struct {
+	Type string `json:"type"`
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	// authorized_user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// GCPDefaultCredentials finds application default credentials like the
+// Google client libraries: the file named by GOOGLE_APPLICATION_CREDENTIALS,
+// the gcloud application-default login, then the metadata server of the
+// instance. Tokens are not cached, GCPOAuth reuses them.
+func GCPDefaultCredentials() TokenSource {
+	return func(ctx context.Context) (string, time.Time, error) {
+		path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+		if path == "" {
+			if dir, err := os.UserConfigDir(); err == nil {
+				wellKnown := filepath.Join(dir, "gcloud", "application_default_credentials.json")
+				if _, err := os.Stat(wellKnown); err == nil {
+					path = wellKnown
+				}
+			}
+		}
+		if path == "" {
+			return GCPMetadataToken(ctx)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		source, err := GCPCredentialsJSON(data, GCPScope)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("%s: %w", path, err)
+		}
+		return source(ctx)
+	}
+}
+
+// GCPCredentialsJSON returns the token source of a service_account or
+// authorized_user credentials file.
+func GCPCredentialsJSON(data []byte, scope string) (TokenSource, error) {
+	var file gcpCredentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid credentials file: %w", err)
+	}
+	switch file.Type {
+	case "service_account":
+		key, err := parseRSAKey(file.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		tokenURI := file.TokenURI
+		if tokenURI == "" {
+			tokenURI = "https://oauth2.googleapis.com/token"
+		}
+		return func(ctx context.Context) (string, time.Time, error) {
+			assertion, err := signJWT(key, file.PrivateKeyID, map[string]any{
+				"iss":   file.ClientEmail,
+				"scope": scope,
+				"aud":   tokenURI,
+			})
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			return exchangeToken(ctx, tokenURI, url.Values{
+				"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+				"assertion":  {assertion},
+			})
+		}, nil
+	case "authorized_user":
+		return func(ctx context.Context) (string, time.Time, error) {
+			return exchangeToken(ctx, "https://oauth2.googleapis.com/token", url.Values{
+				"grant_type":    {"refresh_token"},
+				"client_id":     {file.ClientID},
+				"client_secret": {file.ClientSecret},
+				"refresh_token": {file.RefreshToken},
+			})
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credentials type %q", file.Type)
+	}
+}
+
+// GCPMetadataToken fetches a token of the instance's service account from
+// the metadata server, GCE_METADATA_HOST when set.
+func GCPMetadataToken(ctx context.Context) (string, time.Time, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	var token oauthToken
+	err := getJSON(ctx, "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token",
+		http.Header{"Metadata-Flavor": {"Google"}}, &token)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("gcp metadata server: %w", err)
+	}
+	return token.AccessToken, token.expiry(), nil
+}
+
+type oauthToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (t *oauthToken) expiry() time.Time {
+	return time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+func exchangeToken(ctx context.Context, tokenURI string, form url.Values) (string, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("token exchange: %s: %s", resp.Status, body)
+	}
+	var token oauthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", time.Time{}, fmt.Errorf("token exchange: %w", err)
+	}
+	return token.AccessToken, token.expiry(), nil
+}
+
+func parseRSAKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("private_key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private_key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private_key is not an RSA key")
+	}
+	return key, nil
+}
+
+// signJWT returns an RS256 JWT of claims valid for an hour.
+func signJWT(key *rsa.PrivateKey, keyID string, claims map[string]any) (string, error) {
+	now := time.Now()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Hour).Unix()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func getJSON(ctx context.Context, endpoint string, header http.Header, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	body, err := doText(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), v)
+}
+
+// doText sends a metadata request and returns its body.
+func doText(req *http.Request) (string, error) {
+	resp, err := credentialClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// fanOut serves a request for call.choices choices from a Target returning
+// a single message: the request is translated once and sent that many times
+// concurrently, and the responses become the choices of one response, with
+// their usage summed. Any failure fails the request.
+func (p *Proxy) fanOut(ctx context.Context, w http.ResponseWriter, call proxyCall, body []byte) {
+	// tools renamed for Target get their names back in its responses
+	ctx, _ = transformer.WithToolNames(ctx)
+	req, model, path, err := p.translateRequest(ctx, call, body)
+	if err != nil {
+		writeError(w, p.Source, http.StatusBadRequest, transformer.ErrorTypeInvalidRequest, "%v", err)
+		return
+	}
+
+	// the first failure cancels the other requests and is reported alone
+	var first *transformer.UnifiedError
+	var once sync.Once
+	sendCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fail := func(failure *transformer.UnifiedError) {
+		once.Do(func() {
+			first = failure
+			cancel()
+		})
+	}
+	responses := make([][]byte, call.choices)
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, failure := p.post(sendCtx, path, req)
+			if failure != nil {
+				fail(failure)
+				return
+			}
+			defer resp.Body.Close()
+			limitResponse(resp, orDefault(p.MaxResponseBytes, DefaultMaxResponseBytes))
+			data, err := io.ReadAll(resp.Body)
+			switch {
+			case err != nil:
+				fail(&transformer.UnifiedError{Status: http.StatusBadGateway, Type: transformer.ErrorTypeAPI, Message: upstreamReadError(err)})
+			case resp.StatusCode != http.StatusOK:
+				fail(transformer.ErrorToUnified(p.Target, resp.StatusCode, data))
+			default:
+				responses[i] = data
+			}
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		writeUnifiedError(w, p.Source, first)
+		return
+	}
+
+	var merged *transformer.UnifiedResponse
+	var usage transformer.UsageTracker
+	for _, data := range responses {
+		u, reported, err := p.unifyResponse(ctx, bytes.NewReader(data))
+		if reported != nil {
+			usage.Observe(*reported, transformer.UsageIncremental)
+		}
+		if err != nil {
+			reportUsage(ctx, model, usage.Total())
+			writeError(w, p.Source, http.StatusBadGateway, transformer.ErrorTypeAPI, "%v", err)
+			return
+		}
+		if merged == nil {
+			merged = u
+			continue
+		}
+		merged.Alternatives = append(merged.Alternatives, transformer.UnifiedChoice{
+			Message:       u.Message,
+			FinishReason:  u.FinishReason,
+			ContentFilter: u.ContentFilter,
+		})
+		merged.Alternatives = append(merged.Alternatives, u.Alternatives...)
+	}
+	merged.Usage = usage.Total()
+	reportUsage(ctx, model, merged.Usage)
+	p.writeResponse(ctx, w, merged, model)
+}
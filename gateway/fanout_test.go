@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/transformer"
+)
+
+func TestFanOutChoicesArePostProcessed(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5",`+
+			`"content":[{"type":"text","text":"the key is secret-42"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":5}}`)
+	}))
+	defer upstream.Close()
+	proxy := &Proxy{
+		Source:        transformer.ProviderOpenAI,
+		Target:        transformer.ProviderClaude,
+		Upstream:      &Upstream{BaseURL: upstream.URL},
+		FanOutChoices: true,
+		Pipeline:      transformer.NewResponsePipeline(transformer.Redact(regexp.MustCompile(`secret-\d+`), "[redacted]")),
+	}
+
+	body := `{"model":"claude-sonnet-4-5","n":3,"messages":[{"role":"user","content":"key?"}]}`
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp openai.ChatCompletionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Choices) != 3 {
+		t.Fatalf("got %d choices, want 3", len(resp.Choices))
+	}
+	for i, choice := range resp.Choices {
+		if got := choice.Message.Content; got != "the key is [redacted]" {
+			t.Errorf("choice %d: got %q", i, got)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package gateway serves provider APIs over HTTP on top of other backends,
+// using the transformer package to translate requests, responses and streams.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/phosae/llms/eventstream"
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/transformer"
+)
+
+// Upstream is a provider endpoint the gateway forwards requests to.
+type Upstream struct {
+	// BaseURL is the root of the API, e.g. https://api.anthropic.com.
+	BaseURL string
+	// Header is set on every upstream request, e.g. the x-api-key.
+	Header http.Header
+	// Client sends the requests, http.DefaultClient when nil.
+	Client *http.Client
+	// Signer signs every request once its headers are set, e.g. AWSSigV4
+	// for Bedrock or GCPOAuth for Vertex. The credential of a Keyring's
+	// virtual key replaces it.
+	Signer Signer
+}
+
+// forwardedHeaders are copied from the client request when Header does not set them.
+var forwardedHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Anthropic-Version", "Anthropic-Beta"}
+
+func (u *Upstream) post(ctx context.Context, inbound http.Header, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(u.BaseURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, name := range forwardedHeaders {
+		if value := inbound.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	for name, values := range u.Header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	signer := u.Signer
+	if credential, ok := ctx.Value(credentialKey{}).(Signer); ok {
+		signer = credential
+	}
+	if signer != nil {
+		if err := signer.Sign(req, data); err != nil {
+			return nil, fmt.Errorf("sign upstream request: %w", err)
+		}
+	}
+
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// writeJSON writes v with status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the envelope of provider. typ is one of the
+// transformer.ErrorType constants, e.g. invalid_request_error.
+func writeError(w http.ResponseWriter, provider transformer.Provider, status int, typ, format string, args ...any) {
+	writeUnifiedError(w, provider, &transformer.UnifiedError{Status: status, Type: typ, Message: fmt.Sprintf(format, args...)})
+}
+
+// writeUnifiedError writes u in the envelope of provider, with its status.
+func writeUnifiedError(w http.ResponseWriter, provider transformer.Provider, u *transformer.UnifiedError) {
+	resp, err := transformer.ErrorFromUnified(provider, u)
+	if err != nil {
+		resp, _ = transformer.ErrorFromUnified(transformer.ProviderClaude, u)
+	}
+	if provider == transformer.ProviderBedrock {
+		w.Header().Set("X-Amzn-ErrorType", transformer.BedrockException(u.Type))
+	}
+	writeJSON(w, u.Status, resp)
+}
+
+// relayError copies a failed upstream response to the client.
+func relayError(w http.ResponseWriter, resp *http.Response) {
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// eachEvent calls fn for each event of the stream r of provider until it
+// ends. Bedrock streams are AWS event streams, their events have the
+// :event-type as Event, and exceptions end them with an *eventstream.Error.
+func eachEvent(r io.Reader, provider transformer.Provider, fn func(ev sse.Event) error) error {
+	if provider == transformer.ProviderBedrock {
+		for msg, err := range eventstream.NewReader(r).Messages() {
+			if err == nil {
+				err = msg.Err()
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(sse.Event{Event: msg.EventType(), Data: string(msg.Payload)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	events := sse.NewReader(r)
+	for {
+		ev, err := events.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+}
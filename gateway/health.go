@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/tokens"
+	"github.com/phosae/llms/transformer"
+)
+
+// Healthz serves liveness checks, e.g. /healthz of a Kubernetes pod: 200
+// while the process serves HTTP.
+func Healthz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// Probe checks that Upstream, speaking Provider, serves Model with the
+// cheapest call of its API: counting the tokens of a word for Claude and
+// Gemini, showing the model for Ollama and a one token completion for the
+// others.
+type Probe struct {
+	// Name reports the probe, Provider when empty.
+	Name     string
+	Provider transformer.Provider
+	Upstream *Upstream
+	Model    string
+}
+
+// ProbeStatus is the outcome of a Probe.
+type ProbeStatus struct {
+	OK        bool    `json:"ok"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness is the body of Readyz responses.
+type Readiness struct {
+	Ready     bool                   `json:"ready"`
+	Providers map[string]ProbeStatus `json:"providers,omitempty"`
+}
+
+// Readyz serves readiness checks, e.g. /readyz of a Kubernetes pod: 200
+// when every probe passes, 503 otherwise, with the status of each. Without
+// probes it is always ready.
+type Readyz struct {
+	Probes []Probe
+	// Timeout bounds each probe, 5 seconds when zero.
+	Timeout time.Duration
+	// CacheTTL reuses the last outcome for that long, so frequent checks do
+	// not hammer the upstreams. Zero probes on every check.
+	CacheTTL time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	last    Readiness
+}
+
+func (h *Readyz) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	readiness := h.Check(r.Context())
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, readiness)
+}
+
+// Check runs the probes concurrently, or returns the cached outcome.
+func (h *Readyz) Check(ctx context.Context) Readiness {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.CacheTTL > 0 && !h.checked.IsZero() && time.Since(h.checked) < h.CacheTTL {
+		return h.last
+	}
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	readiness := Readiness{Ready: true}
+	if len(h.Probes) > 0 {
+		readiness.Providers = make(map[string]ProbeStatus, len(h.Probes))
+	}
+	statuses := make([]ProbeStatus, len(h.Probes))
+	var wg sync.WaitGroup
+	for i, probe := range h.Probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := probe.run(ctx)
+			statuses[i] = ProbeStatus{OK: err == nil, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				statuses[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	for i, probe := range h.Probes {
+		name := probe.Name
+		if name == "" {
+			name = string(probe.Provider)
+		}
+		readiness.Providers[name] = statuses[i]
+		readiness.Ready = readiness.Ready && statuses[i].OK
+	}
+	h.checked, h.last = time.Now(), readiness
+	return readiness
+}
+
+// run sends the probe request, failing on any status but 200.
+func (p Probe) run(ctx context.Context) error {
+	header := http.Header{}
+	ping := []map[string]any{{"role": "user", "content": "ping"}}
+	geminiPing := map[string]any{"contents": []map[string]any{{"role": "user", "parts": []map[string]any{{"text": "ping"}}}}}
+	var path string
+	var body any
+	switch p.Provider {
+	case transformer.ProviderClaude:
+		header.Set("Anthropic-Version", messagesAPIVersion)
+		path, body = "/v1/messages/count_tokens", map[string]any{"model": p.Model, "messages": ping}
+	case transformer.ProviderGemini, transformer.ProviderVertexGemini:
+		path, body = tokens.GeminiCountTokensPath(p.Provider, p.Model), geminiPing
+	case transformer.ProviderBedrock:
+		path = "/model/" + url.PathEscape(p.Model) + "/converse"
+		body = map[string]any{
+			"messages":        []map[string]any{{"role": "user", "content": []map[string]any{{"text": "ping"}}}},
+			"inferenceConfig": map[string]any{"maxTokens": 1},
+		}
+	case transformer.ProviderOllama:
+		path, body = "/api/show", map[string]any{"model": p.Model}
+	case transformer.ProviderOpenAI:
+		path, body = "/v1/chat/completions", map[string]any{"model": p.Model, "messages": ping, "max_tokens": 1}
+	default:
+		return fmt.Errorf("unsupported provider %s", p.Provider)
+	}
+	resp, err := p.Upstream.post(ctx, header, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return fmt.Errorf("status %d: %w", resp.StatusCode, transformer.ErrorToUnified(p.Provider, resp.StatusCode, data))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/transformer"
+)
+
+// Hedge is the secondary upstream of a Proxy. When Upstream has not started
+// answering after Delay, the request is translated for Target and sent to
+// the secondary too; the client gets whichever response starts first, its
+// first content delta for streams, and the other request is canceled. Stream
+// openings such as Claude's message_start and pings do not count. Requests
+// forwarded untouched, when Source and Target of the Proxy match, are not
+// hedged.
+type Hedge struct {
+	Target   transformer.Provider
+	Upstream *Upstream
+	// AzureAPIVersion makes an OpenAI Target an Azure OpenAI resource, as
+	// Proxy.AzureAPIVersion.
+	AzureAPIVersion string
+	Delay           time.Duration
+}
+
+// attempt is a request to one upstream of a hedged Proxy.
+type attempt struct {
+	proxy   *Proxy
+	ctx     context.Context
+	cancel  context.CancelFunc
+	resp    *http.Response
+	model   string
+	failure *transformer.UnifiedError
+}
+
+// started reports whether the upstream answered with the start of a
+// successful response.
+func (a *attempt) started() bool {
+	return a.failure == nil && a.resp.StatusCode == http.StatusOK
+}
+
+// close cancels the request and releases its response.
+func (a *attempt) close() {
+	a.cancel()
+	if a.resp != nil {
+		a.resp.Body.Close()
+	}
+}
+
+// try sends the request with p and waits for the first byte of a successful
+// response, or the first content delta of a stream. cancel cancels ctx.
+func (p *Proxy) try(ctx context.Context, cancel context.CancelFunc, call proxyCall, body []byte) *attempt {
+	// tools renamed for Target get their names back in its responses
+	ctx, _ = transformer.WithToolNames(ctx)
+	a := &attempt{proxy: p, ctx: ctx, cancel: cancel}
+	a.resp, a.model, a.failure = p.send(ctx, call, body)
+	if a.failure != nil || a.resp.StatusCode != http.StatusOK {
+		return a
+	}
+	var read bytes.Buffer
+	var err error
+	if call.stream {
+		err = p.firstContent(io.TeeReader(a.resp.Body, &read))
+	} else {
+		_, err = io.CopyN(&read, a.resp.Body, 1)
+	}
+	if err != nil && err != io.EOF {
+		a.resp.Body.Close()
+		a.resp, a.failure = nil, &transformer.UnifiedError{Status: http.StatusBadGateway, Type: transformer.ErrorTypeAPI, Message: "read upstream response: " + err.Error()}
+		return a
+	}
+	a.resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(&read, a.resp.Body), a.resp.Body}
+	return a
+}
+
+// firstContent reads the stream of Target from r up to its first content
+// delta, or to its end.
+func (p *Proxy) firstContent(r io.Reader) error {
+	// a bare context: reading ahead reports no warnings, the stream is
+	// translated again when served
+	ctx := context.Background()
+	for event, err := range sse.NewStream(r, p.Target, transformer.ProviderOpenAI).Events(ctx) {
+		if err != nil {
+			return err
+		}
+		if chunk, err := transformer.ToUnifiedChunk(ctx, transformer.ProviderOpenAI, event); err == nil && chunk.HasContent() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// hedge serves a translated request from the first of Upstream and the
+// Hedge upstream to start answering. When both fail, the client gets the
+// failure of Upstream.
+func (p *Proxy) hedge(ctx context.Context, w http.ResponseWriter, call proxyCall, body []byte) {
+	secondary := *p
+	secondary.Target, secondary.Upstream, secondary.AzureAPIVersion, secondary.Hedge = p.Hedge.Target, p.Hedge.Upstream, p.Hedge.AzureAPIVersion, nil
+	attempts := make(chan *attempt, 2)
+	cancels := map[*Proxy]context.CancelFunc{}
+	launch := func(p *Proxy) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancels[p] = cancel
+		go func() { attempts <- p.try(ctx, cancel, call, body) }()
+	}
+	launch(p)
+	pending := 1
+	timer := time.NewTimer(p.Hedge.Delay)
+	defer timer.Stop()
+
+	var failed *attempt
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			launch(&secondary)
+			pending++
+		case a := <-attempts:
+			pending--
+			if !a.started() {
+				if failed == nil || a.proxy == p {
+					failed, a = a, failed
+				}
+				if a != nil {
+					a.close()
+				}
+				if len(cancels) == 1 {
+					// the primary failed before the hedge was sent
+					pending = 0
+				}
+				continue
+			}
+			if failed != nil {
+				failed.close()
+			}
+			// the loser is canceled now and released once it returns
+			for proxy, cancel := range cancels {
+				if proxy != a.proxy {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for range pending {
+					(<-attempts).close()
+				}
+			}(pending)
+			defer a.close()
+			a.proxy.serve(a.ctx, w, a.resp, call, a.model)
+			return
+		}
+	}
+	defer failed.close()
+	if failed.failure != nil {
+		writeUnifiedError(w, p.Source, failed.failure)
+		return
+	}
+	failed.proxy.serve(failed.ctx, w, failed.resp, call, failed.model)
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+func TestHedgeWaitsForTheFirstContentDelta(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// the primary opens its stream and pings at once, but sends its text late
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-sonnet-4-5\",\"content\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":1}}}\n\n")
+		io.WriteString(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"fast\"}}]}\n\n")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer secondary.Close()
+
+	proxy := &Proxy{
+		Source:   transformer.ProviderGemini,
+		Target:   transformer.ProviderClaude,
+		Upstream: &Upstream{BaseURL: primary.URL},
+		Hedge:    &Hedge{Target: transformer.ProviderOpenAI, Upstream: &Upstream{BaseURL: secondary.URL}, Delay: 20 * time.Millisecond},
+	}
+	body := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1beta/models/claude-sonnet-4-5:streamGenerateContent?alt=sse", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"text":"fast"`) {
+		t.Errorf("got %s, want the stream of the hedge", rec.Body)
+	}
+}
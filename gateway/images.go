@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/transformer"
+)
+
+// Images serves OpenAI's /v1/images/generations from upstream speaking
+// target, OpenAI or Gemini, whose Imagen models get the model of the
+// request in the path, models/{model}:predict. Requests and responses
+// translate through the unified image model; opts apply to every request,
+// e.g. the Model to draw with.
+func Images(target transformer.Provider, upstream *Upstream, opts transformer.Options) http.Handler {
+	registry := transformer.NewTransformationRegistry()
+	registry.RegisterImages(transformer.NewOpenAITransformer())
+	registry.RegisterImages(transformer.NewGeminiTransformer())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, transformer.ProviderOpenAI, http.StatusMethodNotAllowed, transformer.ErrorTypeInvalidRequest, "method %s not allowed", r.Method)
+			return
+		}
+		body, ok := readBody(w, r, transformer.ProviderOpenAI, DefaultMaxRequestBytes)
+		if !ok {
+			return
+		}
+		var req openai.ImageRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, transformer.ProviderOpenAI, http.StatusBadRequest, transformer.ErrorTypeInvalidRequest, "invalid request body: %v", err)
+			return
+		}
+		ctx := transformer.WithOptions(r.Context(), opts)
+		dst, err := transformer.NewImagesPayload(target, transformer.TransformerTypeRequest)
+		if err == nil {
+			err = registry.TransformImages(ctx, transformer.ProviderOpenAI, target, transformer.TransformerTypeRequest, &req, dst)
+		}
+		if err != nil {
+			writeError(w, transformer.ProviderOpenAI, http.StatusBadRequest, transformer.ErrorTypeInvalidRequest, "%v", err)
+			return
+		}
+		model, path := req.Model, "/v1/images/generations"
+		if opts.Model != "" {
+			model = opts.Model
+		}
+		if _, ok := dst.(*gemini.ImagenRequest); ok {
+			// Imagen requests carry no model, it goes in the path
+			model = transformer.TargetModel(ctx, transformer.ProviderGemini, req.Model)
+			path = "/v1beta/models/" + url.PathEscape(model) + ":predict"
+		}
+
+		resp, err := upstream.post(ctx, r.Header, path, dst)
+		if err != nil {
+			writeError(w, transformer.ProviderOpenAI, http.StatusBadGateway, transformer.ErrorTypeAPI, "upstream request failed: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		limitResponse(resp, DefaultMaxResponseBytes)
+		if resp.StatusCode != http.StatusOK {
+			data, _ := io.ReadAll(resp.Body)
+			writeUnifiedError(w, transformer.ProviderOpenAI, transformer.ErrorToUnified(target, resp.StatusCode, data))
+			return
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			writeError(w, transformer.ProviderOpenAI, http.StatusBadGateway, transformer.ErrorTypeAPI, "%s", upstreamReadError(err))
+			return
+		}
+		upstreamResp, _ := transformer.NewImagesPayload(target, transformer.TransformerTypeResponse)
+		if err := json.Unmarshal(data, upstreamResp); err != nil {
+			writeError(w, transformer.ProviderOpenAI, http.StatusBadGateway, transformer.ErrorTypeAPI, "invalid upstream response: %v", err)
+			return
+		}
+		var out openai.ImageResponse
+		if err := registry.TransformImages(ctx, target, transformer.ProviderOpenAI, transformer.TransformerTypeResponse, upstreamResp, &out); err != nil {
+			writeError(w, transformer.ProviderOpenAI, http.StatusBadGateway, transformer.ErrorTypeAPI, "%v", err)
+			return
+		}
+		if out.Usage != nil {
+			reportUsage(ctx, model, transformer.StreamUsage{InputTokens: out.Usage.InputTokens, OutputTokens: out.Usage.OutputTokens})
+		}
+		writeJSON(w, http.StatusOK, &out)
+	})
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/transformer"
+)
+
+// VirtualKey is what a virtual API key issued to a client grants: the
+// upstream provider serving it, the credential of the upstream account,
+// the models it may use and its quota.
+type VirtualKey struct {
+	Provider transformer.Provider
+	// Credential signs the upstream requests of the key in place of the
+	// Signer of the route's Upstream, e.g. an APIKeySigner of the account.
+	Credential Signer
+	// Models are the models the key may request, path.Match patterns such
+	// as claude-*; empty allows any.
+	Models []string
+	// Quota limits the key when the Keyring has a Store.
+	Quota Quota
+}
+
+// Keyring fronts several upstream accounts with virtual API keys: each
+// request is served by the route of its key's provider, signed with the
+// key's credential. The virtual key itself never reaches an upstream.
+type Keyring struct {
+	// Lookup returns the grant of a virtual key, false to reject the key.
+	Lookup func(key string) (VirtualKey, bool)
+	// Store enforces the quotas of the keys, nil for none.
+	Store QuotaStore
+	// Pricing prices usage for Cost limits and accounting.
+	Pricing *config.Store
+	// MaxRequestBytes limits request bodies, as Proxy.MaxRequestBytes.
+	MaxRequestBytes int64
+}
+
+// Handler serves the API of source, e.g. OpenAI chat completions, from the
+// routes of the providers of the keys, typically Proxies from source to
+// each provider:
+//
+//	keyring.Handler(transformer.ProviderOpenAI, map[transformer.Provider]http.Handler{
+//		transformer.ProviderOpenAI: &gateway.Proxy{Source: transformer.ProviderOpenAI, Target: transformer.ProviderOpenAI, Upstream: openai},
+//		transformer.ProviderClaude: &gateway.Proxy{Source: transformer.ProviderOpenAI, Target: transformer.ProviderClaude, Upstream: anthropic},
+//	})
+func (k *Keyring) Handler(source transformer.Provider, routes map[transformer.Provider]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := APIKey(r)
+		grant, ok := k.Lookup(key)
+		if key == "" || !ok {
+			writeError(w, source, http.StatusUnauthorized, transformer.ErrorTypeAuthentication, "invalid API key")
+			return
+		}
+		route, ok := routes[grant.Provider]
+		if !ok {
+			writeError(w, source, http.StatusInternalServerError, transformer.ErrorTypeAPI, "no route to provider %s", grant.Provider)
+			return
+		}
+
+		body, ok := readBody(w, r, source, orDefault(k.MaxRequestBytes, DefaultMaxRequestBytes))
+		if !ok {
+			return
+		}
+		if len(grant.Models) > 0 {
+			call, err := (&Proxy{Source: source}).parseCall(r, body)
+			if err != nil {
+				writeError(w, source, http.StatusBadRequest, transformer.ErrorTypeInvalidRequest, "%v", err)
+				return
+			}
+			if !modelAllowed(grant.Models, call.model) {
+				writeError(w, source, http.StatusForbidden, transformer.ErrorTypePermission, "model %s is not allowed for this API key", call.model)
+				return
+			}
+		}
+
+		r = r.Clone(withCredential(r.Context(), grant.Credential))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		for _, name := range []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Api-Key"} {
+			r.Header.Del(name)
+		}
+		if query := r.URL.Query(); query.Has("key") {
+			query.Del("key")
+			r.URL.RawQuery = query.Encode()
+		}
+
+		if k.Store == nil {
+			route.ServeHTTP(w, r)
+			return
+		}
+		quotas := &Quotas{
+			Store:   k.Store,
+			Quota:   func(string) (Quota, bool) { return grant.Quota, true },
+			Key:     func(*http.Request) string { return key },
+			Pricing: k.Pricing,
+		}
+		quotas.Wrap(source, route).ServeHTTP(w, r)
+	})
+}
+
+// modelAllowed reports whether model matches one of patterns.
+func modelAllowed(patterns []string, model string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type credentialKey struct{}
+
+// withCredential returns a context whose upstream requests are signed with
+// signer, unless it is nil.
+func withCredential(ctx context.Context, signer Signer) context.Context {
+	if signer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialKey{}, signer)
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// Body limits of a Proxy when its own are zero. Claude accepts requests of
+// up to 32 MB, inline images and documents included.
+const (
+	DefaultMaxRequestBytes  = 32 << 20
+	DefaultMaxResponseBytes = 64 << 20
+)
+
+// LimitRequestBodies rejects requests whose body exceeds limit bytes before
+// they reach next, which serves the API of provider, with a 413 in its error
+// format. Bodies of unknown length are cut at limit as next reads them; next
+// reports the failure when it reads with readBody or checks writeReadError.
+func LimitRequestBodies(provider transformer.Provider, limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			writeError(w, provider, http.StatusRequestEntityTooLarge, transformer.ErrorTypeRequestTooLarge, "request body exceeds %d bytes", limit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readBody reads the body of r in chunks, at most limit bytes unless limit
+// is negative. It reports failures to the client in the error format of
+// provider and returns false.
+func readBody(w http.ResponseWriter, r *http.Request, provider transformer.Provider, limit int64) ([]byte, bool) {
+	if limit >= 0 {
+		if r.ContentLength > limit {
+			writeError(w, provider, http.StatusRequestEntityTooLarge, transformer.ErrorTypeRequestTooLarge, "request body exceeds %d bytes", limit)
+			return nil, false
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	var buf bytes.Buffer
+	if r.ContentLength > 0 {
+		buf.Grow(int(r.ContentLength))
+	}
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		writeReadError(w, provider, err)
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// writeReadError reports a failure to read or decode a request body: a 413
+// when it exceeds a limit, a 400 otherwise.
+func writeReadError(w http.ResponseWriter, provider transformer.Provider, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, provider, http.StatusRequestEntityTooLarge, transformer.ErrorTypeRequestTooLarge, "request body exceeds %d bytes", tooLarge.Limit)
+		return
+	}
+	writeError(w, provider, http.StatusBadRequest, transformer.ErrorTypeInvalidRequest, "invalid request body: %v", err)
+}
+
+// limitResponse cuts the body of resp at limit bytes unless limit is
+// negative, so reading past it fails with an *http.MaxBytesError.
+func limitResponse(resp *http.Response, limit int64) {
+	if limit >= 0 {
+		resp.Body = http.MaxBytesReader(nil, resp.Body, limit)
+	}
+}
+
+// upstreamReadError describes a failure to read an upstream response.
+func upstreamReadError(err error) string {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Sprintf("upstream response exceeds %d bytes", tooLarge.Limit)
+	}
+	return fmt.Sprintf("invalid upstream response: %v", err)
+}
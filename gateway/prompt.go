@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/transformer"
+)
+
+// PromptTools serves Anthropic's experimental prompt tools,
+// /v1/experimental/generate_prompt, improve_prompt and templatize_prompt,
+// from upstream: requests are checked and forwarded untouched with the
+// prompt tools beta, and responses relayed as they are.
+// transformer.PromptTemplateToUnified turns the prompts they return into
+// requests of other providers.
+func PromptTools(upstream *Upstream) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, transformer.ProviderClaude, http.StatusMethodNotAllowed, transformer.ErrorTypeInvalidRequest, "method %s not allowed", r.Method)
+			return
+		}
+		body, ok := readBody(w, r, transformer.ProviderClaude, DefaultMaxRequestBytes)
+		if !ok {
+			return
+		}
+		model, problem := checkPromptToolsRequest(r.URL.Path, body)
+		if problem != "" {
+			writeError(w, transformer.ProviderClaude, http.StatusBadRequest, transformer.ErrorTypeInvalidRequest, "%s", problem)
+			return
+		}
+
+		header := r.Header.Clone()
+		if header.Get("Anthropic-Version") == "" {
+			header.Set("Anthropic-Version", messagesAPIVersion)
+		}
+		if beta := header.Get("Anthropic-Beta"); !strings.Contains(beta, claude.PromptToolsBeta) {
+			header.Set("Anthropic-Beta", strings.TrimPrefix(beta+","+claude.PromptToolsBeta, ","))
+		}
+		resp, err := upstream.post(r.Context(), header, r.URL.Path, json.RawMessage(body))
+		if err != nil {
+			writeError(w, transformer.ProviderClaude, http.StatusBadGateway, transformer.ErrorTypeAPI, "upstream request failed: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		limitResponse(resp, DefaultMaxResponseBytes)
+		if resp.StatusCode != http.StatusOK {
+			relayError(w, resp)
+			return
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			writeError(w, transformer.ProviderClaude, http.StatusBadGateway, transformer.ErrorTypeAPI, "%s", upstreamReadError(err))
+			return
+		}
+		var prompt claude.PromptToolsResponse
+		if json.Unmarshal(data, &prompt) == nil {
+			var usage transformer.UsageTracker
+			for _, u := range prompt.Usage {
+				usage.Observe(transformer.StreamUsageFromClaude(&u), transformer.UsageIncremental)
+			}
+			reportUsage(r.Context(), model, usage.Total())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// checkPromptToolsRequest checks a request of the prompt tool at path,
+// returning its target model and what is wrong with it, "" when nothing.
+func checkPromptToolsRequest(path string, body []byte) (string, string) {
+	switch path[strings.LastIndex(path, "/")+1:] {
+	case "generate_prompt":
+		var req claude.GeneratePromptRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", "invalid request body: " + err.Error()
+		}
+		if req.Task == "" {
+			return "", "task: field required"
+		}
+		return req.TargetModel, ""
+	case "improve_prompt":
+		var req claude.ImprovePromptRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", "invalid request body: " + err.Error()
+		}
+		if len(req.Messages) == 0 {
+			return "", "messages: field required"
+		}
+		return req.TargetModel, ""
+	case "templatize_prompt":
+		var req claude.TemplatizePromptRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", "invalid request body: " + err.Error()
+		}
+		if len(req.Messages) == 0 {
+			return "", "messages: field required"
+		}
+		return "", ""
+	default:
+		return "", "unknown prompt tool " + path
+	}
+}
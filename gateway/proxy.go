@@ -0,0 +1,727 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/eventstream"
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/tokens"
+	"github.com/phosae/llms/transformer"
+)
+
+// Proxy serves the API of Source from an upstream speaking Target,
+// translating requests, responses, streams and errors between the two, e.g.
+// OpenAI clients of Claude:
+//
+//	http.Handle("/v1/chat/completions", &gateway.Proxy{
+//		Source:   transformer.ProviderOpenAI,
+//		Target:   transformer.ProviderClaude,
+//		Upstream: &gateway.Upstream{BaseURL: "https://api.anthropic.com", Signer: signer},
+//	})
+//
+// Gemini requests carry the model and the streaming mode in the path, so a
+// Proxy with Source Gemini serves /v1beta/models/{model}:generateContent
+// and :streamGenerateContent, and with Source Vertex AI Gemini the same
+// methods under .../publishers/google/models/{model}. For a Vertex AI Target
+// the BaseURL of Upstream includes the project and location, e.g.
+// https://us-central1-aiplatform.googleapis.com/v1/projects/{project}/locations/us-central1.
+// When Source and Target match, payloads are forwarded untouched, except
+// that requests are normalized when Options.Normalize is set.
+//
+// Ollama clients and upstreams speak /api/chat, which streams unless the
+// request sets stream to false.
+//
+// Bedrock clients and upstreams speak the Converse API, naming the model in
+// the path: /model/{modelId}/converse, and /model/{modelId}/converse-stream,
+// which streams AWS event streams. Bedrock upstreams are signed with
+// AWSSigV4, their BaseURL is the runtime endpoint of a region, e.g.
+// https://bedrock-runtime.us-east-1.amazonaws.com.
+//
+// Azure OpenAI clients are OpenAI clients naming the deployment in the path,
+// /openai/deployments/{deployment}/chat/completions; for an Azure OpenAI
+// upstream set AzureAPIVersion.
+type Proxy struct {
+	Source   transformer.Provider
+	Target   transformer.Provider
+	Upstream *Upstream
+	// Config maps models and selects presets for the translation, nil for none.
+	Config *config.Store
+	// Options apply to every translated request.
+	Options transformer.Options
+	// AzureAPIVersion makes an OpenAI Target an Azure OpenAI resource served
+	// with this api-version, e.g. 2024-10-21. Requests go to the deployment
+	// named by Options.Model or the config mappings of provider azure.
+	AzureAPIVersion string
+	// StreamLog, when set, receives every event streamed to clients as
+	// newline delimited JSON sse.LogRecords. It must be safe for concurrent
+	// use, records of concurrent streams interleave.
+	StreamLog io.Writer
+	// MaxRequestBytes limits client request bodies, larger ones get a 413.
+	// Zero means DefaultMaxRequestBytes, a negative value no limit.
+	MaxRequestBytes int64
+	// MaxResponseBytes limits the upstream responses read whole, all but
+	// streams, as MaxRequestBytes with DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+	// Hedge, when set, races a secondary upstream against Upstream for
+	// translated requests.
+	Hedge *Hedge
+	// FanOutChoices serves requests for several choices, n of OpenAI or
+	// candidateCount of Gemini, from a Target returning a single message
+	// with one upstream request per choice. Otherwise such a Target gets a
+	// request for one choice, or the request fails with Options.Strict.
+	// Streams are not fanned out.
+	FanOutChoices bool
+	// Resume, when set, keeps streams for clients to resume after losing
+	// their connection, and serves the requests resuming them.
+	Resume *Resumer
+	// Pipeline, when set, post-processes responses before they reach the
+	// client, each choice of fanned out requests included. Streams are
+	// processed for OpenAI, Claude and Gemini clients.
+	Pipeline *transformer.ResponsePipeline
+	// MaxInputTokens rejects requests whose input, as tokens.CountUnified
+	// counts it for Target, exceeds this many tokens, with a 400 before they
+	// are sent upstream. Zero means no limit.
+	MaxInputTokens int
+}
+
+// orDefault returns limit, or def when limit is zero.
+func orDefault(limit, def int64) int64 {
+	if limit == 0 {
+		return def
+	}
+	return limit
+}
+
+// proxyCall is what the proxy needs to know about an inbound request.
+type proxyCall struct {
+	model  string
+	stream bool
+	// array frames a Gemini stream as a JSON array instead of events.
+	array bool
+	// includeUsage is the stream_options.include_usage of an OpenAI client.
+	includeUsage bool
+	// choices is the number of choices requested, fanOut whether they are
+	// requested one by one.
+	choices int
+	fanOut  bool
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.Resume != nil && r.Header.Get(ResumeTokenHeader) != "" {
+		p.Resume.serve(w, r, p.Source)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, p.Source, http.StatusMethodNotAllowed, "invalid_request_error", "method %s not allowed", r.Method)
+		return
+	}
+	body, ok := readBody(w, r, p.Source, orDefault(p.MaxRequestBytes, DefaultMaxRequestBytes))
+	if !ok {
+		return
+	}
+	call, err := p.parseCall(r, body)
+	if err != nil {
+		writeError(w, p.Source, http.StatusBadRequest, "invalid_request_error", "%v", err)
+		return
+	}
+
+	ctx := p.context(r.Context())
+	if p.Resume != nil && call.stream {
+		// resumable streams outlive the connection of the client
+		ctx = context.WithoutCancel(ctx)
+	}
+	if p.Source == p.Target {
+		if transformer.OptionsFromContext(ctx).Normalize != transformer.NormalizeNone {
+			if body, err = p.normalizeRequest(ctx, body); err != nil {
+				writeError(w, p.Source, http.StatusBadRequest, "invalid_request_error", "%v", err)
+				return
+			}
+		}
+		p.forward(ctx, w, r, call, body)
+		return
+	}
+	if capabilities, _ := transformer.Capabilities(p.Target); p.FanOutChoices && call.choices > 1 && !call.stream && !capabilities.MultipleChoices {
+		call.fanOut = true
+		p.fanOut(ctx, w, call, body)
+		return
+	}
+	if p.Hedge != nil {
+		p.hedge(ctx, w, call, body)
+		return
+	}
+	// tools renamed for Target get their names back in its responses
+	ctx, _ = transformer.WithToolNames(ctx)
+
+	resp, model, failure := p.send(ctx, call, body)
+	if failure != nil {
+		writeUnifiedError(w, p.Source, failure)
+		return
+	}
+	defer resp.Body.Close()
+	p.serve(ctx, w, resp, call, model)
+}
+
+// context returns ctx with the config and options of the proxy.
+func (p *Proxy) context(ctx context.Context) context.Context {
+	if p.Config != nil {
+		ctx = config.NewContext(ctx, p.Config.Current())
+	}
+	opts := p.Options
+	opts.Azure = opts.Azure || p.AzureAPIVersion != ""
+	return transformer.WithOptions(ctx, opts)
+}
+
+// Do sends a unified request to Target, without streaming, and returns the
+// unified response with its usage, for Go clients of any provider. Failed
+// upstream responses return a *transformer.UnifiedError.
+func (p *Proxy) Do(ctx context.Context, u *transformer.UnifiedRequest) (*transformer.UnifiedResponse, transformer.StreamUsage, error) {
+	// tools renamed for Target get their names back in its responses
+	ctx, _ = transformer.WithToolNames(p.context(ctx))
+	single := *u
+	single.Stream = false
+	req, model, path, err := p.targetRequest(ctx, &single)
+	if err != nil {
+		return nil, transformer.StreamUsage{}, err
+	}
+	resp, failure := p.post(ctx, path, req)
+	if failure != nil {
+		return nil, transformer.StreamUsage{}, failure
+	}
+	defer resp.Body.Close()
+	limitResponse(resp, orDefault(p.MaxResponseBytes, DefaultMaxResponseBytes))
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, transformer.StreamUsage{}, transformer.ErrorToUnified(p.Target, resp.StatusCode, data)
+	}
+	out, reported, err := p.unifyResponse(ctx, resp.Body)
+	var usage transformer.StreamUsage
+	if reported != nil {
+		usage = *reported
+		reportUsage(ctx, model, usage)
+	}
+	if err != nil {
+		return nil, usage, err
+	}
+	if out.Model == "" {
+		out.Model = model
+	}
+	if p.Pipeline != nil {
+		p.Pipeline.ProcessUnified(out, "")
+	}
+	return out, usage, nil
+}
+
+// send translates the client request for Target and posts it upstream,
+// returning the response and the model it was sent to.
+func (p *Proxy) send(ctx context.Context, call proxyCall, body []byte) (*http.Response, string, *transformer.UnifiedError) {
+	req, model, path, err := p.translateRequest(ctx, call, body)
+	if err != nil {
+		return nil, "", &transformer.UnifiedError{Status: http.StatusBadRequest, Type: transformer.ErrorTypeInvalidRequest, Message: err.Error()}
+	}
+	resp, failure := p.post(ctx, path, req)
+	return resp, model, failure
+}
+
+// post sends a request translated for Target upstream.
+func (p *Proxy) post(ctx context.Context, path string, req any) (*http.Response, *transformer.UnifiedError) {
+	header := http.Header{}
+	if p.Target == transformer.ProviderClaude {
+		header.Set("Anthropic-Version", messagesAPIVersion)
+		if req, ok := req.(*claude.ClaudeRequest); ok {
+			if betas := req.Betas(); len(betas) > 0 {
+				header.Set("Anthropic-Beta", strings.Join(betas, ","))
+			}
+		}
+	}
+	resp, err := p.Upstream.post(ctx, header, path, req)
+	if err != nil {
+		return nil, &transformer.UnifiedError{Status: http.StatusBadGateway, Type: transformer.ErrorTypeAPI, Message: "upstream request failed: " + err.Error()}
+	}
+	return resp, nil
+}
+
+// serve answers the client from the upstream response to send.
+func (p *Proxy) serve(ctx context.Context, w http.ResponseWriter, resp *http.Response, call proxyCall, model string) {
+	if resp.StatusCode != http.StatusOK || !call.stream {
+		limitResponse(resp, orDefault(p.MaxResponseBytes, DefaultMaxResponseBytes))
+	}
+	if resp.StatusCode != http.StatusOK {
+		p.translateError(w, resp)
+		return
+	}
+	if call.stream {
+		p.stream(ctx, w, resp, call, model)
+		return
+	}
+	p.translateResponse(ctx, w, resp, model)
+}
+
+func (p *Proxy) parseCall(r *http.Request, body []byte) (proxyCall, error) {
+	var call proxyCall
+	if p.Source == transformer.ProviderGemini || p.Source == transformer.ProviderVertexGemini {
+		// /v1beta/models/{model}:{action} or, on Vertex,
+		// /v1/projects/{project}/locations/{location}/publishers/google/models/{model}:{action}
+		_, resource, _ := strings.Cut(r.URL.Path, "/models/")
+		model, action, ok := strings.Cut(resource, ":")
+		if !ok || model == "" {
+			return call, fmt.Errorf("unsupported path %s", r.URL.Path)
+		}
+		switch action {
+		case "generateContent":
+		case "streamGenerateContent":
+			call.stream = true
+			call.array = r.URL.Query().Get("alt") != "sse"
+		default:
+			return call, fmt.Errorf("unsupported method %s", action)
+		}
+		call.model = model
+		var head struct {
+			GenerationConfig struct {
+				CandidateCount int `json:"candidateCount"`
+			} `json:"generationConfig"`
+		}
+		// an invalid body fails its translation
+		json.Unmarshal(body, &head)
+		call.choices = head.GenerationConfig.CandidateCount
+		return call, nil
+	}
+	if p.Source == transformer.ProviderBedrock {
+		// /model/{modelId}/converse or /model/{modelId}/converse-stream; ARNs
+		// as model ids are escaped
+		_, resource, _ := strings.Cut(r.URL.EscapedPath(), "/model/")
+		i := strings.LastIndex(resource, "/")
+		if i <= 0 {
+			return call, fmt.Errorf("unsupported path %s", r.URL.Path)
+		}
+		model, err := url.PathUnescape(resource[:i])
+		if err != nil {
+			return call, fmt.Errorf("unsupported path %s", r.URL.Path)
+		}
+		switch action := resource[i+1:]; action {
+		case "converse":
+		case "converse-stream":
+			call.stream = true
+		default:
+			return call, fmt.Errorf("unsupported method %s", action)
+		}
+		call.model = model
+		return call, nil
+	}
+
+	var head struct {
+		Model         string `json:"model"`
+		Stream        *bool  `json:"stream"`
+		N             int    `json:"n"`
+		StreamOptions *struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+	}
+	if err := json.Unmarshal(body, &head); err != nil {
+		return call, fmt.Errorf("invalid request body: %v", err)
+	}
+	call.model = head.Model
+	call.stream = head.Stream != nil && *head.Stream
+	if p.Source == transformer.ProviderOllama {
+		call.stream = head.Stream == nil || *head.Stream
+	}
+	if deployment, ok := azureDeployment(r.URL.Path); ok && call.model == "" {
+		call.model = deployment
+	}
+	call.includeUsage = head.StreamOptions != nil && head.StreamOptions.IncludeUsage
+	call.choices = head.N
+	return call, nil
+}
+
+// azureDeployment returns the deployment of an Azure OpenAI path.
+func azureDeployment(path string) (string, bool) {
+	_, rest, ok := strings.Cut(path, "/openai/deployments/")
+	if !ok {
+		return "", false
+	}
+	deployment, _, _ := strings.Cut(rest, "/")
+	return deployment, deployment != ""
+}
+
+// translateRequest converts the client request to the request of Target,
+// returning it with its model and upstream path.
+func (p *Proxy) translateRequest(ctx context.Context, call proxyCall, body []byte) (any, string, string, error) {
+	src, err := transformer.ParseRequest(ctx, p.Source, body)
+	if err != nil {
+		return nil, "", "", err
+	}
+	u, err := transformer.ToUnifiedRequest(ctx, p.Source, src)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if u.Model == "" {
+		u.Model = call.model
+	}
+	u.Stream = call.stream
+	if call.fanOut {
+		u.N = 0
+	}
+	return p.targetRequest(ctx, u)
+}
+
+// targetRequest converts a unified request to the request of Target,
+// returning it with its model and upstream path.
+func (p *Proxy) targetRequest(ctx context.Context, u *transformer.UnifiedRequest) (any, string, string, error) {
+	if p.MaxInputTokens > 0 {
+		if n := tokens.CountUnified(p.Target, u); n > p.MaxInputTokens {
+			return nil, "", "", fmt.Errorf("the request has about %d input tokens, more than the limit of %d", n, p.MaxInputTokens)
+		}
+	}
+	dst, err := transformer.NewPayload(p.Target, transformer.TransformerTypeRequest)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := transformer.FromUnifiedRequest(ctx, p.Target, u, dst); err != nil {
+		return nil, "", "", err
+	}
+
+	switch req := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		if req.Stream {
+			// usage is needed for accounting and by Claude and Gemini clients
+			req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+		}
+		if p.AzureAPIVersion != "" {
+			// the model is the deployment name in Azure mode
+			path := "/openai/deployments/" + url.PathEscape(req.Model) + "/chat/completions"
+			return req, req.Model, path + "?api-version=" + url.QueryEscape(p.AzureAPIVersion), nil
+		}
+		return req, req.Model, "/v1/chat/completions", nil
+	case *claude.ClaudeRequest:
+		return req, req.Model, "/v1/messages", nil
+	case *gemini.GeminiChatRequest, *vertex.GenerateContentRequest:
+		// Gemini requests carry no model, it goes in the path
+		model := transformer.TargetModel(ctx, p.Target, u.Model)
+		path := "/v1beta/models/" + model
+		if p.Target == transformer.ProviderVertexGemini {
+			path = "/publishers/google/models/" + model
+		}
+		if u.Stream {
+			return req, model, path + ":streamGenerateContent?alt=sse", nil
+		}
+		return req, model, path + ":generateContent", nil
+	case *ollama.ChatRequest:
+		return req, req.Model, "/api/chat", nil
+	case *bedrock.ConverseRequest:
+		// the model goes in the path, not in the body
+		model := req.ModelID
+		body := *req
+		body.ModelID = ""
+		path := "/model/" + url.PathEscape(model) + "/converse"
+		if u.Stream {
+			path += "-stream"
+		}
+		return &body, model, path, nil
+	default:
+		return nil, "", "", fmt.Errorf("unsupported upstream provider %s", p.Target)
+	}
+}
+
+func (p *Proxy) translateResponse(ctx context.Context, w http.ResponseWriter, resp *http.Response, model string) {
+	u, usage, err := p.unifyResponse(ctx, resp.Body)
+	if usage != nil {
+		reportUsage(ctx, model, *usage)
+	}
+	if err != nil {
+		writeError(w, p.Source, http.StatusBadGateway, "api_error", "%v", err)
+		return
+	}
+	p.writeResponse(ctx, w, u, model)
+}
+
+// unifyResponse decodes a successful response of Target, returning it in
+// the unified format with its usage, nil when it reports none.
+func (p *Proxy) unifyResponse(ctx context.Context, body io.Reader) (*transformer.UnifiedResponse, *transformer.StreamUsage, error) {
+	src, err := transformer.NewPayload(p.Target, transformer.TransformerTypeResponse)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.NewDecoder(body).Decode(src); err != nil {
+		return nil, nil, errors.New(upstreamReadError(err))
+	}
+	var usage *transformer.StreamUsage
+	if u, ok := transformer.UsageOf(src); ok {
+		usage = &u
+	}
+	u, err := transformer.ToUnifiedResponse(ctx, p.Target, src)
+	if err != nil {
+		return nil, usage, fmt.Errorf("translate upstream response: %w", err)
+	}
+	return u, usage, nil
+}
+
+// writeResponse answers the client with u in the format of Source.
+func (p *Proxy) writeResponse(ctx context.Context, w http.ResponseWriter, u *transformer.UnifiedResponse, model string) {
+	if u.Model == "" {
+		u.Model = model
+	}
+	if p.Pipeline != nil {
+		p.Pipeline.ProcessUnified(u, "")
+	}
+	dst, err := transformer.NewPayload(p.Source, transformer.TransformerTypeResponse)
+	if err != nil {
+		writeError(w, p.Source, http.StatusInternalServerError, "api_error", "%v", err)
+		return
+	}
+	if err := transformer.FromUnifiedResponse(ctx, p.Source, u, dst); err != nil {
+		writeError(w, p.Source, http.StatusBadGateway, "api_error", "translate upstream response: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dst)
+}
+
+// translateError rewrites a failed upstream response into the error
+// envelope of Source, keeping its status, type and message.
+func (p *Proxy) translateError(w http.ResponseWriter, resp *http.Response) {
+	data, _ := io.ReadAll(resp.Body)
+	writeUnifiedError(w, p.Source, transformer.ErrorToUnified(p.Target, resp.StatusCode, data))
+}
+
+// forward proxies a request between identical APIs, only observing usage.
+// normalizeRequest canonicalizes a request forwarded to a Target of the
+// same provider.
+func (p *Proxy) normalizeRequest(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := transformer.NewPayload(p.Source, transformer.TransformerTypeRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("invalid %s request: %w", p.Source, err)
+	}
+	if err := transformer.Normalize(ctx, p.Source, transformer.TransformerTypeRequest, req); err != nil {
+		return nil, err
+	}
+	return json.Marshal(req)
+}
+
+func (p *Proxy) forward(ctx context.Context, w http.ResponseWriter, r *http.Request, call proxyCall, body []byte) {
+	resp, err := p.Upstream.post(ctx, r.Header, r.URL.RequestURI(), json.RawMessage(body))
+	if err != nil {
+		writeError(w, p.Source, http.StatusBadGateway, "api_error", "upstream request failed: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !call.stream {
+		limitResponse(resp, orDefault(p.MaxResponseBytes, DefaultMaxResponseBytes))
+	}
+	if resp.StatusCode != http.StatusOK {
+		relayError(w, resp)
+		return
+	}
+	if call.stream {
+		p.stream(ctx, w, resp, call, call.model)
+		return
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeError(w, p.Source, http.StatusBadGateway, "api_error", "%s", upstreamReadError(err))
+		return
+	}
+	if payload, err := transformer.NewPayload(p.Target, transformer.TransformerTypeResponse); err == nil && json.Unmarshal(data, payload) == nil {
+		if usage, ok := transformer.UsageOf(payload); ok {
+			reportUsage(ctx, call.model, usage)
+		}
+		if p.Pipeline != nil {
+			if err := p.Pipeline.Process(ctx, p.Target, payload); err != nil {
+				writeError(w, p.Source, http.StatusBadGateway, "api_error", "%v", err)
+				return
+			}
+			writeJSON(w, http.StatusOK, payload)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// stream relays the upstream stream to the client, translated when Source
+// and Target differ. Errors after the headers are sent are reported in-band.
+func (p *Proxy) stream(ctx context.Context, w http.ResponseWriter, resp *http.Response, call proxyCall, model string) {
+	var dst io.Writer = w
+	var rec *resumeRecorder
+	if p.Resume != nil {
+		rec = p.Resume.record(w)
+		defer rec.finish()
+		dst = rec
+	}
+	out := sse.NewWriter(dst, p.Source)
+	if call.array {
+		out = sse.NewGeminiArrayWriter(dst)
+	}
+	if rec != nil {
+		out.NumberEvents()
+		rec.open(out.ContentType())
+	}
+	w.Header().Set("Content-Type", out.ContentType())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// upstream streams are converted into client streams
+	opts := transformer.OptionsFromContext(ctx)
+	opts.IncludeUsage = call.includeUsage
+	converter := transformer.NewStreamConverter(transformer.WithOptions(ctx, opts), p.Target, p.Source)
+	converter.Model = model
+	var usage transformer.UsageTracker
+	defer func() { reportUsage(ctx, model, usage.Total()) }()
+	var log *sse.LogWriter
+	if p.StreamLog != nil {
+		log = sse.NewLogWriter(p.StreamLog, p.Target, p.Source)
+	}
+	var post *transformer.ResponseStream
+	if p.Pipeline != nil && streamProcessed(p.Source) {
+		post = p.Pipeline.Stream()
+	}
+	err := eachEvent(resp.Body, p.Target, func(ev sse.Event) error {
+		payload, err := decodeEvent(p.Target, ev)
+		if err != nil {
+			return fmt.Errorf("invalid upstream event: %w", err)
+		}
+		if p.Target == transformer.ProviderOllama && p.Source != p.Target {
+			// Ollama ends a failing stream with an error object
+			var failure ollama.ErrorResponse
+			if json.Unmarshal([]byte(ev.Data), &failure) == nil && failure.Error != "" {
+				return fmt.Errorf("upstream error: %s", failure.Error)
+			}
+		}
+		if u, ok := transformer.UsageOf(payload); ok {
+			usage.Observe(u, transformer.UsageCumulative)
+		}
+		if p.Source == p.Target && post == nil {
+			if log != nil {
+				log.Write(json.RawMessage(ev.Data))
+			}
+			return out.WriteEvent(ev)
+		}
+		events := []any{payload}
+		if p.Source != p.Target {
+			if events, err = converter.Transform(payload); err != nil {
+				return err
+			}
+		}
+		if events, err = postProcess(post, events); err != nil {
+			return err
+		}
+		return writeAll(out, log, events)
+	})
+	if err == nil {
+		var events []any
+		if p.Source != p.Target {
+			events, err = converter.Finish()
+		}
+		if err == nil {
+			events, err = postProcess(post, events)
+		}
+		if err == nil && post != nil {
+			events = append(events, post.Flush()...)
+		}
+		if err == nil {
+			err = writeAll(out, log, events)
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		writeStreamError(out, p.Source, err)
+	}
+	out.Close()
+}
+
+// decodeEvent decodes an event of a stream of provider into its chunk type.
+func decodeEvent(provider transformer.Provider, ev sse.Event) (any, error) {
+	if provider == transformer.ProviderBedrock {
+		return bedrock.ParseConverseStreamEvent(ev.Event, []byte(ev.Data))
+	}
+	payload, err := transformer.NewPayload(provider, transformer.TransformerTypeChunk)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(ev.Data), payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// streamProcessed reports whether a Pipeline processes the streams of
+// provider.
+func streamProcessed(provider transformer.Provider) bool {
+	switch provider {
+	case transformer.ProviderOpenAI, transformer.ProviderClaude, transformer.ProviderGemini:
+		return true
+	}
+	return false
+}
+
+// postProcess returns events rewritten by post, events when it is nil.
+func postProcess(post *transformer.ResponseStream, events []any) ([]any, error) {
+	if post == nil {
+		return events, nil
+	}
+	var processed []any
+	for _, event := range events {
+		out, err := post.Process(event)
+		if err != nil {
+			return nil, err
+		}
+		processed = append(processed, out...)
+	}
+	return processed, nil
+}
+
+// writeAll writes events to out, and to log unless nil. Logging errors are
+// ignored.
+func writeAll(out *sse.Writer, log *sse.LogWriter, events []any) error {
+	for _, event := range events {
+		if err := out.Write(event); err != nil {
+			return err
+		}
+		if log != nil {
+			log.Write(event)
+		}
+	}
+	return nil
+}
+
+// writeStreamError reports err as the last event of a stream of provider,
+// an exception message for Bedrock.
+func writeStreamError(out *sse.Writer, provider transformer.Provider, err error) {
+	u := &transformer.UnifiedError{Status: http.StatusInternalServerError, Type: transformer.ErrorTypeAPI, Message: err.Error()}
+	var terr *transformer.TransformationError
+	if errors.As(err, &terr) {
+		u.Type, u.Message = terr.Type, terr.Message
+	}
+	if provider == transformer.ProviderBedrock {
+		var exception *eventstream.Error
+		if errors.As(err, &exception) && exception.Type != "" {
+			// an exception of a Bedrock upstream is relayed as is
+			out.WriteException(exception.Type, exception.Message)
+			return
+		}
+		// streams name exceptions in lower camel case
+		typ := transformer.BedrockException(u.Type)
+		out.WriteException(strings.ToLower(typ[:1])+typ[1:], u.Message)
+		return
+	}
+	resp, ferr := transformer.ErrorFromUnified(provider, u)
+	if ferr != nil {
+		resp, _ = transformer.ErrorFromUnified(transformer.ProviderClaude, u)
+	}
+	out.Write(resp)
+}
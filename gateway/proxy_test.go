@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/eventstream"
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/transformer"
+)
+
+func TestProxyStreamsFromBedrock(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/model/anthropic.claude-sonnet-4-5-v1:0/converse-stream"; r.URL.Path != want {
+			t.Errorf("got path %s, want %s", r.URL.Path, want)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["modelId"]; ok {
+			t.Error("the request body names the model")
+		}
+		out := eventstream.NewWriter(w)
+		w.Header().Set("Content-Type", out.ContentType())
+		for _, event := range []struct {
+			typ     string
+			payload string
+		}{
+			{"messageStart", `{"role":"assistant"}`},
+			{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hello"}}`},
+			{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":" there"}}`},
+			{"contentBlockStop", `{"contentBlockIndex":0}`},
+			{"messageStop", `{"stopReason":"end_turn"}`},
+			{"metadata", `{"usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5}}`},
+		} {
+			msg, err := eventstream.NewEvent(event.typ, json.RawMessage(event.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.Write(msg)
+		}
+	}))
+	defer upstream.Close()
+	proxy := &Proxy{
+		Source:   transformer.ProviderOpenAI,
+		Target:   transformer.ProviderBedrock,
+		Upstream: &Upstream{BaseURL: upstream.URL},
+	}
+
+	body := `{"model":"anthropic.claude-sonnet-4-5-v1:0","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var content string
+	var finishReason openai.FinishReason
+	for ev, err := range sse.NewReader(rec.Body).Events() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Data == transformer.StreamDone {
+			break
+		}
+		var chunk openai.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			t.Fatalf("%v: %s", err, ev.Data)
+		}
+		for _, choice := range chunk.Choices {
+			content += choice.Delta.Content
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+	if content != "Hello there" || finishReason != openai.FinishReasonStop {
+		t.Errorf("got content %q finishing with %q, want %q and stop", content, finishReason, "Hello there")
+	}
+}
+
+func TestProxyStreamsToBedrockClients(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "gpt-4o" || !req.Stream {
+			t.Errorf("got model %q streaming %v, want gpt-4o streaming", req.Model, req.Stream)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hi\"}}]}\n\n")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		io.WriteString(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1,\"total_tokens\":4}}\n\n")
+		io.WriteString(w, "data: {\"invalid\n\n")
+	}))
+	defer upstream.Close()
+	proxy := &Proxy{
+		Source:   transformer.ProviderBedrock,
+		Target:   transformer.ProviderOpenAI,
+		Upstream: &Upstream{BaseURL: upstream.URL},
+	}
+
+	body := `{"messages":[{"role":"user","content":[{"text":"hi"}]}]}`
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/model/gpt-4o/converse-stream", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/vnd.amazon.eventstream" {
+		t.Errorf("got content type %q", got)
+	}
+	var types []string
+	var text string
+	var failure error
+	for msg, err := range eventstream.NewReader(rec.Body).Messages() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failure = msg.Err(); failure != nil {
+			break
+		}
+		event, err := bedrock.ParseConverseStreamEvent(msg.EventType(), msg.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, msg.EventType())
+		if event.ContentBlockDelta != nil && event.ContentBlockDelta.Delta.Text != nil {
+			text += *event.ContentBlockDelta.Delta.Text
+		}
+	}
+	if text != "Hi" {
+		t.Errorf("got text %q, want Hi", text)
+	}
+	if len(types) == 0 || types[0] != "messageStart" {
+		t.Errorf("got events %q, want a stream starting with messageStart", types)
+	}
+	// the invalid upstream event ends the stream with an exception
+	if exception, ok := failure.(*eventstream.Error); !ok || exception.Type != "internalServerException" {
+		t.Errorf("got %v, want an internalServerException", failure)
+	}
+}
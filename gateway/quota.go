@@ -0,0 +1,259 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/transformer"
+)
+
+// Limit is a token bucket holding up to Burst units, refilled at Rate units
+// per second. The zero Limit is unlimited.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst float64 `json:"burst"`
+}
+
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0 && l.Burst <= 0
+}
+
+// Quota limits one API key. Tokens counts input plus output tokens, Cost
+// prices them with the configured pricing.
+type Quota struct {
+	Requests Limit `json:"requests"`
+	Tokens   Limit `json:"tokens"`
+	Cost     Limit `json:"cost"`
+}
+
+// Usage is what a key has consumed.
+type Usage struct {
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// QuotaStore keeps token buckets and usage totals. Implementations backed
+// by a shared database let several gateway replicas enforce one quota.
+type QuotaStore interface {
+	// Adjust refills the named bucket of limit up to now, adds delta and
+	// returns the new level. A new bucket starts full.
+	Adjust(ctx context.Context, bucket string, limit Limit, delta float64, now time.Time) (float64, error)
+	// Record adds usage to the totals of key.
+	Record(ctx context.Context, key string, usage Usage) error
+	// Usage returns the totals of key.
+	Usage(ctx context.Context, key string) (Usage, error)
+}
+
+// MemoryQuotaStore is a QuotaStore for a single process.
+type MemoryQuotaStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	usage   map[string]Usage
+}
+
+type bucket struct {
+	level   float64
+	updated time.Time
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{buckets: make(map[string]*bucket), usage: make(map[string]Usage)}
+}
+
+func (s *MemoryQuotaStore) Adjust(_ context.Context, name string, limit Limit, delta float64, now time.Time) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[name]
+	if !ok {
+		b = &bucket{level: limit.Burst, updated: now}
+		s.buckets[name] = b
+	}
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.level = math.Min(limit.Burst, b.level+elapsed*limit.Rate)
+		b.updated = now
+	}
+	b.level += delta
+	return b.level, nil
+}
+
+func (s *MemoryQuotaStore) Record(_ context.Context, key string, usage Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := s.usage[key]
+	total.Requests += usage.Requests
+	total.InputTokens += usage.InputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.Cost += usage.Cost
+	s.usage[key] = total
+	return nil
+}
+
+func (s *MemoryQuotaStore) Usage(_ context.Context, key string) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[key], nil
+}
+
+// Quotas meters and limits the requests of each API key. A request is
+// admitted when its key has a request token and its token and cost buckets
+// are not in debt; tokens and cost are charged once the response reports
+// its usage, so a long response can push a bucket below zero and delay the
+// next requests of the key.
+type Quotas struct {
+	Store QuotaStore
+	// Quota returns the quota of key, false to reject the key as unknown.
+	Quota func(key string) (Quota, bool)
+	// Key extracts the API key, APIKey when nil.
+	Key func(r *http.Request) string
+	// Pricing prices usage for Cost limits and accounting.
+	Pricing *config.Store
+}
+
+// APIKey returns the key of a request in any provider's convention: the
+// x-api-key or x-goog-api-key header, a bearer token or the key query
+// parameter.
+func APIKey(r *http.Request) string {
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+	if key := r.Header.Get("X-Goog-Api-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("key")
+}
+
+// Wrap enforces the quotas on next, which serves the API of provider:
+// rejections use its error envelope.
+func (q *Quotas) Wrap(provider transformer.Provider, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyOf := q.Key
+		if keyOf == nil {
+			keyOf = APIKey
+		}
+		key := keyOf(r)
+		quota, ok := q.Quota(key)
+		if key == "" || !ok {
+			writeError(w, provider, http.StatusUnauthorized, "authentication_error", "invalid API key")
+			return
+		}
+
+		ctx := r.Context()
+		ok, retry, err := q.admit(ctx, key, quota, time.Now())
+		if err != nil {
+			writeError(w, provider, http.StatusInternalServerError, "api_error", "quota store: %v", err)
+			return
+		}
+		if !ok {
+			if retry == 0 {
+				writeError(w, provider, http.StatusTooManyRequests, "rate_limit_error", "quota of this API key is exhausted")
+				return
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			writeError(w, provider, http.StatusTooManyRequests, "rate_limit_error", "quota of this API key exceeded, retry in %s", retry.Round(time.Second))
+			return
+		}
+
+		ctx, report := withUsageReport(ctx)
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		usage := Usage{
+			Requests:     1,
+			InputTokens:  int64(report.usage.InputTokens),
+			OutputTokens: int64(report.usage.OutputTokens),
+		}
+		var cfg *config.Config
+		if q.Pricing != nil {
+			cfg = q.Pricing.Current()
+		}
+		if price, ok := cfg.Price(report.model); ok {
+			u := report.usage
+			usage.Cost = price.Cost(u.InputTokens-u.CacheReadTokens-u.CacheCreationTokens, u.OutputTokens, u.CacheReadTokens, u.CacheCreationTokens)
+		}
+		// The request is served, failing to charge it must not fail it
+		ctx = context.WithoutCancel(ctx)
+		now := time.Now()
+		if !quota.Tokens.unlimited() {
+			q.Store.Adjust(ctx, key+":tokens", quota.Tokens, -float64(usage.InputTokens+usage.OutputTokens), now)
+		}
+		if !quota.Cost.unlimited() {
+			q.Store.Adjust(ctx, key+":cost", quota.Cost, -usage.Cost, now)
+		}
+		q.Store.Record(ctx, key, usage)
+	})
+}
+
+// admit checks the post-paid buckets and takes a request token. It returns
+// whether the key is within quota and, when it is not, how long it has to
+// wait, zero when the exhausted bucket never refills.
+func (q *Quotas) admit(ctx context.Context, key string, quota Quota, now time.Time) (bool, time.Duration, error) {
+	for _, b := range []struct {
+		name  string
+		limit Limit
+	}{{"tokens", quota.Tokens}, {"cost", quota.Cost}} {
+		if b.limit.unlimited() {
+			continue
+		}
+		level, err := q.Store.Adjust(ctx, key+":"+b.name, b.limit, 0, now)
+		if err != nil {
+			return false, 0, err
+		}
+		if level <= 0 {
+			return false, refillTime(b.limit, -level), nil
+		}
+	}
+
+	if quota.Requests.unlimited() {
+		return true, 0, nil
+	}
+	level, err := q.Store.Adjust(ctx, key+":requests", quota.Requests, -1, now)
+	if err != nil {
+		return false, 0, err
+	}
+	if level >= 0 {
+		return true, 0, nil
+	}
+	// give the token back, the request is rejected
+	if _, err := q.Store.Adjust(ctx, key+":requests", quota.Requests, 1, now); err != nil {
+		return false, 0, err
+	}
+	return false, refillTime(quota.Requests, -level), nil
+}
+
+// refillTime is how long limit takes to refill amount, at least a second.
+func refillTime(limit Limit, amount float64) time.Duration {
+	if limit.Rate <= 0 {
+		return 0
+	}
+	return max(time.Duration(amount/limit.Rate*float64(time.Second)), time.Second)
+}
+
+// usageReport collects the usage a handler served, for the quota middleware.
+type usageReport struct {
+	model string
+	usage transformer.StreamUsage
+}
+
+type usageReportKey struct{}
+
+func withUsageReport(ctx context.Context) (context.Context, *usageReport) {
+	report := &usageReport{}
+	return context.WithValue(ctx, usageReportKey{}, report), report
+}
+
+// reportUsage records the usage of the response a handler served.
+func reportUsage(ctx context.Context, model string, usage transformer.StreamUsage) {
+	if report, ok := ctx.Value(usageReportKey{}).(*usageReport); ok {
+		report.model, report.usage = model, usage
+	}
+}
@@ -0,0 +1,202 @@
+package gateway
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// ResumeTokenHeader carries the resumption token of a resumable stream: in
+// the response starting it, and in the requests resuming it.
+const ResumeTokenHeader = "X-Resumption-Token"
+
+// DefaultResumeTTL is how long a Resumer keeps a finished stream when its
+// TTL is zero.
+const DefaultResumeTTL = 5 * time.Minute
+
+// Resumer keeps the streams a Proxy translates so that clients losing their
+// connection can pick them up again. A resumable stream runs to its end
+// whether or not the client stays, and its events are numbered in their
+// id field. The client resumes it by sending the token of the
+// X-Resumption-Token response header back, with GET or POST to the same
+// endpoint, and the id of the last event it got as Last-Event-ID: it gets
+// the events after that one, then the rest of the stream as it arrives.
+// Without Last-Event-ID, and for JSON array, Ollama and Bedrock streams,
+// which carry no ids, the stream is replayed from its start.
+//
+// Streams are held in memory whole, until TTL after their end.
+type Resumer struct {
+	// TTL is how long a finished stream can still be resumed.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*resumable
+}
+
+// resumable is a stream kept for resumption, as the writes of its sse.Writer.
+type resumable struct {
+	contentType string
+
+	mu      sync.Mutex
+	chunks  [][]byte
+	done    bool
+	expires time.Time
+	// wake is closed, and replaced, when the stream grows or ends.
+	wake chan struct{}
+}
+
+// record returns a recorder of the stream written to w; the stream becomes
+// resumable when the recorder is opened.
+func (res *Resumer) record(w http.ResponseWriter) *resumeRecorder {
+	return &resumeRecorder{resumer: res, client: w, stream: &resumable{wake: make(chan struct{})}}
+}
+
+// add registers stream under a new token, dropping the expired streams.
+func (res *Resumer) add(stream *resumable) string {
+	var b [16]byte
+	rand.Read(b[:])
+	token := hex.EncodeToString(b[:])
+	now := time.Now()
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if res.streams == nil {
+		res.streams = map[string]*resumable{}
+	}
+	for key, s := range res.streams {
+		if s.expired(now) {
+			delete(res.streams, key)
+		}
+	}
+	res.streams[token] = stream
+	return token
+}
+
+// get returns the stream of token, nil when unknown or expired.
+func (res *Resumer) get(token string) *resumable {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if s := res.streams[token]; s != nil && !s.expired(time.Now()) {
+		return s
+	}
+	return nil
+}
+
+// serve resumes the stream of the token of r for a client of provider.
+func (res *Resumer) serve(w http.ResponseWriter, r *http.Request, provider transformer.Provider) {
+	stream := res.get(r.Header.Get(ResumeTokenHeader))
+	if stream == nil {
+		writeError(w, provider, http.StatusNotFound, "not_found_error", "no stream to resume for this %s", ResumeTokenHeader)
+		return
+	}
+	next := 0
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil || n < 0 {
+			writeError(w, provider, http.StatusBadRequest, "invalid_request_error", "invalid Last-Event-ID %q", id)
+			return
+		}
+		next = n
+	}
+	w.Header().Set("Content-Type", stream.contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for {
+		chunks, done, wake := stream.since(next)
+		for _, chunk := range chunks {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+		next += len(chunks)
+		if flusher != nil && len(chunks) > 0 {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+		select {
+		case <-wake:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// since returns the chunks from the i-th on, whether the stream has ended,
+// and a channel closed when that changes.
+func (s *resumable) since(i int) ([][]byte, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i > len(s.chunks) {
+		i = len(s.chunks)
+	}
+	return s.chunks[i:], s.done, s.wake
+}
+
+func (s *resumable) append(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunks = append(s.chunks, bytes.Clone(p))
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// finish ends the stream, to expire ttl later.
+func (s *resumable) finish(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done, s.expires = true, time.Now().Add(ttl)
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+func (s *resumable) expired(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done && now.After(s.expires)
+}
+
+// resumeRecorder is the destination of a resumable stream: it keeps every
+// write, one event each, and relays them to the client until it goes away.
+type resumeRecorder struct {
+	resumer *Resumer
+	client  http.ResponseWriter
+	stream  *resumable
+	gone    bool
+}
+
+// open makes the stream resumable and sends its token to the client. It
+// must precede the response headers.
+func (rec *resumeRecorder) open(contentType string) {
+	rec.stream.contentType = contentType
+	rec.client.Header().Set(ResumeTokenHeader, rec.resumer.add(rec.stream))
+}
+
+func (rec *resumeRecorder) Write(p []byte) (int, error) {
+	rec.stream.append(p)
+	if !rec.gone {
+		if _, err := rec.client.Write(p); err != nil {
+			rec.gone = true
+		}
+	}
+	return len(p), nil
+}
+
+func (rec *resumeRecorder) Flush() {
+	if flusher, ok := rec.client.(http.Flusher); ok && !rec.gone {
+		flusher.Flush()
+	}
+}
+
+// finish ends the stream for the clients resuming it.
+func (rec *resumeRecorder) finish() {
+	rec.stream.finish(cmp.Or(rec.resumer.TTL, DefaultResumeTTL))
+}
@@ -0,0 +1,291 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// Signer authenticates an upstream request, body is its payload. Signers
+// replace the credentials forwarded from the client.
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// SignerFunc adapts a function to Signer.
+type SignerFunc func(req *http.Request, body []byte) error
+
+func (f SignerFunc) Sign(req *http.Request, body []byte) error {
+	return f(req, body)
+}
+
+// Verifier authenticates an inbound client request, body is its payload.
+type Verifier interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+// VerifierFunc adapts a function to Verifier.
+type VerifierFunc func(r *http.Request, body []byte) error
+
+func (f VerifierFunc) Verify(r *http.Request, body []byte) error {
+	return f(r, body)
+}
+
+// VerifySignatures rejects requests failing verifier before they reach next,
+// which serves the API of provider. It reads bodies whole, wrap it in
+// LimitRequestBodies to bound them.
+func VerifySignatures(provider transformer.Provider, verifier Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readBody(w, r, provider, -1)
+		if !ok {
+			return
+		}
+		if err := verifier.Verify(r, body); err != nil {
+			writeError(w, provider, http.StatusUnauthorized, "authentication_error", "invalid request signature: %v", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HMACVerifier verifies HMAC-SHA256 request signatures sent in Header as
+// "t=<unix seconds>,v1=<hex signature>", where the signature covers the
+// timestamp, a dot and the body. Several v1 entries are accepted during
+// secret rotation.
+type HMACVerifier struct {
+	// Secret returns the signing secret of the API key, false when the key
+	// does not sign its requests.
+	Secret func(key string) ([]byte, bool)
+	// Header carries the signature, X-Signature when empty.
+	Header string
+	// Tolerance is the accepted clock skew, 5 minutes when zero.
+	Tolerance time.Duration
+}
+
+func (v *HMACVerifier) Verify(r *http.Request, body []byte) error {
+	secret, ok := v.Secret(APIKey(r))
+	if !ok {
+		return errors.New("unknown API key")
+	}
+	header := v.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+	value := r.Header.Get(header)
+	if value == "" {
+		return fmt.Errorf("missing %s header", header)
+	}
+
+	var timestamp string
+	var signatures [][]byte
+	for _, field := range strings.Split(value, ",") {
+		name, val, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch name {
+		case "t":
+			timestamp = val
+		case "v1":
+			if sig, err := hex.DecodeString(val); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = 5 * time.Minute
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > tolerance || skew < -tolerance {
+		return errors.New("timestamp outside the tolerance")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
+
+// AWSCredentials sign requests to AWS.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken and Expires are set for temporary credentials.
+	SessionToken string
+	Expires      time.Time
+}
+
+// AWSSigV4 signs requests with AWS Signature Version 4, e.g. for Bedrock
+// with Service bedrock.
+type AWSSigV4 struct {
+	Region  string
+	Service string
+	// Credentials returns the credentials to sign with, called per request so
+	// temporary credentials can be refreshed, e.g. DefaultAWSCredentials().
+	Credentials AWSCredentialsSource
+}
+
+func (s *AWSSigV4) Sign(req *http.Request, body []byte) error {
+	creds, err := s.Credentials(req.Context())
+	if err != nil {
+		return fmt.Errorf("aws credentials: %w", err)
+	}
+	signAWSRequest(req, body, creds, s.Region, s.Service, time.Now().UTC())
+	return nil
+}
+
+func signAWSRequest(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	req.Header.Del("X-Api-Key")
+	req.Header.Del("Authorization")
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	payloadHash := sha256Hex(body)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") || strings.HasPrefix(name, "anthropic-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.EscapedPath(), false),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsCanonicalQuery sorts and escapes query parameters as SigV4 requires.
+func awsCanonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+	slices.Sort(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes all but unreserved characters, and slashes
+// unless escapeSlash.
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// GCPOAuth authorizes requests to Google Cloud, e.g. Vertex AI, with OAuth
+// access tokens, reusing a token until shortly before it expires.
+type GCPOAuth struct {
+	// Token returns a new access token and its expiry, e.g.
+	// GCPDefaultCredentials().
+	Token TokenSource
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// tokenRefreshMargin renews tokens before they expire in flight.
+const tokenRefreshMargin = time.Minute
+
+func (g *GCPOAuth) Sign(req *http.Request, _ []byte) error {
+	token, err := g.accessToken(req.Context())
+	if err != nil {
+		return fmt.Errorf("gcp access token: %w", err)
+	}
+	req.Header.Del("X-Api-Key")
+	req.Header.Del("X-Goog-Api-Key")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (g *GCPOAuth) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Until(g.expiry) > tokenRefreshMargin {
+		return g.token, nil
+	}
+	token, expiry, err := g.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	g.token, g.expiry = token, expiry
+	return token, nil
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// StickyKey returns the key of the conversation of a request, "" for none.
+type StickyKey func(r *http.Request, body []byte) string
+
+// StickyHeader keys conversations by a header the caller sets, such as
+// X-Conversation-Id.
+func StickyHeader(name string) StickyKey {
+	return func(r *http.Request, _ []byte) string {
+		return r.Header.Get(name)
+	}
+}
+
+// StickyHistory keys conversations by their history, for requests of
+// source: see ConversationKey.
+func StickyHistory(source transformer.Provider) StickyKey {
+	return func(r *http.Request, body []byte) string {
+		key, _ := ConversationKey(r.Context(), source, body)
+		return key
+	}
+}
+
+// ConversationKey returns a key of the conversation of a request of
+// provider that its later turns share: the hash of its messages up to the
+// first user message, system prompt included, which every turn repeats.
+// Cache control markers are left out, as clients move them between turns.
+func ConversationKey(ctx context.Context, provider transformer.Provider, body []byte) (string, error) {
+	req, err := transformer.ParseRequest(ctx, provider, body)
+	if err != nil {
+		return "", err
+	}
+	u, err := transformer.ToUnifiedRequest(ctx, provider, req)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, message := range u.Messages {
+		writeField(h, message.Role)
+		for _, part := range message.Parts {
+			writeField(h, part.Type)
+			writeField(h, part.Text)
+			writeField(h, part.URL)
+		}
+		if message.Role == "user" {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+	}
+	return "", nil
+}
+
+// writeField writes s to h, terminated so that fields do not run together.
+func writeField(h io.Writer, s string) {
+	io.WriteString(h, s)
+	h.Write([]byte{0})
+}
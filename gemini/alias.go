@@ -0,0 +1,30 @@
+// Package gemini is kept for migration only; the types now live in
+// github.com/phosae/llms/dto/gemini.
+//
+// Deprecated: import github.com/phosae/llms/dto/gemini instead.
+package gemini
+
+import "github.com/phosae/llms/dto/gemini"
+
+type (
+	FunctionCall                  = gemini.FunctionCall
+	FunctionResponse              = gemini.FunctionResponse
+	GeminiChatCandidate           = gemini.GeminiChatCandidate
+	GeminiChatContent             = gemini.GeminiChatContent
+	GeminiChatGenerationConfig    = gemini.GeminiChatGenerationConfig
+	GeminiChatPromptFeedback      = gemini.GeminiChatPromptFeedback
+	GeminiChatRequest             = gemini.GeminiChatRequest
+	GeminiChatResponse            = gemini.GeminiChatResponse
+	GeminiChatSafetyRating        = gemini.GeminiChatSafetyRating
+	GeminiChatSafetySettings      = gemini.GeminiChatSafetySettings
+	GeminiChatTool                = gemini.GeminiChatTool
+	GeminiError                   = gemini.GeminiError
+	GeminiFileData                = gemini.GeminiFileData
+	GeminiInlineData              = gemini.GeminiInlineData
+	GeminiPart                    = gemini.GeminiPart
+	GeminiPartCodeExecutionResult = gemini.GeminiPartCodeExecutionResult
+	GeminiPartExecutableCode      = gemini.GeminiPartExecutableCode
+	GeminiPromptTokensDetails     = gemini.GeminiPromptTokensDetails
+	GeminiThinkingConfig          = gemini.GeminiThinkingConfig
+	GeminiUsageMetadata           = gemini.GeminiUsageMetadata
+)
@@ -0,0 +1,30 @@
+// Package metrics defines the instrumentation hooks of the transformer
+// registry and of bridges built on it, with a dependency-free Prometheus
+// implementation.
+package metrics
+
+import "time"
+
+// Recorder receives the events worth monitoring. Implementations must be safe
+// for concurrent use.
+type Recorder interface {
+	// ObserveTransformation counts one transformation of the given pair and payload type.
+	ObserveTransformation(source, target, typ string, err error)
+	// ObserveWarning counts one transformation warning by its code.
+	ObserveWarning(code string)
+	// ObserveStream records the duration of a relayed stream.
+	ObserveStream(provider string, d time.Duration)
+	// ObserveTokens counts the tokens of one exchange.
+	ObserveTokens(provider, model string, input, output int)
+	// ObserveUpstreamError counts a failed upstream call, status 0 for transport errors.
+	ObserveUpstreamError(provider string, status int)
+}
+
+// Nop discards every observation.
+type Nop struct{}
+
+func (Nop) ObserveTransformation(source, target, typ string, err error) {}
+func (Nop) ObserveWarning(code string)                                  {}
+func (Nop) ObserveStream(provider string, d time.Duration)              {}
+func (Nop) ObserveTokens(provider, model string, input, output int)     {}
+func (Nop) ObserveUpstreamError(provider string, status int)            {}
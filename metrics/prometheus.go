@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStreamBuckets are the stream duration histogram buckets, in seconds.
+var DefaultStreamBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Prometheus is a Recorder exposing its observations in the Prometheus text
+// format. Serve it on the metrics endpoint:
+//
+//	m := metrics.NewPrometheus()
+//	registry.UseMetrics(m)
+//	http.Handle("/metrics", m)
+type Prometheus struct {
+	mu            sync.Mutex
+	counters      map[string]map[string]float64 // metric name -> label set -> value
+	buckets       []float64
+	streamBuckets map[string][]uint64 // label set -> cumulative bucket counts, +Inf last
+	streamSum     map[string]float64
+}
+
+var promHelp = map[string]string{
+	"llms_transformations_total":   "Transformations by provider pair, payload type and result.",
+	"llms_warnings_total":          "Transformation warnings by code.",
+	"llms_tokens_total":            "Tokens by provider, model and direction.",
+	"llms_upstream_errors_total":   "Failed upstream calls by provider and status.",
+	"llms_stream_duration_seconds": "Duration of relayed streams.",
+}
+
+// NewPrometheus returns an empty Prometheus recorder.
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		counters:      make(map[string]map[string]float64),
+		buckets:       DefaultStreamBuckets,
+		streamBuckets: make(map[string][]uint64),
+		streamSum:     make(map[string]float64),
+	}
+}
+
+func (p *Prometheus) ObserveTransformation(source, target, typ string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	p.add("llms_transformations_total", 1, "source", source, "target", target, "type", typ, "result", result)
+}
+
+func (p *Prometheus) ObserveWarning(code string) {
+	p.add("llms_warnings_total", 1, "code", code)
+}
+
+func (p *Prometheus) ObserveTokens(provider, model string, input, output int) {
+	p.add("llms_tokens_total", float64(input), "provider", provider, "model", model, "direction", "input")
+	p.add("llms_tokens_total", float64(output), "provider", provider, "model", model, "direction", "output")
+}
+
+func (p *Prometheus) ObserveUpstreamError(provider string, status int) {
+	p.add("llms_upstream_errors_total", 1, "provider", provider, "status", strconv.Itoa(status))
+}
+
+func (p *Prometheus) ObserveStream(provider string, d time.Duration) {
+	labels := labelSet("provider", provider)
+	seconds := d.Seconds()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts, ok := p.streamBuckets[labels]
+	if !ok {
+		counts = make([]uint64, len(p.buckets)+1)
+		p.streamBuckets[labels] = counts
+	}
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			counts[i]++
+		}
+	}
+	counts[len(p.buckets)]++
+	p.streamSum[labels] += seconds
+}
+
+func (p *Prometheus) add(name string, value float64, labels ...string) {
+	set := labelSet(labels...)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counters[name] == nil {
+		p.counters[name] = make(map[string]float64)
+	}
+	p.counters[name][set] += value
+}
+
+// labelSet renders label pairs in the exposition format, e.g. {code="x"}
+func labelSet(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"="+strconv.Quote(pairs[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withLabel appends one label to a rendered label set
+func withLabel(set, name, value string) string {
+	label := name + "=" + strconv.Quote(value)
+	if set == "{}" {
+		return "{" + label + "}"
+	}
+	return strings.TrimSuffix(set, "}") + "," + label + "}"
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	names := make([]string, 0, len(p.counters))
+	for name := range p.counters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, promHelp[name], name)
+		sets := sortedKeys(p.counters[name])
+		for _, set := range sets {
+			fmt.Fprintf(&b, "%s%s %s\n", name, set, strconv.FormatFloat(p.counters[name][set], 'g', -1, 64))
+		}
+	}
+
+	if len(p.streamBuckets) > 0 {
+		const name = "llms_stream_duration_seconds"
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, promHelp[name], name)
+		for _, set := range sortedKeys(p.streamBuckets) {
+			counts := p.streamBuckets[set]
+			for i, bound := range p.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(set, "le", strconv.FormatFloat(bound, 'g', -1, 64)), counts[i])
+			}
+			total := counts[len(p.buckets)]
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(set, "le", "+Inf"), total)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, set, strconv.FormatFloat(p.streamSum[set], 'g', -1, 64))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, set, total)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics endpoint.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
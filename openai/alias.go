@@ -0,0 +1,91 @@
+// Package openai is kept for migration only; the types now live in
+// github.com/phosae/llms/dto/openai.
+//
+// Deprecated: import github.com/phosae/llms/dto/openai instead.
+package openai
+
+import "github.com/phosae/llms/dto/openai"
+
+type (
+	ChatCompletionChoice                   = openai.ChatCompletionChoice
+	ChatCompletionMessage                  = openai.ChatCompletionMessage
+	ChatCompletionRequest                  = openai.ChatCompletionRequest
+	ChatCompletionRequestExtensions        = openai.ChatCompletionRequestExtensions
+	ChatCompletionResponse                 = openai.ChatCompletionResponse
+	ChatCompletionResponseFormat           = openai.ChatCompletionResponseFormat
+	ChatCompletionResponseFormatJSONSchema = openai.ChatCompletionResponseFormatJSONSchema
+	ChatCompletionResponseFormatType       = openai.ChatCompletionResponseFormatType
+	ChatCompletionStreamChoice             = openai.ChatCompletionStreamChoice
+	ChatCompletionStreamChoiceDelta        = openai.ChatCompletionStreamChoiceDelta
+	ChatCompletionStreamChoiceLogprobs     = openai.ChatCompletionStreamChoiceLogprobs
+	ChatCompletionStreamResponse           = openai.ChatCompletionStreamResponse
+	ChatCompletionTokenLogprob             = openai.ChatCompletionTokenLogprob
+	ChatCompletionTokenLogprobTopLogprob   = openai.ChatCompletionTokenLogprobTopLogprob
+	ChatMessageFile                        = openai.ChatMessageFile
+	ChatMessageImageURL                    = openai.ChatMessageImageURL
+	ChatMessageInputAudio                  = openai.ChatMessageInputAudio
+	ChatMessagePart                        = openai.ChatMessagePart
+	ChatMessagePartType                    = openai.ChatMessagePartType
+	CompletionTokensDetails                = openai.CompletionTokensDetails
+	ContentFilterResults                   = openai.ContentFilterResults
+	FinishReason                           = openai.FinishReason
+	FunctionCall                           = openai.FunctionCall
+	FunctionDefine                         = openai.FunctionDefine
+	FunctionDefinition                     = openai.FunctionDefinition
+	Hate                                   = openai.Hate
+	ImageURLDetail                         = openai.ImageURLDetail
+	JailBreak                              = openai.JailBreak
+	LogProb                                = openai.LogProb
+	LogProbs                               = openai.LogProbs
+	Prediction                             = openai.Prediction
+	Profanity                              = openai.Profanity
+	PromptAnnotation                       = openai.PromptAnnotation
+	PromptFilterResult                     = openai.PromptFilterResult
+	PromptTokensDetails                    = openai.PromptTokensDetails
+	SelfHarm                               = openai.SelfHarm
+	ServiceTier                            = openai.ServiceTier
+	Sexual                                 = openai.Sexual
+	StreamOptions                          = openai.StreamOptions
+	Tool                                   = openai.Tool
+	ToolCall                               = openai.ToolCall
+	ToolChoice                             = openai.ToolChoice
+	ToolFunction                           = openai.ToolFunction
+	ToolType                               = openai.ToolType
+	TopLogProbs                            = openai.TopLogProbs
+	Usage                                  = openai.Usage
+	Violence                               = openai.Violence
+)
+
+const (
+	ChatMessageRoleSystem                      = openai.ChatMessageRoleSystem
+	ChatMessageRoleUser                        = openai.ChatMessageRoleUser
+	ChatMessageRoleAssistant                   = openai.ChatMessageRoleAssistant
+	ChatMessageRoleFunction                    = openai.ChatMessageRoleFunction
+	ChatMessageRoleTool                        = openai.ChatMessageRoleTool
+	ChatMessageRoleDeveloper                   = openai.ChatMessageRoleDeveloper
+	ChatCompletionResponseFormatTypeJSONObject = openai.ChatCompletionResponseFormatTypeJSONObject
+	ChatCompletionResponseFormatTypeJSONSchema = openai.ChatCompletionResponseFormatTypeJSONSchema
+	ChatCompletionResponseFormatTypeText       = openai.ChatCompletionResponseFormatTypeText
+	ChatMessagePartTypeText                    = openai.ChatMessagePartTypeText
+	ChatMessagePartTypeImageURL                = openai.ChatMessagePartTypeImageURL
+	ChatMessagePartTypeInputAudio              = openai.ChatMessagePartTypeInputAudio
+	ChatMessagePartTypeFile                    = openai.ChatMessagePartTypeFile
+	FinishReasonStop                           = openai.FinishReasonStop
+	FinishReasonLength                         = openai.FinishReasonLength
+	FinishReasonFunctionCall                   = openai.FinishReasonFunctionCall
+	FinishReasonToolCalls                      = openai.FinishReasonToolCalls
+	FinishReasonContentFilter                  = openai.FinishReasonContentFilter
+	FinishReasonNull                           = openai.FinishReasonNull
+	ImageURLDetailHigh                         = openai.ImageURLDetailHigh
+	ImageURLDetailLow                          = openai.ImageURLDetailLow
+	ImageURLDetailAuto                         = openai.ImageURLDetailAuto
+	ServiceTierAuto                            = openai.ServiceTierAuto
+	ServiceTierDefault                         = openai.ServiceTierDefault
+	ServiceTierFlex                            = openai.ServiceTierFlex
+	ServiceTierPriority                        = openai.ServiceTierPriority
+	ToolTypeFunction                           = openai.ToolTypeFunction
+)
+
+var (
+	ErrContentFieldsMisused = openai.ErrContentFieldsMisused
+)
@@ -0,0 +1,63 @@
+package sse
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// LogRecord is one line of a stream log: an event of a translated stream,
+// the pair that produced it and when.
+type LogRecord struct {
+	Source transformer.Provider `json:"source"`
+	Target transformer.Provider `json:"target"`
+	// Index is the position of the event in the stream, from 0.
+	Index int       `json:"index"`
+	Time  time.Time `json:"time"`
+	// ElapsedMS is the time since the first event of the stream.
+	ElapsedMS float64 `json:"elapsed_ms"`
+	Event     any     `json:"event"`
+}
+
+// LogWriter writes the events of one stream as newline delimited JSON
+// LogRecords, for jq and log processors. Each record is written with a
+// single Write.
+type LogWriter struct {
+	w              io.Writer
+	source, target transformer.Provider
+	n              int
+	start          time.Time
+}
+
+func NewLogWriter(w io.Writer, source, target transformer.Provider) *LogWriter {
+	return &LogWriter{w: w, source: source, target: target}
+}
+
+// Write logs event, a payload or the raw JSON of one, as the next record.
+func (l *LogWriter) Write(event any) error {
+	return l.WriteAt(event, time.Now())
+}
+
+// WriteAt logs event as written at t, to keep the timing of a recorded
+// stream.
+func (l *LogWriter) WriteAt(event any, t time.Time) error {
+	if l.n == 0 {
+		l.start = t
+	}
+	data, err := json.Marshal(LogRecord{
+		Source:    l.source,
+		Target:    l.target,
+		Index:     l.n,
+		Time:      t,
+		ElapsedMS: float64(t.Sub(l.start).Microseconds()) / 1000,
+		Event:     event,
+	})
+	if err != nil {
+		return err
+	}
+	l.n++
+	_, err = l.w.Write(append(data, '\n'))
+	return err
+}
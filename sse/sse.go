@@ -0,0 +1,372 @@
+// Package sse reads and writes the stream framing of the provider APIs:
+// server-sent events, named after their payload type for Claude and
+// terminated by a [DONE] sentinel for OpenAI, the JSON array Gemini
+// streams when alt=sse is not requested, the newline delimited JSON of
+// Ollama and, for writing, the AWS event streams of Bedrock.
+//
+//	r := sse.NewReader(resp.Body)
+//	for {
+//		ev, err := r.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+//
+// or, with iterators:
+//
+//	for ev, err := range sse.NewReader(resp.Body).Events() {
+//		...
+//	}
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/eventstream"
+	"github.com/phosae/llms/transformer"
+)
+
+// maxEventSize bounds the lines of an event, long enough for inline images.
+const maxEventSize = 32 * 1024 * 1024
+
+// Event is one server-sent event, one element of a JSON array stream or one
+// line of a newline delimited JSON stream.
+type Event struct {
+	// Event is the event name, empty for unnamed events.
+	Event string
+	// Data is the payload, the data lines joined by newlines.
+	Data string
+	ID   string
+}
+
+// Reader parses a stream into events. A stream whose first byte is '[' is
+// read as a JSON array, one event per element, and one whose first byte is
+// '{' as newline delimited JSON, one event per line.
+type Reader struct {
+	br      *bufio.Reader
+	scanner *bufio.Scanner
+	array   *json.Decoder
+	lines   bool
+	started bool
+	done    bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// Next returns the next event. It returns io.EOF at the end of the stream
+// and after the [DONE] sentinel, which is not returned as an event.
+func (r *Reader) Next() (Event, error) {
+	if r.done {
+		return Event{}, io.EOF
+	}
+	if !r.started {
+		if err := r.start(); err != nil {
+			return Event{}, err
+		}
+	}
+	if r.array != nil {
+		return r.nextElement()
+	}
+	if r.lines {
+		return r.nextLine()
+	}
+	return r.nextEvent()
+}
+
+// Events yields the remaining events. A failing read is yielded with its
+// error and ends the iteration; the end of the stream ends it without one.
+func (r *Reader) Events() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for {
+			ev, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(ev, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (r *Reader) start() error {
+	r.started = true
+	for {
+		b, err := r.br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		if err := r.br.UnreadByte(); err != nil {
+			return err
+		}
+		if b == '[' {
+			r.array = json.NewDecoder(r.br)
+			// consume the opening bracket, so More and Decode walk the elements
+			_, err := r.array.Token()
+			return err
+		}
+		r.lines = b == '{'
+		r.scanner = bufio.NewScanner(r.br)
+		r.scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+		return nil
+	}
+}
+
+func (r *Reader) nextElement() (Event, error) {
+	if !r.array.More() {
+		r.done = true
+		return Event{}, io.EOF
+	}
+	var element json.RawMessage
+	if err := r.array.Decode(&element); err != nil {
+		return Event{}, fmt.Errorf("invalid JSON array stream: %w", err)
+	}
+	return Event{Data: string(element)}, nil
+}
+
+func (r *Reader) nextLine() (Event, error) {
+	for r.scanner.Scan() {
+		if line := strings.TrimSpace(r.scanner.Text()); line != "" {
+			return Event{Data: line}, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	r.done = true
+	return Event{}, io.EOF
+}
+
+func (r *Reader) nextEvent() (Event, error) {
+	var ev Event
+	var data []string
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if len(data) == 0 {
+				ev = Event{}
+				continue
+			}
+			return r.dispatch(ev, data)
+		}
+		if strings.HasPrefix(line, ":") {
+			// comment, e.g. a keep-alive
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	if len(data) > 0 {
+		// the stream ended without the blank line after the last event
+		return r.dispatch(ev, data)
+	}
+	r.done = true
+	return Event{}, io.EOF
+}
+
+func (r *Reader) dispatch(ev Event, data []string) (Event, error) {
+	ev.Data = strings.Join(data, "\n")
+	if ev.Data == transformer.StreamDone {
+		r.done = true
+		return Event{}, io.EOF
+	}
+	return ev, nil
+}
+
+// Writer serializes the stream of one provider. Events are flushed as
+// they are written when the destination is an http.Flusher.
+type Writer struct {
+	w        io.Writer
+	provider transformer.Provider
+	array    bool
+	n        int
+	closed   bool
+	// ids numbers the events.
+	ids bool
+}
+
+// NewWriter writes server-sent events: Claude events are named after the
+// type of their payload, OpenAI streams end with [DONE]. Ollama streams are
+// written as newline delimited JSON, Bedrock streams as AWS event streams
+// whose messages have the Event of an event as their :event-type.
+func NewWriter(w io.Writer, provider transformer.Provider) *Writer {
+	return &Writer{w: w, provider: provider}
+}
+
+// NewGeminiArrayWriter writes a Gemini stream as a JSON array, the framing
+// of streamGenerateContent without alt=sse.
+func NewGeminiArrayWriter(w io.Writer) *Writer {
+	return &Writer{w: w, provider: transformer.ProviderGemini, array: true}
+}
+
+// NumberEvents makes w set the id of the events it writes to their position
+// in the stream, from 1, for clients resuming it with Last-Event-ID. JSON
+// arrays, Ollama and Bedrock streams carry no ids.
+func (w *Writer) NumberEvents() {
+	w.ids = true
+}
+
+// ContentType is the content type of the stream.
+func (w *Writer) ContentType() string {
+	if w.array {
+		return "application/json"
+	}
+	switch w.provider {
+	case transformer.ProviderOllama:
+		return "application/x-ndjson"
+	case transformer.ProviderBedrock:
+		return eventstream.NewWriter(nil).ContentType()
+	}
+	return "text/event-stream"
+}
+
+// Write writes v as the JSON payload of the next event.
+func (w *Writer) Write(v any) error {
+	if w.provider == transformer.ProviderBedrock {
+		event, ok := v.(*bedrock.ConverseStreamEvent)
+		if !ok {
+			return fmt.Errorf("sse: unexpected bedrock event %T", v)
+		}
+		data, err := json.Marshal(event.Payload())
+		if err != nil {
+			return err
+		}
+		return w.WriteEvent(Event{Event: event.EventType(), Data: string(data)})
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ev := Event{Data: string(data)}
+	if w.provider == transformer.ProviderClaude {
+		var payload struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return err
+		}
+		ev.Event = payload.Type
+	}
+	return w.WriteEvent(ev)
+}
+
+// WriteEvent writes ev as is.
+func (w *Writer) WriteEvent(ev Event) error {
+	if w.closed {
+		return errors.New("sse: write after close")
+	}
+	if w.ids {
+		ev.ID = strconv.Itoa(w.n + 1)
+	}
+	var buf bytes.Buffer
+	if w.array {
+		if w.n == 0 {
+			buf.WriteString("[")
+		} else {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(ev.Data)
+	} else if w.provider == transformer.ProviderOllama {
+		buf.WriteString(ev.Data)
+		buf.WriteString("\n")
+	} else if w.provider == transformer.ProviderBedrock {
+		msg, err := eventstream.NewEvent(ev.Event, json.RawMessage(ev.Data))
+		if err != nil {
+			return err
+		}
+		if err := eventstream.NewWriter(&buf).Write(msg); err != nil {
+			return err
+		}
+	} else {
+		if ev.ID != "" {
+			fmt.Fprintf(&buf, "id: %s\n", ev.ID)
+		}
+		if ev.Event != "" {
+			fmt.Fprintf(&buf, "event: %s\n", ev.Event)
+		}
+		for _, line := range strings.Split(ev.Data, "\n") {
+			fmt.Fprintf(&buf, "data: %s\n", line)
+		}
+		buf.WriteString("\n")
+	}
+	w.n++
+	return w.write(buf.Bytes())
+}
+
+// WriteException ends a Bedrock stream with an exception message of
+// exceptionType, e.g. throttlingException. Other streams report errors in
+// events of their payload type.
+func (w *Writer) WriteException(exceptionType, message string) error {
+	if w.provider != transformer.ProviderBedrock {
+		return fmt.Errorf("sse: %s streams carry no exceptions", w.provider)
+	}
+	if w.closed {
+		return errors.New("sse: write after close")
+	}
+	var buf bytes.Buffer
+	if err := eventstream.NewWriter(&buf).Write(eventstream.NewException(exceptionType, message)); err != nil {
+		return err
+	}
+	w.n++
+	return w.write(buf.Bytes())
+}
+
+// Close ends the stream: [DONE] for OpenAI, the closing bracket of a JSON
+// array. It does not close the destination.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	var end string
+	switch {
+	case w.array && w.n == 0:
+		end = "[]"
+	case w.array:
+		end = "]"
+	case w.provider == transformer.ProviderOpenAI:
+		end = "data: " + transformer.StreamDone + "\n\n"
+	}
+	w.closed = true
+	if end == "" {
+		return nil
+	}
+	return w.write([]byte(end))
+}
+
+func (w *Writer) write(p []byte) error {
+	if _, err := w.w.Write(p); err != nil {
+		return err
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/eventstream"
+	"github.com/phosae/llms/transformer"
+)
+
+// Stream translates a provider stream, as read from the body of a streaming
+// response, into the chunks of another provider:
+//
+//	stream := sse.NewStream(resp.Body, transformer.ProviderClaude, transformer.ProviderOpenAI)
+//	for chunk, err := range stream.Events(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Bedrock sources are read as AWS event streams.
+type Stream struct {
+	// Model names the stream when the source sends no model, as Gemini.
+	Model string
+
+	r              io.Reader
+	source, target transformer.Provider
+}
+
+func NewStream(r io.Reader, source, target transformer.Provider) *Stream {
+	return &Stream{r: r, source: source, target: target}
+}
+
+// Events yields the chunks of the target, pointers to its chunk type, as
+// the events of the source arrive. ctx carries the options and warnings of
+// the translation; once it is done, the iteration ends with its error. A
+// stream is consumed once.
+func (s *Stream) Events(ctx context.Context) iter.Seq2[any, error] {
+	converter := transformer.NewStreamConverter(ctx, s.source, s.target)
+	converter.Model = s.Model
+	return converter.All(s.chunks(ctx))
+}
+
+// StreamPipe translates the stream of source read from r into the stream of
+// target written to w, flushing each event when w is an http.Flusher. The
+// output ends as streams of target do, with [DONE] for OpenAI and
+// message_stop for Claude. Bedrock streams are read and written as AWS event
+// streams. Errors are returned rather than written, so the caller decides
+// how to report them, and the output is not terminated then.
+func StreamPipe(ctx context.Context, source, target transformer.Provider, r io.Reader, w io.Writer) error {
+	out := NewWriter(w, target)
+	for chunk, err := range NewStream(r, source, target).Events(ctx) {
+		if err != nil {
+			return err
+		}
+		if err := out.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+// chunks yields the events of the source decoded into its chunk type.
+func (s *Stream) chunks(ctx context.Context) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		if s.source == transformer.ProviderBedrock {
+			s.bedrockChunks(ctx, yield)
+			return
+		}
+		for ev, err := range NewReader(s.r).Events() {
+			if err == nil {
+				err = ctx.Err()
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			chunk, err := transformer.NewPayload(s.source, transformer.TransformerTypeChunk)
+			if err == nil {
+				err = json.Unmarshal([]byte(ev.Data), chunk)
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("invalid %s event: %w", s.source, err))
+				return
+			}
+			if s.source == transformer.ProviderOllama {
+				// Ollama ends a failing stream with an error object
+				var failure ollama.ErrorResponse
+				if json.Unmarshal([]byte(ev.Data), &failure) == nil && failure.Error != "" {
+					yield(nil, fmt.Errorf("stream error: %s", failure.Error))
+					return
+				}
+			}
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Stream) bedrockChunks(ctx context.Context, yield func(any, error) bool) {
+	for msg, err := range eventstream.NewReader(s.r).Messages() {
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			err = msg.Err()
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		event, err := bedrock.ParseConverseStreamEvent(msg.EventType(), msg.Payload)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if !yield(event, nil) {
+			return
+		}
+	}
+}
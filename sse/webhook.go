@@ -0,0 +1,145 @@
+package sse
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"time"
+)
+
+// DefaultWebhookInterval is the time a Webhook collects chunks for when its
+// Interval is zero.
+const DefaultWebhookInterval = 500 * time.Millisecond
+
+// Webhook delivers a translated stream to a callback URL in batches, for
+// consumers that cannot hold a streaming connection, such as serverless
+// functions:
+//
+//	hook := &sse.Webhook{URL: callbackURL, Interval: time.Second}
+//	err := hook.Deliver(ctx, id, sse.NewStream(resp.Body, source, target).Events(ctx))
+//
+// The Chunking option of the translation coalesces the text deltas of a
+// batch further.
+type Webhook struct {
+	// URL receives the batches, POSTed as JSON WebhookBatches.
+	URL string
+	// Header is set on every delivery, e.g. a bearer token.
+	Header http.Header
+	// Interval is the time chunks are collected for before a delivery.
+	Interval time.Duration
+	// Client sends the deliveries, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// WebhookBatch is the body of a delivery.
+type WebhookBatch struct {
+	// ID names the stream, the same in each of its batches.
+	ID string `json:"id"`
+	// Sequence numbers the batches of a stream from 0.
+	Sequence int `json:"sequence"`
+	// Chunks are the chunks of the target stream since the previous batch.
+	Chunks []json.RawMessage `json:"chunks"`
+	// Done marks the last batch of the stream.
+	Done bool `json:"done,omitempty"`
+	// Error is the error a failed stream ended with.
+	Error string `json:"error,omitempty"`
+}
+
+// Deliver collects chunks, e.g. the Events of a Stream, and posts those
+// collected every Interval, skipping empty batches, then a last batch marked
+// Done. A stream failing is delivered with its error, which Deliver returns.
+// Deliveries are sent one at a time, in order; the first one failing ends
+// Deliver with its error. ctx bounds the stream and the deliveries.
+func (h *Webhook) Deliver(ctx context.Context, id string, chunks iter.Seq2[any, error]) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		chunk any
+		err   error
+	}
+	results := make(chan result)
+	go func() {
+		defer close(results)
+		for chunk, err := range chunks {
+			select {
+			case results <- result{chunk, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(cmp.Or(h.Interval, DefaultWebhookInterval))
+	defer ticker.Stop()
+	batch := &WebhookBatch{ID: id, Chunks: []json.RawMessage{}}
+	for {
+		select {
+		case r, ok := <-results:
+			switch {
+			case !ok:
+				batch.Done = true
+				return h.post(ctx, batch)
+			case r.err != nil:
+				batch.Done, batch.Error = true, r.err.Error()
+				if err := h.post(ctx, batch); err != nil {
+					return err
+				}
+				return r.err
+			}
+			data, err := json.Marshal(r.chunk)
+			if err != nil {
+				return err
+			}
+			batch.Chunks = append(batch.Chunks, data)
+		case <-ticker.C:
+			if len(batch.Chunks) == 0 {
+				continue
+			}
+			if err := h.post(ctx, batch); err != nil {
+				return err
+			}
+			batch = &WebhookBatch{ID: id, Sequence: batch.Sequence + 1, Chunks: []json.RawMessage{}}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// post delivers batch, failing on any status but 2xx.
+func (h *Webhook) post(ctx context.Context, batch *WebhookBatch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range h.Header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("deliver batch %d: %w", batch.Sequence, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("deliver batch %d: webhook answered %s", batch.Sequence, resp.Status)
+	}
+	return nil
+}
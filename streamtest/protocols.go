@@ -0,0 +1,215 @@
+package streamtest
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/transformer"
+)
+
+var (
+	geminiFinishReasons = map[string]string{"stop": "STOP", "length": "MAX_TOKENS", "tool_calls": "STOP"}
+	claudeStopReasons   = map[string]string{"stop": "end_turn", "length": "max_tokens", "tool_calls": "tool_use"}
+)
+
+type recorder struct {
+	events []Event
+	err    error
+}
+
+func (r *recorder) add(delay time.Duration, event string, v any) {
+	if r.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.events = append(r.events, Event{Delay: delay, StreamEvent: transformer.StreamEvent{Event: event, Data: string(data)}})
+}
+
+func openAIEvents(s Script) ([]Event, error) {
+	r := &recorder{}
+	chunk := func(delta openai.ChatCompletionStreamChoiceDelta, finish openai.FinishReason) *openai.ChatCompletionStreamResponse {
+		return &openai.ChatCompletionStreamResponse{
+			ID:      s.ID,
+			Object:  "chat.completion.chunk",
+			Model:   s.Model,
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	role := openai.ChatMessageRoleAssistant
+	toolIndex, toolID := -1, ""
+	for _, step := range s.Steps {
+		delta := openai.ChatCompletionStreamChoiceDelta{Role: role}
+		role = ""
+		switch {
+		case step.Text != "":
+			delta.Content = step.Text
+		case step.Thinking != "":
+			delta.ReasoningContent = step.Thinking
+		default:
+			call := openai.ToolCall{Function: openai.FunctionCall{Arguments: step.ToolCall.Arguments}}
+			if step.ToolCall.ID != toolID {
+				toolIndex, toolID = toolIndex+1, step.ToolCall.ID
+				call.ID, call.Type, call.Function.Name = step.ToolCall.ID, openai.ToolTypeFunction, step.ToolCall.Name
+			}
+			index := toolIndex
+			call.Index = &index
+			delta.ToolCalls = []openai.ToolCall{call}
+		}
+		r.add(step.Delay, "", chunk(delta, ""))
+	}
+
+	final := chunk(openai.ChatCompletionStreamChoiceDelta{Role: role}, openai.FinishReason(s.FinishReason))
+	r.add(0, "", final)
+	if s.Usage != (transformer.StreamUsage{}) {
+		r.add(0, "", transformer.OpenAIUsageChunk(final, s.Usage.OpenAI()))
+	}
+	r.events = append(r.events, Event{StreamEvent: transformer.StreamEvent{Data: transformer.StreamDone}})
+	return r.events, r.err
+}
+
+func geminiEvents(s Script) ([]Event, error) {
+	r := &recorder{}
+	chunk := func(parts []gemini.GeminiPart) *gemini.GeminiChatResponse {
+		return &gemini.GeminiChatResponse{
+			Candidates: []gemini.GeminiChatCandidate{{Content: gemini.GeminiChatContent{Role: "model", Parts: parts}}},
+		}
+	}
+
+	// Gemini sends function calls whole, fragments are sent with the call's last step
+	var pending *gemini.GeminiPart
+	var pendingID string
+	var pendingArgs strings.Builder
+	var pendingDelay time.Duration
+	flush := func() {
+		if pending == nil {
+			return
+		}
+		var args any
+		if err := json.Unmarshal([]byte(pendingArgs.String()), &args); err != nil && r.err == nil {
+			r.err = err
+		}
+		pending.FunctionCall.Arguments = args
+		r.add(pendingDelay, "", chunk([]gemini.GeminiPart{*pending}))
+		pending, pendingID, pendingDelay = nil, "", 0
+		pendingArgs.Reset()
+	}
+
+	for _, step := range s.Steps {
+		if step.ToolCall != nil && step.ToolCall.ID == pendingID && pending != nil {
+			pendingArgs.WriteString(step.ToolCall.Arguments)
+			pendingDelay += step.Delay
+			continue
+		}
+		flush()
+		switch {
+		case step.Text != "":
+			r.add(step.Delay, "", chunk([]gemini.GeminiPart{{Text: step.Text}}))
+		case step.Thinking != "":
+			r.add(step.Delay, "", chunk([]gemini.GeminiPart{{Text: step.Thinking, Thought: true}}))
+		default:
+			pending = &gemini.GeminiPart{FunctionCall: &gemini.FunctionCall{FunctionName: step.ToolCall.Name}}
+			pendingID, pendingDelay = step.ToolCall.ID, step.Delay
+			pendingArgs.WriteString(step.ToolCall.Arguments)
+		}
+	}
+	flush()
+
+	finish := geminiFinishReasons[s.FinishReason]
+	if finish == "" {
+		finish = "OTHER"
+	}
+	final := chunk(nil)
+	final.Candidates[0].FinishReason = &finish
+	final.UsageMetadata = s.Usage.Gemini()
+	r.add(0, "", final)
+	return r.events, r.err
+}
+
+func claudeEvents(s Script) ([]Event, error) {
+	r := &recorder{}
+	add := func(delay time.Duration, event *claude.ClaudeResponse) {
+		r.add(delay, event.Type, event)
+	}
+	index := func(i int) *int { return &i }
+
+	usage := s.Usage.Claude()
+	add(0, &claude.ClaudeResponse{
+		Type: "message_start",
+		Message: &claude.ClaudeMediaMessage{
+			Type:  "message",
+			Id:    s.ID,
+			Role:  "assistant",
+			Model: s.Model,
+			Usage: &claude.ClaudeUsage{
+				InputTokens:              usage.InputTokens,
+				CacheReadInputTokens:     usage.CacheReadInputTokens,
+				CacheCreationInputTokens: usage.CacheCreationInputTokens,
+			},
+			Content: []any{},
+		},
+	})
+
+	blockIndex, blockKey := -1, ""
+	for _, step := range s.Steps {
+		key := "text"
+		switch {
+		case step.Thinking != "":
+			key = "thinking"
+		case step.ToolCall != nil:
+			key = "tool_use:" + step.ToolCall.ID
+		}
+		delay := step.Delay
+		if key != blockKey {
+			if blockIndex >= 0 {
+				add(delay, &claude.ClaudeResponse{Type: "content_block_stop", Index: index(blockIndex)})
+				delay = 0
+			}
+			blockIndex, blockKey = blockIndex+1, key
+			block := &claude.ClaudeMediaMessage{Type: key}
+			switch {
+			case step.Text != "":
+				block.SetText("")
+			case step.ToolCall != nil:
+				block.Type, block.Id, block.Name, block.Input = "tool_use", step.ToolCall.ID, step.ToolCall.Name, map[string]any{}
+			}
+			add(delay, &claude.ClaudeResponse{Type: "content_block_start", Index: index(blockIndex), ContentBlock: block})
+			delay = 0
+		}
+
+		delta := &claude.ClaudeMediaMessage{}
+		switch {
+		case step.Text != "":
+			delta.Type = "text_delta"
+			delta.SetText(step.Text)
+		case step.Thinking != "":
+			delta.Type, delta.Thinking = "thinking_delta", step.Thinking
+		default:
+			delta.Type, delta.PartialJson = "input_json_delta", &step.ToolCall.Arguments
+		}
+		add(delay, &claude.ClaudeResponse{Type: "content_block_delta", Index: index(blockIndex), Delta: delta})
+	}
+	if blockIndex >= 0 {
+		add(0, &claude.ClaudeResponse{Type: "content_block_stop", Index: index(blockIndex)})
+	}
+
+	stopReason := claudeStopReasons[s.FinishReason]
+	if stopReason == "" {
+		stopReason = s.FinishReason
+	}
+	add(0, &claude.ClaudeResponse{
+		Type:  "message_delta",
+		Delta: &claude.ClaudeMediaMessage{StopReason: &stopReason},
+		Usage: usage,
+	})
+	add(0, &claude.ClaudeResponse{Type: "message_stop"})
+	return r.events, r.err
+}
@@ -0,0 +1,116 @@
+package streamtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/phosae/llms/sse"
+	"github.com/phosae/llms/transformer"
+)
+
+// ReadLog reads a recorded stream, the NDJSON sse.LogRecords written by an
+// sse.LogWriter such as the StreamLog of a gateway Proxy, into events whose
+// delays reproduce the recorded timing.
+func ReadLog(r io.Reader) ([]Event, error) {
+	var events []Event
+	var last float64
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var record struct {
+			ElapsedMS float64         `json:"elapsed_ms"`
+			Event     json.RawMessage `json:"event"`
+		}
+		if err := dec.Decode(&record); err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(events), err)
+		}
+		delay := time.Duration((record.ElapsedMS - last) * float64(time.Millisecond))
+		last = record.ElapsedMS
+		events = append(events, Event{Delay: max(delay, 0), StreamEvent: transformer.StreamEvent{Event: eventName(record.Event), Data: string(record.Event)}})
+	}
+	return events, nil
+}
+
+// WriteLog writes events as sse.LogRecords timed by their delays, from now.
+// The [DONE] sentinel has no record.
+func WriteLog(w io.Writer, source, target transformer.Provider, events []Event) error {
+	log := sse.NewLogWriter(w, source, target)
+	at := time.Now()
+	for _, ev := range events {
+		at = at.Add(ev.Delay)
+		if ev.Data == transformer.StreamDone {
+			continue
+		}
+		if err := log.WriteAt(json.RawMessage(ev.Data), at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Translate converts the recorded events of source into events of target,
+// keeping the timing: the first event translated from a source event waits
+// for its delay, the others follow at once, as a translating proxy would
+// send them. ctx carries the options of the translation.
+func Translate(ctx context.Context, source, target transformer.Provider, events []Event) ([]Event, error) {
+	converter := transformer.NewStreamConverter(ctx, source, target)
+	var translated []Event
+	add := func(delay time.Duration, chunks []any) error {
+		for _, chunk := range chunks {
+			data, err := json.Marshal(chunk)
+			if err != nil {
+				return err
+			}
+			translated = append(translated, Event{Delay: delay, StreamEvent: transformer.StreamEvent{Event: eventName(data), Data: string(data)}})
+			delay = 0
+		}
+		return nil
+	}
+	var pending time.Duration
+	for i, ev := range events {
+		pending += ev.Delay
+		if ev.Data == transformer.StreamDone {
+			continue
+		}
+		chunk, err := transformer.NewPayload(source, transformer.TransformerTypeChunk)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(ev.Data), chunk); err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		chunks, err := converter.Transform(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		if len(chunks) > 0 {
+			if err := add(pending, chunks); err != nil {
+				return nil, err
+			}
+			pending = 0
+		}
+	}
+	chunks, err := converter.Finish()
+	if err != nil {
+		return nil, err
+	}
+	if err := add(pending, chunks); err != nil {
+		return nil, err
+	}
+	if target == transformer.ProviderOpenAI {
+		translated = append(translated, Event{StreamEvent: transformer.StreamEvent{Data: transformer.StreamDone}})
+	}
+	return translated, nil
+}
+
+// eventName returns the SSE event name of a payload: its type for Claude
+// events, none for the others.
+func eventName(data []byte) string {
+	var payload struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(data, &payload)
+	return payload.Type
+}
@@ -0,0 +1,173 @@
+// Package streamtest generates deterministic provider streams from scripts, so
+// stream consumers can be integration-tested against all three protocols
+// without live APIs.
+//
+//	script := streamtest.Script{
+//		Steps: []streamtest.Step{
+//			{Text: "Hello"},
+//			{Text: " world", Delay: 50 * time.Millisecond},
+//		},
+//		FinishReason: "stop",
+//	}
+//	srv := httptest.NewServer(streamtest.Handler(transformer.ProviderClaude, script))
+package streamtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// Script describes the content of a stream independently of its protocol.
+type Script struct {
+	ID    string
+	Model string
+	Steps []Step
+	// FinishReason is stop, length or tool_calls, stop when empty.
+	FinishReason string
+	Usage        transformer.StreamUsage
+	// MaxDelta splits the text and thinking of steps into deltas of at most
+	// MaxDelta bytes, with transformer.SplitText. Zero keeps steps whole.
+	MaxDelta int
+}
+
+// Step is one delta of a stream. Exactly one of Text, Thinking and ToolCall is set.
+type Step struct {
+	// Delay is waited before the step is sent.
+	Delay    time.Duration
+	Text     string
+	Thinking string
+	// ToolCall streams a fragment of a call. Consecutive steps with the same
+	// call ID extend the call's arguments.
+	ToolCall *ToolCall
+}
+
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// Event is a stream event with the delay to wait before sending it.
+type Event struct {
+	Delay time.Duration
+	transformer.StreamEvent
+}
+
+// Events renders script in the protocol of provider.
+func Events(provider transformer.Provider, script Script) ([]Event, error) {
+	if script.ID == "" {
+		script.ID = "scripted"
+	}
+	if script.Model == "" {
+		script.Model = "scripted-model"
+	}
+	if script.FinishReason == "" {
+		script.FinishReason = "stop"
+	}
+	var steps []Step
+	for i, step := range script.Steps {
+		if kinds(step) != 1 {
+			return nil, fmt.Errorf("step %d: exactly one of Text, Thinking and ToolCall must be set", i)
+		}
+		steps = append(steps, splitStep(step, script.MaxDelta)...)
+	}
+	script.Steps = steps
+
+	switch provider {
+	case transformer.ProviderOpenAI:
+		return openAIEvents(script)
+	case transformer.ProviderGemini:
+		return geminiEvents(script)
+	case transformer.ProviderClaude:
+		return claudeEvents(script)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// splitStep splits the text of step into deltas of at most size bytes, the
+// first one keeping the delay of the step.
+func splitStep(step Step, size int) []Step {
+	if step.ToolCall != nil || size <= 0 {
+		return []Step{step}
+	}
+	text := step.Text
+	if step.Thinking != "" {
+		text = step.Thinking
+	}
+	var steps []Step
+	for i, piece := range transformer.SplitText(text, size) {
+		delta := step
+		if i > 0 {
+			delta.Delay = 0
+		}
+		if step.Thinking != "" {
+			delta.Thinking = piece
+		} else {
+			delta.Text = piece
+		}
+		steps = append(steps, delta)
+	}
+	return steps
+}
+
+func kinds(step Step) int {
+	n := 0
+	if step.Text != "" {
+		n++
+	}
+	if step.Thinking != "" {
+		n++
+	}
+	if step.ToolCall != nil {
+		n++
+	}
+	return n
+}
+
+// Play writes the events as server-sent events, waiting for their delays.
+func Play(ctx context.Context, w io.Writer, events []Event) error {
+	flusher, _ := w.(http.Flusher)
+	for _, ev := range events {
+		if ev.Delay > 0 {
+			timer := time.NewTimer(ev.Delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if ev.Event != "" {
+			if _, err := fmt.Fprintf(w, "event: %s\n", ev.Event); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", ev.Data); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// Handler serves script as a provider stream on every request.
+func Handler(provider transformer.Provider, script Script) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events, err := Events(provider, script)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		Play(r.Context(), w, events)
+	})
+}
@@ -0,0 +1,206 @@
+package tokens
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encodings of OpenAI models.
+const (
+	CL100KBase = "cl100k_base"
+	O200KBase  = "o200k_base"
+)
+
+// splitPatterns split text into the pieces tiktoken encodes apart. RE2 has
+// no lookahead: the \s+(?!\S) alternative before the last \s+ is applied by
+// Encoding.pieces.
+var splitPatterns = map[string]*regexp.Regexp{
+	CL100KBase: splitPattern(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`),
+	O200KBase: splitPattern(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`),
+}
+
+// splitPattern compiles a tiktoken pattern, whose \s is Unicode white
+// space as unicode.IsSpace tells, where RE2's is ASCII.
+func splitPattern(pattern string) *regexp.Regexp {
+	const space = `\s\x0B\x{85}\p{Z}`
+	return regexp.MustCompile(strings.NewReplacer(`[^\s`, `[^`+space, `\s`, `[`+space+`]`).Replace(pattern))
+}
+
+// Encoding is a byte pair encoding of tiktoken, the tokenizer of OpenAI
+// models. Encode matches tiktoken's encode_ordinary: special tokens are
+// encoded as text.
+type Encoding struct {
+	name  string
+	ranks map[string]int
+	split *regexp.Regexp
+}
+
+// LoadEncoding reads encoding name, cl100k_base or o200k_base, from its
+// .tiktoken file of base64 tokens and their ranks, as published at
+// https://openaipublic.blob.core.windows.net/encodings/{name}.tiktoken.
+func LoadEncoding(name string, r io.Reader) (*Encoding, error) {
+	split, ok := splitPatterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	e := &Encoding{name: name, ranks: make(map[string]int), split: split}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if scanner.Text() == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing rank", line)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
+		}
+		e.ranks[string(b)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for b := range 256 {
+		if _, ok := e.ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("no token for byte %#x", b)
+		}
+	}
+	return e, nil
+}
+
+// Name returns the name of the encoding, such as o200k_base.
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode returns the tokens of text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.pieces(text) {
+		tokens = e.encodePiece([]byte(piece), tokens)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens of text.
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// pieces splits text as the pattern of the encoding does.
+func (e *Encoding) pieces(text string) []string {
+	var pieces []string
+	for rest := text; rest != ""; {
+		loc := e.split.FindStringIndex(rest)
+		if loc == nil || loc[1] == 0 {
+			// every character matches the patterns, but be safe
+			_, size := utf8.DecodeRuneInString(rest)
+			loc = []int{0, size}
+		}
+		piece := rest[:loc[1]]
+		// \s+(?!\S) leaves the last space of a run before a non-space to it
+		if last, size := utf8.DecodeLastRuneInString(piece); loc[1] < len(rest) && size < len(piece) &&
+			strings.TrimSpace(piece) == "" && last != '\r' && last != '\n' {
+			if next, _ := utf8.DecodeRuneInString(rest[loc[1]:]); !unicode.IsSpace(next) {
+				piece = piece[:len(piece)-size]
+			}
+		}
+		pieces = append(pieces, piece)
+		rest = rest[len(piece):]
+	}
+	return pieces
+}
+
+// encodePiece appends the tokens of piece to tokens, merging the pair of
+// adjacent parts of lowest rank until none is a token.
+func (e *Encoding) encodePiece(piece []byte, tokens []int) []int {
+	if rank, ok := e.ranks[string(piece)]; ok {
+		return append(tokens, rank)
+	}
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, at := -1, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := e.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && (at < 0 || rank < best) {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		bounds = slices.Delete(bounds, at+1, at+2)
+	}
+	for i := 0; i+1 < len(bounds); i++ {
+		tokens = append(tokens, e.ranks[string(piece[bounds[i]:bounds[i+1]])])
+	}
+	return tokens
+}
+
+var (
+	encodingsMu sync.RWMutex
+	encodings   = map[string]*Encoding{}
+)
+
+// RegisterEncoding makes the counts of the OpenAI models using e exact; they
+// are estimated until their encoding is registered.
+func RegisterEncoding(e *Encoding) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	encodings[e.name] = e
+}
+
+// encodingOf returns the registered encoding of an OpenAI model, nil when
+// there is none.
+func encodingOf(model string) *Encoding {
+	encodingsMu.RLock()
+	defer encodingsMu.RUnlock()
+	return encodings[EncodingForModel(model)]
+}
+
+// modelEncodings are the encodings of OpenAI model families, by prefix.
+var modelEncodings = []struct{ prefix, encoding string }{
+	{"gpt-4o", O200KBase},
+	{"chatgpt-4o", O200KBase},
+	{"gpt-4.1", O200KBase},
+	{"gpt-4.5", O200KBase},
+	{"gpt-5", O200KBase},
+	{"gpt-oss", O200KBase},
+	{"o1", O200KBase},
+	{"o3", O200KBase},
+	{"o4", O200KBase},
+	{"gpt-4", CL100KBase},
+	{"gpt-3.5", CL100KBase},
+	{"text-embedding-3", CL100KBase},
+	{"text-embedding-ada-002", CL100KBase},
+}
+
+// EncodingForModel returns the name of the encoding of an OpenAI model, ""
+// for unknown models.
+func EncodingForModel(model string) string {
+	for _, m := range modelEncodings {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.encoding
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package tokens
+
+import (
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/transformer"
+)
+
+// GeminiCountTokensPath returns the path of the countTokens call of model,
+// relative to the base URL of provider, Gemini or Vertex AI.
+func GeminiCountTokensPath(provider transformer.Provider, model string) string {
+	model = strings.TrimPrefix(model, "models/")
+	if provider == transformer.ProviderVertexGemini {
+		return "/publishers/google/models/" + model + ":countTokens"
+	}
+	return "/v1beta/models/" + model + ":countTokens"
+}
+
+// GeminiCountTokensRequest returns the body of the Gemini countTokens call
+// counting req as generateContent sent to model would, its system
+// instruction, tools and cached content included.
+func GeminiCountTokensRequest(model string, req *gemini.GeminiChatRequest) *gemini.CountTokensRequest {
+	if !strings.HasPrefix(model, "models/") {
+		model = "models/" + model
+	}
+	return &gemini.CountTokensRequest{GenerateContentRequest: &gemini.GenerateContentRequest{Model: model, GeminiChatRequest: *req}}
+}
+
+// VertexCountTokensRequest returns the body of the Vertex AI countTokens
+// call counting req, which takes the fields of generateContent that count:
+// contents, system instruction, tools and generation config.
+func VertexCountTokensRequest(req *gemini.GeminiChatRequest) *gemini.GeminiChatRequest {
+	return &gemini.GeminiChatRequest{
+		Contents:           req.Contents,
+		SystemInstructions: req.SystemInstructions,
+		Tools:              req.Tools,
+		GenerationConfig:   req.GenerationConfig,
+	}
+}
@@ -0,0 +1,194 @@
+// Package tokens counts the input tokens of requests before they are sent,
+// so gateways can enforce context limits without a round trip:
+//
+//	n, err := tokens.CountRequest(transformer.ProviderClaude, claudeReq)
+//
+// OpenAI models are counted with their tiktoken encoding once it is
+// registered with RegisterEncoding, exactly for text. Claude tokens are
+// estimated, Claude publishing no tokenizer; Gemini ones too, exact counts
+// taking a countTokens call, whose requests GeminiCountTokensRequest
+// builds. Media whose size the request does not tell, such as images by
+// URL, count as a typical item of their kind.
+package tokens
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phosae/llms/transformer"
+)
+
+// counter counts the tokens of unified requests as a provider does.
+type counter struct {
+	// charsPerToken estimates the tokens of text, unless text counts it.
+	charsPerToken float64
+	text          func(model, text string) (int, bool)
+	// perMessage and perRequest frame the messages of the prompt.
+	perMessage, perRequest int
+	// tools is the overhead of declaring tools, on top of their definitions.
+	tools int
+	// image, audio and document count media of unknown size.
+	image, audio, document int
+}
+
+// counters by provider. OpenAI frames messages as the OpenAI cookbook
+// counts them and a high detail image of 1024x1024 takes 765 tokens;
+// Claude adds a system prompt of 346 tokens for tools and an image of
+// about 1.15 megapixels takes 1600; Gemini counts 258 tokens per image or
+// PDF page and 32 per second of audio, here 30 seconds and 5 pages.
+var (
+	openAICounter = counter{
+		charsPerToken: 4, text: openAIText,
+		perMessage: 3, perRequest: 3, tools: 12,
+		image: 765, audio: 1000, document: 1500,
+	}
+	claudeCounter = counter{
+		charsPerToken: 3.5,
+		perMessage:    3, perRequest: 1, tools: 346,
+		image: 1600, audio: 0, document: 1500,
+	}
+	geminiCounter = counter{
+		charsPerToken: 4,
+		perMessage:    1,
+		image:         258, audio: 960, document: 1290,
+	}
+	// defaultCounter estimates the tokens of models hosted by Bedrock,
+	// Ollama and others.
+	defaultCounter = counter{
+		charsPerToken: 4,
+		perMessage:    3, perRequest: 3,
+		image: 1000, audio: 1000, document: 1500,
+	}
+)
+
+// counterOf returns the counter of the models of provider.
+func counterOf(provider transformer.Provider, model string) counter {
+	switch provider {
+	case transformer.ProviderOpenAI:
+		return openAICounter
+	case transformer.ProviderClaude:
+		return claudeCounter
+	case transformer.ProviderGemini, transformer.ProviderVertexGemini:
+		return geminiCounter
+	case transformer.ProviderBedrock:
+		if strings.Contains(model, "anthropic") || strings.Contains(model, "claude") {
+			return claudeCounter
+		}
+	}
+	return defaultCounter
+}
+
+// CountRequest returns the input tokens of a chat request of provider, such
+// as a *claude.ClaudeRequest: its messages, system prompt and tools.
+func CountRequest(provider transformer.Provider, request any) (int, error) {
+	u, err := transformer.ToUnifiedRequest(context.Background(), provider, request)
+	if err != nil {
+		return 0, err
+	}
+	return CountUnified(provider, u), nil
+}
+
+// CountUnified returns the input tokens of a unified request sent to
+// provider.
+func CountUnified(provider transformer.Provider, u *transformer.UnifiedRequest) int {
+	c := counterOf(provider, u.Model)
+	n := c.perRequest
+	for _, message := range u.Messages {
+		n += c.perMessage + c.count(u.Model, message.Role)
+		for _, part := range message.Parts {
+			n += c.part(u.Model, part)
+		}
+		for _, call := range message.ToolCalls {
+			n += c.count(u.Model, call.Name) + c.count(u.Model, call.Arguments)
+		}
+		for _, result := range message.ServerToolResults {
+			n += c.count(u.Model, result.Input) + c.count(u.Model, result.Output) + c.count(u.Model, result.Error)
+			for _, source := range result.Sources {
+				n += c.count(u.Model, source.Title) + c.count(u.Model, source.URL)
+			}
+		}
+	}
+	if len(u.Tools) > 0 {
+		n += c.tools
+		for _, tool := range u.Tools {
+			n += c.count(u.Model, tool.Name) + c.count(u.Model, tool.Description)
+			if tool.Parameters != nil {
+				parameters, _ := json.Marshal(tool.Parameters)
+				n += c.count(u.Model, string(parameters))
+			}
+		}
+	}
+	if format := u.ResponseFormat; format != nil && len(format.Schema) > 0 {
+		n += c.count(u.Model, string(format.Schema))
+	}
+	return n
+}
+
+// CountText returns the tokens of text for a model of provider.
+func CountText(provider transformer.Provider, model, text string) int {
+	c := counterOf(provider, model)
+	return c.count(model, text)
+}
+
+func (c counter) count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	if c.text != nil {
+		if n, ok := c.text(model, text); ok {
+			return n
+		}
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / c.charsPerToken))
+}
+
+// part returns the tokens of a message part. Inline plain text documents
+// count as their text.
+func (c counter) part(model string, part transformer.UnifiedPart) int {
+	switch part.Type {
+	case transformer.UnifiedPartText, transformer.UnifiedPartThinking:
+		return c.count(model, part.Text)
+	case transformer.UnifiedPartImage:
+		return c.image
+	case transformer.UnifiedPartAudio:
+		return c.audio
+	case transformer.UnifiedPartDocument:
+		if text, ok := inlineText(part); ok {
+			return c.count(model, part.Title) + c.count(model, text)
+		}
+		return c.count(model, part.Title) + c.document
+	default:
+		return 0
+	}
+}
+
+// inlineText returns the text of a document part holding plain text in a
+// data URL.
+func inlineText(part transformer.UnifiedPart) (string, bool) {
+	header, data, ok := strings.Cut(part.URL, ",")
+	if !ok || !strings.HasPrefix(header, "data:text/") {
+		return "", false
+	}
+	if !strings.HasSuffix(header, ";base64") {
+		return data, true
+	}
+	text, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", false
+	}
+	return string(text), true
+}
+
+// openAIText counts text with the encoding of an OpenAI model, false when
+// it is not registered.
+func openAIText(model, text string) (int, bool) {
+	e := encodingOf(model)
+	if e == nil {
+		return 0, false
+	}
+	return e.Count(text), true
+}
@@ -0,0 +1,286 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// AudioOperation names the speech workload of an audio payload.
+type AudioOperation string
+
+const (
+	// AudioTranscription turns speech into text.
+	AudioTranscription AudioOperation = "transcription"
+	// AudioSpeech turns text into speech.
+	AudioSpeech AudioOperation = "speech"
+)
+
+// UnifiedTranscriptionRequest is the provider-neutral speech to text request.
+type UnifiedTranscriptionRequest struct {
+	Model    string `json:"model"`
+	Audio    []byte `json:"audio"`
+	MimeType string `json:"mime_type"`
+	// Language is the ISO-639-1 code of the speech, empty to detect it.
+	Language string `json:"language,omitempty"`
+	// Prompt is context for the transcript, such as the spelling of names.
+	Prompt      string   `json:"prompt,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type UnifiedTranscriptionResponse struct {
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+}
+
+// UnifiedSpeechRequest is the provider-neutral text to speech request.
+type UnifiedSpeechRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+	// Voice is an OpenAI voice, or one of the provider when the voice map
+	// names no OpenAI voice for it.
+	Voice        string `json:"voice,omitempty"`
+	Instructions string `json:"instructions,omitempty"`
+	// Format is the requested audio format, in OpenAI terms: mp3, wav, pcm...
+	Format string   `json:"format,omitempty"`
+	Speed  *float64 `json:"speed,omitempty"`
+}
+
+type UnifiedSpeechResponse struct {
+	Audio    []byte `json:"audio"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// AudioTransformer converts the speech payloads of its provider to and from
+// the unified format.
+type AudioTransformer interface {
+	GetProvider() Provider
+	// AudioToUnified returns a *UnifiedTranscriptionRequest,
+	// *UnifiedTranscriptionResponse, *UnifiedSpeechRequest or
+	// *UnifiedSpeechResponse.
+	AudioToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error)
+	AudioFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error
+}
+
+// RegisterAudio adds a provider's speech converters.
+func (r *TransformationRegistry) RegisterAudio(transformer AudioTransformer) {
+	r.audio[transformer.GetProvider()] = transformer
+}
+
+// TransformAudio converts a transcription or speech payload of source into
+// one of target, through the unified format. Dst must be a pointer to the
+// target type, e.g. one created by NewAudioPayload.
+func (r *TransformationRegistry) TransformAudio(ctx context.Context, sourceProvider, targetProvider Provider, typ TransformerType, src interface{}, dst interface{}) error {
+	source, sourceOK := r.audio[sourceProvider]
+	target, targetOK := r.audio[targetProvider]
+	if !sourceOK || !targetOK {
+		return &TransformationError{
+			Type:    "transformer_not_found",
+			Message: "audio transformer not found for " + string(sourceProvider) + " -> " + string(targetProvider),
+		}
+	}
+	if r.configStore != nil && config.FromContext(ctx) == nil {
+		ctx = config.NewContext(ctx, r.configStore.Current())
+	}
+	if r.voiceMap != nil && ctx.Value(voiceMapKey{}) == nil {
+		ctx = WithVoiceMap(ctx, r.voiceMap)
+	}
+	u, err := source.AudioToUnified(ctx, typ, src)
+	if err != nil {
+		return err
+	}
+	return target.AudioFromUnified(ctx, typ, u, dst)
+}
+
+// NewAudioPayload returns a pointer to a zero payload of provider for op.
+// Gemini serves both operations with generateContent.
+func NewAudioPayload(provider Provider, op AudioOperation, typ TransformerType) (interface{}, error) {
+	if typ != TransformerTypeRequest && typ != TransformerTypeResponse {
+		return nil, fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+	if op != AudioTranscription && op != AudioSpeech {
+		return nil, fmt.Errorf("unsupported audio operation: %s", op)
+	}
+	request := typ == TransformerTypeRequest
+	switch provider {
+	case ProviderOpenAI:
+		switch {
+		case op == AudioTranscription && request:
+			return &openai.TranscriptionRequest{}, nil
+		case op == AudioTranscription:
+			return &openai.TranscriptionResponse{}, nil
+		case request:
+			return &openai.SpeechRequest{}, nil
+		default:
+			return &openai.SpeechResponse{}, nil
+		}
+	case ProviderGemini:
+		if request {
+			return &gemini.GeminiChatRequest{}, nil
+		}
+		return &gemini.GeminiChatResponse{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// unifiedAudio asserts the unified payload AudioFromUnified is given.
+func unifiedAudio[T any](src interface{}) (*T, error) {
+	if u, ok := src.(*T); ok {
+		return u, nil
+	}
+	return nil, fmt.Errorf("source must be a %T, got %T", new(T), src)
+}
+
+// audioFormats maps audio file extensions to MIME types.
+var audioFormats = map[string]string{
+	"aac":  "audio/aac",
+	"aiff": "audio/aiff",
+	"flac": "audio/flac",
+	"m4a":  "audio/mp4",
+	"mp3":  "audio/mp3",
+	"mp4":  "audio/mp4",
+	"mpeg": "audio/mpeg",
+	"mpga": "audio/mpeg",
+	"ogg":  "audio/ogg",
+	"opus": "audio/ogg",
+	"pcm":  "audio/pcm",
+	"wav":  "audio/wav",
+	"webm": "audio/webm",
+}
+
+// audioMimeType returns the MIME type of an audio file, by extension or
+// else by content.
+func audioMimeType(name string, data []byte) string {
+	if mimeType, ok := audioFormats[strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))]; ok {
+		return mimeType
+	}
+	return http.DetectContentType(data)
+}
+
+// audioFormat returns the file extension of an audio MIME type, the OpenAI
+// name of the format, or "" when unknown.
+func audioFormat(mimeType string) string {
+	mimeType, _, _ = strings.Cut(strings.ToLower(mimeType), ";")
+	switch mimeType {
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	case "audio/wav", "audio/wave", "audio/x-wav":
+		return "wav"
+	case "audio/l16", "audio/pcm":
+		return "pcm"
+	}
+	for format, known := range audioFormats {
+		if known == mimeType && format != "m4a" && format != "opus" {
+			return format
+		}
+	}
+	return ""
+}
+
+// AudioToUnified implements AudioTransformer for the transcription and
+// speech endpoints. Transcripts convert as text: timestamps and subtitle
+// formats are specific to OpenAI.
+func (t *OpenAITransformer) AudioToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *openai.TranscriptionRequest:
+		if len(src.File) == 0 {
+			return nil, fmt.Errorf("file is required")
+		}
+		switch src.ResponseFormat {
+		case "", "json", "text":
+		default:
+			if err := dropUnsupported(ctx, "response_format", "transcripts are returned as json"); err != nil {
+				return nil, err
+			}
+		}
+		if len(src.TimestampGranularities) > 0 {
+			if err := dropUnsupported(ctx, "timestamp_granularities", "timestamps are specific to OpenAI"); err != nil {
+				return nil, err
+			}
+		}
+		return &UnifiedTranscriptionRequest{
+			Model:       src.Model,
+			Audio:       src.File,
+			MimeType:    audioMimeType(src.FileName, src.File),
+			Language:    src.Language,
+			Prompt:      src.Prompt,
+			Temperature: src.Temperature,
+		}, nil
+	case *openai.TranscriptionResponse:
+		return &UnifiedTranscriptionResponse{Text: src.Text, Language: src.Language}, nil
+	case *openai.SpeechRequest:
+		return &UnifiedSpeechRequest{
+			Model:        src.Model,
+			Input:        src.Input,
+			Voice:        src.Voice,
+			Instructions: src.Instructions,
+			Format:       src.ResponseFormat,
+			Speed:        src.Speed,
+		}, nil
+	case *openai.SpeechResponse:
+		return &UnifiedSpeechResponse{Audio: src.Audio, MimeType: src.ContentType}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// AudioFromUnified implements AudioTransformer.
+func (t *OpenAITransformer) AudioFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	switch dst := dst.(type) {
+	case *openai.TranscriptionRequest:
+		req, err := unifiedAudio[UnifiedTranscriptionRequest](src)
+		if err != nil {
+			return err
+		}
+		name := "audio"
+		if format := audioFormat(req.MimeType); format != "" {
+			name += "." + format
+		}
+		*dst = openai.TranscriptionRequest{
+			File:        req.Audio,
+			FileName:    name,
+			Model:       TargetModel(ctx, ProviderOpenAI, req.Model),
+			Language:    req.Language,
+			Prompt:      req.Prompt,
+			Temperature: req.Temperature,
+		}
+		return nil
+	case *openai.TranscriptionResponse:
+		resp, err := unifiedAudio[UnifiedTranscriptionResponse](src)
+		if err != nil {
+			return err
+		}
+		*dst = openai.TranscriptionResponse{Text: resp.Text, Language: resp.Language}
+		return nil
+	case *openai.SpeechRequest:
+		req, err := unifiedAudio[UnifiedSpeechRequest](src)
+		if err != nil {
+			return err
+		}
+		*dst = openai.SpeechRequest{
+			Model:          TargetModel(ctx, ProviderOpenAI, req.Model),
+			Input:          req.Input,
+			Voice:          req.Voice,
+			Instructions:   req.Instructions,
+			ResponseFormat: req.Format,
+			Speed:          req.Speed,
+		}
+		return nil
+	case *openai.SpeechResponse:
+		resp, err := unifiedAudio[UnifiedSpeechResponse](src)
+		if err != nil {
+			return err
+		}
+		*dst = openai.SpeechResponse{Audio: resp.Audio, ContentType: resp.MimeType}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for OpenAI audio")
+	}
+}
@@ -0,0 +1,172 @@
+package transformer
+
+import (
+	"context"
+	"slices"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Azure OpenAI serves the OpenAI API per deployment, and annotates responses
+// and stream chunks with the results of its content filters: per choice for
+// the completion, per prompt for the request. The annotations convert to
+// and from Gemini safety ratings; Claude only learns that content was
+// filtered from the refusal stop reason.
+
+// azureDeployment returns the Azure OpenAI deployment serving model.
+func azureDeployment(ctx context.Context, model string) string {
+	return config.FromContext(ctx).MapModel("azure", model)
+}
+
+// Gemini harm probabilities, in ascending order
+var geminiProbabilities = []string{"NEGLIGIBLE", "LOW", "MEDIUM", "HIGH"}
+
+var azureSeverities = map[string]string{
+	openai.ContentFilterSeveritySafe:   "NEGLIGIBLE",
+	openai.ContentFilterSeverityLow:    "LOW",
+	openai.ContentFilterSeverityMedium: "MEDIUM",
+	openai.ContentFilterSeverityHigh:   "HIGH",
+}
+
+// geminiSafetyRatings converts content filter results to safety ratings.
+// Violence and self harm are both dangerous content on Gemini.
+func geminiSafetyRatings(results openai.ContentFilterResults) []gemini.GeminiChatSafetyRating {
+	var ratings []gemini.GeminiChatSafetyRating
+	add := func(category string, filtered bool, severity string) {
+		rating := gemini.GeminiChatSafetyRating{Category: category, Probability: azureSeverities[severity], Blocked: filtered}
+		if rating.Probability == "" {
+			rating.Probability = "NEGLIGIBLE"
+		}
+		ratings = mergeSafetyRating(ratings, rating)
+	}
+	if r := results.Hate; r != nil {
+		add("HARM_CATEGORY_HATE_SPEECH", r.Filtered, r.Severity)
+	}
+	if r := results.Sexual; r != nil {
+		add("HARM_CATEGORY_SEXUALLY_EXPLICIT", r.Filtered, r.Severity)
+	}
+	if r := results.Violence; r != nil {
+		add("HARM_CATEGORY_DANGEROUS_CONTENT", r.Filtered, r.Severity)
+	}
+	if r := results.SelfHarm; r != nil {
+		add("HARM_CATEGORY_DANGEROUS_CONTENT", r.Filtered, r.Severity)
+	}
+	return ratings
+}
+
+// mergeSafetyRating adds rating to ratings, combining it with the rating of
+// the same category into the higher probability.
+func mergeSafetyRating(ratings []gemini.GeminiChatSafetyRating, rating gemini.GeminiChatSafetyRating) []gemini.GeminiChatSafetyRating {
+	i := slices.IndexFunc(ratings, func(r gemini.GeminiChatSafetyRating) bool { return r.Category == rating.Category })
+	if i < 0 {
+		return append(ratings, rating)
+	}
+	if slices.Index(geminiProbabilities, rating.Probability) > slices.Index(geminiProbabilities, ratings[i].Probability) {
+		ratings[i].Probability = rating.Probability
+	}
+	ratings[i].Blocked = ratings[i].Blocked || rating.Blocked
+	return ratings
+}
+
+// geminiPromptFeedback converts the prompt filter results of a request.
+// Filtered jailbreaks and profanity have no harm category, only a block
+// reason.
+func geminiPromptFeedback(results []openai.PromptFilterResult) gemini.GeminiChatPromptFeedback {
+	var feedback gemini.GeminiChatPromptFeedback
+	for _, result := range results {
+		for _, rating := range geminiSafetyRatings(result.ContentFilterResults) {
+			feedback.SafetyRatings = mergeSafetyRating(feedback.SafetyRatings, rating)
+			if rating.Blocked {
+				feedback.BlockReason = "SAFETY"
+			}
+		}
+		if feedback.BlockReason != "" {
+			continue
+		}
+		switch r := result.ContentFilterResults; {
+		case r.Profanity != nil && r.Profanity.Filtered:
+			feedback.BlockReason = "BLOCKLIST"
+		case r.JailBreak != nil && r.JailBreak.Filtered:
+			feedback.BlockReason = "OTHER"
+		}
+	}
+	return feedback
+}
+
+// azureContentFilterResults converts safety ratings to content filter
+// results. Harassment counts as hate, dangerous content as violence, and
+// categories without an Azure counterpart are left out.
+func azureContentFilterResults(ratings []gemini.GeminiChatSafetyRating) openai.ContentFilterResults {
+	var merged []gemini.GeminiChatSafetyRating
+	for _, rating := range ratings {
+		if rating.Category == "HARM_CATEGORY_HARASSMENT" {
+			rating.Category = "HARM_CATEGORY_HATE_SPEECH"
+		}
+		merged = mergeSafetyRating(merged, rating)
+	}
+
+	var results openai.ContentFilterResults
+	for _, rating := range merged {
+		severity := azureSeverity(rating.Probability)
+		if severity == "" {
+			continue
+		}
+		switch rating.Category {
+		case "HARM_CATEGORY_HATE_SPEECH":
+			results.Hate = &openai.Hate{Filtered: rating.Blocked, Severity: severity}
+		case "HARM_CATEGORY_SEXUALLY_EXPLICIT":
+			results.Sexual = &openai.Sexual{Filtered: rating.Blocked, Severity: severity}
+		case "HARM_CATEGORY_DANGEROUS_CONTENT":
+			results.Violence = &openai.Violence{Filtered: rating.Blocked, Severity: severity}
+		}
+	}
+	return results
+}
+
+// azureSeverity returns the severity of a Gemini harm probability, empty
+// when unspecified.
+func azureSeverity(probability string) string {
+	for severity, p := range azureSeverities {
+		if p == probability {
+			return severity
+		}
+	}
+	return ""
+}
+
+// azurePromptFilterResults converts the prompt feedback of a response, nil
+// when it carries nothing.
+func azurePromptFilterResults(feedback gemini.GeminiChatPromptFeedback) []openai.PromptFilterResult {
+	results := azureContentFilterResults(feedback.SafetyRatings)
+	if feedback.BlockReason == "BLOCKLIST" {
+		results.Profanity = &openai.Profanity{Filtered: true, Detected: true}
+	}
+	if !contentFilterAnnotated(results) {
+		return nil
+	}
+	return []openai.PromptFilterResult{{Index: 0, ContentFilterResults: results}}
+}
+
+// contentFilterAnnotated reports whether results hold any annotation.
+func contentFilterAnnotated(results openai.ContentFilterResults) bool {
+	return results != openai.ContentFilterResults{}
+}
+
+// contentFiltered reports whether results filtered any content.
+func contentFiltered(results openai.ContentFilterResults) bool {
+	return (results.Hate != nil && results.Hate.Filtered) ||
+		(results.SelfHarm != nil && results.SelfHarm.Filtered) ||
+		(results.Sexual != nil && results.Sexual.Filtered) ||
+		(results.Violence != nil && results.Violence.Filtered) ||
+		(results.JailBreak != nil && results.JailBreak.Filtered) ||
+		(results.Profanity != nil && results.Profanity.Filtered)
+}
+
+// promptFiltered reports whether Azure filtered the prompt of a response.
+func promptFiltered(results []openai.PromptFilterResult) bool {
+	return slices.ContainsFunc(results, func(r openai.PromptFilterResult) bool {
+		return contentFiltered(r.ContentFilterResults)
+	})
+}
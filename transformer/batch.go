@@ -0,0 +1,74 @@
+package transformer
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// TransformJob is one payload of a batch transformation. Dst must be a
+// pointer to the target type, e.g. one created by NewPayload.
+type TransformJob struct {
+	Source Provider
+	Target Provider
+	Type   TransformerType
+	Src    interface{}
+	Dst    interface{}
+}
+
+// TransformResult reports the outcome of the job with the same index.
+type TransformResult struct {
+	Index    int
+	Warnings []Warning
+	Err      error
+}
+
+// SetBatchConcurrency bounds the number of jobs TransformAll runs at once,
+// GOMAXPROCS when n <= 0.
+func (r *TransformationRegistry) SetBatchConcurrency(n int) {
+	r.batchConcurrency = n
+}
+
+// TransformAll runs jobs on a bounded worker pool. A failing job does not stop
+// the others; results are in job order. Once ctx is done, jobs not yet started
+// fail with its error.
+func (r *TransformationRegistry) TransformAll(ctx context.Context, jobs []TransformJob) []TransformResult {
+	results := make([]TransformResult, len(jobs))
+	workers := r.batchConcurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(jobs))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = r.transformJob(ctx, i, jobs[i])
+			}
+		}()
+	}
+
+	for i := range jobs {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			results[i] = TransformResult{Index: i, Err: ctx.Err()}
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func (r *TransformationRegistry) transformJob(ctx context.Context, index int, job TransformJob) TransformResult {
+	if err := ctx.Err(); err != nil {
+		return TransformResult{Index: index, Err: err}
+	}
+	jobCtx, warnings := WithWarnings(ctx)
+	err := r.Transform(jobCtx, job.Source, job.Target, job.Type, job.Src, job.Dst)
+	return TransformResult{Index: index, Warnings: warnings.List(), Err: err}
+}
@@ -0,0 +1,982 @@
+package transformer
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/usage"
+)
+
+// BedrockTransformer handles Amazon Bedrock Converse transformations. It
+// converts to OpenAI directly and to Claude and Gemini through the unified
+// format.
+type BedrockTransformer struct{}
+
+// NewBedrockTransformer creates a new Bedrock Converse transformer
+func NewBedrockTransformer() *BedrockTransformer {
+	return &BedrockTransformer{}
+}
+
+// GetProvider returns the source provider (Bedrock)
+func (t *BedrockTransformer) GetProvider() Provider {
+	return ProviderBedrock
+}
+
+// ValidateRequest checks the Converse request: alternating roles, content
+// blocks, tool results answering the tool uses of the turn before, sampling
+// ranges, image formats and the output limit of the model.
+func (t *BedrockTransformer) ValidateRequest(ctx context.Context, request interface{}) error {
+	req, ok := request.(*bedrock.ConverseRequest)
+	if !ok {
+		return fmt.Errorf("invalid request type for Bedrock transformer")
+	}
+
+	v := &requestValidator{}
+	if inference := req.InferenceConfig; inference != nil {
+		v.maxTokens("/inferenceConfig/maxTokens", req.ModelID, inference.MaxTokens)
+		if inference.Temperature != nil {
+			v.between("/inferenceConfig/temperature", *inference.Temperature, 0, 1)
+		}
+		if inference.TopP != nil {
+			v.between("/inferenceConfig/topP", *inference.TopP, 0, 1)
+		}
+	}
+
+	if len(req.Messages) == 0 {
+		v.add("/messages", "messages cannot be empty")
+	}
+	for i, message := range req.Messages {
+		path := fmt.Sprintf("/messages/%d", i)
+		switch {
+		case message.Role != "user" && message.Role != "assistant":
+			v.add(path+"/role", "invalid role %q", message.Role)
+		case i == 0 && message.Role != "user":
+			v.add(path+"/role", "the first message must be from the user")
+		case i > 0 && message.Role == req.Messages[i-1].Role:
+			v.add(path+"/role", "roles must alternate between user and assistant, got %s twice", message.Role)
+		}
+		if len(message.Content) == 0 {
+			v.add(path+"/content", "content cannot be empty")
+		}
+		for j, block := range message.Content {
+			path := fmt.Sprintf("%s/content/%d", path, j)
+			if n := bedrockBlockFields(block); n != 1 {
+				v.add(path, "%d fields set, a content block sets exactly one", n)
+			}
+			switch {
+			case block.ToolResult != nil:
+				id := block.ToolResult.ToolUseID
+				if i == 0 || !slices.ContainsFunc(req.Messages[i-1].Content, func(b bedrock.ContentBlock) bool { return b.ToolUse != nil && b.ToolUse.ToolUseID == id }) {
+					v.add(path+"/toolResult/toolUseId", "toolResult %q does not answer a toolUse of the previous message", id)
+				}
+			case block.ToolUse != nil:
+				id := block.ToolUse.ToolUseID
+				if i+1 < len(req.Messages) && !slices.ContainsFunc(req.Messages[i+1].Content, func(b bedrock.ContentBlock) bool { return b.ToolResult != nil && b.ToolResult.ToolUseID == id }) {
+					v.add(path+"/toolUse/toolUseId", "toolUse %q has no toolResult in the next message", id)
+				}
+			case block.Image != nil:
+				v.mediaType(path+"/image/format", "image/"+block.Image.Format, imageMediaTypes)
+			}
+		}
+	}
+	return v.err(ProviderBedrock)
+}
+
+// ValidateResponse checks the output message and stop reason of the Converse response
+func (t *BedrockTransformer) ValidateResponse(ctx context.Context, response interface{}) error {
+	resp, ok := response.(*bedrock.ConverseResponse)
+	if !ok {
+		return fmt.Errorf("invalid response type for Bedrock transformer")
+	}
+
+	var errs []error
+	if resp.Output.Message == nil {
+		errs = append(errs, fmt.Errorf("output.message cannot be empty"))
+	} else if resp.Output.Message.Role != "assistant" {
+		errs = append(errs, fmt.Errorf("output.message: invalid role %q", resp.Output.Message.Role))
+	}
+	if resp.StopReason == "" {
+		errs = append(errs, fmt.Errorf("stopReason cannot be empty"))
+	}
+	return errors.Join(errs...)
+}
+
+// Do performs the transformation based on the type
+func (t *BedrockTransformer) Do(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	switch typ {
+	case TransformerTypeRequest:
+		return t.transformRequest(ctx, src, dst)
+	case TransformerTypeResponse:
+		return t.transformResponse(ctx, src, dst)
+	case TransformerTypeStream:
+		return fmt.Errorf("stream response transformation not yet implemented")
+	case TransformerTypeChunk:
+		return t.transformChunk(ctx, src, dst)
+	default:
+		return fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+}
+
+// ToUnified converts a Bedrock request, response or chunk to the unified format.
+func (t *BedrockTransformer) ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	return toUnified(ctx, ProviderBedrock, typ, src)
+}
+
+// FromUnified converts a unified request, response or chunk to the Bedrock payload dst.
+func (t *BedrockTransformer) FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	return fromUnified(ctx, ProviderBedrock, typ, src, dst)
+}
+
+func (t *BedrockTransformer) transformRequest(ctx context.Context, src interface{}, dst interface{}) error {
+	req, ok := src.(*bedrock.ConverseRequest)
+	if !ok {
+		return fmt.Errorf("invalid source type for Bedrock request transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		if err := transformBedrockRequestToOpenAI(ctx, req, target); err != nil {
+			return err
+		}
+		stripOpenAIReasoning(ctx, target)
+		return nil
+	case *claude.ClaudeRequest:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderClaude, TransformerTypeRequest, req, target)
+	case *gemini.GeminiChatRequest:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderGemini, TransformerTypeRequest, req, target)
+	case *bedrock.ConverseRequest:
+		return sameProvider(ctx, ProviderBedrock, TransformerTypeRequest, req, target)
+	case *vertex.GenerateContentRequest:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderVertexGemini, TransformerTypeRequest, req, target)
+	case *ollama.ChatRequest:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderOllama, TransformerTypeRequest, req, target)
+	default:
+		return fmt.Errorf("invalid target type for Bedrock transformer")
+	}
+}
+
+func (t *BedrockTransformer) transformResponse(ctx context.Context, src interface{}, dst interface{}) error {
+	resp, ok := src.(*bedrock.ConverseResponse)
+	if !ok {
+		return fmt.Errorf("invalid source type for Bedrock response transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionResponse:
+		return transformBedrockResponseToOpenAI(ctx, resp, target)
+	case *claude.ClaudeResponse:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderClaude, TransformerTypeResponse, resp, target)
+	case *gemini.GeminiChatResponse:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderGemini, TransformerTypeResponse, resp, target)
+	case *bedrock.ConverseResponse:
+		return sameProvider(ctx, ProviderBedrock, TransformerTypeResponse, resp, target)
+	case *vertex.GenerateContentResponse:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderVertexGemini, TransformerTypeResponse, resp, target)
+	case *ollama.ChatResponse:
+		return transformViaUnified(ctx, ProviderBedrock, ProviderOllama, TransformerTypeResponse, resp, target)
+	default:
+		return fmt.Errorf("invalid target type for Bedrock transformer")
+	}
+}
+
+// transformChunk converts one ConverseStream event; use BedrockStreamState
+// to convert a whole stream.
+func (t *BedrockTransformer) transformChunk(ctx context.Context, src interface{}, dst interface{}) error {
+	event, ok := src.(*bedrock.ConverseStreamEvent)
+	if !ok {
+		return fmt.Errorf("invalid source type for Bedrock transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		return transformBedrockChunkToOpenAI(ctx, event, target)
+	case *[]*openai.ChatCompletionStreamResponse:
+		oaiChunk := &openai.ChatCompletionStreamResponse{}
+		if err := transformBedrockChunkToOpenAI(ctx, event, oaiChunk); err != nil {
+			return err
+		}
+		if oaiChunk.Usage != nil && !OptionsFromContext(ctx).IncludeUsage {
+			return nil
+		}
+		*target = append(*target, oaiChunk)
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Bedrock transformer")
+	}
+}
+
+func transformBedrockRequestToOpenAI(ctx context.Context, req *bedrock.ConverseRequest, oaiReq *openai.ChatCompletionRequest) error {
+	cfg := config.FromContext(ctx)
+	oaiReq.Model = TargetModel(ctx, ProviderOpenAI, req.ModelID)
+	preset := presetFor(ctx, oaiReq.Model)
+	quirks := cfg.Quirks(oaiReq.Model)
+
+	inference := req.InferenceConfig
+	if inference == nil {
+		inference = &bedrock.InferenceConfig{}
+	}
+	maxTokens := cmp.Or(inference.MaxTokens, preset.maxTokens())
+	if quirks.UseMaxCompletionTokens {
+		oaiReq.MaxCompletionTokens = maxTokens
+	} else {
+		oaiReq.MaxTokens = maxTokens
+	}
+	oaiReq.Temperature = cmp.Or(inference.Temperature, preset.temperature())
+	oaiReq.TopP = cmp.Or(inference.TopP, preset.topP())
+	oaiReq.Stop = inference.StopSequences
+
+	for _, key := range slices.Sorted(maps.Keys(req.AdditionalModelRequestFields)) {
+		if key == "thinking" {
+			thinking, err := common.Any2Type[claude.Thinking](req.AdditionalModelRequestFields[key])
+			if err != nil {
+				return fmt.Errorf("invalid additionalModelRequestFields.thinking: %w", err)
+			}
+			if budget := thinking.GetBudgetTokens(); thinking.Type == "enabled" && budget > 0 {
+				oaiReq.ReasoningEffort = quirks.ReasoningEffort(budget)
+			}
+			continue
+		}
+		if err := dropUnsupported(ctx, "additionalModelRequestFields."+key, "OpenAI has no equivalent"); err != nil {
+			return err
+		}
+	}
+
+	if toolConfig := req.ToolConfig; toolConfig != nil {
+		for _, tool := range toolConfig.Tools {
+			switch {
+			case tool.ToolSpec != nil:
+				oaiReq.Tools = append(oaiReq.Tools, openai.Tool{
+					Type: openai.ToolTypeFunction,
+					Function: &openai.FunctionDefinition{
+						Name:        tool.ToolSpec.Name,
+						Description: tool.ToolSpec.Description,
+						Parameters:  tool.ToolSpec.InputSchema.JSON,
+					},
+				})
+			case tool.CachePoint != nil && len(oaiReq.Tools) > 0:
+				oaiReq.Tools[len(oaiReq.Tools)-1].CacheControl = ephemeralCacheControl()
+			}
+		}
+		if choice := toolConfig.ToolChoice; choice != nil && len(oaiReq.Tools) > 0 {
+			switch {
+			case choice.Auto != nil:
+				oaiReq.ToolChoice = "auto"
+			case choice.Any != nil:
+				oaiReq.ToolChoice = "required"
+			case choice.Tool != nil:
+				oaiReq.ToolChoice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: choice.Tool.Name}}
+			}
+		}
+	}
+
+	var messages []openai.ChatCompletionMessage
+	if prefix := preset.systemPrefix(); prefix != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: prefix})
+	}
+	var systemParts []openai.ChatMessagePart
+	for _, block := range req.System {
+		switch {
+		case block.Text != nil:
+			systemParts = append(systemParts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: *block.Text})
+		case block.CachePoint != nil && len(systemParts) > 0:
+			systemParts[len(systemParts)-1].CacheControl = ephemeralCacheControl()
+		}
+	}
+	if len(systemParts) > 0 {
+		messages = append(messages, openAIMessageFromParts(openai.ChatMessageRoleSystem, systemParts))
+	}
+
+	for i, m := range req.Messages {
+		var parts []openai.ChatMessagePart
+		message := openai.ChatCompletionMessage{Role: m.Role}
+		for j, block := range m.Content {
+			path := fmt.Sprintf("messages[%d].content[%d]", i, j)
+			switch {
+			case block.Text != nil:
+				parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: *block.Text})
+			case block.Image != nil:
+				if block.Image.Source.S3Location != nil {
+					if err := dropUnsupported(ctx, path, "S3 images have no OpenAI equivalent"); err != nil {
+						return err
+					}
+					continue
+				}
+				parts = append(parts, openai.ChatMessagePart{
+					Type:     openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{URL: bedrockImageURL(block.Image)},
+				})
+			case block.Document != nil:
+				part, err := openAIBedrockDocument(block.Document)
+				if err != nil {
+					if err := dropUnsupported(ctx, path, "%v", err); err != nil {
+						return err
+					}
+					continue
+				}
+				parts = append(parts, part)
+			case block.ToolUse != nil:
+				message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+					ID:   block.ToolUse.ToolUseID,
+					Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      block.ToolUse.Name,
+						Arguments: toJSONString(block.ToolUse.Input),
+					},
+				})
+			case block.ToolResult != nil:
+				// tool results become tool messages, before the rest of the turn
+				content, err := bedrockToolResultContent(ctx, path, block.ToolResult)
+				if err != nil {
+					return err
+				}
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					Content:    content,
+					ToolCallID: block.ToolResult.ToolUseID,
+				})
+			case block.ReasoningContent != nil:
+				if reasoning := block.ReasoningContent.ReasoningText; reasoning != nil {
+					message.ReasoningContent += reasoning.Text
+				} else {
+					warn(ctx, WarningRedactedThinking, path, "redacted reasoning can only be sent back to Bedrock")
+				}
+			case block.CachePoint != nil:
+				if len(parts) > 0 {
+					parts[len(parts)-1].CacheControl = ephemeralCacheControl()
+				} else if n := len(messages); n > 0 && messages[n-1].Role == openai.ChatMessageRoleTool {
+					messages[n-1].CacheControl = ephemeralCacheControl()
+				}
+			default:
+				if err := dropUnsupported(ctx, path, "%s block has no OpenAI equivalent", bedrockBlockType(block)); err != nil {
+					return err
+				}
+			}
+		}
+		if len(parts) > 0 {
+			text := openAIMessageFromParts(m.Role, parts)
+			message.Content, message.MultiContent = text.Content, text.MultiContent
+		}
+		if message.Content != "" || len(message.MultiContent) > 0 || len(message.ToolCalls) > 0 || message.ReasoningContent != "" {
+			messages = append(messages, message)
+		}
+	}
+
+	oaiReq.Messages = messages
+	applyOpenAIQuirks(oaiReq, quirks)
+	cached, err := cacheHints(ctx, ProviderOpenAI, oaiReq)
+	if err != nil {
+		return err
+	}
+	normalized, err := normalizeTools(ctx, ProviderOpenAI, cached)
+	if err != nil {
+		return err
+	}
+	*oaiReq = *normalized
+	return nil
+}
+
+// openAIMessageFromParts uses the plain string content for a single uncached text part
+func openAIMessageFromParts(role string, parts []openai.ChatMessagePart) openai.ChatCompletionMessage {
+	if len(parts) == 1 && parts[0].Type == openai.ChatMessagePartTypeText && parts[0].CacheControl == nil {
+		return openai.ChatCompletionMessage{Role: role, Content: parts[0].Text}
+	}
+	return openai.ChatCompletionMessage{Role: role, MultiContent: parts}
+}
+
+// bedrockToolResultContent flattens the content of a tool result into the
+// content of an OpenAI tool message
+func bedrockToolResultContent(ctx context.Context, path string, result *bedrock.ToolResultBlock) (string, error) {
+	var texts []string
+	for k, block := range result.Content {
+		switch {
+		case block.Text != nil:
+			texts = append(texts, *block.Text)
+		case block.JSON != nil:
+			texts = append(texts, toJSONString(block.JSON))
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("%s.toolResult.content[%d]", path, k), "only text and JSON tool results have an OpenAI equivalent"); err != nil {
+				return "", err
+			}
+		}
+	}
+	content := strings.Join(texts, "\n")
+	if result.Status == bedrock.ToolResultError {
+		content = markToolError(content)
+	}
+	return content, nil
+}
+
+func bedrockImageURL(image *bedrock.ImageBlock) string {
+	return fmt.Sprintf("data:image/%s;base64,%s", image.Format, base64.StdEncoding.EncodeToString(image.Source.Bytes))
+}
+
+func ephemeralCacheControl() *common.CacheControl {
+	return &common.CacheControl{Type: "ephemeral"}
+}
+
+func transformRequestToBedrock(ctx context.Context, oaiReq *openai.ChatCompletionRequest, req *bedrock.ConverseRequest) error {
+	oaiReq, err := cacheHints(ctx, ProviderBedrock, oaiReq)
+	if err != nil {
+		return err
+	}
+	if oaiReq, err = normalizeTools(ctx, ProviderBedrock, oaiReq); err != nil {
+		return err
+	}
+	req.ModelID = TargetModel(ctx, ProviderBedrock, oaiReq.Model)
+	preset := presetFor(ctx, req.ModelID)
+
+	inference := &bedrock.InferenceConfig{
+		MaxTokens:     cmp.Or(oaiReq.MaxCompletionTokens, oaiReq.MaxTokens, preset.maxTokens()),
+		Temperature:   cmp.Or(oaiReq.Temperature, preset.temperature()),
+		TopP:          cmp.Or(oaiReq.TopP, preset.topP()),
+		StopSequences: oaiReq.Stop,
+	}
+	budget := reasoningEffortBudget(ctx, oaiReq.Model, oaiReq.ReasoningEffort)
+	if budget > 0 || inference.MaxTokens > 0 {
+		maxTokens := inference.MaxTokens
+		if budget > 0 {
+			maxTokens = cmp.Or(maxTokens, defaultClaudeMaxTokens)
+		}
+		if inference.MaxTokens, budget, err = fitClaudeModel(ctx, req.ModelID, maxTokens, budget); err != nil {
+			return err
+		}
+	}
+	if budget > 0 {
+		// the thinking field of Anthropic models
+		req.AdditionalModelRequestFields = map[string]any{
+			"thinking": claude.Thinking{Type: "enabled", BudgetTokens: &budget},
+		}
+	}
+	if inference.MaxTokens != 0 || inference.Temperature != nil || inference.TopP != nil || len(inference.StopSequences) > 0 {
+		req.InferenceConfig = inference
+	}
+
+	for _, field := range []struct {
+		name, message string
+		set           bool
+	}{
+		{"n", "Bedrock returns a single message", oaiReq.N > 1},
+		{"seed", "Bedrock Converse has no seed", oaiReq.Seed != nil},
+		{"modalities", "Bedrock cannot generate audio", slices.Contains(oaiReq.Modalities, "audio") || oaiReq.Audio != nil},
+		{"response_format", "Bedrock Converse has no structured output", oaiReq.ResponseFormat != nil && oaiReq.ResponseFormat.Type != openai.ChatCompletionResponseFormatTypeText},
+		{"web_search_options", "Bedrock has no web search tool", oaiReq.WebSearchOptions != nil},
+		{"parallel_tool_calls", "Bedrock cannot disable parallel tool calls", oaiReq.ParallelToolCalls == false},
+	} {
+		if !field.set {
+			continue
+		}
+		if err := dropUnsupported(ctx, field.name, "%s", field.message); err != nil {
+			return err
+		}
+	}
+
+	// only the allowed tools, as Bedrock cannot restrict calls to some
+	toolMode, toolName, allowed, err := openAIToolChoice(oaiReq.ToolChoice)
+	if err != nil {
+		return fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	if err := dropStrictTools(ctx, ProviderBedrock, oaiReq.Tools); err != nil {
+		return err
+	}
+	if err := dropBuiltinTools(ctx, ProviderBedrock, oaiReq.Tools); err != nil {
+		return err
+	}
+	var tools []bedrock.Tool
+	for _, tool := range allowTools(oaiReq.Tools, allowed) {
+		if tool.Function == nil {
+			continue
+		}
+		inputSchema := tool.Function.Parameters
+		if inputSchema == nil {
+			inputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, bedrock.Tool{ToolSpec: &bedrock.ToolSpec{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: bedrock.ToolInputSchema{JSON: inputSchema},
+		}})
+		if tool.CacheControl != nil {
+			tools = append(tools, bedrock.Tool{CachePoint: bedrockCachePoint()})
+		}
+	}
+	if len(tools) > 0 {
+		req.ToolConfig = &bedrock.ToolConfig{Tools: tools}
+		switch {
+		case toolMode == "":
+		case toolName != "":
+			req.ToolConfig.ToolChoice = &bedrock.ToolChoice{Tool: &bedrock.SpecificTool{Name: toolName}}
+		case toolMode == "none":
+			if err := dropUnsupported(ctx, "tool_choice", "Bedrock has no tool_choice none, the model may call tools"); err != nil {
+				return err
+			}
+		case toolMode == "required":
+			req.ToolConfig.ToolChoice = &bedrock.ToolChoice{Any: &struct{}{}}
+		default:
+			req.ToolConfig.ToolChoice = &bedrock.ToolChoice{Auto: &struct{}{}}
+		}
+	}
+
+	if prefix := preset.systemPrefix(); prefix != "" {
+		req.System = append(req.System, bedrock.SystemContentBlock{Text: &prefix})
+	}
+	appendBlocks := func(role string, blocks ...bedrock.ContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Bedrock expects alternating turns, consecutive turns of a role are merged
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			return
+		}
+		req.Messages = append(req.Messages, bedrock.Message{Role: role, Content: blocks})
+	}
+
+	for i, message := range oaiReq.Messages {
+		switch message.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			blocks, err := bedrockBlocksFromOpenAI(ctx, i, message)
+			if err != nil {
+				return err
+			}
+			for j, block := range blocks {
+				switch {
+				case block.Text != nil:
+					req.System = append(req.System, bedrock.SystemContentBlock{Text: block.Text})
+				case block.CachePoint != nil:
+					req.System = append(req.System, bedrock.SystemContentBlock{CachePoint: block.CachePoint})
+				default:
+					if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d].content[%d]", i, j), "Bedrock system prompts only hold text"); err != nil {
+						return err
+					}
+				}
+			}
+		case openai.ChatMessageRoleTool:
+			content, isError := parseToolError(message.Content)
+			result := &bedrock.ToolResultBlock{
+				ToolUseID: message.ToolCallID,
+				Content:   []bedrock.ToolResultContentBlock{{Text: &content}},
+				Status:    bedrock.ToolResultSuccess,
+			}
+			if isError {
+				result.Status = bedrock.ToolResultError
+			}
+			blocks := []bedrock.ContentBlock{{ToolResult: result}}
+			if message.CacheControl != nil {
+				blocks = append(blocks, bedrock.ContentBlock{CachePoint: bedrockCachePoint()})
+			}
+			appendBlocks("user", blocks...)
+		case openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+			blocks, err := bedrockBlocksFromOpenAI(ctx, i, message)
+			if err != nil {
+				return err
+			}
+			for _, call := range message.ToolCalls {
+				var input any
+				if err := json.Unmarshal([]byte(call.Function.Arguments), &input); err != nil || input == nil {
+					input = map[string]any{}
+				}
+				blocks = append(blocks, bedrock.ContentBlock{ToolUse: &bedrock.ToolUseBlock{
+					ToolUseID: call.ID,
+					Name:      call.Function.Name,
+					Input:     input,
+				}})
+			}
+			appendBlocks(message.Role, blocks...)
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d]", i), "role %q has no Bedrock equivalent", message.Role); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bedrockBlocksFromOpenAI converts the content of an OpenAI message to
+// Bedrock content blocks, a cache point following each cached part
+func bedrockBlocksFromOpenAI(ctx context.Context, index int, message openai.ChatCompletionMessage) ([]bedrock.ContentBlock, error) {
+	var blocks []bedrock.ContentBlock
+	if message.Content != "" {
+		text := message.Content
+		blocks = append(blocks, bedrock.ContentBlock{Text: &text})
+		if message.CacheControl != nil {
+			blocks = append(blocks, bedrock.ContentBlock{CachePoint: bedrockCachePoint()})
+		}
+		return blocks, nil
+	}
+
+	cached := false
+	for j, part := range message.MultiContent {
+		path := fmt.Sprintf("messages[%d].content[%d]", index, j)
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			text := part.Text
+			blocks = append(blocks, bedrock.ContentBlock{Text: &text})
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			image, err := bedrockImage(part.ImageURL.URL)
+			if err != nil {
+				if err := dropUnsupported(ctx, path, "%v", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			blocks = append(blocks, bedrock.ContentBlock{Image: image})
+		case openai.ChatMessagePartTypeFile:
+			if part.File == nil {
+				continue
+			}
+			document, err := bedrockDocument(part.File, fmt.Sprintf("document-%d-%d", index, j))
+			if err != nil {
+				if err := dropUnsupported(ctx, path, "%v", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			blocks = append(blocks, bedrock.ContentBlock{Document: document})
+		default:
+			if err := dropUnsupported(ctx, path, "%s content has no Bedrock equivalent", part.Type); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if cached = part.CacheControl != nil; cached {
+			blocks = append(blocks, bedrock.ContentBlock{CachePoint: bedrockCachePoint()})
+		}
+	}
+	if len(blocks) > 0 && message.CacheControl != nil && !cached {
+		blocks = append(blocks, bedrock.ContentBlock{CachePoint: bedrockCachePoint()})
+	}
+	return blocks, nil
+}
+
+// bedrockImage converts an OpenAI image data URL, Bedrock takes no remote URLs
+func bedrockImage(URL string) (*bedrock.ImageBlock, error) {
+	header, data, ok := strings.Cut(URL, ",")
+	if !strings.HasPrefix(URL, "data:") || !ok {
+		return nil, fmt.Errorf("Bedrock only accepts inline or S3 images")
+	}
+	mediaType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	format := strings.TrimPrefix(mediaType, "image/")
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	bytes, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image data: %w", err)
+	}
+	return &bedrock.ImageBlock{Format: format, Source: bedrock.ImageSource{Bytes: bytes}}, nil
+}
+
+// bedrockDocumentFormats are the document formats of Bedrock by media type.
+var bedrockDocumentFormats = map[string]string{
+	"application/pdf": "pdf",
+	"text/csv":        "csv",
+	"text/html":       "html",
+	"text/plain":      "txt",
+	"text/markdown":   "md",
+
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.ms-excel": "xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+}
+
+// bedrockDocument converts an inline OpenAI file to a document, named after
+// the file or else name.
+func bedrockDocument(file *openai.ChatMessageFile, name string) (*bedrock.DocumentBlock, error) {
+	data, ok := fileData(file)
+	if !ok {
+		return nil, fmt.Errorf("Bedrock only accepts inline or S3 documents")
+	}
+	mediaType := fileMediaType(file)
+	format, ok := bedrockDocumentFormats[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("Bedrock documents are not %s", cmp.Or(mediaType, "of an unknown media type"))
+	}
+	bytes, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file data: %w", err)
+	}
+	if title := bedrockDocumentName(fileTitle(file)); title != "" {
+		name = title
+	}
+	return &bedrock.DocumentBlock{Format: format, Name: name, Source: bedrock.ImageSource{Bytes: bytes}}, nil
+}
+
+// bedrockDocumentName turns a file name into a document name, which Bedrock
+// limits to alphanumerics, single spaces, hyphens, parentheses and square
+// brackets.
+func bedrockDocumentName(fileName string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune("-()[]", r) {
+			return r
+		}
+		return '-'
+	}, strings.TrimSuffix(fileName, path.Ext(fileName)))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// openAIBedrockDocument converts an inline document to a file part.
+func openAIBedrockDocument(document *bedrock.DocumentBlock) (openai.ChatMessagePart, error) {
+	if document.Source.S3Location != nil {
+		return openai.ChatMessagePart{}, fmt.Errorf("S3 documents have no OpenAI equivalent")
+	}
+	for mediaType, format := range bedrockDocumentFormats {
+		if format == document.Format {
+			part := openAIFilePart(mediaType, base64.StdEncoding.EncodeToString(document.Source.Bytes))
+			return titled(part, document.Name), nil
+		}
+	}
+	return openai.ChatMessagePart{}, fmt.Errorf("unsupported document format %q", document.Format)
+}
+
+func bedrockCachePoint() *bedrock.CachePointBlock {
+	return &bedrock.CachePointBlock{Type: bedrock.CachePointDefault}
+}
+
+func transformBedrockResponseToOpenAI(ctx context.Context, resp *bedrock.ConverseResponse, oaiResp *openai.ChatCompletionResponse) error {
+	oaiResp.ID = "chatcmpl-" + generateUUID()
+	oaiResp.Object = "chat.completion"
+	oaiResp.Created = time.Now().Unix()
+
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	if resp.Output.Message != nil {
+		var texts []string
+		for i, block := range resp.Output.Message.Content {
+			path := fmt.Sprintf("output.message.content[%d]", i)
+			switch {
+			case block.Text != nil:
+				texts = append(texts, *block.Text)
+			case block.ReasoningContent != nil:
+				if reasoning := block.ReasoningContent.ReasoningText; reasoning != nil {
+					message.ReasoningContent += reasoning.Text
+				} else {
+					warn(ctx, WarningRedactedThinking, path, "redacted reasoning can only be sent back to Bedrock")
+				}
+			case block.ToolUse != nil:
+				message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+					ID:   block.ToolUse.ToolUseID,
+					Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      originalToolName(ctx, block.ToolUse.Name),
+						Arguments: toJSONString(block.ToolUse.Input),
+					},
+				})
+			default:
+				if err := dropUnsupported(ctx, path, "%s block has no OpenAI equivalent", bedrockBlockType(block)); err != nil {
+					return err
+				}
+			}
+		}
+		message.Content = strings.Join(texts, "")
+	}
+
+	oaiResp.Choices = []openai.ChatCompletionChoice{{
+		Message:      message,
+		FinishReason: stopReasonBedrock2OpenAI(resp.StopReason),
+	}}
+	oaiResp.Usage = *usage.FromBedrock(resp.Usage).OpenAI()
+	return nil
+}
+
+func transformResponseToBedrock(ctx context.Context, oaiResp *openai.ChatCompletionResponse, resp *bedrock.ConverseResponse) error {
+	message := &bedrock.Message{Role: "assistant", Content: []bedrock.ContentBlock{}}
+	for i, choice := range oaiResp.Choices {
+		if i > 0 {
+			if err := dropUnsupported(ctx, fmt.Sprintf("choices[%d]", i), "Bedrock returns a single message"); err != nil {
+				return err
+			}
+			break
+		}
+		if reasoning := choice.Message.ReasoningContent; reasoning != "" {
+			message.Content = append(message.Content, bedrock.ContentBlock{ReasoningContent: &bedrock.ReasoningContentBlock{
+				ReasoningText: &bedrock.ReasoningText{Text: reasoning},
+			}})
+		}
+		if text := choice.Message.Content; text != "" {
+			message.Content = append(message.Content, bedrock.ContentBlock{Text: &text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			var input any
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &input); err != nil || input == nil {
+				input = map[string]any{}
+			}
+			message.Content = append(message.Content, bedrock.ContentBlock{ToolUse: &bedrock.ToolUseBlock{
+				ToolUseID: call.ID,
+				Name:      originalToolName(ctx, call.Function.Name),
+				Input:     input,
+			}})
+		}
+		resp.StopReason = stopReasonOpenAI2Bedrock(choice.FinishReason)
+	}
+	if resp.StopReason == "" {
+		resp.StopReason = bedrock.StopReasonEndTurn
+	}
+	resp.Output = bedrock.ConverseOutput{Message: message}
+	resp.Usage = usage.FromOpenAI(&oaiResp.Usage).Bedrock()
+	return nil
+}
+
+// transformBedrockChunkToOpenAI converts one ConverseStream event on its
+// own: tool call fragments are indexed by content block and events without
+// content yield a chunk without choices.
+func transformBedrockChunkToOpenAI(ctx context.Context, event *bedrock.ConverseStreamEvent, oaiChunk *openai.ChatCompletionStreamResponse) error {
+	oaiChunk.Object = "chat.completion.chunk"
+	oaiChunk.Choices = []openai.ChatCompletionStreamChoice{}
+	var delta openai.ChatCompletionStreamChoiceDelta
+	var finishReason openai.FinishReason
+	switch {
+	case event.MessageStart != nil:
+		delta.Role = openai.ChatMessageRoleAssistant
+	case event.ContentBlockStart != nil:
+		start := event.ContentBlockStart
+		if start.Start.ToolUse == nil {
+			return nil
+		}
+		index := start.ContentBlockIndex
+		delta.ToolCalls = []openai.ToolCall{{
+			Index:    &index,
+			ID:       start.Start.ToolUse.ToolUseID,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: originalToolName(ctx, start.Start.ToolUse.Name)},
+		}}
+	case event.ContentBlockDelta != nil:
+		d := event.ContentBlockDelta.Delta
+		switch {
+		case d.Text != nil:
+			delta.Content = *d.Text
+		case d.ToolUse != nil:
+			index := event.ContentBlockDelta.ContentBlockIndex
+			delta.ToolCalls = []openai.ToolCall{{Index: &index, Function: openai.FunctionCall{Arguments: d.ToolUse.Input}}}
+		case d.ReasoningContent != nil && d.ReasoningContent.Text != nil:
+			delta.ReasoningContent = *d.ReasoningContent.Text
+		case d.ReasoningContent != nil && d.ReasoningContent.RedactedContent != nil:
+			warn(ctx, WarningRedactedThinking, fmt.Sprintf("content[%d]", event.ContentBlockDelta.ContentBlockIndex), "redacted reasoning can only be sent back to Bedrock")
+			return nil
+		default:
+			// signatures have no OpenAI equivalent
+			return nil
+		}
+	case event.MessageStop != nil:
+		finishReason = stopReasonBedrock2OpenAI(event.MessageStop.StopReason)
+	case event.Metadata != nil:
+		if event.Metadata.Usage != nil {
+			oaiChunk.Usage = usage.FromBedrock(event.Metadata.Usage).OpenAI()
+		}
+		return nil
+	default:
+		return nil
+	}
+	oaiChunk.Choices = append(oaiChunk.Choices, openai.ChatCompletionStreamChoice{Delta: delta, FinishReason: finishReason})
+	return nil
+}
+
+func stopReasonBedrock2OpenAI(reason string) openai.FinishReason {
+	switch reason {
+	case bedrock.StopReasonMaxTokens:
+		return openai.FinishReasonLength
+	case bedrock.StopReasonToolUse:
+		return openai.FinishReasonToolCalls
+	case bedrock.StopReasonGuardrailIntervened, bedrock.StopReasonContentFiltered:
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+func stopReasonOpenAI2Bedrock(reason openai.FinishReason) string {
+	switch reason {
+	case openai.FinishReasonLength:
+		return bedrock.StopReasonMaxTokens
+	case openai.FinishReasonToolCalls, openai.FinishReasonFunctionCall:
+		return bedrock.StopReasonToolUse
+	case openai.FinishReasonContentFilter:
+		return bedrock.StopReasonContentFiltered
+	default:
+		return bedrock.StopReasonEndTurn
+	}
+}
+
+// bedrockBlockFields counts the fields set in a content block union
+func bedrockBlockFields(block bedrock.ContentBlock) int {
+	n := 0
+	for _, set := range []bool{
+		block.Text != nil, block.Image != nil, block.Document != nil, block.ToolUse != nil,
+		block.ToolResult != nil, block.ReasoningContent != nil, block.CachePoint != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// bedrockBlockType names the field set in a content block union
+func bedrockBlockType(block bedrock.ContentBlock) string {
+	switch {
+	case block.Text != nil:
+		return "text"
+	case block.Image != nil:
+		return "image"
+	case block.Document != nil:
+		return "document"
+	case block.ToolUse != nil:
+		return "toolUse"
+	case block.ToolResult != nil:
+		return "toolResult"
+	case block.ReasoningContent != nil:
+		return "reasoningContent"
+	case block.CachePoint != nil:
+		return "cachePoint"
+	}
+	return "empty"
+}
+
+// BedrockAnthropicRequest returns the InvokeModel body of a Claude request
+// for an Anthropic model on Bedrock: the model moves to the URL, streaming
+// to the InvokeModelWithResponseStream operation, and anthropic_version is
+// set.
+func BedrockAnthropicRequest(req *claude.ClaudeRequest) ([]byte, error) {
+	body, err := jsonObject(req)
+	if err != nil {
+		return nil, err
+	}
+	delete(body, "model")
+	delete(body, "stream")
+	body["anthropic_version"], _ = json.Marshal(bedrock.AnthropicVersion)
+	return json.Marshal(body)
+}
+
+// ParseBedrockAnthropicChunk decodes the Claude stream event carried by the
+// payload of a chunk event of InvokeModelWithResponseStream.
+func ParseBedrockAnthropicChunk(payload []byte) (*claude.ClaudeResponse, error) {
+	var part bedrock.PayloadPart
+	if err := json.Unmarshal(payload, &part); err != nil {
+		return nil, fmt.Errorf("invalid bedrock chunk: %w", err)
+	}
+	event := &claude.ClaudeResponse{}
+	if err := json.Unmarshal(part.Bytes, event); err != nil {
+		return nil, fmt.Errorf("invalid claude event in bedrock chunk: %w", err)
+	}
+	return event, nil
+}
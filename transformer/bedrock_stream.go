@@ -0,0 +1,270 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/usage"
+)
+
+// BedrockStreamState converts a ConverseStream event stream into OpenAI chat
+// completion chunks as the events arrive. Text and reasoning deltas become
+// content and reasoning_content, and each toolUse block becomes a tool call
+// whose index counts the tool calls of the message. The metadata event
+// after messageStop becomes the usage chunk.
+type BedrockStreamState struct {
+	ctx          context.Context
+	includeUsage bool
+	id           string
+	created      int64
+	// toolCalls maps Bedrock block indexes to OpenAI tool call indexes.
+	toolCalls map[int]int
+	// toolInput records the tool blocks that streamed input.
+	toolInput map[int]bool
+}
+
+// NewBedrockStreamState returns the state of one stream. With includeUsage
+// the stream ends with a usage chunk, as for stream_options.include_usage.
+func NewBedrockStreamState(ctx context.Context, includeUsage bool) *BedrockStreamState {
+	return &BedrockStreamState{
+		ctx:          ctx,
+		includeUsage: includeUsage,
+		id:           "chatcmpl-" + generateUUID(),
+		created:      time.Now().Unix(),
+		toolCalls:    make(map[int]int),
+		toolInput:    make(map[int]bool),
+	}
+}
+
+// Transform consumes one event and returns the chunks it produces, none for
+// events without an OpenAI counterpart.
+func (s *BedrockStreamState) Transform(event *bedrock.ConverseStreamEvent) ([]*openai.ChatCompletionStreamResponse, error) {
+	switch {
+	case event.ContentBlockStart != nil && event.ContentBlockStart.Start.ToolUse != nil:
+		start := event.ContentBlockStart
+		index := len(s.toolCalls)
+		s.toolCalls[start.ContentBlockIndex] = index
+		return s.chunks(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+			Index:    &index,
+			ID:       start.Start.ToolUse.ToolUseID,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: originalToolName(s.ctx, start.Start.ToolUse.Name)},
+		}}}, ""), nil
+	case event.ContentBlockDelta != nil && event.ContentBlockDelta.Delta.ToolUse != nil:
+		block := event.ContentBlockDelta.ContentBlockIndex
+		index, ok := s.toolCalls[block]
+		if !ok {
+			return nil, fmt.Errorf("toolUse delta for block %d which is not a started tool", block)
+		}
+		if event.ContentBlockDelta.Delta.ToolUse.Input == "" {
+			return nil, nil
+		}
+		s.toolInput[block] = true
+		return s.toolArguments(index, event.ContentBlockDelta.Delta.ToolUse.Input), nil
+	case event.ContentBlockStop != nil:
+		// a tool called without input streams no toolUse delta
+		block := event.ContentBlockStop.ContentBlockIndex
+		if index, ok := s.toolCalls[block]; ok && !s.toolInput[block] {
+			return s.toolArguments(index, "{}"), nil
+		}
+		return nil, nil
+	case event.Metadata != nil && !s.includeUsage:
+		return nil, nil
+	}
+
+	chunk := s.template()
+	if err := transformBedrockChunkToOpenAI(s.ctx, event, chunk); err != nil {
+		return nil, err
+	}
+	if len(chunk.Choices) == 0 && chunk.Usage == nil {
+		return nil, nil
+	}
+	return []*openai.ChatCompletionStreamResponse{chunk}, nil
+}
+
+func (s *BedrockStreamState) toolArguments(index int, arguments string) []*openai.ChatCompletionStreamResponse {
+	return s.chunks(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+		Index:    &index,
+		Function: openai.FunctionCall{Arguments: arguments},
+	}}}, "")
+}
+
+// template returns an empty chunk with the identity of the stream.
+func (s *BedrockStreamState) template() *openai.ChatCompletionStreamResponse {
+	return &openai.ChatCompletionStreamResponse{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+	}
+}
+
+func (s *BedrockStreamState) chunks(delta openai.ChatCompletionStreamChoiceDelta, finishReason openai.FinishReason) []*openai.ChatCompletionStreamResponse {
+	chunk := s.template()
+	chunk.Choices = []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}}
+	return []*openai.ChatCompletionStreamResponse{chunk}
+}
+
+// BedrockStreamTransformer converts an OpenAI chat completion stream into
+// ConverseStream events. Like StreamTransformer for Claude it opens a
+// content block whenever the kind of delta changes, and holds back
+// messageStop until Finish, since OpenAI reports usage in a chunk after the
+// finish reason and Bedrock reports it in the metadata event after
+// messageStop.
+//
+// One BedrockStreamTransformer serves a single stream and only choice 0.
+type BedrockStreamTransformer struct {
+	ctx     context.Context
+	started bool
+	stopped bool
+	// open is the index of the open block, -1 when none is, and openKind
+	// its kind: text, reasoning or tool.
+	open     int
+	openKind string
+	next     int
+	// toolBlocks maps OpenAI tool call indexes to Bedrock block indexes.
+	toolBlocks   map[int]int
+	finishReason openai.FinishReason
+	usage        *openai.Usage
+	droppedN     bool
+	events       []*bedrock.ConverseStreamEvent
+}
+
+// NewBedrockStreamTransformer returns a transformer for one stream. ctx
+// carries the options and warnings of the stream.
+func NewBedrockStreamTransformer(ctx context.Context) *BedrockStreamTransformer {
+	return &BedrockStreamTransformer{ctx: ctx, open: -1, toolBlocks: make(map[int]int)}
+}
+
+// Transform converts one chunk into the events it completes, none when the
+// chunk carries nothing new.
+func (s *BedrockStreamTransformer) Transform(chunk *openai.ChatCompletionStreamResponse) ([]*bedrock.ConverseStreamEvent, error) {
+	if s.stopped {
+		return nil, fmt.Errorf("chunk after the stream finished")
+	}
+	s.events = nil
+	s.start()
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			if !s.droppedN {
+				s.droppedN = true
+				if err := dropUnsupported(s.ctx, fmt.Sprintf("choices[%d]", choice.Index), "Bedrock streams a single message"); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		delta := choice.Delta
+		if delta.ReasoningContent != "" {
+			reasoning := delta.ReasoningContent
+			s.delta("reasoning", bedrock.ContentBlockDelta{ReasoningContent: &bedrock.ReasoningContentDelta{Text: &reasoning}})
+		}
+		if text := delta.Content + delta.Refusal; text != "" {
+			s.delta("text", bedrock.ContentBlockDelta{Text: &text})
+		}
+		for _, call := range delta.ToolCalls {
+			if err := s.toolCall(call); err != nil {
+				return nil, err
+			}
+		}
+		if choice.FinishReason != "" && choice.FinishReason != openai.FinishReasonNull {
+			if s.finishReason != openai.FinishReasonToolCalls {
+				s.finishReason = choice.FinishReason
+			}
+			s.closeBlock()
+		}
+	}
+	return s.events, nil
+}
+
+// Finish closes the stream with the open block's contentBlockStop,
+// messageStop and the metadata event carrying usage.
+func (s *BedrockStreamTransformer) Finish() ([]*bedrock.ConverseStreamEvent, error) {
+	if s.stopped {
+		return nil, nil
+	}
+	s.events = nil
+	s.start()
+	s.closeBlock()
+	s.stopped = true
+	s.events = append(s.events,
+		&bedrock.ConverseStreamEvent{MessageStop: &bedrock.MessageStopEvent{StopReason: stopReasonOpenAI2Bedrock(s.finishReason)}},
+		&bedrock.ConverseStreamEvent{Metadata: &bedrock.MetadataEvent{Usage: usage.FromOpenAI(s.usage).Bedrock()}},
+	)
+	return s.events, nil
+}
+
+func (s *BedrockStreamTransformer) start() {
+	if !s.started {
+		s.started = true
+		s.events = append(s.events, &bedrock.ConverseStreamEvent{MessageStart: &bedrock.MessageStartEvent{Role: "assistant"}})
+	}
+}
+
+// delta streams a delta into the open block of kind, opening one first when
+// the open block is of another kind.
+func (s *BedrockStreamTransformer) delta(kind string, delta bedrock.ContentBlockDelta) {
+	if s.open < 0 || s.openKind != kind {
+		s.startBlock(kind, nil)
+	}
+	s.events = append(s.events, &bedrock.ConverseStreamEvent{ContentBlockDelta: &bedrock.ContentBlockDeltaEvent{
+		ContentBlockIndex: s.open,
+		Delta:             delta,
+	}})
+}
+
+// toolCall starts a toolUse block for a new call and streams the argument
+// fragments of its calls. Blocks cannot interleave, so calls must be
+// streamed one after the other.
+func (s *BedrockStreamTransformer) toolCall(call openai.ToolCall) error {
+	callIndex := 0
+	if call.Index != nil {
+		callIndex = *call.Index
+	}
+	index, known := s.toolBlocks[callIndex]
+	if !known {
+		s.startBlock("tool", &bedrock.ContentBlockStart{ToolUse: &bedrock.ToolUseBlockStart{ToolUseID: call.ID, Name: call.Function.Name}})
+		index = s.open
+		s.toolBlocks[callIndex] = index
+	} else if s.open != index {
+		return &TransformationError{
+			Type:    "invalid_stream",
+			Message: fmt.Sprintf("tool call %d continues after another content block started", callIndex),
+		}
+	}
+	if call.Function.Arguments != "" {
+		s.events = append(s.events, &bedrock.ConverseStreamEvent{ContentBlockDelta: &bedrock.ContentBlockDeltaEvent{
+			ContentBlockIndex: index,
+			Delta:             bedrock.ContentBlockDelta{ToolUse: &bedrock.ToolUseBlockDelta{Input: call.Function.Arguments}},
+		}})
+	}
+	return nil
+}
+
+// startBlock opens the next block; Bedrock only sends contentBlockStart for
+// tool blocks.
+func (s *BedrockStreamTransformer) startBlock(kind string, start *bedrock.ContentBlockStart) {
+	s.closeBlock()
+	s.open, s.openKind = s.next, kind
+	s.next++
+	if start != nil {
+		s.events = append(s.events, &bedrock.ConverseStreamEvent{ContentBlockStart: &bedrock.ContentBlockStartEvent{
+			ContentBlockIndex: s.open,
+			Start:             *start,
+		}})
+	}
+}
+
+// closeBlock stops the open block, if any.
+func (s *BedrockStreamTransformer) closeBlock() {
+	if s.open >= 0 {
+		s.events = append(s.events, &bedrock.ConverseStreamEvent{ContentBlockStop: &bedrock.ContentBlockStopEvent{ContentBlockIndex: s.open}})
+		s.open = -1
+	}
+}
@@ -0,0 +1,163 @@
+package transformer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// CachePolicy sets what becomes of the cache_control hints of requests,
+// which only Claude and Bedrock targets honor.
+type CachePolicy int
+
+const (
+	// CacheKeep passes the hints on: OpenAI targets get the un-official
+	// cache_control fields, Gemini targets none.
+	CacheKeep CachePolicy = iota
+	// CacheStrip removes the hints from the requests of targets not honoring
+	// them.
+	CacheStrip
+	// CacheSimulate turns the hints into the caching of the target. OpenAI
+	// targets, caching prefixes on their own, get a prompt_cache_key derived
+	// from the cached prefix, and a 24h retention for hints of 1h. Requests
+	// without hints get breakpoints on the tools, the system prompt and the
+	// last message for Claude and Bedrock targets, as OpenAI would cache
+	// them, of 1h with a 24h prompt_cache_retention.
+	CacheSimulate
+)
+
+// cacheHints applies Options.Caching to the hints of req for target,
+// returning req or an updated copy. Gemini cachedContent references reach
+// Gemini targets, and OpenAI ones as the un-official cached_content.
+func cacheHints(ctx context.Context, target Provider, req *openai.ChatCompletionRequest) (*openai.ChatCompletionRequest, error) {
+	if req.CachedContent != "" && target != ProviderOpenAI && target != ProviderGemini {
+		if err := dropUnsupported(ctx, "cached_content", "cached contents are specific to Gemini"); err != nil {
+			return nil, err
+		}
+	}
+	honored := target == ProviderClaude || target == ProviderBedrock
+	controls := cacheControls(req)
+	hinted := len(controls) > 0
+	switch policy := OptionsFromContext(ctx).Caching; {
+	case policy == CacheStrip && !honored && hinted:
+		out := cloneCacheHints(req)
+		clearCacheControl(out)
+		return out, nil
+	case policy == CacheSimulate && !honored && hinted:
+		out := cloneCacheHints(req)
+		clearCacheControl(out)
+		if target == ProviderOpenAI {
+			if out.PromptCacheKey == "" {
+				out.PromptCacheKey = promptCacheKey(req)
+			}
+			if out.PromptCacheRetention == "" && slices.ContainsFunc(controls, func(c *common.CacheControl) bool { return c.TTL == "1h" }) {
+				out.PromptCacheRetention = openai.PromptCacheRetention24h
+			}
+		}
+		return out, nil
+	case policy == CacheSimulate && honored && !hinted:
+		out := cloneCacheHints(req)
+		breakpoint := &common.CacheControl{Type: "ephemeral"}
+		if req.PromptCacheRetention == openai.PromptCacheRetention24h {
+			breakpoint.TTL = "1h"
+		}
+		if n := len(out.Tools); n > 0 {
+			out.Tools[n-1].CacheControl = breakpoint
+		}
+		if i := lastSystemMessage(out.Messages); i >= 0 {
+			out.Messages[i].CacheControl = breakpoint
+		}
+		if n := len(out.Messages); n > 0 {
+			out.Messages[n-1].CacheControl = breakpoint
+		}
+		return out, nil
+	}
+	return req, nil
+}
+
+// cacheControls returns the hints of the tools and messages of req.
+func cacheControls(req *openai.ChatCompletionRequest) []*common.CacheControl {
+	var controls []*common.CacheControl
+	for _, tool := range req.Tools {
+		if tool.CacheControl != nil {
+			controls = append(controls, tool.CacheControl)
+		}
+	}
+	for _, message := range req.Messages {
+		if message.CacheControl != nil {
+			controls = append(controls, message.CacheControl)
+		}
+		for _, part := range message.MultiContent {
+			if part.CacheControl != nil {
+				controls = append(controls, part.CacheControl)
+			}
+		}
+	}
+	return controls
+}
+
+// lastCachedMessage returns the index of the last message carrying a hint,
+// or -1.
+func lastCachedMessage(messages []openai.ChatCompletionMessage) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		if message.CacheControl != nil || slices.ContainsFunc(message.MultiContent, func(part openai.ChatMessagePart) bool { return part.CacheControl != nil }) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastSystemMessage returns the index of the last system or developer
+// message, or -1.
+func lastSystemMessage(messages []openai.ChatCompletionMessage) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if role := messages[i].Role; role == openai.ChatMessageRoleSystem || role == openai.ChatMessageRoleDeveloper {
+			return i
+		}
+	}
+	return -1
+}
+
+// promptCacheKey derives a key from the model, the tools and the messages up
+// to the last hinted one: requests sharing the cached prefix share the key.
+func promptCacheKey(req *openai.ChatCompletionRequest) string {
+	prefix := struct {
+		Model    string                         `json:"model"`
+		Tools    []openai.Tool                  `json:"tools"`
+		Messages []openai.ChatCompletionMessage `json:"messages"`
+	}{req.Model, req.Tools, req.Messages[:lastCachedMessage(req.Messages)+1]}
+	data, _ := json.Marshal(prefix)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// cloneCacheHints returns a copy of req whose hints can change without
+// changing req.
+func cloneCacheHints(req *openai.ChatCompletionRequest) *openai.ChatCompletionRequest {
+	out := *req
+	out.Tools = slices.Clone(req.Tools)
+	out.Messages = slices.Clone(req.Messages)
+	for i := range out.Messages {
+		out.Messages[i].MultiContent = slices.Clone(out.Messages[i].MultiContent)
+	}
+	return &out
+}
+
+// clearCacheControl strips the un-official cache_control fields of req.
+func clearCacheControl(req *openai.ChatCompletionRequest) {
+	for i := range req.Tools {
+		req.Tools[i].CacheControl = nil
+	}
+	for i := range req.Messages {
+		req.Messages[i].CacheControl = nil
+		for j := range req.Messages[i].MultiContent {
+			req.Messages[i].MultiContent[j].CacheControl = nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package transformer
+
+// ProviderCapabilities describes what the chat API of a provider carries,
+// so callers can warn before a transformation drops content.
+type ProviderCapabilities struct {
+	Provider Provider `json:"provider"`
+	// Vision is image input.
+	Vision            bool `json:"vision"`
+	ToolCalls         bool `json:"tool_calls"`
+	ParallelToolCalls bool `json:"parallel_tool_calls"`
+	// JSONSchema is output constrained to a JSON schema; JSON mode alone
+	// may still be emulated, see Options.EmulateJSONMode.
+	JSONSchema bool `json:"json_schema"`
+	// Reasoning is extended thinking with a budget or effort.
+	Reasoning  bool `json:"reasoning"`
+	AudioInput bool `json:"audio_input"`
+	// DocumentInput is attached documents, such as PDFs.
+	DocumentInput bool `json:"document_input"`
+	// MultipleChoices is generating several alternative messages in one
+	// request, n in OpenAI and candidateCount in Gemini.
+	MultipleChoices bool `json:"multiple_choices"`
+	// MaxContextTokens is the largest context window of the provider's
+	// models, 0 when it depends on the hosted model.
+	MaxContextTokens int `json:"max_context_tokens,omitempty"`
+}
+
+// Capability names, as reported by MissingCapabilities.
+const (
+	CapabilityVision            = "vision"
+	CapabilityToolCalls         = "tool_calls"
+	CapabilityParallelToolCalls = "parallel_tool_calls"
+	CapabilityJSONSchema        = "json_schema"
+	CapabilityReasoning         = "reasoning"
+	CapabilityAudioInput        = "audio_input"
+	CapabilityMultipleChoices   = "multiple_choices"
+	CapabilityDocumentInput     = "document_input"
+)
+
+var capabilities = map[Provider]ProviderCapabilities{
+	ProviderOpenAI: {
+		Vision: true, ToolCalls: true, ParallelToolCalls: true, JSONSchema: true, Reasoning: true, AudioInput: true, DocumentInput: true, MultipleChoices: true,
+		MaxContextTokens: 1047576,
+	},
+	ProviderClaude: {
+		Vision: true, ToolCalls: true, ParallelToolCalls: true, Reasoning: true, DocumentInput: true,
+		MaxContextTokens: 200000,
+	},
+	ProviderGemini: {
+		Vision: true, ToolCalls: true, ParallelToolCalls: true, JSONSchema: true, Reasoning: true, AudioInput: true, DocumentInput: true, MultipleChoices: true,
+		MaxContextTokens: 1048576,
+	},
+	ProviderVertexGemini: {
+		Vision: true, ToolCalls: true, ParallelToolCalls: true, JSONSchema: true, Reasoning: true, AudioInput: true, DocumentInput: true, MultipleChoices: true,
+		MaxContextTokens: 1048576,
+	},
+	ProviderBedrock: {
+		Vision: true, ToolCalls: true, ParallelToolCalls: true, Reasoning: true, DocumentInput: true,
+	},
+	ProviderOllama: {
+		Vision: true, ToolCalls: true, ParallelToolCalls: true, JSONSchema: true, Reasoning: true,
+	},
+}
+
+// Capabilities returns the capabilities of the chat API of provider, false
+// for providers without one, such as Cohere.
+func Capabilities(provider Provider) (ProviderCapabilities, bool) {
+	c, ok := capabilities[provider]
+	c.Provider = provider
+	return c, ok
+}
+
+// MissingCapabilities returns the capabilities of source that target lacks,
+// whose content a transformation from source to target drops or emulates.
+func MissingCapabilities(source, target Provider) []string {
+	s, _ := Capabilities(source)
+	t, _ := Capabilities(target)
+	var missing []string
+	for _, c := range []struct {
+		name           string
+		source, target bool
+	}{
+		{CapabilityVision, s.Vision, t.Vision},
+		{CapabilityToolCalls, s.ToolCalls, t.ToolCalls},
+		{CapabilityParallelToolCalls, s.ParallelToolCalls, t.ParallelToolCalls},
+		{CapabilityJSONSchema, s.JSONSchema, t.JSONSchema},
+		{CapabilityReasoning, s.Reasoning, t.Reasoning},
+		{CapabilityAudioInput, s.AudioInput, t.AudioInput},
+		{CapabilityDocumentInput, s.DocumentInput, t.DocumentInput},
+		{CapabilityMultipleChoices, s.MultipleChoices, t.MultipleChoices},
+	} {
+		if c.source && !c.target {
+			missing = append(missing, c.name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,283 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// claudeCitationUnits are the units of the document locations of Claude
+// citations.
+var claudeCitationUnits = map[string]string{
+	claude.CitationCharLocation:         "char",
+	claude.CitationPageLocation:         "page",
+	claude.CitationContentBlockLocation: "content_block",
+}
+
+// unifiedCitations converts the annotations of an OpenAI message.
+func unifiedCitations(annotations []openai.Annotation) []UnifiedCitation {
+	var citations []UnifiedCitation
+	for _, annotation := range annotations {
+		switch {
+		case annotation.URLCitation != nil:
+			c := annotation.URLCitation
+			citations = append(citations, UnifiedCitation{StartIndex: c.StartIndex, EndIndex: c.EndIndex, URL: c.URL, Title: c.Title, CitedText: c.CitedText})
+		case annotation.DocumentCitation != nil:
+			c := annotation.DocumentCitation
+			citations = append(citations, UnifiedCitation{
+				StartIndex: c.StartIndex,
+				EndIndex:   c.EndIndex,
+				CitedText:  c.CitedText,
+				Document:   &UnifiedDocumentLocation{Index: c.DocumentIndex, Title: c.DocumentTitle, Unit: c.Unit, Start: c.Start, End: c.End},
+			})
+		}
+	}
+	return citations
+}
+
+// openAIAnnotations converts unified citations to annotations.
+func openAIAnnotations(citations []UnifiedCitation) []openai.Annotation {
+	var annotations []openai.Annotation
+	for _, c := range citations {
+		if d := c.Document; d != nil {
+			annotations = append(annotations, openai.Annotation{
+				Type: openai.AnnotationTypeDocumentCitation,
+				DocumentCitation: &openai.DocumentCitation{
+					StartIndex:    c.StartIndex,
+					EndIndex:      c.EndIndex,
+					DocumentIndex: d.Index,
+					DocumentTitle: d.Title,
+					CitedText:     c.CitedText,
+					Unit:          d.Unit,
+					Start:         d.Start,
+					End:           d.End,
+				},
+			})
+			continue
+		}
+		annotations = append(annotations, openai.Annotation{
+			Type:        openai.AnnotationTypeURLCitation,
+			URLCitation: &openai.URLCitation{StartIndex: c.StartIndex, EndIndex: c.EndIndex, URL: c.URL, Title: c.Title, CitedText: c.CitedText},
+		})
+	}
+	return annotations
+}
+
+// annotationSpan returns the span of the content an annotation cites.
+func annotationSpan(annotation openai.Annotation) (int, int) {
+	switch {
+	case annotation.URLCitation != nil:
+		return annotation.URLCitation.StartIndex, annotation.URLCitation.EndIndex
+	case annotation.DocumentCitation != nil:
+		return annotation.DocumentCitation.StartIndex, annotation.DocumentCitation.EndIndex
+	default:
+		return 0, 0
+	}
+}
+
+// annotationsFromClaude converts the citations of a Claude text block
+// spanning the content from start to end. Search result citations are
+// dropped.
+func annotationsFromClaude(ctx context.Context, path string, start, end int, block claude.ClaudeMediaMessage) ([]openai.Annotation, error) {
+	citations, err := block.GetCitations()
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid citations: %w", path, err)
+	}
+	var annotations []openai.Annotation
+	for i, c := range citations {
+		if c.Type == claude.CitationWebSearchResultLocation {
+			annotations = append(annotations, openai.Annotation{
+				Type:        openai.AnnotationTypeURLCitation,
+				URLCitation: &openai.URLCitation{StartIndex: start, EndIndex: end, URL: c.URL, Title: c.Title, CitedText: c.CitedText},
+			})
+			continue
+		}
+		unit, ok := claudeCitationUnits[c.Type]
+		if !ok {
+			if err := dropUnsupported(ctx, fmt.Sprintf("%s.citations[%d]", path, i), "%s citations have no OpenAI equivalent", c.Type); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		from, to := c.StartCharIndex, c.EndCharIndex
+		switch c.Type {
+		case claude.CitationPageLocation:
+			from, to = c.StartPageNumber, c.EndPageNumber
+		case claude.CitationContentBlockLocation:
+			from, to = c.StartBlockIndex, c.EndBlockIndex
+		}
+		annotations = append(annotations, openai.Annotation{
+			Type: openai.AnnotationTypeDocumentCitation,
+			DocumentCitation: &openai.DocumentCitation{
+				StartIndex:    start,
+				EndIndex:      end,
+				DocumentIndex: value(c.DocumentIndex),
+				DocumentTitle: c.DocumentTitle,
+				CitedText:     c.CitedText,
+				Unit:          unit,
+				Start:         value(from),
+				End:           value(to),
+			},
+		})
+	}
+	return annotations, nil
+}
+
+// claudeTextBlocks returns text as Claude text blocks, split where the
+// spans of its annotations begin and end so that each block carries the
+// citations covering it.
+func claudeTextBlocks(text string, annotations []openai.Annotation) []claude.ClaudeMediaMessage {
+	if len(annotations) == 0 {
+		return []claude.ClaudeMediaMessage{{Type: "text", Text: &text}}
+	}
+	runes := []rune(text)
+	bounds := []int{0, len(runes)}
+	for _, annotation := range annotations {
+		start, end := annotationSpan(annotation)
+		bounds = append(bounds, min(max(start, 0), len(runes)), min(max(end, 0), len(runes)))
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	var blocks []claude.ClaudeMediaMessage
+	for i := 0; i+1 < len(bounds); i++ {
+		from, to := bounds[i], bounds[i+1]
+		block := claude.ClaudeMediaMessage{Type: "text"}
+		block.SetText(string(runes[from:to]))
+		var citations []claude.Citation
+		for _, annotation := range annotations {
+			if start, end := annotationSpan(annotation); start <= from && to <= end {
+				citations = append(citations, claudeCitation(annotation))
+			}
+		}
+		if len(citations) > 0 {
+			block.SetCitations(citations)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// claudeCitation converts an annotation to a Claude citation.
+func claudeCitation(annotation openai.Annotation) claude.Citation {
+	if c := annotation.URLCitation; c != nil {
+		return claude.Citation{Type: claude.CitationWebSearchResultLocation, CitedText: c.CitedText, URL: c.URL, Title: c.Title}
+	}
+	c := annotation.DocumentCitation
+	citation := claude.Citation{Type: claude.CitationCharLocation, CitedText: c.CitedText, DocumentIndex: &c.DocumentIndex, DocumentTitle: c.DocumentTitle}
+	from, to := &c.Start, &c.End
+	switch c.Unit {
+	case "page":
+		citation.Type, citation.StartPageNumber, citation.EndPageNumber = claude.CitationPageLocation, from, to
+	case "content_block":
+		citation.Type, citation.StartBlockIndex, citation.EndBlockIndex = claude.CitationContentBlockLocation, from, to
+	default:
+		citation.StartCharIndex, citation.EndCharIndex = from, to
+	}
+	return citation
+}
+
+// annotationsFromGemini converts the grounding metadata of a candidate
+// whose text parts start at offsets of the content: each segment a web page
+// supports is cited. Other sources are dropped.
+func annotationsFromGemini(ctx context.Context, path string, candidate gemini.GeminiChatCandidate, offsets map[int]int) ([]openai.Annotation, error) {
+	if len(candidate.GroundingMetadata) == 0 {
+		return nil, nil
+	}
+	var metadata gemini.GroundingMetadata
+	if err := json.Unmarshal(candidate.GroundingMetadata, &metadata); err != nil {
+		return nil, fmt.Errorf("%s: invalid groundingMetadata: %w", path, err)
+	}
+	for i, chunk := range metadata.GroundingChunks {
+		if chunk.Web == nil {
+			if err := dropUnsupported(ctx, fmt.Sprintf("%s.groundingMetadata.groundingChunks[%d]", path, i), "only web sources have an OpenAI equivalent"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var annotations []openai.Annotation
+	for _, support := range metadata.GroundingSupports {
+		segment := support.Segment
+		offset, ok := offsets[segment.PartIndex]
+		if !ok {
+			continue
+		}
+		text := candidate.Content.Parts[segment.PartIndex].Text
+		start, end := offset+runeIndex(text, segment.StartIndex), offset+runeIndex(text, segment.EndIndex)
+		for _, i := range support.GroundingChunkIndices {
+			if i < 0 || i >= len(metadata.GroundingChunks) || metadata.GroundingChunks[i].Web == nil {
+				continue
+			}
+			web := metadata.GroundingChunks[i].Web
+			annotations = append(annotations, openai.Annotation{
+				Type:        openai.AnnotationTypeURLCitation,
+				URLCitation: &openai.URLCitation{StartIndex: start, EndIndex: end, URL: web.URI, Title: web.Title},
+			})
+		}
+	}
+	return annotations, nil
+}
+
+// geminiGroundingMetadata converts the annotations of text, the part at
+// partIndex of a candidate, to grounding metadata, nil without web pages.
+// Document citations are dropped.
+func geminiGroundingMetadata(ctx context.Context, path, text string, partIndex int, annotations []openai.Annotation) (json.RawMessage, error) {
+	var metadata gemini.GroundingMetadata
+	chunks := map[string]int{}
+	for i, annotation := range annotations {
+		c := annotation.URLCitation
+		if c == nil {
+			if err := dropUnsupported(ctx, fmt.Sprintf("%s.annotations[%d]", path, i), "%s annotations have no Gemini equivalent", annotation.Type); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		chunk, ok := chunks[c.URL]
+		if !ok {
+			chunk = len(metadata.GroundingChunks)
+			chunks[c.URL] = chunk
+			metadata.GroundingChunks = append(metadata.GroundingChunks, gemini.GroundingChunk{Web: &gemini.GroundingChunkWeb{URI: c.URL, Title: c.Title}})
+		}
+		start, end := byteIndex(text, c.StartIndex), byteIndex(text, c.EndIndex)
+		segment := gemini.GroundingSegment{PartIndex: partIndex, StartIndex: start, EndIndex: end, Text: text[start:max(start, end)]}
+		// supports of the same segment cite all their pages
+		if n := len(metadata.GroundingSupports); n > 0 && metadata.GroundingSupports[n-1].Segment == segment {
+			metadata.GroundingSupports[n-1].GroundingChunkIndices = append(metadata.GroundingSupports[n-1].GroundingChunkIndices, chunk)
+			continue
+		}
+		metadata.GroundingSupports = append(metadata.GroundingSupports, gemini.GroundingSupport{Segment: segment, GroundingChunkIndices: []int{chunk}})
+	}
+	if len(metadata.GroundingChunks) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(metadata)
+}
+
+// runeIndex returns the character index of the byte index i of s.
+func runeIndex(s string, i int) int {
+	return utf8.RuneCountInString(s[:min(max(i, 0), len(s))])
+}
+
+// byteIndex returns the byte index of the character index i of s.
+func byteIndex(s string, i int) int {
+	for j := range s {
+		if i <= 0 {
+			return j
+		}
+		i--
+	}
+	return len(s)
+}
+
+// value returns *p, 0 for nil.
+func value(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
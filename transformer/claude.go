@@ -1,14 +1,26 @@
 package transformer
 
 import (
+	"cmp"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
-	"github.com/phosae/llms/claude"
 	"github.com/phosae/llms/common"
-	"github.com/phosae/llms/gemini"
-	"github.com/phosae/llms/openai"
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/usage"
 )
 
 // ClaudeTransformer handles direct Claude to OpenAI transformations
@@ -24,26 +36,126 @@ func (t *ClaudeTransformer) GetProvider() Provider {
 	return ProviderClaude
 }
 
-// ValidateRequest validates the Claude request
+// ValidateRequest checks the Claude request: required fields, alternating
+// roles, tool results answering the tool uses of the turn before, sampling
+// ranges, image media types and the output limit of the model.
 func (t *ClaudeTransformer) ValidateRequest(ctx context.Context, request interface{}) error {
 	req, ok := request.(*claude.ClaudeRequest)
 	if !ok {
 		return fmt.Errorf("invalid request type for Claude transformer")
 	}
 
+	v := &requestValidator{}
 	if req.Model == "" {
-		return fmt.Errorf("model is required")
+		v.add("/model", "model is required")
+	}
+	if req.MaxTokens == 0 {
+		v.add("/max_tokens", "max_tokens is required")
+	} else {
+		v.maxTokens("/max_tokens", req.Model, int(req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		v.between("/temperature", *req.Temperature, 0, 1)
+	}
+	if req.TopP != nil {
+		v.between("/top_p", *req.TopP, 0, 1)
+	}
+	if req.Thinking != nil && req.Thinking.Type == "enabled" {
+		if budget := req.Thinking.GetBudgetTokens(); budget < 1024 || budget >= int(req.MaxTokens) {
+			v.add("/thinking/budget_tokens", "must be at least 1024 and less than max_tokens, got %d", budget)
+		}
 	}
 
 	if len(req.Messages) == 0 {
-		return fmt.Errorf("messages cannot be empty")
+		v.add("/messages", "messages cannot be empty")
+	}
+	contents := make([][]claude.ClaudeMediaMessage, len(req.Messages))
+	for i, message := range req.Messages {
+		path := fmt.Sprintf("/messages/%d", i)
+		switch {
+		case message.Role != "user" && message.Role != "assistant":
+			v.add(path+"/role", "invalid role %q", message.Role)
+		case i == 0 && message.Role != "user":
+			v.add(path+"/role", "the first message must be from the user")
+		case i > 0 && message.Role == req.Messages[i-1].Role:
+			v.add(path+"/role", "roles must alternate between user and assistant, got %s twice", message.Role)
+		}
+		if !message.IsStringContent() {
+			contents[i], _ = message.ParseContent()
+		}
+	}
+	for i, blocks := range contents {
+		// the tool uses a user turn answers are those of the turn before
+		var previous, next []claude.ClaudeMediaMessage
+		if i > 0 {
+			previous = contents[i-1]
+		}
+		if i+1 < len(contents) {
+			next = contents[i+1]
+		}
+		for j, block := range blocks {
+			path := fmt.Sprintf("/messages/%d/content/%d", i, j)
+			switch block.Type {
+			case "tool_result":
+				if !slices.ContainsFunc(previous, func(b claude.ClaudeMediaMessage) bool { return b.Type == "tool_use" && b.Id == block.ToolUseId }) {
+					v.add(path+"/tool_use_id", "tool_result %q does not answer a tool_use of the previous message", block.ToolUseId)
+				}
+			case "tool_use":
+				if i+1 < len(contents) && !slices.ContainsFunc(next, func(b claude.ClaudeMediaMessage) bool { return b.Type == "tool_result" && b.ToolUseId == block.Id }) {
+					v.add(path+"/id", "tool_use %q has no tool_result in the next message", block.Id)
+				}
+			case "image":
+				if block.Source != nil && block.Source.Type == "base64" {
+					v.mediaType(path+"/source/media_type", block.Source.MediaType, imageMediaTypes)
+				}
+			}
+		}
 	}
+	return v.err(ProviderClaude)
+}
 
-	if req.MaxTokens == 0 {
-		return fmt.Errorf("max_tokens is required")
+// ValidateResponse checks role, stop reason and content block consistency of the Claude response
+func (t *ClaudeTransformer) ValidateResponse(ctx context.Context, response interface{}) error {
+	resp, ok := response.(*claude.ClaudeResponse)
+	if !ok {
+		return fmt.Errorf("invalid response type for Claude transformer")
 	}
 
-	return nil
+	var errs []error
+	if resp.Type != "message" {
+		errs = append(errs, fmt.Errorf("type must be message, got %q", resp.Type))
+	}
+	if resp.Role != "assistant" {
+		errs = append(errs, fmt.Errorf("role must be assistant, got %q", resp.Role))
+	}
+	switch resp.StopReason {
+	case "end_turn", "max_tokens", "stop_sequence", "tool_use", "pause_turn", "refusal", "":
+	default:
+		errs = append(errs, fmt.Errorf("unknown stop_reason %q", resp.StopReason))
+	}
+
+	hasToolUse := false
+	for i, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			if block.Text == nil {
+				errs = append(errs, fmt.Errorf("content[%d]: text block without text", i))
+			}
+		case "thinking", "redacted_thinking", "web_search_tool_result", "code_execution_tool_result":
+		case "tool_use", "server_tool_use":
+			hasToolUse = true
+			if block.Id == "" || block.Name == "" {
+				errs = append(errs, fmt.Errorf("content[%d]: %s block requires id and name", i, block.Type))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("content[%d]: unknown block type %q", i, block.Type))
+		}
+	}
+	if resp.StopReason == "tool_use" && !hasToolUse {
+		errs = append(errs, fmt.Errorf("stop_reason is tool_use but content has no tool_use block"))
+	}
+
+	return errors.Join(errs...)
 }
 
 // Do performs the transformation based on the type
@@ -62,6 +174,16 @@ func (t *ClaudeTransformer) Do(ctx context.Context, typ TransformerType, src int
 	}
 }
 
+// ToUnified converts a Claude request, response or chunk to the unified format.
+func (t *ClaudeTransformer) ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	return toUnified(ctx, ProviderClaude, typ, src)
+}
+
+// FromUnified converts a unified request, response or chunk to the Claude payload dst.
+func (t *ClaudeTransformer) FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	return fromUnified(ctx, ProviderClaude, typ, src, dst)
+}
+
 // transformRequest transforms Claude request to OpenAI request
 func (t *ClaudeTransformer) transformRequest(ctx context.Context, src interface{}, dst interface{}) error {
 	claudeReq, ok := src.(*claude.ClaudeRequest)
@@ -69,60 +191,161 @@ func (t *ClaudeTransformer) transformRequest(ctx context.Context, src interface{
 		return fmt.Errorf("invalid source type for Claude request transformer")
 	}
 
-	switch dst.(type) {
+	switch target := dst.(type) {
 	case *openai.ChatCompletionRequest:
-		return transformRequestToOpenAI(ctx, claudeReq, dst.(*openai.ChatCompletionRequest))
+		if err := transformRequestToOpenAI(ctx, claudeReq, target); err != nil {
+			return err
+		}
+		stripOpenAIReasoning(ctx, target)
+		return dropCodeInterpreter(ctx, target)
 	case *claude.ClaudeRequest:
-		return nil
+		// same provider: content, including opaque redacted_thinking blocks, is kept as is
+		return sameProvider(ctx, ProviderClaude, TransformerTypeRequest, claudeReq, target)
 	case *gemini.GeminiChatRequest:
 		return fmt.Errorf("gemini is not supported")
+	case *bedrock.ConverseRequest:
+		return transformViaUnified(ctx, ProviderClaude, ProviderBedrock, TransformerTypeRequest, claudeReq, target)
+	case *vertex.GenerateContentRequest:
+		return transformViaUnified(ctx, ProviderClaude, ProviderVertexGemini, TransformerTypeRequest, claudeReq, target)
+	case *ollama.ChatRequest:
+		return transformViaUnified(ctx, ProviderClaude, ProviderOllama, TransformerTypeRequest, claudeReq, target)
 	default:
 		return fmt.Errorf("invalid target type for Claude transformer")
 	}
 }
 
 func transformRequestToOpenAI(ctx context.Context, claudeReq *claude.ClaudeRequest, oaiReq *openai.ChatCompletionRequest) error {
-	oaiReq.Model = claudeReq.Model
-	oaiReq.MaxTokens = int(claudeReq.MaxTokens)
-	oaiReq.Temperature = func() float32 {
-		if claudeReq.Temperature == nil {
-			return 0
-		}
-		return float32(*claudeReq.Temperature)
-	}()
-	oaiReq.TopP = float32(claudeReq.TopP)
+	cfg := config.FromContext(ctx)
+	oaiReq.Model = TargetModel(ctx, ProviderOpenAI, claudeReq.Model)
+	preset := presetFor(ctx, oaiReq.Model)
+	quirks := cfg.Quirks(oaiReq.Model)
+
+	maxTokens := int(claudeReq.MaxTokens)
+	if maxTokens == 0 {
+		maxTokens = preset.maxTokens()
+	}
+	if quirks.UseMaxCompletionTokens {
+		oaiReq.MaxCompletionTokens = maxTokens
+	} else {
+		oaiReq.MaxTokens = maxTokens
+	}
+	oaiReq.Temperature = cmp.Or(claudeReq.Temperature, preset.temperature())
+	oaiReq.TopP = cmp.Or(claudeReq.TopP, preset.topP())
 	oaiReq.Stream = claudeReq.Stream
 	oaiReq.Stop = claudeReq.StopSequences
 
 	if claudeReq.Thinking != nil && claudeReq.Thinking.Type == "enabled" {
 		budgetTokens := claudeReq.Thinking.GetBudgetTokens()
 		if budgetTokens > 0 {
-			if budgetTokens < 1024 {
-				oaiReq.ReasoningEffort = "low"
-			} else if budgetTokens < 2048 {
-				oaiReq.ReasoningEffort = "medium"
-			} else {
-				oaiReq.ReasoningEffort = "high"
+			oaiReq.ReasoningEffort = quirks.ReasoningEffort(budgetTokens)
+		}
+	}
+
+	if claudeReq.Metadata != nil {
+		oaiReq.User = claudeReq.Metadata.UserId
+	}
+	switch claudeReq.ServiceTier {
+	case "auto":
+		oaiReq.ServiceTier = openai.ServiceTierAuto
+	case "standard_only":
+		oaiReq.ServiceTier = openai.ServiceTierDefault
+	}
+	if len(claudeReq.OutputFormat) > 0 {
+		var format struct {
+			Type   string          `json:"type"`
+			Schema json.RawMessage `json:"schema"`
+		}
+		if err := json.Unmarshal(claudeReq.OutputFormat, &format); err != nil {
+			return fmt.Errorf("invalid output_format: %w", err)
+		}
+		if format.Type == "json_schema" {
+			oaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "output",
+					Schema: format.Schema,
+				},
 			}
 		}
 	}
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"top_k", claudeReq.TopK != nil},
+		{"container", claudeReq.Container != nil},
+		{"mcp_servers", len(claudeReq.MCPServers) > 0},
+		{"context_management", len(claudeReq.ContextManagement) > 0},
+	} {
+		if !field.set {
+			continue
+		}
+		if err := dropUnsupported(ctx, field.name, "OpenAI has no equivalent"); err != nil {
+			return err
+		}
+	}
 
 	tools, _ := common.Any2Type[[]claude.Tool](claudeReq.Tools)
+	searchTools, _ := common.Any2Type[[]claude.ClaudeWebSearchTool](claudeReq.Tools)
 	openAITools := make([]openai.Tool, 0)
-	for _, claudeTool := range tools {
+	for i, claudeTool := range tools {
+		if strings.HasPrefix(claudeTool.Type, claude.WebSearchToolName+"_") {
+			oaiReq.WebSearchOptions = &openai.WebSearchOptions{}
+			if i >= len(searchTools) {
+				continue
+			}
+			search := searchTools[i]
+			if loc := search.UserLocation; loc != nil {
+				oaiReq.WebSearchOptions.UserLocation = &openai.WebSearchUserLocation{
+					Type: "approximate",
+					Approximate: openai.WebSearchApproximateLocation{
+						City:     loc.City,
+						Country:  loc.Country,
+						Region:   loc.Region,
+						Timezone: loc.Timezone,
+					},
+				}
+			}
+			if search.MaxUses != 0 || len(search.AllowedDomains) > 0 || len(search.BlockedDomains) > 0 {
+				if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d]", i), "OpenAI web search has no use limit or domain filters"); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(claudeTool.Type, claude.CodeExecutionToolName+"_") {
+			openAITools = append(openAITools, openai.Tool{Type: openai.ToolTypeCodeInterpreter})
+			continue
+		}
+		if claudeTool.Type != "" && claudeTool.Type != "custom" {
+			if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d]", i), "server tool %s has no OpenAI equivalent", claudeTool.Type); err != nil {
+				return err
+			}
+			continue
+		}
 		openAITools = append(openAITools, openai.Tool{
 			Type: "function",
 			Function: &openai.FunctionDefinition{
 				Name:        claudeTool.Name,
 				Description: claudeTool.Description,
 				Parameters:  claudeTool.InputSchema,
+				Strict:      claudeTool.Strict,
 			},
 			CacheControl: claudeTool.CacheControl,
 		})
 	}
 	oaiReq.Tools = openAITools
+	if len(openAITools) > 0 {
+		var err error
+		if oaiReq.ToolChoice, oaiReq.ParallelToolCalls, err = toolChoiceClaude2OpenAI(claudeReq.ToolChoice); err != nil {
+			return err
+		}
+	}
 
 	oaiMessages := make([]openai.ChatCompletionMessage, 0)
+	if prefix := preset.systemPrefix(); prefix != "" {
+		oaiMessages = append(oaiMessages, openai.ChatCompletionMessage{Role: "system", Content: prefix})
+	}
 	if claudeReq.System != nil {
 		if claudeReq.IsStringSystem() && claudeReq.GetStringSystem() != "" {
 			oaiMessages = append(oaiMessages, openai.ChatCompletionMessage{
@@ -145,7 +368,7 @@ func transformRequestToOpenAI(ctx context.Context, claudeReq *claude.ClaudeReque
 		}
 	}
 
-	for _, claudeMessage := range claudeReq.Messages {
+	for i, claudeMessage := range claudeReq.Messages {
 		openAIMessage := openai.ChatCompletionMessage{
 			Role: claudeMessage.Role,
 		}
@@ -159,7 +382,7 @@ func transformRequestToOpenAI(ctx context.Context, claudeReq *claude.ClaudeReque
 			}
 			parts := make([]openai.ChatMessagePart, 0, len(contents))
 
-			for _, content := range contents {
+			for j, content := range contents {
 				switch content.Type {
 				case "text":
 					parts = append(parts, openai.ChatMessagePart{
@@ -168,20 +391,23 @@ func transformRequestToOpenAI(ctx context.Context, claudeReq *claude.ClaudeReque
 						CacheControl: content.CacheControl,
 					})
 				case "image":
-					var imageData string
-					switch content.Source.Type {
-					case "base64":
-						imageData = fmt.Sprintf("data:%s;base64,%s", content.Source.MediaType, content.Source.Data)
-					case "url":
-						imageData = content.Source.Url
+					part, err := openAIImagePart(content)
+					if err != nil {
+						if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d].content[%d]", i, j), "%v", err); err != nil {
+							return err
+						}
+						continue
 					}
-					parts = append(parts, openai.ChatMessagePart{
-						Type: "image_url",
-						ImageURL: &openai.ChatMessageImageURL{
-							URL: imageData,
-						},
-						CacheControl: content.CacheControl,
-					})
+					parts = append(parts, part)
+				case "document":
+					part, err := openAIDocumentPart(content)
+					if err != nil {
+						if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d].content[%d]", i, j), "%v", err); err != nil {
+							return err
+						}
+						continue
+					}
+					parts = append(parts, part)
 				case "tool_use":
 					openAIMessage.ToolCalls = append(openAIMessage.ToolCalls, openai.ToolCall{
 						ID:   content.Id,
@@ -200,30 +426,249 @@ func transformRequestToOpenAI(ctx context.Context, claudeReq *claude.ClaudeReque
 					}
 					if content.IsStringContent() {
 						oaiToolMessage.Content = content.GetStringContent()
+					} else if text, images, ok := toolResultOpenAIParts(content.ParseMediaContent()); ok {
+						// tool messages only hold text, images follow in the
+						// user message, in place of the tool result
+						oaiToolMessage.Content = text
+						parts = append(parts, images...)
 					} else {
 						mContents := content.ParseMediaContent()
 						json, _ := json.Marshal(mContents)
 						oaiToolMessage.Content = string(json)
 					}
+					if content.GetIsError() {
+						oaiToolMessage.Content = markToolError(oaiToolMessage.Content)
+					}
 					oaiToolMessage.CacheControl = content.CacheControl
 					oaiMessages = append(oaiMessages, oaiToolMessage)
+				case "thinking", "redacted_thinking":
+					openAIMessage.ReasoningContent += content.Thinking
+					openAIMessage.ReasoningDetails = append(openAIMessage.ReasoningDetails, reasoningDetailFromClaude(content))
+				default:
+					if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d].content[%d]", i, j), "%s block has no OpenAI equivalent", content.Type); err != nil {
+						return err
+					}
 				}
 			}
 			openAIMessage.MultiContent = parts
 		}
 
-		if len(openAIMessage.Content) > 0 || len(openAIMessage.MultiContent) > 0 || len(openAIMessage.ToolCalls) > 0 {
+		if len(openAIMessage.Content) > 0 || len(openAIMessage.MultiContent) > 0 || len(openAIMessage.ToolCalls) > 0 || len(openAIMessage.ReasoningDetails) > 0 {
 			oaiMessages = append(oaiMessages, openAIMessage)
 		}
 	}
 
 	oaiReq.Messages = oaiMessages
+	applyOpenAIQuirks(oaiReq, quirks)
+	cached, err := cacheHints(ctx, ProviderOpenAI, oaiReq)
+	if err != nil {
+		return err
+	}
+	normalized, err := normalizeTools(ctx, ProviderOpenAI, cached)
+	if err != nil {
+		return err
+	}
+	*oaiReq = *normalized
 	return nil
 }
 
+// openAIImagePart converts a Claude image block
+func openAIImagePart(block claude.ClaudeMediaMessage) (openai.ChatMessagePart, error) {
+	part := openai.ChatMessagePart{Type: openai.ChatMessagePartTypeImageURL, CacheControl: block.CacheControl}
+	if block.Source == nil {
+		return part, fmt.Errorf("image without source")
+	}
+	switch block.Source.Type {
+	case "base64":
+		part.ImageURL = &openai.ChatMessageImageURL{URL: fmt.Sprintf("data:%s;base64,%s", block.Source.MediaType, block.Source.Data)}
+	case "url":
+		part.ImageURL = &openai.ChatMessageImageURL{URL: block.Source.Url}
+	default:
+		return part, fmt.Errorf("unsupported image source %q", block.Source.Type)
+	}
+	return part, nil
+}
+
+// toolResultOpenAIParts splits tool result content made of text and images
+// into the joined text and the image parts, false for other content
+func toolResultOpenAIParts(blocks []claude.ClaudeMediaMessage) (string, []openai.ChatMessagePart, bool) {
+	var texts []string
+	var images []openai.ChatMessagePart
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.GetText())
+		case "image":
+			part, err := openAIImagePart(block)
+			if err != nil {
+				return "", nil, false
+			}
+			images = append(images, part)
+		default:
+			return "", nil, false
+		}
+	}
+	return strings.Join(texts, "\n"), images, true
+}
+
+// openAIDocumentPart converts a Claude document block to a file part
+func openAIDocumentPart(block claude.ClaudeMediaMessage) (openai.ChatMessagePart, error) {
+	if block.Source == nil {
+		return openai.ChatMessagePart{}, fmt.Errorf("document without source")
+	}
+	data, _ := block.Source.Data.(string)
+	var part openai.ChatMessagePart
+	switch block.Source.Type {
+	case "base64":
+		part = openAIFilePart(block.Source.MediaType, data)
+	case "text":
+		part = openAIFilePart("text/plain", base64.StdEncoding.EncodeToString([]byte(data)))
+	case "url":
+		part = openAIFileReference("application/pdf", block.Source.Url)
+	case "file":
+		part = openAIFileReference(block.Source.MediaType, block.Source.FileId)
+	case "content":
+		// custom content documents keep their text, a plain text file
+		var texts []string
+		for _, content := range block.Source.Content {
+			if content.Type != "text" || content.Text == nil {
+				return part, fmt.Errorf("%s blocks of content documents have no OpenAI equivalent", content.Type)
+			}
+			texts = append(texts, *content.Text)
+		}
+		part = openAIFilePart("text/plain", base64.StdEncoding.EncodeToString([]byte(strings.Join(texts, "\n\n"))))
+	default:
+		return part, fmt.Errorf("unsupported document source %q", block.Source.Type)
+	}
+	part = titled(part, block.Title)
+	part.CacheControl = block.CacheControl
+	return part, nil
+}
+
+// applyOpenAIQuirks adjusts a converted OpenAI request for non-conforming backends
+func applyOpenAIQuirks(oaiReq *openai.ChatCompletionRequest, quirks config.Quirks) {
+	if quirks.DropCacheControl {
+		clearCacheControl(oaiReq)
+	}
+	if quirks.SystemAsUser {
+		for i := range oaiReq.Messages {
+			if oaiReq.Messages[i].Role == openai.ChatMessageRoleSystem {
+				oaiReq.Messages[i].Role = openai.ChatMessageRoleUser
+			}
+		}
+	}
+}
+
 // transformResponse transforms Claude response to OpenAI response
 func (t *ClaudeTransformer) transformResponse(ctx context.Context, src interface{}, dst interface{}) error {
-	return fmt.Errorf("response transformation not yet implemented")
+	claudeResp, ok := src.(*claude.ClaudeResponse)
+	if !ok {
+		return fmt.Errorf("invalid source type for Claude response transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionResponse:
+		return transformResponseToOpenAI(ctx, claudeResp, target)
+	case *claude.ClaudeResponse:
+		return sameProvider(ctx, ProviderClaude, TransformerTypeResponse, claudeResp, target)
+	case *bedrock.ConverseResponse:
+		return transformViaUnified(ctx, ProviderClaude, ProviderBedrock, TransformerTypeResponse, claudeResp, target)
+	case *vertex.GenerateContentResponse:
+		return transformViaUnified(ctx, ProviderClaude, ProviderVertexGemini, TransformerTypeResponse, claudeResp, target)
+	case *ollama.ChatResponse:
+		return transformViaUnified(ctx, ProviderClaude, ProviderOllama, TransformerTypeResponse, claudeResp, target)
+	default:
+		return fmt.Errorf("invalid target type for Claude transformer")
+	}
+}
+
+func transformResponseToOpenAI(ctx context.Context, claudeResp *claude.ClaudeResponse, oaiResp *openai.ChatCompletionResponse) error {
+	oaiResp.ID = claudeResp.Id
+	oaiResp.Object = "chat.completion"
+	oaiResp.Created = time.Now().Unix()
+	oaiResp.Model = claudeResp.Model
+
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	_, serverTools, err := splitClaudeServerTools(claudeResp.Content)
+	if err != nil {
+		return err
+	}
+	var texts []string
+	// structured is whether the answer came as a JSON schema response format
+	structured := false
+	for i, block := range claudeResp.Content {
+		switch block.Type {
+		case "text":
+			text := block.GetText()
+			// citations span the text where it lands in the content
+			offset := utf8.RuneCountInString(strings.Join(texts, ""))
+			texts = append(texts, text)
+			if len(block.Citations) > 0 {
+				annotations, err := annotationsFromClaude(ctx, fmt.Sprintf("content[%d]", i), offset, offset+utf8.RuneCountInString(text), block)
+				if err != nil {
+					return err
+				}
+				message.Annotations = append(message.Annotations, annotations...)
+			}
+		case "thinking", "redacted_thinking":
+			message.ReasoningContent += block.Thinking
+			message.ReasoningDetails = append(message.ReasoningDetails, reasoningDetailFromClaude(block))
+		case claude.ContentTypeServerToolUse:
+		case claude.ContentTypeCodeExecutionToolResult, claude.ContentTypeWebSearchToolResult:
+			// rendered where the result arrives
+			for _, result := range serverTools {
+				if result.ID == block.ToolUseId {
+					rendered, err := renderServerToolResult(ctx, fmt.Sprintf("content[%d]", i), result)
+					if err != nil {
+						return err
+					}
+					texts = append(texts, rendered)
+				}
+			}
+		case "tool_use":
+			if block.Name == claudeStructuredOutputTool {
+				texts = append(texts, toJSONString(block.Input))
+				structured = true
+				continue
+			}
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:   block.Id,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      originalToolName(ctx, block.Name),
+					Arguments: toJSONString(block.Input),
+				},
+			})
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("content[%d]", i), "%s block has no OpenAI equivalent", block.Type); err != nil {
+				return err
+			}
+		}
+	}
+	message.Content = strings.Join(texts, "")
+	if OptionsFromContext(ctx).EmulateJSONMode && (message.Content != "" || len(message.ToolCalls) == 0) {
+		content, err := finishEmulatedJSON(ctx, "content", message.Content)
+		if err != nil {
+			return err
+		}
+		message.Content = content
+	}
+
+	finishReason := stopReasonClaude2OpenAI(claudeResp.StopReason)
+	if structured && len(message.ToolCalls) == 0 && finishReason == openai.FinishReasonToolCalls {
+		finishReason = openai.FinishReasonStop
+	}
+	oaiResp.Choices = []openai.ChatCompletionChoice{
+		{
+			Index:        0,
+			Message:      message,
+			FinishReason: finishReason,
+		},
+	}
+	if claudeResp.Usage != nil {
+		oaiResp.Usage = *usage.FromClaude(claudeResp.Usage).OpenAI()
+	}
+	return nil
 }
 
 // transformStreamResponse transforms Claude stream response to OpenAI stream response
@@ -239,6 +684,23 @@ func (t *ClaudeTransformer) transformChunk(ctx context.Context, src interface{},
 
 // helper functions
 
+func stopReasonClaude2OpenAI(reason string) openai.FinishReason {
+	switch reason {
+	case "end_turn", "stop_sequence", "pause_turn":
+		return openai.FinishReasonStop
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "refusal":
+		return openai.FinishReasonContentFilter
+	case "":
+		return openai.FinishReasonNull
+	default:
+		return openai.FinishReason(reason)
+	}
+}
+
 func toJSONString(v interface{}) string {
 	b, err := json.Marshal(v)
 	if err != nil {
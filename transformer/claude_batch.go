@@ -0,0 +1,79 @@
+package transformer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/phosae/llms/dto/claude"
+)
+
+// BatchResult is one converted entry of a Claude Message Batch results file.
+type BatchResult struct {
+	CustomID string
+	// Type is one of the claude.MessageBatch* result types.
+	Type string
+	// Response is the target provider response of succeeded entries.
+	Response interface{}
+	// Err is set for entries that did not succeed or failed to convert. Errored
+	// entries carry the batch error as a *TransformationError.
+	Err error
+}
+
+// ReadClaudeBatchResults reads the JSON Lines results of a Message Batch and
+// calls fn with each entry converted to a response of target, in file order.
+// Reading stops at the first malformed line or when fn returns an error.
+func ReadClaudeBatchResults(ctx context.Context, r io.Reader, target Provider, fn func(BatchResult) error) error {
+	scanner := bufio.NewScanner(r)
+	// results hold whole messages, lines can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry claude.MessageBatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(convertClaudeBatchResult(ctx, &entry, target)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func convertClaudeBatchResult(ctx context.Context, entry *claude.MessageBatchResult, target Provider) BatchResult {
+	result := BatchResult{CustomID: entry.CustomID, Type: entry.Result.Type}
+	switch entry.Result.Type {
+	case claude.MessageBatchSucceeded:
+		if entry.Result.Message == nil {
+			result.Err = fmt.Errorf("succeeded result without message")
+			return result
+		}
+		if target == ProviderClaude {
+			result.Response = entry.Result.Message
+			return result
+		}
+		dst, err := NewPayload(target, TransformerTypeResponse)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		u, err := ToUnifiedResponse(ctx, ProviderClaude, entry.Result.Message)
+		if err == nil {
+			err = FromUnifiedResponse(ctx, target, u, dst)
+		}
+		result.Response, result.Err = dst, err
+	case claude.MessageBatchErrored:
+		batchErr := &TransformationError{Type: "batch_errored", Message: "batch request errored"}
+		if e := entry.Result.Error; e != nil {
+			batchErr.Type, batchErr.Message = e.Error.Type, e.Error.Message
+		}
+		result.Err = batchErr
+	default:
+		result.Err = &TransformationError{Type: "batch_" + entry.Result.Type, Message: "batch request " + entry.Result.Type}
+	}
+	return result
+}
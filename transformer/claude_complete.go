@@ -0,0 +1,139 @@
+package transformer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/phosae/llms/dto/claude"
+)
+
+// Turn markers of legacy Text Completions prompts
+const (
+	HumanPrompt     = "\n\nHuman:"
+	AssistantPrompt = "\n\nAssistant:"
+)
+
+var completionTurn = regexp.MustCompile(`\n\n(Human|Assistant):`)
+
+// UpgradeClaudeCompletion converts a legacy /v1/complete request to a
+// Messages request. Text before the first Human turn becomes the system
+// prompt, and a non-empty final Assistant turn is kept as a prefill.
+func UpgradeClaudeCompletion(req *claude.ClaudeRequest) (*claude.ClaudeRequest, error) {
+	prompt := req.Prompt
+	if strings.HasPrefix(prompt, "Human:") {
+		prompt = "\n\n" + prompt
+	}
+
+	markers := completionTurn.FindAllStringSubmatchIndex(prompt, -1)
+	if len(markers) == 0 || prompt[markers[0][2]:markers[0][3]] != "Human" {
+		return nil, fmt.Errorf("prompt must contain %q before any %q turn", HumanPrompt, AssistantPrompt)
+	}
+
+	msgReq := &claude.ClaudeRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokensToSample,
+		StopSequences: req.StopSequences,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		TopK:          req.TopK,
+		Stream:        req.Stream,
+		Metadata:      req.Metadata,
+	}
+	if system := strings.TrimSpace(prompt[:markers[0][0]]); system != "" {
+		msgReq.System = system
+	}
+
+	for i, marker := range markers {
+		end := len(prompt)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		role := "user"
+		if prompt[marker[2]:marker[3]] == "Assistant" {
+			role = "assistant"
+		}
+		text := strings.TrimSpace(prompt[marker[1]:end])
+		if text == "" {
+			// the trailing Assistant turn the model completes
+			continue
+		}
+		if n := len(msgReq.Messages); n > 0 && msgReq.Messages[n-1].Role == role {
+			previous := msgReq.Messages[n-1].Content.(string)
+			msgReq.Messages[n-1].Content = previous + "\n\n" + text
+			continue
+		}
+		msgReq.Messages = append(msgReq.Messages, claude.ClaudeMessage{Role: role, Content: text})
+	}
+	if len(msgReq.Messages) == 0 || msgReq.Messages[0].Role != "user" {
+		return nil, fmt.Errorf("prompt has no %q content", HumanPrompt)
+	}
+	return msgReq, nil
+}
+
+// DowngradeClaudeMessage converts a Messages response to a legacy completion.
+func DowngradeClaudeMessage(resp *claude.ClaudeResponse) *claude.ClaudeResponse {
+	var completion strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			completion.WriteString(block.GetText())
+		}
+	}
+	stopReason, stop := completionStopReason(resp.StopReason, resp.StopSequence)
+	return &claude.ClaudeResponse{
+		Id:         resp.Id,
+		Type:       "completion",
+		Completion: completion.String(),
+		StopReason: stopReason,
+		Stop:       stop,
+		Model:      resp.Model,
+	}
+}
+
+// completionStopReason maps a Messages stop reason to the legacy
+// stop_sequence or max_tokens. A natural end of turn is reported as the
+// Human stop sequence, as the legacy endpoint did.
+func completionStopReason(reason, sequence string) (string, string) {
+	switch reason {
+	case "max_tokens":
+		return reason, ""
+	case "stop_sequence":
+		return reason, sequence
+	default:
+		return "stop_sequence", HumanPrompt
+	}
+}
+
+// ClaudeCompletionStream downgrades a Messages event stream to legacy
+// completion events. The zero value is ready to use, one per stream.
+type ClaudeCompletionStream struct {
+	id    string
+	model string
+}
+
+// Downgrade converts one Messages event, nil when the event has no legacy
+// counterpart.
+func (s *ClaudeCompletionStream) Downgrade(event *claude.ClaudeResponse) *claude.ClaudeResponse {
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.id, s.model = event.Message.Id, event.Message.Model
+		}
+	case "content_block_delta":
+		if event.Delta != nil && event.Delta.Type == "text_delta" {
+			return &claude.ClaudeResponse{Id: s.id, Type: "completion", Completion: event.Delta.GetText(), Model: s.model}
+		}
+	case "message_delta":
+		if event.Delta != nil && event.Delta.StopReason != nil {
+			var sequence string
+			if event.Delta.StopSequence != nil {
+				sequence = *event.Delta.StopSequence
+			}
+			stopReason, stop := completionStopReason(*event.Delta.StopReason, sequence)
+			return &claude.ClaudeResponse{Id: s.id, Type: "completion", StopReason: stopReason, Stop: stop, Model: s.model}
+		}
+	case "ping", "error":
+		return event
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package transformer
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// ClaudeTier is the tier of a Claude model.
+type ClaudeTier string
+
+const (
+	ClaudeTierHaiku  ClaudeTier = "haiku"
+	ClaudeTierSonnet ClaudeTier = "sonnet"
+	ClaudeTierOpus   ClaudeTier = "opus"
+)
+
+// ClaudeModel is what a Claude model accepts, told by its name.
+type ClaudeModel struct {
+	Tier ClaudeTier `json:"tier"`
+	// Major and Minor are the version of the model, 3 and 5 for Claude 3.5
+	// Sonnet.
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	// MaxOutputTokens is the largest max_tokens of the model, 0 when
+	// unknown.
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+	// Thinking is extended thinking, from Claude 3.7 Sonnet on.
+	Thinking bool `json:"thinking"`
+}
+
+// ClaudeModelOf returns the tier and limits of a Claude model named by the
+// Claude API, such as claude-3-5-haiku-20241022 or claude-sonnet-4-5, by
+// Vertex AI (claude-3-5-sonnet-v2@20241022) or by Bedrock
+// (us.anthropic.claude-3-7-sonnet-20250219-v1:0); false for other models.
+func ClaudeModelOf(model string) (ClaudeModel, bool) {
+	name := strings.ToLower(model)
+	if _, after, ok := strings.Cut(name, "anthropic."); ok {
+		name = after
+	}
+	name, _, _ = strings.Cut(name, "@")
+	rest, ok := strings.CutPrefix(name, "claude-")
+	if !ok {
+		return ClaudeModel{}, false
+	}
+	var m ClaudeModel
+	var version []int
+	for _, token := range strings.Split(rest, "-") {
+		switch tier := ClaudeTier(token); tier {
+		case ClaudeTierHaiku, ClaudeTierSonnet, ClaudeTierOpus:
+			m.Tier = tier
+			continue
+		}
+		// version numbers, not dates
+		if n, err := strconv.Atoi(token); err == nil && len(token) <= 2 {
+			version = append(version, n)
+		}
+	}
+	if m.Tier == "" || len(version) == 0 {
+		return ClaudeModel{}, false
+	}
+	m.Major = version[0]
+	if len(version) > 1 {
+		m.Minor = version[1]
+	}
+	m.MaxOutputTokens, _ = maxOutputTokens(name)
+	m.Thinking = m.Major > 3 || m.Major == 3 && m.Minor >= 7
+	return m, true
+}
+
+// fitClaudeModel fits the max tokens and thinking budget of a request to
+// what the Claude model accepts: budgets are dropped for models without
+// extended thinking, max tokens make room for the budget and are capped at
+// the output limit of the model. Other models keep them.
+func fitClaudeModel(ctx context.Context, model string, maxTokens, budget int) (int, int, error) {
+	m, known := ClaudeModelOf(model)
+	if known && budget > 0 && !m.Thinking {
+		if err := dropUnsupported(ctx, "reasoning_effort", "%s has no extended thinking", model); err != nil {
+			return 0, 0, err
+		}
+		budget = 0
+	}
+	if budget > 0 && maxTokens <= budget {
+		maxTokens += budget
+	}
+	if limit := m.MaxOutputTokens; known && limit > 0 && maxTokens > limit {
+		warn(ctx, WarningMaxTokensCapped, "max_tokens", "%s generates at most %d tokens, not %d", model, limit, maxTokens)
+		maxTokens = limit
+		if budget >= maxTokens {
+			budget = maxTokens / 2
+		}
+	}
+	return maxTokens, budget, nil
+}
@@ -0,0 +1,54 @@
+package transformer
+
+import (
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/phosae/llms/dto/claude"
+)
+
+// promptVariable matches the {{VARIABLE}} placeholders of prompt templates.
+var promptVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// PromptTemplateToUnified converts a prompt of Anthropic's prompt tools to a
+// unified request, so it can be sent to or stored for any provider. The
+// {{VARIABLE}} placeholders of its texts are replaced by values, e.g. the
+// VariableValues of templatize_prompt; those without a value are kept.
+func PromptTemplateToUnified(ctx context.Context, prompt *claude.PromptToolsResponse, values map[string]string) (*UnifiedRequest, error) {
+	req := &claude.ClaudeRequest{Messages: prompt.Messages}
+	if prompt.System != "" {
+		req.System = prompt.System
+	}
+	u, err := ToUnifiedRequest(ctx, ProviderClaude, req)
+	if err != nil {
+		return nil, err
+	}
+	for i := range u.Messages {
+		for j, part := range u.Messages[i].Parts {
+			u.Messages[i].Parts[j].Text = promptVariable.ReplaceAllStringFunc(part.Text, func(placeholder string) string {
+				if value, ok := values[promptVariable.FindStringSubmatch(placeholder)[1]]; ok {
+					return value
+				}
+				return placeholder
+			})
+		}
+	}
+	return u, nil
+}
+
+// PromptVariables returns the names of the placeholders left in the texts
+// of u, in order of first use.
+func PromptVariables(u *UnifiedRequest) []string {
+	var names []string
+	for _, message := range u.Messages {
+		for _, part := range message.Parts {
+			for _, match := range promptVariable.FindAllStringSubmatch(part.Text, -1) {
+				if !slices.Contains(names, match[1]) {
+					names = append(names, match[1])
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,134 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/phosae/llms/dto/claude"
+)
+
+// splitClaudeServerTools separates the server tool blocks of content,
+// pairing each server_tool_use with its result.
+func splitClaudeServerTools(content []claude.ClaudeMediaMessage) ([]claude.ClaudeMediaMessage, []UnifiedServerToolResult, error) {
+	var rest []claude.ClaudeMediaMessage
+	var results []UnifiedServerToolResult
+	calls := make(map[string]int)
+	for i, block := range content {
+		var tool string
+		switch block.Type {
+		case claude.ContentTypeServerToolUse:
+			calls[block.Id] = len(results)
+			results = append(results, UnifiedServerToolResult{Tool: block.Name, ID: block.Id, Input: toJSONString(block.Input)})
+			continue
+		case claude.ContentTypeCodeExecutionToolResult:
+			tool = ServerToolCodeExecution
+		case claude.ContentTypeWebSearchToolResult:
+			tool = ServerToolWebSearch
+		default:
+			rest = append(rest, block)
+			continue
+		}
+
+		index, ok := calls[block.ToolUseId]
+		if !ok {
+			index = len(results)
+			results = append(results, UnifiedServerToolResult{Tool: tool, ID: block.ToolUseId})
+		}
+		result := &results[index]
+		var err error
+		var failure *claude.ServerToolError
+		if tool == ServerToolCodeExecution {
+			var execution claude.CodeExecutionResult
+			if failure, err = block.ServerToolResult(&execution); failure == nil && err == nil {
+				result.Output, result.Error, result.ReturnCode = execution.Stdout, execution.Stderr, execution.ReturnCode
+			}
+		} else {
+			var pages []claude.WebSearchResult
+			if failure, err = block.ServerToolResult(&pages); failure == nil && err == nil {
+				for _, page := range pages {
+					result.Sources = append(result.Sources, UnifiedSource{URL: page.URL, Title: page.Title})
+				}
+			}
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("content[%d]: invalid %s: %w", i, block.Type, err)
+		}
+		if failure != nil {
+			result.Error = failure.ErrorCode
+		}
+	}
+	return rest, results, nil
+}
+
+// claudeServerToolBlocks returns the server_tool_use and result blocks of
+// results. Web search results lack the encrypted content Claude needs to
+// read them again in a later turn.
+func claudeServerToolBlocks(ctx context.Context, results []UnifiedServerToolResult) ([]claude.ClaudeMediaMessage, error) {
+	var blocks []claude.ClaudeMediaMessage
+	for i, result := range results {
+		path := fmt.Sprintf("server_tool_results[%d]", i)
+		if result.Tool != ServerToolCodeExecution && result.Tool != ServerToolWebSearch {
+			if err := dropUnsupported(ctx, path, "Claude has no %s server tool", result.Tool); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(result.Images) > 0 {
+			if err := dropUnsupported(ctx, path+".images", "Claude server tool results hold no images"); err != nil {
+				return nil, err
+			}
+		}
+		var input any = map[string]any{}
+		if result.Input != "" {
+			json.Unmarshal([]byte(result.Input), &input)
+		}
+		id := result.ID
+		if id == "" {
+			// Gemini code executions have no ids
+			id = fmt.Sprintf("srvtoolu_%d", i)
+		}
+		blocks = append(blocks, claude.ClaudeMediaMessage{Type: claude.ContentTypeServerToolUse, Id: id, Name: result.Tool, Input: input})
+
+		block := claude.ClaudeMediaMessage{ToolUseId: id}
+		switch result.Tool {
+		case ServerToolCodeExecution:
+			block.Type = claude.ContentTypeCodeExecutionToolResult
+			block.Content = claude.CodeExecutionResult{Type: "code_execution_result", Stdout: result.Output, Stderr: result.Error, ReturnCode: result.ReturnCode}
+		case ServerToolWebSearch:
+			block.Type = claude.ContentTypeWebSearchToolResult
+			if result.Error != "" && len(result.Sources) == 0 {
+				block.Content = claude.ServerToolError{Type: "web_search_tool_result_error", ErrorCode: result.Error}
+				break
+			}
+			pages := []claude.WebSearchResult{}
+			for _, source := range result.Sources {
+				pages = append(pages, claude.WebSearchResult{Type: "web_search_result", URL: source.URL, Title: source.Title})
+			}
+			block.Content = pages
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// renderServerToolResult renders a code execution as fenced code and
+// output, as Gemini code execution converts, for targets without server
+// tool blocks. Web search results are dropped: the answer cites them.
+func renderServerToolResult(ctx context.Context, path string, result UnifiedServerToolResult) (string, error) {
+	if result.Tool != ServerToolCodeExecution {
+		return "", dropUnsupported(ctx, path, "%s results have no equivalent in the target", result.Tool)
+	}
+	if len(result.Images) > 0 {
+		if err := dropUnsupported(ctx, path+".images", "text holds no images"); err != nil {
+			return "", err
+		}
+	}
+	var input struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal([]byte(result.Input), &input)
+	output := strings.TrimSuffix(result.Output+result.Error, "\n")
+	return "\n```python\n" + input.Code + "\n```\n```output\n" + output + "\n```\n", nil
+}
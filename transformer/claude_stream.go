@@ -0,0 +1,373 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/usage"
+)
+
+// ClaudeBlockState is the state of one content block of a Claude stream.
+type ClaudeBlockState struct {
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+	Open  bool   `json:"open"`
+	// ToolID and ToolName identify tool_use blocks.
+	ToolID   string `json:"tool_id,omitempty"`
+	ToolName string `json:"tool_name,omitempty"`
+	// ToolInput accumulates input_json_delta fragments of tool_use blocks.
+	ToolInput string `json:"tool_input,omitempty"`
+	// Signature is the signature of a thinking block once received.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ClaudeMessageState is the per-message state machine of a Claude stream. It
+// follows the message_start, content_block_*, message_delta, message_stop
+// lifecycle and can be snapshotted and restored, so a proxy can resume
+// translation after a reconnect or checkpoint a long stream. The sessions
+// translating Claude streams, ClaudeStreamState and StreamTransformer,
+// snapshot it together with their own state.
+type ClaudeMessageState struct {
+	MessageID  string             `json:"message_id,omitempty"`
+	Model      string             `json:"model,omitempty"`
+	Started    bool               `json:"started"`
+	Stopped    bool               `json:"stopped"`
+	Blocks     []ClaudeBlockState `json:"blocks,omitempty"`
+	StopReason string             `json:"stop_reason,omitempty"`
+	// Usage so far, merged with cumulative semantics (see UsageCumulative).
+	Usage claude.ClaudeUsage `json:"usage"`
+}
+
+// NewClaudeMessageState returns the state of a stream that has not started yet.
+func NewClaudeMessageState() *ClaudeMessageState {
+	return &ClaudeMessageState{}
+}
+
+// OpenBlock returns the currently open content block, or nil.
+func (s *ClaudeMessageState) OpenBlock() *ClaudeBlockState {
+	if n := len(s.Blocks); n > 0 && s.Blocks[n-1].Open {
+		return &s.Blocks[n-1]
+	}
+	return nil
+}
+
+// Block returns the content block with the given index, or nil.
+func (s *ClaudeMessageState) Block(index int) *ClaudeBlockState {
+	if index < 0 || index >= len(s.Blocks) {
+		return nil
+	}
+	return &s.Blocks[index]
+}
+
+// NextIndex returns the index of the next content block to be started.
+func (s *ClaudeMessageState) NextIndex() int {
+	return len(s.Blocks)
+}
+
+// Apply advances the state with one stream event, rejecting events that
+// violate the block lifecycle.
+func (s *ClaudeMessageState) Apply(event *claude.ClaudeResponse) error {
+	if event.Type == "ping" || event.Type == "error" {
+		return nil
+	}
+	if s.Stopped {
+		return fmt.Errorf("%s after message_stop", event.Type)
+	}
+	if !s.Started && event.Type != "message_start" {
+		return fmt.Errorf("%s before message_start", event.Type)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if s.Started {
+			return fmt.Errorf("duplicate message_start")
+		}
+		s.Started = true
+		if event.Message != nil {
+			s.MessageID = event.Message.Id
+			s.Model = event.Message.Model
+			mergeClaudeUsage(&s.Usage, event.Message.Usage)
+		}
+	case "content_block_start":
+		if open := s.OpenBlock(); open != nil {
+			return fmt.Errorf("content_block_start while block %d is open", open.Index)
+		}
+		if event.GetIndex() != s.NextIndex() {
+			return fmt.Errorf("content_block_start index %d, expected %d", event.GetIndex(), s.NextIndex())
+		}
+		if event.ContentBlock == nil {
+			return fmt.Errorf("content_block_start without content_block")
+		}
+		s.Blocks = append(s.Blocks, ClaudeBlockState{
+			Index:    event.GetIndex(),
+			Type:     event.ContentBlock.Type,
+			Open:     true,
+			ToolID:   event.ContentBlock.Id,
+			ToolName: event.ContentBlock.Name,
+		})
+	case "content_block_delta":
+		open := s.OpenBlock()
+		if open == nil || open.Index != event.GetIndex() {
+			return fmt.Errorf("content_block_delta for block %d which is not open", event.GetIndex())
+		}
+		if event.Delta == nil {
+			return fmt.Errorf("content_block_delta without delta")
+		}
+		switch event.Delta.Type {
+		case "input_json_delta":
+			if event.Delta.PartialJson != nil {
+				open.ToolInput += *event.Delta.PartialJson
+			}
+		case "signature_delta":
+			open.Signature += event.Delta.Signature
+		}
+	case "content_block_stop":
+		open := s.OpenBlock()
+		if open == nil || open.Index != event.GetIndex() {
+			return fmt.Errorf("content_block_stop for block %d which is not open", event.GetIndex())
+		}
+		open.Open = false
+	case "message_delta":
+		if open := s.OpenBlock(); open != nil {
+			return fmt.Errorf("message_delta while block %d is open", open.Index)
+		}
+		if event.Delta != nil && event.Delta.StopReason != nil {
+			s.StopReason = *event.Delta.StopReason
+		}
+		mergeClaudeUsage(&s.Usage, event.Usage)
+	case "message_stop":
+		s.Stopped = true
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return nil
+}
+
+// mergeClaudeUsage folds a cumulative Claude usage report into dst. message_delta
+// repeats the totals so far rather than an increment, so counters are never summed.
+func mergeClaudeUsage(dst *claude.ClaudeUsage, src *claude.ClaudeUsage) {
+	if src == nil {
+		return
+	}
+	dst.InputTokens = max(dst.InputTokens, src.InputTokens)
+	dst.OutputTokens = max(dst.OutputTokens, src.OutputTokens)
+	dst.CacheCreationInputTokens = max(dst.CacheCreationInputTokens, src.CacheCreationInputTokens)
+	dst.CacheReadInputTokens = max(dst.CacheReadInputTokens, src.CacheReadInputTokens)
+	if src.CacheCreationDetails != nil {
+		dst.CacheCreationDetails = src.CacheCreationDetails
+	}
+	if src.ServerToolUse != nil {
+		dst.ServerToolUse = src.ServerToolUse
+	}
+}
+
+// Snapshot serializes the state.
+func (s *ClaudeMessageState) Snapshot() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// RestoreClaudeMessageState rebuilds a state from a Snapshot.
+func RestoreClaudeMessageState(snapshot []byte) (*ClaudeMessageState, error) {
+	s := &ClaudeMessageState{}
+	if err := json.Unmarshal(snapshot, s); err != nil {
+		return nil, fmt.Errorf("invalid claude stream snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// ClaudeStreamState converts a Claude event stream into OpenAI chat
+// completion chunks as the events arrive. Content blocks are flattened into
+// choice 0: text and thinking deltas become content and reasoning_content,
+// and each tool_use block becomes a tool call whose index counts the tool
+// calls of the message, with its input_json_delta fragments streamed as
+// arguments, except that the input of the tool answering a JSON schema
+// response format is streamed as content.
+type ClaudeStreamState struct {
+	ctx          context.Context
+	message      *ClaudeMessageState
+	includeUsage bool
+	created      int64
+	// toolCalls maps Claude block indexes to OpenAI tool call indexes.
+	toolCalls map[int]int
+	// structured are the indexes of the blocks answering a JSON schema
+	// response format.
+	structured map[int]bool
+}
+
+// NewClaudeStreamState returns the state of one stream. With includeUsage
+// the stream ends with a usage chunk, as for stream_options.include_usage.
+func NewClaudeStreamState(ctx context.Context, includeUsage bool) *ClaudeStreamState {
+	return &ClaudeStreamState{
+		ctx:          ctx,
+		message:      NewClaudeMessageState(),
+		includeUsage: includeUsage,
+		created:      time.Now().Unix(),
+		toolCalls:    make(map[int]int),
+		structured:   make(map[int]bool),
+	}
+}
+
+// Message returns the state of the Claude stream consumed so far.
+func (s *ClaudeStreamState) Message() *ClaudeMessageState {
+	return s.message
+}
+
+type claudeStreamStateSnapshot struct {
+	Message      *ClaudeMessageState `json:"message"`
+	IncludeUsage bool                `json:"include_usage"`
+	Created      int64               `json:"created"`
+	ToolCalls    map[int]int         `json:"tool_calls,omitempty"`
+	Structured   map[int]bool        `json:"structured,omitempty"`
+}
+
+// Snapshot serializes the state, the Claude stream consumed so far with
+// the tool calls it became.
+func (s *ClaudeStreamState) Snapshot() ([]byte, error) {
+	return json.Marshal(claudeStreamStateSnapshot{
+		Message:      s.message,
+		IncludeUsage: s.includeUsage,
+		Created:      s.created,
+		ToolCalls:    s.toolCalls,
+		Structured:   s.structured,
+	})
+}
+
+// RestoreClaudeStreamState rebuilds a state from a Snapshot, continuing the
+// stream with the events after it. ctx carries the options and warnings of
+// the stream.
+func RestoreClaudeStreamState(ctx context.Context, snapshot []byte) (*ClaudeStreamState, error) {
+	var snap claudeStreamStateSnapshot
+	if err := json.Unmarshal(snapshot, &snap); err != nil {
+		return nil, fmt.Errorf("invalid claude stream snapshot: %w", err)
+	}
+	s := NewClaudeStreamState(ctx, snap.IncludeUsage)
+	s.created = snap.Created
+	if snap.Message != nil {
+		s.message = snap.Message
+	}
+	for block, index := range snap.ToolCalls {
+		s.toolCalls[block] = index
+	}
+	for block, structured := range snap.Structured {
+		s.structured[block] = structured
+	}
+	return s, nil
+}
+
+// Transform consumes one event and returns the chunks it produces, none
+// for events without an OpenAI counterpart. An error event is returned as
+// a *TransformationError.
+func (s *ClaudeStreamState) Transform(event *claude.ClaudeResponse) ([]*openai.ChatCompletionStreamResponse, error) {
+	if event.Type == "error" && event.Error != nil {
+		return nil, &TransformationError{Type: event.Error.Type, Message: event.Error.Message}
+	}
+	if err := s.message.Apply(event); err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case "message_start":
+		return s.chunks(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, ""), nil
+	case "content_block_start":
+		block := event.ContentBlock
+		switch block.Type {
+		case "text":
+			if text := block.GetText(); text != "" {
+				return s.chunks(openai.ChatCompletionStreamChoiceDelta{Content: text}, ""), nil
+			}
+		case "thinking":
+			if block.Thinking != "" {
+				return s.chunks(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: block.Thinking}, ""), nil
+			}
+		case "redacted_thinking":
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{ReasoningDetails: []openai.ReasoningDetail{reasoningDetailFromClaude(*block)}}, ""), nil
+		case "tool_use":
+			if block.Name == claudeStructuredOutputTool {
+				s.structured[event.GetIndex()] = true
+				return nil, nil
+			}
+			index := len(s.toolCalls)
+			s.toolCalls[event.GetIndex()] = index
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+				Index:    &index,
+				ID:       block.Id,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: originalToolName(s.ctx, block.Name)},
+			}}}, ""), nil
+		default:
+			if err := dropUnsupported(s.ctx, fmt.Sprintf("content[%d]", event.GetIndex()), "%s block has no OpenAI equivalent", block.Type); err != nil {
+				return nil, err
+			}
+		}
+	case "content_block_delta":
+		delta := event.Delta
+		switch delta.Type {
+		case "text_delta":
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{Content: delta.GetText()}, ""), nil
+		case "thinking_delta":
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: delta.Thinking}, ""), nil
+		case "input_json_delta":
+			if s.structured[event.GetIndex()] && delta.PartialJson != nil && *delta.PartialJson != "" {
+				return s.chunks(openai.ChatCompletionStreamChoiceDelta{Content: *delta.PartialJson}, ""), nil
+			}
+			if index, ok := s.toolCalls[event.GetIndex()]; ok && delta.PartialJson != nil && *delta.PartialJson != "" {
+				return s.toolArguments(index, *delta.PartialJson), nil
+			}
+		}
+	case "content_block_stop":
+		// a tool called without input streams no input_json_delta
+		block := s.message.Block(event.GetIndex())
+		if index, ok := s.toolCalls[event.GetIndex()]; ok && block.ToolInput == "" {
+			return s.toolArguments(index, "{}"), nil
+		}
+		if s.structured[event.GetIndex()] && block.ToolInput == "" {
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{Content: "{}"}, ""), nil
+		}
+		// the thinking streamed already, its signature closes it
+		if block.Type == "thinking" && block.Signature != "" {
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{ReasoningDetails: []openai.ReasoningDetail{{
+				Type: openai.ReasoningDetailTypeText, Signature: block.Signature,
+			}}}, ""), nil
+		}
+	case "message_delta":
+		if event.Delta != nil && event.Delta.StopReason != nil {
+			finishReason := stopReasonClaude2OpenAI(*event.Delta.StopReason)
+			if len(s.structured) > 0 && len(s.toolCalls) == 0 && finishReason == openai.FinishReasonToolCalls {
+				finishReason = openai.FinishReasonStop
+			}
+			return s.chunks(openai.ChatCompletionStreamChoiceDelta{}, finishReason), nil
+		}
+	case "message_stop":
+		if s.includeUsage {
+			return []*openai.ChatCompletionStreamResponse{OpenAIUsageChunk(s.template(), usage.FromClaude(&s.message.Usage).OpenAI())}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *ClaudeStreamState) toolArguments(index int, arguments string) []*openai.ChatCompletionStreamResponse {
+	return s.chunks(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+		Index:    &index,
+		Function: openai.FunctionCall{Arguments: arguments},
+	}}}, "")
+}
+
+// template returns an empty chunk with the identity of the stream.
+func (s *ClaudeStreamState) template() *openai.ChatCompletionStreamResponse {
+	return &openai.ChatCompletionStreamResponse{
+		ID:      s.message.MessageID,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.message.Model,
+	}
+}
+
+func (s *ClaudeStreamState) chunks(delta openai.ChatCompletionStreamChoiceDelta, finishReason openai.FinishReason) []*openai.ChatCompletionStreamResponse {
+	chunk := s.template()
+	chunk.Choices = []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}}
+	return []*openai.ChatCompletionStreamResponse{chunk}
+}
@@ -0,0 +1,57 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// claudeStructuredOutputTool is the tool Claude answers requests for a JSON
+// schema response format through, its input being the answer.
+const claudeStructuredOutputTool = "json_response"
+
+// structuredOutputToClaude asks Claude for an answer of the JSON schema of
+// format by declaring the schema as the input of claudeStructuredOutputTool,
+// and calling that tool: forced when no other tool is declared, required
+// otherwise. With extended thinking, which forces no call, the tool is only
+// offered. Responses get the tool input back as content.
+func structuredOutputToClaude(ctx context.Context, format *openai.ChatCompletionResponseFormatJSONSchema, claudeReq *claude.ClaudeRequest) error {
+	tools, _ := claudeReq.Tools.([]any)
+	choice, _ := claudeReq.ToolChoice.(*claude.ClaudeToolChoice)
+	if choice != nil && choice.Type == "none" {
+		return dropUnsupported(ctx, "response_format", "Claude answers a JSON schema through a tool, which tool_choice none forbids")
+	}
+	for _, tool := range tools {
+		if tool, ok := tool.(claude.Tool); ok && tool.Name == claudeStructuredOutputTool {
+			return dropUnsupported(ctx, "response_format", "Claude answers a JSON schema through a tool named %s, which is taken", claudeStructuredOutputTool)
+		}
+	}
+	schema, err := common.Any2Type[map[string]interface{}](format.Schema)
+	if err != nil {
+		return fmt.Errorf("invalid response_format.json_schema.schema: %w", err)
+	}
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object"}
+	}
+	description := format.Description
+	if description == "" {
+		description = "Respond with your answer as the input of this tool."
+	}
+	claudeReq.Tools = append(tools, claude.Tool{
+		Name:        claudeStructuredOutputTool,
+		Description: description,
+		InputSchema: schema,
+	})
+
+	switch {
+	case claudeReq.Thinking != nil, choice != nil && choice.Type == "tool":
+	case len(tools) == 0:
+		claudeReq.ToolChoice = &claude.ClaudeToolChoice{Type: "tool", Name: claudeStructuredOutputTool}
+	default:
+		claudeReq.ToolChoice = &claude.ClaudeToolChoice{Type: "any", DisableParallelToolUse: choice != nil && choice.DisableParallelToolUse}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+)
+
+func TestClaudeToolResultsWithImages(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// messages are the OpenAI messages following the assistant turn
+		messages string
+	}{
+		{
+			name:     "text",
+			content:  `"rendered"`,
+			messages: `[{"role":"tool","content":"rendered","tool_call_id":"toolu_1"},{"role":"user","content":[{"type":"text","text":"what is it?"}]}]`,
+		},
+		{
+			name:    "text and image",
+			content: `[{"type":"text","text":"rendered"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}}]`,
+			// the image follows in the user message, ahead of its text
+			messages: `[{"role":"tool","content":"rendered","tool_call_id":"toolu_1"},{"role":"user","content":[
+				{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}},{"type":"text","text":"what is it?"}]}]`,
+		},
+		{
+			name:    "images",
+			content: `[{"type":"image","source":{"type":"url","url":"https://example.com/a.png"}},{"type":"image","source":{"type":"url","url":"https://example.com/b.png"}}]`,
+			messages: `[{"role":"tool","tool_call_id":"toolu_1"},{"role":"user","content":[
+				{"type":"image_url","image_url":{"url":"https://example.com/a.png"}},{"type":"image_url","image_url":{"url":"https://example.com/b.png"}},
+				{"type":"text","text":"what is it?"}]}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req claude.ClaudeRequest
+			if err := json.Unmarshal([]byte(`{"model":"claude-sonnet-4-5","max_tokens":100,"messages":[
+				{"role":"user","content":"draw"},
+				{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"render","input":{}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":`+tt.content+`},
+					{"type":"text","text":"what is it?"}]}]}`), &req); err != nil {
+				t.Fatal(err)
+			}
+			var oaiReq openai.ChatCompletionRequest
+			if err := NewClaudeTransformer().Do(context.Background(), TransformerTypeRequest, &req, &oaiReq); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := jsonValue(t, oaiReq.Messages[2:]), jsonValue(t, json.RawMessage(tt.messages)); got != want {
+				t.Errorf("messages = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestClaudeStreamSnapshotResumes cuts the translation of a stream at
+// every chunk, restores the snapshot taken there and checks that the
+// resumed translation matches an uninterrupted one, both ways.
+func TestClaudeStreamSnapshotResumes(t *testing.T) {
+	var chunks []*openai.ChatCompletionStreamResponse
+	for _, s := range []string{
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Let me"}}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":" check."}}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[
+			{"index":0,"id":"call_a","type":"function","function":{"name":"a","arguments":"{\"x\":"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"1}"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[
+			{"index":1,"id":"call_b","type":"function","function":{"name":"b","arguments":"{}"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`,
+	} {
+		var chunk openai.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(s), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, &chunk)
+	}
+
+	// toClaude translates chunks, restoring a snapshot before chunk cut.
+	toClaude := func(cut int) []*claude.ClaudeResponse {
+		s := NewStreamTransformer(context.Background())
+		var events []*claude.ClaudeResponse
+		for i, chunk := range chunks {
+			if i == cut {
+				snapshot, err := s.Snapshot()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s, err = RestoreStreamTransformer(context.Background(), snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+			out, err := s.Transform(chunk)
+			if err != nil {
+				t.Fatalf("cut %d: chunk %d: %v", cut, i, err)
+			}
+			events = append(events, out...)
+		}
+		out, err := s.Finish()
+		if err != nil {
+			t.Fatalf("cut %d: %v", cut, err)
+		}
+		return append(events, out...)
+	}
+	events := toClaude(-1)
+	want := jsonValue(t, events)
+	for cut := range chunks {
+		if got := jsonValue(t, toClaude(cut)); got != want {
+			t.Errorf("to claude, cut at chunk %d:\ngot  %s\nwant %s", cut, got, want)
+		}
+	}
+
+	// toOpenAI translates events back, restoring a snapshot before event cut.
+	toOpenAI := func(cut int) []*openai.ChatCompletionStreamResponse {
+		s := NewClaudeStreamState(context.Background(), true)
+		var out []*openai.ChatCompletionStreamResponse
+		for i, event := range events {
+			if i == cut {
+				snapshot, err := s.Snapshot()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s, err = RestoreClaudeStreamState(context.Background(), snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+			chunks, err := s.Transform(event)
+			if err != nil {
+				t.Fatalf("cut %d: event %d: %v", cut, i, err)
+			}
+			for _, chunk := range chunks {
+				chunk.Created = 0
+			}
+			out = append(out, chunks...)
+		}
+		return out
+	}
+	want = jsonValue(t, toOpenAI(-1))
+	for cut := range events {
+		if got := jsonValue(t, toOpenAI(cut)); got != want {
+			t.Errorf("to openai, cut at event %d:\ngot  %s\nwant %s", cut, got, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/phosae/llms/dto/cohere"
+)
+
+// CohereTransformer converts the embedding payloads of Cohere; chat is not
+// supported.
+type CohereTransformer struct{}
+
+// NewCohereTransformer creates a new Cohere embeddings transformer
+func NewCohereTransformer() *CohereTransformer {
+	return &CohereTransformer{}
+}
+
+func (t *CohereTransformer) GetProvider() Provider {
+	return ProviderCohere
+}
+
+var embeddingTaskCohere = map[string]string{
+	EmbeddingTaskQuery:          cohere.InputTypeSearchQuery,
+	EmbeddingTaskDocument:       cohere.InputTypeSearchDocument,
+	EmbeddingTaskClassification: cohere.InputTypeClassification,
+	EmbeddingTaskClustering:     cohere.InputTypeClustering,
+}
+
+// EmbeddingsToUnified implements EmbeddingsTransformer. Only float
+// embeddings convert.
+func (t *CohereTransformer) EmbeddingsToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *cohere.EmbedRequest:
+		if len(src.EmbeddingTypes) > 0 && !slices.Contains(src.EmbeddingTypes, "float") {
+			return nil, fmt.Errorf("embedding_types: only float embeddings can be converted")
+		}
+		if len(src.EmbeddingTypes) > 1 {
+			if err := dropUnsupported(ctx, "embedding_types", "only float embeddings are returned"); err != nil {
+				return nil, err
+			}
+		}
+		if src.Truncate != "" {
+			if err := dropUnsupported(ctx, "truncate", "the truncation of the target applies"); err != nil {
+				return nil, err
+			}
+		}
+		u := &UnifiedEmbeddingRequest{Model: src.Model, Inputs: src.Texts, Dimensions: src.OutputDimension}
+		for task, inputType := range embeddingTaskCohere {
+			if inputType == src.InputType {
+				u.Task = task
+			}
+		}
+		return u, nil
+	case *cohere.EmbedResponse:
+		if src.Embeddings.Float == nil && (src.Embeddings.Int8 != nil || src.Embeddings.Uint8 != nil) {
+			return nil, fmt.Errorf("embeddings: only float embeddings can be converted")
+		}
+		u := &UnifiedEmbeddingResponse{Embeddings: src.Embeddings.Float}
+		if src.Meta != nil && src.Meta.BilledUnits != nil {
+			u.InputTokens = src.Meta.BilledUnits.InputTokens
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// EmbeddingsFromUnified implements EmbeddingsTransformer. Cohere requires
+// an input type, search_document unless the task says otherwise.
+func (t *CohereTransformer) EmbeddingsFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	req, resp, err := unifiedEmbeddings(typ, src)
+	if err != nil {
+		return err
+	}
+	switch dst := dst.(type) {
+	case *cohere.EmbedRequest:
+		if req == nil {
+			return fmt.Errorf("target type not supported for embedding responses")
+		}
+		inputType, ok := embeddingTaskCohere[req.Task]
+		if !ok {
+			if req.Task != "" {
+				if err := dropUnsupported(ctx, "task", "Cohere has no %s input type", req.Task); err != nil {
+					return err
+				}
+			}
+			inputType = cohere.InputTypeSearchDocument
+		}
+		*dst = cohere.EmbedRequest{
+			Model:           TargetModel(ctx, ProviderCohere, req.Model),
+			Texts:           req.Inputs,
+			InputType:       inputType,
+			EmbeddingTypes:  []string{"float"},
+			OutputDimension: req.Dimensions,
+		}
+		return nil
+	case *cohere.EmbedResponse:
+		if resp == nil {
+			return fmt.Errorf("target type not supported for embedding requests")
+		}
+		*dst = cohere.EmbedResponse{
+			ID:         generateUUID(),
+			Embeddings: cohere.EmbedByType{Float: resp.Embeddings},
+			Meta:       &cohere.Meta{BilledUnits: &cohere.BilledUnits{InputTokens: resp.InputTokens}},
+		}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Cohere embeddings")
+	}
+}
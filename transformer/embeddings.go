@@ -0,0 +1,203 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/cohere"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Embedding tasks, the intended use of the embeddings of a request
+const (
+	EmbeddingTaskQuery          = "query"
+	EmbeddingTaskDocument       = "document"
+	EmbeddingTaskSimilarity     = "similarity"
+	EmbeddingTaskClassification = "classification"
+	EmbeddingTaskClustering     = "clustering"
+)
+
+// UnifiedEmbeddingRequest is the provider-neutral embedding request, the hub
+// through which the registry translates embedding calls.
+type UnifiedEmbeddingRequest struct {
+	Model  string   `json:"model"`
+	Inputs []string `json:"inputs"`
+	// Dimensions truncates the embeddings, 0 for the size of the model.
+	Dimensions int `json:"dimensions,omitempty"`
+	// Task is one of the EmbeddingTask constants, empty when unknown.
+	Task string `json:"task,omitempty"`
+}
+
+// UnifiedEmbeddingResponse holds the embeddings of the inputs, in input order.
+type UnifiedEmbeddingResponse struct {
+	Model       string      `json:"model,omitempty"`
+	Embeddings  [][]float64 `json:"embeddings"`
+	InputTokens int         `json:"input_tokens,omitempty"`
+}
+
+// EmbeddingsTransformer converts the embedding payloads of its provider to
+// and from the unified format.
+type EmbeddingsTransformer interface {
+	GetProvider() Provider
+	// EmbeddingsToUnified returns a *UnifiedEmbeddingRequest or
+	// *UnifiedEmbeddingResponse.
+	EmbeddingsToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error)
+	EmbeddingsFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error
+}
+
+// RegisterEmbeddings adds a provider's embedding converters.
+func (r *TransformationRegistry) RegisterEmbeddings(transformer EmbeddingsTransformer) {
+	r.embeddings[transformer.GetProvider()] = transformer
+}
+
+// TransformEmbeddings converts an embedding request or response of source
+// into one of target, through the unified format. Dst must be a pointer to
+// the target type, e.g. one created by NewEmbeddingsPayload.
+func (r *TransformationRegistry) TransformEmbeddings(ctx context.Context, sourceProvider, targetProvider Provider, typ TransformerType, src interface{}, dst interface{}) error {
+	source, sourceOK := r.embeddings[sourceProvider]
+	target, targetOK := r.embeddings[targetProvider]
+	if !sourceOK || !targetOK {
+		return &TransformationError{
+			Type:    "transformer_not_found",
+			Message: "embeddings transformer not found for " + string(sourceProvider) + " -> " + string(targetProvider),
+		}
+	}
+	if r.configStore != nil && config.FromContext(ctx) == nil {
+		ctx = config.NewContext(ctx, r.configStore.Current())
+	}
+	u, err := source.EmbeddingsToUnified(ctx, typ, src)
+	if err != nil {
+		return err
+	}
+	return target.EmbeddingsFromUnified(ctx, typ, u, dst)
+}
+
+// NewEmbeddingsPayload returns a pointer to a zero embedding request or
+// response of provider, the batch form for Gemini.
+func NewEmbeddingsPayload(provider Provider, typ TransformerType) (interface{}, error) {
+	if typ != TransformerTypeRequest && typ != TransformerTypeResponse {
+		return nil, fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+	request := typ == TransformerTypeRequest
+	switch provider {
+	case ProviderOpenAI:
+		if request {
+			return &openai.EmbeddingRequest{}, nil
+		}
+		return &openai.EmbeddingResponse{}, nil
+	case ProviderGemini:
+		if request {
+			return &gemini.BatchEmbedContentsRequest{}, nil
+		}
+		return &gemini.BatchEmbedContentsResponse{}, nil
+	case ProviderCohere:
+		if request {
+			return &cohere.EmbedRequest{}, nil
+		}
+		return &cohere.EmbedResponse{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// unifiedEmbeddings asserts the unified payload EmbeddingsFromUnified is given.
+func unifiedEmbeddings(typ TransformerType, src interface{}) (*UnifiedEmbeddingRequest, *UnifiedEmbeddingResponse, error) {
+	switch typ {
+	case TransformerTypeRequest:
+		if req, ok := src.(*UnifiedEmbeddingRequest); ok {
+			return req, nil, nil
+		}
+		return nil, nil, fmt.Errorf("source must be a *UnifiedEmbeddingRequest, got %T", src)
+	case TransformerTypeResponse:
+		if resp, ok := src.(*UnifiedEmbeddingResponse); ok {
+			return nil, resp, nil
+		}
+		return nil, nil, fmt.Errorf("source must be a *UnifiedEmbeddingResponse, got %T", src)
+	default:
+		return nil, nil, fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+}
+
+// EmbeddingsToUnified implements EmbeddingsTransformer. Token id inputs
+// have no text and fail the conversion.
+func (t *OpenAITransformer) EmbeddingsToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *openai.EmbeddingRequest:
+		u := &UnifiedEmbeddingRequest{Model: src.Model, Dimensions: src.Dimensions}
+		switch input := src.Input.(type) {
+		case string:
+			u.Inputs = []string{input}
+		case []string:
+			u.Inputs = input
+		case []any:
+			for i, item := range input {
+				text, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("input[%d]: token ids cannot be converted, send text", i)
+				}
+				u.Inputs = append(u.Inputs, text)
+			}
+		default:
+			return nil, fmt.Errorf("input must be a string or an array of strings")
+		}
+		if src.EncodingFormat == "base64" {
+			if err := dropUnsupported(ctx, "encoding_format", "embeddings are returned as floats"); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	case *openai.EmbeddingResponse:
+		u := &UnifiedEmbeddingResponse{
+			Model:       src.Model,
+			Embeddings:  make([][]float64, len(src.Data)),
+			InputTokens: src.Usage.PromptTokens,
+		}
+		for _, data := range src.Data {
+			if data.Index < 0 || data.Index >= len(src.Data) {
+				return nil, fmt.Errorf("data: index %d out of range", data.Index)
+			}
+			u.Embeddings[data.Index] = data.Embedding
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// EmbeddingsFromUnified implements EmbeddingsTransformer.
+func (t *OpenAITransformer) EmbeddingsFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	req, resp, err := unifiedEmbeddings(typ, src)
+	if err != nil {
+		return err
+	}
+	switch dst := dst.(type) {
+	case *openai.EmbeddingRequest:
+		if req == nil {
+			return fmt.Errorf("target type not supported for embedding responses")
+		}
+		*dst = openai.EmbeddingRequest{
+			Input:      req.Inputs,
+			Model:      TargetModel(ctx, ProviderOpenAI, req.Model),
+			Dimensions: req.Dimensions,
+		}
+		return nil
+	case *openai.EmbeddingResponse:
+		if resp == nil {
+			return fmt.Errorf("target type not supported for embedding requests")
+		}
+		*dst = openai.EmbeddingResponse{
+			Object: "list",
+			Data:   make([]openai.Embedding, len(resp.Embeddings)),
+			Model:  resp.Model,
+			Usage:  openai.EmbeddingUsage{PromptTokens: resp.InputTokens, TotalTokens: resp.InputTokens},
+		}
+		for i, embedding := range resp.Embeddings {
+			dst.Data[i] = openai.Embedding{Object: "embedding", Index: i, Embedding: embedding}
+		}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for OpenAI embeddings")
+	}
+}
@@ -0,0 +1,287 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Claude error types, the types of UnifiedError. OpenAI shares
+// invalid_request_error.
+const (
+	ErrorTypeInvalidRequest  = "invalid_request_error"
+	ErrorTypeAuthentication  = "authentication_error"
+	ErrorTypePermission      = "permission_error"
+	ErrorTypeNotFound        = "not_found_error"
+	ErrorTypeRequestTooLarge = "request_too_large"
+	ErrorTypeRateLimit       = "rate_limit_error"
+	ErrorTypeAPI             = "api_error"
+	ErrorTypeOverloaded      = "overloaded_error"
+)
+
+// StatusOverloaded is the status of Claude's overloaded_error.
+const StatusOverloaded = 529
+
+// UnifiedError is the provider-neutral error of a failed call, through which
+// the error envelopes of providers are translated.
+type UnifiedError struct {
+	// Status is the HTTP status of the failed response.
+	Status int `json:"status"`
+	// Type is one of the ErrorType constants.
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Code is the provider's finer error code, e.g. OpenAI's
+	// context_length_exceeded or Gemini's RPC status, when known.
+	Code string `json:"code,omitempty"`
+	// Param is the request field at fault, when known.
+	Param string `json:"param,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *UnifiedError) Error() string {
+	return e.Message
+}
+
+// errorTypeStatus is the HTTP status of error types.
+var errorTypeStatus = map[string]int{
+	ErrorTypeInvalidRequest:  http.StatusBadRequest,
+	ErrorTypeAuthentication:  http.StatusUnauthorized,
+	ErrorTypePermission:      http.StatusForbidden,
+	ErrorTypeNotFound:        http.StatusNotFound,
+	ErrorTypeRequestTooLarge: http.StatusRequestEntityTooLarge,
+	ErrorTypeRateLimit:       http.StatusTooManyRequests,
+	ErrorTypeAPI:             http.StatusInternalServerError,
+	ErrorTypeOverloaded:      StatusOverloaded,
+}
+
+// ErrorStatus returns the HTTP status of an error type, 500 for unknown
+// types.
+func ErrorStatus(typ string) int {
+	if status, ok := errorTypeStatus[typ]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorType returns the error type of an HTTP status.
+func ErrorType(status int) string {
+	switch {
+	case status == http.StatusBadRequest, status == http.StatusUnprocessableEntity, status == http.StatusConflict:
+		return ErrorTypeInvalidRequest
+	case status == http.StatusUnauthorized:
+		return ErrorTypeAuthentication
+	case status == http.StatusForbidden:
+		return ErrorTypePermission
+	case status == http.StatusNotFound:
+		return ErrorTypeNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrorTypeRequestTooLarge
+	case status == http.StatusTooManyRequests:
+		return ErrorTypeRateLimit
+	case status == StatusOverloaded, status == http.StatusServiceUnavailable:
+		return ErrorTypeOverloaded
+	case status >= 400 && status < 500:
+		return ErrorTypeInvalidRequest
+	default:
+		return ErrorTypeAPI
+	}
+}
+
+// geminiErrorStatus is the HTTP status of Google RPC statuses.
+var geminiErrorStatus = map[string]int{
+	"INVALID_ARGUMENT":    http.StatusBadRequest,
+	"FAILED_PRECONDITION": http.StatusBadRequest,
+	"OUT_OF_RANGE":        http.StatusBadRequest,
+	"UNAUTHENTICATED":     http.StatusUnauthorized,
+	"PERMISSION_DENIED":   http.StatusForbidden,
+	"NOT_FOUND":           http.StatusNotFound,
+	"ALREADY_EXISTS":      http.StatusConflict,
+	"ABORTED":             http.StatusConflict,
+	"RESOURCE_EXHAUSTED":  http.StatusTooManyRequests,
+	"CANCELLED":           499,
+	"UNKNOWN":             http.StatusInternalServerError,
+	"INTERNAL":            http.StatusInternalServerError,
+	"DATA_LOSS":           http.StatusInternalServerError,
+	"UNIMPLEMENTED":       http.StatusNotImplemented,
+	"UNAVAILABLE":         http.StatusServiceUnavailable,
+	"DEADLINE_EXCEEDED":   http.StatusGatewayTimeout,
+}
+
+// GeminiErrorStatus returns the Google RPC status of an HTTP status.
+func GeminiErrorStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return "UNIMPLEMENTED"
+	case http.StatusConflict:
+		return "ABORTED"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusBadGateway, http.StatusServiceUnavailable, StatusOverloaded:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	default:
+		return "INTERNAL"
+	}
+}
+
+// bedrockExceptions is the Bedrock exception of error types.
+var bedrockExceptions = map[string]string{
+	ErrorTypeInvalidRequest:  "ValidationException",
+	ErrorTypeAuthentication:  "UnrecognizedClientException",
+	ErrorTypePermission:      "AccessDeniedException",
+	ErrorTypeNotFound:        "ResourceNotFoundException",
+	ErrorTypeRequestTooLarge: "ValidationException",
+	ErrorTypeRateLimit:       "ThrottlingException",
+	ErrorTypeOverloaded:      "ServiceUnavailableException",
+}
+
+// BedrockException returns the Bedrock exception of an error type, sent in
+// the X-Amzn-ErrorType header of failed responses and as the
+// :exception-type of stream exceptions.
+func BedrockException(typ string) string {
+	if exception, ok := bedrockExceptions[typ]; ok {
+		return exception
+	}
+	return "InternalServerException"
+}
+
+// ErrorToUnified parses the error body of a failed response of provider
+// with status. Bodies that are not an error envelope of provider become the
+// message, and the type follows from status when the body has none.
+func ErrorToUnified(provider Provider, status int, body []byte) *UnifiedError {
+	u := &UnifiedError{Status: status}
+	switch provider {
+	case ProviderOpenAI:
+		var resp struct {
+			Error struct {
+				Message string  `json:"message"`
+				Type    string  `json:"type"`
+				Param   *string `json:"param"`
+				Code    any     `json:"code"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &resp)
+		u.Message = resp.Error.Message
+		if resp.Error.Param != nil {
+			u.Param = *resp.Error.Param
+		}
+		// the code is a string, or a number for some compatible servers
+		if resp.Error.Code != nil {
+			u.Code = fmt.Sprint(resp.Error.Code)
+		}
+		switch resp.Error.Type {
+		case "server_error":
+			u.Type = ErrorTypeAPI
+		case "insufficient_quota", "requests", "tokens":
+			u.Type = ErrorTypeRateLimit
+		default:
+			if _, ok := errorTypeStatus[resp.Error.Type]; ok {
+				u.Type = resp.Error.Type
+			}
+		}
+	case ProviderGemini, ProviderVertexGemini:
+		var resp gemini.GeminiError
+		json.Unmarshal(body, &resp)
+		u.Message, u.Code = resp.Error.Message, resp.Error.Status
+		if u.Status == 0 {
+			u.Status = resp.Error.Code
+		}
+		if u.Status == 0 {
+			u.Status = geminiErrorStatus[resp.Error.Status]
+		}
+	case ProviderClaude:
+		var resp claude.ClaudeResponse
+		json.Unmarshal(body, &resp)
+		if resp.Error != nil {
+			u.Message = resp.Error.Message
+			if _, ok := errorTypeStatus[resp.Error.Type]; ok {
+				u.Type = resp.Error.Type
+			}
+		}
+	case ProviderBedrock:
+		// the message is also spelled Message, which decodes alike
+		var resp bedrock.Exception
+		json.Unmarshal(body, &resp)
+		u.Message = resp.Message
+	case ProviderOllama:
+		var resp ollama.ErrorResponse
+		json.Unmarshal(body, &resp)
+		u.Message = resp.Error
+	}
+	if u.Message == "" {
+		u.Message = strings.TrimSpace(string(body))
+	}
+	if u.Status == 0 {
+		u.Status = ErrorStatus(u.Type)
+	}
+	if u.Message == "" {
+		u.Message = http.StatusText(u.Status)
+	}
+	if u.Type == "" {
+		u.Type = ErrorType(u.Status)
+	}
+	return u
+}
+
+// ErrorFromUnified returns the error envelope of provider for u, to send
+// with u.Status: an *openai.ErrorResponse, *gemini.GeminiError,
+// *claude.ClaudeResponse, *bedrock.Exception or *ollama.ErrorResponse.
+func ErrorFromUnified(provider Provider, u *UnifiedError) (any, error) {
+	status := u.Status
+	if status == 0 {
+		status = ErrorStatus(u.Type)
+	}
+	typ := u.Type
+	if typ == "" {
+		typ = ErrorType(status)
+	}
+	switch provider {
+	case ProviderOpenAI:
+		apiError := &openai.APIError{Message: u.Message, Type: typ}
+		switch typ {
+		case ErrorTypeAPI, ErrorTypeOverloaded:
+			apiError.Type = "server_error"
+		case ErrorTypeRequestTooLarge:
+			apiError.Type = ErrorTypeInvalidRequest
+		}
+		if u.Param != "" {
+			apiError.Param = &u.Param
+		}
+		if _, rpc := geminiErrorStatus[u.Code]; u.Code != "" && !rpc {
+			apiError.Code = u.Code
+		}
+		return &openai.ErrorResponse{Error: apiError}, nil
+	case ProviderGemini, ProviderVertexGemini:
+		var resp gemini.GeminiError
+		resp.Error.Code, resp.Error.Message = status, u.Message
+		resp.Error.Status = u.Code
+		if _, ok := geminiErrorStatus[resp.Error.Status]; !ok {
+			resp.Error.Status = GeminiErrorStatus(status)
+		}
+		return &resp, nil
+	case ProviderClaude:
+		return &claude.ClaudeResponse{Type: "error", Error: &claude.ClaudeError{Type: typ, Message: u.Message}}, nil
+	case ProviderBedrock:
+		return &bedrock.Exception{Message: u.Message}, nil
+	case ProviderOllama:
+		return &ollama.ErrorResponse{Error: u.Message}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %s", provider)
+	}
+}
@@ -0,0 +1,212 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FidelityCategory groups the fields a FidelityReport scores.
+type FidelityCategory string
+
+const (
+	FidelityMessages FidelityCategory = "messages"
+	FidelitySystem   FidelityCategory = "system"
+	FidelityTools    FidelityCategory = "tools"
+	// FidelitySampling covers the generation parameters: temperature, top_p,
+	// token limits, stop sequences, seeds and the like.
+	FidelitySampling FidelityCategory = "sampling"
+	FidelityFormat   FidelityCategory = "format"
+	FidelityUsage    FidelityCategory = "usage"
+	FidelityOther    FidelityCategory = "other"
+)
+
+// fidelityCategories classifies fields by name, across providers. A field
+// belongs to the category of the first classified name of its path, so
+// generationConfig.temperature is sampling and choices[0].message.tool_calls
+// is messages.
+var fidelityCategories = map[string]FidelityCategory{
+	"messages": FidelityMessages, "contents": FidelityMessages, "choices": FidelityMessages,
+	"candidates": FidelityMessages, "content": FidelityMessages, "message": FidelityMessages,
+	"output": FidelityMessages, "prompt": FidelityMessages,
+
+	"system": FidelitySystem, "systemInstruction": FidelitySystem,
+
+	"tools": FidelityTools, "tool_choice": FidelityTools, "toolConfig": FidelityTools,
+	"parallel_tool_calls": FidelityTools,
+
+	"temperature": FidelitySampling, "top_p": FidelitySampling, "topP": FidelitySampling,
+	"top_k": FidelitySampling, "topK": FidelitySampling, "max_tokens": FidelitySampling,
+	"max_completion_tokens": FidelitySampling, "maxOutputTokens": FidelitySampling,
+	"stop": FidelitySampling, "stop_sequences": FidelitySampling, "stopSequences": FidelitySampling,
+	"seed": FidelitySampling, "n": FidelitySampling, "candidateCount": FidelitySampling,
+	"presence_penalty": FidelitySampling, "frequency_penalty": FidelitySampling,
+	"presencePenalty": FidelitySampling, "frequencyPenalty": FidelitySampling,
+	"inferenceConfig": FidelitySampling, "options": FidelitySampling,
+	"reasoning_effort": FidelitySampling, "thinking": FidelitySampling, "thinkingConfig": FidelitySampling,
+	"think": FidelitySampling,
+
+	"response_format": FidelityFormat, "responseMimeType": FidelityFormat, "responseSchema": FidelityFormat,
+	"output_format": FidelityFormat, "format": FidelityFormat,
+
+	"usage": FidelityUsage, "usageMetadata": FidelityUsage, "prompt_eval_count": FidelityUsage,
+	"eval_count": FidelityUsage,
+}
+
+// FidelityScore compares the fields of one category before and after a
+// round trip. Fields are the leaves of the JSON payload, named by path,
+// e.g. messages[1].content.
+type FidelityScore struct {
+	// Fields counts the fields of the original payload.
+	Fields int `json:"fields"`
+	// Lost fields are missing after the round trip, Changed ones have
+	// another value and Added ones were not in the original.
+	Lost    []string `json:"lost,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	// Score is the share of fields kept, counting added fields as
+	// differences too: 1 for a lossless round trip.
+	Score float64 `json:"score"`
+}
+
+// FidelityReport scores a round trip of a payload from Source through Via
+// and back, per category and overall.
+type FidelityReport struct {
+	Source     Provider                           `json:"source"`
+	Via        Provider                           `json:"via"`
+	Type       TransformerType                    `json:"type"`
+	Categories map[FidelityCategory]FidelityScore `json:"categories"`
+	Score      float64                            `json:"score"`
+	// Warnings are those of both legs.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Fidelity transforms src, a payload of source, to via and back and scores
+// what survives, to quantify the loss of a route for a given request shape.
+// Fields are compared structurally, so equivalent encodings, such as a text
+// content string and a single text part, count as changes.
+func (r *TransformationRegistry) Fidelity(ctx context.Context, source, via Provider, typ TransformerType, src interface{}) (*FidelityReport, error) {
+	ctx, warnings := WithWarnings(ctx)
+	mid, err := NewPayload(via, typ)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Transform(ctx, source, via, typ, src, mid); err != nil {
+		return nil, fmt.Errorf("%s to %s: %w", source, via, err)
+	}
+	back, err := NewPayload(source, typ)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Transform(ctx, via, source, typ, mid, back); err != nil {
+		return nil, fmt.Errorf("%s to %s: %w", via, source, err)
+	}
+
+	before, err := fidelityLeaves(src)
+	if err != nil {
+		return nil, err
+	}
+	after, err := fidelityLeaves(back)
+	if err != nil {
+		return nil, err
+	}
+	report := &FidelityReport{
+		Source:     source,
+		Via:        via,
+		Type:       typ,
+		Categories: make(map[FidelityCategory]FidelityScore),
+		Warnings:   warnings.List(),
+	}
+	scores := make(map[FidelityCategory]*FidelityScore)
+	score := func(path string) *FidelityScore {
+		category := fidelityCategory(path)
+		if scores[category] == nil {
+			scores[category] = &FidelityScore{}
+		}
+		return scores[category]
+	}
+	for _, path := range slices.Sorted(maps.Keys(before)) {
+		s := score(path)
+		s.Fields++
+		value, ok := after[path]
+		switch {
+		case !ok:
+			s.Lost = append(s.Lost, path)
+		case !reflect.DeepEqual(before[path], value):
+			s.Changed = append(s.Changed, path)
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(after)) {
+		if _, ok := before[path]; !ok {
+			s := score(path)
+			s.Added = append(s.Added, path)
+		}
+	}
+
+	var kept, total int
+	for category, s := range scores {
+		s.Score = fidelityRatio(s.Fields-len(s.Lost)-len(s.Changed), s.Fields+len(s.Added))
+		kept += s.Fields - len(s.Lost) - len(s.Changed)
+		total += s.Fields + len(s.Added)
+		report.Categories[category] = *s
+	}
+	report.Score = fidelityRatio(kept, total)
+	return report, nil
+}
+
+func fidelityRatio(kept, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(kept) / float64(total)
+}
+
+// fidelityCategory classifies a path by the first classified name in it.
+func fidelityCategory(path string) FidelityCategory {
+	names := strings.FieldsFunc(path, func(r rune) bool { return r == '.' || r == '[' || r == ']' })
+	for _, name := range names {
+		if category, ok := fidelityCategories[name]; ok {
+			return category
+		}
+	}
+	return FidelityOther
+}
+
+// fidelityLeaves flattens the JSON encoding of payload into its leaves by
+// path. Empty objects and arrays have none.
+func fidelityLeaves(payload interface{}) (map[string]any, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	leaves := make(map[string]any)
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, child := range v {
+				if path != "" {
+					key = path + "." + key
+				}
+				walk(key, child)
+			}
+		case []any:
+			for i, child := range v {
+				walk(path+"["+strconv.Itoa(i)+"]", child)
+			}
+		default:
+			leaves[path] = v
+		}
+	}
+	walk("", doc)
+	return leaves, nil
+}
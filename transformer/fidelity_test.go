@@ -0,0 +1,143 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/phosae/llms/dto/openai"
+)
+
+// fidelityRequest has a system prompt, a tool and sampling fields, seed
+// among them, which Claude has no home for.
+const fidelityRequest = `{"model":"gpt-4o","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}],
+	"max_tokens":100,"temperature":0.5,"seed":7,"tools":[{"type":"function","function":{"name":"f","parameters":{"type":"object"}}}]}`
+
+func fidelityRegistry() *TransformationRegistry {
+	registry := NewTransformationRegistry()
+	providers := []Provider{ProviderOpenAI, ProviderGemini, ProviderClaude, ProviderBedrock, ProviderVertexGemini, ProviderOllama}
+	for _, t := range []Transformer{
+		NewOpenAITransformer(),
+		NewGeminiTransformer(),
+		NewClaudeTransformer(),
+		NewBedrockTransformer(),
+		NewVertexGeminiTransformer(),
+		NewOllamaTransformer(),
+	} {
+		for _, target := range providers {
+			registry.Register(t.GetProvider(), target, t)
+		}
+	}
+	return registry
+}
+
+func TestFidelityCategory(t *testing.T) {
+	tests := []struct {
+		path string
+		want FidelityCategory
+	}{
+		{"messages[1].content", FidelityMessages},
+		{"choices[0].message.tool_calls[0].id", FidelityMessages},
+		{"systemInstruction.parts[0].text", FidelitySystem},
+		{"generationConfig.temperature", FidelitySampling},
+		{"options.num_predict", FidelitySampling},
+		{"tools[0].function.name", FidelityTools},
+		{"response_format.type", FidelityFormat},
+		{"usage.prompt_tokens", FidelityUsage},
+		{"model", FidelityOther},
+	}
+	for _, tt := range tests {
+		if got := fidelityCategory(tt.path); got != tt.want {
+			t.Errorf("fidelityCategory(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFidelityScoresRoundTrips(t *testing.T) {
+	var req openai.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(fidelityRequest), &req); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		via   Provider
+		score float64
+		// lost and changed fields by category, none for the others
+		lost    map[FidelityCategory][]string
+		changed map[FidelityCategory][]string
+	}{
+		{via: ProviderOpenAI, score: 1},
+		{via: ProviderOllama, score: 1},
+		{via: ProviderClaude, score: 10.0 / 11, lost: map[FidelityCategory][]string{FidelitySampling: {"seed"}}},
+		// Gemini carries the model in the URL, the way back maps it anew
+		{via: ProviderGemini, score: 10.0 / 11, changed: map[FidelityCategory][]string{FidelityOther: {"model"}}},
+	}
+	registry := fidelityRegistry()
+	for _, tt := range tests {
+		t.Run(string(tt.via), func(t *testing.T) {
+			report, err := registry.Fidelity(context.Background(), ProviderOpenAI, tt.via, TransformerTypeRequest, &req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Score != tt.score {
+				t.Errorf("score = %v, want %v", report.Score, tt.score)
+			}
+			for category, score := range report.Categories {
+				if !slices.Equal(score.Lost, tt.lost[category]) || !slices.Equal(score.Changed, tt.changed[category]) {
+					t.Errorf("%s lost %v and changed %v, want %v and %v", category, score.Lost, score.Changed, tt.lost[category], tt.changed[category])
+				}
+				if len(score.Added) > 0 {
+					t.Errorf("%s added %v", category, score.Added)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiHopKeepsMessagesAndTools(t *testing.T) {
+	// each provider converts to and from OpenAI
+	hops := []Provider{ProviderOpenAI, ProviderClaude, ProviderOpenAI, ProviderGemini, ProviderOpenAI, ProviderOllama, ProviderOpenAI, ProviderBedrock, ProviderOpenAI}
+	var src interface{} = &openai.ChatCompletionRequest{}
+	if err := json.Unmarshal([]byte(fidelityRequest), src); err != nil {
+		t.Fatal(err)
+	}
+	registry := fidelityRegistry()
+	payload := src
+	for i, via := range hops[1:] {
+		dst, err := NewPayload(via, TransformerTypeRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := registry.Transform(context.Background(), hops[i], via, TransformerTypeRequest, payload, dst); err != nil {
+			t.Fatalf("%s to %s: %v", hops[i], via, err)
+		}
+		payload = dst
+	}
+
+	before, err := fidelityLeaves(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := fidelityLeaves(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, value := range before {
+		if category := fidelityCategory(path); category != FidelityMessages && category != FidelityTools {
+			continue
+		}
+		if !reflect.DeepEqual(after[path], value) {
+			t.Errorf("%s = %v after %s, want %v", path, after[path], strings.Join(hopNames(hops), " to "), value)
+		}
+	}
+}
+
+func hopNames(hops []Provider) []string {
+	names := make([]string, len(hops))
+	for i, hop := range hops {
+		names[i] = string(hop)
+	}
+	return names
+}
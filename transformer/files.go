@@ -0,0 +1,168 @@
+package transformer
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// fileExtensions are the extensions of the files OpenAI file parts carry
+// for Gemini and Claude: OpenAI names the media type of a file only in its
+// file name, or in the data URL of inline files.
+var fileExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+	"text/csv":        ".csv",
+	"text/html":       ".html",
+	"text/markdown":   ".md",
+	"video/mp4":       ".mp4",
+	"video/mpeg":      ".mpeg",
+	"video/quicktime": ".mov",
+	"video/webm":      ".webm",
+
+	"application/msword": ".doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.ms-excel": ".xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": ".xlsx",
+}
+
+// fileName names a file of mimeType.
+func fileName(mimeType string) string {
+	return "file" + fileExtensions[mimeType]
+}
+
+// openAIFilePart returns the file part of inline data, as a data URL.
+func openAIFilePart(mimeType, data string) openai.ChatMessagePart {
+	return openai.ChatMessagePart{
+		Type: openai.ChatMessagePartTypeFile,
+		File: &openai.ChatMessageFile{FileName: fileName(mimeType), FileData: "data:" + mimeType + ";base64," + data},
+	}
+}
+
+// openAIFileReference returns the file part of a file stored elsewhere, its
+// URI or id in file_id.
+func openAIFileReference(mimeType, id string) openai.ChatMessagePart {
+	return openai.ChatMessagePart{
+		Type: openai.ChatMessagePartTypeFile,
+		File: &openai.ChatMessageFile{FileName: fileName(mimeType), FileId: id},
+	}
+}
+
+// fileTitle returns the name given to an OpenAI file without the extension
+// telling its media type, "" for the names fileName makes up.
+func fileTitle(file *openai.ChatMessageFile) string {
+	mimeType := fileMediaType(file)
+	if file.FileName == fileName(mimeType) {
+		return ""
+	}
+	if ext := path.Ext(file.FileName); ext != "" && strings.EqualFold(ext, fileExtensions[mimeType]) {
+		return strings.TrimSuffix(file.FileName, ext)
+	}
+	return file.FileName
+}
+
+// titled names the file of part after the title of a document, keeping the
+// extension telling its media type.
+func titled(part openai.ChatMessagePart, title string) openai.ChatMessagePart {
+	if title == "" {
+		return part
+	}
+	ext := path.Ext(part.File.FileName)
+	if !strings.EqualFold(path.Ext(title), ext) {
+		title += ext
+	}
+	part.File.FileName = title
+	return part
+}
+
+// fileMediaType returns the media type of an OpenAI file, from its data URL
+// or the extension of its name, "" when unknown.
+func fileMediaType(file *openai.ChatMessageFile) string {
+	if header, _, ok := strings.Cut(file.FileData, ","); ok && strings.HasPrefix(header, "data:") {
+		return strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	}
+	ext := strings.ToLower(path.Ext(file.FileName))
+	for mimeType, known := range fileExtensions {
+		if known == ext {
+			return mimeType
+		}
+	}
+	return ""
+}
+
+// fileData returns the base64 data of an inline OpenAI file, false when the
+// file is referenced by id.
+func fileData(file *openai.ChatMessageFile) (string, bool) {
+	if file.FileData == "" {
+		return "", false
+	}
+	if _, data, ok := strings.Cut(file.FileData, ","); ok && strings.HasPrefix(file.FileData, "data:") {
+		return data, true
+	}
+	// file_data may also be bare base64
+	return file.FileData, true
+}
+
+// isFileURI reports whether a file_id is a URI, such as a Gemini file
+// https://generativelanguage.googleapis.com/v1beta/files/{id} or gs://, not
+// the id of an OpenAI or Claude file.
+func isFileURI(id string) bool {
+	return strings.Contains(id, "://")
+}
+
+// isGeminiFileURI reports whether url names a file Gemini reads by
+// reference, rather than a web resource.
+func isGeminiFileURI(url string) bool {
+	return strings.HasPrefix(url, "gs://") || strings.HasPrefix(url, "https://generativelanguage.googleapis.com/")
+}
+
+// geminiFilePart converts an OpenAI file to inline data, or to file data
+// when its file_id is a URI. Files stored by OpenAI or Claude are dropped.
+func geminiFilePart(ctx context.Context, path string, file *openai.ChatMessageFile) (*gemini.GeminiPart, error) {
+	mimeType := fileMediaType(file)
+	if data, ok := fileData(file); ok {
+		if mimeType == "" {
+			return nil, dropUnsupported(ctx, path, "file %q has no known media type", file.FileName)
+		}
+		return &gemini.GeminiPart{InlineData: &gemini.GeminiInlineData{MimeType: mimeType, Data: data}}, nil
+	}
+	if isFileURI(file.FileId) {
+		return &gemini.GeminiPart{FileData: &gemini.GeminiFileData{MimeType: mimeType, FileUri: file.FileId}}, nil
+	}
+	return nil, dropUnsupported(ctx, path, "file %q is stored by another provider", file.FileId)
+}
+
+// claudeDocument converts an OpenAI file to a Claude document block: a PDF,
+// inline or by URL, plain text, or a file stored by Claude.
+func claudeDocument(ctx context.Context, path string, part openai.ChatMessagePart) (*claude.ClaudeMediaMessage, error) {
+	file := part.File
+	block := &claude.ClaudeMediaMessage{Type: "document", Title: fileTitle(file), CacheControl: part.CacheControl}
+	mimeType := fileMediaType(file)
+	data, inline := fileData(file)
+	switch {
+	case inline && mimeType == "application/pdf":
+		block.Source = &claude.ClaudeMessageSource{Type: "base64", MediaType: mimeType, Data: data}
+	case inline && mimeType == "text/plain":
+		text, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid file data: %w", path, err)
+		}
+		block.Source = &claude.ClaudeMessageSource{Type: "text", MediaType: mimeType, Data: string(text)}
+	case inline:
+		return nil, dropUnsupported(ctx, path, "Claude documents are PDF or plain text, not %s", cmp.Or(mimeType, "an unknown media type"))
+	case isGeminiFileURI(file.FileId):
+		return nil, dropUnsupported(ctx, path, "Claude cannot read Gemini file %s", file.FileId)
+	case isFileURI(file.FileId):
+		block.Source = &claude.ClaudeMessageSource{Type: "url", Url: file.FileId}
+	default:
+		block.Source = &claude.ClaudeMessageSource{Type: "file", FileId: file.FileId}
+	}
+	return block, nil
+}
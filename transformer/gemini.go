@@ -1,14 +1,24 @@
 package transformer
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
-
-	"github.com/phosae/llms/gemini"
-	"github.com/phosae/llms/openai"
+	"unicode/utf8"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/usage"
 )
 
 // GeminiTransformer handles direct Gemini to OpenAI transformations
@@ -24,18 +34,115 @@ func (t *GeminiTransformer) GetProvider() Provider {
 	return ProviderGemini
 }
 
-// ValidateRequest validates the Gemini request
+// ValidateRequest checks the Gemini request: roles, function responses
+// answering the function calls of the turn before, sampling ranges and
+// inline media types. The model is not part of the payload, so the output
+// limit is not checked.
 func (t *GeminiTransformer) ValidateRequest(ctx context.Context, request interface{}) error {
 	req, ok := request.(*gemini.GeminiChatRequest)
 	if !ok {
 		return fmt.Errorf("invalid request type for Gemini transformer")
 	}
 
+	v := &requestValidator{}
+	validateGeminiRequest(v, req)
+	return v.err(ProviderGemini)
+}
+
+// validateGeminiRequest checks req for Gemini and Vertex.
+func validateGeminiRequest(v *requestValidator, req *gemini.GeminiChatRequest) {
+	generation := req.GenerationConfig
+	if generation.Temperature != nil {
+		v.between("/generationConfig/temperature", *generation.Temperature, 0, 2)
+	}
+	if generation.TopP != nil {
+		v.between("/generationConfig/topP", *generation.TopP, 0, 1)
+	}
+
 	if len(req.Contents) == 0 {
-		return fmt.Errorf("contents cannot be empty")
+		v.add("/contents", "contents cannot be empty")
 	}
+	for i, content := range req.Contents {
+		path := fmt.Sprintf("/contents/%d", i)
+		switch content.Role {
+		case "", "user", "model", "function":
+		default:
+			v.add(path+"/role", "invalid role %q", content.Role)
+		}
+		for j, part := range content.Parts {
+			if part.FunctionResponse != nil {
+				name := part.FunctionResponse.Name
+				if i == 0 || !slices.ContainsFunc(req.Contents[i-1].Parts, func(p gemini.GeminiPart) bool { return p.FunctionCall != nil && p.FunctionCall.FunctionName == name }) {
+					v.add(fmt.Sprintf("%s/parts/%d/functionResponse/name", path, j), "functionResponse %q does not answer a functionCall of the previous content", name)
+				}
+			}
+			if part.InlineData != nil && !geminiMediaType(part.InlineData.MimeType) {
+				v.add(fmt.Sprintf("%s/parts/%d/inlineData/mimeType", path, j), "unsupported media type %q", part.InlineData.MimeType)
+			}
+		}
+	}
+}
 
-	return nil
+// geminiMediaType reports whether Gemini accepts inline data of mediaType:
+// images, audio, video, PDF and plain text.
+func geminiMediaType(mediaType string) bool {
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/webp", "image/heic", "image/heif", "application/pdf", "text/plain":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/")
+}
+
+// ValidateResponse checks roles, finish reasons and part consistency of the Gemini response
+func (t *GeminiTransformer) ValidateResponse(ctx context.Context, response interface{}) error {
+	resp, ok := response.(*gemini.GeminiChatResponse)
+	if !ok {
+		return fmt.Errorf("invalid response type for Gemini transformer")
+	}
+
+	var errs []error
+	if len(resp.Candidates) == 0 && resp.PromptFeedback.BlockReason == "" && len(resp.PromptFeedback.SafetyRatings) == 0 {
+		errs = append(errs, fmt.Errorf("candidates cannot be empty unless the prompt was blocked"))
+	}
+	seen := make(map[int64]bool, len(resp.Candidates))
+	for i, candidate := range resp.Candidates {
+		if seen[candidate.Index] {
+			errs = append(errs, fmt.Errorf("candidates[%d]: duplicate index %d", i, candidate.Index))
+		}
+		seen[candidate.Index] = true
+		if role := candidate.Content.Role; role != "" && role != "model" {
+			errs = append(errs, fmt.Errorf("candidates[%d].content.role must be model, got %q", i, role))
+		}
+		if candidate.FinishReason != nil && !geminiFinishReasons[*candidate.FinishReason] {
+			errs = append(errs, fmt.Errorf("candidates[%d]: unknown finishReason %q", i, *candidate.FinishReason))
+		}
+		for j, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil && part.FunctionCall.FunctionName == "" {
+				errs = append(errs, fmt.Errorf("candidates[%d].content.parts[%d]: functionCall name is required", i, j))
+			}
+			if part.InlineData != nil && part.InlineData.MimeType == "" {
+				errs = append(errs, fmt.Errorf("candidates[%d].content.parts[%d]: inlineData mimeType is required", i, j))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var geminiFinishReasons = map[string]bool{
+	"FINISH_REASON_UNSPECIFIED": true,
+	"STOP":                      true,
+	"MAX_TOKENS":                true,
+	"SAFETY":                    true,
+	"RECITATION":                true,
+	"LANGUAGE":                  true,
+	"OTHER":                     true,
+	"BLOCKLIST":                 true,
+	"PROHIBITED_CONTENT":        true,
+	"SPII":                      true,
+	"MALFORMED_FUNCTION_CALL":   true,
+	"IMAGE_SAFETY":              true,
+	"UNEXPECTED_TOOL_CALL":      true,
 }
 
 // Do performs the transformation based on the type
@@ -54,8 +161,289 @@ func (t *GeminiTransformer) Do(ctx context.Context, typ TransformerType, src int
 	}
 }
 
+// ToUnified converts a Gemini request, response or chunk to the unified format.
+func (t *GeminiTransformer) ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	return toUnified(ctx, ProviderGemini, typ, src)
+}
+
+// FromUnified converts a unified request, response or chunk to the Gemini payload dst.
+func (t *GeminiTransformer) FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	return fromUnified(ctx, ProviderGemini, typ, src, dst)
+}
+
 func (t *GeminiTransformer) transformRequest(ctx context.Context, src interface{}, dst interface{}) error {
-	return fmt.Errorf("request transformation not yet implemented")
+	geminiReq, ok := src.(*gemini.GeminiChatRequest)
+	if !ok {
+		return fmt.Errorf("invalid source type for Gemini transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		if err := transformGeminiRequestToOpenAI(ctx, geminiReq, target); err != nil {
+			return err
+		}
+		stripOpenAIReasoning(ctx, target)
+		return dropCodeInterpreter(ctx, target)
+	case *gemini.GeminiChatRequest:
+		return sameProvider(ctx, ProviderGemini, TransformerTypeRequest, geminiReq, target)
+	case *bedrock.ConverseRequest:
+		return transformViaUnified(ctx, ProviderGemini, ProviderBedrock, TransformerTypeRequest, geminiReq, target)
+	case *vertex.GenerateContentRequest:
+		return vertexRequestFromGemini(ctx, geminiReq, target)
+	case *ollama.ChatRequest:
+		return transformViaUnified(ctx, ProviderGemini, ProviderOllama, TransformerTypeRequest, geminiReq, target)
+	default:
+		return fmt.Errorf("target type not supported for Gemini transformer")
+	}
+}
+
+func transformGeminiRequestToOpenAI(ctx context.Context, geminiReq *gemini.GeminiChatRequest, oaiReq *openai.ChatCompletionRequest) error {
+	// Gemini carries the model in the URL, the options or config mappings name it
+	oaiReq.Model = TargetModel(ctx, ProviderOpenAI, "")
+	preset := presetFor(ctx, oaiReq.Model)
+
+	genConfig := geminiReq.GenerationConfig
+	oaiReq.Temperature = cmp.Or(genConfig.Temperature, preset.temperature())
+	oaiReq.TopP = cmp.Or(genConfig.TopP, preset.topP())
+	oaiReq.PresencePenalty, oaiReq.FrequencyPenalty = genConfig.PresencePenalty, genConfig.FrequencyPenalty
+	oaiReq.MaxTokens = int(genConfig.MaxOutputTokens)
+	if oaiReq.MaxTokens == 0 {
+		oaiReq.MaxTokens = preset.maxTokens()
+	}
+	oaiReq.N = genConfig.CandidateCount
+	oaiReq.Stop = genConfig.StopSequences
+	oaiReq.CachedContent = geminiReq.CachedContent
+	if genConfig.Seed != nil {
+		seed := int(*genConfig.Seed)
+		oaiReq.Seed = &seed
+	}
+	if thinking := genConfig.ThinkingConfig; thinking != nil {
+		switch budget := thinking.ThinkingBudget; {
+		case budget != nil && *budget > 0:
+			oaiReq.ReasoningEffort = config.FromContext(ctx).Quirks(oaiReq.Model).ReasoningEffort(*budget)
+		case budget != nil && *budget == 0:
+			// thinking off
+		case thinking.IncludeThoughts:
+			// a dynamic budget, -1 or unset
+			oaiReq.ReasoningEffort = "medium"
+		}
+	}
+	if genConfig.ResponseMimeType == "application/json" {
+		oaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+		if genConfig.ResponseSchema != nil {
+			schema, err := openAIResponseSchema(genConfig.ResponseSchema)
+			if err != nil {
+				return fmt.Errorf("invalid responseSchema: %w", err)
+			}
+			oaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "response",
+					Schema: schema,
+				},
+			}
+		}
+	}
+
+	// Tools
+	for i, tool := range geminiReq.Tools {
+		if tool.GoogleSearch != nil || tool.GoogleSearchRetrieval != nil {
+			oaiReq.WebSearchOptions = &openai.WebSearchOptions{}
+			continue
+		}
+		if tool.CodeExecution != nil {
+			oaiReq.Tools = append(oaiReq.Tools, openai.Tool{Type: openai.ToolTypeCodeInterpreter})
+			continue
+		}
+		if tool.FunctionDeclarations == nil {
+			if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d]", i), "only function declarations, Google Search and code execution have an OpenAI equivalent"); err != nil {
+				return err
+			}
+			continue
+		}
+		declarations, err := geminiFunctionDeclarations(tool)
+		if err != nil {
+			return fmt.Errorf("invalid functionDeclarations in tools[%d]: %w", i, err)
+		}
+		for _, declaration := range declarations {
+			oaiReq.Tools = append(oaiReq.Tools, openai.Tool{Type: openai.ToolTypeFunction, Function: &declaration})
+		}
+	}
+	if geminiReq.ToolConfig != nil {
+		oaiReq.ToolChoice = toolConfigGemini2OpenAI(geminiReq.ToolConfig.FunctionCallingConfig)
+		if config := geminiReq.ToolConfig.FunctionCallingConfig; config != nil && config.Mode == gemini.FunctionCallingValidated {
+			// validated calls are strict ones, for the schemas strict mode takes
+			for _, tool := range oaiReq.Tools {
+				tool.Function.Strict = strictSchema(tool.Function.Parameters)
+			}
+		}
+	}
+
+	// Messages
+	if prefix := preset.systemPrefix(); prefix != "" {
+		oaiReq.Messages = append(oaiReq.Messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: prefix})
+	}
+	if geminiReq.SystemInstructions != nil {
+		// one system message per part, in order
+		for j, part := range geminiReq.SystemInstructions.Parts {
+			if part.Text == "" {
+				if err := dropUnsupported(ctx, fmt.Sprintf("systemInstruction.parts[%d]", j), "OpenAI system messages only hold text"); err != nil {
+					return err
+				}
+				continue
+			}
+			oaiReq.Messages = append(oaiReq.Messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: part.Text,
+			})
+		}
+	}
+
+	// Gemini has no call IDs, responses are matched to calls by name in order
+	pendingCalls := make(map[string][]string)
+	for i, content := range geminiReq.Contents {
+		message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser}
+		if content.Role == "model" {
+			message.Role = openai.ChatMessageRoleAssistant
+		}
+
+		var parts []openai.ChatMessagePart
+		for j, part := range content.Parts {
+			path := fmt.Sprintf("contents[%d].parts[%d]", i, j)
+			switch {
+			case part.Thought:
+				message.ReasoningContent += part.Text
+			case part.Text != "":
+				parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: part.Text})
+			case isAudioPart(part):
+				format := audioFormat(part.InlineData.MimeType)
+				if format != "wav" && format != "mp3" {
+					if err := dropUnsupported(ctx, path, "OpenAI input audio is wav or mp3, not %s", part.InlineData.MimeType); err != nil {
+						return err
+					}
+					continue
+				}
+				parts = append(parts, openai.ChatMessagePart{
+					Type:       openai.ChatMessagePartTypeInputAudio,
+					InputAudio: &openai.ChatMessageInputAudio{Data: part.InlineData.Data, Format: format},
+				})
+			case part.InlineData != nil && !strings.HasPrefix(part.InlineData.MimeType, "image/"):
+				// documents and videos
+				parts = append(parts, openAIFilePart(part.InlineData.MimeType, part.InlineData.Data))
+			case part.InlineData != nil:
+				parts = append(parts, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{
+						URL: fmt.Sprintf("data:%s;base64,%s", part.InlineData.MimeType, part.InlineData.Data),
+					},
+				})
+			case part.FileData != nil && part.FileData.MimeType != "" && !strings.HasPrefix(part.FileData.MimeType, "image/"):
+				parts = append(parts, openAIFileReference(part.FileData.MimeType, part.FileData.FileUri))
+			case part.FileData != nil:
+				parts = append(parts, openai.ChatMessagePart{
+					Type:     openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{URL: part.FileData.FileUri},
+				})
+			case part.FunctionCall != nil:
+				call, err := parseGeminiToolCall(&part)
+				if err != nil {
+					return fmt.Errorf("invalid functionCall in %s: %w", path, err)
+				}
+				call.ID = fmt.Sprintf("call_%d_%d", i, j)
+				pendingCalls[call.Function.Name] = append(pendingCalls[call.Function.Name], call.ID)
+				message.ToolCalls = append(message.ToolCalls, *call)
+			case part.FunctionResponse != nil:
+				name := part.FunctionResponse.Name
+				id := fmt.Sprintf("call_%s", name)
+				if ids := pendingCalls[name]; len(ids) > 0 {
+					id, pendingCalls[name] = ids[0], ids[1:]
+				}
+				oaiReq.Messages = append(oaiReq.Messages, openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					ToolCallID: id,
+					Content:    geminiFunctionResponseContent(part.FunctionResponse.Response),
+				})
+			default:
+				if err := dropUnsupported(ctx, path, "part has no OpenAI equivalent"); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(parts) == 1 && parts[0].Type == openai.ChatMessagePartTypeText {
+			message.Content = parts[0].Text
+		} else {
+			message.MultiContent = parts
+		}
+		if len(parts) > 0 || len(message.ToolCalls) > 0 || message.ReasoningContent != "" {
+			oaiReq.Messages = append(oaiReq.Messages, message)
+		}
+	}
+	normalized, err := normalizeTools(ctx, ProviderOpenAI, oaiReq)
+	if err != nil {
+		return err
+	}
+	*oaiReq = *normalized
+	return nil
+}
+
+// geminiFunctionResponseContent renders a functionResponse as OpenAI tool
+// content, marking {"error": ...} responses as tool errors
+func geminiFunctionResponseContent(response map[string]interface{}) string {
+	if errValue, ok := response["error"]; ok && len(response) == 1 {
+		if text, ok := errValue.(string); ok {
+			return markToolError(text)
+		}
+		return markToolError(toJSONString(errValue))
+	}
+	if content, ok := response["content"].(string); ok && len(response) == 1 {
+		return content
+	}
+	return toJSONString(response)
+}
+
+// geminiFunctionDeclarations reads the declarations of a tool, which clients
+// send either as an array or as a single object
+func geminiFunctionDeclarations(tool gemini.GeminiChatTool) ([]openai.FunctionDefinition, error) {
+	declarations, err := common.Any2Type[[]openai.FunctionDefinition](tool.FunctionDeclarations)
+	if err != nil {
+		declaration, err := common.Any2Type[openai.FunctionDefinition](tool.FunctionDeclarations)
+		if err != nil {
+			return nil, err
+		}
+		declarations = []openai.FunctionDefinition{declaration}
+	}
+	return declarations, nil
+}
+
+// toolConfigGemini2OpenAI converts a Gemini function calling config to an
+// OpenAI tool_choice, an allowed_tools one when it restricts calls to some
+// functions
+func toolConfigGemini2OpenAI(callingConfig *gemini.GeminiFunctionCallingConfig) any {
+	if callingConfig == nil {
+		return nil
+	}
+	allowed := callingConfig.AllowedFunctionNames
+	switch callingConfig.Mode {
+	case gemini.FunctionCallingNone:
+		return "none"
+	case gemini.FunctionCallingAny:
+		switch len(allowed) {
+		case 0:
+			return "required"
+		case 1:
+			return openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: allowed[0]}}
+		default:
+			return allowedToolsChoice("required", allowed)
+		}
+	case gemini.FunctionCallingAuto, gemini.FunctionCallingValidated:
+		if len(allowed) > 0 {
+			return allowedToolsChoice("auto", allowed)
+		}
+		return "auto"
+	default:
+		return nil
+	}
 }
 
 func (t *GeminiTransformer) transformResponse(ctx context.Context, src interface{}, dst interface{}) error {
@@ -67,18 +455,28 @@ func (t *GeminiTransformer) transformResponse(ctx context.Context, src interface
 	switch target := dst.(type) {
 	case *openai.ChatCompletionResponse:
 		return transformGeminiResponseToOpenAI(ctx, geminiResp, target)
+	case *gemini.GeminiChatResponse:
+		return sameProvider(ctx, ProviderGemini, TransformerTypeResponse, geminiResp, target)
+	case *bedrock.ConverseResponse:
+		return transformViaUnified(ctx, ProviderGemini, ProviderBedrock, TransformerTypeResponse, geminiResp, target)
+	case *vertex.GenerateContentResponse:
+		*target = vertex.GenerateContentResponse{GeminiChatResponse: *geminiResp}
+		return nil
+	case *ollama.ChatResponse:
+		return transformViaUnified(ctx, ProviderGemini, ProviderOllama, TransformerTypeResponse, geminiResp, target)
 	default:
 		return fmt.Errorf("target type not supported for Gemini transformer")
 	}
 }
 
 func transformGeminiResponseToOpenAI(ctx context.Context, geminiResp *gemini.GeminiChatResponse, oaiResp *openai.ChatCompletionResponse) error {
+	oaiResp.ID, oaiResp.Model = geminiResp.ResponseID, geminiResp.ModelVersion
 	oaiResp.Object = "chat.completion"
 	oaiResp.Created = time.Now().Unix()
 	oaiResp.Choices = make([]openai.ChatCompletionChoice, 0, len(geminiResp.Candidates))
 
-	isToolCall := false
 	for candidateIndex, candidate := range geminiResp.Candidates {
+		isToolCall := false
 		choice := openai.ChatCompletionChoice{
 			Index: int(candidate.Index),
 			Message: openai.ChatCompletionMessage{
@@ -91,6 +489,9 @@ func transformGeminiResponseToOpenAI(ctx context.Context, geminiResp *gemini.Gem
 		if len(candidate.Content.Parts) > 0 {
 			var texts []string
 			var toolCalls []openai.ToolCall
+			// offsets are where the text parts start in the content, in
+			// characters, for the grounding metadata
+			offsets := map[int]int{}
 
 			for i, part := range candidate.Content.Parts {
 				if part.FunctionCall != nil {
@@ -110,6 +511,10 @@ func transformGeminiResponseToOpenAI(ctx context.Context, geminiResp *gemini.Gem
 					} else {
 						// Filter out empty lines
 						if part.Text != "\n" && part.Text != "" {
+							offsets[i] = utf8.RuneCountInString(strings.Join(texts, "\n"))
+							if len(texts) > 0 {
+								offsets[i]++
+							}
 							texts = append(texts, part.Text)
 						}
 					}
@@ -117,22 +522,22 @@ func transformGeminiResponseToOpenAI(ctx context.Context, geminiResp *gemini.Gem
 			}
 
 			if len(toolCalls) > 0 {
+				restoreToolCalls(ctx, toolCalls)
 				choice.Message.ToolCalls = toolCalls
 				isToolCall = true
 			}
 			choice.Message.Content = strings.Join(texts, "\n")
+			annotations, err := annotationsFromGemini(ctx, fmt.Sprintf("candidates[%d]", candidateIndex), candidate, offsets)
+			if err != nil {
+				return err
+			}
+			choice.Message.Annotations = annotations
 		}
 
 		if candidate.FinishReason != nil {
-			switch *candidate.FinishReason {
-			case "STOP":
-				choice.FinishReason = openai.FinishReasonStop
-			case "MAX_TOKENS":
-				choice.FinishReason = openai.FinishReasonLength
-			default:
-				choice.FinishReason = openai.FinishReasonContentFilter
-			}
+			choice.FinishReason = finishReasonGemini2OpenAI(*candidate.FinishReason)
 		}
+		choice.ContentFilterResults = azureContentFilterResults(candidate.SafetyRatings)
 
 		if isToolCall {
 			choice.FinishReason = openai.FinishReasonToolCalls
@@ -141,25 +546,12 @@ func transformGeminiResponseToOpenAI(ctx context.Context, geminiResp *gemini.Gem
 		oaiResp.Choices = append(oaiResp.Choices, choice)
 	}
 
+	oaiResp.PromptFilterResults = azurePromptFilterResults(geminiResp.PromptFeedback)
+
 	// Convert usage metadata
+	// completion tokens include the thoughts, which Gemini counts apart
 	if geminiResp.UsageMetadata.TotalTokenCount > 0 {
-		oaiResp.Usage = openai.Usage{
-			PromptTokens:     geminiResp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      geminiResp.UsageMetadata.TotalTokenCount,
-		}
-
-		if geminiResp.UsageMetadata.ThoughtsTokenCount > 0 {
-			oaiResp.Usage.CompletionTokensDetails = &openai.CompletionTokensDetails{
-				ReasoningTokens: geminiResp.UsageMetadata.ThoughtsTokenCount,
-			}
-		}
-
-		if geminiResp.UsageMetadata.CachedContentTokenCount > 0 {
-			oaiResp.Usage.PromptTokensDetails = &openai.PromptTokensDetails{
-				CachedTokens: geminiResp.UsageMetadata.CachedContentTokenCount,
-			}
-		}
+		oaiResp.Usage = *usage.FromGemini(&geminiResp.UsageMetadata).OpenAI()
 	}
 
 	return nil
@@ -178,6 +570,17 @@ func (t *GeminiTransformer) transformChunk(ctx context.Context, src interface{},
 	switch target := dst.(type) {
 	case *openai.ChatCompletionStreamResponse:
 		return transformGeminiChunkToOpenAI(ctx, geminiChunk, target)
+	case *[]*openai.ChatCompletionStreamResponse:
+		oaiChunk := &openai.ChatCompletionStreamResponse{}
+		if err := transformGeminiChunkToOpenAI(ctx, geminiChunk, oaiChunk); err != nil {
+			return err
+		}
+		chunks := []*openai.ChatCompletionStreamResponse{oaiChunk}
+		if isFinalGeminiChunk(geminiChunk) && geminiChunk.UsageMetadata.TotalTokenCount > 0 && OptionsFromContext(ctx).IncludeUsage {
+			chunks = append(chunks, OpenAIUsageChunk(oaiChunk, usage.FromGemini(&geminiChunk.UsageMetadata).OpenAI()))
+		}
+		*target = append(*target, chunks...)
+		return nil
 	default:
 		return fmt.Errorf("target type not supported for Gemini transformer")
 	}
@@ -185,6 +588,7 @@ func (t *GeminiTransformer) transformChunk(ctx context.Context, src interface{},
 
 func transformGeminiChunkToOpenAI(ctx context.Context, geminiChunk *gemini.GeminiChatResponse, oaiChunk *openai.ChatCompletionStreamResponse) error {
 	oaiChunk.Object = "chat.completion.chunk"
+	oaiChunk.ID, oaiChunk.Model = geminiChunk.ResponseID, geminiChunk.ModelVersion
 	oaiChunk.Choices = make([]openai.ChatCompletionStreamChoice, 0, len(geminiChunk.Candidates))
 
 	for candidateIndex, candidate := range geminiChunk.Candidates {
@@ -200,14 +604,7 @@ func transformGeminiChunkToOpenAI(ctx context.Context, geminiChunk *gemini.Gemin
 		isThought := false
 
 		if candidate.FinishReason != nil {
-			switch *candidate.FinishReason {
-			case "STOP":
-				choice.FinishReason = "stop"
-			case "MAX_TOKENS":
-				choice.FinishReason = "length"
-			default:
-				choice.FinishReason = "content_filter"
-			}
+			choice.FinishReason = finishReasonGemini2OpenAI(*candidate.FinishReason)
 		}
 
 		for i, part := range candidate.Content.Parts {
@@ -246,15 +643,43 @@ func transformGeminiChunkToOpenAI(ctx context.Context, geminiChunk *gemini.Gemin
 
 		if isTools {
 			choice.FinishReason = "tool_calls"
+			restoreToolCalls(ctx, choice.Delta.ToolCalls)
 		}
+		choice.ContentFilterResults = azureContentFilterResults(candidate.SafetyRatings)
 
 		oaiChunk.Choices = append(oaiChunk.Choices, choice)
 	}
+	oaiChunk.PromptFilterResults = azurePromptFilterResults(geminiChunk.PromptFeedback)
 
 	return nil
 }
 
 // Helper functions
+
+func finishReasonGemini2OpenAI(reason string) openai.FinishReason {
+	switch reason {
+	case "STOP":
+		return openai.FinishReasonStop
+	case "MAX_TOKENS":
+		return openai.FinishReasonLength
+	default:
+		return openai.FinishReasonContentFilter
+	}
+}
+
+// isFinalGeminiChunk reports whether every candidate of the chunk has finished
+func isFinalGeminiChunk(chunk *gemini.GeminiChatResponse) bool {
+	if len(chunk.Candidates) == 0 {
+		return false
+	}
+	for _, candidate := range chunk.Candidates {
+		if candidate.FinishReason == nil {
+			return false
+		}
+	}
+	return true
+}
+
 func parseGeminiToolCall(part *gemini.GeminiPart) (*openai.ToolCall, error) {
 	var argsBytes []byte
 	var err error
@@ -269,14 +694,20 @@ func parseGeminiToolCall(part *gemini.GeminiPart) (*openai.ToolCall, error) {
 		return nil, err
 	}
 
-	return &openai.ToolCall{
+	call := &openai.ToolCall{
 		ID:   fmt.Sprintf("call_%s", generateUUID()),
 		Type: "function",
 		Function: openai.FunctionCall{
 			Arguments: string(argsBytes),
 			Name:      part.FunctionCall.FunctionName,
 		},
-	}, nil
+	}
+	if part.ThoughtSignature != "" {
+		call.ExtraContent = &openai.ToolCallExtraContent{
+			Google: &openai.GoogleToolCallExtraContent{ThoughtSignature: part.ThoughtSignature},
+		}
+	}
+	return call, nil
 }
 
 // Simple UUID generator (simplified version)
@@ -0,0 +1,197 @@
+package transformer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+)
+
+// geminiSpeechMimeType is the audio Gemini speech generation returns: 24kHz
+// 16-bit mono PCM, as OpenAI pcm.
+const geminiSpeechMimeType = "audio/L16;codec=pcm;rate=24000"
+
+// geminiSpeechConfig returns the speechConfig of the prebuilt voice standing
+// in for an OpenAI voice, per the voice map of ctx.
+func geminiSpeechConfig(ctx context.Context, voice string) json.RawMessage {
+	voice, language := voiceMap(ctx).Gemini(voice)
+	config := map[string]any{
+		"voiceConfig": map[string]any{
+			"prebuiltVoiceConfig": map[string]any{"voiceName": voice},
+		},
+	}
+	if language != "" {
+		config["languageCode"] = language
+	}
+	speechConfig, _ := json.Marshal(config)
+	return speechConfig
+}
+
+// geminiVoice returns the OpenAI voice of the prebuilt voice of a
+// speechConfig, per the voice map of ctx, "" for none.
+func geminiVoice(ctx context.Context, speechConfig json.RawMessage) string {
+	var config struct {
+		VoiceConfig struct {
+			PrebuiltVoiceConfig struct {
+				VoiceName string `json:"voiceName"`
+			} `json:"prebuiltVoiceConfig"`
+		} `json:"voiceConfig"`
+	}
+	json.Unmarshal(speechConfig, &config)
+	if voice := config.VoiceConfig.PrebuiltVoiceConfig.VoiceName; voice != "" {
+		return voiceMap(ctx).OpenAI(voice)
+	}
+	return ""
+}
+
+func isAudioPart(part gemini.GeminiPart) bool {
+	return part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/")
+}
+
+// AudioToUnified implements AudioTransformer for generateContent payloads.
+// Requests with an AUDIO response modality are speech requests, others
+// transcription requests of their first audio part; the text around that
+// part is taken as the instruction to transcribe and is not kept. Responses
+// with audio are speech responses, others transcripts.
+func (t *GeminiTransformer) AudioToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *gemini.GeminiChatRequest:
+		if slices.Contains(src.GenerationConfig.ResponseModalities, "AUDIO") {
+			u := &UnifiedSpeechRequest{Voice: geminiVoice(ctx, src.GenerationConfig.SpeechConfig)}
+			for _, content := range src.Contents {
+				for _, part := range content.Parts {
+					u.Input += part.Text
+				}
+			}
+			return u, nil
+		}
+		u := &UnifiedTranscriptionRequest{Temperature: src.GenerationConfig.Temperature}
+		for i, content := range src.Contents {
+			for j, part := range content.Parts {
+				if !isAudioPart(part) {
+					continue
+				}
+				if u.Audio != nil {
+					if err := dropUnsupported(ctx, fmt.Sprintf("contents[%d].parts[%d]", i, j), "a transcription request holds one audio file"); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				audio, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+				if err != nil {
+					return nil, fmt.Errorf("contents[%d].parts[%d]: invalid audio data: %w", i, j, err)
+				}
+				u.Audio, u.MimeType = audio, part.InlineData.MimeType
+			}
+		}
+		if u.Audio == nil {
+			return nil, fmt.Errorf("contents: no audio part to transcribe")
+		}
+		return u, nil
+	case *gemini.GeminiChatResponse:
+		if len(src.Candidates) == 0 {
+			return nil, fmt.Errorf("candidates: empty response")
+		}
+		var text strings.Builder
+		for i, part := range src.Candidates[0].Content.Parts {
+			if isAudioPart(part) {
+				audio, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+				if err != nil {
+					return nil, fmt.Errorf("candidates[0].content.parts[%d]: invalid audio data: %w", i, err)
+				}
+				return &UnifiedSpeechResponse{Audio: audio, MimeType: part.InlineData.MimeType}, nil
+			}
+			if !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+		return &UnifiedTranscriptionResponse{Text: text.String()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// AudioFromUnified implements AudioTransformer. Transcription requests
+// become an instruction to transcribe followed by the audio; speech requests
+// ask for the AUDIO modality, with the instructions leading the text to say.
+func (t *GeminiTransformer) AudioFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	switch dst := dst.(type) {
+	case *gemini.GeminiChatRequest:
+		switch req := src.(type) {
+		case *UnifiedTranscriptionRequest:
+			instruction := "Generate a transcript of the speech."
+			if req.Language != "" {
+				instruction += " The speech is in the language of ISO-639-1 code " + req.Language + "."
+			}
+			if req.Prompt != "" {
+				instruction += " Context: " + req.Prompt
+			}
+			*dst = gemini.GeminiChatRequest{
+				Contents: []gemini.GeminiChatContent{{
+					Role: "user",
+					Parts: []gemini.GeminiPart{
+						{Text: instruction},
+						{InlineData: &gemini.GeminiInlineData{MimeType: req.MimeType, Data: base64.StdEncoding.EncodeToString(req.Audio)}},
+					},
+				}},
+				GenerationConfig: gemini.GeminiChatGenerationConfig{Temperature: req.Temperature},
+			}
+			return nil
+		case *UnifiedSpeechRequest:
+			if req.Format != "" && req.Format != "pcm" {
+				if err := dropUnsupported(ctx, "format", "Gemini generates 24kHz PCM audio"); err != nil {
+					return err
+				}
+			}
+			if req.Speed != nil {
+				if err := dropUnsupported(ctx, "speed", "Gemini speech has no speed, instruct the pace instead"); err != nil {
+					return err
+				}
+			}
+			text := req.Input
+			if req.Instructions != "" {
+				text = req.Instructions + ": " + req.Input
+			}
+			*dst = gemini.GeminiChatRequest{
+				Contents: []gemini.GeminiChatContent{{Role: "user", Parts: []gemini.GeminiPart{{Text: text}}}},
+				GenerationConfig: gemini.GeminiChatGenerationConfig{
+					ResponseModalities: []string{"AUDIO"},
+				},
+			}
+			if req.Voice != "" {
+				dst.GenerationConfig.SpeechConfig = geminiSpeechConfig(ctx, req.Voice)
+			}
+			return nil
+		default:
+			return fmt.Errorf("source must be a unified transcription or speech request, got %T", src)
+		}
+	case *gemini.GeminiChatResponse:
+		var part gemini.GeminiPart
+		switch resp := src.(type) {
+		case *UnifiedTranscriptionResponse:
+			part.Text = resp.Text
+		case *UnifiedSpeechResponse:
+			mimeType := resp.MimeType
+			if mimeType == "" {
+				mimeType = geminiSpeechMimeType
+			}
+			part.InlineData = &gemini.GeminiInlineData{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(resp.Audio)}
+		default:
+			return fmt.Errorf("source must be a unified transcription or speech response, got %T", src)
+		}
+		finishReason := "STOP"
+		*dst = gemini.GeminiChatResponse{
+			Candidates: []gemini.GeminiChatCandidate{{
+				Content:      gemini.GeminiChatContent{Role: "model", Parts: []gemini.GeminiPart{part}},
+				FinishReason: &finishReason,
+			}},
+		}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Gemini audio")
+	}
+}
@@ -0,0 +1,140 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+)
+
+var embeddingTaskGemini = map[string]string{
+	EmbeddingTaskQuery:          gemini.TaskTypeRetrievalQuery,
+	EmbeddingTaskDocument:       gemini.TaskTypeRetrievalDocument,
+	EmbeddingTaskSimilarity:     gemini.TaskTypeSemanticSimilarity,
+	EmbeddingTaskClassification: gemini.TaskTypeClassification,
+	EmbeddingTaskClustering:     gemini.TaskTypeClustering,
+}
+
+// EmbeddingsToUnified implements EmbeddingsTransformer for embedContent and
+// batchEmbedContents payloads. A batch converts to a single request, so its
+// requests should share the model, task type and dimensionality; the first
+// request decides.
+func (t *GeminiTransformer) EmbeddingsToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *gemini.EmbedContentRequest:
+		return embeddingRequestFromGemini(ctx, []gemini.EmbedContentRequest{*src}, "")
+	case *gemini.BatchEmbedContentsRequest:
+		return embeddingRequestFromGemini(ctx, src.Requests, "requests")
+	case *gemini.EmbedContentResponse:
+		return &UnifiedEmbeddingResponse{Embeddings: [][]float64{src.Embedding.Values}}, nil
+	case *gemini.BatchEmbedContentsResponse:
+		u := &UnifiedEmbeddingResponse{}
+		for _, embedding := range src.Embeddings {
+			u.Embeddings = append(u.Embeddings, embedding.Values)
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// embeddingRequestFromGemini converts the requests of a batch, or the single
+// request of embedContent when prefix is empty.
+func embeddingRequestFromGemini(ctx context.Context, requests []gemini.EmbedContentRequest, prefix string) (*UnifiedEmbeddingRequest, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("requests is required")
+	}
+	first := requests[0]
+	u := &UnifiedEmbeddingRequest{
+		Model:      strings.TrimPrefix(first.Model, "models/"),
+		Dimensions: first.OutputDimensionality,
+	}
+	for task, taskType := range embeddingTaskGemini {
+		if taskType == first.TaskType {
+			u.Task = task
+		}
+	}
+	for i, req := range requests {
+		path := ""
+		if prefix != "" {
+			path = fmt.Sprintf("%s[%d].", prefix, i)
+		}
+		var text strings.Builder
+		for j, part := range req.Content.Parts {
+			if part.Text == "" {
+				if err := dropUnsupported(ctx, fmt.Sprintf("%scontent.parts[%d]", path, j), "only text is embedded"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			text.WriteString(part.Text)
+		}
+		u.Inputs = append(u.Inputs, text.String())
+
+		if req.Title != "" {
+			if err := dropUnsupported(ctx, path+"title", "document titles are specific to Gemini"); err != nil {
+				return nil, err
+			}
+		}
+		if req.TaskType != first.TaskType || req.OutputDimensionality != first.OutputDimensionality {
+			if err := dropUnsupported(ctx, fmt.Sprintf("%s[%d]", prefix, i), "the task type and dimensionality of the first request apply to the batch"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return u, nil
+}
+
+// EmbeddingsFromUnified implements EmbeddingsTransformer. embedContent
+// payloads hold a single input.
+func (t *GeminiTransformer) EmbeddingsFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	req, resp, err := unifiedEmbeddings(typ, src)
+	if err != nil {
+		return err
+	}
+	switch dst := dst.(type) {
+	case *gemini.EmbedContentRequest, *gemini.BatchEmbedContentsRequest:
+		if req == nil {
+			return fmt.Errorf("target type not supported for embedding responses")
+		}
+		model := "models/" + TargetModel(ctx, ProviderGemini, req.Model)
+		var requests []gemini.EmbedContentRequest
+		for _, input := range req.Inputs {
+			requests = append(requests, gemini.EmbedContentRequest{
+				Model:                model,
+				Content:              gemini.GeminiChatContent{Parts: []gemini.GeminiPart{{Text: input}}},
+				TaskType:             embeddingTaskGemini[req.Task],
+				OutputDimensionality: req.Dimensions,
+			})
+		}
+		if batch, ok := dst.(*gemini.BatchEmbedContentsRequest); ok {
+			*batch = gemini.BatchEmbedContentsRequest{Requests: requests}
+			return nil
+		}
+		if len(requests) != 1 {
+			return fmt.Errorf("embedContent embeds one input, got %d, use batchEmbedContents", len(requests))
+		}
+		*dst.(*gemini.EmbedContentRequest) = requests[0]
+		return nil
+	case *gemini.EmbedContentResponse, *gemini.BatchEmbedContentsResponse:
+		if resp == nil {
+			return fmt.Errorf("target type not supported for embedding requests")
+		}
+		var embeddings []gemini.ContentEmbedding
+		for _, values := range resp.Embeddings {
+			embeddings = append(embeddings, gemini.ContentEmbedding{Values: values})
+		}
+		if batch, ok := dst.(*gemini.BatchEmbedContentsResponse); ok {
+			*batch = gemini.BatchEmbedContentsResponse{Embeddings: embeddings}
+			return nil
+		}
+		if len(embeddings) != 1 {
+			return fmt.Errorf("embedContent returns one embedding, got %d", len(embeddings))
+		}
+		*dst.(*gemini.EmbedContentResponse) = gemini.EmbedContentResponse{Embedding: embeddings[0]}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Gemini embeddings")
+	}
+}
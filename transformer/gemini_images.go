@@ -0,0 +1,124 @@
+package transformer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+)
+
+// imagenAspectRatios are the aspect ratios Imagen draws.
+var imagenAspectRatios = []string{"1:1", "3:4", "4:3", "9:16", "16:9"}
+
+// ImagesToUnified implements ImagesTransformer for Imagen predict payloads,
+// which carry no model: it is in the path. A request holds one prompt, the
+// first instance's.
+func (t *GeminiTransformer) ImagesToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *gemini.ImagenRequest:
+		if len(src.Instances) == 0 || src.Instances[0].Prompt == "" {
+			return nil, fmt.Errorf("instances[0].prompt is required")
+		}
+		if len(src.Instances) > 1 {
+			if err := dropUnsupported(ctx, "instances", "a request holds one prompt"); err != nil {
+				return nil, err
+			}
+		}
+		params := src.Parameters
+		u := &UnifiedImageRequest{
+			Prompt:         src.Instances[0].Prompt,
+			NegativePrompt: params.NegativePrompt,
+			N:              params.SampleCount,
+			AspectRatio:    params.AspectRatio,
+		}
+		if options := params.OutputOptions; options != nil && options.MimeType != "" {
+			u.Format = strings.TrimPrefix(options.MimeType, "image/")
+		}
+		if params.ImageSize != "" {
+			if err := dropUnsupported(ctx, "parameters.imageSize", "image sizes are specific to Imagen"); err != nil {
+				return nil, err
+			}
+		}
+		if params.Seed != nil {
+			if err := dropUnsupported(ctx, "parameters.seed", "image seeds are specific to Imagen"); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	case *gemini.ImagenResponse:
+		u := &UnifiedImageResponse{}
+		for i, prediction := range src.Predictions {
+			if prediction.BytesBase64Encoded == "" {
+				warn(ctx, WarningDroppedContent, fmt.Sprintf("predictions[%d]", i), "image filtered: %s", prediction.RAIFilteredReason)
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(prediction.BytesBase64Encoded)
+			if err != nil {
+				return nil, fmt.Errorf("predictions[%d]: invalid image data: %w", i, err)
+			}
+			u.Images = append(u.Images, UnifiedImage{Data: data, MimeType: prediction.MimeType, RevisedPrompt: prediction.Prompt})
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// ImagesFromUnified implements ImagesTransformer. Imagen on the Gemini API
+// draws png images, in the nearest of its aspect ratios, and answers with
+// their data: images only linked cannot be converted.
+func (t *GeminiTransformer) ImagesFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	req, resp, err := unifiedImages(typ, src)
+	if err != nil {
+		return err
+	}
+	switch dst := dst.(type) {
+	case *gemini.ImagenRequest:
+		if req == nil {
+			return fmt.Errorf("target type not supported for image responses")
+		}
+		*dst = gemini.ImagenRequest{
+			Instances:  []gemini.ImagenInstance{{Prompt: req.Prompt}},
+			Parameters: gemini.ImagenParameters{SampleCount: req.N},
+		}
+		if req.NegativePrompt != "" {
+			if err := dropUnsupported(ctx, "negative_prompt", "Imagen on the Gemini API has no negative prompt"); err != nil {
+				return err
+			}
+		}
+		if req.AspectRatio != "" {
+			if dst.Parameters.AspectRatio, err = nearestAspectRatio(ctx, "aspect_ratio", req.AspectRatio, imagenAspectRatios); err != nil {
+				return err
+			}
+		}
+		if req.Format != "" && req.Format != "png" {
+			if err := dropUnsupported(ctx, "format", "Imagen on the Gemini API draws png images"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *gemini.ImagenResponse:
+		if resp == nil {
+			return fmt.Errorf("target type not supported for image requests")
+		}
+		*dst = gemini.ImagenResponse{Predictions: []gemini.ImagenPrediction{}}
+		for i, image := range resp.Images {
+			if len(image.Data) == 0 {
+				if err := dropUnsupported(ctx, fmt.Sprintf("images[%d]", i), "Imagen responses hold image data, not URLs"); err != nil {
+					return err
+				}
+				continue
+			}
+			dst.Predictions = append(dst.Predictions, gemini.ImagenPrediction{
+				BytesBase64Encoded: base64.StdEncoding.EncodeToString(image.Data),
+				MimeType:           image.MimeType,
+				Prompt:             image.RevisedPrompt,
+			})
+		}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Gemini images")
+	}
+}
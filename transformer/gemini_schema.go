@@ -0,0 +1,468 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Gemini responseSchema is a subset of OpenAPI 3.0 schemas, where OpenAI
+// json_schema response formats are JSON schemas. geminiResponseSchema and
+// openAIResponseSchema convert between the two dialects:
+//
+//   - Types: JSON schema types are lowercase and may list several, with
+//     "null" for nullable values; Gemini types are uppercase, one per
+//     schema, with nullable. Several types become an anyOf.
+//   - Property order: OpenAI generates properties in the order of the
+//     schema, Gemini in propertyOrdering, alphabetically without it. Each
+//     gets the order of the other.
+//   - Enums: Gemini enums are strings, of a STRING schema of format enum.
+//     const strings become one-value enums. Other values of enums and
+//     consts become their JSON text, with a WarningStringifiedEnum.
+//   - References: $ref to $defs or definitions are inlined, recursive ones
+//     are dropped for an OBJECT. oneOf becomes anyOf, allOf is merged into
+//     its schema.
+//   - Other keywords: Gemini has no additionalProperties; false is Gemini's
+//     behavior and dropped silently, other values are dropped. examples
+//     become the example of Gemini and back. Annotations such as $schema
+//     are removed, unknown validation keywords dropped.
+//
+// Dropped keywords warn, or fail under Options.Strict.
+
+// geminiSchemaKeywords are the keywords of JSON schemas Gemini takes as
+// they are.
+var geminiSchemaKeywords = map[string]bool{
+	"title": true, "description": true, "default": true, "nullable": true, "required": true,
+	"minItems": true, "maxItems": true, "minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true, "minProperties": true, "maxProperties": true,
+	"propertyOrdering": true,
+}
+
+// geminiSchemaFormats are the formats of Gemini schemas.
+var geminiSchemaFormats = map[string]bool{
+	"enum": true, "date-time": true, "int32": true, "int64": true, "float": true, "double": true,
+}
+
+// schemaAnnotations are the keywords of JSON schemas that do not constrain
+// values, removed for Gemini.
+var schemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$defs": true, "definitions": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// geminiSchemaConverter converts a JSON schema to a Gemini schema.
+type geminiSchemaConverter struct {
+	ctx context.Context
+	// defs are the definitions $ref points to, by reference.
+	defs map[string]any
+	// expanding are the references being inlined, to stop recursion.
+	expanding map[string]bool
+}
+
+// geminiResponseSchema converts the JSON schema of an OpenAI response format
+// at path to a Gemini responseSchema.
+func geminiResponseSchema(ctx context.Context, path string, schema json.Marshaler) (any, error) {
+	data, err := schema.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid schema: %w", path, err)
+	}
+	root, err := decodeOrdered(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid schema: %w", path, err)
+	}
+	c := &geminiSchemaConverter{ctx: ctx, defs: map[string]any{}, expanding: map[string]bool{}}
+	if object, ok := root.(*orderedObject); ok {
+		for _, key := range []string{"$defs", "definitions"} {
+			if defs, ok := object.get(key).(*orderedObject); ok {
+				for _, name := range defs.keys {
+					c.defs["#/"+key+"/"+name] = defs.values[name]
+				}
+			}
+		}
+	}
+	return c.convert(path, root)
+}
+
+func (c *geminiSchemaConverter) convert(path string, node any) (map[string]any, error) {
+	object, ok := node.(*orderedObject)
+	if !ok {
+		// true or an unexpected value, any value
+		return map[string]any{}, nil
+	}
+	if ref, ok := object.get("$ref").(string); ok {
+		def, found := c.defs[ref]
+		switch {
+		case !found:
+			return map[string]any{"type": "OBJECT"}, dropUnsupported(c.ctx, path+".$ref", "Gemini schemas cannot refer to %s", ref)
+		case c.expanding[ref]:
+			return map[string]any{"type": "OBJECT"}, dropUnsupported(c.ctx, path+".$ref", "Gemini schemas cannot recurse into %s", ref)
+		}
+		c.expanding[ref] = true
+		defer delete(c.expanding, ref)
+		return c.convert(path, def)
+	}
+
+	out := map[string]any{}
+	for _, key := range object.keys {
+		value, at := object.values[key], path+"."+key
+		switch {
+		case geminiSchemaKeywords[key]:
+			out[key] = value
+		case schemaAnnotations[key]:
+		case key == "type":
+			if err := c.convertType(at, value, out); err != nil {
+				return nil, err
+			}
+		case key == "format":
+			if format, _ := value.(string); geminiSchemaFormats[format] {
+				out[key] = format
+			} else if err := dropUnsupported(c.ctx, at, "Gemini schemas have no format %v", value); err != nil {
+				return nil, err
+			}
+		case key == "enum" || key == "const":
+			values, _ := value.([]any)
+			if key == "const" {
+				values = []any{value}
+			}
+			if err := c.convertEnum(at, values, out); err != nil {
+				return nil, err
+			}
+		case key == "properties":
+			properties, _ := value.(*orderedObject)
+			if properties == nil {
+				continue
+			}
+			converted := map[string]any{}
+			for _, name := range properties.keys {
+				property, err := c.convert(at+"."+name, properties.values[name])
+				if err != nil {
+					return nil, err
+				}
+				converted[name] = property
+			}
+			out[key] = converted
+			if _, ok := object.values["propertyOrdering"]; !ok && len(properties.keys) > 1 {
+				out["propertyOrdering"] = slices.Clone(properties.keys)
+			}
+		case key == "items":
+			items, err := c.convert(at, value)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = items
+		case key == "anyOf" || key == "oneOf":
+			if key == "oneOf" {
+				if err := dropUnsupported(c.ctx, at, "Gemini has no oneOf, anyOf stands in"); err != nil {
+					return nil, err
+				}
+			}
+			schemas, _ := value.([]any)
+			var anyOf []any
+			for i, schema := range schemas {
+				converted, err := c.convert(fmt.Sprintf("%s[%d]", at, i), schema)
+				if err != nil {
+					return nil, err
+				}
+				anyOf = append(anyOf, converted)
+			}
+			out["anyOf"] = anyOf
+		case key == "allOf":
+			schemas, _ := value.([]any)
+			for i, schema := range schemas {
+				converted, err := c.convert(fmt.Sprintf("%s[%d]", at, i), schema)
+				if err != nil {
+					return nil, err
+				}
+				mergeGeminiSchema(out, converted)
+			}
+		case key == "additionalProperties":
+			if value == false {
+				continue
+			}
+			if err := dropUnsupported(c.ctx, at, "Gemini schemas have no additionalProperties"); err != nil {
+				return nil, err
+			}
+		case key == "examples":
+			if examples, _ := value.([]any); len(examples) > 0 {
+				out["example"] = examples[0]
+			}
+		default:
+			if err := dropUnsupported(c.ctx, at, "Gemini schemas have no %s", key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// convertType sets the type of a Gemini schema from a JSON schema type: a
+// name or a list of names, "null" making it nullable.
+func (c *geminiSchemaConverter) convertType(path string, value any, out map[string]any) error {
+	var types []string
+	switch value := value.(type) {
+	case string:
+		types = []string{value}
+	case []any:
+		for _, t := range value {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+	}
+	if i := slices.Index(types, "null"); i >= 0 {
+		types = slices.Delete(types, i, i+1)
+		out["nullable"] = true
+	}
+	switch len(types) {
+	case 0:
+		return nil
+	case 1:
+		out["type"] = strings.ToUpper(types[0])
+	default:
+		var anyOf []any
+		for _, t := range types {
+			anyOf = append(anyOf, map[string]any{"type": strings.ToUpper(t)})
+		}
+		out["anyOf"] = anyOf
+	}
+	return nil
+}
+
+// convertEnum sets the values of a Gemini enum, strings of format enum,
+// null making it nullable. Other values become their JSON text.
+func (c *geminiSchemaConverter) convertEnum(path string, values []any, out map[string]any) error {
+	var enum []any
+	for _, value := range values {
+		switch value.(type) {
+		case string:
+			enum = append(enum, value)
+		case nil:
+			out["nullable"] = true
+		default:
+			text, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			warn(c.ctx, WarningStringifiedEnum, path, "Gemini enums are strings, %s becomes %q", text, text)
+			enum = append(enum, string(text))
+		}
+	}
+	if len(enum) > 0 {
+		out["type"], out["format"], out["enum"] = "STRING", "enum", enum
+	}
+	return nil
+}
+
+// mergeGeminiSchema merges src, a schema of an allOf, into dst: their
+// properties and required properties are joined, other keywords of src
+// win.
+func mergeGeminiSchema(dst, src map[string]any) {
+	for key, value := range src {
+		switch key {
+		case "properties":
+			properties, _ := dst[key].(map[string]any)
+			if properties == nil {
+				properties = map[string]any{}
+			}
+			for name, property := range value.(map[string]any) {
+				properties[name] = property
+			}
+			dst[key] = properties
+		case "required", "propertyOrdering":
+			joined, _ := dst[key].([]any)
+			if names, ok := value.([]string); ok {
+				for _, name := range names {
+					joined = append(joined, name)
+				}
+			} else {
+				joined = append(joined, value.([]any)...)
+			}
+			dst[key] = joined
+		default:
+			dst[key] = value
+		}
+	}
+}
+
+// openAIResponseSchema converts a Gemini responseSchema to the JSON schema
+// of an OpenAI response format: types become lowercase, nullable a "null"
+// type, propertyOrdering the order of the properties and example the
+// examples. Gemini schemas are JSON schemas otherwise.
+func openAIResponseSchema(schema any) (json.RawMessage, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	root, err := decodeOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(openAISchema(root))
+}
+
+func openAISchema(node any) any {
+	object, ok := node.(*orderedObject)
+	if !ok {
+		return node
+	}
+	nullable := object.get("nullable") == true
+	out := &orderedObject{values: map[string]any{}}
+	for _, key := range object.keys {
+		value := object.values[key]
+		switch key {
+		case "nullable", "propertyOrdering":
+		case "type":
+			t, _ := value.(string)
+			if nullable {
+				out.set(key, []any{strings.ToLower(t), "null"})
+			} else {
+				out.set(key, strings.ToLower(t))
+			}
+		case "format":
+			if value != "enum" {
+				out.set(key, value)
+			}
+		case "enum":
+			if values, ok := value.([]any); ok && nullable {
+				value = append(slices.Clone(values), nil)
+			}
+			out.set(key, value)
+		case "example":
+			out.set("examples", []any{value})
+		case "properties":
+			properties, _ := value.(*orderedObject)
+			if properties == nil {
+				continue
+			}
+			ordered := &orderedObject{values: map[string]any{}}
+			for _, name := range propertyOrder(object, properties) {
+				ordered.set(name, openAISchema(properties.values[name]))
+			}
+			out.set(key, ordered)
+		case "items":
+			out.set(key, openAISchema(value))
+		case "anyOf":
+			schemas, _ := value.([]any)
+			converted := make([]any, len(schemas))
+			for i, schema := range schemas {
+				converted[i] = openAISchema(schema)
+			}
+			out.set(key, converted)
+		default:
+			out.set(key, value)
+		}
+	}
+	return out
+}
+
+// propertyOrder returns the names of the properties of a Gemini schema in
+// the order Gemini generates them: propertyOrdering, then alphabetically.
+func propertyOrder(schema, properties *orderedObject) []string {
+	var names []string
+	ordering, _ := schema.get("propertyOrdering").([]any)
+	for _, name := range ordering {
+		if name, ok := name.(string); ok && properties.values[name] != nil && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	rest := slices.Sorted(func(yield func(string) bool) {
+		for _, name := range properties.keys {
+			if !slices.Contains(names, name) && !yield(name) {
+				return
+			}
+		}
+	})
+	return append(names, rest...)
+}
+
+// orderedObject is a JSON object keeping the order of its keys.
+type orderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *orderedObject) get(key string) any {
+	return o.values[key]
+}
+
+func (o *orderedObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrdered decodes JSON with its objects as *orderedObject and its
+// numbers as json.Number.
+func decodeOrdered(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("data after the value")
+	}
+	return value, nil
+}
+
+func decodeOrderedValue(dec *json.Decoder) (any, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := &orderedObject{values: map[string]any{}}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.(string), value)
+		}
+		_, err := dec.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for dec.More() {
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := dec.Token()
+		return array, err
+	default:
+		return token, nil
+	}
+}
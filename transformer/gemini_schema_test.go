@@ -0,0 +1,148 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestGeminiResponseSchemaRoundTrips(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		gemini string
+		// back is the schema converted back, when it differs from schema
+		back string
+	}{
+		{
+			name:   "types",
+			schema: `{"type":"object","properties":{"b":{"type":["string","null"]},"a":{"type":"integer"}},"required":["b"]}`,
+			gemini: `{"type":"OBJECT","properties":{"b":{"type":"STRING","nullable":true},"a":{"type":"INTEGER"}},"required":["b"],"propertyOrdering":["b","a"]}`,
+		},
+		{
+			name:   "string enum",
+			schema: `{"type":"string","enum":["x","y",null]}`,
+			gemini: `{"type":"STRING","format":"enum","enum":["x","y"],"nullable":true}`,
+			back:   `{"type":["string","null"],"enum":["x","y",null]}`,
+		},
+		{
+			name:   "const",
+			schema: `{"const":"x"}`,
+			gemini: `{"type":"STRING","format":"enum","enum":["x"]}`,
+			back:   `{"type":"string","enum":["x"]}`,
+		},
+		{
+			name:   "array",
+			schema: `{"type":"array","items":{"type":"number","format":"double"},"minItems":1}`,
+			gemini: `{"type":"ARRAY","items":{"type":"NUMBER","format":"double"},"minItems":1}`,
+		},
+		{
+			name:   "refs",
+			schema: `{"$defs":{"point":{"type":"object","properties":{"x":{"type":"number"}}}},"type":"object","properties":{"p":{"$ref":"#/$defs/point"}}}`,
+			gemini: `{"type":"OBJECT","properties":{"p":{"type":"OBJECT","properties":{"x":{"type":"NUMBER"}}}}}`,
+			back:   `{"type":"object","properties":{"p":{"type":"object","properties":{"x":{"type":"number"}}}}}`,
+		},
+		{
+			name:   "examples",
+			schema: `{"type":"string","examples":["a","b"]}`,
+			gemini: `{"type":"STRING","example":"a"}`,
+			back:   `{"type":"string","examples":["a"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithOptions(context.Background(), Options{Strict: true})
+			converted, err := geminiResponseSchema(ctx, "response_format", json.RawMessage(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := jsonValue(t, converted), jsonValue(t, json.RawMessage(tt.gemini)); got != want {
+				t.Errorf("gemini schema = %s, want %s", got, want)
+			}
+			back, err := openAIResponseSchema(converted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.back
+			if want == "" {
+				want = tt.schema
+			}
+			if jsonValue(t, back) != jsonValue(t, json.RawMessage(want)) {
+				t.Errorf("schema back = %s, want %s", back, want)
+			}
+		})
+	}
+}
+
+func TestGeminiSchemaPropertyOrder(t *testing.T) {
+	tests := []struct {
+		gemini string
+		want   []string
+	}{
+		{`{"type":"OBJECT","properties":{"a":{"type":"STRING"},"c":{"type":"STRING"},"b":{"type":"STRING"}},"propertyOrdering":["c","a"]}`, []string{"c", "a", "b"}},
+		{`{"type":"OBJECT","properties":{"c":{"type":"STRING"},"a":{"type":"STRING"}}}`, []string{"a", "c"}},
+	}
+	for _, tt := range tests {
+		back, err := openAIResponseSchema(json.RawMessage(tt.gemini))
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := decodeOrdered(back)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := root.(*orderedObject).get("properties").(*orderedObject).keys; !slices.Equal(got, tt.want) {
+			t.Errorf("%s: properties in order %v, want %v", tt.gemini, got, tt.want)
+		}
+	}
+
+	converted, err := geminiResponseSchema(context.Background(), "response_format", json.RawMessage(`{"type":"object","properties":{"b":{},"a":{}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := converted.(map[string]any)["propertyOrdering"]; !slices.Equal(got.([]string), []string{"b", "a"}) {
+		t.Errorf("propertyOrdering = %v, want [b a]", got)
+	}
+}
+
+func TestGeminiEnumsStringifyOtherValues(t *testing.T) {
+	ctx, warnings := WithWarnings(context.Background())
+	converted, err := geminiResponseSchema(ctx, "response_format", json.RawMessage(`{"enum":[1,"a",true]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := jsonValue(t, converted), `{"enum":["1","a","true"],"format":"enum","type":"STRING"}`; got != want {
+		t.Errorf("gemini schema = %s, want %s", got, want)
+	}
+	if !hasWarning(warnings, WarningStringifiedEnum) {
+		t.Errorf("warnings = %v, want %s", warnings.List(), WarningStringifiedEnum)
+	}
+}
+
+func TestGeminiSchemaDropsUnsupportedKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"additionalProperties", `{"type":"object","additionalProperties":{"type":"string"}}`},
+		{"format", `{"type":"string","format":"uuid"}`},
+		{"recursive ref", `{"$defs":{"node":{"type":"object","properties":{"next":{"$ref":"#/$defs/node"}}}},"$ref":"#/$defs/node"}`},
+		{"keyword", `{"type":"string","contentEncoding":"base64"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, warnings := WithWarnings(context.Background())
+			if _, err := geminiResponseSchema(ctx, "response_format", json.RawMessage(tt.schema)); err != nil {
+				t.Fatal(err)
+			}
+			if !hasWarning(warnings, WarningDroppedContent) {
+				t.Errorf("warnings = %v, want %s", warnings.List(), WarningDroppedContent)
+			}
+			strict := WithOptions(context.Background(), Options{Strict: true})
+			if _, err := geminiResponseSchema(strict, "response_format", json.RawMessage(tt.schema)); err == nil {
+				t.Error("strict conversion succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+)
+
+// splitGeminiServerTools separates the code execution parts of parts,
+// pairing each executableCode with the codeExecutionResult after it. Images
+// directly after a result, such as plots, belong to it.
+func splitGeminiServerTools(parts []gemini.GeminiPart) ([]gemini.GeminiPart, []UnifiedServerToolResult) {
+	var rest []gemini.GeminiPart
+	var results []UnifiedServerToolResult
+	pending, lastResult := -1, -1
+	for _, part := range parts {
+		switch {
+		case part.ExecutableCode != nil:
+			input, _ := json.Marshal(map[string]string{"code": part.ExecutableCode.Code})
+			pending = len(results)
+			results = append(results, UnifiedServerToolResult{Tool: ServerToolCodeExecution, Input: string(input)})
+			lastResult = -1
+			continue
+		case part.CodeExecutionResult != nil:
+			if pending < 0 {
+				pending = len(results)
+				results = append(results, UnifiedServerToolResult{Tool: ServerToolCodeExecution})
+			}
+			result := &results[pending]
+			if execution := part.CodeExecutionResult; execution.Outcome == "" || execution.Outcome == "OUTCOME_OK" {
+				result.Output = execution.Output
+			} else {
+				result.ReturnCode, result.Error = 1, execution.Output
+				if result.Error == "" {
+					result.Error = execution.Outcome
+				}
+			}
+			pending, lastResult = -1, pending
+			continue
+		case lastResult >= 0 && part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "image/"):
+			result := &results[lastResult]
+			result.Images = append(result.Images, fmt.Sprintf("data:%s;base64,%s", part.InlineData.MimeType, part.InlineData.Data))
+			continue
+		}
+		lastResult = -1
+		rest = append(rest, part)
+	}
+	return rest, results
+}
+
+// geminiServerToolParts returns the executableCode, codeExecutionResult and
+// image parts of the code executions of results. Gemini has no other
+// server tool results.
+func geminiServerToolParts(ctx context.Context, results []UnifiedServerToolResult) ([]gemini.GeminiPart, error) {
+	var parts []gemini.GeminiPart
+	for i, result := range results {
+		path := fmt.Sprintf("server_tool_results[%d]", i)
+		if result.Tool != ServerToolCodeExecution {
+			if err := dropUnsupported(ctx, path, "Gemini responses hold no %s results", result.Tool); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var input struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal([]byte(result.Input), &input)
+		execution := &gemini.GeminiPartCodeExecutionResult{Outcome: "OUTCOME_OK", Output: result.Output}
+		if result.ReturnCode != 0 {
+			execution.Outcome = "OUTCOME_FAILED"
+			execution.Output += result.Error
+		}
+		parts = append(parts,
+			gemini.GeminiPart{ExecutableCode: &gemini.GeminiPartExecutableCode{Language: "PYTHON", Code: input.Code}},
+			gemini.GeminiPart{CodeExecutionResult: execution},
+		)
+		for j, image := range result.Images {
+			inlineData := geminiInlineData(image)
+			if inlineData == nil {
+				if err := dropUnsupported(ctx, fmt.Sprintf("%s.images[%d]", path, j), "images must be data URLs for Gemini"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			parts = append(parts, gemini.GeminiPart{InlineData: inlineData})
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,236 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/usage"
+)
+
+// GeminiStreamState converts a Gemini stream into OpenAI chat completion
+// chunks as they arrive. Gemini streams function calls whole, one chunk
+// after another, so calls get indexes counting the calls of their
+// candidate across the stream, and tool_calls finishes the candidate only
+// with its finishReason. With includeUsage the final chunk is followed by
+// the usage chunk.
+//
+// One GeminiStreamState serves a single stream.
+type GeminiStreamState struct {
+	ctx          context.Context
+	includeUsage bool
+	// toolCalls counts the calls of each candidate so far.
+	toolCalls map[int]int
+}
+
+// NewGeminiStreamState returns the state of one stream. With includeUsage
+// the stream ends with a usage chunk, as for stream_options.include_usage.
+func NewGeminiStreamState(ctx context.Context, includeUsage bool) *GeminiStreamState {
+	return &GeminiStreamState{ctx: ctx, includeUsage: includeUsage, toolCalls: make(map[int]int)}
+}
+
+// Transform consumes one chunk and returns the chunks it produces.
+func (s *GeminiStreamState) Transform(chunk *gemini.GeminiChatResponse) ([]*openai.ChatCompletionStreamResponse, error) {
+	oaiChunk := &openai.ChatCompletionStreamResponse{}
+	if err := transformGeminiChunkToOpenAI(s.ctx, chunk, oaiChunk); err != nil {
+		return nil, err
+	}
+	// choices follow the candidates one for one
+	for i, candidate := range chunk.Candidates {
+		choice := &oaiChunk.Choices[i]
+		for j := range choice.Delta.ToolCalls {
+			index := s.toolCalls[choice.Index] + j
+			choice.Delta.ToolCalls[j].Index = &index
+		}
+		s.toolCalls[choice.Index] += len(choice.Delta.ToolCalls)
+		choice.FinishReason = ""
+		if candidate.FinishReason != nil {
+			choice.FinishReason = finishReasonGemini2OpenAI(*candidate.FinishReason)
+			if choice.FinishReason == openai.FinishReasonStop && s.toolCalls[choice.Index] > 0 {
+				choice.FinishReason = openai.FinishReasonToolCalls
+			}
+		}
+	}
+	chunks := []*openai.ChatCompletionStreamResponse{oaiChunk}
+	if isFinalGeminiChunk(chunk) && chunk.UsageMetadata.TotalTokenCount > 0 && s.includeUsage {
+		chunks = append(chunks, OpenAIUsageChunk(oaiChunk, usage.FromGemini(&chunk.UsageMetadata).OpenAI()))
+	}
+	return chunks, nil
+}
+
+// GeminiStreamTransformer converts an OpenAI chat completion stream into
+// Gemini stream chunks. Gemini streams function calls whole while OpenAI
+// streams their arguments in fragments, so calls are buffered until their
+// choice finishes, and the finishing chunk is held back until the usage
+// chunk OpenAI sends after it, or Finish.
+//
+// Gemini has no partial function calls: the calls a length finish cuts off
+// are dropped with a warning.
+//
+// One GeminiStreamTransformer serves a single stream.
+type GeminiStreamTransformer struct {
+	ctx context.Context
+	// calls buffers the tool calls of each choice by OpenAI index.
+	calls   map[int]map[int]*openai.ToolCall
+	order   map[int][]int
+	held    *gemini.GeminiChatResponse
+	stopped bool
+}
+
+// NewGeminiStreamTransformer returns a transformer for one stream. ctx
+// carries the options and warnings of the stream.
+func NewGeminiStreamTransformer(ctx context.Context) *GeminiStreamTransformer {
+	return &GeminiStreamTransformer{
+		ctx:   ctx,
+		calls: make(map[int]map[int]*openai.ToolCall),
+		order: make(map[int][]int),
+	}
+}
+
+// Transform converts one chunk into the Gemini chunks it completes, none
+// when the chunk carries nothing new.
+func (s *GeminiStreamTransformer) Transform(chunk *openai.ChatCompletionStreamResponse) ([]*gemini.GeminiChatResponse, error) {
+	if s.stopped {
+		return nil, fmt.Errorf("chunk after the stream finished")
+	}
+	var out []*gemini.GeminiChatResponse
+	if chunk.Usage != nil && s.held != nil {
+		s.held.UsageMetadata = usage.FromOpenAI(chunk.Usage).Gemini()
+		out = append(out, s.held)
+		s.held = nil
+	}
+
+	resp := &gemini.GeminiChatResponse{PromptFeedback: geminiPromptFeedback(chunk.PromptFilterResults)}
+	finished := false
+	for _, choice := range chunk.Choices {
+		var parts []gemini.GeminiPart
+		if choice.Delta.ReasoningContent != "" {
+			parts = append(parts, gemini.GeminiPart{Text: choice.Delta.ReasoningContent, Thought: true})
+		}
+		if choice.Delta.Content != "" {
+			parts = append(parts, gemini.GeminiPart{Text: choice.Delta.Content})
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			s.bufferCall(choice.Index, call)
+		}
+
+		candidate := gemini.GeminiChatCandidate{
+			Index:         int64(choice.Index),
+			SafetyRatings: geminiSafetyRatings(choice.ContentFilterResults),
+		}
+		if choice.FinishReason != "" {
+			calls, err := s.flushCalls(choice.Index, choice.FinishReason)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, calls...)
+			reason := finishReasonOpenAI2Gemini(choice.FinishReason)
+			candidate.FinishReason = &reason
+			finished = true
+		} else if len(parts) == 0 {
+			continue
+		}
+		candidate.Content = gemini.GeminiChatContent{Role: "model", Parts: parts}
+		resp.Candidates = append(resp.Candidates, candidate)
+	}
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback.BlockReason != "" || len(resp.PromptFeedback.SafetyRatings) > 0 {
+			// Azure annotates the prompt in a chunk without choices
+			out = append(out, resp)
+		}
+		return out, nil
+	}
+	if finished {
+		if s.held != nil {
+			out = append(out, s.held)
+		}
+		s.held = resp
+		return out, nil
+	}
+	return append(out, resp), nil
+}
+
+// Finish ends the stream, returning the chunk held back for usage.
+func (s *GeminiStreamTransformer) Finish() ([]*gemini.GeminiChatResponse, error) {
+	if s.stopped {
+		return nil, nil
+	}
+	s.stopped = true
+	var out []*gemini.GeminiChatResponse
+	for _, choice := range slices.Sorted(maps.Keys(s.order)) {
+		// the stream ended without finishing the choice
+		calls, err := s.flushCalls(choice, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(calls) > 0 {
+			out = append(out, &gemini.GeminiChatResponse{Candidates: []gemini.GeminiChatCandidate{{
+				Index:   int64(choice),
+				Content: gemini.GeminiChatContent{Role: "model", Parts: calls},
+			}}})
+		}
+	}
+	if s.held != nil {
+		out = append(out, s.held)
+		s.held = nil
+	}
+	return out, nil
+}
+
+func (s *GeminiStreamTransformer) bufferCall(choice int, call openai.ToolCall) {
+	index := 0
+	if call.Index != nil {
+		index = *call.Index
+	}
+	calls := s.calls[choice]
+	if calls == nil {
+		calls = make(map[int]*openai.ToolCall)
+		s.calls[choice] = calls
+	}
+	buffered, ok := calls[index]
+	if !ok {
+		buffered = &openai.ToolCall{ID: call.ID, Type: call.Type}
+		calls[index] = buffered
+		s.order[choice] = append(s.order[choice], index)
+	}
+	if call.Function.Name != "" {
+		buffered.Function.Name = call.Function.Name
+	}
+	buffered.Function.Arguments += call.Function.Arguments
+	if call.ExtraContent != nil {
+		buffered.ExtraContent = call.ExtraContent
+	}
+}
+
+// flushCalls returns the buffered tool calls of choice, finishing for
+// reason, as function call parts.
+func (s *GeminiStreamTransformer) flushCalls(choice int, reason openai.FinishReason) ([]gemini.GeminiPart, error) {
+	var parts []gemini.GeminiPart
+	for _, index := range s.order[choice] {
+		call := s.calls[choice][index]
+		if reason == openai.FinishReasonLength && partialArguments(call.Function.Arguments) {
+			if err := dropUnsupported(s.ctx, fmt.Sprintf("choices[%d].tool_calls[%d]", choice, index), "Gemini has no partial function calls"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var args any = map[string]any{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("choices[%d].tool_calls[%d]: invalid arguments: %w", choice, index, err)
+			}
+		}
+		part := gemini.GeminiPart{FunctionCall: &gemini.FunctionCall{FunctionName: call.Function.Name, Arguments: args}}
+		if call.ExtraContent != nil && call.ExtraContent.Google != nil {
+			part.ThoughtSignature = call.ExtraContent.Google.ThoughtSignature
+		}
+		parts = append(parts, part)
+	}
+	delete(s.calls, choice)
+	delete(s.order, choice)
+	return parts, nil
+}
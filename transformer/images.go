@@ -0,0 +1,299 @@
+package transformer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// UnifiedImageRequest is the provider-neutral image generation request.
+type UnifiedImageRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	// N is the number of images, 1 when zero.
+	N int `json:"n,omitempty"`
+	// AspectRatio is width:height in lowest terms, e.g. 16:9, empty for the
+	// model default.
+	AspectRatio string `json:"aspect_ratio,omitempty"`
+	// Format is png, jpeg or webp, empty for the provider default.
+	Format string `json:"format,omitempty"`
+}
+
+// UnifiedImageResponse holds the generated images, in order.
+type UnifiedImageResponse struct {
+	Images       []UnifiedImage `json:"images"`
+	InputTokens  int            `json:"input_tokens,omitempty"`
+	OutputTokens int            `json:"output_tokens,omitempty"`
+}
+
+// UnifiedImage is a generated image: its data, or its URL when the provider
+// only links it.
+type UnifiedImage struct {
+	Data     []byte `json:"data,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	// RevisedPrompt is the prompt the model rewrote and drew.
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// ImagesTransformer converts the image generation payloads of its provider
+// to and from the unified format.
+type ImagesTransformer interface {
+	GetProvider() Provider
+	// ImagesToUnified returns a *UnifiedImageRequest or *UnifiedImageResponse.
+	ImagesToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error)
+	ImagesFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error
+}
+
+// RegisterImages adds a provider's image generation converters.
+func (r *TransformationRegistry) RegisterImages(transformer ImagesTransformer) {
+	r.images[transformer.GetProvider()] = transformer
+}
+
+// TransformImages converts an image generation request or response of
+// source into one of target, through the unified format. Dst must be a
+// pointer to the target type, e.g. one created by NewImagesPayload.
+func (r *TransformationRegistry) TransformImages(ctx context.Context, sourceProvider, targetProvider Provider, typ TransformerType, src interface{}, dst interface{}) error {
+	source, sourceOK := r.images[sourceProvider]
+	target, targetOK := r.images[targetProvider]
+	if !sourceOK || !targetOK {
+		return &TransformationError{
+			Type:    "transformer_not_found",
+			Message: "images transformer not found for " + string(sourceProvider) + " -> " + string(targetProvider),
+		}
+	}
+	if r.configStore != nil && config.FromContext(ctx) == nil {
+		ctx = config.NewContext(ctx, r.configStore.Current())
+	}
+	u, err := source.ImagesToUnified(ctx, typ, src)
+	if err != nil {
+		return err
+	}
+	return target.ImagesFromUnified(ctx, typ, u, dst)
+}
+
+// NewImagesPayload returns a pointer to a zero image generation request or
+// response of provider, Imagen's for Gemini.
+func NewImagesPayload(provider Provider, typ TransformerType) (interface{}, error) {
+	if typ != TransformerTypeRequest && typ != TransformerTypeResponse {
+		return nil, fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+	request := typ == TransformerTypeRequest
+	switch provider {
+	case ProviderOpenAI:
+		if request {
+			return &openai.ImageRequest{}, nil
+		}
+		return &openai.ImageResponse{}, nil
+	case ProviderGemini:
+		if request {
+			return &gemini.ImagenRequest{}, nil
+		}
+		return &gemini.ImagenResponse{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// unifiedImages asserts the unified payload ImagesFromUnified is given.
+func unifiedImages(typ TransformerType, src interface{}) (*UnifiedImageRequest, *UnifiedImageResponse, error) {
+	switch typ {
+	case TransformerTypeRequest:
+		if req, ok := src.(*UnifiedImageRequest); ok {
+			return req, nil, nil
+		}
+		return nil, nil, fmt.Errorf("source must be a *UnifiedImageRequest, got %T", src)
+	case TransformerTypeResponse:
+		if resp, ok := src.(*UnifiedImageResponse); ok {
+			return nil, resp, nil
+		}
+		return nil, nil, fmt.Errorf("source must be a *UnifiedImageResponse, got %T", src)
+	default:
+		return nil, nil, fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+}
+
+// aspectRatio returns width:height in lowest terms.
+func aspectRatio(width, height int) string {
+	a, b := width, height
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return fmt.Sprintf("%d:%d", width/a, height/a)
+}
+
+// parseAspectRatio returns the width to height ratio of an aspect ratio.
+func parseAspectRatio(ratio string) (float64, bool) {
+	w, h, ok := strings.Cut(ratio, ":")
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if !ok || err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return 0, false
+	}
+	return float64(width) / float64(height), true
+}
+
+// nearestAspectRatio returns the ratio among supported closest to ratio,
+// warning when it differs. An invalid ratio is dropped.
+func nearestAspectRatio(ctx context.Context, path, ratio string, supported []string) (string, error) {
+	want, ok := parseAspectRatio(ratio)
+	if !ok {
+		return "", dropUnsupported(ctx, path, "invalid aspect ratio %q", ratio)
+	}
+	best, distance := "", 0.0
+	for _, candidate := range supported {
+		value, _ := parseAspectRatio(candidate)
+		if d := max(value/want, want/value); best == "" || d < distance {
+			best, distance = candidate, d
+		}
+	}
+	if best != ratio {
+		warn(ctx, WarningDroppedContent, path, "no %s aspect ratio, %s used", ratio, best)
+	}
+	return best, nil
+}
+
+// isDallE reports whether model is a dall-e model, whose images default to
+// URLs, rather than a gpt-image one.
+func isDallE(model string) bool {
+	return strings.HasPrefix(model, "dall-e")
+}
+
+// ImagesToUnified implements ImagesTransformer for /v1/images/generations.
+// Sizes convert to aspect ratios; quality, style and background are
+// specific to OpenAI.
+func (t *OpenAITransformer) ImagesToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	switch src := src.(type) {
+	case *openai.ImageRequest:
+		if src.Prompt == "" {
+			return nil, fmt.Errorf("prompt is required")
+		}
+		u := &UnifiedImageRequest{Model: src.Model, Prompt: src.Prompt, N: src.N, Format: src.OutputFormat}
+		if src.Size != "" && src.Size != "auto" {
+			w, h, _ := strings.Cut(src.Size, "x")
+			width, err1 := strconv.Atoi(w)
+			height, err2 := strconv.Atoi(h)
+			if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+				return nil, fmt.Errorf("invalid size %q, expected {width}x{height}", src.Size)
+			}
+			u.AspectRatio = aspectRatio(width, height)
+		}
+		for _, field := range [][2]string{{"quality", src.Quality}, {"style", src.Style}, {"background", src.Background}} {
+			if field, value := field[0], field[1]; value != "" && value != "auto" {
+				if err := dropUnsupported(ctx, field, "%s is specific to OpenAI", field); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if src.OutputCompression != nil {
+			if err := dropUnsupported(ctx, "output_compression", "output_compression is specific to OpenAI"); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	case *openai.ImageResponse:
+		u := &UnifiedImageResponse{}
+		if src.Usage != nil {
+			u.InputTokens, u.OutputTokens = src.Usage.InputTokens, src.Usage.OutputTokens
+		}
+		for i, data := range src.Data {
+			image := UnifiedImage{URL: data.URL, RevisedPrompt: data.RevisedPrompt}
+			if data.B64JSON != "" {
+				decoded, err := base64.StdEncoding.DecodeString(data.B64JSON)
+				if err != nil {
+					return nil, fmt.Errorf("data[%d]: invalid b64_json: %w", i, err)
+				}
+				image.Data, image.MimeType = decoded, http.DetectContentType(decoded)
+			}
+			u.Images = append(u.Images, image)
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+// ImagesFromUnified implements ImagesTransformer. Aspect ratios become the
+// nearest size of the model: dall-e-2 only draws squares.
+func (t *OpenAITransformer) ImagesFromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	req, resp, err := unifiedImages(typ, src)
+	if err != nil {
+		return err
+	}
+	switch dst := dst.(type) {
+	case *openai.ImageRequest:
+		if req == nil {
+			return fmt.Errorf("target type not supported for image responses")
+		}
+		model := TargetModel(ctx, ProviderOpenAI, req.Model)
+		*dst = openai.ImageRequest{Prompt: req.Prompt, Model: model, N: req.N}
+		if req.NegativePrompt != "" {
+			if err := dropUnsupported(ctx, "negative_prompt", "OpenAI has no negative prompt"); err != nil {
+				return err
+			}
+		}
+		if req.AspectRatio != "" {
+			sizes := []string{"1024x1024", "1536x1024", "1024x1536"}
+			switch {
+			case model == "dall-e-2":
+				sizes = []string{"1024x1024"}
+			case isDallE(model):
+				sizes = []string{"1024x1024", "1792x1024", "1024x1792"}
+			}
+			var ratios []string
+			for _, size := range sizes {
+				w, h, _ := strings.Cut(size, "x")
+				width, _ := strconv.Atoi(w)
+				height, _ := strconv.Atoi(h)
+				ratios = append(ratios, aspectRatio(width, height))
+			}
+			ratio, err := nearestAspectRatio(ctx, "aspect_ratio", req.AspectRatio, ratios)
+			if err != nil {
+				return err
+			}
+			if ratio != "" {
+				dst.Size = sizes[slices.Index(ratios, ratio)]
+			}
+		}
+		if isDallE(model) {
+			// dall-e links images unless asked for their data
+			dst.ResponseFormat = "b64_json"
+			if req.Format != "" && req.Format != "png" {
+				if err := dropUnsupported(ctx, "format", "dall-e models draw png images"); err != nil {
+					return err
+				}
+			}
+		} else {
+			dst.OutputFormat = req.Format
+		}
+		return nil
+	case *openai.ImageResponse:
+		if resp == nil {
+			return fmt.Errorf("target type not supported for image requests")
+		}
+		*dst = openai.ImageResponse{Created: time.Now().Unix(), Data: []openai.ImageData{}}
+		if resp.InputTokens > 0 || resp.OutputTokens > 0 {
+			dst.Usage = &openai.ImageUsage{InputTokens: resp.InputTokens, OutputTokens: resp.OutputTokens, TotalTokens: resp.InputTokens + resp.OutputTokens}
+		}
+		for _, image := range resp.Images {
+			data := openai.ImageData{URL: image.URL, RevisedPrompt: image.RevisedPrompt}
+			if len(image.Data) > 0 {
+				data.B64JSON, data.URL = base64.StdEncoding.EncodeToString(image.Data), ""
+			}
+			dst.Data = append(dst.Data, data)
+		}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for OpenAI images")
+	}
+}
@@ -3,6 +3,9 @@ package transformer
 import (
 	"context"
 	"strings"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/metrics"
 )
 
 // Provider represents supported LLM providers
@@ -12,6 +15,14 @@ const (
 	ProviderOpenAI Provider = "openai"
 	ProviderGemini Provider = "gemini"
 	ProviderClaude Provider = "claude"
+	// ProviderBedrock is the Amazon Bedrock Converse API.
+	ProviderBedrock Provider = "bedrock"
+	// ProviderVertexGemini is the Gemini API of Google Vertex AI.
+	ProviderVertexGemini Provider = "vertex_gemini"
+	// ProviderOllama is the /api/chat API of Ollama.
+	ProviderOllama Provider = "ollama"
+	// ProviderCohere is the /v2/embed API of Cohere, for embeddings only.
+	ProviderCohere Provider = "cohere"
 )
 
 type TransformerType string
@@ -32,6 +43,19 @@ type Transformer interface {
 
 	// ValidateRequest validates the provider-specific request
 	ValidateRequest(ctx context.Context, request interface{}) error
+
+	// ValidateResponse checks the structural invariants of the provider-specific response
+	ValidateResponse(ctx context.Context, response interface{}) error
+}
+
+// UnifiedTransformer converts the payloads of its provider to and from the
+// unified format, the hub through which the registry connects providers
+// without a direct transformer.
+type UnifiedTransformer interface {
+	Transformer
+	// ToUnified returns a *UnifiedRequest, *UnifiedResponse or *UnifiedChunk.
+	ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error)
+	FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error
 }
 
 // TransformationPair represents a source->target transformation
@@ -42,13 +66,42 @@ type TransformationPair struct {
 
 // TransformationRegistry manages all available transformers for direct one-to-one transformations
 type TransformationRegistry struct {
-	transformers map[string]Transformer // key format: "sourceProvider->targetProvider"
+	transformers     map[string]Transformer // key format: "sourceProvider->targetProvider"
+	unified          map[Provider]UnifiedTransformer
+	embeddings       map[Provider]EmbeddingsTransformer
+	audio            map[Provider]AudioTransformer
+	images           map[Provider]ImagesTransformer
+	configStore      *config.Store
+	metrics          metrics.Recorder
+	hooks            []TransformHook
+	batchConcurrency int
+	modelMappers     map[string]*ModelMapper // key format: "sourceProvider->targetProvider"
+	voiceMap         *VoiceMap
+}
+
+// TransformEvent describes a completed transformation.
+type TransformEvent struct {
+	Source   Provider
+	Target   Provider
+	Type     TransformerType
+	Src      interface{}
+	Dst      interface{}
+	Warnings []Warning
+	Err      error
 }
 
+// TransformHook observes completed transformations, e.g. for audit logging.
+// Hooks run synchronously and must not modify the payloads.
+type TransformHook func(ctx context.Context, event *TransformEvent)
+
 // NewTransformationRegistry creates a new transformation registry
 func NewTransformationRegistry() *TransformationRegistry {
 	return &TransformationRegistry{
 		transformers: make(map[string]Transformer),
+		unified:      make(map[Provider]UnifiedTransformer),
+		embeddings:   make(map[Provider]EmbeddingsTransformer),
+		audio:        make(map[Provider]AudioTransformer),
+		images:       make(map[Provider]ImagesTransformer),
 	}
 }
 
@@ -58,11 +111,58 @@ func (r *TransformationRegistry) Register(sourceProvider, targetProvider Provide
 	r.transformers[key] = transformer
 }
 
-// GetTransformer returns the transformer for a specific source->target pair
+// RegisterUnified adds a provider's unified converters. Pairs of providers
+// both registered this way, without a direct transformer, transform through
+// the unified format.
+func (r *TransformationRegistry) RegisterUnified(transformer UnifiedTransformer) {
+	r.unified[transformer.GetProvider()] = transformer
+}
+
+// UseConfig makes Transform attach the store's current configuration to
+// contexts that don't carry one. The snapshot is taken once per call, so a
+// reload never changes the configuration seen by an in-flight transformation.
+func (r *TransformationRegistry) UseConfig(store *config.Store) {
+	r.configStore = store
+}
+
+// UseMetrics makes Transform report every transformation and its warnings to m.
+func (r *TransformationRegistry) UseMetrics(m metrics.Recorder) {
+	r.metrics = m
+}
+
+// OnTransform registers a hook run after every transformation.
+func (r *TransformationRegistry) OnTransform(hook TransformHook) {
+	r.hooks = append(r.hooks, hook)
+}
+
+// GetTransformer returns the transformer for a specific source->target pair,
+// the direct one or else one through the unified format
 func (r *TransformationRegistry) GetTransformer(sourceProvider, targetProvider Provider) (Transformer, bool) {
 	key := string(sourceProvider) + "->" + string(targetProvider)
-	transformer, exists := r.transformers[key]
-	return transformer, exists
+	if transformer, exists := r.transformers[key]; exists {
+		return transformer, true
+	}
+	source, sourceOK := r.unified[sourceProvider]
+	target, targetOK := r.unified[targetProvider]
+	if !sourceOK || !targetOK || sourceProvider == targetProvider {
+		return nil, false
+	}
+	return &hubTransformer{UnifiedTransformer: source, target: target}, true
+}
+
+// hubTransformer transforms the payloads of its source through the unified
+// format into those of target.
+type hubTransformer struct {
+	UnifiedTransformer
+	target UnifiedTransformer
+}
+
+func (t *hubTransformer) Do(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	u, err := t.ToUnified(ctx, typ, src)
+	if err != nil {
+		return err
+	}
+	return t.target.FromUnified(ctx, typ, u, dst)
 }
 
 // Transform performs direct transformation from source to target format
@@ -75,13 +175,46 @@ func (r *TransformationRegistry) Transform(ctx context.Context, sourceProvider,
 		}
 	}
 
-	return transformer.Do(ctx, typ, src, dst)
+	if r.configStore != nil && config.FromContext(ctx) == nil {
+		ctx = config.NewContext(ctx, r.configStore.Current())
+	}
+	if m, ok := r.modelMappers[string(sourceProvider)+"->"+string(targetProvider)]; ok && ctx.Value(modelMapperKey{}) == nil {
+		ctx = WithModelMapper(ctx, targetProvider, m)
+	}
+	if r.voiceMap != nil && ctx.Value(voiceMapKey{}) == nil {
+		ctx = WithVoiceMap(ctx, r.voiceMap)
+	}
+	if r.metrics == nil && len(r.hooks) == 0 {
+		return transformer.Do(ctx, typ, src, dst)
+	}
+
+	ctx, warnings := WithWarnings(ctx)
+	err := transformer.Do(ctx, typ, src, dst)
+	event := &TransformEvent{
+		Source:   sourceProvider,
+		Target:   targetProvider,
+		Type:     typ,
+		Src:      src,
+		Dst:      dst,
+		Warnings: warnings.List(),
+		Err:      err,
+	}
+	if r.metrics != nil {
+		r.metrics.ObserveTransformation(string(sourceProvider), string(targetProvider), string(typ), err)
+		for _, w := range event.Warnings {
+			r.metrics.ObserveWarning(w.Code)
+		}
+	}
+	for _, hook := range r.hooks {
+		hook(ctx, event)
+	}
+	return err
 }
 
 // GetAvailableTransformations returns all available transformation pairs
 func (r *TransformationRegistry) GetAvailableTransformations() []TransformationPair {
 	var pairs []TransformationPair
-	
+
 	for key := range r.transformers {
 		parts := strings.Split(key, "->")
 		if len(parts) == 2 {
@@ -91,14 +224,21 @@ func (r *TransformationRegistry) GetAvailableTransformations() []TransformationP
 			})
 		}
 	}
-	
+	for source := range r.unified {
+		for target := range r.unified {
+			if _, direct := r.transformers[string(source)+"->"+string(target)]; !direct && source != target {
+				pairs = append(pairs, TransformationPair{Source: source, Target: target})
+			}
+		}
+	}
+
 	return pairs
 }
 
 // GetSupportedProviders returns all unique providers that have transformers
 func (r *TransformationRegistry) GetSupportedProviders() []Provider {
 	providerMap := make(map[Provider]bool)
-	
+
 	for key := range r.transformers {
 		parts := strings.Split(key, "->")
 		if len(parts) == 2 {
@@ -106,12 +246,17 @@ func (r *TransformationRegistry) GetSupportedProviders() []Provider {
 			providerMap[Provider(parts[1])] = true
 		}
 	}
-	
+	if len(r.unified) > 1 {
+		for provider := range r.unified {
+			providerMap[provider] = true
+		}
+	}
+
 	var providers []Provider
 	for provider := range providerMap {
 		providers = append(providers, provider)
 	}
-	
+
 	return providers
 }
 
@@ -125,4 +270,4 @@ type TransformationError struct {
 // Error implements the error interface
 func (e *TransformationError) Error() string {
 	return e.Message
-}
\ No newline at end of file
+}
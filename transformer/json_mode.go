@@ -0,0 +1,48 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/phosae/llms/dto/openai"
+)
+
+// claudeJSONInstruction is appended to the system prompt when emulating JSON mode
+const claudeJSONInstruction = "Respond only with a single valid JSON object. Do not write any text, explanation or markdown code fence before or after it."
+
+// jsonPrefill is the assistant prefill used when emulating JSON mode
+const jsonPrefill = "{"
+
+// emulatesJSONMode reports whether a request asking for format must be given
+// the JSON-only instruction
+func emulatesJSONMode(ctx context.Context, format *openai.ChatCompletionResponseFormat) bool {
+	return OptionsFromContext(ctx).EmulateJSONMode && format != nil &&
+		format.Type == openai.ChatCompletionResponseFormatTypeJSONObject
+}
+
+// finishEmulatedJSON restores the prefill, strips code fences and validates
+// the answer of a model asked to emulate JSON mode
+func finishEmulatedJSON(ctx context.Context, path, content string) (string, error) {
+	opts := OptionsFromContext(ctx)
+	content = strings.TrimSpace(content)
+	if rest, ok := strings.CutPrefix(content, "```"); ok {
+		rest = strings.TrimPrefix(rest, "json")
+		rest, _ = strings.CutSuffix(strings.TrimSpace(rest), "```")
+		content = strings.TrimSpace(rest)
+	}
+	if opts.PrefillJSON && !strings.HasPrefix(content, jsonPrefill) {
+		content = jsonPrefill + content
+	}
+
+	if !json.Valid([]byte(content)) {
+		if opts.Strict {
+			return "", &TransformationError{
+				Type:    "invalid_json",
+				Message: path + ": model output is not valid JSON",
+			}
+		}
+		warn(ctx, WarningInvalidJSON, path, "model output is not valid JSON")
+	}
+	return content, nil
+}
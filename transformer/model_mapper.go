@@ -0,0 +1,117 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ModelRule rewrites the models it matches to To. A rule matches by exactly
+// one of Exact, Prefix or Regex; To of a Regex rule may refer to its
+// submatches, e.g. $1.
+type ModelRule struct {
+	Exact  string `json:"exact,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+	To     string `json:"to"`
+}
+
+// ModelMapper rewrites the model names of requests, e.g. gpt-4o to
+// claude-3-7-sonnet-latest. Rules are tried in order, models matching none
+// become the fallback, or are left to the config mappings without one.
+// Models that are the To of an exact or prefix rule or the fallback map to
+// themselves, so a request mapped twice on its way through the hub keeps
+// the first mapping.
+type ModelMapper struct {
+	rules    []ModelRule
+	patterns []*regexp.Regexp
+	fallback string
+}
+
+// NewModelMapper returns a mapper of rules, falling back to fallback when
+// it is not empty.
+func NewModelMapper(rules []ModelRule, fallback string) (*ModelMapper, error) {
+	m := &ModelMapper{rules: rules, patterns: make([]*regexp.Regexp, len(rules)), fallback: fallback}
+	for i, rule := range rules {
+		set := 0
+		for _, match := range []string{rule.Exact, rule.Prefix, rule.Regex} {
+			if match != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("rules[%d]: exactly one of exact, prefix and regex is required", i)
+		}
+		if rule.To == "" {
+			return nil, fmt.Errorf("rules[%d]: to is required", i)
+		}
+		if rule.Regex != "" {
+			pattern, err := regexp.Compile("^(?:" + rule.Regex + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("rules[%d]: %w", i, err)
+			}
+			m.patterns[i] = pattern
+		}
+	}
+	return m, nil
+}
+
+// Map returns the model to send for model, false when no rule matches and
+// there is no fallback.
+func (m *ModelMapper) Map(model string) (string, bool) {
+	if model != "" && model == m.fallback {
+		return model, true
+	}
+	for i, rule := range m.rules {
+		switch {
+		case rule.Exact != "" && rule.Exact == model,
+			rule.Prefix != "" && strings.HasPrefix(model, rule.Prefix):
+			return rule.To, true
+		case m.patterns[i] != nil && m.patterns[i].MatchString(model):
+			return m.patterns[i].ReplaceAllString(model, rule.To), true
+		}
+	}
+	for i, rule := range m.rules {
+		if m.patterns[i] == nil && rule.To == model {
+			return model, true
+		}
+	}
+	if m.fallback != "" {
+		return m.fallback, true
+	}
+	return model, false
+}
+
+// modelMapping is the mapper of the requests of one target provider.
+type modelMapping struct {
+	target Provider
+	mapper *ModelMapper
+}
+
+type modelMapperKey struct{}
+
+// WithModelMapper returns a context mapping the models of requests
+// transformed for target with m, before the config mappings and after
+// Options.Model.
+func WithModelMapper(ctx context.Context, target Provider, m *ModelMapper) context.Context {
+	return context.WithValue(ctx, modelMapperKey{}, modelMapping{target: target, mapper: m})
+}
+
+// mapModel maps model with the mapper of target carried by ctx.
+func mapModel(ctx context.Context, target Provider, model string) (string, bool) {
+	mapping, ok := ctx.Value(modelMapperKey{}).(modelMapping)
+	if !ok || mapping.target != target {
+		return model, false
+	}
+	return mapping.mapper.Map(model)
+}
+
+// UseModelMapper makes Transform map the models of requests from source to
+// target with m, unless the context carries a mapper already.
+func (r *TransformationRegistry) UseModelMapper(source, target Provider, m *ModelMapper) {
+	if r.modelMappers == nil {
+		r.modelMappers = make(map[string]*ModelMapper)
+	}
+	r.modelMappers[string(source)+"->"+string(target)] = m
+}
@@ -0,0 +1,286 @@
+package transformer
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+// NormalizePolicy selects what transformations between payloads of the same
+// provider do.
+type NormalizePolicy int
+
+const (
+	// NormalizeNone copies payloads as they are.
+	NormalizeNone NormalizePolicy = iota
+	// NormalizeCanonical upgrades legacy fields, maps the model and fills
+	// in the defaults of model presets and of the API, keeping every field.
+	NormalizeCanonical
+	// NormalizeStrip additionally strips the fields the unified format has
+	// no home for, which other providers would not receive either.
+	NormalizeStrip
+)
+
+// Normalize canonicalizes a request or response of provider in place, as
+// NormalizeCanonical does, or as NormalizeStrip when Options.Normalize
+// selects it. Stripped fields are reported as dropped content.
+func Normalize(ctx context.Context, provider Provider, typ TransformerType, payload interface{}) error {
+	var homes map[Provider][]string
+	switch typ {
+	case TransformerTypeRequest:
+		homes = unifiedRequestHomes
+		if err := normalizeRequest(ctx, payload); err != nil {
+			return err
+		}
+	case TransformerTypeResponse:
+		homes = unifiedResponseHomes
+		normalizeResponse(payload)
+	default:
+		return fmt.Errorf("normalize supports requests and responses, not %s", typ)
+	}
+	if OptionsFromContext(ctx).Normalize != NormalizeStrip {
+		return nil
+	}
+	return stripExtensions(ctx, provider, homes[provider], payload)
+}
+
+// sameProvider sets dst to src, a payload of the same provider, normalized
+// when Options.Normalize asks for it.
+func sameProvider[T any](ctx context.Context, provider Provider, typ TransformerType, src, dst *T) error {
+	if OptionsFromContext(ctx).Normalize == NormalizeNone {
+		*dst = *src
+		return nil
+	}
+	// a deep copy, normalizing must not touch src
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	var zero T
+	*dst = zero
+	if err := json.Unmarshal(data, dst); err != nil {
+		return err
+	}
+	return Normalize(ctx, provider, typ, dst)
+}
+
+func normalizeRequest(ctx context.Context, payload interface{}) error {
+	switch req := payload.(type) {
+	case *openai.ChatCompletionRequest:
+		normalizeOpenAIRequest(ctx, req)
+	case *claude.ClaudeRequest:
+		if req.Prompt != "" && len(req.Messages) == 0 {
+			upgraded, err := UpgradeClaudeCompletion(req)
+			if err != nil {
+				return err
+			}
+			*req = *upgraded
+		}
+		req.Model = TargetModel(ctx, ProviderClaude, req.Model)
+		preset := presetFor(ctx, req.Model)
+		if req.MaxTokens == 0 {
+			req.MaxTokens = uint(cmp.Or(preset.maxTokens(), defaultClaudeMaxTokens))
+		}
+		req.Temperature = cmp.Or(req.Temperature, preset.temperature())
+		req.TopP = cmp.Or(req.TopP, preset.topP())
+	case *gemini.GeminiChatRequest:
+		normalizeGeminiRequest(req)
+	case *vertex.GenerateContentRequest:
+		normalizeGeminiRequest(&req.GeminiChatRequest)
+	case *bedrock.ConverseRequest:
+		req.ModelID = TargetModel(ctx, ProviderBedrock, req.ModelID)
+		preset := presetFor(ctx, req.ModelID)
+		if req.InferenceConfig == nil {
+			req.InferenceConfig = &bedrock.InferenceConfig{}
+		}
+		inference := req.InferenceConfig
+		inference.MaxTokens = cmp.Or(inference.MaxTokens, preset.maxTokens())
+		inference.Temperature = cmp.Or(inference.Temperature, preset.temperature())
+		inference.TopP = cmp.Or(inference.TopP, preset.topP())
+		if inference.MaxTokens == 0 && inference.Temperature == nil && inference.TopP == nil && len(inference.StopSequences) == 0 {
+			req.InferenceConfig = nil
+		}
+	case *ollama.ChatRequest:
+		req.Model = TargetModel(ctx, ProviderOllama, req.Model)
+		preset := presetFor(ctx, req.Model)
+		if req.Stream == nil {
+			stream := true
+			req.Stream = &stream
+		}
+		defaults := map[string]any{}
+		if maxTokens := preset.maxTokens(); maxTokens > 0 {
+			defaults["num_predict"] = maxTokens
+		}
+		if temperature := preset.temperature(); temperature != nil {
+			defaults["temperature"] = *temperature
+		}
+		if topP := preset.topP(); topP != nil {
+			defaults["top_p"] = *topP
+		}
+		for key, value := range defaults {
+			if _, ok := req.Options[key]; !ok {
+				if req.Options == nil {
+					req.Options = make(map[string]any)
+				}
+				req.Options[key] = value
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported request type %T", payload)
+	}
+	return nil
+}
+
+// normalizeOpenAIRequest upgrades function calls to tool calls and moves the
+// token limit to the field the backend takes.
+func normalizeOpenAIRequest(ctx context.Context, req *openai.ChatCompletionRequest) {
+	req.Model = TargetModel(ctx, ProviderOpenAI, req.Model)
+	preset := presetFor(ctx, req.Model)
+	quirks := config.FromContext(ctx).Quirks(req.Model)
+
+	maxTokens := cmp.Or(req.MaxCompletionTokens, req.MaxTokens, preset.maxTokens())
+	req.MaxTokens, req.MaxCompletionTokens = 0, 0
+	if quirks.UseMaxCompletionTokens {
+		req.MaxCompletionTokens = maxTokens
+	} else {
+		req.MaxTokens = maxTokens
+	}
+	req.Temperature = cmp.Or(req.Temperature, preset.temperature())
+	req.TopP = cmp.Or(req.TopP, preset.topP())
+	if !req.Stream {
+		req.StreamOptions = nil
+	}
+	for i := range req.Tools {
+		if req.Tools[i].Type == "" && req.Tools[i].Function != nil {
+			req.Tools[i].Type = openai.ToolTypeFunction
+		}
+	}
+
+	// function results answer the earliest unanswered call of their function
+	var pending []openai.ToolCall
+	for i := range req.Messages {
+		message := &req.Messages[i]
+		switch {
+		case message.FunctionCall != nil:
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:       fmt.Sprintf("call_%d", i),
+				Type:     openai.ToolTypeFunction,
+				Function: *message.FunctionCall,
+			})
+			message.FunctionCall = nil
+		case message.Role == openai.ChatMessageRoleFunction:
+			message.Role = openai.ChatMessageRoleTool
+			message.ToolCallID = "call_" + message.Name
+			if j := slices.IndexFunc(pending, func(call openai.ToolCall) bool { return call.Function.Name == message.Name }); j >= 0 {
+				message.ToolCallID = pending[j].ID
+				pending = slices.Delete(pending, j, j+1)
+			}
+			message.Name = ""
+		}
+		pending = append(pending, message.ToolCalls...)
+	}
+	applyOpenAIQuirks(req, quirks)
+}
+
+// normalizeGeminiRequest names the role of contents, which single-turn
+// requests may leave out. The model is in the URL, so no preset applies.
+func normalizeGeminiRequest(req *gemini.GeminiChatRequest) {
+	for i := range req.Contents {
+		if req.Contents[i].Role == "" {
+			req.Contents[i].Role = "user"
+		}
+	}
+}
+
+func normalizeResponse(payload interface{}) {
+	switch resp := payload.(type) {
+	case *openai.ChatCompletionResponse:
+		resp.Object = cmp.Or(resp.Object, "chat.completion")
+		for i := range resp.Choices {
+			choice := &resp.Choices[i]
+			if call := choice.Message.FunctionCall; call != nil {
+				choice.Message.ToolCalls = append(choice.Message.ToolCalls, openai.ToolCall{
+					ID:       fmt.Sprintf("call_%d", i),
+					Type:     openai.ToolTypeFunction,
+					Function: *call,
+				})
+				choice.Message.FunctionCall = nil
+			}
+			if choice.FinishReason == openai.FinishReasonFunctionCall {
+				choice.FinishReason = openai.FinishReasonToolCalls
+			}
+		}
+		if resp.Usage.TotalTokens == 0 {
+			resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+		}
+	case *claude.ClaudeResponse:
+		resp.Type = cmp.Or(resp.Type, "message")
+		resp.Role = cmp.Or(resp.Role, "assistant")
+	case *gemini.GeminiChatResponse:
+		normalizeGeminiResponse(resp)
+	case *vertex.GenerateContentResponse:
+		normalizeGeminiResponse(&resp.GeminiChatResponse)
+	case *ollama.ChatResponse:
+		resp.Message.Role = cmp.Or(resp.Message.Role, "assistant")
+	}
+}
+
+func normalizeGeminiResponse(resp *gemini.GeminiChatResponse) {
+	for i := range resp.Candidates {
+		candidate := &resp.Candidates[i]
+		candidate.Content.Role = cmp.Or(candidate.Content.Role, "model")
+	}
+	usage := &resp.UsageMetadata
+	if usage.TotalTokenCount == 0 {
+		usage.TotalTokenCount = usage.PromptTokenCount + usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	}
+}
+
+// stripExtensions removes the fields of payload outside homes.
+func stripExtensions(ctx context.Context, provider Provider, homes []string, payload interface{}) error {
+	ext, err := collectExtensions(provider, payload, homes)
+	if err != nil || len(ext) == 0 {
+		return err
+	}
+	object, err := jsonObject(payload)
+	if err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(ext)) {
+		path := strings.TrimPrefix(key, string(provider)+".")
+		warn(ctx, WarningDroppedContent, path, "stripped by the normalize policy")
+		field, sub, nested := strings.Cut(path, ".")
+		if !nested {
+			delete(object, field)
+			continue
+		}
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(object[field], &inner); err != nil {
+			return err
+		}
+		delete(inner, sub)
+		if object[field], err = json.Marshal(inner); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(payload).Elem().SetZero()
+	return json.Unmarshal(data, payload)
+}
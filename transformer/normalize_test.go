@@ -0,0 +1,74 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+func TestNormalizeStripKeepsMappedFields(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		payload  any
+		json     string
+		// stripped is the one field without a unified home
+		stripped string
+	}{
+		{ProviderOpenAI, &openai.ChatCompletionRequest{}, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],
+			"max_tokens":100,"temperature":0.5,"top_p":0.9,"stop":["x"],"stream":true,"seed":1,"n":2,"user":"u",
+			"reasoning_effort":"low","response_format":{"type":"json_object"},
+			"tools":[{"type":"function","function":{"name":"f"}}],"tool_choice":"auto","parallel_tool_calls":false,
+			"web_search_options":{"search_context_size":"low"},"prompt_cache_key":"k","prompt_cache_retention":"24h",
+			"presence_penalty":0.1,"frequency_penalty":0.2,"metadata":{"a":"b"},"logprobs":true,"top_logprobs":2,
+			"service_tier":"flex"}`, "service_tier"},
+		{ProviderClaude, &claude.ClaudeRequest{}, `{"model":"claude-sonnet-4-5","max_tokens":4096,
+			"system":"be brief","messages":[{"role":"user","content":"hi"}],"temperature":1,"top_p":0.9,"top_k":40,
+			"stop_sequences":["x"],"stream":true,"tools":[{"name":"f","input_schema":{"type":"object"}}],
+			"tool_choice":{"type":"auto"},"thinking":{"type":"enabled","budget_tokens":2048},
+			"metadata":{"user_id":"u"},"output_format":{"type":"json_schema","schema":{"type":"object"}},
+			"container":"c"}`, "container"},
+		{ProviderGemini, &gemini.GeminiChatRequest{}, `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+			"systemInstruction":{"parts":[{"text":"be brief"}]},"cachedContent":"cachedContents/1",
+			"generationConfig":{"temperature":0.5,"topP":0.9,"topK":40,"maxOutputTokens":100,"stopSequences":["x"],
+			"seed":1,"candidateCount":2,"presencePenalty":0.1,"frequencyPenalty":0.2,
+			"thinkingConfig":{"thinkingBudget":1024},"responseLogprobs":true}}`, "generationConfig.responseLogprobs"},
+		{ProviderVertexGemini, &vertex.GenerateContentRequest{}, `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+			"labels":{"team":"a"},"generationConfig":{"topK":40,"presencePenalty":0.1,"frequencyPenalty":0.2,
+			"thinkingConfig":{"thinkingBudget":1024}},"safetySettings":[{"category":"HARM_CATEGORY_HATE_SPEECH",
+			"threshold":"BLOCK_NONE"}]}`, "safetySettings"},
+		{ProviderOllama, &ollama.ChatRequest{}, `{"model":"llama3","messages":[{"role":"user","content":"hi"}],
+			"stream":false,"think":true,"options":{"temperature":0.5,"top_p":0.9,"top_k":40,"num_predict":100,
+			"stop":["x"],"seed":1,"presence_penalty":0.1,"frequency_penalty":0.2,"mirostat":1}}`, "options.mirostat"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.json), tt.payload); err != nil {
+				t.Fatal(err)
+			}
+			want, err := collectExtensions(tt.provider, tt.payload, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := WithOptions(context.Background(), Options{Normalize: NormalizeStrip})
+			if err := Normalize(ctx, tt.provider, TransformerTypeRequest, tt.payload); err != nil {
+				t.Fatal(err)
+			}
+			got, err := collectExtensions(tt.provider, tt.payload, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key := range want {
+				_, kept := got[key]
+				if stripped := key == string(tt.provider)+"."+tt.stripped; kept == stripped {
+					t.Errorf("%s kept = %v, want %v", key, kept, !stripped)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,732 @@
+package transformer
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/usage"
+)
+
+// OllamaTransformer handles the /api/chat API of Ollama. It converts to
+// OpenAI directly and to the other providers through the unified format.
+// Ollama tool calls have no IDs: tool results name their tool instead, and
+// calls get IDs when converted.
+type OllamaTransformer struct{}
+
+// NewOllamaTransformer creates a new Ollama transformer
+func NewOllamaTransformer() *OllamaTransformer {
+	return &OllamaTransformer{}
+}
+
+// GetProvider returns the source provider (Ollama)
+func (t *OllamaTransformer) GetProvider() Provider {
+	return ProviderOllama
+}
+
+// ValidateRequest checks the chat request: the model, roles, tool results
+// answering the tool calls before them, sampling options and image formats.
+func (t *OllamaTransformer) ValidateRequest(ctx context.Context, request interface{}) error {
+	req, ok := request.(*ollama.ChatRequest)
+	if !ok {
+		return fmt.Errorf("invalid request type for Ollama transformer")
+	}
+
+	v := &requestValidator{}
+	if req.Model == "" {
+		v.add("/model", "model cannot be empty")
+	}
+	for _, option := range []struct {
+		name     string
+		min, max float64
+	}{{"temperature", 0, 2}, {"top_p", 0, 1}, {"min_p", 0, 1}} {
+		if value, ok := req.Options[option.name].(float64); ok {
+			v.between("/options/"+option.name, value, option.min, option.max)
+		}
+	}
+
+	// calls are the tools called by the last assistant message
+	var calls []string
+	for i, message := range req.Messages {
+		path := fmt.Sprintf("/messages/%d", i)
+		switch message.Role {
+		case "tool":
+			if message.ToolName != "" && !slices.Contains(calls, message.ToolName) {
+				v.add(path+"/tool_name", "tool message %q does not answer a tool call of the preceding assistant message", message.ToolName)
+			}
+		case "assistant":
+			calls = calls[:0]
+			for _, call := range message.ToolCalls {
+				calls = append(calls, call.Function.Name)
+			}
+		case "system", "user":
+			calls = calls[:0]
+		default:
+			v.add(path+"/role", "invalid role %q", message.Role)
+		}
+		for j, image := range message.Images {
+			if !slices.ContainsFunc(ollamaImageMediaTypes, func(t struct{ prefix, mediaType string }) bool { return strings.HasPrefix(image, t.prefix) }) {
+				v.add(fmt.Sprintf("%s/images/%d", path, j), "unsupported image format, expected PNG, JPEG, GIF or WebP")
+			}
+		}
+	}
+	return v.err(ProviderOllama)
+}
+
+// ValidateResponse checks the message and done reason of the chat response
+func (t *OllamaTransformer) ValidateResponse(ctx context.Context, response interface{}) error {
+	resp, ok := response.(*ollama.ChatResponse)
+	if !ok {
+		return fmt.Errorf("invalid response type for Ollama transformer")
+	}
+
+	var errs []error
+	if resp.Message.Role != "assistant" {
+		errs = append(errs, fmt.Errorf("message: invalid role %q", resp.Message.Role))
+	}
+	if !resp.Done {
+		errs = append(errs, fmt.Errorf("done must be true for a whole response"))
+	}
+	return errors.Join(errs...)
+}
+
+// Do performs the transformation based on the type
+func (t *OllamaTransformer) Do(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	switch typ {
+	case TransformerTypeRequest:
+		return t.transformRequest(ctx, src, dst)
+	case TransformerTypeResponse:
+		return t.transformResponse(ctx, src, dst)
+	case TransformerTypeChunk:
+		return t.transformChunk(ctx, src, dst)
+	default:
+		return fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+}
+
+// ToUnified converts an Ollama request, response or chunk to the unified format.
+func (t *OllamaTransformer) ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	return toUnified(ctx, ProviderOllama, typ, src)
+}
+
+// FromUnified converts a unified request, response or chunk to the Ollama payload dst.
+func (t *OllamaTransformer) FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	return fromUnified(ctx, ProviderOllama, typ, src, dst)
+}
+
+func (t *OllamaTransformer) transformRequest(ctx context.Context, src interface{}, dst interface{}) error {
+	req, ok := src.(*ollama.ChatRequest)
+	if !ok {
+		return fmt.Errorf("invalid source type for Ollama transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		if err := transformOllamaRequestToOpenAI(ctx, req, target); err != nil {
+			return err
+		}
+		stripOpenAIReasoning(ctx, target)
+		return nil
+	case *ollama.ChatRequest:
+		return sameProvider(ctx, ProviderOllama, TransformerTypeRequest, req, target)
+	case *claude.ClaudeRequest:
+		return transformViaUnified(ctx, ProviderOllama, ProviderClaude, TransformerTypeRequest, req, target)
+	case *gemini.GeminiChatRequest:
+		return transformViaUnified(ctx, ProviderOllama, ProviderGemini, TransformerTypeRequest, req, target)
+	case *bedrock.ConverseRequest:
+		return transformViaUnified(ctx, ProviderOllama, ProviderBedrock, TransformerTypeRequest, req, target)
+	case *vertex.GenerateContentRequest:
+		return transformViaUnified(ctx, ProviderOllama, ProviderVertexGemini, TransformerTypeRequest, req, target)
+	default:
+		return fmt.Errorf("target type not supported for Ollama transformer")
+	}
+}
+
+func (t *OllamaTransformer) transformResponse(ctx context.Context, src interface{}, dst interface{}) error {
+	resp, ok := src.(*ollama.ChatResponse)
+	if !ok {
+		return fmt.Errorf("invalid source type for Ollama transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionResponse:
+		return transformOllamaResponseToOpenAI(ctx, resp, target)
+	case *ollama.ChatResponse:
+		return sameProvider(ctx, ProviderOllama, TransformerTypeResponse, resp, target)
+	case *claude.ClaudeResponse:
+		return transformViaUnified(ctx, ProviderOllama, ProviderClaude, TransformerTypeResponse, resp, target)
+	case *gemini.GeminiChatResponse:
+		return transformViaUnified(ctx, ProviderOllama, ProviderGemini, TransformerTypeResponse, resp, target)
+	case *bedrock.ConverseResponse:
+		return transformViaUnified(ctx, ProviderOllama, ProviderBedrock, TransformerTypeResponse, resp, target)
+	case *vertex.GenerateContentResponse:
+		return transformViaUnified(ctx, ProviderOllama, ProviderVertexGemini, TransformerTypeResponse, resp, target)
+	default:
+		return fmt.Errorf("target type not supported for Ollama transformer")
+	}
+}
+
+// transformChunk converts one streamed object; use OllamaStreamState to
+// convert a whole stream.
+func (t *OllamaTransformer) transformChunk(ctx context.Context, src interface{}, dst interface{}) error {
+	chunk, ok := src.(*ollama.ChatResponse)
+	if !ok {
+		return fmt.Errorf("invalid source type for Ollama transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		return transformOllamaChunkToOpenAI(ctx, chunk, target)
+	case *[]*openai.ChatCompletionStreamResponse:
+		chunks, err := NewOllamaStreamState(ctx, OptionsFromContext(ctx).IncludeUsage).Transform(chunk)
+		if err != nil {
+			return err
+		}
+		*target = append(*target, chunks...)
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Ollama transformer")
+	}
+}
+
+// ollamaOptions are the model options with an OpenAI counterpart.
+var ollamaOptions = []string{"temperature", "top_p", "num_predict", "stop", "seed", "presence_penalty", "frequency_penalty"}
+
+func transformOllamaRequestToOpenAI(ctx context.Context, req *ollama.ChatRequest, oaiReq *openai.ChatCompletionRequest) error {
+	cfg := config.FromContext(ctx)
+	oaiReq.Model = TargetModel(ctx, ProviderOpenAI, req.Model)
+	preset := presetFor(ctx, oaiReq.Model)
+	quirks := cfg.Quirks(oaiReq.Model)
+	oaiReq.Stream = req.Stream == nil || *req.Stream
+
+	options := req.Options
+	for _, key := range slices.Sorted(maps.Keys(options)) {
+		if !slices.Contains(ollamaOptions, key) {
+			if err := dropUnsupported(ctx, "options."+key, "OpenAI has no equivalent"); err != nil {
+				return err
+			}
+		}
+	}
+	numPredict, _ := ollamaNumber(options["num_predict"])
+	// a negative num_predict generates until the context is full
+	maxTokens := cmp.Or(max(int(numPredict), 0), preset.maxTokens())
+	if quirks.UseMaxCompletionTokens {
+		oaiReq.MaxCompletionTokens = maxTokens
+	} else {
+		oaiReq.MaxTokens = maxTokens
+	}
+	oaiReq.Temperature = preset.temperature()
+	if temperature, ok := ollamaNumber(options["temperature"]); ok {
+		oaiReq.Temperature = &temperature
+	}
+	oaiReq.TopP = preset.topP()
+	if topP, ok := ollamaNumber(options["top_p"]); ok {
+		oaiReq.TopP = &topP
+	}
+	if seed, ok := ollamaNumber(options["seed"]); ok {
+		s := int(seed)
+		oaiReq.Seed = &s
+	}
+	if penalty, ok := ollamaNumber(options["presence_penalty"]); ok {
+		oaiReq.PresencePenalty = &penalty
+	}
+	if penalty, ok := ollamaNumber(options["frequency_penalty"]); ok {
+		oaiReq.FrequencyPenalty = &penalty
+	}
+	if stop, ok := options["stop"]; ok {
+		sequences, err := common.Any2Type[[]string](stop)
+		if err != nil {
+			return fmt.Errorf("invalid options.stop: %w", err)
+		}
+		oaiReq.Stop = sequences
+	}
+
+	if len(req.Format) > 0 {
+		format, err := openAIResponseFormat(req.Format)
+		if err != nil {
+			return err
+		}
+		oaiReq.ResponseFormat = format
+	}
+	if len(req.Think) > 0 {
+		var think any
+		if err := json.Unmarshal(req.Think, &think); err != nil {
+			return fmt.Errorf("invalid think: %w", err)
+		}
+		switch think := think.(type) {
+		case bool:
+			if think {
+				oaiReq.ReasoningEffort = "medium"
+			}
+		case string:
+			oaiReq.ReasoningEffort = think
+		}
+	}
+	if len(req.KeepAlive) > 0 {
+		if err := dropUnsupported(ctx, "keep_alive", "OpenAI models are not loaded per request"); err != nil {
+			return err
+		}
+	}
+
+	for _, tool := range req.Tools {
+		oaiReq.Tools = append(oaiReq.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+
+	if prefix := preset.systemPrefix(); prefix != "" {
+		oaiReq.Messages = append(oaiReq.Messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: prefix})
+	}
+	// tool results name their tool, they answer its earliest unanswered call
+	var pending []openai.ToolCall
+	for i, m := range req.Messages {
+		switch m.Role {
+		case "tool":
+			id := "call_" + m.ToolName
+			if j := slices.IndexFunc(pending, func(call openai.ToolCall) bool {
+				return m.ToolName == "" || call.Function.Name == m.ToolName
+			}); j >= 0 {
+				id = pending[j].ID
+				pending = slices.Delete(pending, j, j+1)
+			}
+			oaiReq.Messages = append(oaiReq.Messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    m.Content,
+				ToolCallID: id,
+			})
+			continue
+		}
+
+		message := openai.ChatCompletionMessage{Role: m.Role, ReasoningContent: m.Thinking}
+		if len(m.Images) == 0 {
+			message.Content = m.Content
+		} else {
+			if m.Content != "" {
+				message.MultiContent = append(message.MultiContent, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: m.Content})
+			}
+			for _, image := range m.Images {
+				message.MultiContent = append(message.MultiContent, openai.ChatMessagePart{
+					Type:     openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{URL: ollamaImageURL(image)},
+				})
+			}
+		}
+		for j, call := range m.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:   fmt.Sprintf("call_%d_%d", i, j),
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: ollamaArguments(call.Function.Arguments),
+				},
+			})
+		}
+		pending = append(pending, message.ToolCalls...)
+		oaiReq.Messages = append(oaiReq.Messages, message)
+	}
+
+	applyOpenAIQuirks(oaiReq, quirks)
+	normalized, err := normalizeTools(ctx, ProviderOpenAI, oaiReq)
+	if err != nil {
+		return err
+	}
+	*oaiReq = *normalized
+	return nil
+}
+
+// openAIResponseFormat converts the format of an Ollama request: "json" or
+// a JSON schema.
+func openAIResponseFormat(format json.RawMessage) (*openai.ChatCompletionResponseFormat, error) {
+	var name string
+	if json.Unmarshal(format, &name) == nil {
+		if name != "json" {
+			return nil, fmt.Errorf("invalid format %q", name)
+		}
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}, nil
+	}
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "response",
+			Schema: format,
+		},
+	}, nil
+}
+
+// ollamaNumber returns a numeric option, decoded from JSON as float64.
+func ollamaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func ollamaArguments(arguments map[string]any) string {
+	if arguments == nil {
+		return "{}"
+	}
+	return toJSONString(arguments)
+}
+
+// ollamaImageMediaTypes tell image formats apart by the base64 of their
+// magic numbers.
+var ollamaImageMediaTypes = []struct{ prefix, mediaType string }{
+	{"iVBORw0KGgo", "image/png"},
+	{"/9j/", "image/jpeg"},
+	{"R0lGOD", "image/gif"},
+	{"UklGR", "image/webp"},
+}
+
+// ollamaImageURL returns the data URL of a base64 image, PNG when the
+// format is not recognized.
+func ollamaImageURL(data string) string {
+	mediaType := "image/png"
+	for _, t := range ollamaImageMediaTypes {
+		if strings.HasPrefix(data, t.prefix) {
+			mediaType = t.mediaType
+			break
+		}
+	}
+	return "data:" + mediaType + ";base64," + data
+}
+
+func transformRequestToOllama(ctx context.Context, oaiReq *openai.ChatCompletionRequest, req *ollama.ChatRequest) error {
+	oaiReq, err := cacheHints(ctx, ProviderOllama, oaiReq)
+	if err != nil {
+		return err
+	}
+	if oaiReq, err = normalizeTools(ctx, ProviderOllama, oaiReq); err != nil {
+		return err
+	}
+	req.Model = TargetModel(ctx, ProviderOllama, oaiReq.Model)
+	preset := presetFor(ctx, req.Model)
+	// Ollama streams unless told otherwise
+	stream := oaiReq.Stream
+	req.Stream = &stream
+
+	options := make(map[string]any)
+	if maxTokens := cmp.Or(oaiReq.MaxCompletionTokens, oaiReq.MaxTokens, preset.maxTokens()); maxTokens > 0 {
+		options["num_predict"] = maxTokens
+	}
+	if temperature := cmp.Or(oaiReq.Temperature, preset.temperature()); temperature != nil {
+		options["temperature"] = *temperature
+	}
+	if topP := cmp.Or(oaiReq.TopP, preset.topP()); topP != nil {
+		options["top_p"] = *topP
+	}
+	if len(oaiReq.Stop) > 0 {
+		options["stop"] = oaiReq.Stop
+	}
+	if oaiReq.Seed != nil {
+		options["seed"] = *oaiReq.Seed
+	}
+	if oaiReq.PresencePenalty != nil {
+		options["presence_penalty"] = *oaiReq.PresencePenalty
+	}
+	if oaiReq.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *oaiReq.FrequencyPenalty
+	}
+	if len(options) > 0 {
+		req.Options = options
+	}
+	if oaiReq.ReasoningEffort != "" {
+		// most thinking models take a switch, not a level
+		req.Think = json.RawMessage("true")
+	}
+
+	for _, field := range []struct {
+		name, message string
+		set           bool
+	}{
+		{"n", "Ollama returns a single message", oaiReq.N > 1},
+		{"modalities", "Ollama cannot generate audio", slices.Contains(oaiReq.Modalities, "audio") || oaiReq.Audio != nil},
+		{"web_search_options", "Ollama has no web search tool", oaiReq.WebSearchOptions != nil},
+		{"logprobs", "Ollama chat returns no log probabilities", oaiReq.LogProbs},
+		{"parallel_tool_calls", "Ollama cannot disable parallel tool calls", oaiReq.ParallelToolCalls == false},
+	} {
+		if !field.set {
+			continue
+		}
+		if err := dropUnsupported(ctx, field.name, "%s", field.message); err != nil {
+			return err
+		}
+	}
+
+	if format := oaiReq.ResponseFormat; format != nil {
+		switch {
+		case format.Type == openai.ChatCompletionResponseFormatTypeJSONSchema && format.JSONSchema != nil && format.JSONSchema.Schema != nil:
+			schema, err := json.Marshal(format.JSONSchema.Schema)
+			if err != nil {
+				return fmt.Errorf("invalid response_format.json_schema.schema: %w", err)
+			}
+			req.Format = schema
+		case format.Type == openai.ChatCompletionResponseFormatTypeJSONObject || format.Type == openai.ChatCompletionResponseFormatTypeJSONSchema:
+			req.Format = json.RawMessage(`"json"`)
+		}
+	}
+
+	toolChoice, toolName, allowed, err := openAIToolChoice(oaiReq.ToolChoice)
+	if err != nil {
+		return fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	if toolName != "" {
+		toolChoice = "named"
+	}
+	if err := dropStrictTools(ctx, ProviderOllama, oaiReq.Tools); err != nil {
+		return err
+	}
+	if err := dropBuiltinTools(ctx, ProviderOllama, oaiReq.Tools); err != nil {
+		return err
+	}
+	switch toolChoice {
+	case "none":
+		// without tools the model cannot call any
+	case "required", "named":
+		if err := dropUnsupported(ctx, "tool_choice", "Ollama cannot force a tool call"); err != nil {
+			return err
+		}
+		fallthrough
+	default:
+		for _, tool := range allowTools(oaiReq.Tools, allowed) {
+			if tool.Function == nil {
+				continue
+			}
+			req.Tools = append(req.Tools, ollama.Tool{
+				Type: "function",
+				Function: ollama.ToolFunction{
+					Name:        tool.Function.Name,
+					Description: tool.Function.Description,
+					Parameters:  tool.Function.Parameters,
+				},
+			})
+		}
+	}
+
+	if prefix := preset.systemPrefix(); prefix != "" {
+		req.Messages = append(req.Messages, ollama.Message{Role: "system", Content: prefix})
+	}
+	toolNames := make(map[string]string)
+	for i, message := range oaiReq.Messages {
+		switch message.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper, openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+			m, err := ollamaMessageFromOpenAI(ctx, i, message)
+			if err != nil {
+				return err
+			}
+			for _, call := range message.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+			}
+			req.Messages = append(req.Messages, m)
+		case openai.ChatMessageRoleTool:
+			req.Messages = append(req.Messages, ollama.Message{
+				Role:     "tool",
+				Content:  message.Content,
+				ToolName: toolNames[message.ToolCallID],
+			})
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d]", i), "role %q has no Ollama equivalent", message.Role); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ollamaMessageFromOpenAI converts a message of another role than tool.
+// Text parts are joined and images must be inline, Ollama takes no URLs.
+func ollamaMessageFromOpenAI(ctx context.Context, index int, message openai.ChatCompletionMessage) (ollama.Message, error) {
+	m := ollama.Message{Role: message.Role, Content: message.Content, Thinking: message.ReasoningContent}
+	if m.Role == openai.ChatMessageRoleDeveloper {
+		m.Role = "system"
+	}
+	var texts []string
+	for j, part := range message.MultiContent {
+		path := fmt.Sprintf("messages[%d].content[%d]", index, j)
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			texts = append(texts, part.Text)
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			_, data, ok := strings.Cut(part.ImageURL.URL, ";base64,")
+			if !strings.HasPrefix(part.ImageURL.URL, "data:") || !ok {
+				if err := dropUnsupported(ctx, path, "Ollama only accepts inline images"); err != nil {
+					return m, err
+				}
+				continue
+			}
+			m.Images = append(m.Images, data)
+		default:
+			if err := dropUnsupported(ctx, path, "%s content has no Ollama equivalent", part.Type); err != nil {
+				return m, err
+			}
+		}
+	}
+	if len(texts) > 0 {
+		m.Content = strings.Join(texts, "\n")
+	}
+	for _, call := range message.ToolCalls {
+		var arguments map[string]any
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil || arguments == nil {
+			arguments = map[string]any{}
+		}
+		m.ToolCalls = append(m.ToolCalls, ollama.ToolCall{Function: ollama.ToolCallFunction{
+			Name:      call.Function.Name,
+			Arguments: arguments,
+		}})
+	}
+	return m, nil
+}
+
+func transformOllamaResponseToOpenAI(ctx context.Context, resp *ollama.ChatResponse, oaiResp *openai.ChatCompletionResponse) error {
+	oaiResp.ID = "chatcmpl-" + generateUUID()
+	oaiResp.Object = "chat.completion"
+	oaiResp.Created = ollamaCreated(resp.CreatedAt)
+	oaiResp.Model = resp.Model
+
+	message := openai.ChatCompletionMessage{
+		Role:             openai.ChatMessageRoleAssistant,
+		Content:          resp.Message.Content,
+		ReasoningContent: resp.Message.Thinking,
+		ToolCalls:        openAIToolCallsFromOllama(ctx, resp.Message.ToolCalls, 0),
+	}
+	oaiResp.Choices = []openai.ChatCompletionChoice{{
+		Message:      message,
+		FinishReason: doneReasonOllama2OpenAI(resp.DoneReason, len(message.ToolCalls) > 0),
+	}}
+	oaiResp.Usage = *usage.FromOllama(&resp.Metrics).OpenAI()
+	return nil
+}
+
+// openAIToolCallsFromOllama gives the tool calls IDs and indexes counting
+// from first.
+func openAIToolCallsFromOllama(ctx context.Context, calls []ollama.ToolCall, first int) []openai.ToolCall {
+	var oaiCalls []openai.ToolCall
+	id := generateUUID()
+	for j, call := range calls {
+		index := first + j
+		oaiCalls = append(oaiCalls, openai.ToolCall{
+			Index: &index,
+			ID:    fmt.Sprintf("call_%s_%d", id, index),
+			Type:  openai.ToolTypeFunction,
+			Function: openai.FunctionCall{
+				Name:      originalToolName(ctx, call.Function.Name),
+				Arguments: ollamaArguments(call.Function.Arguments),
+			},
+		})
+	}
+	return oaiCalls
+}
+
+// ollamaCreated returns the Unix time of a created_at timestamp, now when
+// it is missing.
+func ollamaCreated(createdAt string) int64 {
+	if created, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+		return created.Unix()
+	}
+	return time.Now().Unix()
+}
+
+func transformResponseToOllama(ctx context.Context, oaiResp *openai.ChatCompletionResponse, resp *ollama.ChatResponse) error {
+	resp.Model = oaiResp.Model
+	resp.CreatedAt = ollamaCreatedAt(oaiResp.Created)
+	resp.Message = ollama.Message{Role: "assistant"}
+	resp.Done = true
+	resp.DoneReason = ollama.DoneReasonStop
+	for i, choice := range oaiResp.Choices {
+		if i > 0 {
+			if err := dropUnsupported(ctx, fmt.Sprintf("choices[%d]", i), "Ollama returns a single message"); err != nil {
+				return err
+			}
+			break
+		}
+		message, err := ollamaMessageFromOpenAI(ctx, i, choice.Message)
+		if err != nil {
+			return err
+		}
+		for j := range message.ToolCalls {
+			message.ToolCalls[j].Function.Name = originalToolName(ctx, message.ToolCalls[j].Function.Name)
+		}
+		message.Role = "assistant"
+		if message.Content == "" {
+			message.Content = choice.Message.Refusal
+		}
+		resp.Message = message
+		resp.DoneReason = doneReasonOpenAI2Ollama(choice.FinishReason)
+	}
+	resp.Metrics = usage.FromOpenAI(&oaiResp.Usage).Ollama()
+	return nil
+}
+
+func ollamaCreatedAt(created int64) string {
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+	return time.Unix(created, 0).UTC().Format(time.RFC3339Nano)
+}
+
+// transformOllamaChunkToOpenAI converts one streamed object on its own: tool
+// calls are indexed within the object, and the final object carries both
+// the finish reason and usage.
+func transformOllamaChunkToOpenAI(ctx context.Context, chunk *ollama.ChatResponse, oaiChunk *openai.ChatCompletionStreamResponse) error {
+	oaiChunk.Object = "chat.completion.chunk"
+	oaiChunk.Created = ollamaCreated(chunk.CreatedAt)
+	oaiChunk.Model = chunk.Model
+	delta := openai.ChatCompletionStreamChoiceDelta{
+		Content:          chunk.Message.Content,
+		ReasoningContent: chunk.Message.Thinking,
+		ToolCalls:        openAIToolCallsFromOllama(ctx, chunk.Message.ToolCalls, 0),
+	}
+	var finishReason openai.FinishReason
+	if chunk.Done {
+		finishReason = doneReasonOllama2OpenAI(chunk.DoneReason, len(delta.ToolCalls) > 0)
+		oaiChunk.Usage = usage.FromOllama(&chunk.Metrics).OpenAI()
+	}
+	oaiChunk.Choices = []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}}
+	return nil
+}
+
+// doneReasonOllama2OpenAI maps a done reason; Ollama has none for tool
+// calls and stops after them.
+func doneReasonOllama2OpenAI(reason string, toolCalls bool) openai.FinishReason {
+	switch {
+	case reason == ollama.DoneReasonLength:
+		return openai.FinishReasonLength
+	case toolCalls:
+		return openai.FinishReasonToolCalls
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+func doneReasonOpenAI2Ollama(reason openai.FinishReason) string {
+	if reason == openai.FinishReasonLength {
+		return ollama.DoneReasonLength
+	}
+	return ollama.DoneReasonStop
+}
@@ -0,0 +1,53 @@
+package transformer
+
+import (
+	"fmt"
+
+	"github.com/phosae/llms/dto/ollama"
+)
+
+// UpgradeOllamaGenerate converts a /api/generate request to a chat request:
+// the system prompt and the prompt become the system and user turns. Raw
+// prompts, templates, suffixes and the context of earlier responses are
+// token level features of /api/generate and fail the conversion.
+func UpgradeOllamaGenerate(req *ollama.GenerateRequest) (*ollama.ChatRequest, error) {
+	switch {
+	case req.Raw:
+		return nil, fmt.Errorf("raw prompts have no chat equivalent")
+	case req.Template != "":
+		return nil, fmt.Errorf("template has no chat equivalent")
+	case req.Suffix != "":
+		return nil, fmt.Errorf("suffix has no chat equivalent")
+	case len(req.Context) > 0:
+		return nil, fmt.Errorf("context has no chat equivalent, send the earlier turns as messages")
+	}
+
+	chatReq := &ollama.ChatRequest{
+		Model:     req.Model,
+		Format:    req.Format,
+		Options:   req.Options,
+		Stream:    req.Stream,
+		KeepAlive: req.KeepAlive,
+		Think:     req.Think,
+	}
+	if req.System != "" {
+		chatReq.Messages = append(chatReq.Messages, ollama.Message{Role: "system", Content: req.System})
+	}
+	chatReq.Messages = append(chatReq.Messages, ollama.Message{Role: "user", Content: req.Prompt, Images: req.Images})
+	return chatReq, nil
+}
+
+// DowngradeOllamaChat converts a chat response, or one object of a chat
+// stream, to its /api/generate counterpart. Tool calls have none and are
+// left out.
+func DowngradeOllamaChat(resp *ollama.ChatResponse) *ollama.GenerateResponse {
+	return &ollama.GenerateResponse{
+		Model:      resp.Model,
+		CreatedAt:  resp.CreatedAt,
+		Response:   resp.Message.Content,
+		Thinking:   resp.Message.Thinking,
+		Done:       resp.Done,
+		DoneReason: resp.DoneReason,
+		Metrics:    resp.Metrics,
+	}
+}
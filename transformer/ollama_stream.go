@@ -0,0 +1,218 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/usage"
+)
+
+// OllamaStreamState converts an Ollama chat stream into OpenAI chat
+// completion chunks as the objects arrive. Tool calls arrive whole and get
+// indexes counting the tool calls of the stream. The final object becomes
+// the finishing chunk, followed by the usage chunk.
+type OllamaStreamState struct {
+	ctx          context.Context
+	includeUsage bool
+	id           string
+	started      bool
+	toolCalls    int
+}
+
+// NewOllamaStreamState returns the state of one stream. With includeUsage
+// the stream ends with a usage chunk, as for stream_options.include_usage.
+func NewOllamaStreamState(ctx context.Context, includeUsage bool) *OllamaStreamState {
+	return &OllamaStreamState{ctx: ctx, includeUsage: includeUsage, id: "chatcmpl-" + generateUUID()}
+}
+
+// Transform consumes one object and returns the chunks it produces, none
+// for objects without content.
+func (s *OllamaStreamState) Transform(chunk *ollama.ChatResponse) ([]*openai.ChatCompletionStreamResponse, error) {
+	delta := openai.ChatCompletionStreamChoiceDelta{
+		Content:          chunk.Message.Content,
+		ReasoningContent: chunk.Message.Thinking,
+		ToolCalls:        openAIToolCallsFromOllama(s.ctx, chunk.Message.ToolCalls, s.toolCalls),
+	}
+	s.toolCalls += len(delta.ToolCalls)
+	if !s.started {
+		s.started = true
+		delta.Role = openai.ChatMessageRoleAssistant
+	}
+	if !chunk.Done && delta.Role == "" && delta.Content == "" && delta.ReasoningContent == "" && len(delta.ToolCalls) == 0 {
+		return nil, nil
+	}
+
+	oaiChunk := s.template(chunk)
+	choice := openai.ChatCompletionStreamChoice{Delta: delta}
+	if chunk.Done {
+		choice.FinishReason = doneReasonOllama2OpenAI(chunk.DoneReason, s.toolCalls > 0)
+	}
+	oaiChunk.Choices = []openai.ChatCompletionStreamChoice{choice}
+	chunks := []*openai.ChatCompletionStreamResponse{oaiChunk}
+	if chunk.Done && s.includeUsage {
+		usageChunk := s.template(chunk)
+		usageChunk.Choices = []openai.ChatCompletionStreamChoice{}
+		usageChunk.Usage = usage.FromOllama(&chunk.Metrics).OpenAI()
+		chunks = append(chunks, usageChunk)
+	}
+	return chunks, nil
+}
+
+// template returns an empty chunk with the identity of the stream.
+func (s *OllamaStreamState) template(chunk *ollama.ChatResponse) *openai.ChatCompletionStreamResponse {
+	return &openai.ChatCompletionStreamResponse{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: ollamaCreated(chunk.CreatedAt),
+		Model:   chunk.Model,
+	}
+}
+
+// OllamaStreamTransformer converts an OpenAI chat completion stream into an
+// Ollama chat stream. Ollama streams tool calls whole, so calls are
+// buffered until the choice finishes, and the final object is held back
+// until Finish, since OpenAI reports usage in a chunk after the finish
+// reason. The calls a length finish cuts off are dropped with a warning.
+//
+// One OllamaStreamTransformer serves a single stream and only choice 0.
+type OllamaStreamTransformer struct {
+	ctx     context.Context
+	model   string
+	created int64
+	// calls buffers the tool calls by OpenAI index, in order of arrival.
+	calls        map[int]*openai.ToolCall
+	order        []int
+	finishReason openai.FinishReason
+	usage        *openai.Usage
+	droppedN     bool
+	stopped      bool
+}
+
+// NewOllamaStreamTransformer returns a transformer for one stream. ctx
+// carries the options and warnings of the stream.
+func NewOllamaStreamTransformer(ctx context.Context) *OllamaStreamTransformer {
+	return &OllamaStreamTransformer{ctx: ctx, calls: make(map[int]*openai.ToolCall)}
+}
+
+// Transform converts one chunk into the objects it completes, none when the
+// chunk carries nothing new.
+func (s *OllamaStreamTransformer) Transform(chunk *openai.ChatCompletionStreamResponse) ([]*ollama.ChatResponse, error) {
+	if s.stopped {
+		return nil, fmt.Errorf("chunk after the stream finished")
+	}
+	if chunk.Model != "" {
+		s.model = chunk.Model
+	}
+	if chunk.Created != 0 {
+		s.created = chunk.Created
+	}
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	var out []*ollama.ChatResponse
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			if !s.droppedN {
+				s.droppedN = true
+				if err := dropUnsupported(s.ctx, fmt.Sprintf("choices[%d]", choice.Index), "Ollama streams a single message"); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		delta := choice.Delta
+		if text := delta.Content + delta.Refusal; text != "" || delta.ReasoningContent != "" {
+			out = append(out, s.object(ollama.Message{Role: "assistant", Content: text, Thinking: delta.ReasoningContent}))
+		}
+		for _, call := range delta.ToolCalls {
+			s.bufferCall(call)
+		}
+		if choice.FinishReason != "" && choice.FinishReason != openai.FinishReasonNull {
+			s.finishReason = choice.FinishReason
+			calls, err := s.flushCalls()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, calls...)
+		}
+	}
+	return out, nil
+}
+
+// Finish closes the stream with the final object, carrying the done reason
+// and metrics.
+func (s *OllamaStreamTransformer) Finish() ([]*ollama.ChatResponse, error) {
+	if s.stopped {
+		return nil, nil
+	}
+	s.stopped = true
+	// the stream ended without finishing the choice
+	out, err := s.flushCalls()
+	if err != nil {
+		return nil, err
+	}
+	done := s.object(ollama.Message{Role: "assistant"})
+	done.Done = true
+	done.DoneReason = doneReasonOpenAI2Ollama(s.finishReason)
+	done.Metrics = usage.FromOpenAI(s.usage).Ollama()
+	return append(out, done), nil
+}
+
+func (s *OllamaStreamTransformer) object(message ollama.Message) *ollama.ChatResponse {
+	return &ollama.ChatResponse{Model: s.model, CreatedAt: ollamaCreatedAt(s.created), Message: message}
+}
+
+func (s *OllamaStreamTransformer) bufferCall(call openai.ToolCall) {
+	index := 0
+	if call.Index != nil {
+		index = *call.Index
+	}
+	buffered, ok := s.calls[index]
+	if !ok {
+		buffered = &openai.ToolCall{ID: call.ID, Type: call.Type}
+		s.calls[index] = buffered
+		s.order = append(s.order, index)
+	}
+	if call.Function.Name != "" {
+		buffered.Function.Name = call.Function.Name
+	}
+	buffered.Function.Arguments += call.Function.Arguments
+}
+
+// flushCalls returns the buffered tool calls as one object, none when no
+// call is buffered.
+func (s *OllamaStreamTransformer) flushCalls() ([]*ollama.ChatResponse, error) {
+	if len(s.order) == 0 {
+		return nil, nil
+	}
+	message := ollama.Message{Role: "assistant"}
+	for _, index := range s.order {
+		call := s.calls[index]
+		if s.finishReason == openai.FinishReasonLength && partialArguments(call.Function.Arguments) {
+			if err := dropUnsupported(s.ctx, fmt.Sprintf("tool_calls[%d]", index), "Ollama has no partial tool calls"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		arguments := map[string]any{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+				return nil, fmt.Errorf("tool_calls[%d]: invalid arguments: %w", index, err)
+			}
+		}
+		message.ToolCalls = append(message.ToolCalls, ollama.ToolCall{Function: ollama.ToolCallFunction{
+			Name:      originalToolName(s.ctx, call.Function.Name),
+			Arguments: arguments,
+		}})
+	}
+	s.calls = make(map[int]*openai.ToolCall)
+	s.order = nil
+	if len(message.ToolCalls) == 0 {
+		return nil, nil
+	}
+	return []*ollama.ChatResponse{s.object(message)}, nil
+}
@@ -1,14 +1,23 @@
 package transformer
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
-	"github.com/phosae/llms/claude"
-	"github.com/phosae/llms/gemini"
-	"github.com/phosae/llms/openai"
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/usage"
 )
 
 // OpenAITransformer handles direct OpenAI to other provider's transformations
@@ -24,22 +33,120 @@ func (t *OpenAITransformer) GetProvider() Provider {
 	return ProviderOpenAI
 }
 
-// ValidateRequest validates the OpenAI request
+// ValidateRequest checks the OpenAI request: required fields, roles, tool
+// messages answering the tool calls before them, sampling ranges, image
+// media types and the output limit of the model.
 func (t *OpenAITransformer) ValidateRequest(ctx context.Context, request interface{}) error {
 	req, ok := request.(*openai.ChatCompletionRequest)
 	if !ok {
 		return fmt.Errorf("invalid request type for OpenAI transformer")
 	}
 
+	v := &requestValidator{}
 	if req.Model == "" {
-		return fmt.Errorf("model is required")
+		v.add("/model", "model is required")
+	}
+	v.maxTokens("/max_tokens", req.Model, req.MaxTokens)
+	v.maxTokens("/max_completion_tokens", req.Model, req.MaxCompletionTokens)
+	if req.Temperature != nil {
+		v.between("/temperature", *req.Temperature, 0, 2)
+	}
+	if req.TopP != nil {
+		v.between("/top_p", *req.TopP, 0, 1)
 	}
 
 	if len(req.Messages) == 0 {
-		return fmt.Errorf("messages cannot be empty")
+		v.add("/messages", "messages cannot be empty")
+	}
+	// calls are the tool calls of the last assistant message not yet answered
+	var calls []string
+	assistant := 0
+	for i, message := range req.Messages {
+		path := fmt.Sprintf("/messages/%d", i)
+		switch message.Role {
+		case openai.ChatMessageRoleTool:
+			if j := slices.Index(calls, message.ToolCallID); j >= 0 {
+				calls = slices.Delete(calls, j, j+1)
+			} else {
+				v.add(path+"/tool_call_id", "tool message %q does not answer a tool call of the preceding assistant message", message.ToolCallID)
+			}
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper, openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant, openai.ChatMessageRoleFunction:
+			for _, id := range calls {
+				j := slices.IndexFunc(req.Messages[assistant].ToolCalls, func(call openai.ToolCall) bool { return call.ID == id })
+				v.add(fmt.Sprintf("/messages/%d/tool_calls/%d/id", assistant, j), "tool call %q has no tool message", id)
+			}
+			calls = nil
+			if message.Role == openai.ChatMessageRoleAssistant {
+				assistant = i
+				for _, call := range message.ToolCalls {
+					calls = append(calls, call.ID)
+				}
+			}
+		default:
+			v.add(path+"/role", "invalid role %q", message.Role)
+		}
+		for j, part := range message.MultiContent {
+			if part.Type != openai.ChatMessagePartTypeImageURL || part.ImageURL == nil {
+				continue
+			}
+			if header, _, ok := strings.Cut(part.ImageURL.URL, ","); ok && strings.HasPrefix(header, "data:") {
+				mediaType, _, _ := strings.Cut(strings.TrimPrefix(header, "data:"), ";")
+				v.mediaType(fmt.Sprintf("%s/content/%d/image_url/url", path, j), mediaType, imageMediaTypes)
+			}
+		}
 	}
+	return v.err(ProviderOpenAI)
+}
 
-	return nil
+// ValidateResponse checks roles, finish reasons and tool call consistency of the OpenAI response
+func (t *OpenAITransformer) ValidateResponse(ctx context.Context, response interface{}) error {
+	resp, ok := response.(*openai.ChatCompletionResponse)
+	if !ok {
+		return fmt.Errorf("invalid response type for OpenAI transformer")
+	}
+
+	var errs []error
+	if len(resp.Choices) == 0 {
+		errs = append(errs, fmt.Errorf("choices cannot be empty"))
+	}
+	seen := make(map[int]bool, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		if seen[choice.Index] {
+			errs = append(errs, fmt.Errorf("choices[%d]: duplicate index %d", i, choice.Index))
+		}
+		seen[choice.Index] = true
+		if choice.Message.Role != openai.ChatMessageRoleAssistant {
+			errs = append(errs, fmt.Errorf("choices[%d].message.role must be assistant, got %q", i, choice.Message.Role))
+		}
+		switch choice.FinishReason {
+		case openai.FinishReasonStop, openai.FinishReasonLength, openai.FinishReasonFunctionCall,
+			openai.FinishReasonToolCalls, openai.FinishReasonContentFilter, openai.FinishReasonNull, "":
+		default:
+			errs = append(errs, fmt.Errorf("choices[%d]: unknown finish_reason %q", i, choice.FinishReason))
+		}
+		if choice.FinishReason == openai.FinishReasonToolCalls && len(choice.Message.ToolCalls) == 0 {
+			errs = append(errs, fmt.Errorf("choices[%d]: finish_reason is tool_calls but message has no tool_calls", i))
+		}
+		for j, call := range choice.Message.ToolCalls {
+			if call.ID == "" {
+				errs = append(errs, fmt.Errorf("choices[%d].message.tool_calls[%d]: id is required", i, j))
+			}
+			if call.Type != openai.ToolTypeFunction {
+				errs = append(errs, fmt.Errorf("choices[%d].message.tool_calls[%d]: unknown type %q", i, j, call.Type))
+			}
+			if call.Function.Name == "" {
+				errs = append(errs, fmt.Errorf("choices[%d].message.tool_calls[%d]: function name is required", i, j))
+			}
+			if call.Function.Arguments != "" && !json.Valid([]byte(call.Function.Arguments)) {
+				errs = append(errs, fmt.Errorf("choices[%d].message.tool_calls[%d]: arguments are not valid JSON", i, j))
+			}
+		}
+	}
+	if resp.Usage.TotalTokens != 0 && resp.Usage.TotalTokens < resp.Usage.PromptTokens+resp.Usage.CompletionTokens {
+		errs = append(errs, fmt.Errorf("usage.total_tokens is less than prompt_tokens + completion_tokens"))
+	}
+
+	return errors.Join(errs...)
 }
 
 // Do performs the transformation based on the type
@@ -58,6 +165,16 @@ func (t *OpenAITransformer) Do(ctx context.Context, typ TransformerType, src int
 	}
 }
 
+// ToUnified converts a OpenAI request, response or chunk to the unified format.
+func (t *OpenAITransformer) ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	return toUnified(ctx, ProviderOpenAI, typ, src)
+}
+
+// FromUnified converts a unified request, response or chunk to the OpenAI payload dst.
+func (t *OpenAITransformer) FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	return fromUnified(ctx, ProviderOpenAI, typ, src, dst)
+}
+
 func (t *OpenAITransformer) transformRequest(ctx context.Context, src interface{}, dst interface{}) error {
 	oaiReq, ok := src.(*openai.ChatCompletionRequest)
 	if !ok {
@@ -65,10 +182,18 @@ func (t *OpenAITransformer) transformRequest(ctx context.Context, src interface{
 	}
 
 	switch target := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		return sameProvider(ctx, ProviderOpenAI, TransformerTypeRequest, oaiReq, target)
 	case *claude.ClaudeRequest:
 		return transformRequestToClaude(ctx, oaiReq, target)
 	case *gemini.GeminiChatRequest:
 		return transformRequestToGemini(ctx, oaiReq, target)
+	case *bedrock.ConverseRequest:
+		return transformRequestToBedrock(ctx, oaiReq, target)
+	case *vertex.GenerateContentRequest:
+		return transformRequestToVertex(ctx, oaiReq, target)
+	case *ollama.ChatRequest:
+		return transformRequestToOllama(ctx, oaiReq, target)
 	default:
 		return fmt.Errorf("target type not supported for OpenAI transformer")
 	}
@@ -81,8 +206,18 @@ func (t *OpenAITransformer) transformResponse(ctx context.Context, src interface
 	}
 
 	switch dst.(type) {
+	case *openai.ChatCompletionResponse:
+		return sameProvider(ctx, ProviderOpenAI, TransformerTypeResponse, oaiResp, dst.(*openai.ChatCompletionResponse))
 	case *claude.ClaudeResponse:
 		return transformResponseToClaude(ctx, oaiResp, dst.(*claude.ClaudeResponse))
+	case *gemini.GeminiChatResponse:
+		return transformResponseToGemini(ctx, oaiResp, dst.(*gemini.GeminiChatResponse))
+	case *bedrock.ConverseResponse:
+		return transformResponseToBedrock(ctx, oaiResp, dst.(*bedrock.ConverseResponse))
+	case *vertex.GenerateContentResponse:
+		return transformResponseToVertex(ctx, oaiResp, dst.(*vertex.GenerateContentResponse))
+	case *ollama.ChatResponse:
+		return transformResponseToOllama(ctx, oaiResp, dst.(*ollama.ChatResponse))
 	default:
 		return fmt.Errorf("target type not supported for OpenAI transformer")
 	}
@@ -94,127 +229,735 @@ func transformResponseToClaude(ctx context.Context, oaiResp *openai.ChatCompleti
 	claudeResp.Role = "assistant"
 	claudeResp.Model = oaiResp.Model
 
-	for _, choice := range oaiResp.Choices {
+	for i, choice := range oaiResp.Choices {
+		if i > 0 {
+			if err := dropUnsupported(ctx, fmt.Sprintf("choices[%d]", i), "Claude returns a single message"); err != nil {
+				return err
+			}
+			break
+		}
 		claudeResp.StopReason = stopReasonOpenAI2Claude(string(choice.FinishReason))
+		claudeResp.Content = append(claudeResp.Content, claudeThinkingBlocks(choice.Message, false)...)
 		if choice.FinishReason == "tool_calls" {
 			for _, toolCall := range choice.Message.ToolCalls {
 				claudeResp.Content = append(claudeResp.Content, claude.ClaudeMediaMessage{
 					Type:  "tool_use",
 					Id:    toolCall.ID,
-					Name:  toolCall.Function.Name,
+					Name:  originalToolName(ctx, toolCall.Function.Name),
 					Input: toolCall.Function.Arguments,
 				})
 			}
 		} else {
-			claudeResp.Content = append(claudeResp.Content, claude.ClaudeMediaMessage{
-				Type: "text",
-				Text: &choice.Message.Content,
-			})
+			claudeResp.Content = append(claudeResp.Content, claudeTextBlocks(choice.Message.Content, choice.Message.Annotations)...)
 		}
 	}
 
-	claudeResp.Usage = &claude.ClaudeUsage{
-		InputTokens:  oaiResp.Usage.PromptTokens,
-		OutputTokens: oaiResp.Usage.CompletionTokens,
-	}
+	// cached_tokens of OpenAI are Claude's cache reads
+	claudeResp.Usage = usage.FromOpenAI(&oaiResp.Usage).Claude()
+	return nil
+}
 
-	if oaiResp.Usage.PromptTokensDetails != nil && (oaiResp.Usage.PromptTokensDetails.CacheCreationInputTokens > 0 || oaiResp.Usage.PromptTokensDetails.CacheReadInputTokens > 0) {
-		if oaiResp.Usage.PromptTokensDetails.CacheCreationInputTokens > 0 {
-			claudeResp.Usage.CacheCreationInputTokens = oaiResp.Usage.PromptTokensDetails.CacheCreationInputTokens
-			claudeResp.Usage.InputTokens -= oaiResp.Usage.PromptTokensDetails.CacheCreationInputTokens
+func transformResponseToGemini(ctx context.Context, oaiResp *openai.ChatCompletionResponse, geminiResp *gemini.GeminiChatResponse) error {
+	for i, choice := range oaiResp.Choices {
+		var parts []gemini.GeminiPart
+		var grounding json.RawMessage
+		if choice.Message.ReasoningContent != "" {
+			parts = append(parts, gemini.GeminiPart{Text: choice.Message.ReasoningContent, Thought: true})
 		}
-		if oaiResp.Usage.PromptTokensDetails.CacheReadInputTokens > 0 {
-			claudeResp.Usage.CacheReadInputTokens = oaiResp.Usage.PromptTokensDetails.CacheReadInputTokens
-			claudeResp.Usage.InputTokens -= oaiResp.Usage.PromptTokensDetails.CacheReadInputTokens
+		if choice.Message.Content != "" {
+			var err error
+			grounding, err = geminiGroundingMetadata(ctx, fmt.Sprintf("choices[%d].message", i), choice.Message.Content, len(parts), choice.Message.Annotations)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, gemini.GeminiPart{Text: choice.Message.Content})
 		}
+		for _, call := range choice.Message.ToolCalls {
+			var args any
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				args = call.Function.Arguments
+			}
+			part := gemini.GeminiPart{FunctionCall: &gemini.FunctionCall{FunctionName: originalToolName(ctx, call.Function.Name), Arguments: args}}
+			if call.ExtraContent != nil && call.ExtraContent.Google != nil {
+				part.ThoughtSignature = call.ExtraContent.Google.ThoughtSignature
+			}
+			parts = append(parts, part)
+		}
+
+		finishReason := finishReasonOpenAI2Gemini(choice.FinishReason)
+		geminiResp.Candidates = append(geminiResp.Candidates, gemini.GeminiChatCandidate{
+			Content:       gemini.GeminiChatContent{Role: "model", Parts: parts},
+			FinishReason:  &finishReason,
+			Index:         int64(choice.Index),
+			SafetyRatings: geminiSafetyRatings(choice.ContentFilterResults),
+
+			GroundingMetadata: grounding,
+		})
 	}
+	geminiResp.ResponseID, geminiResp.ModelVersion = oaiResp.ID, oaiResp.Model
+	geminiResp.PromptFeedback = geminiPromptFeedback(oaiResp.PromptFilterResults)
+	geminiResp.UsageMetadata = usage.FromOpenAI(&oaiResp.Usage).Gemini()
 	return nil
 }
 
+func finishReasonOpenAI2Gemini(reason openai.FinishReason) string {
+	switch reason {
+	case openai.FinishReasonLength:
+		return "MAX_TOKENS"
+	case openai.FinishReasonContentFilter:
+		return "SAFETY"
+	case openai.FinishReasonStop, openai.FinishReasonToolCalls, openai.FinishReasonFunctionCall:
+		return "STOP"
+	default:
+		return "OTHER"
+	}
+}
+
+// defaultClaudeMaxTokens is used when neither the request nor the config sets
+// max_tokens, which Claude requires
+const defaultClaudeMaxTokens = 4096
+
 func transformRequestToClaude(ctx context.Context, oaiReq *openai.ChatCompletionRequest, claudeReq *claude.ClaudeRequest) error {
-	// TODO: Implement OpenAI -> Claude request transformation
-	return fmt.Errorf("OpenAI -> Claude request transformation not yet implemented")
+	oaiReq, err := cacheHints(ctx, ProviderClaude, oaiReq)
+	if err != nil {
+		return err
+	}
+	if oaiReq, err = normalizeTools(ctx, ProviderClaude, oaiReq); err != nil {
+		return err
+	}
+	claudeReq.Model = TargetModel(ctx, ProviderClaude, oaiReq.Model)
+	preset := presetFor(ctx, claudeReq.Model)
+
+	maxTokens := oaiReq.MaxCompletionTokens
+	if maxTokens == 0 {
+		maxTokens = oaiReq.MaxTokens
+	}
+	if maxTokens == 0 {
+		maxTokens = preset.maxTokens()
+	}
+	if maxTokens == 0 {
+		maxTokens = defaultClaudeMaxTokens
+	}
+	maxTokens, budget, err := fitClaudeModel(ctx, claudeReq.Model, maxTokens, reasoningEffortBudget(ctx, oaiReq.Model, oaiReq.ReasoningEffort))
+	if err != nil {
+		return err
+	}
+	claudeReq.MaxTokens = uint(maxTokens)
+
+	claudeReq.Temperature = cmp.Or(oaiReq.Temperature, preset.temperature())
+	claudeReq.TopP = cmp.Or(oaiReq.TopP, preset.topP())
+	claudeReq.Stream = oaiReq.Stream
+	claudeReq.StopSequences = oaiReq.Stop
+	if oaiReq.User != "" {
+		claudeReq.Metadata = &claude.ClaudeMetadata{UserId: oaiReq.User}
+	}
+	switch oaiReq.ServiceTier {
+	case openai.ServiceTierAuto:
+		claudeReq.ServiceTier = "auto"
+	case openai.ServiceTierDefault:
+		claudeReq.ServiceTier = "standard_only"
+	}
+
+	if budget > 0 {
+		claudeReq.Thinking = &claude.Thinking{Type: "enabled", BudgetTokens: &budget}
+	}
+
+	// Tools, only the allowed ones as Claude cannot restrict calls to some
+	toolMode, toolName, allowed, err := openAIToolChoice(oaiReq.ToolChoice)
+	if err != nil {
+		return fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	var tools []any
+	for _, tool := range allowTools(oaiReq.Tools, allowed) {
+		if tool.Function == nil {
+			continue
+		}
+		inputSchema, _ := common.Any2Type[map[string]interface{}](tool.Function.Parameters)
+		if inputSchema == nil {
+			inputSchema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tools = append(tools, claude.Tool{
+			Name:         tool.Function.Name,
+			Description:  tool.Function.Description,
+			InputSchema:  inputSchema,
+			CacheControl: tool.CacheControl,
+			Strict:       tool.Function.Strict,
+		})
+	}
+	if len(tools) > 0 {
+		claudeReq.Tools = tools
+	}
+	if builtinTool(oaiReq, ServerToolWebSearch) {
+		search := cmp.Or(oaiReq.WebSearchOptions, &openai.WebSearchOptions{})
+		if search.SearchContextSize != "" {
+			if err := dropUnsupported(ctx, "web_search_options.search_context_size", "Claude web search has no context size"); err != nil {
+				return err
+			}
+		}
+		tool := claude.ClaudeWebSearchTool{Type: claude.WebSearchToolType, Name: claude.WebSearchToolName}
+		if loc := search.UserLocation; loc != nil {
+			tool.UserLocation = &claude.ClaudeWebSearchUserLocation{
+				Type:     "approximate",
+				City:     loc.Approximate.City,
+				Country:  loc.Approximate.Country,
+				Region:   loc.Approximate.Region,
+				Timezone: loc.Approximate.Timezone,
+			}
+		}
+		tools = append(tools, tool)
+		claudeReq.Tools = tools
+	}
+	if builtinTool(oaiReq, ServerToolCodeExecution) {
+		tools = append(tools, claude.CodeExecutionTool{Type: claude.CodeExecutionToolType, Name: claude.CodeExecutionToolName})
+		claudeReq.Tools = tools
+	}
+	if claudeReq.Tools != nil {
+		toolChoice := toolChoiceOpenAI2Claude(toolMode, toolName)
+		if parallel, ok := oaiReq.ParallelToolCalls.(bool); ok && !parallel {
+			if toolChoice == nil {
+				toolChoice = &claude.ClaudeToolChoice{Type: "auto"}
+			}
+			// Claude rejects the flag with "none", where no tool is used anyway
+			toolChoice.DisableParallelToolUse = toolChoice.Type != "none"
+		}
+		if toolChoice != nil {
+			claudeReq.ToolChoice = toolChoice
+		}
+	}
+	if format := oaiReq.ResponseFormat; format != nil && format.Type == openai.ChatCompletionResponseFormatTypeJSONSchema && format.JSONSchema != nil {
+		if err := structuredOutputToClaude(ctx, format.JSONSchema, claudeReq); err != nil {
+			return err
+		}
+	}
+	if slices.Contains(oaiReq.Modalities, "audio") || oaiReq.Audio != nil {
+		if err := dropUnsupported(ctx, "modalities", "Claude cannot generate audio"); err != nil {
+			return err
+		}
+	}
+	if oaiReq.N > 1 {
+		if err := dropUnsupported(ctx, "n", "Claude returns a single message"); err != nil {
+			return err
+		}
+	}
+	if oaiReq.Seed != nil {
+		if err := dropUnsupported(ctx, "seed", "Claude has no seed, its samples cannot be reproduced"); err != nil {
+			return err
+		}
+	}
+
+	// Messages
+	var systemBlocks []claude.ClaudeMediaMessage
+	if prefix := preset.systemPrefix(); prefix != "" {
+		block := claude.ClaudeMediaMessage{Type: "text"}
+		block.SetText(prefix)
+		systemBlocks = append(systemBlocks, block)
+	}
+	var messages []claude.ClaudeMessage
+	appendBlocks := func(role string, blocks ...claude.ClaudeMediaMessage) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Claude expects alternating turns, consecutive turns of a role are merged
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content.([]claude.ClaudeMediaMessage), blocks...)
+			return
+		}
+		messages = append(messages, claude.ClaudeMessage{Role: role, Content: blocks})
+	}
+
+	for i, message := range oaiReq.Messages {
+		switch message.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			blocks, err := claudeBlocksFromOpenAI(ctx, i, message)
+			if err != nil {
+				return err
+			}
+			systemBlocks = append(systemBlocks, blocks...)
+		case openai.ChatMessageRoleTool:
+			content, isError := parseToolError(message.Content)
+			block := claude.ClaudeMediaMessage{
+				Type:         "tool_result",
+				ToolUseId:    message.ToolCallID,
+				Content:      content,
+				CacheControl: message.CacheControl,
+			}
+			if isError {
+				block.IsError = &isError
+			}
+			appendBlocks("user", block)
+		case openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+			blocks, err := claudeBlocksFromOpenAI(ctx, i, message)
+			if err != nil {
+				return err
+			}
+			if message.Role == openai.ChatMessageRoleAssistant {
+				// signed thinking leads the turn, as Claude requires before tool use
+				blocks = append(claudeThinkingBlocks(message, true), blocks...)
+			}
+			for _, call := range message.ToolCalls {
+				var input any
+				if err := json.Unmarshal([]byte(call.Function.Arguments), &input); err != nil || input == nil {
+					input = map[string]any{}
+				}
+				blocks = append(blocks, claude.ClaudeMediaMessage{
+					Type:  "tool_use",
+					Id:    call.ID,
+					Name:  call.Function.Name,
+					Input: input,
+				})
+			}
+			appendBlocks(message.Role, blocks...)
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d]", i), "role %q has no Claude equivalent", message.Role); err != nil {
+				return err
+			}
+		}
+	}
+
+	if emulatesJSONMode(ctx, oaiReq.ResponseFormat) {
+		instruction := claude.ClaudeMediaMessage{Type: "text"}
+		instruction.SetText(claudeJSONInstruction)
+		systemBlocks = append(systemBlocks, instruction)
+		// Claude rejects prefilled assistant turns when thinking is enabled
+		if OptionsFromContext(ctx).PrefillJSON && claudeReq.Thinking == nil &&
+			len(messages) > 0 && messages[len(messages)-1].Role == "user" {
+			prefill := claude.ClaudeMediaMessage{Type: "text"}
+			prefill.SetText(jsonPrefill)
+			appendBlocks("assistant", prefill)
+		}
+	}
+
+	claudeReq.System = claudeSystemFromBlocks(systemBlocks)
+	claudeReq.Messages = simplifyClaudeMessages(messages)
+	return nil
+}
+
+// claudeBlocksFromOpenAI converts the content of an OpenAI message to Claude content blocks
+func claudeBlocksFromOpenAI(ctx context.Context, index int, message openai.ChatCompletionMessage) ([]claude.ClaudeMediaMessage, error) {
+	var blocks []claude.ClaudeMediaMessage
+	if message.Content != "" {
+		block := claude.ClaudeMediaMessage{Type: "text", CacheControl: message.CacheControl}
+		block.SetText(message.Content)
+		return append(blocks, block), nil
+	}
+
+	for j, part := range message.MultiContent {
+		path := fmt.Sprintf("messages[%d].content[%d]", index, j)
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			block := claude.ClaudeMediaMessage{Type: "text", CacheControl: part.CacheControl}
+			block.SetText(part.Text)
+			blocks = append(blocks, block)
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			if detail := part.ImageURL.Detail; detail != "" && detail != openai.ImageURLDetailAuto {
+				warn(ctx, WarningDroppedContent, path+".image_url.detail", "Claude has no image detail level, %q ignored", detail)
+			}
+			blocks = append(blocks, claude.ClaudeMediaMessage{
+				Type:         "image",
+				Source:       claudeImageSource(part.ImageURL.URL),
+				CacheControl: part.CacheControl,
+			})
+		case openai.ChatMessagePartTypeFile:
+			if part.File == nil {
+				continue
+			}
+			block, err := claudeDocument(ctx, path, part)
+			if err != nil {
+				return nil, err
+			}
+			if block != nil {
+				blocks = append(blocks, *block)
+			}
+		default:
+			if err := dropUnsupported(ctx, path, "%s content has no Claude equivalent", part.Type); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(blocks) > 0 && message.CacheControl != nil && blocks[len(blocks)-1].CacheControl == nil {
+		blocks[len(blocks)-1].CacheControl = message.CacheControl
+	}
+	return blocks, nil
+}
+
+// mediaResolutionFromDetails maps OpenAI image detail levels to Gemini's
+// request-wide media resolution: high if any image asks for it, low only if
+// every image does
+func mediaResolutionFromDetails(ctx context.Context, details []openai.ImageURLDetail) string {
+	if len(details) == 0 {
+		return ""
+	}
+	for _, detail := range details[1:] {
+		if detail != details[0] {
+			warn(ctx, WarningDroppedContent, "messages", "Gemini applies one media resolution to all images, per-image detail levels were merged")
+			break
+		}
+	}
+	switch {
+	case slices.Contains(details, openai.ImageURLDetailHigh):
+		return gemini.MediaResolutionHigh
+	case !slices.ContainsFunc(details, func(d openai.ImageURLDetail) bool { return d != openai.ImageURLDetailLow }):
+		return gemini.MediaResolutionLow
+	default:
+		return ""
+	}
+}
+
+// claudeImageSource converts an OpenAI image URL, either a data URL or a remote URL
+func claudeImageSource(URL string) *claude.ClaudeMessageSource {
+	if subStrs := strings.SplitN(URL, ",", 2); strings.HasPrefix(URL, "data:") && len(subStrs) == 2 {
+		mediaType := strings.TrimSuffix(strings.TrimPrefix(subStrs[0], "data:"), ";base64")
+		return &claude.ClaudeMessageSource{Type: "base64", MediaType: mediaType, Data: subStrs[1]}
+	}
+	return &claude.ClaudeMessageSource{Type: "url", Url: URL}
+}
+
+// claudeSystemFromBlocks uses the plain string form for a single uncached text block
+func claudeSystemFromBlocks(blocks []claude.ClaudeMediaMessage) any {
+	switch {
+	case len(blocks) == 0:
+		return nil
+	case len(blocks) == 1 && blocks[0].Type == "text" && blocks[0].CacheControl == nil:
+		return blocks[0].GetText()
+	default:
+		// keep block boundaries, cache breakpoints apply to the prefix ending at their block
+		return blocks
+	}
+}
+
+// simplifyClaudeMessages turns single plain text turns into string content
+func simplifyClaudeMessages(messages []claude.ClaudeMessage) []claude.ClaudeMessage {
+	for i := range messages {
+		blocks, ok := messages[i].Content.([]claude.ClaudeMediaMessage)
+		if ok && len(blocks) == 1 && blocks[0].Type == "text" && blocks[0].CacheControl == nil {
+			messages[i].Content = blocks[0].GetText()
+		}
+	}
+	return messages
+}
+
+// openAIToolChoice reads an OpenAI tool_choice: mode is auto, none or
+// required, empty when unset, name the tool it forces and allowed the tools
+// an allowed_tools choice restricts calls to.
+func openAIToolChoice(toolChoice any) (mode, name string, allowed []string, err error) {
+	switch tc := toolChoice.(type) {
+	case nil:
+		return "", "", nil, nil
+	case string:
+		return tc, "", nil, nil
+	}
+	choice, err := common.Any2Type[openai.ToolChoice](toolChoice)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if choice.Type == openai.ToolTypeAllowedTools {
+		if choice.AllowedTools == nil {
+			return "", "", nil, fmt.Errorf("allowed_tools is required")
+		}
+		for _, tool := range choice.AllowedTools.Tools {
+			allowed = append(allowed, tool.Function.Name)
+		}
+		return choice.AllowedTools.Mode, "", allowed, nil
+	}
+	if choice.Function.Name == "" {
+		return "", "", nil, nil
+	}
+	return "required", choice.Function.Name, nil, nil
+}
+
+// allowedToolsChoice returns an OpenAI tool_choice restricting calls to the
+// named tools, mode being auto or required.
+func allowedToolsChoice(mode string, names []string) openai.ToolChoice {
+	choice := openai.ToolChoice{Type: openai.ToolTypeAllowedTools, AllowedTools: &openai.AllowedTools{Mode: mode}}
+	for _, name := range names {
+		choice.AllowedTools.Tools = append(choice.AllowedTools.Tools, openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: name}})
+	}
+	return choice
+}
+
+// allowTools returns the tools among allowed, all of them when allowed is
+// empty, for providers that cannot restrict calls to some declared tools
+func allowTools(tools []openai.Tool, allowed []string) []openai.Tool {
+	if len(allowed) == 0 {
+		return tools
+	}
+	return slices.DeleteFunc(slices.Clone(tools), func(tool openai.Tool) bool {
+		return tool.Function != nil && !slices.Contains(allowed, tool.Function.Name)
+	})
+}
+
+// toolChoiceOpenAI2Claude converts the mode and forced tool of an OpenAI tool_choice
+func toolChoiceOpenAI2Claude(mode, name string) *claude.ClaudeToolChoice {
+	switch {
+	case mode == "":
+		return nil
+	case name != "":
+		return &claude.ClaudeToolChoice{Type: "tool", Name: name}
+	case mode == "none":
+		return &claude.ClaudeToolChoice{Type: "none"}
+	case mode == "required":
+		return &claude.ClaudeToolChoice{Type: "any"}
+	default:
+		return &claude.ClaudeToolChoice{Type: "auto"}
+	}
+}
+
+// toolChoiceClaude2OpenAI converts a Claude tool_choice to an OpenAI
+// tool_choice and parallel_tool_calls
+func toolChoiceClaude2OpenAI(toolChoice any) (choice any, parallelToolCalls any, err error) {
+	if toolChoice == nil {
+		return nil, nil, nil
+	}
+	tc, err := common.Any2Type[claude.ClaudeToolChoice](toolChoice)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	switch tc.Type {
+	case "auto":
+		choice = "auto"
+	case "any":
+		choice = "required"
+	case "none":
+		choice = "none"
+	case "tool":
+		choice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: tc.Name}}
+	default:
+		return nil, nil, fmt.Errorf("invalid tool_choice: unknown type %q", tc.Type)
+	}
+	if tc.DisableParallelToolUse {
+		parallelToolCalls = false
+	}
+	return choice, parallelToolCalls, nil
+}
+
+// toolChoiceOpenAI2Gemini converts an OpenAI tool_choice to a Gemini
+// function calling config. Gemini restricts calls in the ANY mode, and in
+// the VALIDATED one, which like AUTO lets the model answer in text.
+func toolChoiceOpenAI2Gemini(toolChoice any) *gemini.GeminiToolConfig {
+	mode, name, allowed, err := openAIToolChoice(toolChoice)
+	if err != nil || mode == "" {
+		return nil
+	}
+	callingConfig := &gemini.GeminiFunctionCallingConfig{}
+	switch {
+	case name != "":
+		callingConfig.Mode = gemini.FunctionCallingAny
+		callingConfig.AllowedFunctionNames = []string{name}
+	case mode == "none":
+		callingConfig.Mode = gemini.FunctionCallingNone
+	case mode == "required":
+		callingConfig.Mode = gemini.FunctionCallingAny
+		callingConfig.AllowedFunctionNames = allowed
+	case len(allowed) > 0:
+		callingConfig.Mode = gemini.FunctionCallingValidated
+		callingConfig.AllowedFunctionNames = allowed
+	default:
+		callingConfig.Mode = gemini.FunctionCallingAuto
+	}
+	return &gemini.GeminiToolConfig{FunctionCallingConfig: callingConfig}
+}
+
+// geminiInlineData returns the inline data of a data URL, nil for other URLs
+func geminiInlineData(url string) *gemini.GeminiInlineData {
+	header, data, ok := strings.Cut(url, ",")
+	if !ok || !strings.HasPrefix(header, "data:") {
+		return nil
+	}
+	return &gemini.GeminiInlineData{
+		MimeType: strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64"),
+		Data:     data,
+	}
 }
 
 func transformRequestToGemini(ctx context.Context, oaiReq *openai.ChatCompletionRequest, geminiReq *gemini.GeminiChatRequest) error {
+	oaiReq, err := cacheHints(ctx, ProviderGemini, oaiReq)
+	if err != nil {
+		return err
+	}
+	if oaiReq, err = normalizeTools(ctx, ProviderGemini, oaiReq); err != nil {
+		return err
+	}
 	geminiReq.Contents = make([]gemini.GeminiChatContent, 0, len(oaiReq.Messages))
+	geminiReq.CachedContent = oaiReq.CachedContent
+
+	cfg := config.FromContext(ctx)
+	model := TargetModel(ctx, ProviderGemini, oaiReq.Model)
+	preset := presetFor(ctx, model)
+	quirks := cfg.Quirks(model)
 
 	// Generation config
 	geminiReq.GenerationConfig = gemini.GeminiChatGenerationConfig{
-		Temperature: func() *float64 {
-			if oaiReq.Temperature == 0 {
-				return nil
+		Temperature: cmp.Or(oaiReq.Temperature, preset.temperature()),
+		TopP:        cmp.Or(oaiReq.TopP, preset.topP()),
+
+		PresencePenalty:  oaiReq.PresencePenalty,
+		FrequencyPenalty: oaiReq.FrequencyPenalty,
+		MaxOutputTokens: func() uint {
+			if oaiReq.MaxCompletionTokens != 0 {
+				return uint(oaiReq.MaxCompletionTokens)
 			}
-			t := float64(oaiReq.Temperature)
-			return &t
-		}(),
-		TopP: func() float64 {
-			if oaiReq.TopP == 0 {
-				return 0
+			if oaiReq.MaxTokens == 0 {
+				return uint(preset.maxTokens())
 			}
-			return float64(oaiReq.TopP)
+			return uint(oaiReq.MaxTokens)
 		}(),
-		MaxOutputTokens: uint(oaiReq.MaxTokens),
-		Seed: func() int64 {
+		Seed: func() *int64 {
 			if oaiReq.Seed == nil {
-				return 0
+				return nil
 			}
-			return int64(*oaiReq.Seed)
+			seed := int64(*oaiReq.Seed)
+			return &seed
 		}(),
 	}
+	if oaiReq.N > 1 {
+		geminiReq.GenerationConfig.CandidateCount = oaiReq.N
+	}
+	if budget := reasoningEffortBudget(ctx, oaiReq.Model, oaiReq.ReasoningEffort); budget > 0 {
+		geminiReq.GenerationConfig.ThinkingConfig = &gemini.GeminiThinkingConfig{IncludeThoughts: true, ThinkingBudget: &budget}
+	}
 
-	// Safety settings - disable all
-	geminiReq.SafetySettings = []gemini.GeminiChatSafetySettings{
-		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_CIVIC_INTEGRITY", Threshold: "BLOCK_NONE"},
+	// Safety settings - configured profile, or disable all
+	profileName := preset.safetyProfile()
+	profile, ok := cfg.SafetySettings(profileName)
+	if !ok && OptionsFromContext(ctx).SafetyProfile != "" {
+		return &TransformationError{
+			Type:    "invalid_options",
+			Message: "unknown safety profile " + profileName,
+		}
+	}
+	if ok {
+		for _, setting := range profile {
+			geminiReq.SafetySettings = append(geminiReq.SafetySettings, gemini.GeminiChatSafetySettings{
+				Category:  setting.Category,
+				Threshold: setting.Threshold,
+			})
+		}
+	} else {
+		geminiReq.SafetySettings = []gemini.GeminiChatSafetySettings{
+			{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
+			{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
+			{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
+			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+			{Category: "HARM_CATEGORY_CIVIC_INTEGRITY", Threshold: "BLOCK_NONE"},
+		}
 	}
 
-	// Handle tools
+	// Handle tools: built-in ones are declared, or named, when asked for
+	namedBuiltins := OptionsFromContext(ctx).GeminiBuiltinTools
+	googleSearch, codeExecution := builtinTool(oaiReq, ServerToolWebSearch), builtinTool(oaiReq, ServerToolCodeExecution)
 	for _, tool := range oaiReq.Tools {
-		switch tool.Function.Name {
-		case "googleSearch", "google_search":
-			geminiReq.Tools = append(geminiReq.Tools, gemini.GeminiChatTool{
-				GoogleSearch: make(map[string]string),
-			})
-		case "codeExecution", "code_execution":
-			geminiReq.Tools = append(geminiReq.Tools, gemini.GeminiChatTool{
-				CodeExecution: make(map[string]string),
-			})
+		if tool.Function == nil {
+			continue
+		}
+		switch name := tool.Function.Name; {
+		case namedBuiltins && (name == "googleSearch" || name == "google_search"):
+			googleSearch = true
+		case namedBuiltins && (name == "codeExecution" || name == "code_execution"):
+			codeExecution = true
 		default:
+			// strict becomes the VALIDATED mode below
+			declaration := *tool.Function
+			declaration.Strict = false
 			geminiReq.Tools = append(geminiReq.Tools, gemini.GeminiChatTool{
-				FunctionDeclarations: tool.Function,
+				FunctionDeclarations: &declaration,
 			})
 		}
 	}
 
+	if search := oaiReq.WebSearchOptions; search != nil && (search.SearchContextSize != "" || search.UserLocation != nil) {
+		if err := dropUnsupported(ctx, "web_search_options", "Google Search takes no context size or user location"); err != nil {
+			return err
+		}
+	}
+	if googleSearch {
+		geminiReq.Tools = append(geminiReq.Tools, gemini.GeminiChatTool{
+			GoogleSearch: make(map[string]string),
+		})
+	}
+	if codeExecution {
+		geminiReq.Tools = append(geminiReq.Tools, gemini.GeminiChatTool{
+			CodeExecution: make(map[string]string),
+		})
+	}
+	geminiReq.ToolConfig = toolChoiceOpenAI2Gemini(oaiReq.ToolChoice)
+	if strictTools(oaiReq.Tools) {
+		// the VALIDATED mode holds calls to the function schemas
+		switch config := geminiReq.ToolConfig; {
+		case config == nil:
+			geminiReq.ToolConfig = &gemini.GeminiToolConfig{FunctionCallingConfig: &gemini.GeminiFunctionCallingConfig{Mode: gemini.FunctionCallingValidated}}
+		case config.FunctionCallingConfig.Mode == gemini.FunctionCallingAuto:
+			config.FunctionCallingConfig.Mode = gemini.FunctionCallingValidated
+		}
+	}
+	if parallel, ok := oaiReq.ParallelToolCalls.(bool); ok && !parallel && len(oaiReq.Tools) > 0 {
+		if err := dropUnsupported(ctx, "parallel_tool_calls", "Gemini cannot disable parallel function calls"); err != nil {
+			return err
+		}
+	}
+
+	// Output modalities, OpenAI voices become Gemini ones per the voice map
+	for _, modality := range oaiReq.Modalities {
+		geminiReq.GenerationConfig.ResponseModalities = append(geminiReq.GenerationConfig.ResponseModalities, strings.ToUpper(modality))
+	}
+	if oaiReq.Audio != nil && oaiReq.Audio.Voice != "" {
+		geminiReq.GenerationConfig.SpeechConfig = geminiSpeechConfig(ctx, oaiReq.Audio.Voice)
+	}
+
 	// Handle response format
 	if respFormat := oaiReq.ResponseFormat; respFormat != nil && (respFormat.Type == "json_schema" || respFormat.Type == "json_object") {
 		geminiReq.GenerationConfig.ResponseMimeType = "application/json"
 		if respFormat.JSONSchema != nil && respFormat.JSONSchema.Schema != nil {
-			geminiReq.GenerationConfig.ResponseSchema = respFormat.JSONSchema.Schema
+			schema, err := geminiResponseSchema(ctx, "response_format.json_schema.schema", respFormat.JSONSchema.Schema)
+			if err != nil {
+				return err
+			}
+			geminiReq.GenerationConfig.ResponseSchema = schema
 		}
 	}
 
 	// Process messages
 	toolCallIds := make(map[string]string)
-	var systemContents []string
+	// each system message or content part becomes a part of the system
+	// instruction, in order
+	var systemParts []gemini.GeminiPart
+	if prefix := preset.systemPrefix(); prefix != "" {
+		systemParts = append(systemParts, gemini.GeminiPart{Text: prefix})
+	}
+	var imageDetails []openai.ImageURLDetail
 
-	for _, message := range oaiReq.Messages {
+	for i, message := range oaiReq.Messages {
 		switch message.Role {
 		case "system", "developer":
-			systemContents = append(systemContents, func() string {
-				if message.Content == "" && len(message.MultiContent) > 0 {
-					for _, part := range message.MultiContent {
-						if part.Type == openai.ChatMessagePartTypeText {
-							return part.Text
+			if message.Content != "" {
+				systemParts = append(systemParts, gemini.GeminiPart{Text: message.Content})
+			}
+			for j, part := range message.MultiContent {
+				path := fmt.Sprintf("messages[%d].content[%d]", i, j)
+				switch {
+				case part.Type == openai.ChatMessagePartTypeText:
+					if part.Text != "" {
+						systemParts = append(systemParts, gemini.GeminiPart{Text: part.Text})
+					}
+				case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil && quirks.SystemAsUser:
+					// a system turn sent as a user turn may carry images
+					inlineData := geminiInlineData(part.ImageURL.URL)
+					if inlineData == nil {
+						if err := dropUnsupported(ctx, path, "image URLs must be data URLs for Gemini"); err != nil {
+							return err
 						}
+						continue
+					}
+					systemParts = append(systemParts, gemini.GeminiPart{InlineData: inlineData})
+					imageDetails = append(imageDetails, part.ImageURL.Detail)
+				default:
+					if err := dropUnsupported(ctx, path, "Gemini system instructions only hold text"); err != nil {
+						return err
 					}
 				}
-				return message.Content
-			}())
+			}
 		case "tool":
 			name := message.Name
 			if name == "" {
@@ -223,15 +966,7 @@ func transformRequestToGemini(ctx context.Context, oaiReq *openai.ChatCompletion
 				}
 			}
 
-			var contentMap map[string]any
-			if err := json.Unmarshal([]byte(message.Content), &contentMap); err != nil {
-				var contentSlice []any
-				if err := json.Unmarshal([]byte(message.Content), &contentSlice); err == nil {
-					contentMap = map[string]any{"result": contentSlice}
-				} else {
-					contentMap = map[string]any{"content": message.Content}
-				}
-			}
+			contentMap := geminiFunctionResponse(message.Content)
 
 			geminiReq.Contents = append(geminiReq.Contents, gemini.GeminiChatContent{
 				Role: "user",
@@ -255,26 +990,6 @@ func transformRequestToGemini(ctx context.Context, oaiReq *openai.ChatCompletion
 				}(),
 			}
 
-			// Handle tool calls
-			if len(message.ToolCalls) > 0 {
-				for _, call := range message.ToolCalls {
-					toolCall := gemini.GeminiPart{
-						FunctionCall: &gemini.FunctionCall{
-							FunctionName: call.Function.Name,
-							Arguments: func() any {
-								var args map[string]any
-								if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
-									return call.Function.Arguments
-								}
-								return args
-							}(),
-						},
-					}
-					parts = append(parts, toolCall)
-					toolCallIds[call.ID] = call.Function.Name
-				}
-			}
-
 			// Handle text content
 			if message.Content != "" {
 				parts = append(parts, gemini.GeminiPart{
@@ -289,19 +1004,62 @@ func transformRequestToGemini(ctx context.Context, oaiReq *openai.ChatCompletion
 							Text: ocontent.Text,
 						})
 					case openai.ChatMessagePartTypeImageURL:
-						URL := ocontent.ImageURL.URL
-						if subStrs := strings.SplitN(URL, ",", 2); strings.HasPrefix(URL, "data:") && len(subStrs) == 2 {
-							mediaTypePart := strings.TrimPrefix(subStrs[0], "data:")
-							mediaType := strings.TrimSuffix(mediaTypePart, ";base64")
-
-							parts = append(parts, gemini.GeminiPart{
-								InlineData: &gemini.GeminiInlineData{
-									MimeType: mediaType,
-									Data:     subStrs[1],
-								},
-							})
+						if inlineData := geminiInlineData(ocontent.ImageURL.URL); inlineData != nil {
+							parts = append(parts, gemini.GeminiPart{InlineData: inlineData})
+							imageDetails = append(imageDetails, ocontent.ImageURL.Detail)
+						} else if isGeminiFileURI(ocontent.ImageURL.URL) {
+							parts = append(parts, gemini.GeminiPart{FileData: &gemini.GeminiFileData{FileUri: ocontent.ImageURL.URL}})
+						} else if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d]", i), "image URLs must be data URLs or Gemini files for Gemini"); err != nil {
+							return err
 						}
+					case openai.ChatMessagePartTypeFile:
+						if ocontent.File == nil {
+							continue
+						}
+						part, err := geminiFilePart(ctx, fmt.Sprintf("messages[%d]", i), ocontent.File)
+						if err != nil {
+							return err
+						}
+						if part != nil {
+							parts = append(parts, *part)
+						}
+					case openai.ChatMessagePartTypeInputAudio:
+						if ocontent.InputAudio == nil {
+							continue
+						}
+						mimeType, ok := audioFormats[ocontent.InputAudio.Format]
+						if !ok {
+							mimeType = "audio/" + ocontent.InputAudio.Format
+						}
+						parts = append(parts, gemini.GeminiPart{InlineData: &gemini.GeminiInlineData{MimeType: mimeType, Data: ocontent.InputAudio.Data}})
+					default:
+						if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d]", i), "%s content has no Gemini equivalent", ocontent.Type); err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			// Handle tool calls, after the content as Gemini generates them
+			if len(message.ToolCalls) > 0 {
+				for _, call := range message.ToolCalls {
+					toolCall := gemini.GeminiPart{
+						FunctionCall: &gemini.FunctionCall{
+							FunctionName: call.Function.Name,
+							Arguments: func() any {
+								var args map[string]any
+								if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+									return call.Function.Arguments
+								}
+								return args
+							}(),
+						},
+					}
+					if call.ExtraContent != nil && call.ExtraContent.Google != nil {
+						toolCall.ThoughtSignature = call.ExtraContent.Google.ThoughtSignature
 					}
+					parts = append(parts, toolCall)
+					toolCallIds[call.ID] = call.Function.Name
 				}
 			}
 
@@ -312,20 +1070,39 @@ func transformRequestToGemini(ctx context.Context, oaiReq *openai.ChatCompletion
 		}
 	}
 
+	geminiReq.GenerationConfig.MediaResolution = mediaResolutionFromDetails(ctx, imageDetails)
+
 	// Add system instruction
-	if len(systemContents) > 0 {
-		geminiReq.SystemInstructions = &gemini.GeminiChatContent{
-			Parts: []gemini.GeminiPart{
-				{
-					Text: strings.Join(systemContents, "\n"),
-				},
-			},
+	if len(systemParts) > 0 {
+		systemContent := gemini.GeminiChatContent{Parts: systemParts}
+		if quirks.SystemAsUser {
+			systemContent.Role = "user"
+			geminiReq.Contents = append([]gemini.GeminiChatContent{systemContent}, geminiReq.Contents...)
+		} else {
+			geminiReq.SystemInstructions = &systemContent
 		}
 	}
 
 	return nil
 }
 
+// geminiFunctionResponse wraps an OpenAI tool result in a Gemini function
+// response object
+func geminiFunctionResponse(content string) map[string]any {
+	if toolContent, isError := parseToolError(content); isError {
+		return map[string]any{"error": toolContent}
+	}
+	var contentMap map[string]any
+	if err := json.Unmarshal([]byte(content), &contentMap); err == nil {
+		return contentMap
+	}
+	var contentSlice []any
+	if err := json.Unmarshal([]byte(content), &contentSlice); err == nil {
+		return map[string]any{"result": contentSlice}
+	}
+	return map[string]any{"content": content}
+}
+
 // transformStreamResponse transforms OpenAI stream response to Claude stream response
 func (t *OpenAITransformer) transformStreamResponse(ctx context.Context, src interface{}, dst interface{}) error {
 	// This would handle the full stream response transformation
@@ -364,8 +1141,10 @@ func stopReasonOpenAI2Claude(reason string) string {
 		return "end_turn"
 	case "stop_sequence":
 		return "stop_sequence"
-	case "max_tokens":
+	case "max_tokens", "length":
 		return "max_tokens"
+	case "content_filter":
+		return "refusal"
 	case "tool_calls":
 		return "tool_use"
 	default:
@@ -0,0 +1,50 @@
+package transformer
+
+import (
+	"github.com/phosae/llms/dto/openai"
+)
+
+// OpenAIUsageChunk returns the chunk that ends an OpenAI stream when
+// stream_options.include_usage is set: the stream totals and an empty,
+// non-null choices array.
+func OpenAIUsageChunk(template *openai.ChatCompletionStreamResponse, usage *openai.Usage) *openai.ChatCompletionStreamResponse {
+	return &openai.ChatCompletionStreamResponse{
+		ID:                template.ID,
+		Object:            "chat.completion.chunk",
+		Created:           template.Created,
+		Model:             template.Model,
+		SystemFingerprint: template.SystemFingerprint,
+		Choices:           []openai.ChatCompletionStreamChoice{},
+		Usage:             usage,
+	}
+}
+
+// NormalizeOpenAIStreamUsage enforces the usage chunk shape of the OpenAI spec
+// on translated chunks: usage is removed from every content chunk and, only
+// when includeUsage is set, the last usage seen is appended as a separate
+// usage-only chunk. Usage reports are treated as cumulative.
+func NormalizeOpenAIStreamUsage(chunks []*openai.ChatCompletionStreamResponse, includeUsage bool) []*openai.ChatCompletionStreamResponse {
+	var tracker UsageTracker
+	var last *openai.ChatCompletionStreamResponse
+	seen := false
+
+	normalized := make([]*openai.ChatCompletionStreamResponse, 0, len(chunks)+1)
+	for _, chunk := range chunks {
+		if chunk.Usage != nil {
+			tracker.Observe(StreamUsageFromOpenAI(chunk.Usage), UsageCumulative)
+			seen = true
+		}
+		if len(chunk.Choices) == 0 {
+			// a usage-only chunk, re-emitted at the end
+			continue
+		}
+		chunk.Usage = nil
+		normalized = append(normalized, chunk)
+		last = chunk
+	}
+
+	if includeUsage && seen && last != nil {
+		normalized = append(normalized, OpenAIUsageChunk(last, tracker.Total().OpenAI()))
+	}
+	return normalized
+}
@@ -0,0 +1,46 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+func TestGeminiModelTurnsKeepPartOrder(t *testing.T) {
+	var req openai.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(`{"model":"gemini-2.5-flash","messages":[
+		{"role":"user","content":"weather?"},
+		{"role":"assistant","content":"let me check","tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Paris\"}"}},
+			{"id":"call_2","type":"function","function":{"name":"time","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":"sunny"},
+		{"role":"tool","tool_call_id":"call_2","content":"noon"}]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	var geminiReq gemini.GeminiChatRequest
+	if err := NewOpenAITransformer().Do(context.Background(), TransformerTypeRequest, &req, &geminiReq); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, content := range geminiReq.Contents {
+		if content.Role != "model" {
+			continue
+		}
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				got = append(got, "call "+part.FunctionCall.FunctionName)
+			default:
+				got = append(got, "text "+part.Text)
+			}
+		}
+	}
+	want := []string{"text let me check", "call weather", "call time"}
+	if !slices.Equal(got, want) {
+		t.Errorf("model parts = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phosae/llms/config"
+)
+
+// Options are per-request transformation policies. They travel on the context
+// so a shared registry can serve callers with different policies concurrently.
+type Options struct {
+	// Model overrides the model of the transformed request, bypassing config mappings.
+	Model string
+	// SafetyProfile selects a configured safety profile instead of the per-model one.
+	SafetyProfile string
+	// Strict makes a transformation fail on content the target cannot represent
+	// instead of silently dropping it.
+	Strict bool
+	// IncludeUsage mirrors stream_options.include_usage of the client request:
+	// OpenAI streams produced by a transformation end with a usage-only chunk
+	// only when it is set.
+	IncludeUsage bool
+	// EmulateJSONMode emulates response_format json_object for targets lacking
+	// it (Claude): the request gets a JSON-only instruction and the response is
+	// stripped of code fences and validated. Set it on both legs.
+	EmulateJSONMode bool
+	// PrefillJSON additionally prefills the assistant turn with "{" when
+	// emulating JSON mode. It is ignored when extended thinking is enabled.
+	PrefillJSON bool
+	// Chunking sets the granularity of the text deltas of translated streams.
+	Chunking ChunkPolicy
+	// GeminiBuiltinTools maps OpenAI functions named googleSearch or
+	// google_search and codeExecution or code_execution to the built-in
+	// tools of Gemini targets. Without it they are declared like any other
+	// function.
+	GeminiBuiltinTools bool
+	// Azure makes OpenAI targets Azure OpenAI deployments: the model of
+	// OpenAI requests is the deployment name, mapped by the config mappings
+	// of provider azure.
+	Azure bool
+	// Normalize sets what transformations between payloads of the same
+	// provider do; by default they copy.
+	Normalize NormalizePolicy
+	// StrictDecoding makes ParseRequest and ParseResponse reject keys the
+	// payload types do not know, instead of ignoring them.
+	StrictDecoding bool
+	// Caching sets what becomes of the cache_control hints of requests on
+	// targets not honoring them.
+	Caching CachePolicy
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a context carrying opts.
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// OptionsFromContext returns the options carried by ctx, or the zero Options.
+func OptionsFromContext(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	return opts
+}
+
+// TargetModel resolves the model name sent to the target provider:
+// Options.Model, else the model mapper or the config mappings of ctx.
+// Gemini and Vertex AI requests carry it in their path rather than their body.
+func TargetModel(ctx context.Context, target Provider, model string) string {
+	opts := OptionsFromContext(ctx)
+	if opts.Model != "" {
+		return opts.Model
+	}
+	if mapped, ok := mapModel(ctx, target, model); ok {
+		return mapped
+	}
+	if target == ProviderOpenAI && opts.Azure {
+		return azureDeployment(ctx, model)
+	}
+	return config.FromContext(ctx).MapModel(string(target), model)
+}
+
+// dropUnsupported reports content the target cannot represent: an error in
+// strict mode, a warning otherwise
+func dropUnsupported(ctx context.Context, path, format string, args ...any) error {
+	if OptionsFromContext(ctx).Strict {
+		return &TransformationError{
+			Type:    "unsupported_content",
+			Message: path + ": " + fmt.Sprintf(format, args...),
+		}
+	}
+	warn(ctx, WarningDroppedContent, path, format, args...)
+	return nil
+}
@@ -0,0 +1,191 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Decode problem kinds
+const (
+	DecodeUnknownField = "unknown_field"
+	DecodeTypeMismatch = "type_mismatch"
+	DecodeSyntax       = "syntax"
+)
+
+// DecodeProblem is one reason a payload failed to decode.
+type DecodeProblem struct {
+	// Path is the JSON pointer of the offending value, e.g. /messages/0/rol.
+	Path string `json:"path"`
+	// Kind is one of the Decode constants.
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// DecodeError lists the problems of a payload that failed to decode.
+type DecodeError struct {
+	Provider Provider        `json:"provider"`
+	Problems []DecodeProblem `json:"problems"`
+}
+
+func (e *DecodeError) Error() string {
+	var problems []string
+	for _, p := range e.Problems {
+		problems = append(problems, p.Path+": "+p.Message)
+	}
+	return fmt.Sprintf("invalid %s payload: %s", e.Provider, strings.Join(problems, "; "))
+}
+
+// ParseRequest decodes a request of provider. Type mismatches and syntax
+// errors fail with a *DecodeError; with Options.StrictDecoding so do
+// unrecognized keys, which are otherwise ignored, as by encoding/json.
+func ParseRequest(ctx context.Context, provider Provider, data []byte) (interface{}, error) {
+	return parsePayload(ctx, provider, TransformerTypeRequest, data)
+}
+
+// ParseResponse decodes a response of provider, as ParseRequest.
+func ParseResponse(ctx context.Context, provider Provider, data []byte) (interface{}, error) {
+	return parsePayload(ctx, provider, TransformerTypeResponse, data)
+}
+
+func parsePayload(ctx context.Context, provider Provider, typ TransformerType, data []byte) (interface{}, error) {
+	payload, err := NewPayload(provider, typ)
+	if err != nil {
+		return nil, err
+	}
+	strict := OptionsFromContext(ctx).StrictDecoding
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	err = dec.Decode(payload)
+	if err != nil && strict && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		// the decoder stops at the first unknown key and knows not where,
+		// the walk below finds them all
+		payload, _ = NewPayload(provider, typ)
+		err = json.Unmarshal(data, payload)
+	}
+	if err != nil {
+		return nil, &DecodeError{Provider: provider, Problems: []DecodeProblem{decodeProblem(err)}}
+	}
+	if !strict {
+		return payload, nil
+	}
+	// keys below fields with their own UnmarshalJSON, such as OpenAI
+	// messages, escape DisallowUnknownFields
+	var value any
+	json.Unmarshal(data, &value)
+	var problems []DecodeProblem
+	unknownFields(value, reflect.TypeOf(payload), "", &problems)
+	if len(problems) > 0 {
+		slices.SortFunc(problems, func(a, b DecodeProblem) int { return strings.Compare(a.Path, b.Path) })
+		return nil, &DecodeError{Provider: provider, Problems: problems}
+	}
+	return payload, nil
+}
+
+// decodeProblem describes an error of encoding/json.
+func decodeProblem(err error) DecodeProblem {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		path := ""
+		if typeErr.Field != "" {
+			path = "/" + strings.ReplaceAll(typeErr.Field, ".", "/")
+		}
+		return DecodeProblem{Path: path, Kind: DecodeTypeMismatch, Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return DecodeProblem{Kind: DecodeSyntax, Message: fmt.Sprintf("%v at offset %d", syntaxErr, syntaxErr.Offset)}
+	}
+	return DecodeProblem{Kind: DecodeSyntax, Message: err.Error()}
+}
+
+// unknownFields reports the keys of the objects of value that t, the Go type
+// it was decoded into, has no field for. Values of interface types and
+// raw messages take any keys.
+func unknownFields(value any, t reflect.Type, path string, problems *[]DecodeProblem) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for key, item := range v {
+				field, ok := fields[key]
+				if !ok {
+					for name, f := range fields {
+						if strings.EqualFold(name, key) {
+							field, ok = f, true
+							break
+						}
+					}
+				}
+				if !ok {
+					*problems = append(*problems, DecodeProblem{Path: path + "/" + pointerToken(key), Kind: DecodeUnknownField, Message: fmt.Sprintf("unknown field %q", key)})
+					continue
+				}
+				unknownFields(item, field, path+"/"+pointerToken(key), problems)
+			}
+		case reflect.Map:
+			for key, item := range v {
+				unknownFields(item, t.Elem(), path+"/"+pointerToken(key), problems)
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range v {
+				unknownFields(item, t.Elem(), path+"/"+strconv.Itoa(i), problems)
+			}
+		}
+	}
+}
+
+// jsonFields returns the types of the fields of struct t by JSON name,
+// those of embedded structs included.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for name, t := range jsonFields(embedded) {
+					if _, ok := fields[name]; !ok {
+						fields[name] = t
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// pointerToken escapes a key for a JSON pointer.
+func pointerToken(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
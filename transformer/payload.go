@@ -0,0 +1,68 @@
+package transformer
+
+import (
+	"fmt"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+// NewPayload returns a pointer to an empty provider DTO suitable for decoding
+// a payload of the given transformation type.
+func NewPayload(provider Provider, typ TransformerType) (interface{}, error) {
+	switch provider {
+	case ProviderOpenAI:
+		switch typ {
+		case TransformerTypeRequest:
+			return &openai.ChatCompletionRequest{}, nil
+		case TransformerTypeResponse:
+			return &openai.ChatCompletionResponse{}, nil
+		case TransformerTypeStream, TransformerTypeChunk:
+			return &openai.ChatCompletionStreamResponse{}, nil
+		}
+	case ProviderGemini:
+		switch typ {
+		case TransformerTypeRequest:
+			return &gemini.GeminiChatRequest{}, nil
+		case TransformerTypeResponse, TransformerTypeStream, TransformerTypeChunk:
+			return &gemini.GeminiChatResponse{}, nil
+		}
+	case ProviderClaude:
+		switch typ {
+		case TransformerTypeRequest:
+			return &claude.ClaudeRequest{}, nil
+		case TransformerTypeResponse, TransformerTypeStream, TransformerTypeChunk:
+			return &claude.ClaudeResponse{}, nil
+		}
+	case ProviderBedrock:
+		switch typ {
+		case TransformerTypeRequest:
+			return &bedrock.ConverseRequest{}, nil
+		case TransformerTypeResponse:
+			return &bedrock.ConverseResponse{}, nil
+		case TransformerTypeStream, TransformerTypeChunk:
+			return &bedrock.ConverseStreamEvent{}, nil
+		}
+	case ProviderVertexGemini:
+		switch typ {
+		case TransformerTypeRequest:
+			return &vertex.GenerateContentRequest{}, nil
+		case TransformerTypeResponse, TransformerTypeStream, TransformerTypeChunk:
+			return &vertex.GenerateContentResponse{}, nil
+		}
+	case ProviderOllama:
+		switch typ {
+		case TransformerTypeRequest:
+			return &ollama.ChatRequest{}, nil
+		case TransformerTypeResponse, TransformerTypeStream, TransformerTypeChunk:
+			return &ollama.ChatResponse{}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	return nil, fmt.Errorf("unsupported transformation type: %s", typ)
+}
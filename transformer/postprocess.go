@@ -0,0 +1,546 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Segment is a piece of response content seen by a ResponseRewriter: the
+// text of a part of a response, or one delta of a stream.
+type Segment struct {
+	// Kind is UnifiedPartText or UnifiedPartThinking.
+	Kind string
+	Text string
+	// Drop removes the segment. In Claude streams, where content comes in
+	// blocks, the whole block is dropped when the empty segment opening it
+	// is.
+	Drop bool
+}
+
+// ResponseEnd is how a response ends, rewriters may change it.
+type ResponseEnd struct {
+	// FinishReason uses OpenAI's values: stop, length, tool_calls or content_filter.
+	FinishReason string
+	// StopSequence is the stop sequence that ended the response, known
+	// only when the provider reports it, as Claude does.
+	StopSequence string
+	Usage        StreamUsage
+	// Footer is appended to the response as text.
+	Footer string
+}
+
+// ResponseRewriter rewrites the content of one response. Rewrite sees the
+// segments in order, then Finish sees the end of the response.
+type ResponseRewriter interface {
+	Rewrite(segment *Segment)
+	Finish(end *ResponseEnd)
+}
+
+// ResponseProcessor returns the rewriter of a new response.
+type ResponseProcessor func() ResponseRewriter
+
+// ResponsePipeline post-processes transformed responses and streams of any
+// provider with a chain of processors, run in order.
+type ResponsePipeline struct {
+	processors []ResponseProcessor
+}
+
+func NewResponsePipeline(processors ...ResponseProcessor) *ResponsePipeline {
+	return &ResponsePipeline{processors: processors}
+}
+
+// rewriterChain runs the rewriters of one response, or of one choice.
+type rewriterChain []ResponseRewriter
+
+func (p *ResponsePipeline) begin() rewriterChain {
+	chain := make(rewriterChain, len(p.processors))
+	for i, processor := range p.processors {
+		chain[i] = processor()
+	}
+	return chain
+}
+
+// rewrite returns the rewritten text, false when the segment is dropped.
+func (c rewriterChain) rewrite(kind, text string) (string, bool) {
+	segment := &Segment{Kind: kind, Text: text}
+	for _, rewriter := range c {
+		rewriter.Rewrite(segment)
+		if segment.Drop {
+			return "", false
+		}
+	}
+	return segment.Text, true
+}
+
+func (c rewriterChain) finish(finishReason, stopSequence string, usage StreamUsage) (string, string) {
+	end := &ResponseEnd{FinishReason: finishReason, StopSequence: stopSequence, Usage: usage}
+	for _, rewriter := range c {
+		rewriter.Finish(end)
+	}
+	return end.FinishReason, end.Footer
+}
+
+// Process rewrites resp, a response of provider, in place.
+func (p *ResponsePipeline) Process(ctx context.Context, provider Provider, resp interface{}) error {
+	u, err := ToUnifiedResponse(ctx, provider, resp)
+	if err != nil {
+		return err
+	}
+	var stopSequence string
+	if r, ok := resp.(*claude.ClaudeResponse); ok {
+		stopSequence = r.StopSequence
+	}
+	p.ProcessUnified(u, stopSequence)
+
+	// the response is encoded again from scratch, leaving nothing of the
+	// original content
+	fresh, err := NewPayload(provider, TransformerTypeResponse)
+	if err != nil {
+		return err
+	}
+	dst, src := reflect.ValueOf(resp), reflect.ValueOf(fresh)
+	if dst.Kind() != reflect.Pointer || dst.IsNil() || dst.Type() != src.Type() {
+		return fmt.Errorf("unsupported %s response type %T", provider, resp)
+	}
+	if err := FromUnifiedResponse(ctx, provider, u, fresh); err != nil {
+		return err
+	}
+	dst.Elem().Set(src.Elem())
+	return nil
+}
+
+// ProcessUnified rewrites u in place, each of its choices with rewriters of
+// its own. stopSequence is the stop sequence that ended the response, when
+// the provider reports it.
+func (p *ResponsePipeline) ProcessUnified(u *UnifiedResponse, stopSequence string) {
+	u.Message.Parts, u.FinishReason = p.processMessage(u.Message.Parts, u.FinishReason, stopSequence, u.Usage)
+	for i := range u.Alternatives {
+		choice := &u.Alternatives[i]
+		choice.Message.Parts, choice.FinishReason = p.processMessage(choice.Message.Parts, choice.FinishReason, "", u.Usage)
+	}
+}
+
+// processMessage runs a new chain over the parts of one message, returning
+// them rewritten with the finish reason.
+func (p *ResponsePipeline) processMessage(in []UnifiedPart, finishReason, stopSequence string, usage StreamUsage) ([]UnifiedPart, string) {
+	chain := p.begin()
+	var parts []UnifiedPart
+	for _, part := range in {
+		if part.Type == UnifiedPartText || part.Type == UnifiedPartThinking {
+			text, keep := chain.rewrite(part.Type, part.Text)
+			if !keep || text == "" {
+				continue
+			}
+			part.Text = text
+		}
+		parts = append(parts, part)
+	}
+	finishReason, footer := chain.finish(finishReason, stopSequence, usage)
+	if footer != "" {
+		// responses carry a single text part
+		if i := slices.IndexFunc(parts, func(p UnifiedPart) bool { return p.Type == UnifiedPartText }); i >= 0 {
+			parts[i].Text += footer
+		} else {
+			parts = append(parts, UnifiedPart{Type: UnifiedPartText, Text: footer})
+		}
+	}
+	return parts, finishReason
+}
+
+// ResponseStream applies a pipeline to the chunks of one stream.
+type ResponseStream struct {
+	pipeline *ResponsePipeline
+	// chains holds the rewriters of each OpenAI choice or Gemini candidate.
+	chains map[int]rewriterChain
+	// held are OpenAI chunks finishing choices, which wait for the usage
+	// chunk that follows them.
+	held []*openai.ChatCompletionStreamResponse
+
+	// Claude block indexes are renumbered around dropped blocks.
+	blocks    map[int]int
+	dropped   map[int]bool
+	nextBlock int
+	usage     claude.ClaudeUsage
+}
+
+// Stream returns the processor of one stream, of any provider.
+func (p *ResponsePipeline) Stream() *ResponseStream {
+	return &ResponseStream{
+		pipeline: p,
+		chains:   make(map[int]rewriterChain),
+		blocks:   make(map[int]int),
+		dropped:  make(map[int]bool),
+	}
+}
+
+func (s *ResponseStream) chain(index int) rewriterChain {
+	chain, ok := s.chains[index]
+	if !ok {
+		chain = s.pipeline.begin()
+		s.chains[index] = chain
+	}
+	return chain
+}
+
+// Process rewrites one chunk and returns the chunks to send in its place:
+// none, the chunk, or the chunk with injected ones. OpenAI chunks finishing
+// a choice are held until the usage chunk, so call Flush at the end of the
+// stream before [DONE].
+func (s *ResponseStream) Process(chunk interface{}) ([]interface{}, error) {
+	switch c := chunk.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		return s.processOpenAI(c), nil
+	case *claude.ClaudeResponse:
+		return s.processClaude(c), nil
+	case *gemini.GeminiChatResponse:
+		s.processGemini(c)
+		return []interface{}{c}, nil
+	default:
+		return nil, fmt.Errorf("unsupported stream chunk type %T", chunk)
+	}
+}
+
+// Flush returns the chunks still held, finished without usage.
+func (s *ResponseStream) Flush() []interface{} {
+	return s.releaseOpenAI(nil)
+}
+
+func (s *ResponseStream) processOpenAI(chunk *openai.ChatCompletionStreamResponse) []interface{} {
+	finishing := false
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		chain := s.chain(choice.Index)
+		if choice.Delta.Content != "" {
+			choice.Delta.Content, _ = chain.rewrite(UnifiedPartText, choice.Delta.Content)
+		}
+		if choice.Delta.ReasoningContent != "" {
+			choice.Delta.ReasoningContent, _ = chain.rewrite(UnifiedPartThinking, choice.Delta.ReasoningContent)
+		}
+		if choice.FinishReason != "" && choice.FinishReason != openai.FinishReasonNull {
+			finishing = true
+		}
+	}
+	if finishing {
+		s.held = append(s.held, chunk)
+		if chunk.Usage == nil {
+			return nil
+		}
+		return s.releaseOpenAI(chunk.Usage)
+	}
+	if chunk.Usage != nil && len(s.held) > 0 {
+		return append(s.releaseOpenAI(chunk.Usage), chunk)
+	}
+	return []interface{}{chunk}
+}
+
+// releaseOpenAI finishes the choices of the held chunks.
+func (s *ResponseStream) releaseOpenAI(usage *openai.Usage) []interface{} {
+	var total StreamUsage
+	if usage != nil {
+		total = StreamUsageFromOpenAI(usage)
+	}
+	var chunks []interface{}
+	for _, chunk := range s.held {
+		for i := range chunk.Choices {
+			choice := &chunk.Choices[i]
+			if choice.FinishReason == "" || choice.FinishReason == openai.FinishReasonNull {
+				continue
+			}
+			reason, footer := s.chain(choice.Index).finish(string(choice.FinishReason), "", total)
+			choice.FinishReason = openai.FinishReason(reason)
+			choice.Delta.Content += footer
+		}
+		chunks = append(chunks, chunk)
+	}
+	s.held = nil
+	return chunks
+}
+
+func (s *ResponseStream) processClaude(event *claude.ClaudeResponse) []interface{} {
+	chain := s.chain(0)
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			mergeClaudeUsage(&s.usage, event.Message.Usage)
+		}
+	case "content_block_start":
+		index := event.GetIndex()
+		if kind, ok := claudeSegmentKind(event.ContentBlock.Type); ok {
+			if _, keep := chain.rewrite(kind, ""); !keep {
+				s.dropped[index] = true
+				return nil
+			}
+		}
+		s.blocks[index] = s.nextBlock
+		s.nextBlock++
+		event.SetIndex(s.blocks[index])
+	case "content_block_delta":
+		index := event.GetIndex()
+		if s.dropped[index] {
+			return nil
+		}
+		event.SetIndex(s.blocks[index])
+		var text *string
+		kind := UnifiedPartText
+		switch event.Delta.Type {
+		case "text_delta":
+			text = event.Delta.Text
+		case "thinking_delta":
+			text, kind = &event.Delta.Thinking, UnifiedPartThinking
+		}
+		if text != nil {
+			rewritten, _ := chain.rewrite(kind, *text)
+			if rewritten == "" {
+				return nil
+			}
+			*text = rewritten
+		}
+	case "content_block_stop":
+		index := event.GetIndex()
+		if s.dropped[index] {
+			return nil
+		}
+		event.SetIndex(s.blocks[index])
+	case "message_delta":
+		mergeClaudeUsage(&s.usage, event.Usage)
+		if event.Delta == nil || event.Delta.StopReason == nil {
+			break
+		}
+		stopReason := string(stopReasonClaude2OpenAI(*event.Delta.StopReason))
+		var stopSequence string
+		if event.Delta.StopSequence != nil {
+			stopSequence = *event.Delta.StopSequence
+		}
+		reason, footer := chain.finish(stopReason, stopSequence, StreamUsageFromClaude(&s.usage))
+		if reason != stopReason {
+			claudeReason := stopReasonOpenAI2Claude(reason)
+			event.Delta.StopReason = &claudeReason
+		}
+		if footer == "" {
+			break
+		}
+		start := blockEvent("content_block_start", s.nextBlock, nil)
+		start.ContentBlock = &claude.ClaudeMediaMessage{Type: "text"}
+		start.ContentBlock.SetText("")
+		delta := blockEvent("content_block_delta", s.nextBlock, &claude.ClaudeMediaMessage{Type: "text_delta", Text: &footer})
+		stop := blockEvent("content_block_stop", s.nextBlock, nil)
+		s.nextBlock++
+		return []interface{}{start, delta, stop, event}
+	}
+	return []interface{}{event}
+}
+
+func claudeSegmentKind(blockType string) (string, bool) {
+	switch blockType {
+	case "text":
+		return UnifiedPartText, true
+	case "thinking", "redacted_thinking":
+		return UnifiedPartThinking, true
+	}
+	return "", false
+}
+
+func (s *ResponseStream) processGemini(chunk *gemini.GeminiChatResponse) {
+	for i := range chunk.Candidates {
+		candidate := &chunk.Candidates[i]
+		chain := s.chain(int(candidate.Index))
+		parts := candidate.Content.Parts[:0]
+		for _, part := range candidate.Content.Parts {
+			if part.Text != "" {
+				kind := UnifiedPartText
+				if part.Thought {
+					kind = UnifiedPartThinking
+				}
+				text, keep := chain.rewrite(kind, part.Text)
+				if !keep || (text == "" && part.ThoughtSignature == "") {
+					continue
+				}
+				part.Text = text
+			}
+			parts = append(parts, part)
+		}
+		candidate.Content.Parts = parts
+
+		if candidate.FinishReason == nil {
+			continue
+		}
+		finishReason := string(finishReasonGemini2OpenAI(*candidate.FinishReason))
+		reason, footer := chain.finish(finishReason, "", StreamUsageFromGemini(&chunk.UsageMetadata))
+		if reason != finishReason {
+			geminiReason := finishReasonOpenAI2Gemini(openai.FinishReason(reason))
+			candidate.FinishReason = &geminiReason
+		}
+		if footer != "" {
+			candidate.Content.Parts = append(candidate.Content.Parts, gemini.GeminiPart{Text: footer})
+		}
+	}
+}
+
+// rewriterFuncs adapts functions to a ResponseRewriter.
+type rewriterFuncs struct {
+	rewrite func(segment *Segment)
+	finish  func(end *ResponseEnd)
+}
+
+func (r rewriterFuncs) Rewrite(segment *Segment) {
+	if r.rewrite != nil {
+		r.rewrite(segment)
+	}
+}
+
+func (r rewriterFuncs) Finish(end *ResponseEnd) {
+	if r.finish != nil {
+		r.finish(end)
+	}
+}
+
+// StripReasoning drops thinking before the response reaches the client.
+func StripReasoning() ResponseProcessor {
+	return func() ResponseRewriter {
+		return rewriterFuncs{rewrite: func(segment *Segment) {
+			segment.Drop = segment.Kind == UnifiedPartThinking
+		}}
+	}
+}
+
+// Redact replaces the matches of pattern in text and thinking. In streams a
+// match is only found within a single delta.
+func Redact(pattern *regexp.Regexp, replacement string) ResponseProcessor {
+	return func() ResponseRewriter {
+		return rewriterFuncs{rewrite: func(segment *Segment) {
+			segment.Text = pattern.ReplaceAllString(segment.Text, replacement)
+		}}
+	}
+}
+
+// Footer appends the text returned by footer, e.g. a watermark or a usage
+// summary, to the end of each response.
+func Footer(footer func(end ResponseEnd) string) ResponseProcessor {
+	return func() ResponseRewriter {
+		return rewriterFuncs{finish: func(end *ResponseEnd) {
+			end.Footer += footer(*end)
+		}}
+	}
+}
+
+// Truncate cuts the text of each response after maxTokens tokens, as
+// counted by count, and reports the response as finished by length. A nil
+// count estimates four characters per token.
+func Truncate(maxTokens int, count func(text string) int) ResponseProcessor {
+	if count == nil {
+		count = approxTokens
+	}
+	return func() ResponseRewriter {
+		used, truncated := 0, false
+		return rewriterFuncs{
+			rewrite: func(segment *Segment) {
+				if segment.Kind != UnifiedPartText || segment.Text == "" {
+					return
+				}
+				if truncated {
+					segment.Text = ""
+					return
+				}
+				if n := count(segment.Text); used+n <= maxTokens {
+					used += n
+					return
+				}
+				runes := []rune(segment.Text)
+				keep := sort.Search(len(runes)+1, func(i int) bool {
+					return used+count(string(runes[:i])) > maxTokens
+				}) - 1
+				// cut between characters, not inside one
+				segment.Text = segment.Text[:TextBoundary(segment.Text, len(string(runes[:max(keep, 0)])))]
+				used, truncated = maxTokens, true
+			},
+			finish: func(end *ResponseEnd) {
+				if truncated {
+					end.FinishReason = string(openai.FinishReasonLength)
+				}
+			},
+		}
+	}
+}
+
+// StopSequenceMode is how StopSequences normalizes the stop sequence that
+// ended a response.
+type StopSequenceMode int
+
+const (
+	// TrimStopSequence removes a stop sequence ending the text, as the
+	// OpenAI, Claude and Gemini APIs do, for backends that include it.
+	TrimStopSequence StopSequenceMode = iota
+	// IncludeStopSequence appends the stop sequence to the text when the
+	// provider reports which one matched.
+	IncludeStopSequence
+)
+
+// StopSequences normalizes whether the text of responses finished by a stop
+// sequence ends with it, so parsers see the same text from every provider.
+// sequences are the stop sequences of the request. When trimming, streamed
+// text that may begin a stop sequence is held back until it cannot.
+func StopSequences(mode StopSequenceMode, sequences []string) ResponseProcessor {
+	return func() ResponseRewriter {
+		// held is the end of the text seen so far: when trimming, the text
+		// that may begin a stop sequence, otherwise the last tail bytes
+		held, tail := "", 64
+		for _, sequence := range sequences {
+			tail = max(tail, len(sequence))
+		}
+		return rewriterFuncs{
+			rewrite: func(segment *Segment) {
+				if segment.Kind != UnifiedPartText || segment.Text == "" {
+					return
+				}
+				text := held + segment.Text
+				if mode == IncludeStopSequence {
+					held = text[max(len(text)-tail, 0):]
+					return
+				}
+				n := stopSequencePrefix(text, sequences)
+				segment.Text, held = text[:len(text)-n], text[len(text)-n:]
+			},
+			finish: func(end *ResponseEnd) {
+				stopped := end.FinishReason == string(openai.FinishReasonStop)
+				if mode == IncludeStopSequence {
+					if stopped && end.StopSequence != "" && !strings.HasSuffix(held, end.StopSequence) {
+						end.Footer += end.StopSequence
+					}
+					return
+				}
+				if !stopped || (held != end.StopSequence && !slices.Contains(sequences, held)) {
+					end.Footer = held + end.Footer
+				}
+			},
+		}
+	}
+}
+
+// stopSequencePrefix returns the length of the longest end of text that
+// begins, or is, one of sequences.
+func stopSequencePrefix(text string, sequences []string) int {
+	longest := 0
+	for _, sequence := range sequences {
+		for n := min(len(sequence), len(text)); n > longest; n-- {
+			if strings.HasSuffix(text, sequence[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func approxTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
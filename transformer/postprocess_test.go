@@ -0,0 +1,104 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+func TestResponsePipelineProcessesEveryChoice(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor ResponseProcessor
+		want      []string
+	}{
+		{"redact", Redact(regexp.MustCompile(`secret-\d`), "[redacted]"), []string{"one [redacted]", "two [redacted]", "three [redacted]"}},
+		{"footer", Footer(func(ResponseEnd) string { return " --" }), []string{"one secret-1 --", "two secret-2 --", "three secret-3 --"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &openai.ChatCompletionResponse{
+				ID: "chatcmpl-1", Model: "gpt-4o",
+				Choices: []openai.ChatCompletionChoice{
+					{Index: 0, Message: openai.ChatCompletionMessage{Role: "assistant", Content: "one secret-1"}, FinishReason: "stop"},
+					{Index: 1, Message: openai.ChatCompletionMessage{Role: "assistant", Content: "two secret-2"}, FinishReason: "stop"},
+					{Index: 2, Message: openai.ChatCompletionMessage{Role: "assistant", Content: "three secret-3"}, FinishReason: "stop"},
+				},
+			}
+			if err := NewResponsePipeline(tt.processor).Process(context.Background(), ProviderOpenAI, resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Choices) != len(tt.want) {
+				t.Fatalf("got %d choices, want %d", len(resp.Choices), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := resp.Choices[i].Message.Content; got != want {
+					t.Errorf("choice %d: got %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResponsePipelineStripsReasoningOfEveryChoice(t *testing.T) {
+	u := &UnifiedResponse{
+		Message: UnifiedMessage{Role: "assistant", Parts: []UnifiedPart{{Type: UnifiedPartThinking, Text: "hmm"}, {Type: UnifiedPartText, Text: "a"}}},
+		Alternatives: []UnifiedChoice{
+			{Message: UnifiedMessage{Role: "assistant", Parts: []UnifiedPart{{Type: UnifiedPartThinking, Text: "hmm"}, {Type: UnifiedPartText, Text: "b"}}}},
+		},
+	}
+	NewResponsePipeline(StripReasoning()).ProcessUnified(u, "")
+	for i, message := range []UnifiedMessage{u.Message, u.Alternatives[0].Message} {
+		for _, part := range message.Parts {
+			if part.Type == UnifiedPartThinking || strings.Contains(part.Text, "hmm") {
+				t.Errorf("choice %d keeps thinking %q", i, part.Text)
+			}
+		}
+	}
+}
+
+func TestResponsePipelineRewritesResponsesOfEveryProvider(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		body     string
+	}{
+		{ProviderOpenAI, `{"id":"c1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"secret-1 done"},"finish_reason":"stop"}]}`},
+		{ProviderClaude, `{"id":"m1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"secret-1 done"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":2}}`},
+		{ProviderGemini, `{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"secret-1 done"}]},"finishReason":"STOP"}]}`},
+		{ProviderVertexGemini, `{"responseId":"r1","modelVersion":"gemini-2.5-pro","candidates":[{"index":0,"content":{"role":"model","parts":[` +
+			`{"executableCode":{"language":"PYTHON","code":"print(1)"}},{"codeExecutionResult":{"outcome":"OUTCOME_OK","output":"1"}},{"text":"secret-1 done"}]},"finishReason":"STOP"}]}`},
+		{ProviderBedrock, `{"output":{"message":{"role":"assistant","content":[{"text":"secret-1 done"}]}},"stopReason":"end_turn","usage":{"inputTokens":1,"outputTokens":2,"totalTokens":3}}`},
+		{ProviderOllama, `{"model":"llama3","message":{"role":"assistant","content":"secret-1 done"},"done":true,"done_reason":"stop"}`},
+	}
+	pipeline := NewResponsePipeline(Redact(regexp.MustCompile(`secret-\d`), "[redacted]"))
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			resp, err := NewPayload(tt.provider, TransformerTypeResponse)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.body), resp); err != nil {
+				t.Fatal(err)
+			}
+			if err := pipeline.Process(context.Background(), tt.provider, resp); err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(resp)
+			if strings.Contains(string(data), "secret-1") || strings.Count(string(data), "[redacted] done") != 1 {
+				t.Errorf("got %s, want the text redacted once", data)
+			}
+			if tt.provider == ProviderVertexGemini {
+				var out vertex.GenerateContentResponse
+				json.Unmarshal(data, &out)
+				if len(out.Candidates) != 1 || len(out.Candidates[0].Content.Parts) != 3 || out.Candidates[0].Content.Parts[0].ExecutableCode == nil {
+					t.Errorf("got %s, want one candidate keeping its code execution parts", data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package transformer
+
+import (
+	"context"
+
+	"github.com/phosae/llms/config"
+)
+
+// modelPreset fills the defaults configured for the target model into fields
+// the source request omits. Precedence, highest first: values set in the
+// source request, Options, the settings of the exact target model, the
+// Wildcard settings, built-in defaults such as Claude's max_tokens. Every
+// default filled in is traced as a WarningPresetApplied.
+type modelPreset struct {
+	ctx   context.Context
+	model string
+	config.ModelSettings
+}
+
+func presetFor(ctx context.Context, model string) modelPreset {
+	return modelPreset{ctx: ctx, model: model, ModelSettings: config.FromContext(ctx).ModelSettings(model)}
+}
+
+func (p modelPreset) trace(field string, value any) {
+	warn(p.ctx, WarningPresetApplied, field, "set to %v by the preset of model %q", value, p.model)
+}
+
+func (p modelPreset) temperature() *float64 {
+	if p.Temperature != nil {
+		p.trace("temperature", *p.Temperature)
+	}
+	return p.Temperature
+}
+
+func (p modelPreset) topP() *float64 {
+	if p.TopP != nil {
+		p.trace("top_p", *p.TopP)
+	}
+	return p.TopP
+}
+
+func (p modelPreset) maxTokens() int {
+	if p.MaxTokens > 0 {
+		p.trace("max_tokens", p.MaxTokens)
+	}
+	return p.MaxTokens
+}
+
+// safetyProfile resolves the safety profile name, Options first
+func (p modelPreset) safetyProfile() string {
+	if opts := OptionsFromContext(p.ctx); opts.SafetyProfile != "" {
+		return opts.SafetyProfile
+	}
+	if p.SafetyProfile != "" {
+		p.trace("safetySettings", p.SafetyProfile)
+	}
+	return p.SafetyProfile
+}
+
+// systemPrefix is prepended to the system prompt, whether or not the source
+// has one.
+func (p modelPreset) systemPrefix() string {
+	if p.SystemPrefix != "" {
+		warn(p.ctx, WarningPresetApplied, "system", "prefixed with %q by the preset of model %q", p.SystemPrefix, p.model)
+	}
+	return p.SystemPrefix
+}
@@ -0,0 +1,175 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Answer styles of grounded requests
+const (
+	AnswerStyleAbstractive = "abstractive"
+	AnswerStyleExtractive  = "extractive"
+	AnswerStyleVerbose     = "verbose"
+)
+
+// UnifiedRAGRequest asks for an answer grounded on Passages only. Rendered
+// for chat providers, the passages are stuffed into a system prompt that
+// asks for [id] citations, which ExtractCitations reads back.
+type UnifiedRAGRequest struct {
+	Request  *UnifiedRequest  `json:"request"`
+	Passages []UnifiedPassage `json:"passages"`
+	// AnswerStyle is abstractive, extractive or verbose, abstractive when empty.
+	AnswerStyle string `json:"answer_style,omitempty"`
+}
+
+type UnifiedPassage struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+var answerStyleInstructions = map[string]string{
+	AnswerStyleAbstractive: "Answer concisely, in your own words.",
+	AnswerStyleExtractive:  "Answer by quoting the relevant sentences of the passages verbatim.",
+	AnswerStyleVerbose:     "Answer in detail, covering everything the passages say about the question.",
+}
+
+// RAGRequestFromGemini converts a GenerateAnswer request with inline
+// passages. Requests using a semantic retriever need a Gemini corpus and
+// cannot be converted.
+func RAGRequestFromGemini(ctx context.Context, model string, req *gemini.GenerateAnswerRequest) (*UnifiedRAGRequest, error) {
+	if req.SemanticRetriever != nil {
+		return nil, &TransformationError{
+			Type:    "unsupported_content",
+			Message: "semanticRetriever: passages retrieved from a Gemini corpus cannot be rendered for other providers",
+		}
+	}
+	if req.InlinePassages == nil || len(req.InlinePassages.Passages) == 0 {
+		return nil, fmt.Errorf("inlinePassages is required")
+	}
+
+	u, err := ToUnifiedRequest(ctx, ProviderGemini, &gemini.GeminiChatRequest{
+		Contents:         req.Contents,
+		SafetySettings:   req.SafetySettings,
+		GenerationConfig: gemini.GeminiChatGenerationConfig{Temperature: req.Temperature},
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.Model = model
+
+	rag := &UnifiedRAGRequest{Request: u, AnswerStyle: strings.ToLower(req.AnswerStyle)}
+	for i, passage := range req.InlinePassages.Passages {
+		var texts []string
+		for j, part := range passage.Content.Parts {
+			if part.Text == "" {
+				if err := dropUnsupported(ctx, fmt.Sprintf("inlinePassages.passages[%d].content.parts[%d]", i, j), "only text passages can be grounded on"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			texts = append(texts, part.Text)
+		}
+		id := passage.ID
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		rag.Passages = append(rag.Passages, UnifiedPassage{ID: id, Text: strings.Join(texts, "\n")})
+	}
+	return rag, nil
+}
+
+// RenderRAGRequest converts rag to the chat request dst of provider, with
+// the passages and citation instructions as the first system message.
+func RenderRAGRequest(ctx context.Context, provider Provider, rag *UnifiedRAGRequest, dst interface{}) error {
+	if len(rag.Passages) == 0 {
+		return fmt.Errorf("no passages to ground the answer on")
+	}
+	instructions, ok := answerStyleInstructions[rag.AnswerStyle]
+	if !ok {
+		if rag.AnswerStyle != "" {
+			return fmt.Errorf("unsupported answer style: %s", rag.AnswerStyle)
+		}
+		instructions = answerStyleInstructions[AnswerStyleAbstractive]
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Answer the user's question using only the passages below. ")
+	prompt.WriteString("After each statement, cite the ids of the passages supporting it in square brackets, e.g. [" + rag.Passages[0].ID + "]. ")
+	prompt.WriteString("If the passages do not contain the answer, say that you cannot answer.\n")
+	prompt.WriteString(instructions)
+	prompt.WriteString("\n\nPassages:")
+	for _, passage := range rag.Passages {
+		fmt.Fprintf(&prompt, "\n\n[%s] %s", passage.ID, passage.Text)
+	}
+
+	u := *rag.Request
+	system := UnifiedMessage{Role: openai.ChatMessageRoleSystem, Parts: []UnifiedPart{{Type: UnifiedPartText, Text: prompt.String()}}}
+	u.Messages = append([]UnifiedMessage{system}, rag.Request.Messages...)
+	return FromUnifiedRequest(ctx, provider, &u, dst)
+}
+
+var citationMarker = regexp.MustCompile(`\s?\[([^\[\]]+)\]`)
+
+// ExtractCitations removes the [id] markers citing passages from text and
+// returns the cited passage ids in order of first citation. Brackets not
+// naming known passages are left in place.
+func ExtractCitations(text string, passages []UnifiedPassage) (string, []string) {
+	known := make(map[string]bool, len(passages))
+	for _, passage := range passages {
+		known[passage.ID] = true
+	}
+	var cited []string
+	answer := citationMarker.ReplaceAllStringFunc(text, func(marker string) string {
+		inner := citationMarker.FindStringSubmatch(marker)[1]
+		ids := strings.Split(inner, ",")
+		for i := range ids {
+			ids[i] = strings.TrimSpace(ids[i])
+			if !known[ids[i]] {
+				return marker
+			}
+		}
+		for _, id := range ids {
+			if !slices.Contains(cited, id) {
+				cited = append(cited, id)
+			}
+		}
+		return ""
+	})
+	return answer, cited
+}
+
+// GenerateAnswerFromUnified converts the chat answer to rag into a
+// GenerateAnswer response, attributing the answer to the cited passages.
+func GenerateAnswerFromUnified(ctx context.Context, rag *UnifiedRAGRequest, resp *UnifiedResponse) (*gemini.GenerateAnswerResponse, error) {
+	var texts []string
+	for _, part := range resp.Message.Parts {
+		if part.Type == UnifiedPartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	answer, cited := ExtractCitations(strings.Join(texts, ""), rag.Passages)
+
+	finishReason := finishReasonOpenAI2Gemini(openai.FinishReason(resp.FinishReason))
+	candidate := &gemini.GeminiChatCandidate{
+		Content:      gemini.GeminiChatContent{Role: "model", Parts: []gemini.GeminiPart{{Text: answer}}},
+		FinishReason: &finishReason,
+	}
+	for _, id := range cited {
+		i := slices.IndexFunc(rag.Passages, func(p UnifiedPassage) bool { return p.ID == id })
+		candidate.GroundingAttributions = append(candidate.GroundingAttributions, gemini.GroundingAttribution{
+			SourceID: gemini.AttributionSourceID{GroundingPassage: &gemini.GroundingPassageID{PassageID: id}},
+			Content:  gemini.GeminiChatContent{Parts: []gemini.GeminiPart{{Text: rag.Passages[i].Text}}},
+		})
+	}
+	if len(cited) == 0 {
+		warn(ctx, WarningUncitedAnswer, "answer", "the answer cites none of the passages")
+	}
+	return &gemini.GenerateAnswerResponse{Answer: candidate}, nil
+}
@@ -0,0 +1,577 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Realtime bridges translate text and tool-call events between the OpenAI
+// Realtime API and the Gemini Live API. Both are stateful sessions, so a
+// bridge is created per connection and is not safe for concurrent use; each
+// direction of a connection must be translated in order. Audio and other
+// events without an equivalent go through the drop policy.
+
+// RealtimeToLive serves an OpenAI Realtime client from a Gemini Live upstream.
+type RealtimeToLive struct {
+	// Model is used for the Live setup when the client's session.update
+	// names none, or when the client sends other events first.
+	Model string
+
+	setupSent bool
+	// resumed is set once a tool response resumed generation, Live needs no
+	// further turn for the client's next response.create
+	resumed   bool
+	callNames map[string]string
+	seq       int
+	response  *realtimeResponse
+	usage     *gemini.GeminiUsageMetadata
+}
+
+type realtimeResponse struct {
+	id     string
+	output []openai.RealtimeItem
+	// text is the index of the open message item in output, -1 if none
+	text int
+}
+
+// ClientEvent translates an event of the Realtime client to Live messages.
+func (b *RealtimeToLive) ClientEvent(ctx context.Context, event *openai.RealtimeEvent) ([]*gemini.LiveClientMessage, error) {
+	if event.Type == openai.RealtimeSessionUpdate {
+		if b.setupSent {
+			return nil, dropUnsupported(ctx, "session", "a Gemini Live session cannot be updated after setup")
+		}
+		setup, err := liveSetupFromRealtime(ctx, event.Session, b.Model)
+		if err != nil {
+			return nil, err
+		}
+		b.setupSent = true
+		return []*gemini.LiveClientMessage{{Setup: setup}}, nil
+	}
+
+	var messages []*gemini.LiveClientMessage
+	if !b.setupSent {
+		b.setupSent = true
+		messages = append(messages, &gemini.LiveClientMessage{Setup: &gemini.LiveSetup{Model: liveModel(b.Model)}})
+	}
+
+	switch event.Type {
+	case openai.RealtimeItemCreate:
+		if event.Item == nil {
+			return nil, fmt.Errorf("%s without item", event.Type)
+		}
+		message, err := b.liveItem(ctx, event.Item)
+		if err != nil {
+			return nil, err
+		}
+		if message != nil {
+			messages = append(messages, message)
+		}
+	case openai.RealtimeResponseCreate:
+		if event.Response != nil && event.Response.Instructions != "" {
+			if err := dropUnsupported(ctx, "response.instructions", "Gemini Live takes instructions only at setup"); err != nil {
+				return nil, err
+			}
+		}
+		if b.resumed {
+			b.resumed = false
+			break
+		}
+		messages = append(messages, &gemini.LiveClientMessage{ClientContent: &gemini.LiveClientContent{TurnComplete: true}})
+	default:
+		if err := dropUnsupported(ctx, event.Type, "the event has no Gemini Live equivalent"); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+func (b *RealtimeToLive) liveItem(ctx context.Context, item *openai.RealtimeItem) (*gemini.LiveClientMessage, error) {
+	switch item.Type {
+	case "message":
+		role := "user"
+		switch item.Role {
+		case "assistant":
+			role = "model"
+		case "system":
+			if err := dropUnsupported(ctx, "item.role", "Gemini Live takes system instructions only at setup"); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		}
+		var parts []gemini.GeminiPart
+		for i, part := range item.Content {
+			switch part.Type {
+			case "input_text", "text":
+				parts = append(parts, gemini.GeminiPart{Text: part.Text})
+			default:
+				if err := dropUnsupported(ctx, fmt.Sprintf("item.content[%d]", i), "%s content is not bridged", part.Type); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(parts) == 0 {
+			return nil, nil
+		}
+		return &gemini.LiveClientMessage{ClientContent: &gemini.LiveClientContent{
+			Turns: []gemini.GeminiChatContent{{Role: role, Parts: parts}},
+		}}, nil
+	case "function_call":
+		b.rememberCall(item.CallID, item.Name)
+		var args any
+		if item.Arguments != "" {
+			if err := json.Unmarshal([]byte(item.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments of function call %s: %w", item.CallID, err)
+			}
+		}
+		return &gemini.LiveClientMessage{ClientContent: &gemini.LiveClientContent{
+			Turns: []gemini.GeminiChatContent{{Role: "model", Parts: []gemini.GeminiPart{
+				{FunctionCall: &gemini.FunctionCall{FunctionName: item.Name, Arguments: args}},
+			}}},
+		}}, nil
+	case "function_call_output":
+		b.resumed = true
+		return &gemini.LiveClientMessage{ToolResponse: &gemini.LiveToolResponse{
+			FunctionResponses: []gemini.LiveFunctionResponse{{
+				ID:       item.CallID,
+				Name:     b.callNames[item.CallID],
+				Response: geminiFunctionResponse(item.Output),
+			}},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported item type: %s", item.Type)
+	}
+}
+
+func (b *RealtimeToLive) rememberCall(id, name string) {
+	if b.callNames == nil {
+		b.callNames = make(map[string]string)
+	}
+	b.callNames[id] = name
+}
+
+// ServerMessage translates a message of the Live upstream to Realtime events.
+func (b *RealtimeToLive) ServerMessage(ctx context.Context, message *gemini.LiveServerMessage) ([]*openai.RealtimeEvent, error) {
+	var events []*openai.RealtimeEvent
+	if message.SetupComplete != nil {
+		events = append(events, &openai.RealtimeEvent{
+			Type:    openai.RealtimeSessionCreated,
+			Session: &openai.RealtimeSession{ID: b.nextID("sess"), Model: b.Model, Modalities: []string{"text"}},
+		})
+	}
+	if message.UsageMetadata != nil {
+		b.usage = message.UsageMetadata
+	}
+
+	if message.ToolCall != nil {
+		events = append(events, b.startResponse()...)
+		for _, call := range message.ToolCall.FunctionCalls {
+			b.rememberCall(call.ID, call.Name)
+			events = append(events, b.addFunctionCall(call)...)
+		}
+		// Realtime responses end with their function calls, the client
+		// answers with outputs and a new response.create
+		events = append(events, b.finishResponse("completed")...)
+	}
+	if message.ToolCallCancellation != nil {
+		if err := dropUnsupported(ctx, "toolCallCancellation", "OpenAI Realtime cannot cancel function calls"); err != nil {
+			return nil, err
+		}
+	}
+
+	if content := message.ServerContent; content != nil {
+		if content.ModelTurn != nil {
+			for i, part := range content.ModelTurn.Parts {
+				switch {
+				case part.Thought:
+				case part.Text != "":
+					events = append(events, b.startResponse()...)
+					events = append(events, b.addText(part.Text)...)
+				default:
+					if err := dropUnsupported(ctx, fmt.Sprintf("serverContent.modelTurn.parts[%d]", i), "only text parts are bridged"); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		switch {
+		case content.Interrupted:
+			events = append(events, b.finishResponse("cancelled")...)
+		case content.TurnComplete:
+			events = append(events, b.finishResponse("completed")...)
+		}
+	}
+	return events, nil
+}
+
+func (b *RealtimeToLive) nextID(prefix string) string {
+	b.seq++
+	return fmt.Sprintf("%s_%d", prefix, b.seq)
+}
+
+func (b *RealtimeToLive) startResponse() []*openai.RealtimeEvent {
+	if b.response != nil {
+		return nil
+	}
+	b.response = &realtimeResponse{id: b.nextID("resp"), text: -1}
+	return []*openai.RealtimeEvent{{
+		Type:     openai.RealtimeResponseCreated,
+		Response: &openai.RealtimeResponse{ID: b.response.id, Object: "realtime.response", Status: "in_progress"},
+	}}
+}
+
+func (b *RealtimeToLive) addText(text string) []*openai.RealtimeEvent {
+	r := b.response
+	var events []*openai.RealtimeEvent
+	if r.text < 0 {
+		r.text = len(r.output)
+		item := openai.RealtimeItem{ID: b.nextID("item"), Type: "message", Status: "in_progress", Role: "assistant"}
+		r.output = append(r.output, item)
+		events = append(events,
+			&openai.RealtimeEvent{Type: openai.RealtimeOutputItemAdded, ResponseID: r.id, OutputIndex: r.text, Item: &item},
+			&openai.RealtimeEvent{Type: openai.RealtimeContentPartAdded, ResponseID: r.id, ItemID: item.ID, OutputIndex: r.text,
+				Part: &openai.RealtimeContentPart{Type: "text"}},
+		)
+		r.output[r.text].Content = []openai.RealtimeContentPart{{Type: "text"}}
+	}
+	item := &r.output[r.text]
+	item.Content[0].Text += text
+	return append(events, &openai.RealtimeEvent{
+		Type: openai.RealtimeTextDelta, ResponseID: r.id, ItemID: item.ID, OutputIndex: r.text, Delta: text,
+	})
+}
+
+func (b *RealtimeToLive) closeText() []*openai.RealtimeEvent {
+	r := b.response
+	if r.text < 0 {
+		return nil
+	}
+	index := r.text
+	r.text = -1
+	item := &r.output[index]
+	item.Status = "completed"
+	part := item.Content[0]
+	done := *item
+	return []*openai.RealtimeEvent{
+		{Type: openai.RealtimeTextDone, ResponseID: r.id, ItemID: item.ID, OutputIndex: index, Text: part.Text},
+		{Type: openai.RealtimeContentPartDone, ResponseID: r.id, ItemID: item.ID, OutputIndex: index, Part: &part},
+		{Type: openai.RealtimeOutputItemDone, ResponseID: r.id, OutputIndex: index, Item: &done},
+	}
+}
+
+func (b *RealtimeToLive) addFunctionCall(call gemini.LiveFunctionCall) []*openai.RealtimeEvent {
+	events := b.closeText()
+	r := b.response
+	arguments := "{}"
+	if call.Args != nil {
+		arguments = toJSONString(call.Args)
+	}
+	index := len(r.output)
+	item := openai.RealtimeItem{
+		ID: b.nextID("item"), Type: "function_call", Status: "completed",
+		CallID: call.ID, Name: call.Name, Arguments: arguments,
+	}
+	r.output = append(r.output, item)
+	added := item
+	added.Status, added.Arguments = "in_progress", ""
+	return append(events,
+		&openai.RealtimeEvent{Type: openai.RealtimeOutputItemAdded, ResponseID: r.id, OutputIndex: index, Item: &added},
+		&openai.RealtimeEvent{Type: openai.RealtimeFunctionArgumentsDone, ResponseID: r.id, ItemID: item.ID, OutputIndex: index,
+			CallID: call.ID, Name: call.Name, Arguments: arguments},
+		&openai.RealtimeEvent{Type: openai.RealtimeOutputItemDone, ResponseID: r.id, OutputIndex: index, Item: &item},
+	)
+}
+
+func (b *RealtimeToLive) finishResponse(status string) []*openai.RealtimeEvent {
+	if b.response == nil {
+		b.usage = nil
+		return nil
+	}
+	events := b.closeText()
+	response := &openai.RealtimeResponse{
+		ID:     b.response.id,
+		Object: "realtime.response",
+		Status: status,
+		Output: b.response.output,
+	}
+	if b.usage != nil {
+		usage := StreamUsageFromGemini(b.usage)
+		response.Usage = &openai.RealtimeUsage{
+			TotalTokens:  usage.InputTokens + usage.OutputTokens,
+			InputTokens:  usage.InputTokens,
+			OutputTokens: usage.OutputTokens,
+		}
+	}
+	b.response, b.usage = nil, nil
+	return append(events, &openai.RealtimeEvent{Type: openai.RealtimeResponseDone, Response: response})
+}
+
+func liveModel(model string) string {
+	if model != "" && !strings.HasPrefix(model, "models/") {
+		return "models/" + model
+	}
+	return model
+}
+
+func liveSetupFromRealtime(ctx context.Context, session *openai.RealtimeSession, model string) (*gemini.LiveSetup, error) {
+	if session == nil {
+		return &gemini.LiveSetup{Model: liveModel(model)}, nil
+	}
+	if session.Model != "" {
+		model = session.Model
+	}
+	setup := &gemini.LiveSetup{Model: liveModel(model), GenerationConfig: &gemini.GeminiChatGenerationConfig{
+		Temperature: session.Temperature,
+		// Live answers in a single modality, text is the one bridged
+		ResponseModalities: []string{"TEXT"},
+	}}
+	if tokens, ok := session.MaxResponseOutputTokens.(float64); ok {
+		setup.GenerationConfig.MaxOutputTokens = uint(tokens)
+	}
+	if slices.Contains(session.Modalities, "audio") {
+		if err := dropUnsupported(ctx, "session.modalities", "audio output is not bridged"); err != nil {
+			return nil, err
+		}
+	}
+	if session.Voice != "" {
+		if err := dropUnsupported(ctx, "session.voice", "audio output is not bridged"); err != nil {
+			return nil, err
+		}
+	}
+	if session.Instructions != "" {
+		setup.SystemInstruction = &gemini.GeminiChatContent{Parts: []gemini.GeminiPart{{Text: session.Instructions}}}
+	}
+
+	var declarations []openai.FunctionDefinition
+	for i, tool := range session.Tools {
+		if tool.Type != "function" {
+			if err := dropUnsupported(ctx, fmt.Sprintf("session.tools[%d]", i), "only function tools are bridged"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		declarations = append(declarations, openai.FunctionDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+	if len(declarations) > 0 {
+		setup.Tools = []gemini.GeminiChatTool{{FunctionDeclarations: declarations}}
+	}
+	if session.ToolChoice != nil && session.ToolChoice != "auto" {
+		if err := dropUnsupported(ctx, "session.tool_choice", "Gemini Live sessions always choose tools automatically"); err != nil {
+			return nil, err
+		}
+	}
+	return setup, nil
+}
+
+// LiveToRealtime serves a Gemini Live client from an OpenAI Realtime upstream.
+type LiveToRealtime struct {
+	setupDone bool
+}
+
+// ClientMessage translates a message of the Live client to Realtime events.
+func (b *LiveToRealtime) ClientMessage(ctx context.Context, message *gemini.LiveClientMessage) ([]*openai.RealtimeEvent, error) {
+	var events []*openai.RealtimeEvent
+	switch {
+	case message.Setup != nil:
+		session, err := realtimeSessionFromLive(ctx, message.Setup)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &openai.RealtimeEvent{Type: openai.RealtimeSessionUpdate, Session: session})
+	case message.ClientContent != nil:
+		for i, turn := range message.ClientContent.Turns {
+			items, err := realtimeItemsFromLive(ctx, fmt.Sprintf("clientContent.turns[%d]", i), turn)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				events = append(events, &openai.RealtimeEvent{Type: openai.RealtimeItemCreate, Item: item})
+			}
+		}
+		if message.ClientContent.TurnComplete {
+			events = append(events, &openai.RealtimeEvent{Type: openai.RealtimeResponseCreate})
+		}
+	case message.RealtimeInput != nil:
+		input := message.RealtimeInput
+		if input.Audio != nil || input.Video != nil || len(input.MediaChunks) > 0 {
+			if err := dropUnsupported(ctx, "realtimeInput", "only text input is bridged"); err != nil {
+				return nil, err
+			}
+		}
+		if input.Text != "" {
+			events = append(events,
+				&openai.RealtimeEvent{Type: openai.RealtimeItemCreate, Item: &openai.RealtimeItem{
+					Type: "message", Role: "user",
+					Content: []openai.RealtimeContentPart{{Type: "input_text", Text: input.Text}},
+				}},
+				&openai.RealtimeEvent{Type: openai.RealtimeResponseCreate},
+			)
+		}
+	case message.ToolResponse != nil:
+		for _, response := range message.ToolResponse.FunctionResponses {
+			events = append(events, &openai.RealtimeEvent{Type: openai.RealtimeItemCreate, Item: &openai.RealtimeItem{
+				Type:   "function_call_output",
+				CallID: response.ID,
+				Output: geminiFunctionResponseContent(response.Response),
+			}})
+		}
+		// Live resumes generation on tool responses, Realtime waits to be asked
+		events = append(events, &openai.RealtimeEvent{Type: openai.RealtimeResponseCreate})
+	}
+	return events, nil
+}
+
+func realtimeSessionFromLive(ctx context.Context, setup *gemini.LiveSetup) (*openai.RealtimeSession, error) {
+	session := &openai.RealtimeSession{
+		Model:      strings.TrimPrefix(setup.Model, "models/"),
+		Modalities: []string{"text"},
+	}
+	if genConfig := setup.GenerationConfig; genConfig != nil {
+		session.Temperature = genConfig.Temperature
+		if genConfig.MaxOutputTokens > 0 {
+			session.MaxResponseOutputTokens = genConfig.MaxOutputTokens
+		}
+		if slices.ContainsFunc(genConfig.ResponseModalities, func(m string) bool { return m != "TEXT" }) {
+			if err := dropUnsupported(ctx, "setup.generationConfig.responseModalities", "only text output is bridged"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if setup.SystemInstruction != nil {
+		var texts []string
+		for _, part := range setup.SystemInstruction.Parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		session.Instructions = strings.Join(texts, "\n")
+	}
+	for i, tool := range setup.Tools {
+		if tool.FunctionDeclarations == nil {
+			if err := dropUnsupported(ctx, fmt.Sprintf("setup.tools[%d]", i), "only function declarations are bridged"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		declarations, err := geminiFunctionDeclarations(tool)
+		if err != nil {
+			return nil, fmt.Errorf("invalid functionDeclarations in setup.tools[%d]: %w", i, err)
+		}
+		for _, declaration := range declarations {
+			session.Tools = append(session.Tools, openai.RealtimeTool{
+				Type:        "function",
+				Name:        declaration.Name,
+				Description: declaration.Description,
+				Parameters:  declaration.Parameters,
+			})
+		}
+	}
+	return session, nil
+}
+
+func realtimeItemsFromLive(ctx context.Context, path string, turn gemini.GeminiChatContent) ([]*openai.RealtimeItem, error) {
+	role, textType := "user", "input_text"
+	if turn.Role == "model" {
+		role, textType = "assistant", "text"
+	}
+	var items []*openai.RealtimeItem
+	var message *openai.RealtimeItem
+	for i, part := range turn.Parts {
+		switch {
+		case part.Text != "" && !part.Thought:
+			if message == nil {
+				message = &openai.RealtimeItem{Type: "message", Role: role}
+				items = append(items, message)
+			}
+			message.Content = append(message.Content, openai.RealtimeContentPart{Type: textType, Text: part.Text})
+		case part.FunctionCall != nil:
+			call, err := parseGeminiToolCall(&part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, &openai.RealtimeItem{
+				Type: "function_call", CallID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments,
+			})
+			message = nil
+		case part.Thought:
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("%s.parts[%d]", path, i), "only text and function calls are bridged"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return items, nil
+}
+
+// ServerEvent translates an event of the Realtime upstream to Live messages.
+func (b *LiveToRealtime) ServerEvent(ctx context.Context, event *openai.RealtimeEvent) ([]*gemini.LiveServerMessage, error) {
+	switch event.Type {
+	case openai.RealtimeSessionUpdated:
+		if b.setupDone {
+			return nil, nil
+		}
+		b.setupDone = true
+		return []*gemini.LiveServerMessage{{SetupComplete: &struct{}{}}}, nil
+	case openai.RealtimeTextDelta, openai.RealtimeOutputTextDelta:
+		return []*gemini.LiveServerMessage{{ServerContent: &gemini.LiveServerContent{
+			ModelTurn: &gemini.GeminiChatContent{Role: "model", Parts: []gemini.GeminiPart{{Text: event.Delta}}},
+		}}}, nil
+	case openai.RealtimeFunctionArgumentsDone:
+		var args any
+		if event.Arguments != "" {
+			if err := json.Unmarshal([]byte(event.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments of function call %s: %w", event.CallID, err)
+			}
+		}
+		return []*gemini.LiveServerMessage{{ToolCall: &gemini.LiveToolCall{
+			FunctionCalls: []gemini.LiveFunctionCall{{ID: event.CallID, Name: event.Name, Args: args}},
+		}}}, nil
+	case openai.RealtimeResponseDone:
+		return liveMessagesFromResponse(event.Response)
+	case openai.RealtimeErrorEvent:
+		if event.Error == nil {
+			return nil, &TransformationError{Type: "realtime_error", Message: "upstream reported an error"}
+		}
+		return nil, &TransformationError{Type: event.Error.Type, Message: event.Error.Message}
+	case openai.RealtimeAudioDelta:
+		return nil, dropUnsupported(ctx, event.Type, "audio output is not bridged")
+	}
+	// Lifecycle events without a Live counterpart
+	return nil, nil
+}
+
+func liveMessagesFromResponse(response *openai.RealtimeResponse) ([]*gemini.LiveServerMessage, error) {
+	if response == nil {
+		return nil, nil
+	}
+	message := &gemini.LiveServerMessage{}
+	if response.Usage != nil {
+		usage := StreamUsage{InputTokens: response.Usage.InputTokens, OutputTokens: response.Usage.OutputTokens}.Gemini()
+		message.UsageMetadata = &usage
+	}
+	switch response.Status {
+	case "failed":
+		return nil, &TransformationError{Type: "realtime_response_failed", Message: fmt.Sprintf("response %s failed", response.ID)}
+	case "cancelled":
+		message.ServerContent = &gemini.LiveServerContent{Interrupted: true}
+	default:
+		// Live completes the turn only after the tool responses
+		calls := slices.ContainsFunc(response.Output, func(item openai.RealtimeItem) bool { return item.Type == "function_call" })
+		if !calls {
+			message.ServerContent = &gemini.LiveServerContent{GenerationComplete: true, TurnComplete: true}
+		}
+	}
+	if message.ServerContent == nil && message.UsageMetadata == nil {
+		return nil, nil
+	}
+	return []*gemini.LiveServerMessage{message}, nil
+}
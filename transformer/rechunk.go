@@ -0,0 +1,123 @@
+package transformer
+
+import (
+	"time"
+
+	"github.com/phosae/llms/dto/openai"
+)
+
+// ChunkPolicy controls the granularity of the text deltas of a translated
+// stream. Providers chunk very differently, from a token per chunk to whole
+// sentences; the zero ChunkPolicy keeps the boundaries of the source.
+type ChunkPolicy struct {
+	// MinBytes coalesces consecutive text deltas of a choice until they hold
+	// at least MinBytes.
+	MinBytes int
+	// Interval releases coalesced text once it has been held that long, even
+	// short of MinBytes. With MinBytes zero, deltas are coalesced per Interval.
+	// Held text is released by the next chunk of the stream, there is no timer.
+	Interval time.Duration
+	// MaxBytes splits text deltas larger than MaxBytes, at boundaries SplitText
+	// allows.
+	MaxBytes int
+}
+
+func (p ChunkPolicy) coalesces() bool {
+	return p.MinBytes > 0 || p.Interval > 0
+}
+
+// rechunker applies a ChunkPolicy to an OpenAI chunk stream. Only chunks
+// carrying nothing but the text or reasoning delta of a single choice are
+// coalesced or split; any other chunk first releases the held text.
+type rechunker struct {
+	policy ChunkPolicy
+	held   *openai.ChatCompletionStreamResponse
+	since  time.Time
+}
+
+func (r *rechunker) transform(chunk *openai.ChatCompletionStreamResponse) []*openai.ChatCompletionStreamResponse {
+	text, ok := textDelta(chunk)
+	if !ok {
+		return append(r.flush(), chunk)
+	}
+	if !r.policy.coalesces() {
+		return r.split(chunk)
+	}
+
+	var out []*openai.ChatCompletionStreamResponse
+	if r.held != nil && !sameTextDelta(r.held, chunk) {
+		out = r.flush()
+	}
+	if r.held == nil {
+		held := *chunk
+		held.Choices = []openai.ChatCompletionStreamChoice{chunk.Choices[0]}
+		r.held, r.since = &held, time.Now()
+	} else {
+		delta := &r.held.Choices[0].Delta
+		delta.Content += chunk.Choices[0].Delta.Content
+		delta.ReasoningContent += chunk.Choices[0].Delta.ReasoningContent
+	}
+	text, _ = textDelta(r.held)
+	if (r.policy.MinBytes > 0 && len(text) >= r.policy.MinBytes) ||
+		(r.policy.Interval > 0 && time.Since(r.since) >= r.policy.Interval) {
+		out = append(out, r.flush()...)
+	}
+	return out
+}
+
+// flush releases the held text.
+func (r *rechunker) flush() []*openai.ChatCompletionStreamResponse {
+	if r.held == nil {
+		return nil
+	}
+	held := r.held
+	r.held = nil
+	return r.split(held)
+}
+
+// split splits the text delta of chunk at MaxBytes.
+func (r *rechunker) split(chunk *openai.ChatCompletionStreamResponse) []*openai.ChatCompletionStreamResponse {
+	text, _ := textDelta(chunk)
+	if r.policy.MaxBytes <= 0 || len(text) <= r.policy.MaxBytes {
+		return []*openai.ChatCompletionStreamResponse{chunk}
+	}
+	var out []*openai.ChatCompletionStreamResponse
+	for _, piece := range SplitText(text, r.policy.MaxBytes) {
+		c := *chunk
+		c.Choices = []openai.ChatCompletionStreamChoice{chunk.Choices[0]}
+		if c.Choices[0].Delta.Content != "" {
+			c.Choices[0].Delta.Content = piece
+		} else {
+			c.Choices[0].Delta.ReasoningContent = piece
+		}
+		out = append(out, &c)
+	}
+	return out
+}
+
+// textDelta returns the text of a chunk carrying only the content or
+// reasoning delta of a single choice.
+func textDelta(chunk *openai.ChatCompletionStreamResponse) (string, bool) {
+	if chunk.Usage != nil || len(chunk.Choices) != 1 {
+		return "", false
+	}
+	choice := chunk.Choices[0]
+	delta := choice.Delta
+	if choice.FinishReason != "" || choice.Logprobs != nil || delta.Role != "" || delta.Refusal != "" ||
+		delta.FunctionCall != nil || len(delta.ToolCalls) > 0 || len(delta.ReasoningDetails) > 0 {
+		return "", false
+	}
+	switch {
+	case delta.Content != "" && delta.ReasoningContent == "":
+		return delta.Content, true
+	case delta.ReasoningContent != "" && delta.Content == "":
+		return delta.ReasoningContent, true
+	}
+	return "", false
+}
+
+// sameTextDelta reports whether the text deltas of a and b can be joined.
+func sameTextDelta(a, b *openai.ChatCompletionStreamResponse) bool {
+	x, y := a.Choices[0], b.Choices[0]
+	return x.Index == y.Index && (x.Delta.Content == "") == (y.Delta.Content == "")
+}
@@ -0,0 +1,86 @@
+package transformer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// SplitText splits text into stream deltas of at most size bytes. Pieces
+// never end inside a UTF-8 sequence, so no surrogate pair is split once
+// converted to UTF-16, nor between a character and the combining marks,
+// variation selectors, emoji modifiers or joined emoji extending it. A
+// character longer than size makes a longer piece. size <= 0 keeps text
+// whole.
+func SplitText(text string, size int) []string {
+	if text == "" {
+		return nil
+	}
+	if size <= 0 {
+		return []string{text}
+	}
+	var pieces []string
+	for len(text) > size {
+		n := TextBoundary(text, size)
+		if n == 0 {
+			n = nextTextBoundary(text, size)
+		}
+		pieces = append(pieces, text[:n])
+		text = text[n:]
+	}
+	if text != "" {
+		pieces = append(pieces, text)
+	}
+	return pieces
+}
+
+// TextBoundary returns the largest n at most max where text can be cut as
+// SplitText does, 0 when there is none.
+func TextBoundary(text string, max int) int {
+	if max >= len(text) {
+		return len(text)
+	}
+	for i := max; i > 0; i-- {
+		if isTextBoundary(text, i) {
+			return i
+		}
+	}
+	return 0
+}
+
+func nextTextBoundary(text string, min int) int {
+	for i := min + 1; i < len(text); i++ {
+		if isTextBoundary(text, i) {
+			return i
+		}
+	}
+	return len(text)
+}
+
+func isTextBoundary(text string, i int) bool {
+	if !utf8.RuneStart(text[i]) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	if extendsCharacter(r) {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	return prev != zeroWidthJoiner
+}
+
+// extendsCharacter reports whether r modifies the character before it.
+func extendsCharacter(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner:
+		return true
+	case 0xfe00 <= r && r <= 0xfe0f, 0xe0100 <= r && r <= 0xe01ef:
+		// variation selectors
+		return true
+	case 0x1f3fb <= r && r <= 0x1f3ff:
+		// emoji skin tone modifiers
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
@@ -0,0 +1,299 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// OpenAIStreamAccumulator folds the chunks of an OpenAI stream into the
+// response a non-streaming call would have returned: content, refusal and
+// reasoning deltas are concatenated, reasoning details completed with the
+// reasoning they sign, tool call argument fragments merged by
+// call index and the usage chunk kept. Log probabilities are not kept.
+type OpenAIStreamAccumulator struct {
+	resp    openai.ChatCompletionResponse
+	choices map[int]*openai.ChatCompletionChoice
+	// calls maps the tool call indexes of each choice to ToolCalls positions.
+	calls map[int]map[int]int
+	// reasoned is the length of the reasoning of each choice its reasoning
+	// details cover.
+	reasoned map[int]int
+}
+
+func NewOpenAIStreamAccumulator() *OpenAIStreamAccumulator {
+	return &OpenAIStreamAccumulator{
+		resp:     openai.ChatCompletionResponse{Object: "chat.completion"},
+		choices:  make(map[int]*openai.ChatCompletionChoice),
+		calls:    make(map[int]map[int]int),
+		reasoned: make(map[int]int),
+	}
+}
+
+// Add folds chunk into the response.
+func (a *OpenAIStreamAccumulator) Add(chunk *openai.ChatCompletionStreamResponse) error {
+	if chunk.ID != "" {
+		a.resp.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		a.resp.Model = chunk.Model
+	}
+	if chunk.Created != 0 {
+		a.resp.Created = chunk.Created
+	}
+	if chunk.SystemFingerprint != "" {
+		a.resp.SystemFingerprint = chunk.SystemFingerprint
+	}
+	a.resp.PromptFilterResults = append(a.resp.PromptFilterResults, chunk.PromptFilterResults...)
+	if chunk.Usage != nil {
+		a.resp.Usage = *chunk.Usage
+	}
+
+	for _, c := range chunk.Choices {
+		if c.Index < 0 {
+			return fmt.Errorf("choice index %d", c.Index)
+		}
+		choice := a.choices[c.Index]
+		if choice == nil {
+			choice = &openai.ChatCompletionChoice{Index: c.Index, Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}}
+			a.choices[c.Index] = choice
+			a.calls[c.Index] = make(map[int]int)
+		}
+		message := &choice.Message
+		if c.Delta.Role != "" {
+			message.Role = c.Delta.Role
+		}
+		message.Content += c.Delta.Content
+		message.Refusal += c.Delta.Refusal
+		message.ReasoningContent += c.Delta.ReasoningContent
+		for _, detail := range c.Delta.ReasoningDetails {
+			if detail.Type == openai.ReasoningDetailTypeText && detail.Text == "" {
+				// the text of a signed block streamed as reasoning_content
+				detail.Text = message.ReasoningContent[a.reasoned[c.Index]:]
+			}
+			message.ReasoningDetails = append(message.ReasoningDetails, detail)
+			a.reasoned[c.Index] = len(message.ReasoningContent)
+		}
+		if call := c.Delta.FunctionCall; call != nil {
+			if message.FunctionCall == nil {
+				message.FunctionCall = &openai.FunctionCall{}
+			}
+			message.FunctionCall.Name += call.Name
+			message.FunctionCall.Arguments += call.Arguments
+		}
+		for _, call := range c.Delta.ToolCalls {
+			index := len(message.ToolCalls)
+			if call.Index != nil {
+				index = *call.Index
+			} else if call.ID == "" && index > 0 {
+				// a fragment without index continues the last call
+				index--
+			}
+			pos, ok := a.calls[c.Index][index]
+			if !ok {
+				pos = len(message.ToolCalls)
+				a.calls[c.Index][index] = pos
+				message.ToolCalls = append(message.ToolCalls, openai.ToolCall{Type: openai.ToolTypeFunction})
+			}
+			merged := &message.ToolCalls[pos]
+			if call.ID != "" {
+				merged.ID = call.ID
+			}
+			if call.Type != "" {
+				merged.Type = call.Type
+			}
+			merged.Function.Name += call.Function.Name
+			merged.Function.Arguments += call.Function.Arguments
+			if call.ExtraContent != nil {
+				merged.ExtraContent = call.ExtraContent
+			}
+		}
+		if c.FinishReason != "" {
+			choice.FinishReason = c.FinishReason
+		}
+		if contentFilterAnnotated(c.ContentFilterResults) {
+			choice.ContentFilterResults = c.ContentFilterResults
+		}
+	}
+	return nil
+}
+
+// Response returns the response accumulated so far, choices in index order.
+func (a *OpenAIStreamAccumulator) Response() *openai.ChatCompletionResponse {
+	resp := a.resp
+	resp.Choices = nil
+	for _, index := range slices.Sorted(maps.Keys(a.choices)) {
+		choice := *a.choices[index]
+		choice.Message.ToolCalls = slices.Clone(choice.Message.ToolCalls)
+		resp.Choices = append(resp.Choices, choice)
+	}
+	return &resp
+}
+
+// ClaudeStreamAccumulator folds the events of a Claude stream into the
+// message a non-streaming call would have returned, checking the event
+// lifecycle with a ClaudeMessageState. Tool inputs are parsed when their
+// block stops; those max_tokens cuts off are left empty.
+type ClaudeStreamAccumulator struct {
+	state *ClaudeMessageState
+	resp  claude.ClaudeResponse
+	// partial is the error of the first tool input that is no JSON, which
+	// only a max_tokens stop excuses.
+	partial error
+}
+
+func NewClaudeStreamAccumulator() *ClaudeStreamAccumulator {
+	return &ClaudeStreamAccumulator{
+		state: NewClaudeMessageState(),
+		resp:  claude.ClaudeResponse{Type: "message", Role: "assistant"},
+	}
+}
+
+// Add folds event into the message. Error events are returned as errors.
+func (a *ClaudeStreamAccumulator) Add(event *claude.ClaudeResponse) error {
+	if event.Type == "error" && event.Error != nil {
+		return fmt.Errorf("stream error: %s: %s", event.Error.Type, event.Error.Message)
+	}
+	if err := a.state.Apply(event); err != nil {
+		return err
+	}
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil && event.Message.Role != "" {
+			a.resp.Role = event.Message.Role
+		}
+	case "content_block_start":
+		a.resp.Content = append(a.resp.Content, *event.ContentBlock)
+	case "content_block_delta":
+		block := &a.resp.Content[event.GetIndex()]
+		switch event.Delta.Type {
+		case "text_delta":
+			text := ""
+			if block.Text != nil {
+				text = *block.Text
+			}
+			if event.Delta.Text != nil {
+				text += *event.Delta.Text
+			}
+			block.Text = &text
+		case "thinking_delta":
+			block.Thinking += event.Delta.Thinking
+		}
+	case "content_block_stop":
+		index := event.GetIndex()
+		state := a.state.Block(index)
+		block := &a.resp.Content[index]
+		if state.Signature != "" {
+			block.Signature = state.Signature
+		}
+		if state.ToolInput != "" {
+			var input any
+			if err := json.Unmarshal([]byte(state.ToolInput), &input); err != nil {
+				if a.partial == nil {
+					a.partial = fmt.Errorf("content block %d: invalid tool input: %w", index, err)
+				}
+				input = map[string]any{}
+			}
+			block.Input = input
+		}
+	case "message_delta":
+		if a.partial != nil && a.state.StopReason != "max_tokens" {
+			return a.partial
+		}
+		if event.Delta != nil && event.Delta.StopSequence != nil {
+			a.resp.StopSequence = *event.Delta.StopSequence
+		}
+		if event.Container != nil {
+			a.resp.Container = event.Container
+		}
+	}
+	return nil
+}
+
+// Response returns the message accumulated so far.
+func (a *ClaudeStreamAccumulator) Response() *claude.ClaudeResponse {
+	resp := a.resp
+	resp.Id, resp.Model, resp.StopReason = a.state.MessageID, a.state.Model, a.state.StopReason
+	resp.Content = slices.Clone(a.resp.Content)
+	usage := a.state.Usage
+	resp.Usage = &usage
+	return &resp
+}
+
+// GeminiStreamAccumulator folds the chunks of a Gemini stream into the
+// response generateContent would have returned. Consecutive text parts of
+// a candidate are joined, thoughts apart from answers; function calls
+// arrive whole. Usage metadata is cumulative, the last report is kept.
+type GeminiStreamAccumulator struct {
+	resp       gemini.GeminiChatResponse
+	candidates map[int64]*gemini.GeminiChatCandidate
+}
+
+func NewGeminiStreamAccumulator() *GeminiStreamAccumulator {
+	return &GeminiStreamAccumulator{candidates: make(map[int64]*gemini.GeminiChatCandidate)}
+}
+
+// Add folds chunk into the response.
+func (a *GeminiStreamAccumulator) Add(chunk *gemini.GeminiChatResponse) error {
+	if chunk.UsageMetadata.TotalTokenCount > 0 {
+		a.resp.UsageMetadata = chunk.UsageMetadata
+	}
+	if chunk.PromptFeedback.BlockReason != "" || len(chunk.PromptFeedback.SafetyRatings) > 0 {
+		a.resp.PromptFeedback = chunk.PromptFeedback
+	}
+	for _, c := range chunk.Candidates {
+		candidate := a.candidates[c.Index]
+		if candidate == nil {
+			candidate = &gemini.GeminiChatCandidate{Index: c.Index, Content: gemini.GeminiChatContent{Role: "model"}}
+			a.candidates[c.Index] = candidate
+		}
+		if c.Content.Role != "" {
+			candidate.Content.Role = c.Content.Role
+		}
+		for _, part := range c.Content.Parts {
+			parts := candidate.Content.Parts
+			if n := len(parts); n > 0 && geminiTextPart(parts[n-1]) && geminiTextPart(part) && parts[n-1].Thought == part.Thought {
+				parts[n-1].Text += part.Text
+				if part.ThoughtSignature != "" {
+					parts[n-1].ThoughtSignature = part.ThoughtSignature
+				}
+				continue
+			}
+			candidate.Content.Parts = append(candidate.Content.Parts, part)
+		}
+		if c.FinishReason != nil {
+			candidate.FinishReason = c.FinishReason
+		}
+		if len(c.SafetyRatings) > 0 {
+			candidate.SafetyRatings = c.SafetyRatings
+		}
+		if len(c.GroundingMetadata) > 0 {
+			candidate.GroundingMetadata = c.GroundingMetadata
+		}
+	}
+	return nil
+}
+
+// geminiTextPart reports whether part holds text only.
+func geminiTextPart(part gemini.GeminiPart) bool {
+	return part.Text != "" && part.InlineData == nil && part.FunctionCall == nil && part.FunctionResponse == nil &&
+		part.FileData == nil && part.ExecutableCode == nil && part.CodeExecutionResult == nil
+}
+
+// Response returns the response accumulated so far, candidates in index
+// order.
+func (a *GeminiStreamAccumulator) Response() *gemini.GeminiChatResponse {
+	resp := a.resp
+	resp.Candidates = nil
+	for _, index := range slices.Sorted(maps.Keys(a.candidates)) {
+		candidate := *a.candidates[index]
+		candidate.Content.Parts = slices.Clone(candidate.Content.Parts)
+		resp.Candidates = append(resp.Candidates, candidate)
+	}
+	return &resp
+}
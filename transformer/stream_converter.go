@@ -0,0 +1,302 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+// StreamConverter converts the stream of one provider into the stream of
+// another, pivoting through OpenAI chunks: Claude events are read with a
+// ClaudeStreamState and written with a StreamTransformer, Bedrock events
+// likewise with a BedrockStreamState and a BedrockStreamTransformer, and
+// Gemini and Vertex AI Gemini chunks with a GeminiStreamState and a
+// GeminiStreamTransformer. Ollama objects are read with an
+// OllamaStreamState and written with an OllamaStreamTransformer. OpenAI targets end with a usage chunk only when
+// the IncludeUsage option is set. The Chunking option
+// coalesces or splits the text deltas of the pivot; streams of the same
+// provider pass through untouched.
+//
+// One StreamConverter serves a single stream.
+type StreamConverter struct {
+	// Model names the stream when the source sends no model, as Gemini.
+	Model string
+
+	ctx            context.Context
+	source, target Provider
+	includeUsage   bool
+	id             string
+	created        int64
+
+	rechunk    rechunker
+	claudeIn   *ClaudeStreamState
+	claudeOut  *StreamTransformer
+	geminiIn   *GeminiStreamState
+	geminiOut  *GeminiStreamTransformer
+	bedrockIn  *BedrockStreamState
+	bedrockOut *BedrockStreamTransformer
+	ollamaIn   *OllamaStreamState
+	ollamaOut  *OllamaStreamTransformer
+}
+
+// NewStreamConverter returns a converter for one stream. ctx carries the
+// options and warnings of the stream.
+func NewStreamConverter(ctx context.Context, source, target Provider) *StreamConverter {
+	includeUsage := OptionsFromContext(ctx).IncludeUsage
+	// the pivot always carries usage, targets other than OpenAI report it
+	opts := OptionsFromContext(ctx)
+	opts.IncludeUsage = true
+	ctx = WithOptions(ctx, opts)
+
+	c := &StreamConverter{
+		ctx:          ctx,
+		source:       source,
+		target:       target,
+		includeUsage: includeUsage,
+		rechunk:      rechunker{policy: opts.Chunking},
+		id:           fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano()),
+		created:      time.Now().Unix(),
+	}
+	switch source {
+	case ProviderClaude:
+		c.claudeIn = NewClaudeStreamState(ctx, true)
+	case ProviderGemini, ProviderVertexGemini:
+		c.geminiIn = NewGeminiStreamState(ctx, true)
+	case ProviderBedrock:
+		c.bedrockIn = NewBedrockStreamState(ctx, true)
+	case ProviderOllama:
+		c.ollamaIn = NewOllamaStreamState(ctx, true)
+	}
+	switch target {
+	case ProviderClaude:
+		c.claudeOut = NewStreamTransformer(ctx)
+	case ProviderGemini, ProviderVertexGemini:
+		c.geminiOut = NewGeminiStreamTransformer(ctx)
+	case ProviderBedrock:
+		c.bedrockOut = NewBedrockStreamTransformer(ctx)
+	case ProviderOllama:
+		c.ollamaOut = NewOllamaStreamTransformer(ctx)
+	}
+	return c
+}
+
+// Transform converts one chunk of the source into the chunks of the target
+// it completes, none when it carries nothing new.
+func (c *StreamConverter) Transform(chunk interface{}) ([]interface{}, error) {
+	if c.source == c.target {
+		return []interface{}{chunk}, nil
+	}
+	var chunks []*openai.ChatCompletionStreamResponse
+	switch src := chunk.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		chunks = append(chunks, src)
+	case *claude.ClaudeResponse:
+		if c.claudeIn == nil {
+			return nil, fmt.Errorf("unexpected %s chunk in a %s stream", ProviderClaude, c.source)
+		}
+		var err error
+		if chunks, err = c.claudeIn.Transform(src); err != nil {
+			return nil, err
+		}
+	case *gemini.GeminiChatResponse:
+		if c.geminiIn == nil {
+			return nil, fmt.Errorf("unexpected %s chunk in a %s stream", ProviderGemini, c.source)
+		}
+		var err error
+		if chunks, err = c.geminiIn.Transform(src); err != nil {
+			return nil, err
+		}
+	case *vertex.GenerateContentResponse:
+		if c.geminiIn == nil {
+			return nil, fmt.Errorf("unexpected %s chunk in a %s stream", ProviderVertexGemini, c.source)
+		}
+		var err error
+		if chunks, err = c.geminiIn.Transform(&src.GeminiChatResponse); err != nil {
+			return nil, err
+		}
+		for _, chunk := range chunks {
+			vertexChunkIdentity(src, chunk)
+		}
+	case *bedrock.ConverseStreamEvent:
+		if c.bedrockIn == nil {
+			return nil, fmt.Errorf("unexpected %s chunk in a %s stream", ProviderBedrock, c.source)
+		}
+		var err error
+		if chunks, err = c.bedrockIn.Transform(src); err != nil {
+			return nil, err
+		}
+	case *ollama.ChatResponse:
+		if c.ollamaIn == nil {
+			return nil, fmt.Errorf("unexpected %s chunk in a %s stream", ProviderOllama, c.source)
+		}
+		var err error
+		if chunks, err = c.ollamaIn.Transform(src); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported stream chunk type %T", chunk)
+	}
+
+	var rechunked []*openai.ChatCompletionStreamResponse
+	for _, chunk := range chunks {
+		rechunked = append(rechunked, c.rechunk.transform(chunk)...)
+	}
+	return c.emit(rechunked)
+}
+
+// emit converts pivot chunks into the chunks of the target.
+func (c *StreamConverter) emit(chunks []*openai.ChatCompletionStreamResponse) ([]interface{}, error) {
+	var out []interface{}
+	for _, chunk := range chunks {
+		if chunk.ID == "" {
+			chunk.ID, chunk.Object, chunk.Created = c.id, "chat.completion.chunk", c.created
+		}
+		if chunk.Created == 0 {
+			chunk.Created = c.created
+		}
+		if chunk.Model == "" {
+			chunk.Model = c.Model
+		}
+		for _, choice := range chunk.Choices {
+			restoreToolCalls(c.ctx, choice.Delta.ToolCalls)
+		}
+		switch c.target {
+		case ProviderOpenAI:
+			if len(chunk.Choices) == 0 && chunk.Usage != nil && !c.includeUsage {
+				continue
+			}
+			out = append(out, chunk)
+		case ProviderClaude:
+			events, err := c.claudeOut.Transform(chunk)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range events {
+				out = append(out, event)
+			}
+		case ProviderGemini, ProviderVertexGemini:
+			geminiChunks, err := c.geminiOut.Transform(chunk)
+			if err != nil {
+				return nil, err
+			}
+			for _, geminiChunk := range geminiChunks {
+				out = append(out, c.geminiChunk(geminiChunk))
+			}
+		case ProviderBedrock:
+			events, err := c.bedrockOut.Transform(chunk)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range events {
+				out = append(out, event)
+			}
+		case ProviderOllama:
+			objects, err := c.ollamaOut.Transform(chunk)
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range objects {
+				out = append(out, object)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Finish ends the stream, returning the chunks closing the target stream.
+func (c *StreamConverter) Finish() ([]interface{}, error) {
+	if c.source == c.target {
+		return nil, nil
+	}
+	out, err := c.emit(c.rechunk.flush())
+	if err != nil {
+		return nil, err
+	}
+	switch c.target {
+	case ProviderClaude:
+		events, err := c.claudeOut.Finish()
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			out = append(out, event)
+		}
+	case ProviderGemini, ProviderVertexGemini:
+		chunks, err := c.geminiOut.Finish()
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range chunks {
+			out = append(out, c.geminiChunk(chunk))
+		}
+	case ProviderBedrock:
+		events, err := c.bedrockOut.Finish()
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			out = append(out, event)
+		}
+	case ProviderOllama:
+		objects, err := c.ollamaOut.Finish()
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			out = append(out, object)
+		}
+	}
+	return out, nil
+}
+
+// All converts the chunks of the source as they are yielded, followed by
+// the chunks of Finish. The first error, of chunks or of the conversion,
+// is yielded and ends the iteration.
+func (c *StreamConverter) All(chunks iter.Seq2[interface{}, error]) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		for chunk, err := range chunks {
+			var out []interface{}
+			if err == nil {
+				out, err = c.Transform(chunk)
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, chunk := range out {
+				if !yield(chunk, nil) {
+					return
+				}
+			}
+		}
+		out, err := c.Finish()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, chunk := range out {
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+// geminiChunk returns chunk as a chunk of the target, wrapped for Vertex AI
+// with the identity of the stream.
+func (c *StreamConverter) geminiChunk(chunk *gemini.GeminiChatResponse) interface{} {
+	if c.target != ProviderVertexGemini {
+		return chunk
+	}
+	resp := &vertex.GenerateContentResponse{GeminiChatResponse: *chunk}
+	resp.ResponseID, resp.ModelVersion = c.id, c.Model
+	return resp
+}
@@ -0,0 +1,146 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+// geminiCallChunks streams two function calls in chunks of their own, the
+// way Gemini does, and finishes in a third.
+var geminiCallChunks = []string{
+	`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"functionCall":{"name":"a","args":{"x":1}}}]}}]}`,
+	`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"functionCall":{"name":"b","args":{"y":2}}}]}}]}`,
+	`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":7,"totalTokenCount":12}}`,
+}
+
+// convertStream runs chunks through a StreamConverter from source to
+// target, finishing the stream.
+func convertStream(t *testing.T, source, target Provider, chunks []interface{}) []interface{} {
+	t.Helper()
+	c := NewStreamConverter(context.Background(), source, target)
+	var out []interface{}
+	for _, chunk := range chunks {
+		converted, err := c.Transform(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, converted...)
+	}
+	rest, err := c.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(out, rest...)
+}
+
+func geminiChunks(t *testing.T, raw []string) []interface{} {
+	t.Helper()
+	var chunks []interface{}
+	for _, s := range raw {
+		var chunk gemini.GeminiChatResponse
+		if err := json.Unmarshal([]byte(s), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, &chunk)
+	}
+	return chunks
+}
+
+// checkOpenAICalls checks that the calls a and b streamed in separate
+// chunks keep indexes of their own and finish the stream only at its end.
+func checkOpenAICalls(t *testing.T, out []interface{}) {
+	t.Helper()
+	acc := NewOpenAIStreamAccumulator()
+	for i, chunk := range out {
+		oaiChunk := chunk.(*openai.ChatCompletionStreamResponse)
+		for _, choice := range oaiChunk.Choices {
+			if choice.FinishReason == openai.FinishReasonToolCalls && i < 2 {
+				t.Errorf("chunk %d finishes with tool_calls before the stream ends", i)
+			}
+		}
+		if err := acc.Add(oaiChunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp := acc.Response()
+	if len(resp.Choices) != 1 {
+		t.Fatalf("got %d choices, want 1", len(resp.Choices))
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != openai.FinishReasonToolCalls {
+		t.Errorf("got finish reason %q, want tool_calls", choice.FinishReason)
+	}
+	calls := choice.Message.ToolCalls
+	if len(calls) != 2 || calls[0].Function.Name != "a" || calls[1].Function.Name != "b" {
+		t.Fatalf("got calls %+v, want a and b", calls)
+	}
+	if calls[0].Function.Arguments != `{"x":1}` || calls[1].Function.Arguments != `{"y":2}` {
+		t.Errorf("got arguments %q and %q", calls[0].Function.Arguments, calls[1].Function.Arguments)
+	}
+}
+
+// checkClaudeCalls checks that the calls a and b become tool_use blocks
+// of their own.
+func checkClaudeCalls(t *testing.T, out []interface{}) {
+	t.Helper()
+	acc := NewClaudeStreamAccumulator()
+	for _, event := range out {
+		if err := acc.Add(event.(*claude.ClaudeResponse)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp := acc.Response()
+	if resp.StopReason != "tool_use" {
+		t.Errorf("got stop reason %q, want tool_use", resp.StopReason)
+	}
+	var names []string
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" {
+			names = append(names, block.Name)
+		}
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("got tool_use blocks %q, want a and b", names)
+	}
+}
+
+func TestStreamConverterGeminiCallsInSeparateChunks(t *testing.T) {
+	chunks := geminiChunks(t, geminiCallChunks)
+	t.Run("openai", func(t *testing.T) {
+		checkOpenAICalls(t, convertStream(t, ProviderGemini, ProviderOpenAI, chunks))
+	})
+	t.Run("claude", func(t *testing.T) {
+		checkClaudeCalls(t, convertStream(t, ProviderGemini, ProviderClaude, chunks))
+	})
+}
+
+func TestStreamConverterVertexCallsInSeparateChunks(t *testing.T) {
+	var chunks []interface{}
+	for _, s := range geminiCallChunks {
+		var chunk vertex.GenerateContentResponse
+		if err := json.Unmarshal([]byte(s), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		chunk.ResponseID, chunk.ModelVersion = "resp-1", "gemini-2.5-pro"
+		chunks = append(chunks, &chunk)
+	}
+	t.Run("openai", func(t *testing.T) {
+		out := convertStream(t, ProviderVertexGemini, ProviderOpenAI, chunks)
+		for _, chunk := range out {
+			if c := chunk.(*openai.ChatCompletionStreamResponse); c.ID != "resp-1" || c.Model != "gemini-2.5-pro" {
+				t.Errorf("got id %q and model %q, want the response's", c.ID, c.Model)
+			}
+		}
+		checkOpenAICalls(t, out)
+	})
+	t.Run("claude", func(t *testing.T) {
+		checkClaudeCalls(t, convertStream(t, ProviderVertexGemini, ProviderClaude, chunks))
+	})
+}
@@ -0,0 +1,282 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// StreamDone is the data sentinel terminating an OpenAI stream.
+const StreamDone = "[DONE]"
+
+// StreamEvent is one server-sent event of a provider stream.
+type StreamEvent struct {
+	// Event is the SSE event name, empty for unnamed events.
+	Event string `json:"event,omitempty"`
+	// Data is the event payload, usually a JSON document.
+	Data string `json:"data"`
+}
+
+// StreamViolation is a protocol rule broken by a stream.
+type StreamViolation struct {
+	// Index of the offending event, -1 when the violation concerns the stream as a whole.
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+func (v StreamViolation) String() string {
+	if v.Index < 0 {
+		return "stream: " + v.Message
+	}
+	return fmt.Sprintf("event %d: %s", v.Index, v.Message)
+}
+
+// LintStream checks that events obey the provider's streaming protocol and
+// returns every violation found. It is meant for debugging backends that claim
+// compatibility with one of the official APIs.
+func LintStream(provider Provider, events []StreamEvent) ([]StreamViolation, error) {
+	l := &streamLinter{}
+	switch provider {
+	case ProviderOpenAI:
+		l.lintOpenAI(events)
+	case ProviderGemini:
+		l.lintGemini(events)
+	case ProviderClaude:
+		l.lintClaude(events)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	return l.violations, nil
+}
+
+type streamLinter struct {
+	violations []StreamViolation
+}
+
+func (l *streamLinter) report(index int, format string, args ...any) {
+	l.violations = append(l.violations, StreamViolation{Index: index, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *streamLinter) lintOpenAI(events []StreamEvent) {
+	var id string
+	done := false
+	usageSeen := false
+	finished := make(map[int]bool)
+	toolCalls := make(map[[2]int]bool) // choice index, tool call index
+
+	for i, ev := range events {
+		if done {
+			l.report(i, "event after %s", StreamDone)
+			continue
+		}
+		if ev.Data == StreamDone {
+			done = true
+			continue
+		}
+
+		var chunk openai.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			l.report(i, "invalid chunk: %v", err)
+			continue
+		}
+		if chunk.Object != "chat.completion.chunk" {
+			l.report(i, "object must be chat.completion.chunk, got %q", chunk.Object)
+		}
+		if id == "" {
+			id = chunk.ID
+		} else if chunk.ID != id {
+			l.report(i, "id changed from %q to %q", id, chunk.ID)
+		}
+		if usageSeen {
+			l.report(i, "chunk after the usage chunk")
+		}
+		if len(chunk.Choices) == 0 {
+			if chunk.Usage == nil {
+				l.report(i, "chunk has neither choices nor usage")
+			}
+			usageSeen = true
+			continue
+		}
+		if chunk.Usage != nil {
+			l.report(i, "usage must be sent in a final chunk with empty choices")
+		}
+
+		for _, choice := range chunk.Choices {
+			if finished[choice.Index] {
+				l.report(i, "choice %d continues after its finish_reason", choice.Index)
+			}
+			for _, call := range choice.Delta.ToolCalls {
+				if call.Index == nil {
+					l.report(i, "choice %d: tool call delta without index", choice.Index)
+					continue
+				}
+				key := [2]int{choice.Index, *call.Index}
+				if !toolCalls[key] && (call.ID == "" || call.Function.Name == "") {
+					l.report(i, "choice %d: first delta of tool call %d must carry id and function name", choice.Index, *call.Index)
+				}
+				toolCalls[key] = true
+			}
+			if choice.FinishReason != "" && choice.FinishReason != openai.FinishReasonNull {
+				finished[choice.Index] = true
+			}
+		}
+	}
+
+	if !done {
+		l.report(-1, "missing %s terminator", StreamDone)
+	}
+	if len(finished) == 0 && len(events) > 0 {
+		l.report(-1, "no choice reported a finish_reason")
+	}
+}
+
+func (l *streamLinter) lintGemini(events []StreamEvent) {
+	finished := make(map[int64]bool)
+	seen := make(map[int64]bool)
+	var lastUsage gemini.GeminiUsageMetadata
+
+	for i, ev := range events {
+		var chunk gemini.GeminiChatResponse
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			l.report(i, "invalid chunk: %v", err)
+			continue
+		}
+		if len(chunk.Candidates) == 0 && chunk.PromptFeedback.BlockReason == "" && len(chunk.PromptFeedback.SafetyRatings) == 0 &&
+			chunk.UsageMetadata.TotalTokenCount == 0 {
+			l.report(i, "chunk has no candidates, prompt feedback or usage")
+		}
+		for _, candidate := range chunk.Candidates {
+			if finished[candidate.Index] && len(candidate.Content.Parts) > 0 {
+				l.report(i, "candidate %d continues after its finishReason", candidate.Index)
+			}
+			if role := candidate.Content.Role; role != "" && role != "model" {
+				l.report(i, "candidate %d: role must be model, got %q", candidate.Index, role)
+			}
+			seen[candidate.Index] = true
+			if candidate.FinishReason != nil {
+				finished[candidate.Index] = true
+			}
+		}
+
+		usage := chunk.UsageMetadata
+		if usage.TotalTokenCount != 0 {
+			if usage.PromptTokenCount < lastUsage.PromptTokenCount || usage.CandidatesTokenCount < lastUsage.CandidatesTokenCount {
+				l.report(i, "usageMetadata token counts decreased")
+			}
+			lastUsage = usage
+		}
+	}
+
+	for index := range seen {
+		if !finished[index] {
+			l.report(-1, "candidate %d never reported a finishReason", index)
+		}
+	}
+}
+
+// claudeDeltaBlocks lists the content block types each delta type may target
+var claudeDeltaBlocks = map[string][]string{
+	"text_delta":       {"text"},
+	"citations_delta":  {"text"},
+	"input_json_delta": {"tool_use", "server_tool_use", "mcp_tool_use"},
+	"thinking_delta":   {"thinking"},
+	"signature_delta":  {"thinking"},
+}
+
+func (l *streamLinter) lintClaude(events []StreamEvent) {
+	started, stopped, messageDelta := false, false, false
+	nextIndex := 0
+	openIndex := -1
+	openType := ""
+
+	for i, ev := range events {
+		var event claude.ClaudeResponse
+		if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+			l.report(i, "invalid event: %v", err)
+			continue
+		}
+		if ev.Event != "" && ev.Event != event.Type {
+			l.report(i, "SSE event name %q does not match data type %q", ev.Event, event.Type)
+		}
+		if event.Type == "ping" || event.Type == "error" {
+			continue
+		}
+		if stopped {
+			l.report(i, "%s after message_stop", event.Type)
+			continue
+		}
+		if !started && event.Type != "message_start" {
+			l.report(i, "%s before message_start", event.Type)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if started {
+				l.report(i, "duplicate message_start")
+			}
+			started = true
+		case "content_block_start":
+			if openIndex >= 0 {
+				l.report(i, "content_block_start while block %d is still open", openIndex)
+			}
+			if messageDelta {
+				l.report(i, "content_block_start after message_delta")
+			}
+			if event.GetIndex() != nextIndex {
+				l.report(i, "content_block_start index %d, expected %d", event.GetIndex(), nextIndex)
+			}
+			if event.ContentBlock == nil {
+				l.report(i, "content_block_start without content_block")
+			} else {
+				openType = event.ContentBlock.Type
+			}
+			openIndex = event.GetIndex()
+			nextIndex = openIndex + 1
+		case "content_block_delta":
+			if openIndex < 0 || event.GetIndex() != openIndex {
+				l.report(i, "content_block_delta for block %d which is not open", event.GetIndex())
+				continue
+			}
+			if event.Delta == nil {
+				l.report(i, "content_block_delta without delta")
+				continue
+			}
+			if targets, ok := claudeDeltaBlocks[event.Delta.Type]; !ok {
+				l.report(i, "unknown delta type %q", event.Delta.Type)
+			} else if openType != "" && !slices.Contains(targets, openType) {
+				l.report(i, "%s is not valid for a %s block", event.Delta.Type, openType)
+			}
+		case "content_block_stop":
+			if openIndex < 0 || event.GetIndex() != openIndex {
+				l.report(i, "content_block_stop for block %d which is not open", event.GetIndex())
+			}
+			openIndex, openType = -1, ""
+		case "message_delta":
+			if openIndex >= 0 {
+				l.report(i, "message_delta while block %d is still open", openIndex)
+			}
+			if messageDelta {
+				l.report(i, "duplicate message_delta")
+			}
+			if event.Delta == nil || event.Delta.StopReason == nil {
+				l.report(i, "message_delta without stop_reason")
+			}
+			messageDelta = true
+		case "message_stop":
+			if !messageDelta {
+				l.report(i, "message_stop without preceding message_delta")
+			}
+			stopped = true
+		default:
+			l.report(i, "unknown event type %q", event.Type)
+		}
+	}
+
+	if len(events) > 0 && !stopped {
+		l.report(-1, "missing message_stop")
+	}
+}
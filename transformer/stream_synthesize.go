@@ -0,0 +1,180 @@
+package transformer
+
+import (
+	"encoding/json"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// SynthesizeOpenAIStream returns the chunks of a stream carrying resp, for
+// clients that asked to stream from an upstream that answered whole. Each
+// choice streams its role, reasoning, content and refusal as deltas of at
+// most size bytes (see SplitText), then its tool calls, each a chunk naming
+// the call followed by its argument fragments, and a chunk with its finish
+// reason. With includeUsage a usage chunk ends the stream.
+func SynthesizeOpenAIStream(resp *openai.ChatCompletionResponse, size int, includeUsage bool) []*openai.ChatCompletionStreamResponse {
+	chunk := func(choices ...openai.ChatCompletionStreamChoice) *openai.ChatCompletionStreamResponse {
+		return &openai.ChatCompletionStreamResponse{
+			ID:                resp.ID,
+			Object:            "chat.completion.chunk",
+			Created:           resp.Created,
+			Model:             resp.Model,
+			SystemFingerprint: resp.SystemFingerprint,
+			Choices:           choices,
+		}
+	}
+	var chunks []*openai.ChatCompletionStreamResponse
+	for _, choice := range resp.Choices {
+		delta := func(d openai.ChatCompletionStreamChoiceDelta) {
+			chunks = append(chunks, chunk(openai.ChatCompletionStreamChoice{Index: choice.Index, Delta: d}))
+		}
+		message := choice.Message
+		role := message.Role
+		if role == "" {
+			role = openai.ChatMessageRoleAssistant
+		}
+		delta(openai.ChatCompletionStreamChoiceDelta{Role: role})
+		if len(message.ReasoningDetails) == 0 {
+			for _, piece := range SplitText(message.ReasoningContent, size) {
+				delta(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: piece})
+			}
+		}
+		for _, detail := range message.ReasoningDetails {
+			// the text streams as reasoning_content, the detail signs it
+			if detail.Type == openai.ReasoningDetailTypeText {
+				for _, piece := range SplitText(detail.Text, size) {
+					delta(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: piece})
+				}
+				if detail.Signature == "" {
+					continue
+				}
+				detail.Text = ""
+			}
+			delta(openai.ChatCompletionStreamChoiceDelta{ReasoningDetails: []openai.ReasoningDetail{detail}})
+		}
+		content := message.Content
+		for _, part := range message.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeText {
+				content += part.Text
+			}
+		}
+		for _, piece := range SplitText(content, size) {
+			delta(openai.ChatCompletionStreamChoiceDelta{Content: piece})
+		}
+		for _, piece := range SplitText(message.Refusal, size) {
+			delta(openai.ChatCompletionStreamChoiceDelta{Refusal: piece})
+		}
+		if call := message.FunctionCall; call != nil {
+			delta(openai.ChatCompletionStreamChoiceDelta{FunctionCall: &openai.FunctionCall{Name: call.Name}})
+			for _, piece := range SplitText(call.Arguments, size) {
+				delta(openai.ChatCompletionStreamChoiceDelta{FunctionCall: &openai.FunctionCall{Arguments: piece}})
+			}
+		}
+		for i, call := range message.ToolCalls {
+			delta(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+				Index:        &i,
+				ID:           call.ID,
+				Type:         call.Type,
+				Function:     openai.FunctionCall{Name: call.Function.Name},
+				ExtraContent: call.ExtraContent,
+			}}})
+			for _, piece := range SplitText(call.Function.Arguments, size) {
+				delta(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{Index: &i, Function: openai.FunctionCall{Arguments: piece}}}})
+			}
+		}
+		chunks = append(chunks, chunk(openai.ChatCompletionStreamChoice{
+			Index:                choice.Index,
+			FinishReason:         choice.FinishReason,
+			ContentFilterResults: choice.ContentFilterResults,
+		}))
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, chunk())
+	}
+	chunks[0].PromptFilterResults = resp.PromptFilterResults
+	if includeUsage {
+		usage := resp.Usage
+		chunks = append(chunks, OpenAIUsageChunk(chunks[0], &usage))
+	}
+	return chunks
+}
+
+// SynthesizeClaudeStream returns the events of a stream carrying resp:
+// message_start with the input usage, each content block started empty,
+// filled with deltas of at most size bytes and stopped, then message_delta
+// with the stop reason and usage, and message_stop. Tool inputs stream as
+// fragments of their JSON, thinking blocks end with their signature; other
+// blocks, such as server tool results, start whole.
+func SynthesizeClaudeStream(resp *claude.ClaudeResponse, size int) []*claude.ClaudeResponse {
+	usage := claude.ClaudeUsage{}
+	if resp.Usage != nil {
+		usage = *resp.Usage
+	}
+	startUsage := usage
+	startUsage.OutputTokens = 0
+	role := resp.Role
+	if role == "" {
+		role = "assistant"
+	}
+	events := []*claude.ClaudeResponse{{
+		Type: "message_start",
+		Message: &claude.ClaudeMediaMessage{
+			Type:    "message",
+			Id:      resp.Id,
+			Role:    role,
+			Model:   resp.Model,
+			Content: []any{},
+			Usage:   &startUsage,
+		},
+	}}
+
+	for i, block := range resp.Content {
+		start := block
+		var deltas []*claude.ClaudeMediaMessage
+		switch block.Type {
+		case "text":
+			start.SetText("")
+			for _, piece := range SplitText(block.GetText(), size) {
+				deltas = append(deltas, &claude.ClaudeMediaMessage{Type: "text_delta", Text: &piece})
+			}
+		case "thinking":
+			start.Thinking, start.Signature = "", ""
+			for _, piece := range SplitText(block.Thinking, size) {
+				deltas = append(deltas, &claude.ClaudeMediaMessage{Type: "thinking_delta", Thinking: piece})
+			}
+			if block.Signature != "" {
+				deltas = append(deltas, &claude.ClaudeMediaMessage{Type: "signature_delta", Signature: block.Signature})
+			}
+		case "tool_use", claude.ContentTypeServerToolUse:
+			start.Input = map[string]any{}
+			input, _ := json.Marshal(block.Input)
+			if s := string(input); s != "null" && s != "{}" {
+				for _, piece := range SplitText(s, size) {
+					deltas = append(deltas, &claude.ClaudeMediaMessage{Type: "input_json_delta", PartialJson: &piece})
+				}
+			}
+		}
+		begin := &claude.ClaudeResponse{Type: "content_block_start", ContentBlock: &start}
+		begin.SetIndex(i)
+		events = append(events, begin)
+		for _, delta := range deltas {
+			events = append(events, blockEvent("content_block_delta", i, delta))
+		}
+		events = append(events, blockEvent("content_block_stop", i, nil))
+	}
+
+	stopReason := resp.StopReason
+	if stopReason == "" {
+		stopReason = "end_turn"
+	}
+	delta := &claude.ClaudeMediaMessage{StopReason: &stopReason}
+	if stopSequence := resp.StopSequence; stopSequence != "" {
+		delta.StopSequence = &stopSequence
+	}
+	events = append(events,
+		&claude.ClaudeResponse{Type: "message_delta", Delta: delta, Usage: &usage, Container: resp.Container},
+		&claude.ClaudeResponse{Type: "message_stop"},
+	)
+	return events
+}
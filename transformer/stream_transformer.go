@@ -0,0 +1,295 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/usage"
+)
+
+// StreamTransformer converts an OpenAI chat completion stream into Claude
+// stream events. OpenAI chunks carry bare deltas while Claude streams open
+// and close each content block, so the transformer keeps the per-stream
+// state: it starts the message on the first chunk, opens a block whenever
+// the kind of delta changes and holds back the stop reason until Finish,
+// since OpenAI reports usage in a chunk after the finish reason.
+//
+// One StreamTransformer serves a single stream and only choice 0.
+type StreamTransformer struct {
+	ctx   context.Context
+	state *ClaudeMessageState
+	// toolBlocks maps OpenAI tool call indexes to Claude block indexes.
+	toolBlocks   map[int]int
+	finishReason openai.FinishReason
+	usage        *openai.Usage
+	droppedN     bool
+	events       []*claude.ClaudeResponse
+}
+
+// NewStreamTransformer returns a transformer for one stream. ctx carries the
+// options and warnings of the stream.
+func NewStreamTransformer(ctx context.Context) *StreamTransformer {
+	return &StreamTransformer{ctx: ctx, state: NewClaudeMessageState(), toolBlocks: make(map[int]int)}
+}
+
+// State returns the state of the Claude stream emitted so far.
+func (s *StreamTransformer) State() *ClaudeMessageState {
+	return s.state
+}
+
+type streamTransformerSnapshot struct {
+	State        *ClaudeMessageState `json:"state"`
+	ToolBlocks   map[int]int         `json:"tool_blocks,omitempty"`
+	FinishReason string              `json:"finish_reason,omitempty"`
+	Usage        *openai.Usage       `json:"usage,omitempty"`
+	DroppedN     bool                `json:"dropped_n,omitempty"`
+}
+
+// Snapshot serializes the transformer, the Claude stream emitted so far
+// with the stop reason and usage it holds back until Finish.
+func (s *StreamTransformer) Snapshot() ([]byte, error) {
+	return json.Marshal(streamTransformerSnapshot{
+		State:        s.state,
+		ToolBlocks:   s.toolBlocks,
+		FinishReason: string(s.finishReason),
+		Usage:        s.usage,
+		DroppedN:     s.droppedN,
+	})
+}
+
+// RestoreStreamTransformer rebuilds a transformer from a Snapshot,
+// continuing the stream with the chunks after it. ctx carries the options
+// and warnings of the stream.
+func RestoreStreamTransformer(ctx context.Context, snapshot []byte) (*StreamTransformer, error) {
+	var snap streamTransformerSnapshot
+	if err := json.Unmarshal(snapshot, &snap); err != nil {
+		return nil, fmt.Errorf("invalid claude stream snapshot: %w", err)
+	}
+	s := NewStreamTransformer(ctx)
+	if snap.State != nil {
+		s.state = snap.State
+	}
+	for call, block := range snap.ToolBlocks {
+		s.toolBlocks[call] = block
+	}
+	s.finishReason, s.usage, s.droppedN = openai.FinishReason(snap.FinishReason), snap.Usage, snap.DroppedN
+	return s, nil
+}
+
+// Transform converts one chunk into the Claude events it completes, none
+// when the chunk carries nothing new.
+func (s *StreamTransformer) Transform(chunk *openai.ChatCompletionStreamResponse) ([]*claude.ClaudeResponse, error) {
+	if s.state.Stopped {
+		return nil, fmt.Errorf("chunk after the stream finished")
+	}
+	s.events = nil
+	if err := s.transform(chunk); err != nil {
+		return nil, err
+	}
+	return s.events, nil
+}
+
+func (s *StreamTransformer) transform(chunk *openai.ChatCompletionStreamResponse) error {
+	if !s.state.Started {
+		err := s.emit(&claude.ClaudeResponse{
+			Type: "message_start",
+			Message: &claude.ClaudeMediaMessage{
+				Type:    "message",
+				Id:      chunk.ID,
+				Role:    "assistant",
+				Model:   chunk.Model,
+				Content: []any{},
+				Usage:   &claude.ClaudeUsage{},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			if !s.droppedN {
+				s.droppedN = true
+				if err := dropUnsupported(s.ctx, fmt.Sprintf("choices[%d]", choice.Index), "Claude streams a single choice"); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		delta := choice.Delta
+		if delta.ReasoningContent != "" {
+			if err := s.delta("thinking", &claude.ClaudeMediaMessage{Type: "thinking_delta", Thinking: delta.ReasoningContent}); err != nil {
+				return err
+			}
+		}
+		for _, detail := range delta.ReasoningDetails {
+			if err := s.reasoningDetail(detail); err != nil {
+				return err
+			}
+		}
+		if text := delta.Content + delta.Refusal; text != "" {
+			if err := s.delta("text", &claude.ClaudeMediaMessage{Type: "text_delta", Text: &text}); err != nil {
+				return err
+			}
+		}
+		for _, call := range delta.ToolCalls {
+			if err := s.toolCall(call); err != nil {
+				return err
+			}
+		}
+		if choice.FinishReason != "" && choice.FinishReason != openai.FinishReasonNull {
+			// translated Gemini streams finish the chunk of a call with
+			// tool_calls and the last chunk with stop
+			if s.finishReason != openai.FinishReasonToolCalls {
+				s.finishReason = choice.FinishReason
+			}
+			if err := s.closeBlock(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Finish closes the stream with the open block's content_block_stop, the
+// message_delta carrying the stop reason and usage, and message_stop. A
+// stream without chunks still yields a well-formed, empty message.
+func (s *StreamTransformer) Finish() ([]*claude.ClaudeResponse, error) {
+	if s.state.Stopped {
+		return nil, nil
+	}
+	s.events = nil
+	if !s.state.Started {
+		if err := s.transform(&openai.ChatCompletionStreamResponse{}); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.closeBlock(); err != nil {
+		return nil, err
+	}
+
+	reason := "end_turn"
+	if s.finishReason != "" {
+		reason = stopReasonOpenAI2Claude(string(s.finishReason))
+	}
+	claudeUsage := &claude.ClaudeUsage{}
+	if s.usage != nil {
+		claudeUsage = usage.FromOpenAI(s.usage).Claude()
+	}
+	if err := s.emit(&claude.ClaudeResponse{Type: "message_delta", Delta: &claude.ClaudeMediaMessage{StopReason: &reason}, Usage: claudeUsage}); err != nil {
+		return nil, err
+	}
+	if err := s.emit(&claude.ClaudeResponse{Type: "message_stop"}); err != nil {
+		return nil, err
+	}
+	return s.events, nil
+}
+
+// delta streams a delta into the open block of blockType, opening one first
+// when the open block is of another type.
+func (s *StreamTransformer) delta(blockType string, delta *claude.ClaudeMediaMessage) error {
+	open := s.state.OpenBlock()
+	if open == nil || open.Type != blockType {
+		block := &claude.ClaudeMediaMessage{Type: blockType}
+		if blockType == "text" {
+			block.SetText("")
+		}
+		if err := s.startBlock(block); err != nil {
+			return err
+		}
+		open = s.state.OpenBlock()
+	}
+	return s.emit(blockEvent("content_block_delta", open.Index, delta))
+}
+
+// reasoningDetail signs the thinking streamed last, or streams a
+// redacted_thinking block, whole as Claude does.
+func (s *StreamTransformer) reasoningDetail(detail openai.ReasoningDetail) error {
+	switch detail.Type {
+	case openai.ReasoningDetailTypeText:
+		if detail.Text != "" {
+			if err := s.delta("thinking", &claude.ClaudeMediaMessage{Type: "thinking_delta", Thinking: detail.Text}); err != nil {
+				return err
+			}
+		}
+		if detail.Signature == "" {
+			return nil
+		}
+		return s.delta("thinking", &claude.ClaudeMediaMessage{Type: "signature_delta", Signature: detail.Signature})
+	case openai.ReasoningDetailTypeEncrypted:
+		if err := s.startBlock(&claude.ClaudeMediaMessage{Type: "redacted_thinking", Data: detail.Data}); err != nil {
+			return err
+		}
+		return s.closeBlock()
+	}
+	return nil
+}
+
+// toolCall starts a tool_use block for a new call and streams the argument
+// fragments of its calls as input_json_delta. Claude blocks cannot
+// interleave, so calls must be streamed one after the other.
+func (s *StreamTransformer) toolCall(call openai.ToolCall) error {
+	callIndex := 0
+	if call.Index != nil {
+		callIndex = *call.Index
+	}
+	index, known := s.toolBlocks[callIndex]
+	if !known {
+		index = s.state.NextIndex()
+		s.toolBlocks[callIndex] = index
+		err := s.startBlock(&claude.ClaudeMediaMessage{Type: "tool_use", Id: call.ID, Name: call.Function.Name, Input: map[string]any{}})
+		if err != nil {
+			return err
+		}
+	} else if open := s.state.OpenBlock(); open == nil || open.Index != index {
+		return &TransformationError{
+			Type:    "invalid_stream",
+			Message: fmt.Sprintf("tool call %d continues after another content block started", callIndex),
+		}
+	}
+	if call.Function.Arguments == "" {
+		return nil
+	}
+	arguments := call.Function.Arguments
+	return s.emit(blockEvent("content_block_delta", index, &claude.ClaudeMediaMessage{Type: "input_json_delta", PartialJson: &arguments}))
+}
+
+func (s *StreamTransformer) startBlock(block *claude.ClaudeMediaMessage) error {
+	if err := s.closeBlock(); err != nil {
+		return err
+	}
+	start := &claude.ClaudeResponse{Type: "content_block_start", ContentBlock: block}
+	start.SetIndex(s.state.NextIndex())
+	return s.emit(start)
+}
+
+// closeBlock stops the open block, if any.
+func (s *StreamTransformer) closeBlock() error {
+	if open := s.state.OpenBlock(); open != nil {
+		return s.emit(blockEvent("content_block_stop", open.Index, nil))
+	}
+	return nil
+}
+
+// emit applies event to the state and queues it, so a bug here surfaces as
+// an error rather than as a malformed stream.
+func (s *StreamTransformer) emit(event *claude.ClaudeResponse) error {
+	if err := s.state.Apply(event); err != nil {
+		return fmt.Errorf("emitted invalid claude stream: %w", err)
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func blockEvent(typ string, index int, delta *claude.ClaudeMediaMessage) *claude.ClaudeResponse {
+	event := &claude.ClaudeResponse{Type: typ, Delta: delta}
+	event.SetIndex(index)
+	return event
+}
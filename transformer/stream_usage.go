@@ -0,0 +1,189 @@
+package transformer
+
+import (
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+	"github.com/phosae/llms/usage"
+)
+
+// UsageSemantics describes how a stream reports token usage.
+type UsageSemantics int
+
+const (
+	// UsageCumulative reports carry the totals so far. Claude message_delta,
+	// Gemini usageMetadata and OpenAI-compatible backends that attach usage to
+	// every chunk behave this way.
+	UsageCumulative UsageSemantics = iota
+	// UsageIncremental reports carry only the tokens since the previous report.
+	UsageIncremental
+)
+
+// StreamUsage is provider-neutral token usage. InputTokens includes cached
+// prompt tokens and OutputTokens includes reasoning tokens, as in OpenAI.
+type StreamUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	CacheReadTokens     int `json:"cache_read_tokens,omitempty"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+	ReasoningTokens     int `json:"reasoning_tokens,omitempty"`
+}
+
+// UsageTracker folds the usage reports of a stream into totals without double
+// counting, whatever semantics the source uses.
+type UsageTracker struct {
+	total    StreamUsage
+	reported StreamUsage
+}
+
+// Observe records one usage report.
+func (t *UsageTracker) Observe(u StreamUsage, semantics UsageSemantics) {
+	if semantics == UsageIncremental {
+		t.total.InputTokens += u.InputTokens
+		t.total.OutputTokens += u.OutputTokens
+		t.total.CacheReadTokens += u.CacheReadTokens
+		t.total.CacheCreationTokens += u.CacheCreationTokens
+		t.total.ReasoningTokens += u.ReasoningTokens
+		return
+	}
+	// cumulative counters never decrease; a report may omit fields it already sent
+	t.total.InputTokens = max(t.total.InputTokens, u.InputTokens)
+	t.total.OutputTokens = max(t.total.OutputTokens, u.OutputTokens)
+	t.total.CacheReadTokens = max(t.total.CacheReadTokens, u.CacheReadTokens)
+	t.total.CacheCreationTokens = max(t.total.CacheCreationTokens, u.CacheCreationTokens)
+	t.total.ReasoningTokens = max(t.total.ReasoningTokens, u.ReasoningTokens)
+}
+
+// Total returns the usage of the whole stream so far. Use it when the target
+// reports cumulatively (Claude message_delta, the final OpenAI usage chunk).
+func (t *UsageTracker) Total() StreamUsage {
+	return t.total
+}
+
+// Delta returns the usage not yet returned by a previous Delta call. Use it
+// when the target expects incremental reports.
+func (t *UsageTracker) Delta() StreamUsage {
+	d := StreamUsage{
+		InputTokens:         t.total.InputTokens - t.reported.InputTokens,
+		OutputTokens:        t.total.OutputTokens - t.reported.OutputTokens,
+		CacheReadTokens:     t.total.CacheReadTokens - t.reported.CacheReadTokens,
+		CacheCreationTokens: t.total.CacheCreationTokens - t.reported.CacheCreationTokens,
+		ReasoningTokens:     t.total.ReasoningTokens - t.reported.ReasoningTokens,
+	}
+	t.reported = t.total
+	return d
+}
+
+// StreamUsageOf returns the counts of u the stream trackers fold.
+func StreamUsageOf(u usage.Usage) StreamUsage {
+	return StreamUsage{
+		InputTokens:         u.InputTokens,
+		OutputTokens:        u.OutputTokens,
+		CacheReadTokens:     u.Input.CacheReadTokens,
+		CacheCreationTokens: u.Input.CacheWriteTokens,
+		ReasoningTokens:     u.Output.ReasoningTokens,
+	}
+}
+
+// Usage returns the usage as normalized usage.
+func (u StreamUsage) Usage() usage.Usage {
+	return usage.Usage{
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		Input:        usage.InputDetails{CacheReadTokens: u.CacheReadTokens, CacheWriteTokens: u.CacheCreationTokens},
+		Output:       usage.OutputDetails{ReasoningTokens: u.ReasoningTokens},
+	}
+}
+
+// StreamUsageFromClaude converts Claude usage, whose input_tokens exclude cache reads and writes.
+func StreamUsageFromClaude(u *claude.ClaudeUsage) StreamUsage {
+	return StreamUsageOf(usage.FromClaude(u))
+}
+
+// StreamUsageFromOpenAI converts OpenAI usage.
+func StreamUsageFromOpenAI(u *openai.Usage) StreamUsage {
+	return StreamUsageOf(usage.FromOpenAI(u))
+}
+
+// StreamUsageFromGemini converts Gemini usage metadata, whose candidate count excludes thoughts.
+func StreamUsageFromGemini(u *gemini.GeminiUsageMetadata) StreamUsage {
+	return StreamUsageOf(usage.FromGemini(u))
+}
+
+// StreamUsageFromBedrock converts Bedrock usage, whose inputTokens exclude cache reads and writes.
+func StreamUsageFromBedrock(u *bedrock.TokenUsage) StreamUsage {
+	return StreamUsageOf(usage.FromBedrock(u))
+}
+
+// StreamUsageFromOllama converts the token counts of Ollama metrics.
+func StreamUsageFromOllama(m *ollama.Metrics) StreamUsage {
+	return StreamUsageOf(usage.FromOllama(m))
+}
+
+// Claude converts the usage to Claude's shape.
+func (u StreamUsage) Claude() *claude.ClaudeUsage {
+	return u.Usage().Claude()
+}
+
+// OpenAI converts the usage to OpenAI's shape.
+func (u StreamUsage) OpenAI() *openai.Usage {
+	return u.Usage().OpenAI()
+}
+
+// Gemini converts the usage to Gemini's shape.
+func (u StreamUsage) Gemini() gemini.GeminiUsageMetadata {
+	return u.Usage().Gemini()
+}
+
+// Bedrock converts the usage to Bedrock's shape.
+func (u StreamUsage) Bedrock() *bedrock.TokenUsage {
+	return u.Usage().Bedrock()
+}
+
+// Ollama converts the usage to the token counts of Ollama metrics.
+func (u StreamUsage) Ollama() ollama.Metrics {
+	return u.Usage().Ollama()
+}
+
+// UsageOf returns the usage reported by a provider response or stream chunk.
+func UsageOf(payload interface{}) (StreamUsage, bool) {
+	switch p := payload.(type) {
+	case *openai.ChatCompletionResponse:
+		return StreamUsageFromOpenAI(&p.Usage), p.Usage.TotalTokens > 0
+	case *openai.ChatCompletionStreamResponse:
+		if p.Usage != nil {
+			return StreamUsageFromOpenAI(p.Usage), true
+		}
+	case *claude.ClaudeResponse:
+		if p.Usage != nil {
+			return StreamUsageFromClaude(p.Usage), true
+		}
+		if p.Message != nil && p.Message.Usage != nil {
+			return StreamUsageFromClaude(p.Message.Usage), true
+		}
+	case *gemini.GeminiChatResponse:
+		if p.UsageMetadata.TotalTokenCount > 0 {
+			return StreamUsageFromGemini(&p.UsageMetadata), true
+		}
+	case *vertex.GenerateContentResponse:
+		if p.UsageMetadata.TotalTokenCount > 0 {
+			return StreamUsageFromGemini(&p.UsageMetadata), true
+		}
+	case *bedrock.ConverseResponse:
+		if p.Usage != nil {
+			return StreamUsageFromBedrock(p.Usage), true
+		}
+	case *bedrock.ConverseStreamEvent:
+		if p.Metadata != nil && p.Metadata.Usage != nil {
+			return StreamUsageFromBedrock(p.Metadata.Usage), true
+		}
+	case *ollama.ChatResponse:
+		if p.Done {
+			return StreamUsageFromOllama(&p.Metrics), true
+		}
+	}
+	return StreamUsage{}, false
+}
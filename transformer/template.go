@@ -0,0 +1,76 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplates renders text/template variables into the text parts of
+// unified requests before they are converted, so prompts can be kept in one
+// place whatever provider they are sent to. Named templates defined with
+// Define can be included from message text with {{template "name" .}}.
+//
+// Define is not safe for concurrent use with Render, Render is.
+type PromptTemplates struct {
+	root *template.Template
+}
+
+var promptFuncs = template.FuncMap{
+	"json":  toJSONString,
+	"join":  strings.Join,
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// NewPromptTemplates returns an empty template set. Referencing a variable
+// missing from the render data is an error.
+func NewPromptTemplates() *PromptTemplates {
+	return &PromptTemplates{root: template.New("").Funcs(promptFuncs).Option("missingkey=error")}
+}
+
+// Define parses a named template.
+func (p *PromptTemplates) Define(name, text string) error {
+	if _, err := p.root.New(name).Parse(text); err != nil {
+		return fmt.Errorf("template %s: %w", name, err)
+	}
+	return nil
+}
+
+// Render returns a copy of u whose text parts are executed as templates with
+// data. Thinking, images and tool calls are left as is.
+func (p *PromptTemplates) Render(u *UnifiedRequest, data any) (*UnifiedRequest, error) {
+	set, err := p.root.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := *u
+	rendered.Messages = make([]UnifiedMessage, len(u.Messages))
+	for i, message := range u.Messages {
+		message.Parts = append([]UnifiedPart(nil), message.Parts...)
+		for j, part := range message.Parts {
+			if part.Type != UnifiedPartText || !strings.Contains(part.Text, "{{") {
+				continue
+			}
+			path := fmt.Sprintf("messages[%d].parts[%d]", i, j)
+			tmpl, err := set.New(path).Parse(part.Text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			var text strings.Builder
+			if err := tmpl.Execute(&text, data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			message.Parts[j].Text = text.String()
+		}
+		rendered.Messages[i] = message
+	}
+	return &rendered, nil
+}
+
+// RenderPrompt renders u with data and no named templates.
+func RenderPrompt(u *UnifiedRequest, data any) (*UnifiedRequest, error) {
+	return NewPromptTemplates().Render(u, data)
+}
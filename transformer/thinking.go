@@ -0,0 +1,61 @@
+package transformer
+
+import (
+	"context"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// reasoningEffortBudget maps the reasoning_effort of an OpenAI request for
+// model to a thinking budget, as the quirks of the model say, 0 when
+// reasoning is off.
+func reasoningEffortBudget(ctx context.Context, model, effort string) int {
+	return config.FromContext(ctx).Quirks(model).ThinkingBudget(effort)
+}
+
+// stripOpenAIReasoning removes the reasoning_content and reasoning_details of
+// the messages of req, converted for an OpenAI target, unless the quirks of
+// its model send them back.
+func stripOpenAIReasoning(ctx context.Context, req *openai.ChatCompletionRequest) {
+	if config.FromContext(ctx).Quirks(req.Model).SendReasoning {
+		return
+	}
+	for i := range req.Messages {
+		req.Messages[i].ReasoningContent = ""
+		req.Messages[i].ReasoningDetails = nil
+	}
+}
+
+// reasoningDetailFromClaude converts a thinking or redacted_thinking block.
+func reasoningDetailFromClaude(block claude.ClaudeMediaMessage) openai.ReasoningDetail {
+	if block.Type == "redacted_thinking" {
+		return openai.ReasoningDetail{Type: openai.ReasoningDetailTypeEncrypted, Data: block.Data}
+	}
+	return openai.ReasoningDetail{Type: openai.ReasoningDetailTypeText, Text: block.Thinking, Signature: block.Signature}
+}
+
+// claudeThinkingBlocks converts the reasoning of an assistant message to
+// thinking and redacted_thinking blocks, from its reasoning details when it
+// has any. Claude only accepts signed thinking back, so with signedOnly the
+// reasoning without a signature is left out.
+func claudeThinkingBlocks(message openai.ChatCompletionMessage, signedOnly bool) []claude.ClaudeMediaMessage {
+	details := message.ReasoningDetails
+	if len(details) == 0 && message.ReasoningContent != "" {
+		details = []openai.ReasoningDetail{{Type: openai.ReasoningDetailTypeText, Text: message.ReasoningContent}}
+	}
+	var blocks []claude.ClaudeMediaMessage
+	for _, detail := range details {
+		switch detail.Type {
+		case openai.ReasoningDetailTypeText:
+			if signedOnly && detail.Signature == "" {
+				continue
+			}
+			blocks = append(blocks, claude.ClaudeMediaMessage{Type: "thinking", Thinking: detail.Text, Signature: detail.Signature})
+		case openai.ReasoningDetailTypeEncrypted:
+			blocks = append(blocks, claude.ClaudeMediaMessage{Type: "redacted_thinking", Data: detail.Data})
+		}
+	}
+	return blocks
+}
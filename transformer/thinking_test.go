@@ -0,0 +1,108 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/openai"
+)
+
+func TestReasoningEffortBudgets(t *testing.T) {
+	custom := &config.Config{
+		Models:        map[string]config.ModelSettings{config.Wildcard: {QuirkProfile: "deep"}},
+		QuirkProfiles: map[string]config.Quirks{"deep": {ReasoningLowBudget: 2048, ReasoningMediumBudget: 8192, ReasoningHighBudget: 16384}},
+	}
+	tests := []struct {
+		effort string
+		config *config.Config
+		want   int
+	}{
+		{"low", nil, 1024},
+		{"medium", nil, 2048},
+		{"high", nil, 4096},
+		{"", nil, 0},
+		{"low", custom, 2048},
+		{"high", custom, 16384},
+	}
+	for _, tt := range tests {
+		req := openai.ChatCompletionRequest{
+			Model:           "claude-sonnet-4-5",
+			MaxTokens:       32000,
+			ReasoningEffort: tt.effort,
+			Messages:        []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		}
+		ctx := config.NewContext(context.Background(), tt.config)
+		var claudeReq claude.ClaudeRequest
+		if err := NewOpenAITransformer().Do(ctx, TransformerTypeRequest, &req, &claudeReq); err != nil {
+			t.Fatal(err)
+		}
+		var got int
+		if claudeReq.Thinking != nil {
+			got = claudeReq.Thinking.GetBudgetTokens()
+		}
+		if got != tt.want {
+			t.Errorf("%q with %v: budget %d, want %d", tt.effort, tt.config != nil, got, tt.want)
+		}
+	}
+
+	for budget, want := range map[int]string{512: "low", 1024: "medium", 2048: "high", 4096: "high"} {
+		if got := (config.Quirks{}).ReasoningEffort(budget); got != want {
+			t.Errorf("ReasoningEffort(%d) = %s, want %s", budget, got, want)
+		}
+	}
+}
+
+func TestReasoningIsSentToOpenAIWithSendReasoning(t *testing.T) {
+	var req claude.ClaudeRequest
+	if err := json.Unmarshal([]byte(`{"model":"claude-sonnet-4-5","max_tokens":4096,"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":[{"type":"thinking","thinking":"hmm","signature":"sig"},{"type":"text","text":"hello"}]},
+		{"role":"user","content":"again"}]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	openRouter := &config.Config{
+		Models:        map[string]config.ModelSettings{config.Wildcard: {QuirkProfile: "openrouter"}},
+		QuirkProfiles: map[string]config.Quirks{"openrouter": {SendReasoning: true}},
+	}
+	tests := []struct {
+		name   string
+		config *config.Config
+		sent   bool
+	}{
+		{"openai", nil, false},
+		{"openrouter", openRouter, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := config.NewContext(context.Background(), tt.config)
+			var oaiReq openai.ChatCompletionRequest
+			if err := NewClaudeTransformer().Do(ctx, TransformerTypeRequest, &req, &oaiReq); err != nil {
+				t.Fatal(err)
+			}
+			assistant := oaiReq.Messages[1]
+			if sent := assistant.ReasoningContent != "" || len(assistant.ReasoningDetails) > 0; sent != tt.sent {
+				t.Errorf("reasoning sent = %v, want %v", sent, tt.sent)
+			}
+
+			// the pivot keeps the reasoning for other targets
+			u, err := ToUnifiedRequest(ctx, ProviderClaude, &req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var back claude.ClaudeRequest
+			if err := FromUnifiedRequest(ctx, ProviderClaude, u, &back); err != nil {
+				t.Fatal(err)
+			}
+			blocks, err := back.Messages[1].ParseContent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blocks[0].Type != "thinking" || blocks[0].Signature != "sig" {
+				t.Errorf("assistant turn = %+v, want the signed thinking first", blocks)
+			}
+		})
+	}
+}
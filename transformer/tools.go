@@ -0,0 +1,400 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// ToolErrorPrefix marks tool results reporting a failure in formats without a
+// first-class error flag (OpenAI tool messages). It mirrors Claude's
+// tool_result is_error and Gemini's functionResponse {"error": ...}.
+const ToolErrorPrefix = "[tool error] "
+
+// markToolError prefixes a failed tool result
+func markToolError(content string) string {
+	return ToolErrorPrefix + content
+}
+
+// parseToolError strips the error prefix and reports whether it was present
+func parseToolError(content string) (string, bool) {
+	if rest, ok := strings.CutPrefix(content, ToolErrorPrefix); ok {
+		return rest, true
+	}
+	return content, false
+}
+
+// ToolNames records the tool names transformations renamed to meet the
+// naming rules of their target, so that the tool calls of the target's
+// responses get their original names back. Run both legs of a call with the
+// context of WithToolNames. It is safe for concurrent use.
+type ToolNames struct {
+	mu sync.Mutex
+	// original maps target names to source names.
+	original map[string]string
+}
+
+type toolNamesKey struct{}
+
+// WithToolNames returns a context recording renamed tools into the returned
+// ToolNames.
+func WithToolNames(ctx context.Context) (context.Context, *ToolNames) {
+	n := &ToolNames{original: make(map[string]string)}
+	return context.WithValue(ctx, toolNamesKey{}, n), n
+}
+
+// Original returns the source name of the tool named name on the target.
+func (n *ToolNames) Original(name string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if original, ok := n.original[name]; ok {
+		return original
+	}
+	return name
+}
+
+// record maps renamed back to the name original, following earlier renames
+// of original, e.g. on the hop to a pivot format.
+func (n *ToolNames) record(original, renamed string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if earlier, ok := n.original[original]; ok {
+		original = earlier
+	}
+	n.original[renamed] = original
+}
+
+// originalToolName returns the source name of a tool of the target.
+func originalToolName(ctx context.Context, name string) string {
+	if n, ok := ctx.Value(toolNamesKey{}).(*ToolNames); ok {
+		return n.Original(name)
+	}
+	return name
+}
+
+// restoreToolCalls gives tool calls of a target response their source names.
+func restoreToolCalls(ctx context.Context, calls []openai.ToolCall) {
+	for i := range calls {
+		calls[i].Function.Name = originalToolName(ctx, calls[i].Function.Name)
+	}
+}
+
+func restoreUnifiedToolCalls(ctx context.Context, calls []UnifiedToolCall) {
+	for i := range calls {
+		calls[i].Name = originalToolName(ctx, calls[i].Name)
+	}
+}
+
+// toolNameRule is the tool naming rule of a provider: ASCII letters, digits,
+// underscores, dashes and the extra characters, at most maxLen long.
+type toolNameRule struct {
+	maxLen int
+	extra  string
+	// letterFirst requires a letter or an underscore first.
+	letterFirst bool
+}
+
+var toolNameRules = map[Provider]toolNameRule{
+	ProviderOpenAI:  {maxLen: 64},
+	ProviderClaude:  {maxLen: 64},
+	ProviderBedrock: {maxLen: 64},
+	ProviderOllama:  {maxLen: 64},
+	ProviderGemini:  {maxLen: 64, extra: ".:", letterFirst: true},
+}
+
+func (r toolNameRule) allowed(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' ||
+		strings.ContainsRune(r.extra, c)
+}
+
+func (r toolNameRule) valid(name string) bool {
+	if name == "" || len(name) > r.maxLen || strings.IndexFunc(name, func(c rune) bool { return !r.allowed(c) }) >= 0 {
+		return false
+	}
+	return !r.letterFirst || name[0] == '_' || unicode.IsLetter(rune(name[0]))
+}
+
+// sanitize replaces the characters r disallows with underscores and
+// truncates name to suffix fitting in.
+func (r toolNameRule) sanitize(name, suffix string) string {
+	name = strings.Map(func(c rune) rune {
+		if r.allowed(c) {
+			return c
+		}
+		return '_'
+	}, name)
+	if r.letterFirst && (name == "" || name[0] != '_' && !unicode.IsLetter(rune(name[0]))) {
+		name = "_" + name
+	}
+	if len(name)+len(suffix) > r.maxLen {
+		name = name[:r.maxLen-len(suffix)]
+	}
+	return name + suffix
+}
+
+// normalizeTools prepares the tools of req for target: later definitions of
+// a tool are dropped, and names breaking the naming rule of target are
+// sanitized, numbered apart from the other names, and recorded for
+// originalToolName. req is left untouched; the returned request shares it
+// when nothing changes.
+func normalizeTools(ctx context.Context, target Provider, req *openai.ChatCompletionRequest) (*openai.ChatCompletionRequest, error) {
+	rule, ok := toolNameRules[target]
+	if !ok || len(req.Tools) == 0 && !slices.ContainsFunc(req.Messages, func(m openai.ChatCompletionMessage) bool { return len(m.ToolCalls) > 0 || toolResultName(m) != "" }) {
+		return req, nil
+	}
+
+	var tools []openai.Tool
+	definitions := make(map[string]string)
+	for i, tool := range req.Tools {
+		if tool.Function == nil {
+			tools = append(tools, tool)
+			continue
+		}
+		definition, _ := json.Marshal(tool.Function)
+		if earlier, ok := definitions[tool.Function.Name]; ok {
+			if earlier != string(definition) {
+				if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d]", i), "tool %q is already defined", tool.Function.Name); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		definitions[tool.Function.Name] = string(definition)
+		tools = append(tools, tool)
+	}
+
+	// names of the tools, then of calls to and results of tools no longer defined
+	var names []string
+	for _, tool := range tools {
+		if tool.Function != nil {
+			names = append(names, tool.Function.Name)
+		}
+	}
+	for _, message := range req.Messages {
+		for _, call := range message.ToolCalls {
+			if !slices.Contains(names, call.Function.Name) {
+				names = append(names, call.Function.Name)
+			}
+		}
+		if name := toolResultName(message); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	taken := make(map[string]bool)
+	for _, name := range names {
+		if rule.valid(name) {
+			taken[name] = true
+		}
+	}
+	renamed := make(map[string]string)
+	for _, name := range names {
+		if rule.valid(name) {
+			continue
+		}
+		candidate := rule.sanitize(name, "")
+		for n := 2; taken[candidate]; n++ {
+			candidate = rule.sanitize(name, fmt.Sprintf("_%d", n))
+		}
+		taken[candidate] = true
+		renamed[name] = candidate
+	}
+	if len(renamed) == 0 && len(tools) == len(req.Tools) {
+		return req, nil
+	}
+
+	n, _ := ctx.Value(toolNamesKey{}).(*ToolNames)
+	for _, name := range names {
+		if to, ok := renamed[name]; ok {
+			warn(ctx, WarningRenamedTool, "tools", "tool %q is %q on %s", name, to, target)
+			if n != nil {
+				n.record(name, to)
+			}
+		}
+	}
+	rename := func(name string) string {
+		if to, ok := renamed[name]; ok {
+			return to
+		}
+		return name
+	}
+
+	out := *req
+	out.Tools = tools
+	for i, tool := range out.Tools {
+		if tool.Function != nil {
+			function := *tool.Function
+			function.Name = rename(function.Name)
+			out.Tools[i].Function = &function
+		}
+	}
+	out.Messages = slices.Clone(req.Messages)
+	for i, message := range out.Messages {
+		if name := toolResultName(message); name != "" {
+			out.Messages[i].Name = rename(name)
+		}
+		if len(message.ToolCalls) == 0 {
+			continue
+		}
+		out.Messages[i].ToolCalls = slices.Clone(message.ToolCalls)
+		for j := range out.Messages[i].ToolCalls {
+			out.Messages[i].ToolCalls[j].Function.Name = rename(message.ToolCalls[j].Function.Name)
+		}
+	}
+	if _, ok := req.ToolChoice.(string); !ok && req.ToolChoice != nil {
+		if named, err := common.Any2Type[openai.ToolChoice](req.ToolChoice); err == nil && named.Function.Name != "" {
+			named.Function.Name = rename(named.Function.Name)
+			out.ToolChoice = named
+		} else if err == nil && named.AllowedTools != nil {
+			allowed := *named.AllowedTools
+			allowed.Tools = slices.Clone(allowed.Tools)
+			for i := range allowed.Tools {
+				allowed.Tools[i].Function.Name = rename(allowed.Tools[i].Function.Name)
+			}
+			named.AllowedTools = &allowed
+			out.ToolChoice = named
+		}
+	}
+	return &out, nil
+}
+
+// toolResultName returns the tool name of a tool result message, which
+// Gemini function responses are named after.
+func toolResultName(message openai.ChatCompletionMessage) string {
+	if message.Role != openai.ChatMessageRoleTool {
+		return ""
+	}
+	return message.Name
+}
+
+// strictTools reports whether a tool of tools is strict.
+func strictTools(tools []openai.Tool) bool {
+	return slices.ContainsFunc(tools, func(tool openai.Tool) bool { return tool.Function != nil && tool.Function.Strict })
+}
+
+// dropStrictTools drops the strict flags of tools for a target that does not
+// enforce tool schemas.
+func dropStrictTools(ctx context.Context, target Provider, tools []openai.Tool) error {
+	for i, tool := range tools {
+		if tool.Function != nil && tool.Function.Strict {
+			if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d].function.strict", i), "%s does not enforce tool schemas", target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// builtinTool reports whether req asks for the server tool named tool:
+// ServerToolWebSearch with web_search_options or a web_search_preview tool,
+// ServerToolCodeExecution with a code_interpreter tool.
+func builtinTool(req *openai.ChatCompletionRequest, tool string) bool {
+	hasType := func(toolType openai.ToolType) bool {
+		return slices.ContainsFunc(req.Tools, func(tool openai.Tool) bool { return tool.Type == toolType })
+	}
+	switch tool {
+	case ServerToolWebSearch:
+		return req.WebSearchOptions != nil || hasType(openai.ToolTypeWebSearchPreview)
+	case ServerToolCodeExecution:
+		return hasType(openai.ToolTypeCodeInterpreter)
+	default:
+		return false
+	}
+}
+
+// builtinTools returns the server tools req asks for.
+func builtinTools(req *openai.ChatCompletionRequest) []string {
+	var tools []string
+	for _, tool := range []string{ServerToolWebSearch, ServerToolCodeExecution} {
+		if builtinTool(req, tool) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// dropBuiltinTools drops the built-in tools declared among tools for a
+// target that runs none.
+func dropBuiltinTools(ctx context.Context, target Provider, tools []openai.Tool) error {
+	for i, tool := range tools {
+		if tool.Type == openai.ToolTypeWebSearchPreview || tool.Type == openai.ToolTypeCodeInterpreter {
+			if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d]", i), "%s has no %s tool", target, tool.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dropCodeInterpreter drops the code_interpreter tools of req, which pivot
+// code execution between providers but which chat completions rejects.
+func dropCodeInterpreter(ctx context.Context, req *openai.ChatCompletionRequest) error {
+	var tools []openai.Tool
+	for i, tool := range req.Tools {
+		if tool.Type == openai.ToolTypeCodeInterpreter {
+			if err := dropUnsupported(ctx, fmt.Sprintf("tools[%d]", i), "%s has no %s tool", ProviderOpenAI, tool.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	if len(tools) < len(req.Tools) {
+		req.Tools = tools
+	}
+	return nil
+}
+
+// strictSchema reports whether a JSON schema meets the requirements of
+// OpenAI strict mode: its objects allow no additional properties and require
+// all of theirs.
+func strictSchema(schema any) bool {
+	s, err := common.Any2Type[map[string]any](schema)
+	if err != nil || s == nil {
+		return false
+	}
+	return strictSchemaNode(s)
+}
+
+func strictSchemaNode(node any) bool {
+	switch node := node.(type) {
+	case map[string]any:
+		if properties, ok := node["properties"].(map[string]any); ok || node["type"] == "object" {
+			if node["additionalProperties"] != false {
+				return false
+			}
+			required, _ := node["required"].([]any)
+			if len(required) != len(properties) {
+				return false
+			}
+			for _, name := range required {
+				if name, _ := name.(string); properties[name] == nil {
+					return false
+				}
+			}
+			for _, property := range properties {
+				if !strictSchemaNode(property) {
+					return false
+				}
+			}
+		}
+		for key, value := range node {
+			if key != "properties" && !strictSchemaNode(value) {
+				return false
+			}
+		}
+	case []any:
+		for _, value := range node {
+			if !strictSchemaNode(value) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+func TestCodeInterpreterIsDroppedForOpenAI(t *testing.T) {
+	claudeReq := `{"model":"claude-sonnet-4-5","max_tokens":100,"messages":[{"role":"user","content":"hi"}],
+		"tools":[{"type":"code_execution_20250522","name":"code_execution"},{"name":"lookup","input_schema":{"type":"object"}}]}`
+	geminiReq := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+		"tools":[{"codeExecution":{}},{"functionDeclarations":[{"name":"lookup","parameters":{"type":"object"}}]}]}`
+	tests := []struct {
+		name string
+		do   func(ctx context.Context, dst *openai.ChatCompletionRequest) error
+	}{
+		{"claude", func(ctx context.Context, dst *openai.ChatCompletionRequest) error {
+			var req claude.ClaudeRequest
+			if err := json.Unmarshal([]byte(claudeReq), &req); err != nil {
+				return err
+			}
+			return NewClaudeTransformer().Do(ctx, TransformerTypeRequest, &req, dst)
+		}},
+		{"gemini", func(ctx context.Context, dst *openai.ChatCompletionRequest) error {
+			var req gemini.GeminiChatRequest
+			if err := json.Unmarshal([]byte(geminiReq), &req); err != nil {
+				return err
+			}
+			return NewGeminiTransformer().Do(ctx, TransformerTypeRequest, &req, dst)
+		}},
+		{"vertex", func(ctx context.Context, dst *openai.ChatCompletionRequest) error {
+			var req vertex.GenerateContentRequest
+			if err := json.Unmarshal([]byte(geminiReq), &req); err != nil {
+				return err
+			}
+			return NewVertexGeminiTransformer().Do(ctx, TransformerTypeRequest, &req, dst)
+		}},
+		{"unified", func(ctx context.Context, dst *openai.ChatCompletionRequest) error {
+			var req claude.ClaudeRequest
+			if err := json.Unmarshal([]byte(claudeReq), &req); err != nil {
+				return err
+			}
+			u, err := ToUnifiedRequest(ctx, ProviderClaude, &req)
+			if err != nil {
+				return err
+			}
+			return FromUnifiedRequest(ctx, ProviderOpenAI, u, dst)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, warnings := WithWarnings(context.Background())
+			var req openai.ChatCompletionRequest
+			if err := tt.do(ctx, &req); err != nil {
+				t.Fatal(err)
+			}
+			if len(req.Tools) != 1 || req.Tools[0].Function == nil || req.Tools[0].Function.Name != "lookup" {
+				t.Fatalf("got tools %+v, want the lookup function only", req.Tools)
+			}
+			if !hasWarning(warnings, WarningDroppedContent) {
+				t.Errorf("got warnings %v, want %s", warnings.List(), WarningDroppedContent)
+			}
+
+			strict := WithOptions(context.Background(), Options{Strict: true})
+			if err := tt.do(strict, &openai.ChatCompletionRequest{}); err == nil {
+				t.Error("strict transformation succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCodeExecutionPivotsBetweenClaudeAndGemini(t *testing.T) {
+	var req claude.ClaudeRequest
+	if err := json.Unmarshal([]byte(`{"model":"claude-sonnet-4-5","max_tokens":100,"messages":[{"role":"user","content":"hi"}],
+		"tools":[{"type":"code_execution_20250522","name":"code_execution"}]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	u, err := ToUnifiedRequest(context.Background(), ProviderClaude, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var geminiReq gemini.GeminiChatRequest
+	if err := FromUnifiedRequest(context.Background(), ProviderGemini, u, &geminiReq); err != nil {
+		t.Fatal(err)
+	}
+	if len(geminiReq.Tools) != 1 || geminiReq.Tools[0].CodeExecution == nil {
+		t.Errorf("got tools %+v, want codeExecution", geminiReq.Tools)
+	}
+}
+
+func hasWarning(warnings *Warnings, code string) bool {
+	for _, w := range warnings.List() {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestToolResultsAreRenamedWithTheirCalls(t *testing.T) {
+	var req openai.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(`{"model":"gemini-2.5-flash","messages":[
+		{"role":"user","content":"weather?"},
+		{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"1weather/now","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_1","name":"1weather/now","content":"sunny"}],
+		"tools":[{"type":"function","function":{"name":"1weather/now","parameters":{"type":"object"}}}]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	var geminiReq gemini.GeminiChatRequest
+	if err := NewOpenAITransformer().Do(context.Background(), TransformerTypeRequest, &req, &geminiReq); err != nil {
+		t.Fatal(err)
+	}
+	var call, result string
+	for _, content := range geminiReq.Contents {
+		for _, part := range content.Parts {
+			if part.FunctionCall != nil {
+				call = part.FunctionCall.FunctionName
+			}
+			if part.FunctionResponse != nil {
+				result = part.FunctionResponse.Name
+			}
+		}
+	}
+	if call == "1weather/now" || call != result {
+		t.Errorf("got call %q and result %q, want the same valid Gemini name", call, result)
+	}
+}
+
+func TestOllamaStreamRestoresToolNames(t *testing.T) {
+	ctx, names := WithToolNames(context.Background())
+	names.record("1weather/now", "weather_now")
+	var chunk openai.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(`{"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[
+		{"index":0,"id":"call_1","type":"function","function":{"name":"weather_now","arguments":"{}"}}]},
+		"finish_reason":"tool_calls"}]}`), &chunk); err != nil {
+		t.Fatal(err)
+	}
+	s := NewOllamaStreamTransformer(ctx)
+	objects, err := s.Transform(&chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || len(objects[0].Message.ToolCalls) != 1 {
+		t.Fatalf("got %+v, want one object with one call", objects)
+	}
+	if name := objects[0].Message.ToolCalls[0].Function.Name; name != "1weather/now" {
+		t.Errorf("got %q, want the source name", name)
+	}
+}
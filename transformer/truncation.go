@@ -0,0 +1,83 @@
+package transformer
+
+import (
+	"encoding/json"
+
+	"github.com/phosae/llms/dto/openai"
+)
+
+// partialArguments reports whether the arguments of a tool call cut off by
+// the token limit are incomplete: OpenAI and Claude stream the prefix that
+// was generated, which is no JSON value or, before the first fragment,
+// nothing.
+func partialArguments(arguments string) bool {
+	return arguments == "" || !json.Valid([]byte(arguments))
+}
+
+// flagPartialToolCalls flags the calls whose arguments the token limit cut
+// off, when finishReason is length.
+func flagPartialToolCalls(finishReason string, calls []UnifiedToolCall) {
+	if finishReason != string(openai.FinishReasonLength) {
+		return
+	}
+	for i := range calls {
+		calls[i].Partial = partialArguments(calls[i].Arguments)
+	}
+}
+
+// TruncationTracker follows the tool calls of a unified stream, whose
+// chunks carry their arguments in fragments, so that the stream stopping
+// at the token limit flags the calls it cut off:
+//
+//	tracker := transformer.NewTruncationTracker()
+//	for chunk := range chunks {
+//		tracker.Observe(chunk)
+//		...
+//	}
+//
+// Providers tell these calls apart differently, or not at all: OpenAI and
+// Bedrock finish with length and Claude with max_tokens after the fragments
+// generated so far, while Gemini and Ollama, which send calls whole, drop
+// them. Flagged calls let agent loops ask for the call again, with a larger
+// limit, rather than fail on its arguments.
+//
+// One TruncationTracker serves a single stream.
+type TruncationTracker struct {
+	arguments map[int]string
+	order     []int
+}
+
+// NewTruncationTracker returns a tracker for one stream.
+func NewTruncationTracker() *TruncationTracker {
+	return &TruncationTracker{arguments: make(map[int]string)}
+}
+
+// Observe records the tool call fragments of u. When u finishes the stream
+// with length, each call whose arguments are incomplete is flagged Partial:
+// its fragment in u, or an empty fragment of its index appended to u, which
+// consumers merging fragments by index fold into the call.
+func (t *TruncationTracker) Observe(u *UnifiedChunk) {
+	for _, call := range u.Delta.ToolCalls {
+		if _, ok := t.arguments[call.Index]; !ok {
+			t.order = append(t.order, call.Index)
+		}
+		t.arguments[call.Index] += call.Arguments
+	}
+	if u.FinishReason != string(openai.FinishReasonLength) {
+		return
+	}
+	for _, index := range t.order {
+		if !partialArguments(t.arguments[index]) {
+			continue
+		}
+		flagged := false
+		for i := range u.Delta.ToolCalls {
+			if u.Delta.ToolCalls[i].Index == index {
+				u.Delta.ToolCalls[i].Partial, flagged = true, true
+			}
+		}
+		if !flagged {
+			u.Delta.ToolCalls = append(u.Delta.ToolCalls, UnifiedToolCall{Index: index, Partial: true})
+		}
+	}
+}
@@ -0,0 +1,1522 @@
+package transformer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phosae/llms/common"
+	"github.com/phosae/llms/config"
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+// Extensions hold provider fields without a home in the unified format. Keys
+// are "<provider>.<json path>", e.g. "claude.container" or
+// "gemini.generationConfig.responseLogprobs", values are the original JSON.
+//
+// Emit rules: when converting to a provider, the extensions of that provider
+// are written back into the target payload, overriding derived values since
+// they carry the original ones. Extensions of other providers are not emitted.
+type Extensions map[string]json.RawMessage
+
+// For returns the extensions of provider, keyed by their JSON path.
+func (e Extensions) For(provider Provider) map[string]json.RawMessage {
+	prefix := string(provider) + "."
+	fields := make(map[string]json.RawMessage)
+	for key, value := range e {
+		if path, ok := strings.CutPrefix(key, prefix); ok {
+			fields[path] = value
+		}
+	}
+	return fields
+}
+
+// UnifiedRequest is the provider-neutral pivot between request formats.
+type UnifiedRequest struct {
+	Model       string           `json:"model,omitempty"`
+	Messages    []UnifiedMessage `json:"messages"`
+	MaxTokens   int              `json:"max_tokens,omitempty"`
+	Temperature *float64         `json:"temperature,omitempty"`
+	TopP        *float64         `json:"top_p,omitempty"`
+	// TopK samples from the k most likely tokens, for Claude, Gemini and
+	// Ollama; OpenAI and Bedrock have no equivalent.
+	TopK             *int     `json:"top_k,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Stream           bool     `json:"stream,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	User             string   `json:"user,omitempty"`
+	// N is the number of choices to generate, n in OpenAI and
+	// candidateCount in Gemini; zero means one.
+	N int `json:"n,omitempty"`
+	// LogProbs and TopLogProbs ask OpenAI for the log probabilities of the
+	// output tokens.
+	LogProbs    bool `json:"logprobs,omitempty"`
+	TopLogProbs int  `json:"top_logprobs,omitempty"`
+	// Metadata is OpenAI's metadata, the labels of Vertex AI.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// ReasoningEffort is low, medium or high, empty when reasoning is off.
+	ReasoningEffort string                 `json:"reasoning_effort,omitempty"`
+	ResponseFormat  *UnifiedResponseFormat `json:"response_format,omitempty"`
+	Tools           []UnifiedTool          `json:"tools,omitempty"`
+	// ServerTools are the built-in tools the provider runs itself,
+	// ServerToolWebSearch and ServerToolCodeExecution.
+	ServerTools []string `json:"server_tools,omitempty"`
+	// ToolChoice is auto, none or required. ToolName forces one tool;
+	// AllowedTools restricts calls to the named tools, the others staying
+	// declared.
+	ToolChoice   string   `json:"tool_choice,omitempty"`
+	ToolName     string   `json:"tool_name,omitempty"`
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	// ParallelToolCalls, when false, allows at most one tool call per turn.
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+	// CacheKey and CacheRetention are OpenAI's prompt_cache_key and
+	// prompt_cache_retention; CachedContent names the Gemini cachedContents
+	// resource holding the prefix of the prompt.
+	CacheKey       string     `json:"cache_key,omitempty"`
+	CacheRetention string     `json:"cache_retention,omitempty"`
+	CachedContent  string     `json:"cached_content,omitempty"`
+	Extensions     Extensions `json:"extensions,omitempty"`
+}
+
+// UnifiedMessage is one turn of a conversation.
+type UnifiedMessage struct {
+	// Role is system, user, assistant or tool.
+	Role      string            `json:"role"`
+	Parts     []UnifiedPart     `json:"parts,omitempty"`
+	ToolCalls []UnifiedToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a tool message to the call it answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// ServerToolResults are the calls of tools the provider ran itself,
+	// with their results.
+	ServerToolResults []UnifiedServerToolResult `json:"server_tool_results,omitempty"`
+	// Citations tie spans of the text of an answer to their sources.
+	Citations  []UnifiedCitation `json:"citations,omitempty"`
+	Extensions Extensions        `json:"extensions,omitempty"`
+}
+
+// Server tools
+const (
+	ServerToolCodeExecution = "code_execution"
+	ServerToolWebSearch     = "web_search"
+	// ServerToolComputer is computer use, whose results are screenshots.
+	ServerToolComputer = "computer"
+)
+
+// UnifiedServerToolResult is a call of a server tool and its result, such
+// as Claude's code execution and web search blocks or Gemini's executable
+// code and code execution result parts.
+type UnifiedServerToolResult struct {
+	// Tool is one of the ServerTool constants.
+	Tool string `json:"tool"`
+	ID   string `json:"id,omitempty"`
+	// Input is the JSON input of the call, e.g. the code or the query.
+	Input string `json:"input,omitempty"`
+	// Output and ReturnCode are the stdout and exit status of code
+	// executions.
+	Output     string `json:"output,omitempty"`
+	ReturnCode int    `json:"return_code,omitempty"`
+	// Error is the stderr of code executions, or the error code of a
+	// failed call.
+	Error   string          `json:"error,omitempty"`
+	Sources []UnifiedSource `json:"sources,omitempty"`
+	// Images are the data URLs of the images the tool produced, such as
+	// computer screenshots or the plots of a code execution.
+	Images []string `json:"images,omitempty"`
+}
+
+// UnifiedCitation cites the source of the text of a message from
+// StartIndex to EndIndex, in characters of its text parts: a web page, or a
+// document of the request.
+type UnifiedCitation struct {
+	StartIndex int `json:"start_index"`
+	EndIndex   int `json:"end_index"`
+	// URL and Title name a web page.
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+	// CitedText is the text of the source cited, when the provider tells.
+	CitedText string `json:"cited_text,omitempty"`
+	// Document locates a cited document, nil for web pages.
+	Document *UnifiedDocumentLocation `json:"document,omitempty"`
+}
+
+// UnifiedDocumentLocation is the range of a document of the request from
+// Start to End, in Unit char, page or content_block.
+type UnifiedDocumentLocation struct {
+	Index int    `json:"index"`
+	Title string `json:"title,omitempty"`
+	Unit  string `json:"unit"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// UnifiedSource is a web page a web search found.
+type UnifiedSource struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// Unified part types
+const (
+	UnifiedPartText  = "text"
+	UnifiedPartImage = "image"
+	UnifiedPartAudio = "audio"
+	// UnifiedPartDocument is an attached file: a PDF, a text, a video. Its
+	// source is its data or its URL, in URL, or the id of a file stored by
+	// the provider, in FileID.
+	UnifiedPartDocument = "document"
+	UnifiedPartThinking = "thinking"
+	// UnifiedPartRedactedThinking is encrypted reasoning, only its provider
+	// can read it back.
+	UnifiedPartRedactedThinking = "redacted_thinking"
+)
+
+// UnifiedPart is a piece of message content.
+type UnifiedPart struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// URL of an image, audio or document part, a data URL for inline media.
+	URL string `json:"url,omitempty"`
+	// FileID is the provider id of the file of a document part.
+	FileID string `json:"file_id,omitempty"`
+	// MimeType is the media type of a document part, when its URL does not
+	// tell.
+	MimeType string `json:"mime_type,omitempty"`
+	// Title names a document part.
+	Title string `json:"title,omitempty"`
+	// Signature signs a thinking part, which in chunks closes the thinking
+	// streamed before it.
+	Signature string `json:"signature,omitempty"`
+	// Data is the content of a redacted thinking part.
+	Data         string               `json:"data,omitempty"`
+	CacheControl *common.CacheControl `json:"cache_control,omitempty"`
+	// Index is the Claude content block a part of a UnifiedChunk extends.
+	Index int `json:"index,omitempty"`
+}
+
+type UnifiedTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+	// Strict holds calls to Parameters, OpenAI and Claude strict tools and
+	// the VALIDATED function calling of Gemini.
+	Strict bool `json:"strict,omitempty"`
+}
+
+type UnifiedToolCall struct {
+	// Index identifies the call a fragment of a UnifiedChunk extends.
+	Index int    `json:"index,omitempty"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	// Arguments is a JSON object, a fragment of one in chunks.
+	Arguments string `json:"arguments"`
+	// Partial marks a call the token limit cut off: Arguments holds the part
+	// generated, which may not be valid JSON. See TruncationTracker.
+	Partial bool `json:"partial,omitempty"`
+	// Signature is the Gemini thought signature of the call, which must be
+	// sent back with it.
+	Signature string `json:"signature,omitempty"`
+}
+
+type UnifiedResponseFormat struct {
+	// Type is text, json_object or json_schema.
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// UnifiedResponse is the provider-neutral pivot between response formats.
+type UnifiedResponse struct {
+	ID    string `json:"id,omitempty"`
+	Model string `json:"model,omitempty"`
+	// SystemFingerprint is OpenAI's system_fingerprint, which with the seed of
+	// the request and Model tells whether responses are reproducible.
+	SystemFingerprint string         `json:"system_fingerprint,omitempty"`
+	Message           UnifiedMessage `json:"message"`
+	// FinishReason uses OpenAI's values: stop, length, tool_calls or content_filter.
+	FinishReason string      `json:"finish_reason,omitempty"`
+	Usage        StreamUsage `json:"usage"`
+	// ContentFilter and PromptFilter are the safety annotations of the
+	// message and the prompt, in the content filter form of Azure OpenAI.
+	ContentFilter *openai.ContentFilterResults `json:"content_filter,omitempty"`
+	PromptFilter  []openai.PromptFilterResult  `json:"prompt_filter,omitempty"`
+	Extensions    Extensions                   `json:"extensions,omitempty"`
+	// Alternatives are the choices after Message of a request for several.
+	Alternatives []UnifiedChoice `json:"alternatives,omitempty"`
+}
+
+// UnifiedChoice is one of several generated messages.
+type UnifiedChoice struct {
+	Message       UnifiedMessage               `json:"message"`
+	FinishReason  string                       `json:"finish_reason,omitempty"`
+	ContentFilter *openai.ContentFilterResults `json:"content_filter,omitempty"`
+}
+
+// UnifiedChunk is one delta of a unified stream, for the first choice.
+type UnifiedChunk struct {
+	ID                string `json:"id,omitempty"`
+	Model             string `json:"model,omitempty"`
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// Index is the choice, or Gemini candidate, the chunk extends.
+	Index int `json:"index,omitempty"`
+	// Delta holds the new text and thinking parts and tool call fragments.
+	Delta        UnifiedMessage `json:"delta"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	// Usage is set on chunks reporting usage, with the totals so far.
+	Usage *StreamUsage `json:"usage,omitempty"`
+}
+
+// HasContent reports whether the chunk carries generated content: text,
+// thinking, images, tool calls or server tool results. Chunks opening a
+// stream or only reporting usage or a finish reason carry none.
+func (c *UnifiedChunk) HasContent() bool {
+	delta := c.Delta
+	return len(delta.ToolCalls) > 0 || len(delta.ServerToolResults) > 0 ||
+		slices.ContainsFunc(delta.Parts, func(part UnifiedPart) bool { return part.Text != "" || part.Data != "" || part.URL != "" })
+}
+
+// unifiedHomes lists the payload fields the unified format holds, every other
+// non-empty field becomes an extension. Objects listed in nestedHomes are
+// split one level deeper.
+var (
+	unifiedRequestHomes = map[Provider][]string{
+		ProviderOpenAI: {"model", "messages", "max_tokens", "max_completion_tokens", "temperature", "top_p", "stop",
+			"stream", "seed", "n", "user", "reasoning_effort", "response_format", "tools", "tool_choice", "web_search_options",
+			"parallel_tool_calls", "prompt_cache_key", "prompt_cache_retention", "cached_content", "presence_penalty",
+			"frequency_penalty", "metadata", "logprobs", "top_logprobs"},
+		ProviderClaude: {"model", "system", "messages", "max_tokens", "temperature", "top_p", "top_k", "stop_sequences",
+			"stream", "tools", "tool_choice", "output_format", "thinking", "metadata"},
+		ProviderGemini: {"contents", "systemInstruction", "tools", "toolConfig", "cachedContent",
+			"generationConfig.temperature", "generationConfig.topP", "generationConfig.topK", "generationConfig.maxOutputTokens",
+			"generationConfig.stopSequences", "generationConfig.seed", "generationConfig.responseMimeType",
+			"generationConfig.responseSchema", "generationConfig.candidateCount", "generationConfig.presencePenalty",
+			"generationConfig.frequencyPenalty", "generationConfig.thinkingConfig"},
+		ProviderBedrock: {"modelId", "messages", "system", "inferenceConfig", "toolConfig",
+			"additionalModelRequestFields.thinking"},
+		ProviderVertexGemini: {"contents", "systemInstruction", "tools", "toolConfig", "cachedContent", "labels",
+			"generationConfig.temperature", "generationConfig.topP", "generationConfig.topK", "generationConfig.maxOutputTokens",
+			"generationConfig.stopSequences", "generationConfig.seed", "generationConfig.responseMimeType",
+			"generationConfig.responseSchema", "generationConfig.candidateCount", "generationConfig.presencePenalty",
+			"generationConfig.frequencyPenalty", "generationConfig.thinkingConfig"},
+		ProviderOllama: {"model", "messages", "tools", "format", "stream", "think", "options.temperature",
+			"options.top_p", "options.top_k", "options.num_predict", "options.stop", "options.seed",
+			"options.presence_penalty", "options.frequency_penalty"},
+	}
+	unifiedResponseHomes = map[Provider][]string{
+		ProviderOpenAI:       {"id", "object", "created", "model", "system_fingerprint", "choices", "usage", "prompt_filter_results"},
+		ProviderClaude:       {"id", "type", "role", "content", "stop_reason", "model", "usage"},
+		ProviderGemini:       {"candidates", "usageMetadata", "responseId", "modelVersion"},
+		ProviderBedrock:      {"output", "stopReason", "usage"},
+		ProviderVertexGemini: {"candidates", "usageMetadata", "responseId", "modelVersion"},
+		ProviderOllama:       {"model", "created_at", "message", "done", "done_reason", "prompt_eval_count", "eval_count"},
+	}
+	// OpenAI messages map one to one, so their fields are kept per message
+	unifiedOpenAIMessageHomes = []string{"role", "content", "reasoning_content", "tool_calls", "tool_call_id", "cache_control"}
+
+	nestedHomes = map[string]bool{"generationConfig": true, "additionalModelRequestFields": true, "options": true}
+)
+
+// ToUnifiedRequest converts a provider request to the unified format.
+func ToUnifiedRequest(ctx context.Context, provider Provider, src interface{}) (*UnifiedRequest, error) {
+	oaiReq := &openai.ChatCompletionRequest{}
+	pctx, pivotWarnings := pivotContext(ctx)
+	switch req := src.(type) {
+	case *openai.ChatCompletionRequest:
+		oaiReq = req
+	case *claude.ClaudeRequest:
+		if err := transformRequestToOpenAI(pctx, req, oaiReq); err != nil {
+			return nil, err
+		}
+	case *gemini.GeminiChatRequest:
+		if err := transformGeminiRequestToOpenAI(pctx, req, oaiReq); err != nil {
+			return nil, err
+		}
+	case *bedrock.ConverseRequest:
+		if err := transformBedrockRequestToOpenAI(pctx, req, oaiReq); err != nil {
+			return nil, err
+		}
+	case *vertex.GenerateContentRequest:
+		if err := transformVertexRequestToOpenAI(pctx, req, oaiReq); err != nil {
+			return nil, err
+		}
+	case *ollama.ChatRequest:
+		if err := transformOllamaRequestToOpenAI(pctx, req, oaiReq); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported request type %T", src)
+	}
+
+	u, err := unifiedFromOpenAIRequest(ctx, oaiReq)
+	if err != nil {
+		return nil, err
+	}
+	// OpenAI has no top_k, it bypasses the pivot
+	var topKPath string
+	u.TopK, topKPath = requestTopK(src)
+	if u.Extensions, err = collectExtensions(provider, src, unifiedRequestHomes[provider]); err != nil {
+		return nil, err
+	}
+	if err := forwardPivotWarnings(ctx, provider, pivotWarnings, u.Extensions, topKPath); err != nil {
+		return nil, err
+	}
+	if provider == ProviderOpenAI {
+		for i, message := range oaiReq.Messages {
+			if u.Messages[i].Extensions, err = collectExtensions(provider, message, unifiedOpenAIMessageHomes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return u, nil
+}
+
+// FromUnifiedRequest converts a unified request to the provider request dst.
+func FromUnifiedRequest(ctx context.Context, provider Provider, u *UnifiedRequest, dst interface{}) error {
+	oaiReq, err := openAIFromUnifiedRequest(u)
+	if err != nil {
+		return err
+	}
+
+	switch req := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		oaiReq.Model = TargetModel(ctx, ProviderOpenAI, oaiReq.Model)
+		if err := dropCodeInterpreter(ctx, oaiReq); err != nil {
+			return err
+		}
+		if oaiReq, err = normalizeTools(ctx, ProviderOpenAI, oaiReq); err != nil {
+			return err
+		}
+		stripOpenAIReasoning(ctx, oaiReq)
+		*req = *oaiReq
+		for i, message := range u.Messages {
+			if err := applyExtensions(provider, message.Extensions, &req.Messages[i]); err != nil {
+				return err
+			}
+		}
+	case *claude.ClaudeRequest:
+		if err := transformRequestToClaude(ctx, oaiReq, req); err != nil {
+			return err
+		}
+	case *gemini.GeminiChatRequest:
+		if err := transformRequestToGemini(ctx, oaiReq, req); err != nil {
+			return err
+		}
+	case *bedrock.ConverseRequest:
+		if err := transformRequestToBedrock(ctx, oaiReq, req); err != nil {
+			return err
+		}
+	case *vertex.GenerateContentRequest:
+		if err := transformRequestToVertex(ctx, oaiReq, req); err != nil {
+			return err
+		}
+	case *ollama.ChatRequest:
+		if err := transformRequestToOllama(ctx, oaiReq, req); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported request type %T", dst)
+	}
+	if err := applyTopK(ctx, provider, u.TopK, dst); err != nil {
+		return err
+	}
+	return applyExtensions(provider, u.Extensions, dst)
+}
+
+// requestTopK returns the top_k of a request and its path, nil for
+// providers without one.
+func requestTopK(src interface{}) (*int, string) {
+	var topK *float64
+	var path string
+	switch req := src.(type) {
+	case *claude.ClaudeRequest:
+		return req.TopK, "top_k"
+	case *gemini.GeminiChatRequest:
+		topK, path = req.GenerationConfig.TopK, "generationConfig.topK"
+	case *vertex.GenerateContentRequest:
+		topK, path = req.GenerationConfig.TopK, "generationConfig.topK"
+	case *ollama.ChatRequest:
+		if k, ok := ollamaNumber(req.Options["top_k"]); ok {
+			topK, path = &k, "options.top_k"
+		}
+	}
+	if topK == nil {
+		return nil, ""
+	}
+	k := int(*topK)
+	return &k, path
+}
+
+// applyTopK sets the top_k of the request dst of provider.
+func applyTopK(ctx context.Context, provider Provider, topK *int, dst interface{}) error {
+	if topK == nil {
+		return nil
+	}
+	k := float64(*topK)
+	switch req := dst.(type) {
+	case *claude.ClaudeRequest:
+		req.TopK = topK
+	case *gemini.GeminiChatRequest:
+		req.GenerationConfig.TopK = &k
+	case *vertex.GenerateContentRequest:
+		req.GenerationConfig.TopK = &k
+	case *ollama.ChatRequest:
+		if req.Options == nil {
+			req.Options = make(map[string]any)
+		}
+		req.Options["top_k"] = *topK
+	default:
+		return dropUnsupported(ctx, "top_k", "%s has no top_k", provider)
+	}
+	return nil
+}
+
+// ToUnifiedResponse converts a provider response to the unified format.
+func ToUnifiedResponse(ctx context.Context, provider Provider, src interface{}) (*UnifiedResponse, error) {
+	oaiResp := &openai.ChatCompletionResponse{}
+	pctx, pivotWarnings := pivotContext(ctx)
+	var serverTools []UnifiedServerToolResult
+	switch resp := src.(type) {
+	case *openai.ChatCompletionResponse:
+		oaiResp = resp
+	case *claude.ClaudeResponse:
+		// server tools are kept apart from the text the pivot renders them into
+		content, results, err := splitClaudeServerTools(resp.Content)
+		if err != nil {
+			return nil, err
+		}
+		stripped := *resp
+		stripped.Content = content
+		if err := transformResponseToOpenAI(pctx, &stripped, oaiResp); err != nil {
+			return nil, err
+		}
+		serverTools = results
+	case *gemini.GeminiChatResponse:
+		stripped := *resp
+		if len(resp.Candidates) > 0 {
+			stripped.Candidates = slices.Clone(resp.Candidates)
+			stripped.Candidates[0].Content.Parts, serverTools = splitGeminiServerTools(resp.Candidates[0].Content.Parts)
+		}
+		if err := transformGeminiResponseToOpenAI(pctx, &stripped, oaiResp); err != nil {
+			return nil, err
+		}
+	case *bedrock.ConverseResponse:
+		if err := transformBedrockResponseToOpenAI(pctx, resp, oaiResp); err != nil {
+			return nil, err
+		}
+	case *vertex.GenerateContentResponse:
+		stripped := *resp
+		if len(resp.Candidates) > 0 {
+			stripped.Candidates = slices.Clone(resp.Candidates)
+			stripped.Candidates[0].Content.Parts, serverTools = splitGeminiServerTools(resp.Candidates[0].Content.Parts)
+		}
+		if err := transformVertexResponseToOpenAI(pctx, &stripped, oaiResp); err != nil {
+			return nil, err
+		}
+	case *ollama.ChatResponse:
+		if err := transformOllamaResponseToOpenAI(pctx, resp, oaiResp); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported response type %T", src)
+	}
+
+	u := &UnifiedResponse{
+		ID:                oaiResp.ID,
+		Model:             oaiResp.Model,
+		SystemFingerprint: oaiResp.SystemFingerprint,
+		Usage:             StreamUsageFromOpenAI(&oaiResp.Usage),
+		PromptFilter:      oaiResp.PromptFilterResults,
+	}
+	if len(oaiResp.Choices) > 0 {
+		// Message is choice 0, however the choices are listed
+		choices := slices.SortedStableFunc(slices.Values(oaiResp.Choices), func(a, b openai.ChatCompletionChoice) int {
+			return cmp.Compare(a.Index, b.Index)
+		})
+		choice := choices[0]
+		message, err := unifiedFromOpenAIMessage(ctx, 0, choice.Message)
+		if err != nil {
+			return nil, err
+		}
+		u.Message = message
+		u.Message.ServerToolResults = serverTools
+		restoreUnifiedToolCalls(ctx, u.Message.ToolCalls)
+		u.FinishReason = string(choice.FinishReason)
+		flagPartialToolCalls(u.FinishReason, u.Message.ToolCalls)
+		if contentFilterAnnotated(choice.ContentFilterResults) {
+			u.ContentFilter = &choice.ContentFilterResults
+		}
+		for i, choice := range choices[1:] {
+			message, err := unifiedFromOpenAIMessage(ctx, i+1, choice.Message)
+			if err != nil {
+				return nil, err
+			}
+			restoreUnifiedToolCalls(ctx, message.ToolCalls)
+			flagPartialToolCalls(string(choice.FinishReason), message.ToolCalls)
+			alternative := UnifiedChoice{Message: message, FinishReason: string(choice.FinishReason)}
+			if contentFilterAnnotated(choice.ContentFilterResults) {
+				alternative.ContentFilter = &choice.ContentFilterResults
+			}
+			u.Alternatives = append(u.Alternatives, alternative)
+		}
+	} else if promptFiltered(oaiResp.PromptFilterResults) {
+		u.FinishReason = string(openai.FinishReasonContentFilter)
+	}
+
+	var err error
+	if u.Extensions, err = collectExtensions(provider, src, unifiedResponseHomes[provider]); err != nil {
+		return nil, err
+	}
+	if err := forwardPivotWarnings(ctx, provider, pivotWarnings, u.Extensions); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// FromUnifiedResponse converts a unified response to the provider response dst.
+func FromUnifiedResponse(ctx context.Context, provider Provider, u *UnifiedResponse, dst interface{}) error {
+	message, err := openAIFromUnifiedMessage(u.Message)
+	if err != nil {
+		return err
+	}
+	serverTools := u.Message.ServerToolResults
+	switch dst.(type) {
+	case *claude.ClaudeResponse, *gemini.GeminiChatResponse, *vertex.GenerateContentResponse:
+	default:
+		var rendered string
+		for i, result := range serverTools {
+			text, err := renderServerToolResult(ctx, fmt.Sprintf("server_tool_results[%d]", i), result)
+			if err != nil {
+				return err
+			}
+			rendered += text
+		}
+		if rendered == "" {
+			break
+		}
+		if len(message.MultiContent) > 0 {
+			message.MultiContent = append([]openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: rendered}}, message.MultiContent...)
+		} else {
+			message.Content = rendered + message.Content
+		}
+	}
+	oaiResp := &openai.ChatCompletionResponse{
+		ID:      u.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   u.Model,
+		Choices: []openai.ChatCompletionChoice{
+			{Message: message, FinishReason: openai.FinishReason(u.FinishReason)},
+		},
+		SystemFingerprint:   u.SystemFingerprint,
+		Usage:               *u.Usage.OpenAI(),
+		PromptFilterResults: u.PromptFilter,
+	}
+	if u.ContentFilter != nil {
+		oaiResp.Choices[0].ContentFilterResults = *u.ContentFilter
+	}
+	for i, alternative := range u.Alternatives {
+		message, err := openAIFromUnifiedMessage(alternative.Message)
+		if err != nil {
+			return err
+		}
+		choice := openai.ChatCompletionChoice{Index: i + 1, Message: message, FinishReason: openai.FinishReason(alternative.FinishReason)}
+		if alternative.ContentFilter != nil {
+			choice.ContentFilterResults = *alternative.ContentFilter
+		}
+		oaiResp.Choices = append(oaiResp.Choices, choice)
+	}
+
+	switch resp := dst.(type) {
+	case *openai.ChatCompletionResponse:
+		*resp = *oaiResp
+	case *claude.ClaudeResponse:
+		if err := transformResponseToClaude(ctx, oaiResp, resp); err != nil {
+			return err
+		}
+		blocks, err := claudeServerToolBlocks(ctx, serverTools)
+		if err != nil {
+			return err
+		}
+		resp.Content = append(blocks, resp.Content...)
+	case *gemini.GeminiChatResponse:
+		if err := transformResponseToGemini(ctx, oaiResp, resp); err != nil {
+			return err
+		}
+		parts, err := geminiServerToolParts(ctx, serverTools)
+		if err != nil {
+			return err
+		}
+		if len(parts) > 0 && len(resp.Candidates) > 0 {
+			resp.Candidates[0].Content.Parts = append(parts, resp.Candidates[0].Content.Parts...)
+		}
+	case *bedrock.ConverseResponse:
+		if err := transformResponseToBedrock(ctx, oaiResp, resp); err != nil {
+			return err
+		}
+	case *vertex.GenerateContentResponse:
+		if err := transformResponseToVertex(ctx, oaiResp, resp); err != nil {
+			return err
+		}
+		parts, err := geminiServerToolParts(ctx, serverTools)
+		if err != nil {
+			return err
+		}
+		if len(parts) > 0 && len(resp.Candidates) > 0 {
+			resp.Candidates[0].Content.Parts = append(parts, resp.Candidates[0].Content.Parts...)
+		}
+	case *ollama.ChatResponse:
+		if err := transformResponseToOllama(ctx, oaiResp, resp); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported response type %T", dst)
+	}
+	return applyExtensions(provider, u.Extensions, dst)
+}
+
+// ToUnifiedChunk converts a provider stream chunk to the unified format.
+// Claude events convert one by one: parts and tool call fragments are
+// indexed by content block and events without content yield an empty chunk.
+// Of chunks with several choices or candidates, the first is kept.
+func ToUnifiedChunk(ctx context.Context, provider Provider, src interface{}) (*UnifiedChunk, error) {
+	switch chunk := src.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		u := unifiedFromOpenAIChunk(chunk)
+		restoreUnifiedToolCalls(ctx, u.Delta.ToolCalls)
+		return u, nil
+	case *gemini.GeminiChatResponse:
+		oaiChunk := &openai.ChatCompletionStreamResponse{}
+		if err := transformGeminiChunkToOpenAI(ctx, chunk, oaiChunk); err != nil {
+			return nil, err
+		}
+		u := unifiedFromOpenAIChunk(oaiChunk)
+		signGeminiChunk(u, chunk)
+		if chunk.UsageMetadata.TotalTokenCount > 0 {
+			usage := StreamUsageFromGemini(&chunk.UsageMetadata)
+			u.Usage = &usage
+		}
+		return u, nil
+	case *claude.ClaudeResponse:
+		u, err := unifiedFromClaudeEvent(chunk)
+		if err != nil {
+			return nil, err
+		}
+		restoreUnifiedToolCalls(ctx, u.Delta.ToolCalls)
+		return u, nil
+	case *vertex.GenerateContentResponse:
+		u, err := ToUnifiedChunk(ctx, ProviderGemini, &chunk.GeminiChatResponse)
+		if err != nil {
+			return nil, err
+		}
+		u.ID, u.Model = chunk.ResponseID, chunk.ModelVersion
+		return u, nil
+	case *bedrock.ConverseStreamEvent:
+		oaiChunk := &openai.ChatCompletionStreamResponse{}
+		if err := transformBedrockChunkToOpenAI(ctx, chunk, oaiChunk); err != nil {
+			return nil, err
+		}
+		return unifiedFromOpenAIChunk(oaiChunk), nil
+	case *ollama.ChatResponse:
+		oaiChunk := &openai.ChatCompletionStreamResponse{}
+		if err := transformOllamaChunkToOpenAI(ctx, chunk, oaiChunk); err != nil {
+			return nil, err
+		}
+		return unifiedFromOpenAIChunk(oaiChunk), nil
+	default:
+		return nil, fmt.Errorf("unsupported chunk type %T", src)
+	}
+}
+
+// FromUnifiedChunk converts a unified chunk to the provider chunk dst. Tool
+// calls must be complete for Gemini and Ollama, which stream calls whole and
+// drop partial ones. A chunk converts to the one Claude event it holds, with
+// the content block index of its part or tool call, so a chunk of several
+// events fails; the start and stop events of text and thinking blocks are
+// left to the caller, StreamTransformer emits the whole stream. Bedrock
+// events depend on the blocks opened before them, use
+// BedrockStreamTransformer.
+func FromUnifiedChunk(ctx context.Context, provider Provider, u *UnifiedChunk, dst interface{}) error {
+	switch chunk := dst.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		*chunk = *openAIFromUnifiedChunk(u)
+	case *gemini.GeminiChatResponse:
+		var parts []gemini.GeminiPart
+		for _, part := range u.Delta.Parts {
+			switch part.Type {
+			case UnifiedPartThinking:
+				parts = append(parts, gemini.GeminiPart{Text: part.Text, Thought: part.Text != "", ThoughtSignature: part.Signature})
+			case UnifiedPartText:
+				parts = append(parts, gemini.GeminiPart{Text: part.Text})
+			}
+		}
+		for i, call := range u.Delta.ToolCalls {
+			if call.Partial {
+				if err := dropUnsupported(ctx, fmt.Sprintf("delta.tool_calls[%d]", i), "Gemini has no partial function calls"); err != nil {
+					return err
+				}
+				continue
+			}
+			var args any = map[string]any{}
+			if call.Arguments != "" {
+				if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+					return fmt.Errorf("delta.tool_calls[%d]: incomplete arguments: %w", i, err)
+				}
+			}
+			parts = append(parts, gemini.GeminiPart{FunctionCall: &gemini.FunctionCall{FunctionName: call.Name, Arguments: args}, ThoughtSignature: call.Signature})
+		}
+		*chunk = gemini.GeminiChatResponse{ResponseID: u.ID, ModelVersion: u.Model, Candidates: []gemini.GeminiChatCandidate{{
+			Content: gemini.GeminiChatContent{Role: "model", Parts: parts},
+			Index:   int64(u.Index),
+		}}}
+		if u.FinishReason != "" {
+			reason := finishReasonOpenAI2Gemini(openai.FinishReason(u.FinishReason))
+			chunk.Candidates[0].FinishReason = &reason
+		}
+		if u.Usage != nil {
+			chunk.UsageMetadata = u.Usage.Gemini()
+		}
+	case *vertex.GenerateContentResponse:
+		*chunk = vertex.GenerateContentResponse{}
+		return FromUnifiedChunk(ctx, ProviderGemini, u, &chunk.GeminiChatResponse)
+	case *ollama.ChatResponse:
+		*chunk = ollama.ChatResponse{Model: u.Model, CreatedAt: ollamaCreatedAt(0), Message: ollama.Message{Role: "assistant"}}
+		for _, part := range u.Delta.Parts {
+			switch part.Type {
+			case UnifiedPartThinking:
+				chunk.Message.Thinking += part.Text
+			case UnifiedPartText:
+				chunk.Message.Content += part.Text
+			}
+		}
+		for i, call := range u.Delta.ToolCalls {
+			if call.Partial {
+				if err := dropUnsupported(ctx, fmt.Sprintf("delta.tool_calls[%d]", i), "Ollama has no partial tool calls"); err != nil {
+					return err
+				}
+				continue
+			}
+			arguments := map[string]any{}
+			if call.Arguments != "" {
+				if err := json.Unmarshal([]byte(call.Arguments), &arguments); err != nil {
+					return fmt.Errorf("delta.tool_calls[%d]: incomplete arguments: %w", i, err)
+				}
+			}
+			chunk.Message.ToolCalls = append(chunk.Message.ToolCalls, ollama.ToolCall{Function: ollama.ToolCallFunction{Name: call.Name, Arguments: arguments}})
+		}
+		if u.FinishReason != "" {
+			chunk.Done = true
+			chunk.DoneReason = doneReasonOpenAI2Ollama(openai.FinishReason(u.FinishReason))
+		}
+		if u.Usage != nil {
+			chunk.Metrics = u.Usage.Ollama()
+		}
+	case *claude.ClaudeResponse:
+		event, err := claudeEventFromUnified(u)
+		if err != nil {
+			return err
+		}
+		*chunk = *event
+	case *bedrock.ConverseStreamEvent:
+		return &TransformationError{
+			Type:    "unsupported_transformation",
+			Message: "Bedrock stream events depend on the stream state, use BedrockStreamTransformer",
+		}
+	default:
+		return fmt.Errorf("unsupported chunk type %T", dst)
+	}
+	return nil
+}
+
+func unifiedFromOpenAIChunk(chunk *openai.ChatCompletionStreamResponse) *UnifiedChunk {
+	u := &UnifiedChunk{ID: chunk.ID, Model: chunk.Model, SystemFingerprint: chunk.SystemFingerprint}
+	if chunk.Usage != nil {
+		usage := StreamUsageFromOpenAI(chunk.Usage)
+		u.Usage = &usage
+	}
+	for _, choice := range chunk.Choices[:min(len(chunk.Choices), 1)] {
+		u.Index = choice.Index
+		delta := choice.Delta
+		u.Delta.Role = delta.Role
+		if delta.ReasoningContent != "" {
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartThinking, Text: delta.ReasoningContent})
+		}
+		for _, detail := range delta.ReasoningDetails {
+			u.Delta.Parts = append(u.Delta.Parts, unifiedFromReasoningDetail(detail))
+		}
+		if delta.Content != "" {
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartText, Text: delta.Content})
+		}
+		for _, call := range delta.ToolCalls {
+			index := 0
+			if call.Index != nil {
+				index = *call.Index
+			}
+			unified := UnifiedToolCall{Index: index, ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments}
+			if extra := call.ExtraContent; extra != nil && extra.Google != nil {
+				unified.Signature = extra.Google.ThoughtSignature
+			}
+			u.Delta.ToolCalls = append(u.Delta.ToolCalls, unified)
+		}
+		if choice.FinishReason != openai.FinishReasonNull {
+			u.FinishReason = string(choice.FinishReason)
+		}
+	}
+	return u
+}
+
+// unifiedFromReasoningDetail converts a reasoning detail to a thinking or
+// redacted thinking part.
+func unifiedFromReasoningDetail(detail openai.ReasoningDetail) UnifiedPart {
+	if detail.Type == openai.ReasoningDetailTypeEncrypted {
+		return UnifiedPart{Type: UnifiedPartRedactedThinking, Data: detail.Data}
+	}
+	return UnifiedPart{Type: UnifiedPartThinking, Text: detail.Text, Signature: detail.Signature}
+}
+
+// signGeminiChunk adds the thought signatures of the Gemini candidate u
+// was converted from, which the OpenAI pivot keeps on calls alone, as
+// signature parts.
+func signGeminiChunk(u *UnifiedChunk, chunk *gemini.GeminiChatResponse) {
+	i := slices.IndexFunc(chunk.Candidates, func(candidate gemini.GeminiChatCandidate) bool { return int(candidate.Index) == u.Index })
+	if i < 0 {
+		return
+	}
+	for _, part := range chunk.Candidates[i].Content.Parts {
+		if part.ThoughtSignature != "" && part.FunctionCall == nil {
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartThinking, Signature: part.ThoughtSignature})
+		}
+	}
+}
+
+func openAIFromUnifiedChunk(u *UnifiedChunk) *openai.ChatCompletionStreamResponse {
+	chunk := &openai.ChatCompletionStreamResponse{ID: u.ID, Object: "chat.completion.chunk", Model: u.Model, SystemFingerprint: u.SystemFingerprint}
+	if u.Usage != nil {
+		chunk.Usage = u.Usage.OpenAI()
+	}
+	choice := openai.ChatCompletionStreamChoice{
+		Index:        u.Index,
+		Delta:        openai.ChatCompletionStreamChoiceDelta{Role: u.Delta.Role},
+		FinishReason: openai.FinishReason(u.FinishReason),
+	}
+	for _, part := range u.Delta.Parts {
+		switch part.Type {
+		case UnifiedPartThinking:
+			choice.Delta.ReasoningContent += part.Text
+			if part.Signature != "" {
+				choice.Delta.ReasoningDetails = append(choice.Delta.ReasoningDetails, openai.ReasoningDetail{Type: openai.ReasoningDetailTypeText, Signature: part.Signature})
+			}
+		case UnifiedPartRedactedThinking:
+			choice.Delta.ReasoningDetails = append(choice.Delta.ReasoningDetails, openai.ReasoningDetail{Type: openai.ReasoningDetailTypeEncrypted, Data: part.Data})
+		case UnifiedPartText:
+			choice.Delta.Content += part.Text
+		}
+	}
+	for _, call := range u.Delta.ToolCalls {
+		if call.Partial && call.ID == "" && call.Name == "" && call.Arguments == "" {
+			// OpenAI tells partial calls by the length finish alone
+			continue
+		}
+		index := call.Index
+		toolCall := openai.ToolCall{Index: &index, ID: call.ID, Function: openai.FunctionCall{Name: call.Name, Arguments: call.Arguments}}
+		if call.ID != "" {
+			toolCall.Type = openai.ToolTypeFunction
+		}
+		if call.Signature != "" {
+			toolCall.ExtraContent = &openai.ToolCallExtraContent{Google: &openai.GoogleToolCallExtraContent{ThoughtSignature: call.Signature}}
+		}
+		choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, toolCall)
+	}
+	if u.Usage != nil && len(u.Delta.Parts) == 0 && len(u.Delta.ToolCalls) == 0 && u.Delta.Role == "" && u.FinishReason == "" {
+		// a usage-only chunk
+		chunk.Choices = []openai.ChatCompletionStreamChoice{}
+		return chunk
+	}
+	chunk.Choices = []openai.ChatCompletionStreamChoice{choice}
+	return chunk
+}
+
+// claudeEventFromUnified inverts unifiedFromClaudeEvent. The role and usage
+// ride on message_start and message_delta, chunks without content, role,
+// usage or a finish reason become pings.
+func claudeEventFromUnified(u *UnifiedChunk) (*claude.ClaudeResponse, error) {
+	parts, calls := u.Delta.Parts, u.Delta.ToolCalls
+	events := len(parts) + len(calls)
+	if u.FinishReason != "" {
+		events++
+	}
+	if len(parts) == 1 && parts[0].Text != "" && parts[0].Signature != "" {
+		events++
+	}
+	if len(calls) == 1 && (calls[0].ID != "" || calls[0].Name != "") && calls[0].Arguments != "" {
+		events++
+	}
+	if events > 1 {
+		return nil, &TransformationError{
+			Type:    "unsupported_transformation",
+			Message: "the chunk holds several Claude events, use StreamTransformer",
+		}
+	}
+
+	switch {
+	case len(parts) == 1:
+		part := parts[0]
+		switch part.Type {
+		case UnifiedPartText:
+			return blockEvent("content_block_delta", part.Index, &claude.ClaudeMediaMessage{Type: "text_delta", Text: &part.Text}), nil
+		case UnifiedPartThinking:
+			if part.Signature != "" {
+				return blockEvent("content_block_delta", part.Index, &claude.ClaudeMediaMessage{Type: "signature_delta", Signature: part.Signature}), nil
+			}
+			return blockEvent("content_block_delta", part.Index, &claude.ClaudeMediaMessage{Type: "thinking_delta", Thinking: part.Text}), nil
+		case UnifiedPartRedactedThinking:
+			event := &claude.ClaudeResponse{Type: "content_block_start", ContentBlock: &claude.ClaudeMediaMessage{Type: "redacted_thinking", Data: part.Data}}
+			event.SetIndex(part.Index)
+			return event, nil
+		default:
+			return nil, fmt.Errorf("delta.parts[0]: Claude streams no %s parts", part.Type)
+		}
+	case len(calls) == 1:
+		call := calls[0]
+		if call.ID != "" || call.Name != "" {
+			event := &claude.ClaudeResponse{Type: "content_block_start", ContentBlock: &claude.ClaudeMediaMessage{
+				Type: "tool_use", Id: call.ID, Name: call.Name, Input: map[string]any{},
+			}}
+			event.SetIndex(call.Index)
+			return event, nil
+		}
+		return blockEvent("content_block_delta", call.Index, &claude.ClaudeMediaMessage{Type: "input_json_delta", PartialJson: &call.Arguments}), nil
+	case u.Delta.Role != "" && u.FinishReason == "":
+		usage := &claude.ClaudeUsage{}
+		if u.Usage != nil {
+			usage = u.Usage.Claude()
+		}
+		return &claude.ClaudeResponse{Type: "message_start", Message: &claude.ClaudeMediaMessage{
+			Type: "message", Id: u.ID, Role: "assistant", Model: u.Model, Content: []any{}, Usage: usage,
+		}}, nil
+	case u.FinishReason != "" || u.Usage != nil:
+		event := &claude.ClaudeResponse{Type: "message_delta", Delta: &claude.ClaudeMediaMessage{}}
+		if u.FinishReason != "" {
+			reason := stopReasonOpenAI2Claude(u.FinishReason)
+			event.Delta.StopReason = &reason
+		}
+		if u.Usage != nil {
+			event.Usage = u.Usage.Claude()
+		}
+		return event, nil
+	default:
+		return &claude.ClaudeResponse{Type: "ping"}, nil
+	}
+}
+
+func unifiedFromClaudeEvent(event *claude.ClaudeResponse) (*UnifiedChunk, error) {
+	u := &UnifiedChunk{}
+	switch event.Type {
+	case "error":
+		if event.Error != nil {
+			return nil, &TransformationError{Type: event.Error.Type, Message: event.Error.Message}
+		}
+	case "message_start":
+		if message := event.Message; message != nil {
+			u.ID, u.Model = message.Id, message.Model
+			if message.Usage != nil {
+				usage := StreamUsageFromClaude(message.Usage)
+				u.Usage = &usage
+			}
+		}
+		u.Delta.Role = openai.ChatMessageRoleAssistant
+	case "content_block_start":
+		block := event.ContentBlock
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "text":
+			if text := block.GetText(); text != "" {
+				u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartText, Text: text, Index: event.GetIndex()})
+			}
+		case "thinking":
+			if block.Thinking != "" {
+				u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartThinking, Text: block.Thinking, Index: event.GetIndex()})
+			}
+		case "redacted_thinking":
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartRedactedThinking, Data: block.Data, Index: event.GetIndex()})
+		case "tool_use":
+			u.Delta.ToolCalls = append(u.Delta.ToolCalls, UnifiedToolCall{Index: event.GetIndex(), ID: block.Id, Name: block.Name})
+		}
+	case "content_block_delta":
+		delta := event.Delta
+		if delta == nil {
+			break
+		}
+		switch delta.Type {
+		case "text_delta":
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartText, Text: delta.GetText(), Index: event.GetIndex()})
+		case "thinking_delta":
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartThinking, Text: delta.Thinking, Index: event.GetIndex()})
+		case "signature_delta":
+			u.Delta.Parts = append(u.Delta.Parts, UnifiedPart{Type: UnifiedPartThinking, Signature: delta.Signature, Index: event.GetIndex()})
+		case "input_json_delta":
+			if delta.PartialJson != nil {
+				u.Delta.ToolCalls = append(u.Delta.ToolCalls, UnifiedToolCall{Index: event.GetIndex(), Arguments: *delta.PartialJson})
+			}
+		}
+	case "message_delta":
+		if event.Delta != nil && event.Delta.StopReason != nil {
+			u.FinishReason = string(stopReasonClaude2OpenAI(*event.Delta.StopReason))
+		}
+		if event.Usage != nil {
+			usage := StreamUsageFromClaude(event.Usage)
+			u.Usage = &usage
+		}
+	}
+	return u, nil
+}
+
+// toUnified converts a request, response or chunk of provider, as typ says,
+// to the unified format.
+func toUnified(ctx context.Context, provider Provider, typ TransformerType, src interface{}) (interface{}, error) {
+	switch typ {
+	case TransformerTypeRequest:
+		return ToUnifiedRequest(ctx, provider, src)
+	case TransformerTypeResponse:
+		return ToUnifiedResponse(ctx, provider, src)
+	case TransformerTypeChunk:
+		return ToUnifiedChunk(ctx, provider, src)
+	default:
+		return nil, fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+}
+
+// fromUnified converts a unified payload of typ to the payload dst of provider.
+func fromUnified(ctx context.Context, provider Provider, typ TransformerType, src interface{}, dst interface{}) error {
+	switch u := src.(type) {
+	case *UnifiedRequest:
+		if typ == TransformerTypeRequest {
+			return FromUnifiedRequest(ctx, provider, u, dst)
+		}
+	case *UnifiedResponse:
+		if typ == TransformerTypeResponse {
+			return FromUnifiedResponse(ctx, provider, u, dst)
+		}
+	case *UnifiedChunk:
+		if typ == TransformerTypeChunk {
+			return FromUnifiedChunk(ctx, provider, u, dst)
+		}
+	}
+	return fmt.Errorf("unsupported unified %s type %T", typ, src)
+}
+
+// transformViaUnified transforms src of source into dst of target through
+// the unified format, for pairs without a direct conversion
+func transformViaUnified(ctx context.Context, source, target Provider, typ TransformerType, src interface{}, dst interface{}) error {
+	u, err := toUnified(ctx, source, typ, src)
+	if err != nil {
+		return err
+	}
+	return fromUnified(ctx, target, typ, u, dst)
+}
+
+// pivotContext drops the configuration and options of ctx, which apply to the
+// final target only, and collects the pivot hop's warnings separately
+func pivotContext(ctx context.Context) (context.Context, *Warnings) {
+	ctx = WithOptions(config.NewContext(ctx, nil), Options{})
+	// detached from the collector of ctx, forwardPivotWarnings filters them first
+	w := &Warnings{}
+	return context.WithValue(ctx, warningsKey{}, w), w
+}
+
+// forwardPivotWarnings reports the pivot hop's warnings to ctx, except those
+// about fields kept as extensions or carried beside the pivot
+func forwardPivotWarnings(ctx context.Context, provider Provider, warnings *Warnings, ext Extensions, carried ...string) error {
+	for _, w := range warnings.List() {
+		path, _, _ := strings.Cut(w.Path, "[")
+		field, _, _ := strings.Cut(path, ".")
+		if _, kept := ext[string(provider)+"."+field]; kept {
+			continue
+		}
+		if _, kept := ext[string(provider)+"."+path]; kept || slices.Contains(carried, path) {
+			continue
+		}
+		if w.Code == WarningDroppedContent && OptionsFromContext(ctx).Strict {
+			return &TransformationError{
+				Type:    "unsupported_content",
+				Message: w.Path + ": " + w.Message,
+			}
+		}
+		warn(ctx, w.Code, w.Path, "%s", w.Message)
+	}
+	return nil
+}
+
+func unifiedFromOpenAIRequest(ctx context.Context, req *openai.ChatCompletionRequest) (*UnifiedRequest, error) {
+	u := &UnifiedRequest{
+		Model:           req.Model,
+		MaxTokens:       req.MaxCompletionTokens,
+		Stop:            req.Stop,
+		Stream:          req.Stream,
+		Seed:            req.Seed,
+		N:               req.N,
+		User:            req.User,
+		ReasoningEffort: req.ReasoningEffort,
+		CacheKey:        req.PromptCacheKey,
+		CacheRetention:  req.PromptCacheRetention,
+		CachedContent:   req.CachedContent,
+		LogProbs:        req.LogProbs,
+		TopLogProbs:     req.TopLogProbs,
+		Metadata:        req.Metadata,
+	}
+	if u.MaxTokens == 0 {
+		u.MaxTokens = req.MaxTokens
+	}
+	u.Temperature, u.TopP = req.Temperature, req.TopP
+	u.PresencePenalty, u.FrequencyPenalty = req.PresencePenalty, req.FrequencyPenalty
+	if format := req.ResponseFormat; format != nil {
+		u.ResponseFormat = &UnifiedResponseFormat{Type: string(format.Type)}
+		if format.JSONSchema != nil {
+			u.ResponseFormat.Name = format.JSONSchema.Name
+			if format.JSONSchema.Schema != nil {
+				schema, err := json.Marshal(format.JSONSchema.Schema)
+				if err != nil {
+					return nil, fmt.Errorf("invalid response_format schema: %w", err)
+				}
+				u.ResponseFormat.Schema = schema
+			}
+		}
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		u.Tools = append(u.Tools, UnifiedTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+			Strict:      tool.Function.Strict,
+		})
+	}
+	u.ServerTools = builtinTools(req)
+	var err error
+	if u.ToolChoice, u.ToolName, u.AllowedTools, err = openAIToolChoice(req.ToolChoice); err != nil {
+		return nil, fmt.Errorf("invalid tool_choice: %w", err)
+	}
+	if parallel, ok := req.ParallelToolCalls.(bool); ok {
+		u.ParallelToolCalls = &parallel
+	}
+
+	for i, message := range req.Messages {
+		m, err := unifiedFromOpenAIMessage(ctx, i, message)
+		if err != nil {
+			return nil, err
+		}
+		u.Messages = append(u.Messages, m)
+	}
+	return u, nil
+}
+
+func unifiedFromOpenAIMessage(ctx context.Context, index int, message openai.ChatCompletionMessage) (UnifiedMessage, error) {
+	m := UnifiedMessage{Role: message.Role, ToolCallID: message.ToolCallID}
+	if message.Role == openai.ChatMessageRoleDeveloper {
+		m.Role = openai.ChatMessageRoleSystem
+	}
+	if len(message.ReasoningDetails) > 0 {
+		for _, detail := range message.ReasoningDetails {
+			m.Parts = append(m.Parts, unifiedFromReasoningDetail(detail))
+		}
+	} else if message.ReasoningContent != "" {
+		m.Parts = append(m.Parts, UnifiedPart{Type: UnifiedPartThinking, Text: message.ReasoningContent})
+	}
+	if message.Content != "" {
+		m.Parts = append(m.Parts, UnifiedPart{Type: UnifiedPartText, Text: message.Content})
+	}
+	for j, part := range message.MultiContent {
+		switch {
+		case part.Type == openai.ChatMessagePartTypeText:
+			m.Parts = append(m.Parts, UnifiedPart{Type: UnifiedPartText, Text: part.Text, CacheControl: part.CacheControl})
+		case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+			m.Parts = append(m.Parts, UnifiedPart{Type: UnifiedPartImage, URL: part.ImageURL.URL, CacheControl: part.CacheControl})
+		case part.Type == openai.ChatMessagePartTypeInputAudio && part.InputAudio != nil:
+			mimeType, ok := audioFormats[part.InputAudio.Format]
+			if !ok {
+				mimeType = "audio/" + part.InputAudio.Format
+			}
+			m.Parts = append(m.Parts, UnifiedPart{Type: UnifiedPartAudio, URL: "data:" + mimeType + ";base64," + part.InputAudio.Data, CacheControl: part.CacheControl})
+		case part.Type == openai.ChatMessagePartTypeFile && part.File != nil:
+			document := UnifiedPart{Type: UnifiedPartDocument, MimeType: fileMediaType(part.File), Title: fileTitle(part.File), CacheControl: part.CacheControl}
+			if data, ok := fileData(part.File); ok {
+				document.URL = "data:" + document.MimeType + ";base64," + data
+			} else if isFileURI(part.File.FileId) {
+				document.URL = part.File.FileId
+			} else {
+				document.FileID = part.File.FileId
+			}
+			m.Parts = append(m.Parts, document)
+		default:
+			if err := dropUnsupported(ctx, fmt.Sprintf("messages[%d].content[%d]", index, j), "%s content has no unified equivalent", part.Type); err != nil {
+				return m, err
+			}
+		}
+	}
+	if n := len(m.Parts); n > 0 && message.CacheControl != nil && m.Parts[n-1].CacheControl == nil {
+		m.Parts[n-1].CacheControl = message.CacheControl
+	}
+	for _, call := range message.ToolCalls {
+		m.ToolCalls = append(m.ToolCalls, UnifiedToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	m.Citations = unifiedCitations(message.Annotations)
+	return m, nil
+}
+
+func openAIFromUnifiedRequest(u *UnifiedRequest) (*openai.ChatCompletionRequest, error) {
+	req := &openai.ChatCompletionRequest{
+		Model:                u.Model,
+		MaxTokens:            u.MaxTokens,
+		Stop:                 u.Stop,
+		Stream:               u.Stream,
+		Seed:                 u.Seed,
+		N:                    u.N,
+		User:                 u.User,
+		ReasoningEffort:      u.ReasoningEffort,
+		PromptCacheKey:       u.CacheKey,
+		PromptCacheRetention: u.CacheRetention,
+		LogProbs:             u.LogProbs,
+		TopLogProbs:          u.TopLogProbs,
+		Metadata:             u.Metadata,
+	}
+	req.CachedContent = u.CachedContent
+	req.Temperature, req.TopP = u.Temperature, u.TopP
+	req.PresencePenalty, req.FrequencyPenalty = u.PresencePenalty, u.FrequencyPenalty
+	if format := u.ResponseFormat; format != nil {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatType(format.Type)}
+		if format.Type == string(openai.ChatCompletionResponseFormatTypeJSONSchema) {
+			req.ResponseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{Name: format.Name}
+			if len(format.Schema) > 0 {
+				req.ResponseFormat.JSONSchema.Schema = format.Schema
+			}
+		}
+	}
+
+	for _, tool := range u.Tools {
+		req.Tools = append(req.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+				Strict:      tool.Strict,
+			},
+		})
+	}
+	for _, tool := range u.ServerTools {
+		switch tool {
+		case ServerToolWebSearch:
+			req.WebSearchOptions = &openai.WebSearchOptions{}
+		case ServerToolCodeExecution:
+			req.Tools = append(req.Tools, openai.Tool{Type: openai.ToolTypeCodeInterpreter})
+		}
+	}
+	if u.ToolName != "" {
+		req.ToolChoice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: u.ToolName}}
+	} else if len(u.AllowedTools) > 0 {
+		mode := u.ToolChoice
+		if mode != "required" {
+			mode = "auto"
+		}
+		req.ToolChoice = allowedToolsChoice(mode, u.AllowedTools)
+	} else if u.ToolChoice != "" {
+		req.ToolChoice = u.ToolChoice
+	}
+	if u.ParallelToolCalls != nil {
+		req.ParallelToolCalls = *u.ParallelToolCalls
+	}
+
+	for _, m := range u.Messages {
+		message, err := openAIFromUnifiedMessage(m)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, message)
+	}
+	return req, nil
+}
+
+func openAIFromUnifiedMessage(m UnifiedMessage) (openai.ChatCompletionMessage, error) {
+	message := openai.ChatCompletionMessage{Role: m.Role, ToolCallID: m.ToolCallID}
+	var parts []openai.ChatMessagePart
+	var details []openai.ReasoningDetail
+	signed := false
+	for _, part := range m.Parts {
+		switch part.Type {
+		case UnifiedPartThinking:
+			message.ReasoningContent += part.Text
+			details = append(details, openai.ReasoningDetail{Type: openai.ReasoningDetailTypeText, Text: part.Text, Signature: part.Signature})
+			signed = signed || part.Signature != ""
+		case UnifiedPartRedactedThinking:
+			details = append(details, openai.ReasoningDetail{Type: openai.ReasoningDetailTypeEncrypted, Data: part.Data})
+			signed = true
+		case UnifiedPartText:
+			parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: part.Text, CacheControl: part.CacheControl})
+		case UnifiedPartImage:
+			parts = append(parts, openai.ChatMessagePart{
+				Type:         openai.ChatMessagePartTypeImageURL,
+				ImageURL:     &openai.ChatMessageImageURL{URL: part.URL},
+				CacheControl: part.CacheControl,
+			})
+		case UnifiedPartAudio:
+			inline := geminiInlineData(part.URL)
+			if inline == nil {
+				return message, fmt.Errorf("audio part must hold a data URL")
+			}
+			parts = append(parts, openai.ChatMessagePart{
+				Type:         openai.ChatMessagePartTypeInputAudio,
+				InputAudio:   &openai.ChatMessageInputAudio{Data: inline.Data, Format: audioFormat(inline.MimeType)},
+				CacheControl: part.CacheControl,
+			})
+		case UnifiedPartDocument:
+			file := openAIFileReference(part.MimeType, cmp.Or(part.URL, part.FileID))
+			if inline := geminiInlineData(part.URL); inline != nil {
+				file = openAIFilePart(inline.MimeType, inline.Data)
+			}
+			file = titled(file, part.Title)
+			file.CacheControl = part.CacheControl
+			parts = append(parts, file)
+		default:
+			return message, fmt.Errorf("unknown unified part type %q", part.Type)
+		}
+	}
+	if len(parts) == 1 && parts[0].Type == openai.ChatMessagePartTypeText && parts[0].CacheControl == nil {
+		message.Content = parts[0].Text
+	} else {
+		message.MultiContent = parts
+	}
+	if signed {
+		message.ReasoningDetails = details
+	}
+	for _, call := range m.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+			ID:       call.ID,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+		})
+	}
+	message.Annotations = openAIAnnotations(m.Citations)
+	return message, nil
+}
+
+// collectExtensions returns the non-empty fields of payload outside homes
+func collectExtensions(provider Provider, payload any, homes []string) (Extensions, error) {
+	fields, err := jsonObject(payload)
+	if err != nil {
+		return nil, err
+	}
+	home := make(map[string]bool, len(homes))
+	for _, path := range homes {
+		home[path] = true
+	}
+
+	ext := make(Extensions)
+	for key, value := range fields {
+		switch {
+		case home[key] || isEmptyJSON(value):
+		case nestedHomes[key]:
+			var nested map[string]json.RawMessage
+			if err := json.Unmarshal(value, &nested); err != nil {
+				return nil, err
+			}
+			for sub, subValue := range nested {
+				if path := key + "." + sub; !home[path] && !isEmptyJSON(subValue) {
+					ext[string(provider)+"."+path] = subValue
+				}
+			}
+		default:
+			ext[string(provider)+"."+key] = value
+		}
+	}
+	if len(ext) == 0 {
+		return nil, nil
+	}
+	return ext, nil
+}
+
+// applyExtensions writes the extensions of provider into dst
+func applyExtensions(provider Provider, ext Extensions, dst any) error {
+	fields := ext.For(provider)
+	if len(fields) == 0 {
+		return nil
+	}
+	object, err := jsonObject(dst)
+	if err != nil {
+		return err
+	}
+	for path, value := range fields {
+		key, sub, nested := strings.Cut(path, ".")
+		if !nested {
+			object[key] = value
+			continue
+		}
+		var inner map[string]json.RawMessage
+		if raw, ok := object[key]; ok && !isEmptyJSON(raw) {
+			if err := json.Unmarshal(raw, &inner); err != nil {
+				return fmt.Errorf("extension %s.%s: %w", provider, path, err)
+			}
+		}
+		if inner == nil {
+			inner = make(map[string]json.RawMessage)
+		}
+		inner[sub] = value
+		if object[key], err = json.Marshal(inner); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("invalid %s extensions: %w", provider, err)
+	}
+	return nil
+}
+
+func jsonObject(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// isEmptyJSON reports zero values, including objects holding only zero values
+func isEmptyJSON(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	switch string(value) {
+	case "", "null", "{}", "[]", `""`, "0", "false":
+		return true
+	}
+	var object map[string]json.RawMessage
+	if value[0] != '{' || json.Unmarshal(value, &object) != nil {
+		return false
+	}
+	for _, v := range object {
+		if !isEmptyJSON(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+)
+
+func TestTopKBypassesThePivot(t *testing.T) {
+	var req claude.ClaudeRequest
+	if err := json.Unmarshal([]byte(`{"model":"claude-sonnet-4-5","max_tokens":100,"top_k":40,
+		"messages":[{"role":"user","content":"hi"}]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		provider Provider
+		dst      any
+		topK     func(dst any) any
+		dropped  bool
+	}{
+		{ProviderGemini, &gemini.GeminiChatRequest{}, func(dst any) any { return *dst.(*gemini.GeminiChatRequest).GenerationConfig.TopK }, false},
+		{ProviderOllama, &ollama.ChatRequest{}, func(dst any) any { return dst.(*ollama.ChatRequest).Options["top_k"] }, false},
+		{ProviderClaude, &claude.ClaudeRequest{}, func(dst any) any { return *dst.(*claude.ClaudeRequest).TopK }, false},
+		{ProviderOpenAI, &openai.ChatCompletionRequest{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			ctx, warnings := WithWarnings(context.Background())
+			u, err := ToUnifiedRequest(ctx, ProviderClaude, &req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := FromUnifiedRequest(ctx, tt.provider, u, tt.dst); err != nil {
+				t.Fatal(err)
+			}
+			if got := hasWarning(warnings, WarningDroppedContent); got != tt.dropped {
+				t.Errorf("got warnings %v, want dropped content %v", warnings.List(), tt.dropped)
+			}
+			if tt.topK == nil {
+				return
+			}
+			if got := tt.topK(tt.dst); got != float64(40) && got != 40 {
+				t.Errorf("got top_k %v, want 40", got)
+			}
+		})
+	}
+}
+
+func TestClaudeEventsRoundTripChunks(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"type":"message","id":"msg_1","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":3,"output_tokens":1}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"opaque"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"hi"}}`,
+		`{"type":"content_block_start","index":3,"content_block":{"type":"tool_use","id":"toolu_1","name":"f","input":{}}}`,
+		`{"type":"content_block_delta","index":3,"delta":{"type":"input_json_delta","partial_json":"{\"a\":"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"input_tokens":3,"output_tokens":9}}`,
+		`{"type":"ping"}`,
+	}
+	ctx := context.Background()
+	for _, want := range events {
+		var event claude.ClaudeResponse
+		if err := json.Unmarshal([]byte(want), &event); err != nil {
+			t.Fatal(err)
+		}
+		u, err := ToUnifiedChunk(ctx, ProviderClaude, &event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got claude.ClaudeResponse
+		if err := FromUnifiedChunk(ctx, ProviderClaude, u, &got); err != nil {
+			t.Fatalf("%s: %v", want, err)
+		}
+		wantEvent, gotEvent := jsonValue(t, &event), jsonValue(t, &got)
+		if gotEvent != wantEvent {
+			t.Errorf("got %s, want %s", gotEvent, wantEvent)
+		}
+	}
+}
+
+func TestClaudeEventOfSeveralEventsFails(t *testing.T) {
+	u := &UnifiedChunk{Delta: UnifiedMessage{Parts: []UnifiedPart{{Type: UnifiedPartText, Text: "hi"}}}, FinishReason: "stop"}
+	var event claude.ClaudeResponse
+	if err := FromUnifiedChunk(context.Background(), ProviderClaude, u, &event); err == nil {
+		t.Errorf("converted to %+v, want an error", event)
+	}
+}
+
+func TestGeminiChunkKeepsSignaturesAndIndex(t *testing.T) {
+	var chunk gemini.GeminiChatResponse
+	if err := json.Unmarshal([]byte(`{"candidates":[{"index":1,"content":{"role":"model","parts":[
+		{"text":"hmm","thought":true},{"text":"","thoughtSignature":"thought-sig"},
+		{"functionCall":{"name":"f","args":{"a":1}},"thoughtSignature":"call-sig"}]}}]}`), &chunk); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	u, err := ToUnifiedChunk(ctx, ProviderGemini, &chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Index != 1 {
+		t.Errorf("index = %d, want 1", u.Index)
+	}
+	if len(u.Delta.ToolCalls) != 1 || u.Delta.ToolCalls[0].Signature != "call-sig" {
+		t.Errorf("tool calls = %+v, want one signed call-sig", u.Delta.ToolCalls)
+	}
+
+	tests := []struct {
+		provider Provider
+		dst      any
+		check    func(dst any) string
+	}{
+		{ProviderGemini, &gemini.GeminiChatResponse{}, func(dst any) string {
+			candidate := dst.(*gemini.GeminiChatResponse).Candidates[0]
+			var signatures []string
+			for _, part := range candidate.Content.Parts {
+				signatures = append(signatures, part.ThoughtSignature)
+			}
+			return fmt.Sprint(candidate.Index, signatures)
+		}},
+		{ProviderOpenAI, &openai.ChatCompletionStreamResponse{}, func(dst any) string {
+			choice := dst.(*openai.ChatCompletionStreamResponse).Choices[0]
+			return fmt.Sprintf("%d %s %s", choice.Index, choice.Delta.ReasoningDetails[0].Signature, choice.Delta.ToolCalls[0].ExtraContent.Google.ThoughtSignature)
+		}},
+	}
+	want := map[Provider]string{
+		ProviderGemini: "1 [ thought-sig call-sig]",
+		ProviderOpenAI: "1 thought-sig call-sig",
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			if err := FromUnifiedChunk(ctx, tt.provider, u, tt.dst); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.check(tt.dst); got != want[tt.provider] {
+				t.Errorf("got %q, want %q", got, want[tt.provider])
+			}
+		})
+	}
+}
+
+// jsonValue marshals v, with its keys sorted.
+func jsonValue(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = json.Marshal(value)
+	return string(data)
+}
+
+func TestUnifiedResponseKeepsEveryChoice(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		src      any
+		json     string
+	}{
+		{ProviderOpenAI, &openai.ChatCompletionResponse{}, `{"id":"chatcmpl-1","model":"gpt-4o","choices":[
+			{"index":1,"message":{"role":"assistant","content":"b"},"finish_reason":"length"},
+			{"index":0,"message":{"role":"assistant","content":"a"},"finish_reason":"stop"},
+			{"index":2,"message":{"role":"assistant","content":"c"},"finish_reason":"stop"}]}`},
+		{ProviderGemini, &gemini.GeminiChatResponse{}, `{"candidates":[
+			{"index":0,"content":{"role":"model","parts":[{"text":"a"}]},"finishReason":"STOP"},
+			{"index":1,"content":{"role":"model","parts":[{"text":"b"}]},"finishReason":"MAX_TOKENS"},
+			{"index":2,"content":{"role":"model","parts":[{"text":"c"}]},"finishReason":"STOP"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.json), tt.src); err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			u, err := ToUnifiedResponse(ctx, tt.provider, tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{u.Message.Parts[0].Text + " " + u.FinishReason}
+			for _, alternative := range u.Alternatives {
+				got = append(got, alternative.Message.Parts[0].Text+" "+alternative.FinishReason)
+			}
+			if want := []string{"a stop", "b length", "c stop"}; !slices.Equal(got, want) {
+				t.Errorf("choices = %q, want %q", got, want)
+			}
+
+			var resp openai.ChatCompletionResponse
+			if err := FromUnifiedResponse(ctx, ProviderOpenAI, u, &resp); err != nil {
+				t.Fatal(err)
+			}
+			for i, choice := range resp.Choices {
+				if choice.Index != i || choice.Message.Content != got[i][:1] {
+					t.Errorf("choices[%d] = %d %q, want %d %q", i, choice.Index, choice.Message.Content, i, got[i][:1])
+				}
+			}
+
+			// Claude has a single message
+			ctx, warnings := WithWarnings(ctx)
+			var claudeResp claude.ClaudeResponse
+			if err := FromUnifiedResponse(ctx, ProviderClaude, u, &claudeResp); err != nil {
+				t.Fatal(err)
+			}
+			if !hasWarning(warnings, WarningDroppedContent) {
+				t.Errorf("warnings = %v, want the alternatives dropped", warnings.List())
+			}
+		})
+	}
+}
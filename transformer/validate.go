@@ -0,0 +1,116 @@
+package transformer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ValidationProblem is one rule a request breaks.
+type ValidationProblem struct {
+	// Path is the JSON pointer of the offending value, e.g. /messages/1/role.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem ValidateRequest found in a request.
+type ValidationError struct {
+	Provider Provider            `json:"provider"`
+	Problems []ValidationProblem `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.Path + ": " + p.Message
+	}
+	return strings.Join(lines, "\n")
+}
+
+// requestValidator collects the problems of a request.
+type requestValidator struct {
+	problems []ValidationProblem
+}
+
+func (v *requestValidator) add(path, format string, args ...any) {
+	v.problems = append(v.problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// between checks that value lies within [min, max].
+func (v *requestValidator) between(path string, value, min, max float64) {
+	if value < min || value > max {
+		v.add(path, "must be within [%g, %g], got %g", min, max, value)
+	}
+}
+
+// maxTokens checks that value is positive and within the output limit of
+// model, when it is known.
+func (v *requestValidator) maxTokens(path, model string, value int) {
+	if value < 0 {
+		v.add(path, "must not be negative, got %d", value)
+		return
+	}
+	if limit, ok := maxOutputTokens(model); ok && value > limit {
+		v.add(path, "exceeds the %d output tokens of %s, got %d", limit, model, value)
+	}
+}
+
+// mediaType checks that an inline image is of one of allowed.
+func (v *requestValidator) mediaType(path, mediaType string, allowed []string) {
+	if !slices.Contains(allowed, mediaType) {
+		v.add(path, "unsupported media type %q, expected one of %s", mediaType, strings.Join(allowed, ", "))
+	}
+}
+
+// err returns the problems as a *ValidationError, nil without any.
+func (v *requestValidator) err(provider Provider) error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Provider: provider, Problems: v.problems}
+}
+
+// imageMediaTypes are the inline image formats Claude, OpenAI and Bedrock accept.
+var imageMediaTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// modelOutputTokens are the output token limits of model families, by the
+// prefix of their names.
+var modelOutputTokens = map[string]int{
+	"claude-3-haiku":    4096,
+	"claude-3-opus":     4096,
+	"claude-3-5-haiku":  8192,
+	"claude-3-5-sonnet": 8192,
+	"claude-3-7-sonnet": 64000,
+	"claude-sonnet-4":   64000,
+	"claude-haiku-4":    64000,
+	"claude-opus-4":     32000,
+	"claude-opus-4-5":   64000,
+	"gpt-3.5-turbo":     4096,
+	"gpt-4-turbo":       4096,
+	"gpt-4o":            16384,
+	"gpt-4.1":           32768,
+	"gpt-5":             128000,
+	"o1":                100000,
+	"o3":                100000,
+	"o4-mini":           100000,
+	"gemini-1.5":        8192,
+	"gemini-2.0":        8192,
+	"gemini-2.5":        65536,
+}
+
+// maxOutputTokens returns the output token limit of model by its longest
+// known prefix, ignoring the models/ prefix of Gemini and the region and
+// vendor prefixes of Bedrock model IDs.
+func maxOutputTokens(model string) (int, bool) {
+	model = strings.TrimPrefix(model, "models/")
+	if _, after, ok := strings.Cut(model, "anthropic."); ok {
+		model = after
+	}
+	best, limit := "", 0
+	for prefix, tokens := range modelOutputTokens {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, limit = prefix, tokens
+		}
+	}
+	return limit, best != ""
+}
@@ -0,0 +1,282 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/dto/vertex"
+)
+
+// VertexGeminiTransformer handles the Gemini API of Vertex AI. Payloads
+// convert through their AI Studio Gemini counterparts, mapping labels to
+// OpenAI metadata and safety settings between the two APIs.
+type VertexGeminiTransformer struct{}
+
+// NewVertexGeminiTransformer creates a new Vertex AI Gemini transformer
+func NewVertexGeminiTransformer() *VertexGeminiTransformer {
+	return &VertexGeminiTransformer{}
+}
+
+// GetProvider returns the source provider (Vertex AI Gemini)
+func (t *VertexGeminiTransformer) GetProvider() Provider {
+	return ProviderVertexGemini
+}
+
+// ValidateRequest validates the Vertex request and its labels
+func (t *VertexGeminiTransformer) ValidateRequest(ctx context.Context, request interface{}) error {
+	req, ok := request.(*vertex.GenerateContentRequest)
+	if !ok {
+		return fmt.Errorf("invalid request type for Vertex Gemini transformer")
+	}
+
+	v := &requestValidator{}
+	validateGeminiRequest(v, &req.GeminiChatRequest)
+	for _, key := range slices.Sorted(maps.Keys(req.Labels)) {
+		if !validVertexLabel(key, req.Labels[key]) {
+			v.add("/labels/"+pointerToken(key), "invalid label %q: %q", key, req.Labels[key])
+		}
+	}
+	return v.err(ProviderVertexGemini)
+}
+
+// ValidateResponse validates the Vertex response like a Gemini response
+func (t *VertexGeminiTransformer) ValidateResponse(ctx context.Context, response interface{}) error {
+	resp, ok := response.(*vertex.GenerateContentResponse)
+	if !ok {
+		return fmt.Errorf("invalid response type for Vertex Gemini transformer")
+	}
+	return NewGeminiTransformer().ValidateResponse(ctx, &resp.GeminiChatResponse)
+}
+
+// Do performs the transformation based on the type
+func (t *VertexGeminiTransformer) Do(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	switch typ {
+	case TransformerTypeRequest:
+		return t.transformRequest(ctx, src, dst)
+	case TransformerTypeResponse:
+		return t.transformResponse(ctx, src, dst)
+	case TransformerTypeChunk:
+		return t.transformChunk(ctx, src, dst)
+	default:
+		return fmt.Errorf("unsupported transformation type: %s", typ)
+	}
+}
+
+// ToUnified converts a Vertex request, response or chunk to the unified format.
+func (t *VertexGeminiTransformer) ToUnified(ctx context.Context, typ TransformerType, src interface{}) (interface{}, error) {
+	return toUnified(ctx, ProviderVertexGemini, typ, src)
+}
+
+// FromUnified converts a unified request, response or chunk to the Vertex payload dst.
+func (t *VertexGeminiTransformer) FromUnified(ctx context.Context, typ TransformerType, src interface{}, dst interface{}) error {
+	return fromUnified(ctx, ProviderVertexGemini, typ, src, dst)
+}
+
+func (t *VertexGeminiTransformer) transformRequest(ctx context.Context, src interface{}, dst interface{}) error {
+	req, ok := src.(*vertex.GenerateContentRequest)
+	if !ok {
+		return fmt.Errorf("invalid source type for Vertex Gemini transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionRequest:
+		if err := transformVertexRequestToOpenAI(ctx, req, target); err != nil {
+			return err
+		}
+		stripOpenAIReasoning(ctx, target)
+		return dropCodeInterpreter(ctx, target)
+	case *gemini.GeminiChatRequest:
+		return transformVertexRequestToGemini(ctx, req, target)
+	case *vertex.GenerateContentRequest:
+		return sameProvider(ctx, ProviderVertexGemini, TransformerTypeRequest, req, target)
+	case *claude.ClaudeRequest:
+		return transformViaUnified(ctx, ProviderVertexGemini, ProviderClaude, TransformerTypeRequest, req, target)
+	case *bedrock.ConverseRequest:
+		return transformViaUnified(ctx, ProviderVertexGemini, ProviderBedrock, TransformerTypeRequest, req, target)
+	case *ollama.ChatRequest:
+		return transformViaUnified(ctx, ProviderVertexGemini, ProviderOllama, TransformerTypeRequest, req, target)
+	default:
+		return fmt.Errorf("target type not supported for Vertex Gemini transformer")
+	}
+}
+
+func (t *VertexGeminiTransformer) transformResponse(ctx context.Context, src interface{}, dst interface{}) error {
+	resp, ok := src.(*vertex.GenerateContentResponse)
+	if !ok {
+		return fmt.Errorf("invalid source type for Vertex Gemini transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionResponse:
+		return transformVertexResponseToOpenAI(ctx, resp, target)
+	case *gemini.GeminiChatResponse:
+		*target = resp.GeminiChatResponse
+		return nil
+	case *vertex.GenerateContentResponse:
+		return sameProvider(ctx, ProviderVertexGemini, TransformerTypeResponse, resp, target)
+	case *claude.ClaudeResponse:
+		return transformViaUnified(ctx, ProviderVertexGemini, ProviderClaude, TransformerTypeResponse, resp, target)
+	case *bedrock.ConverseResponse:
+		return transformViaUnified(ctx, ProviderVertexGemini, ProviderBedrock, TransformerTypeResponse, resp, target)
+	case *ollama.ChatResponse:
+		return transformViaUnified(ctx, ProviderVertexGemini, ProviderOllama, TransformerTypeResponse, resp, target)
+	default:
+		return fmt.Errorf("target type not supported for Vertex Gemini transformer")
+	}
+}
+
+// transformChunk converts a streamGenerateContent chunk like a Gemini chunk,
+// identified by the response ID and model version of Vertex.
+func (t *VertexGeminiTransformer) transformChunk(ctx context.Context, src interface{}, dst interface{}) error {
+	chunk, ok := src.(*vertex.GenerateContentResponse)
+	if !ok {
+		return fmt.Errorf("invalid source type for Vertex Gemini transformer")
+	}
+
+	switch target := dst.(type) {
+	case *openai.ChatCompletionStreamResponse:
+		if err := transformGeminiChunkToOpenAI(ctx, &chunk.GeminiChatResponse, target); err != nil {
+			return err
+		}
+		vertexChunkIdentity(chunk, target)
+		return nil
+	case *[]*openai.ChatCompletionStreamResponse:
+		n := len(*target)
+		if err := NewGeminiTransformer().transformChunk(ctx, &chunk.GeminiChatResponse, target); err != nil {
+			return err
+		}
+		for _, oaiChunk := range (*target)[n:] {
+			vertexChunkIdentity(chunk, oaiChunk)
+		}
+		return nil
+	default:
+		return fmt.Errorf("target type not supported for Vertex Gemini transformer")
+	}
+}
+
+func vertexChunkIdentity(chunk *vertex.GenerateContentResponse, oaiChunk *openai.ChatCompletionStreamResponse) {
+	oaiChunk.ID, oaiChunk.Model = chunk.ResponseID, chunk.ModelVersion
+	if created, err := time.Parse(time.RFC3339Nano, chunk.CreateTime); err == nil {
+		oaiChunk.Created = created.Unix()
+	}
+}
+
+func transformVertexRequestToOpenAI(ctx context.Context, req *vertex.GenerateContentRequest, oaiReq *openai.ChatCompletionRequest) error {
+	if err := transformGeminiRequestToOpenAI(ctx, &req.GeminiChatRequest, oaiReq); err != nil {
+		return err
+	}
+	oaiReq.Metadata = maps.Clone(req.Labels)
+	return nil
+}
+
+func transformRequestToVertex(ctx context.Context, oaiReq *openai.ChatCompletionRequest, req *vertex.GenerateContentRequest) error {
+	if err := transformRequestToGemini(ctx, oaiReq, &req.GeminiChatRequest); err != nil {
+		return err
+	}
+	if err := vertexRequestFromGemini(ctx, &req.GeminiChatRequest, req); err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(oaiReq.Metadata)) {
+		value := oaiReq.Metadata[key]
+		if !validVertexLabel(key, value) {
+			if err := dropUnsupported(ctx, "metadata."+key, "not a valid Vertex AI label"); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Labels == nil {
+			req.Labels = make(map[string]string)
+		}
+		req.Labels[key] = value
+	}
+	return nil
+}
+
+// vertexRequestFromGemini sets req to the Gemini request geminiReq. AI
+// Studio blocks on probability, so do the converted safety settings.
+// HARM_CATEGORY_CIVIC_INTEGRITY is deprecated on Vertex and left out.
+func vertexRequestFromGemini(ctx context.Context, geminiReq *gemini.GeminiChatRequest, req *vertex.GenerateContentRequest) error {
+	settings := geminiReq.SafetySettings
+	req.GeminiChatRequest = *geminiReq
+	req.GeminiChatRequest.SafetySettings = nil
+	req.SafetySettings = nil
+	for i, setting := range settings {
+		blocking := setting.Threshold != "BLOCK_NONE" && setting.Threshold != "OFF"
+		if setting.Category == "HARM_CATEGORY_CIVIC_INTEGRITY" {
+			if blocking {
+				if err := dropUnsupported(ctx, fmt.Sprintf("safetySettings[%d]", i), "%s is deprecated on Vertex AI", setting.Category); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		converted := vertex.SafetySetting{Category: setting.Category, Threshold: setting.Threshold}
+		if blocking {
+			converted.Method = vertex.SafetyMethodProbability
+		}
+		req.SafetySettings = append(req.SafetySettings, converted)
+	}
+	return nil
+}
+
+// transformVertexRequestToGemini sets geminiReq to the AI Studio form of
+// req, which has neither labels nor severity-based safety settings.
+func transformVertexRequestToGemini(ctx context.Context, req *vertex.GenerateContentRequest, geminiReq *gemini.GeminiChatRequest) error {
+	*geminiReq = req.GeminiChatRequest
+	geminiReq.SafetySettings = nil
+	for i, setting := range req.SafetySettings {
+		if setting.Method != vertex.SafetyMethodProbability && setting.Threshold != "BLOCK_NONE" && setting.Threshold != "OFF" {
+			if err := dropUnsupported(ctx, fmt.Sprintf("safetySettings[%d].method", i), "AI Studio blocks on probability, not severity"); err != nil {
+				return err
+			}
+		}
+		geminiReq.SafetySettings = append(geminiReq.SafetySettings, gemini.GeminiChatSafetySettings{
+			Category:  setting.Category,
+			Threshold: setting.Threshold,
+		})
+	}
+	if len(req.Labels) > 0 {
+		if err := dropUnsupported(ctx, "labels", "AI Studio has no request labels"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	vertexLabelKey   = regexp.MustCompile(`^[\p{Ll}\p{Lo}][\p{Ll}\p{Lo}\p{N}_-]{0,62}$`)
+	vertexLabelValue = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}_-]{0,63}$`)
+)
+
+func validVertexLabel(key, value string) bool {
+	return vertexLabelKey.MatchString(key) && vertexLabelValue.MatchString(value)
+}
+
+func transformVertexResponseToOpenAI(ctx context.Context, resp *vertex.GenerateContentResponse, oaiResp *openai.ChatCompletionResponse) error {
+	if err := transformGeminiResponseToOpenAI(ctx, &resp.GeminiChatResponse, oaiResp); err != nil {
+		return err
+	}
+	if created, err := time.Parse(time.RFC3339Nano, resp.CreateTime); err == nil {
+		oaiResp.Created = created.Unix()
+	}
+	return nil
+}
+
+func transformResponseToVertex(ctx context.Context, oaiResp *openai.ChatCompletionResponse, resp *vertex.GenerateContentResponse) error {
+	if err := transformResponseToGemini(ctx, oaiResp, &resp.GeminiChatResponse); err != nil {
+		return err
+	}
+	if oaiResp.Created > 0 {
+		resp.CreateTime = time.Unix(oaiResp.Created, 0).UTC().Format(time.RFC3339Nano)
+	}
+	return nil
+}
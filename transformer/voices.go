@@ -0,0 +1,95 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+)
+
+// VoiceMapping pairs an OpenAI voice with the Gemini prebuilt voice standing
+// in for it, spoken in Language, a BCP-47 code such as en-US, or in the
+// language of the text when empty.
+type VoiceMapping struct {
+	OpenAI   string `json:"openai"`
+	Gemini   string `json:"gemini"`
+	Language string `json:"language,omitempty"`
+}
+
+// VoiceMap maps the voices of speech requests between OpenAI and Gemini.
+// Voices it does not name are passed through unchanged, so Gemini voices
+// reach Gemini as they are.
+type VoiceMap struct {
+	voices []VoiceMapping
+}
+
+// DefaultVoiceMap pairs every OpenAI voice with a Gemini voice of a similar
+// character, each Gemini voice once.
+var DefaultVoiceMap = &VoiceMap{voices: []VoiceMapping{
+	{OpenAI: "alloy", Gemini: "Kore"},
+	{OpenAI: "ash", Gemini: "Charon"},
+	{OpenAI: "ballad", Gemini: "Algieba"},
+	{OpenAI: "coral", Gemini: "Aoede"},
+	{OpenAI: "echo", Gemini: "Puck"},
+	{OpenAI: "fable", Gemini: "Fenrir"},
+	{OpenAI: "nova", Gemini: "Leda"},
+	{OpenAI: "onyx", Gemini: "Orus"},
+	{OpenAI: "sage", Gemini: "Sulafat"},
+	{OpenAI: "shimmer", Gemini: "Zephyr"},
+	{OpenAI: "verse", Gemini: "Achird"},
+	{OpenAI: "marin", Gemini: "Despina"},
+	{OpenAI: "cedar", Gemini: "Iapetus"},
+}}
+
+// NewVoiceMap returns a map of voices. An OpenAI voice maps to its first
+// mapping, a Gemini voice back to the OpenAI voice of its first mapping.
+func NewVoiceMap(voices []VoiceMapping) (*VoiceMap, error) {
+	for i, voice := range voices {
+		if voice.OpenAI == "" || voice.Gemini == "" {
+			return nil, fmt.Errorf("voices[%d]: openai and gemini are required", i)
+		}
+	}
+	return &VoiceMap{voices: voices}, nil
+}
+
+// Gemini returns the Gemini voice and language code of an OpenAI voice, the
+// voice itself when it has no mapping.
+func (m *VoiceMap) Gemini(voice string) (string, string) {
+	for _, mapping := range m.voices {
+		if mapping.OpenAI == voice {
+			return mapping.Gemini, mapping.Language
+		}
+	}
+	return voice, ""
+}
+
+// OpenAI returns the OpenAI voice of a Gemini voice, the voice itself when
+// it has no mapping.
+func (m *VoiceMap) OpenAI(voice string) string {
+	for _, mapping := range m.voices {
+		if mapping.Gemini == voice {
+			return mapping.OpenAI
+		}
+	}
+	return voice
+}
+
+type voiceMapKey struct{}
+
+// WithVoiceMap returns a context mapping the voices of speech requests with
+// m instead of DefaultVoiceMap.
+func WithVoiceMap(ctx context.Context, m *VoiceMap) context.Context {
+	return context.WithValue(ctx, voiceMapKey{}, m)
+}
+
+// voiceMap returns the voice map carried by ctx, DefaultVoiceMap without one.
+func voiceMap(ctx context.Context) *VoiceMap {
+	if m, ok := ctx.Value(voiceMapKey{}).(*VoiceMap); ok {
+		return m
+	}
+	return DefaultVoiceMap
+}
+
+// UseVoiceMap makes Transform and TransformAudio map voices with m, unless
+// the context carries a voice map already.
+func (r *TransformationRegistry) UseVoiceMap(m *VoiceMap) {
+	r.voiceMap = m
+}
@@ -0,0 +1,83 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Warning codes
+const (
+	WarningDroppedContent   = "dropped_content"
+	WarningRedactedThinking = "redacted_thinking_dropped"
+	WarningInvalidJSON      = "invalid_json"
+	WarningUncitedAnswer    = "uncited_answer"
+	// WarningRenamedTool reports a tool renamed for the naming rule of the target.
+	WarningRenamedTool = "renamed_tool"
+	// WarningMaxTokensCapped reports max tokens lowered to the output limit
+	// of the target model.
+	WarningMaxTokensCapped = "max_tokens_capped"
+	// WarningPresetApplied traces a default filled in from a model preset.
+	WarningPresetApplied = "preset_applied"
+	// WarningStringifiedEnum reports an enum value of a schema turned into
+	// a string, for targets with string enums alone.
+	WarningStringifiedEnum = "stringified_enum"
+)
+
+// Warning describes information lost or altered by a transformation that did
+// not warrant failing it.
+type Warning struct {
+	Code string `json:"code"`
+	// Path locates the affected field in the source payload, e.g. "messages[2].content[0]".
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Path == "" {
+		return w.Code + ": " + w.Message
+	}
+	return w.Code + " at " + w.Path + ": " + w.Message
+}
+
+// Warnings collects the warnings of the transformations run with its context.
+// It is safe for concurrent use.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+	// parent is the collector of the enclosing context, if any
+	parent *Warnings
+}
+
+type warningsKey struct{}
+
+// WithWarnings returns a context collecting warnings into the returned
+// Warnings. Collectors nest: warnings also reach the collector of ctx.
+func WithWarnings(ctx context.Context) (context.Context, *Warnings) {
+	parent, _ := ctx.Value(warningsKey{}).(*Warnings)
+	w := &Warnings{parent: parent}
+	return context.WithValue(ctx, warningsKey{}, w), w
+}
+
+// List returns the warnings collected so far.
+func (w *Warnings) List() []Warning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.list...)
+}
+
+func (w *Warnings) add(warning Warning) {
+	w.mu.Lock()
+	w.list = append(w.list, warning)
+	w.mu.Unlock()
+	if w.parent != nil {
+		w.parent.add(warning)
+	}
+}
+
+// warn records a warning if ctx collects them
+func warn(ctx context.Context, code, path, format string, args ...any) {
+	if w, ok := ctx.Value(warningsKey{}).(*Warnings); ok {
+		w.add(Warning{Code: code, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
@@ -0,0 +1,326 @@
+// Package usage normalizes the token usage providers report, so billing
+// sees the same numbers whichever provider served a request and whichever
+// format its client speaks. Each provider counts cache and reasoning
+// tokens its own way: Claude and Bedrock leave cache reads and writes out
+// of their input tokens, Gemini leaves thoughts out of its candidate
+// tokens and tool results out of its prompt tokens. Usage counts them in,
+// as OpenAI does, and keeps the split in its details.
+package usage
+
+import (
+	"fmt"
+
+	"github.com/phosae/llms/dto/bedrock"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/ollama"
+	"github.com/phosae/llms/dto/openai"
+)
+
+// Gemini modalities of the token details.
+const (
+	modalityAudio = "AUDIO"
+	modalityImage = "IMAGE"
+	modalityVideo = "VIDEO"
+)
+
+// Usage is provider-neutral token usage.
+type Usage struct {
+	// InputTokens counts every prompt token, cache reads and writes and the
+	// results of built-in tools included.
+	InputTokens int `json:"input_tokens"`
+	// OutputTokens counts every generated token, reasoning included.
+	OutputTokens int `json:"output_tokens"`
+	// Input splits the input tokens.
+	Input InputDetails `json:"input_details,omitzero"`
+	// Output splits the output tokens.
+	Output OutputDetails `json:"output_details,omitzero"`
+	// WebSearchRequests counts the searches of server-side web search.
+	WebSearchRequests int `json:"web_search_requests,omitempty"`
+}
+
+// InputDetails are the parts of the input tokens billed apart. They may
+// overlap: cached audio counts as both.
+type InputDetails struct {
+	// CacheReadTokens are read from the prompt cache.
+	CacheReadTokens int `json:"cache_read_tokens,omitempty"`
+	// CacheWriteTokens are written to the prompt cache.
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+	// CacheWrite1hTokens are the cache writes kept for an hour rather than
+	// five minutes, part of CacheWriteTokens.
+	CacheWrite1hTokens int `json:"cache_write_1h_tokens,omitempty"`
+	AudioTokens        int `json:"audio_tokens,omitempty"`
+	ImageTokens        int `json:"image_tokens,omitempty"`
+	VideoTokens        int `json:"video_tokens,omitempty"`
+	// ToolUseTokens are the results of built-in tools, such as Google
+	// Search, added to the prompt.
+	ToolUseTokens int `json:"tool_use_tokens,omitempty"`
+}
+
+// OutputDetails are the parts of the output tokens billed apart.
+type OutputDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	AudioTokens     int `json:"audio_tokens,omitempty"`
+	ImageTokens     int `json:"image_tokens,omitempty"`
+	// AcceptedPredictionTokens and RejectedPredictionTokens are the tokens of
+	// an OpenAI predicted output that did and did not appear in the
+	// completion.
+	AcceptedPredictionTokens int `json:"accepted_prediction_tokens,omitempty"`
+	RejectedPredictionTokens int `json:"rejected_prediction_tokens,omitempty"`
+}
+
+// TotalTokens returns the input and output tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add returns the sum of two usages.
+func (u Usage) Add(v Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + v.InputTokens,
+		OutputTokens: u.OutputTokens + v.OutputTokens,
+		Input: InputDetails{
+			CacheReadTokens:    u.Input.CacheReadTokens + v.Input.CacheReadTokens,
+			CacheWriteTokens:   u.Input.CacheWriteTokens + v.Input.CacheWriteTokens,
+			CacheWrite1hTokens: u.Input.CacheWrite1hTokens + v.Input.CacheWrite1hTokens,
+			AudioTokens:        u.Input.AudioTokens + v.Input.AudioTokens,
+			ImageTokens:        u.Input.ImageTokens + v.Input.ImageTokens,
+			VideoTokens:        u.Input.VideoTokens + v.Input.VideoTokens,
+			ToolUseTokens:      u.Input.ToolUseTokens + v.Input.ToolUseTokens,
+		},
+		Output: OutputDetails{
+			ReasoningTokens:          u.Output.ReasoningTokens + v.Output.ReasoningTokens,
+			AudioTokens:              u.Output.AudioTokens + v.Output.AudioTokens,
+			ImageTokens:              u.Output.ImageTokens + v.Output.ImageTokens,
+			AcceptedPredictionTokens: u.Output.AcceptedPredictionTokens + v.Output.AcceptedPredictionTokens,
+			RejectedPredictionTokens: u.Output.RejectedPredictionTokens + v.Output.RejectedPredictionTokens,
+		},
+		WebSearchRequests: u.WebSearchRequests + v.WebSearchRequests,
+	}
+}
+
+// Normalize converts the usage a provider reports: *openai.Usage,
+// *claude.ClaudeUsage, *gemini.GeminiUsageMetadata, *bedrock.TokenUsage or
+// *ollama.Metrics.
+func Normalize(report any) (Usage, error) {
+	switch r := report.(type) {
+	case *openai.Usage:
+		return FromOpenAI(r), nil
+	case *claude.ClaudeUsage:
+		return FromClaude(r), nil
+	case *gemini.GeminiUsageMetadata:
+		return FromGemini(r), nil
+	case *bedrock.TokenUsage:
+		return FromBedrock(r), nil
+	case *ollama.Metrics:
+		return FromOllama(r), nil
+	default:
+		return Usage{}, fmt.Errorf("unsupported usage type %T", report)
+	}
+}
+
+// Denormalize stores u in dst, one of the types Normalize takes, as the
+// provider would report it. Ollama metrics keep their durations.
+func Denormalize(u Usage, dst any) error {
+	switch d := dst.(type) {
+	case *openai.Usage:
+		*d = *u.OpenAI()
+	case *claude.ClaudeUsage:
+		*d = *u.Claude()
+	case *gemini.GeminiUsageMetadata:
+		*d = u.Gemini()
+	case *bedrock.TokenUsage:
+		*d = *u.Bedrock()
+	case *ollama.Metrics:
+		d.PromptEvalCount, d.EvalCount = u.InputTokens, u.OutputTokens
+	default:
+		return fmt.Errorf("unsupported usage type %T", dst)
+	}
+	return nil
+}
+
+// FromOpenAI converts OpenAI usage. Anthropic-compatible backends report
+// cache reads as cache_read_input_tokens rather than cached_tokens.
+func FromOpenAI(r *openai.Usage) Usage {
+	if r == nil {
+		return Usage{}
+	}
+	u := Usage{InputTokens: r.PromptTokens, OutputTokens: r.CompletionTokens}
+	if d := r.PromptTokensDetails; d != nil {
+		u.Input.CacheReadTokens = max(d.CachedTokens, d.CacheReadInputTokens)
+		u.Input.CacheWriteTokens = d.CacheCreationInputTokens
+		u.Input.AudioTokens = d.AudioTokens
+	}
+	if d := r.CompletionTokensDetails; d != nil {
+		u.Output.ReasoningTokens = d.ReasoningTokens
+		u.Output.AudioTokens = d.AudioTokens
+		u.Output.AcceptedPredictionTokens = d.AcceptedPredictionTokens
+		u.Output.RejectedPredictionTokens = d.RejectedPredictionTokens
+	}
+	return u
+}
+
+// FromClaude converts Claude usage, whose input_tokens exclude cache reads
+// and writes.
+func FromClaude(r *claude.ClaudeUsage) Usage {
+	if r == nil {
+		return Usage{}
+	}
+	u := Usage{
+		InputTokens:  r.InputTokens + r.CacheReadInputTokens + r.CacheCreationInputTokens,
+		OutputTokens: r.OutputTokens,
+		Input:        InputDetails{CacheReadTokens: r.CacheReadInputTokens, CacheWriteTokens: r.CacheCreationInputTokens},
+	}
+	if r.CacheCreationDetails != nil {
+		u.Input.CacheWrite1hTokens = r.CacheCreationDetails.Ephemeral1hInputTokens
+	}
+	if r.ServerToolUse != nil {
+		u.WebSearchRequests = r.ServerToolUse.WebSearchRequests
+	}
+	return u
+}
+
+// FromGemini converts Gemini usage metadata, whose candidate count excludes
+// thoughts and whose prompt count excludes the results of built-in tools.
+func FromGemini(r *gemini.GeminiUsageMetadata) Usage {
+	if r == nil {
+		return Usage{}
+	}
+	u := Usage{
+		InputTokens:  r.PromptTokenCount + r.ToolUsePromptTokenCount,
+		OutputTokens: r.CandidatesTokenCount + r.ThoughtsTokenCount,
+		Input:        InputDetails{CacheReadTokens: r.CachedContentTokenCount, ToolUseTokens: r.ToolUsePromptTokenCount},
+		Output:       OutputDetails{ReasoningTokens: r.ThoughtsTokenCount},
+	}
+	for _, d := range r.PromptTokensDetails {
+		switch d.Modality {
+		case modalityAudio:
+			u.Input.AudioTokens += d.TokenCount
+		case modalityImage:
+			u.Input.ImageTokens += d.TokenCount
+		case modalityVideo:
+			u.Input.VideoTokens += d.TokenCount
+		}
+	}
+	for _, d := range r.CandidatesTokensDetails {
+		switch d.Modality {
+		case modalityAudio:
+			u.Output.AudioTokens += d.TokenCount
+		case modalityImage:
+			u.Output.ImageTokens += d.TokenCount
+		}
+	}
+	return u
+}
+
+// FromBedrock converts Bedrock usage, whose inputTokens exclude cache reads
+// and writes.
+func FromBedrock(r *bedrock.TokenUsage) Usage {
+	if r == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:  r.InputTokens + r.CacheReadInputTokens + r.CacheWriteInputTokens,
+		OutputTokens: r.OutputTokens,
+		Input:        InputDetails{CacheReadTokens: r.CacheReadInputTokens, CacheWriteTokens: r.CacheWriteInputTokens},
+	}
+}
+
+// FromOllama converts the token counts of Ollama metrics.
+func FromOllama(r *ollama.Metrics) Usage {
+	if r == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: r.PromptEvalCount, OutputTokens: r.EvalCount}
+}
+
+// OpenAI converts the usage to OpenAI's shape, with the details only when
+// they count something. Cache reads are reported both ways, for OpenAI and
+// Anthropic-compatible clients.
+func (u Usage) OpenAI() *openai.Usage {
+	r := &openai.Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens, TotalTokens: u.TotalTokens()}
+	if in := u.Input; in.CacheReadTokens > 0 || in.CacheWriteTokens > 0 || in.AudioTokens > 0 {
+		r.PromptTokensDetails = &openai.PromptTokensDetails{
+			AudioTokens:              in.AudioTokens,
+			CachedTokens:             in.CacheReadTokens,
+			CacheReadInputTokens:     in.CacheReadTokens,
+			CacheCreationInputTokens: in.CacheWriteTokens,
+		}
+	}
+	if out := u.Output; out.ReasoningTokens > 0 || out.AudioTokens > 0 || out.AcceptedPredictionTokens > 0 || out.RejectedPredictionTokens > 0 {
+		r.CompletionTokensDetails = &openai.CompletionTokensDetails{
+			AudioTokens:              out.AudioTokens,
+			ReasoningTokens:          out.ReasoningTokens,
+			AcceptedPredictionTokens: out.AcceptedPredictionTokens,
+			RejectedPredictionTokens: out.RejectedPredictionTokens,
+		}
+	}
+	return r
+}
+
+// Claude converts the usage to Claude's shape.
+func (u Usage) Claude() *claude.ClaudeUsage {
+	r := &claude.ClaudeUsage{
+		InputTokens:              max(u.InputTokens-u.Input.CacheReadTokens-u.Input.CacheWriteTokens, 0),
+		OutputTokens:             u.OutputTokens,
+		CacheReadInputTokens:     u.Input.CacheReadTokens,
+		CacheCreationInputTokens: u.Input.CacheWriteTokens,
+	}
+	if u.Input.CacheWrite1hTokens > 0 {
+		r.CacheCreationDetails = &claude.ClaudeCacheCreation{
+			Ephemeral5mInputTokens: max(u.Input.CacheWriteTokens-u.Input.CacheWrite1hTokens, 0),
+			Ephemeral1hInputTokens: u.Input.CacheWrite1hTokens,
+		}
+	}
+	if u.WebSearchRequests > 0 {
+		r.ServerToolUse = &claude.ClaudeServerToolUse{WebSearchRequests: u.WebSearchRequests}
+	}
+	return r
+}
+
+// Gemini converts the usage to Gemini's shape. Cache writes have no Gemini
+// counterpart and count as plain prompt tokens.
+func (u Usage) Gemini() gemini.GeminiUsageMetadata {
+	return gemini.GeminiUsageMetadata{
+		PromptTokenCount:        max(u.InputTokens-u.Input.ToolUseTokens, 0),
+		CandidatesTokenCount:    max(u.OutputTokens-u.Output.ReasoningTokens, 0),
+		ThoughtsTokenCount:      u.Output.ReasoningTokens,
+		CachedContentTokenCount: u.Input.CacheReadTokens,
+		ToolUsePromptTokenCount: u.Input.ToolUseTokens,
+		TotalTokenCount:         u.TotalTokens(),
+		PromptTokensDetails:     modalities(u.Input.AudioTokens, u.Input.ImageTokens, u.Input.VideoTokens),
+		CandidatesTokensDetails: modalities(u.Output.AudioTokens, u.Output.ImageTokens, 0),
+	}
+}
+
+// modalities returns the Gemini token details of the counts that are not
+// zero.
+func modalities(audio, image, video int) []gemini.GeminiPromptTokensDetails {
+	var details []gemini.GeminiPromptTokensDetails
+	for _, d := range []gemini.GeminiPromptTokensDetails{
+		{Modality: modalityAudio, TokenCount: audio},
+		{Modality: modalityImage, TokenCount: image},
+		{Modality: modalityVideo, TokenCount: video},
+	} {
+		if d.TokenCount > 0 {
+			details = append(details, d)
+		}
+	}
+	return details
+}
+
+// Bedrock converts the usage to Bedrock's shape.
+func (u Usage) Bedrock() *bedrock.TokenUsage {
+	return &bedrock.TokenUsage{
+		InputTokens:           max(u.InputTokens-u.Input.CacheReadTokens-u.Input.CacheWriteTokens, 0),
+		OutputTokens:          u.OutputTokens,
+		TotalTokens:           u.TotalTokens(),
+		CacheReadInputTokens:  u.Input.CacheReadTokens,
+		CacheWriteInputTokens: u.Input.CacheWriteTokens,
+	}
+}
+
+// Ollama converts the usage to the token counts of Ollama metrics.
+func (u Usage) Ollama() ollama.Metrics {
+	return ollama.Metrics{PromptEvalCount: u.InputTokens, EvalCount: u.OutputTokens}
+}
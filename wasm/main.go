@@ -6,11 +6,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"syscall/js"
 
-	"github.com/phosae/llms/claude"
-	"github.com/phosae/llms/gemini"
-	"github.com/phosae/llms/openai"
+	"github.com/phosae/llms/dto/claude"
+	"github.com/phosae/llms/dto/gemini"
+	"github.com/phosae/llms/dto/openai"
+	"github.com/phosae/llms/sse"
 	"github.com/phosae/llms/transformer"
 )
 
@@ -24,6 +27,16 @@ func createErrorResult(message string) map[string]interface{} {
 	}
 }
 
+// warningsResult converts transformation warnings to a JavaScript compatible value
+func warningsResult(warnings *transformer.Warnings) []interface{} {
+	list := warnings.List()
+	result := make([]interface{}, 0, len(list))
+	for _, w := range list {
+		result = append(result, w.String())
+	}
+	return result
+}
+
 // transformRequest transforms a request from source provider to target provider
 func transformRequest(this js.Value, args []js.Value) interface{} {
 	defer func() {
@@ -41,7 +54,7 @@ func transformRequest(this js.Value, args []js.Value) interface{} {
 	requestJsonStr := args[2].String()
 
 	fmt.Printf("Transform request: %s -> %s\n", sourceProvider, targetProvider)
-	ctx := context.Background()
+	ctx, warnings := transformer.WithWarnings(context.Background())
 
 	// Parse the request JSON based on source provider
 	var srcRequest interface{}
@@ -105,8 +118,9 @@ func transformRequest(this js.Value, args []js.Value) interface{} {
 	}
 
 	return map[string]interface{}{
-		"success": true,
-		"result":  string(resultJson),
+		"success":  true,
+		"result":   string(resultJson),
+		"warnings": warningsResult(warnings),
 	}
 }
 
@@ -126,7 +140,7 @@ func transformResponse(this js.Value, args []js.Value) interface{} {
 	targetProvider := transformer.Provider(args[1].String())
 	responseJsonStr := args[2].String()
 
-	ctx := context.Background()
+	ctx, warnings := transformer.WithWarnings(context.Background())
 
 	// Parse the response JSON based on source provider
 	var srcResponse interface{}
@@ -190,12 +204,15 @@ func transformResponse(this js.Value, args []js.Value) interface{} {
 	}
 
 	return map[string]interface{}{
-		"success": true,
-		"result":  string(resultJson),
+		"success":  true,
+		"result":   string(resultJson),
+		"warnings": warningsResult(warnings),
 	}
 }
 
-// transformStream transforms a full stream response from source provider to target provider
+// transformStream transforms a full stream from source provider to target provider. The
+// stream is server-sent events, a JSON array as Gemini streams without alt=sse, or one or
+// more JSON chunks; the result is the JSON array of the target chunks.
 func transformStream(this js.Value, args []js.Value) interface{} {
 	defer func() {
 		if r := recover(); r != nil {
@@ -211,62 +228,44 @@ func transformStream(this js.Value, args []js.Value) interface{} {
 	targetProvider := transformer.Provider(args[1].String())
 	streamJsonStr := args[2].String()
 
-	ctx := context.Background()
-
-	// Parse the stream JSON based on source provider
-	var srcStream interface{}
-	var dstStream interface{}
-	var err error
-
-	switch sourceProvider {
-	case transformer.ProviderOpenAI:
-		stream := &openai.ChatCompletionStreamResponse{}
-		if err = json.Unmarshal([]byte(streamJsonStr), stream); err != nil {
-			return createErrorResult(fmt.Sprintf("Failed to parse OpenAI stream: %v", err))
-		}
-		srcStream = stream
-
-	case transformer.ProviderGemini:
-		stream := &gemini.GeminiChatResponse{}
-		if err = json.Unmarshal([]byte(streamJsonStr), stream); err != nil {
-			return createErrorResult(fmt.Sprintf("Failed to parse Gemini stream: %v", err))
-		}
-		srcStream = stream
+	ctx, warnings := transformer.WithWarnings(context.Background())
 
-	case transformer.ProviderClaude:
-		stream := &claude.ClaudeResponse{}
-		if err = json.Unmarshal([]byte(streamJsonStr), stream); err != nil {
-			return createErrorResult(fmt.Sprintf("Failed to parse Claude stream: %v", err))
-		}
-		srcStream = stream
+	// OpenAI targets show the usage chunk
+	ctx = transformer.WithOptions(ctx, transformer.Options{IncludeUsage: true})
 
-	default:
-		return createErrorResult(fmt.Sprintf("Unsupported source provider for stream: %s", sourceProvider))
+	chunks, err := parseStream(sourceProvider, streamJsonStr)
+	if err != nil {
+		return createErrorResult(fmt.Sprintf("Failed to parse %s stream: %v", sourceProvider, err))
 	}
-
-	// Create destination stream object
-	switch targetProvider {
-	case transformer.ProviderOpenAI:
-		dstStream = &openai.ChatCompletionStreamResponse{}
-	case transformer.ProviderGemini:
-		dstStream = &gemini.GeminiChatResponse{}
-	case transformer.ProviderClaude:
-		dstStream = &claude.ClaudeResponse{}
-	default:
+	if len(chunks) == 0 {
+		return createErrorResult("The stream has no chunks")
+	}
+	if _, err := transformer.NewPayload(targetProvider, transformer.TransformerTypeChunk); err != nil {
 		return createErrorResult(fmt.Sprintf("Unsupported target provider for stream: %s", targetProvider))
 	}
 
-	// Get direct transformer
-	transformerInstance := getDirectTransformer(sourceProvider)
-	if transformerInstance == nil {
-		return createErrorResult(fmt.Sprintf("No transformer available for %s -> %s stream", sourceProvider, targetProvider))
+	if first, ok := chunks[0].(*claude.ClaudeResponse); ok && first.Type != "message_start" {
+		// accept a fragment of a Claude stream, as the examples are
+		chunks = append([]interface{}{&claude.ClaudeResponse{
+			Type:    "message_start",
+			Message: &claude.ClaudeMediaMessage{Type: "message", Role: "assistant", Usage: &claude.ClaudeUsage{}},
+		}}, chunks...)
 	}
 
-	// Perform direct stream transformation
-	err = transformerInstance.Do(ctx, transformer.TransformerTypeStream, srcStream, dstStream)
+	converter := transformer.NewStreamConverter(ctx, sourceProvider, targetProvider)
+	dstStream := []interface{}{}
+	for _, chunk := range chunks {
+		out, err := converter.Transform(chunk)
+		if err != nil {
+			return createErrorResult(fmt.Sprintf("Failed to transform stream: %v", err))
+		}
+		dstStream = append(dstStream, out...)
+	}
+	out, err := converter.Finish()
 	if err != nil {
 		return createErrorResult(fmt.Sprintf("Failed to transform stream: %v", err))
 	}
+	dstStream = append(dstStream, out...)
 
 	// Convert result to JSON
 	resultJson, err := json.MarshalIndent(dstStream, "", "  ")
@@ -275,8 +274,54 @@ func transformStream(this js.Value, args []js.Value) interface{} {
 	}
 
 	return map[string]interface{}{
-		"success": true,
-		"result":  string(resultJson),
+		"success":  true,
+		"result":   string(resultJson),
+		"warnings": warningsResult(warnings),
+	}
+}
+
+// parseStream decodes the chunks of a stream of provider: server-sent events, a JSON
+// array, or JSON chunks one after another.
+func parseStream(provider transformer.Provider, stream string) ([]interface{}, error) {
+	var chunks []interface{}
+	decode := func(data []byte) error {
+		chunk, err := transformer.NewPayload(provider, transformer.TransformerTypeChunk)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, chunk); err != nil {
+			return err
+		}
+		chunks = append(chunks, chunk)
+		return nil
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(stream), "{") {
+		dec := json.NewDecoder(strings.NewReader(stream))
+		for dec.More() {
+			var data json.RawMessage
+			if err := dec.Decode(&data); err != nil {
+				return nil, err
+			}
+			if err := decode(data); err != nil {
+				return nil, err
+			}
+		}
+		return chunks, nil
+	}
+
+	events := sse.NewReader(strings.NewReader(stream))
+	for {
+		ev, err := events.Next()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := decode([]byte(ev.Data)); err != nil {
+			return nil, err
+		}
 	}
 }
 
@@ -296,7 +341,7 @@ func transformChunk(this js.Value, args []js.Value) interface{} {
 	targetProvider := transformer.Provider(args[1].String())
 	chunkJsonStr := args[2].String()
 
-	ctx := context.Background()
+	ctx, warnings := transformer.WithWarnings(context.Background())
 
 	// Parse the chunk JSON based on source provider
 	var srcChunk interface{}
@@ -341,27 +386,38 @@ func transformChunk(this js.Value, args []js.Value) interface{} {
 		return createErrorResult(fmt.Sprintf("Unsupported target provider for chunk: %s", targetProvider))
 	}
 
-	// Get direct transformer
-	transformerInstance := getDirectTransformer(sourceProvider)
-	if transformerInstance == nil {
-		return createErrorResult(fmt.Sprintf("No transformer available for %s -> %s chunk", sourceProvider, targetProvider))
+	var result interface{} = dstChunk
+	switch {
+	case targetProvider == transformer.ProviderGemini && sourceProvider != targetProvider:
+		// Gemini chunks carry whole tool calls, they convert through the unified format
+		var u *transformer.UnifiedChunk
+		if u, err = transformer.ToUnifiedChunk(ctx, sourceProvider, srcChunk); err == nil {
+			err = transformer.FromUnifiedChunk(ctx, targetProvider, u, dstChunk)
+		}
+	case sourceProvider == transformer.ProviderGemini && targetProvider == transformer.ProviderClaude:
+		// a Gemini chunk opens the Claude message and its blocks, the result is a list of events
+		result, err = transformer.NewStreamConverter(ctx, sourceProvider, targetProvider).Transform(srcChunk)
+	default:
+		transformerInstance := getDirectTransformer(sourceProvider)
+		if transformerInstance == nil {
+			return createErrorResult(fmt.Sprintf("No transformer available for %s -> %s chunk", sourceProvider, targetProvider))
+		}
+		err = transformerInstance.Do(ctx, transformer.TransformerTypeChunk, srcChunk, dstChunk)
 	}
-
-	// Perform direct chunk transformation
-	err = transformerInstance.Do(ctx, transformer.TransformerTypeChunk, srcChunk, dstChunk)
 	if err != nil {
 		return createErrorResult(fmt.Sprintf("Failed to transform chunk: %v", err))
 	}
 
 	// Convert result to JSON
-	resultJson, err := json.MarshalIndent(dstChunk, "", "  ")
+	resultJson, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		return createErrorResult(fmt.Sprintf("Failed to serialize chunk result: %v", err))
 	}
 
 	return map[string]interface{}{
-		"success": true,
-		"result":  string(resultJson),
+		"success":  true,
+		"result":   string(resultJson),
+		"warnings": warningsResult(warnings),
 	}
 }
 
@@ -433,6 +489,37 @@ func getAvailableTransformations(this js.Value, args []js.Value) interface{} {
 	return parsedResult
 }
 
+// getCapabilities returns the capabilities of a provider and, given a target
+// provider, those a transformation to it would lose
+func getCapabilities(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || len(args) > 2 {
+		return createErrorResult("Expected 1 or 2 arguments: provider, [targetProvider]")
+	}
+
+	provider := transformer.Provider(args[0].String())
+	capabilities, ok := transformer.Capabilities(provider)
+	if !ok {
+		return createErrorResult(fmt.Sprintf("Unsupported provider: %s", provider))
+	}
+	capabilitiesJson, err := json.Marshal(capabilities)
+	if err != nil {
+		return createErrorResult(fmt.Sprintf("Failed to serialize capabilities: %v", err))
+	}
+
+	missing := []interface{}{}
+	if len(args) == 2 {
+		for _, name := range transformer.MissingCapabilities(provider, transformer.Provider(args[1].String())) {
+			missing = append(missing, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"success":      true,
+		"capabilities": string(capabilitiesJson),
+		"missing":      missing,
+	}
+}
+
 // validateRequest validates a request for a specific provider
 func validateRequest(this js.Value, args []js.Value) interface{} {
 	if len(args) != 2 {
@@ -511,6 +598,81 @@ func validateRequest(this js.Value, args []js.Value) interface{} {
 	}
 }
 
+// validateResponse checks the structural invariants of a response for a specific provider
+func validateResponse(this js.Value, args []js.Value) interface{} {
+	if len(args) != 2 {
+		return map[string]interface{}{
+			"error": "Expected 2 arguments: provider, responseJson",
+		}
+	}
+
+	provider := transformer.Provider(args[0].String())
+	responseJsonStr := args[1].String()
+	ctx := context.Background()
+
+	transformerInstance := getDirectTransformer(provider)
+	if transformerInstance == nil {
+		return map[string]interface{}{
+			"error":   fmt.Sprintf("Unsupported provider: %s", provider),
+			"isValid": false,
+		}
+	}
+
+	response, err := transformer.NewPayload(provider, transformer.TransformerTypeResponse)
+	if err != nil {
+		return map[string]interface{}{
+			"error":   err.Error(),
+			"isValid": false,
+		}
+	}
+	if err = json.Unmarshal([]byte(responseJsonStr), response); err != nil {
+		return map[string]interface{}{
+			"error":   fmt.Sprintf("Failed to parse response: %v", err),
+			"isValid": false,
+		}
+	}
+
+	if err := transformerInstance.ValidateResponse(ctx, response); err != nil {
+		return map[string]interface{}{
+			"error":   fmt.Sprintf("Validation failed: %v", err),
+			"isValid": false,
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"isValid": true,
+	}
+}
+
+// lintStream checks a captured event sequence against the provider's streaming protocol
+func lintStream(this js.Value, args []js.Value) interface{} {
+	if len(args) != 2 {
+		return createErrorResult("Expected 2 arguments: provider, eventsJson")
+	}
+
+	provider := transformer.Provider(args[0].String())
+	var events []transformer.StreamEvent
+	if err := json.Unmarshal([]byte(args[1].String()), &events); err != nil {
+		return createErrorResult(fmt.Sprintf("Failed to parse events: %v", err))
+	}
+
+	violations, err := transformer.LintStream(provider, events)
+	if err != nil {
+		return createErrorResult(err.Error())
+	}
+
+	messages := make([]interface{}, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.String())
+	}
+	return map[string]interface{}{
+		"success":    true,
+		"isValid":    len(violations) == 0,
+		"violations": messages,
+	}
+}
+
 // getDirectTransformer returns the direct transformer for a specific source->target pair
 func getDirectTransformer(sourceProvider transformer.Provider) transformer.Transformer {
 	switch sourceProvider {
@@ -552,8 +714,8 @@ func getExampleRequest(this js.Value, args []js.Value) interface{} {
 				},
 			},
 			MaxTokens:   150,
-			Temperature: 0.7,
-			TopP:        1.0,
+			Temperature: &[]float64{0.7}[0],
+			TopP:        &[]float64{1.0}[0],
 		}
 
 	case transformer.ProviderGemini:
@@ -574,7 +736,7 @@ func getExampleRequest(this js.Value, args []js.Value) interface{} {
 			GenerationConfig: gemini.GeminiChatGenerationConfig{
 				MaxOutputTokens: 150,
 				Temperature:     &[]float64{0.7}[0],
-				TopP:            1.0,
+				TopP:            &[]float64{1.0}[0],
 			},
 		}
 
@@ -640,7 +802,10 @@ func main() {
 	safeRegister("transformChunk", transformChunk)
 	safeRegister("getSupportedProviders", getSupportedProviders)
 	safeRegister("getAvailableTransformations", getAvailableTransformations)
+	safeRegister("getCapabilities", getCapabilities)
 	safeRegister("validateRequest", validateRequest)
+	safeRegister("validateResponse", validateResponse)
+	safeRegister("lintStream", lintStream)
 	safeRegister("getExampleRequest", getExampleRequest)
 
 	fmt.Println("All JavaScript functions registered successfully")
@@ -405,7 +405,10 @@ class LLMTransformers {
         }
 
         try {
-            const result = validateRequest(sourceProvider, input);
+            const transformationType = document.querySelector('input[name="transformationType"]:checked').value;
+            const result = transformationType === 'response' && typeof validateResponse === 'function'
+                ? validateResponse(sourceProvider, input)
+                : validateRequest(sourceProvider, input);
             if (result.success && result.isValid) {
                 validationEl.textContent = '✓ Valid';
                 validationEl.className = 'validation-status valid';